@@ -0,0 +1,3 @@
+module github.com/IPampurin/bigcalc
+
+go 1.24.1
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/IPampurin/bigcalc/pkg/calc"
+)
+
+// коды завершения
+const (
+	exitOK    = 0 // все выражения вычислены
+	exitError = 1 // хотя бы в одном выражении ошибка
+	exitUsage = 2 // неверные флаги
+)
+
+const prompt = "> "
+
+const help = `Операторы: + - * / % ^ (степень, правоассоциативная), скобки, унарный минус.
+Функции: sqrt(x), abs(x), factorial(n), binomial(n, k), gcd(a, b, ...).
+Переменные: x = 2^64, затем x * x; _ - результат предыдущего выражения.
+Команды: :vars - список переменных, :help - эта справка. Выход - Ctrl+D.`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run разбирает флаги и вычисляет выражения из аргументов или, если их нет, построчно из stdin
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	var modeName string
+	var prec uint
+
+	fs := flag.NewFlagSet("bigcalc", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&modeName, "mode", "rat", "режим вычислений: int (big.Int), rat (точные дроби big.Rat), float (big.Float)")
+	fs.UintVar(&prec, "prec", calc.DefaultPrec, "точность режима float, бит мантиссы")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Использование: bigcalc [-mode int|rat|float] [-prec бит] [выражение ...]")
+		fs.PrintDefaults()
+		fmt.Fprintln(stderr, "\n"+help)
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	mode, err := calc.ParseMode(modeName)
+	if err == nil && prec == 0 {
+		err = errors.New("-prec должна быть положительной")
+	}
+	var c *calc.Calculator
+	if err == nil {
+		c, err = calc.New(mode, prec)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Ошибка: %v\n", err)
+		return exitUsage
+	}
+
+	// выражения из аргументов: bigcalc 'x = 2^64' 'x * x'
+	if fs.NArg() > 0 {
+		code := exitOK
+		for _, line := range fs.Args() {
+			if !evalLine(c, line, "", stdout, stderr) {
+				code = exitError
+			}
+		}
+		return code
+	}
+
+	return repl(c, mode, stdin, stdout, stderr)
+}
+
+// repl читает выражения построчно; в терминале выводит приглашение, иначе работает как сценарий
+func repl(c *calc.Calculator, mode calc.Mode, stdin io.Reader, stdout, stderr io.Writer) int {
+
+	interactive := isTerminal(stdin)
+	if interactive {
+		fmt.Fprintf(stdout, "Калькулятор больших чисел, режим %s. :help - справка, Ctrl+D - выход\n", mode)
+	}
+
+	code := exitOK
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for lineNo := 1; ; lineNo++ {
+		if interactive {
+			fmt.Fprint(stdout, prompt)
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+
+		switch line {
+		case "":
+			continue
+		case ":help":
+			fmt.Fprintln(stdout, help)
+			continue
+		case ":vars":
+			fmt.Fprintln(stdout, strings.Join(c.Vars(), " "))
+			continue
+		}
+
+		// в терминале строка уже видна после приглашения, в сценарии - указываем номер строки
+		where := prompt
+		if !interactive {
+			where = fmt.Sprintf("строка %d", lineNo)
+		}
+		if !evalLine(c, scanner.Text(), where, stdout, stderr) {
+			code = exitError
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(stderr, "Ошибка чтения: %v\n", err)
+		return exitError
+	}
+	if interactive {
+		fmt.Fprintln(stdout)
+	}
+
+	return code
+}
+
+// evalLine вычисляет строку и выводит результат или ошибку с указателем на позицию:
+// where == prompt - строка уже на экране после приглашения, иначе её нужно повторить
+func evalLine(c *calc.Calculator, line, where string, stdout, stderr io.Writer) bool {
+
+	result, err := c.Eval(line)
+	if err == nil {
+		fmt.Fprintln(stdout, result)
+		return true
+	}
+
+	var calcErr *calc.Error
+	if !errors.As(err, &calcErr) {
+		fmt.Fprintf(stderr, "Ошибка: %v\n", err)
+		return false
+	}
+
+	indent := strings.Repeat(" ", calcErr.Pos-1)
+	if where == prompt {
+		fmt.Fprintf(stderr, "%s%s^\n", strings.Repeat(" ", len(prompt)), indent)
+		fmt.Fprintf(stderr, "Ошибка: %v\n", err)
+		return false
+	}
+	if where != "" {
+		where += ", "
+	}
+	fmt.Fprintf(stderr, "  %s\n  %s^\nОшибка: %s%v\n", line, indent, where, err)
+
+	return false
+}
+
+// isTerminal сообщает, что r - терминал, а не файл или канал
+func isTerminal(r io.Reader) bool {
+
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
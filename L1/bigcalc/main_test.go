@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"аргументы", []string{"x = 2^64", "x * x"}, "", exitOK,
+			"18446744073709551616\n340282366920938463463374607431768211456\n", ""},
+		{"сценарий из stdin", []string{"-mode", "int"}, "7 / 2\n\n:vars\ny = _ * 10\n:vars\n", exitOK,
+			"3\n\n30\ny\n", ""},
+		{"режим float", []string{"-mode", "float", "-prec", "64"}, "1/3\n", exitOK, "0.333333333333333333\n", ""},
+		{"ошибка в сценарии", nil, "1 + 2\n1 / (3 - 3)\n4\n", exitError,
+			"3\n4\n", "  1 / (3 - 3)\n    ^\nОшибка: строка 2, позиция 3: деление на ноль"},
+		{"ошибка в аргументе", []string{"2 +"}, "", exitError, "", "  2 +\n     ^\nОшибка: позиция 4"},
+		{"неизвестный режим", []string{"-mode", "complex"}, "", exitUsage, "", "неизвестный режим"},
+		{"нулевая точность", []string{"-prec", "0"}, "", exitUsage, "", "-prec должна быть положительной"},
+		{"неизвестный флаг", []string{"-x"}, "", exitUsage, "", "flag provided but not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr); code != tt.code {
+				t.Errorf("code = %d, want %d\nstdout: %s\nstderr: %s", code, tt.code, stdout.String(), stderr.String())
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("stderr не содержит %q:\n%s", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxBits       = 1 << 20 // наибольший размер целого результата (около 315 тысяч десятичных цифр)
+	maxLiteralExp = 100000  // наибольший показатель в записи числа: 1e100000
+	maxFactorial  = 50000   // 50000! - около 213 тысяч цифр
+	maxBinomialK  = 10000   // binomial(n, k) считается при min(k, n-k) не больше этого
+	// числа float - по модулю от 2^-131072 до 2^131072: вывод в десятичном виде требует
+	// точного перевода, и его время быстро растёт с показателем
+	maxFloatExp = 1 << 17
+)
+
+var (
+	// ErrDivisionByZero - деление или остаток от деления на ноль
+	ErrDivisionByZero = errors.New("деление на ноль")
+	// ErrTooLarge - результат превысил ограничение размера (защита от 9^9^9^9)
+	ErrTooLarge = errors.New("слишком большой результат")
+	// ErrNotInteger - операция определена только для целых чисел
+	ErrNotInteger = errors.New("ожидалось целое число")
+)
+
+// arith - арифметика одного режима над значениями типа T. Значения не изменяются:
+// каждая операция возвращает новое значение
+type arith[T any] interface {
+	parse(text string) (T, error)
+	fromInt(x *big.Int) (T, error)
+	toInt(x T) (*big.Int, error) // ErrNotInteger, если у x есть дробная часть
+	neg(x T) T
+	abs(x T) T
+	add(x, y T) (T, error)
+	sub(x, y T) (T, error)
+	mul(x, y T) (T, error)
+	quo(x, y T) (T, error)
+	rem(x, y T) (T, error) // знак остатка - как у делимого, как у % в Go
+	pow(x T, e *big.Int) (T, error)
+	sqrt(x T) (T, error)
+	format(x T) string
+}
+
+// checkLiteralExp ограничивает показатель в записи числа вида 1e100
+func checkLiteralExp(text string) error {
+
+	i := strings.IndexAny(text, "eE")
+	if i < 0 {
+		return nil
+	}
+	exp, err := strconv.Atoi(text[i+1:])
+	if err != nil || exp > maxLiteralExp || exp < -maxLiteralExp {
+		return fmt.Errorf("показатель в записи %s больше %d", text, maxLiteralExp)
+	}
+
+	return nil
+}
+
+// checkBits проверяет, что целое с bits двоичных разрядов не больше ограничения
+func checkBits(bits int) error {
+
+	if bits > maxBits {
+		return fmt.Errorf("%w: больше %d двоичных разрядов", ErrTooLarge, maxBits)
+	}
+
+	return nil
+}
+
+// powBits оценивает размер |x|^e в битах по log2|x|; false - заведомо больше maxBits
+func powBits(x *big.Int, e *big.Int) (int, bool) {
+
+	if x.CmpAbs(big.NewInt(1)) <= 0 {
+		return 1, true // 0, 1 и -1 в любой степени не растут
+	}
+	if !e.IsInt64() || e.Int64() > maxBits {
+		return 0, false
+	}
+
+	// log2|x| по старшим 64 битам
+	shift := max(x.BitLen()-64, 0)
+	top := new(big.Int).Rsh(new(big.Int).Abs(x), uint(shift)).Uint64()
+	log2 := float64(shift) + math.Log2(float64(top))
+
+	return int(math.Ceil(log2*float64(e.Int64()))) + 1, true
+}
+
+// intArith - режим int: целые числа big.Int, деление отбрасывает дробную часть
+type intArith struct{}
+
+func (intArith) parse(text string) (*big.Int, error) {
+
+	if strings.ContainsAny(text, ".eE") {
+		return nil, fmt.Errorf("в режиме int допустимы только целые числа, для %s нужен режим rat или float", text)
+	}
+	x, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil, fmt.Errorf("неверное число %s", text)
+	}
+
+	return x, checkBits(x.BitLen())
+}
+
+func (intArith) fromInt(x *big.Int) (*big.Int, error) { return x, nil }
+func (intArith) toInt(x *big.Int) (*big.Int, error)   { return x, nil }
+func (intArith) neg(x *big.Int) *big.Int              { return new(big.Int).Neg(x) }
+func (intArith) abs(x *big.Int) *big.Int              { return new(big.Int).Abs(x) }
+func (intArith) format(x *big.Int) string             { return x.String() }
+func (intArith) add(x, y *big.Int) (*big.Int, error)  { return new(big.Int).Add(x, y), nil }
+func (intArith) sub(x, y *big.Int) (*big.Int, error)  { return new(big.Int).Sub(x, y), nil }
+
+func (intArith) mul(x, y *big.Int) (*big.Int, error) {
+
+	if err := checkBits(x.BitLen() + y.BitLen()); err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).Mul(x, y), nil
+}
+
+func (intArith) quo(x, y *big.Int) (*big.Int, error) {
+
+	if y.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+
+	return new(big.Int).Quo(x, y), nil
+}
+
+func (intArith) rem(x, y *big.Int) (*big.Int, error) {
+
+	if y.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+
+	return new(big.Int).Rem(x, y), nil
+}
+
+func (intArith) pow(x *big.Int, e *big.Int) (*big.Int, error) {
+
+	if e.Sign() < 0 {
+		return nil, errors.New("отрицательная степень в режиме int: нужен режим rat или float")
+	}
+	bits, ok := powBits(x, e)
+	if !ok {
+		return nil, checkBits(maxBits + 1)
+	}
+	if err := checkBits(bits); err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).Exp(x, e, nil), nil
+}
+
+// sqrt в режиме int - целая часть корня
+func (intArith) sqrt(x *big.Int) (*big.Int, error) {
+
+	if x.Sign() < 0 {
+		return nil, errors.New("корень из отрицательного числа")
+	}
+
+	return new(big.Int).Sqrt(x), nil
+}
+
+// ratArith - режим rat: точные дроби big.Rat
+type ratArith struct{}
+
+func (ratArith) parse(text string) (*big.Rat, error) {
+
+	if err := checkLiteralExp(text); err != nil {
+		return nil, err
+	}
+	x, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("неверное число %s", text)
+	}
+
+	return x, nil
+}
+
+func (ratArith) fromInt(x *big.Int) (*big.Rat, error) { return new(big.Rat).SetInt(x), nil }
+
+func (ratArith) toInt(x *big.Rat) (*big.Int, error) {
+
+	if !x.IsInt() {
+		return nil, fmt.Errorf("%w, а не дробь %s", ErrNotInteger, x.RatString())
+	}
+
+	return new(big.Int).Set(x.Num()), nil
+}
+
+func (ratArith) neg(x *big.Rat) *big.Rat             { return new(big.Rat).Neg(x) }
+func (ratArith) abs(x *big.Rat) *big.Rat             { return new(big.Rat).Abs(x) }
+func (ratArith) format(x *big.Rat) string            { return x.RatString() }
+func (ratArith) add(x, y *big.Rat) (*big.Rat, error) { return new(big.Rat).Add(x, y), nil }
+func (ratArith) sub(x, y *big.Rat) (*big.Rat, error) { return new(big.Rat).Sub(x, y), nil }
+
+func (ratArith) mul(x, y *big.Rat) (*big.Rat, error) {
+
+	bits := x.Num().BitLen() + x.Denom().BitLen() + y.Num().BitLen() + y.Denom().BitLen()
+	if err := checkBits(bits); err != nil {
+		return nil, err
+	}
+
+	return new(big.Rat).Mul(x, y), nil
+}
+
+func (ratArith) quo(x, y *big.Rat) (*big.Rat, error) {
+
+	if y.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	bits := x.Num().BitLen() + x.Denom().BitLen() + y.Num().BitLen() + y.Denom().BitLen()
+	if err := checkBits(bits); err != nil {
+		return nil, err
+	}
+
+	return new(big.Rat).Quo(x, y), nil
+}
+
+// rem - остаток x - y*trunc(x/y); для целых совпадает с % в Go
+func (r ratArith) rem(x, y *big.Rat) (*big.Rat, error) {
+
+	q, err := r.quo(x, y)
+	if err != nil {
+		return nil, err
+	}
+	trunc := new(big.Int).Quo(q.Num(), q.Denom())
+
+	return new(big.Rat).Sub(x, new(big.Rat).Mul(y, new(big.Rat).SetInt(trunc))), nil
+}
+
+func (ratArith) pow(x *big.Rat, e *big.Int) (*big.Rat, error) {
+
+	if x.Sign() == 0 && e.Sign() < 0 {
+		return nil, ErrDivisionByZero
+	}
+	abs := new(big.Int).Abs(e)
+	numBits, ok1 := powBits(x.Num(), abs)
+	denBits, ok2 := powBits(x.Denom(), abs)
+	if !ok1 || !ok2 {
+		return nil, checkBits(maxBits + 1)
+	}
+	if err := checkBits(numBits + denBits); err != nil {
+		return nil, err
+	}
+
+	num := new(big.Int).Exp(x.Num(), abs, nil)
+	den := new(big.Int).Exp(x.Denom(), abs, nil)
+	if e.Sign() < 0 {
+		num, den = den, num
+	}
+
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// sqrt в режиме rat определён только для точных квадратов дробей
+func (ratArith) sqrt(x *big.Rat) (*big.Rat, error) {
+
+	if x.Sign() < 0 {
+		return nil, errors.New("корень из отрицательного числа")
+	}
+	num := new(big.Int).Sqrt(x.Num())
+	den := new(big.Int).Sqrt(x.Denom())
+	if new(big.Int).Mul(num, num).Cmp(x.Num()) != 0 || new(big.Int).Mul(den, den).Cmp(x.Denom()) != 0 {
+		return nil, errors.New("корень не выражается дробью: нужен режим float")
+	}
+
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// floatArith - режим float: двоичные числа big.Float с мантиссой prec бит
+type floatArith struct {
+	prec uint
+}
+
+func (f floatArith) new() *big.Float {
+	return new(big.Float).SetPrec(f.prec)
+}
+
+// checked превращает выход показателя за ±maxFloatExp в ошибку; заодно исключает ±Inf,
+// с которыми big.Float паникует на операциях вроде Inf - Inf
+func checked(z *big.Float) (*big.Float, error) {
+
+	if exp := z.MantExp(nil); z.IsInf() || exp > maxFloatExp || exp < -maxFloatExp {
+		return nil, fmt.Errorf("%w: число float вне 2^±%d", ErrTooLarge, maxFloatExp)
+	}
+
+	return z, nil
+}
+
+func (f floatArith) parse(text string) (*big.Float, error) {
+
+	if err := checkLiteralExp(text); err != nil {
+		return nil, err
+	}
+	x, ok := f.new().SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("неверное число %s", text)
+	}
+
+	return checked(x)
+}
+
+func (f floatArith) fromInt(x *big.Int) (*big.Float, error) { return checked(f.new().SetInt(x)) }
+
+func (floatArith) toInt(x *big.Float) (*big.Int, error) {
+
+	if !x.IsInt() {
+		return nil, fmt.Errorf("%w, а не %s", ErrNotInteger, x.Text('g', 10))
+	}
+	if x.MantExp(nil) > maxBits {
+		return nil, checkBits(maxBits + 1)
+	}
+	i, _ := x.Int(nil)
+
+	return i, nil
+}
+
+func (f floatArith) neg(x *big.Float) *big.Float { return f.new().Neg(x) }
+func (f floatArith) abs(x *big.Float) *big.Float { return f.new().Abs(x) }
+
+func (f floatArith) add(x, y *big.Float) (*big.Float, error) { return checked(f.new().Add(x, y)) }
+func (f floatArith) sub(x, y *big.Float) (*big.Float, error) { return checked(f.new().Sub(x, y)) }
+func (f floatArith) mul(x, y *big.Float) (*big.Float, error) { return checked(f.new().Mul(x, y)) }
+
+func (f floatArith) quo(x, y *big.Float) (*big.Float, error) {
+
+	if y.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+
+	return checked(f.new().Quo(x, y))
+}
+
+// rem - остаток x - y*trunc(x/y), как math.Mod
+func (f floatArith) rem(x, y *big.Float) (*big.Float, error) {
+
+	q, err := f.quo(x, y)
+	if err != nil {
+		return nil, err
+	}
+	// у дробного q показатель меньше prec, поэтому целая часть невелика
+	if !q.IsInt() {
+		i, _ := q.Int(nil)
+		q.SetInt(i)
+	}
+
+	return checked(f.new().Sub(x, f.new().Mul(y, q)))
+}
+
+// pow возводит в целую степень последовательным возведением в квадрат
+func (f floatArith) pow(x *big.Float, e *big.Int) (*big.Float, error) {
+
+	if x.Sign() == 0 && e.Sign() < 0 {
+		return nil, ErrDivisionByZero
+	}
+
+	result := f.new().SetInt64(1)
+	base := f.new().Set(x)
+	abs := new(big.Int).Abs(e)
+	for i := abs.BitLen() - 1; i >= 0; i-- {
+		result.Mul(result, result)
+		if abs.Bit(i) == 1 {
+			result.Mul(result, base)
+		}
+		if result.IsInf() {
+			return checked(result)
+		}
+	}
+	if e.Sign() < 0 {
+		result.Quo(f.new().SetInt64(1), result)
+	}
+
+	return checked(result)
+}
+
+func (f floatArith) sqrt(x *big.Float) (*big.Float, error) {
+
+	if x.Sign() < 0 {
+		return nil, errors.New("корень из отрицательного числа")
+	}
+
+	return f.new().Sqrt(x), nil
+}
+
+// format выводит столько значащих цифр, сколько гарантирует мантисса, без последней
+// неточной: при 256 битах - 76 цифр
+func (f floatArith) format(x *big.Float) string {
+
+	digits := max(int(float64(f.prec)*math.Log10(2))-1, 1)
+
+	return x.Text('g', digits)
+}
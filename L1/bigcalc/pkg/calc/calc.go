@@ -0,0 +1,342 @@
+// Package calc - калькулятор выражений с числами произвольной точности (развитие l1.22):
+// операторы + - * / % ^, скобки, унарный минус, переменные и функции sqrt, gcd, factorial,
+// binomial, abs. Выражение разбирается парсером Пратта и вычисляется в одном из режимов:
+// целые big.Int, дроби big.Rat или числа с плавающей точкой big.Float заданной точности.
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+// Mode - режим вычислений
+type Mode int
+
+const (
+	ModeInt   Mode = iota // целые big.Int, деление с отбрасыванием дробной части
+	ModeRat               // точные дроби big.Rat
+	ModeFloat             // big.Float с заданной точностью
+)
+
+// DefaultPrec - точность режима float по умолчанию, бит мантиссы (около 77 десятичных цифр)
+const DefaultPrec = 256
+
+// MaxPrec - наибольшая точность режима float
+const MaxPrec = 1 << 16
+
+var modeNames = []string{"int", "rat", "float"}
+
+// String возвращает имя режима
+func (m Mode) String() string {
+
+	if int(m) < 0 || int(m) >= len(modeNames) {
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+
+	return modeNames[m]
+}
+
+// ParseMode разбирает имя режима: int, rat или float
+func ParseMode(s string) (Mode, error) {
+
+	i := slices.Index(modeNames, s)
+	if i < 0 {
+		return 0, fmt.Errorf("неизвестный режим %q: допустимы int, rat, float", s)
+	}
+
+	return Mode(i), nil
+}
+
+// Error - ошибка разбора или вычисления с позицией в строке (номер символа с 1)
+type Error struct {
+	Pos int
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("позиция %d: %v", e.Pos, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Calculator вычисляет строки выражений и хранит переменные между вызовами.
+// Результат последнего вычисления доступен как переменная "_"
+type Calculator struct {
+	eval func(statement) (string, error)
+	vars func() []string
+}
+
+// New создаёт калькулятор; prec - точность режима float в битах (0 - DefaultPrec)
+func New(mode Mode, prec uint) (*Calculator, error) {
+
+	if prec == 0 {
+		prec = DefaultPrec
+	}
+	if prec > MaxPrec {
+		return nil, fmt.Errorf("точность больше %d бит", MaxPrec)
+	}
+
+	switch mode {
+	case ModeInt:
+		return newCalculator[*big.Int](intArith{}), nil
+	case ModeRat:
+		return newCalculator[*big.Rat](ratArith{}), nil
+	case ModeFloat:
+		return newCalculator[*big.Float](floatArith{prec: prec}), nil
+	}
+
+	return nil, fmt.Errorf("неизвестный режим %v", mode)
+}
+
+func newCalculator[T any](a arith[T]) *Calculator {
+
+	e := &engine[T]{arith: a, vars: make(map[string]T)}
+
+	return &Calculator{eval: e.run, vars: e.names}
+}
+
+// Eval вычисляет строку "выражение" или "имя = выражение" и возвращает результат.
+// Ошибки разбора и вычисления - *Error с позицией
+func (c *Calculator) Eval(line string) (string, error) {
+
+	st, err := parse(line)
+	if err != nil {
+		return "", err
+	}
+
+	return c.eval(st)
+}
+
+// Vars возвращает имена заданных переменных по алфавиту
+func (c *Calculator) Vars() []string {
+	return c.vars()
+}
+
+// functions - функции и число их аргументов (-1 - два и больше)
+var functions = map[string]int{
+	"sqrt":      1,
+	"abs":       1,
+	"factorial": 1,
+	"binomial":  2,
+	"gcd":       -1,
+}
+
+// engine вычисляет выражения в арифметике одного режима
+type engine[T any] struct {
+	arith arith[T]
+	vars  map[string]T
+}
+
+func (e *engine[T]) run(st statement) (string, error) {
+
+	if _, ok := functions[st.assign]; ok {
+		return "", &Error{Pos: st.assignPos, Err: fmt.Errorf("имя %s занято функцией", st.assign)}
+	}
+
+	v, err := e.eval(st.expr)
+	if err != nil {
+		return "", err
+	}
+	if st.assign != "" {
+		e.vars[st.assign] = v
+	}
+	e.vars["_"] = v
+
+	return e.arith.format(v), nil
+}
+
+func (e *engine[T]) names() []string {
+
+	var names []string
+	for name := range e.vars {
+		if name != "_" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// eval вычисляет узел; ошибки арифметики получают позицию узла
+func (e *engine[T]) eval(n node) (T, error) {
+
+	var zero T
+	v, err := e.evalNode(n)
+	if err != nil {
+		var posErr *Error
+		if !errors.As(err, &posErr) {
+			err = &Error{Pos: n.position(), Err: err}
+		}
+		return zero, err
+	}
+
+	return v, nil
+}
+
+func (e *engine[T]) evalNode(n node) (T, error) {
+
+	var zero T
+	a := e.arith
+
+	switch n := n.(type) {
+	case *number:
+		return a.parse(n.text)
+	case *variable:
+		v, ok := e.vars[n.name]
+		if !ok {
+			if _, isFunc := functions[n.name]; isFunc {
+				return zero, fmt.Errorf("%s - функция, нужны скобки: %s(...)", n.name, n.name)
+			}
+			return zero, fmt.Errorf("неизвестная переменная %s", n.name)
+		}
+		return v, nil
+	case *unary:
+		x, err := e.eval(n.x)
+		if err != nil || n.op == "+" {
+			return x, err
+		}
+		return a.neg(x), nil
+	case *binary:
+		x, err := e.eval(n.x)
+		if err != nil {
+			return zero, err
+		}
+		y, err := e.eval(n.y)
+		if err != nil {
+			return zero, err
+		}
+		return e.binary(n.op, x, y)
+	case *call:
+		return e.call(n)
+	}
+
+	return zero, fmt.Errorf("неизвестный узел %T", n)
+}
+
+func (e *engine[T]) binary(op string, x, y T) (T, error) {
+
+	a := e.arith
+	switch op {
+	case "+":
+		return a.add(x, y)
+	case "-":
+		return a.sub(x, y)
+	case "*":
+		return a.mul(x, y)
+	case "/":
+		return a.quo(x, y)
+	case "%":
+		return a.rem(x, y)
+	}
+
+	// ^: показатель - целое число в любом режиме
+	var zero T
+	exp, err := a.toInt(y)
+	if err != nil {
+		return zero, fmt.Errorf("показатель степени: %w", err)
+	}
+
+	return a.pow(x, exp)
+}
+
+func (e *engine[T]) call(c *call) (T, error) {
+
+	var zero T
+	arity, ok := functions[c.name]
+	if !ok {
+		return zero, fmt.Errorf("неизвестная функция %s (есть: abs, binomial, factorial, gcd, sqrt)", c.name)
+	}
+	if arity >= 0 && len(c.args) != arity || arity < 0 && len(c.args) < 2 {
+		want := fmt.Sprint(arity)
+		if arity < 0 {
+			want = "не меньше 2"
+		}
+		return zero, fmt.Errorf("%s: ожидается аргументов %s, передано %d", c.name, want, len(c.args))
+	}
+
+	args := make([]T, len(c.args))
+	for i, arg := range c.args {
+		v, err := e.eval(arg)
+		if err != nil {
+			return zero, err
+		}
+		args[i] = v
+	}
+
+	a := e.arith
+	switch c.name {
+	case "sqrt":
+		return a.sqrt(args[0])
+	case "abs":
+		return a.abs(args[0]), nil
+	}
+
+	// остальные функции определены на целых числах
+	ints := make([]*big.Int, len(args))
+	for i, v := range args {
+		n, err := a.toInt(v)
+		if err != nil {
+			return zero, &Error{Pos: start(c.args[i]), Err: fmt.Errorf("%s: %w", c.name, err)}
+		}
+		ints[i] = n
+	}
+
+	var res *big.Int
+	var err error
+	switch c.name {
+	case "factorial":
+		res, err = factorial(ints[0])
+	case "binomial":
+		res, err = binomial(ints[0], ints[1])
+	case "gcd":
+		res = new(big.Int)
+		for _, n := range ints {
+			res.GCD(nil, nil, res, n)
+		}
+	}
+	if err != nil {
+		return zero, fmt.Errorf("%s: %w", c.name, err)
+	}
+
+	return a.fromInt(res)
+}
+
+func factorial(n *big.Int) (*big.Int, error) {
+
+	if n.Sign() < 0 {
+		return nil, errors.New("факториал отрицательного числа")
+	}
+	if !n.IsInt64() || n.Int64() > maxFactorial {
+		return nil, fmt.Errorf("%w: аргумент больше %d", ErrTooLarge, maxFactorial)
+	}
+
+	return new(big.Int).MulRange(1, n.Int64()), nil
+}
+
+func binomial(n, k *big.Int) (*big.Int, error) {
+
+	if n.Sign() < 0 || k.Sign() < 0 {
+		return nil, errors.New("аргументы должны быть неотрицательными")
+	}
+	if k.Cmp(n) > 0 {
+		return new(big.Int), nil
+	}
+	if !n.IsInt64() {
+		return nil, fmt.Errorf("%w: n больше 2^63", ErrTooLarge)
+	}
+	// C(n, k) < n^min(k, n-k)
+	small := min(k.Int64(), n.Int64()-k.Int64())
+	if small > maxBinomialK {
+		return nil, fmt.Errorf("%w: min(k, n-k) больше %d", ErrTooLarge, maxBinomialK)
+	}
+	if err := checkBits(int(small) * n.BitLen()); err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).Binomial(n.Int64(), k.Int64()), nil
+}
@@ -0,0 +1,214 @@
+package calc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+
+	tests := []struct {
+		expr string
+		int  string // пусто - ошибка
+		rat  string
+		flt  string
+	}{
+		{"1 + 2 * 3", "7", "7", "7"},
+		{"(1 + 2) * 3", "9", "9", "9"},
+		{"2^3^2", "512", "512", "512"},
+		{"-2^2", "-4", "-4", "-4"},
+		{"(-2)^2", "4", "4", "4"},
+		{"2^-1", "", "1/2", "0.5"},
+		{"--3", "3", "3", "3"},
+		{"+3 - -3", "6", "6", "6"},
+		{"7 / 2", "3", "7/2", "3.5"},
+		{"-7 / 2", "-3", "-7/2", "-3.5"},
+		{"-7 % 3", "-1", "-1", "-1"},
+		{"7.5 % 2", "", "3/2", "1.5"},
+		{"0.1 + 0.2", "", "3/10", "0.3"},
+		{"1e3 / 8", "", "125", "125"},
+		{"2^100", "1267650600228229401496703205376", "1267650600228229401496703205376", "1267650600228229401496703205376"},
+		{"123456789012345678901234567890 * 987654321098765432109876543210",
+			"121932631137021795226185032733622923332237463801111263526900",
+			"121932631137021795226185032733622923332237463801111263526900",
+			"121932631137021795226185032733622923332237463801111263526900"},
+		{"2^300", "2037035976334486086268445688409378161051468393665936250636140449354381299763336706183397376",
+			"2037035976334486086268445688409378161051468393665936250636140449354381299763336706183397376",
+			"2.037035976334486086268445688409378161051468393665936250636140449354381299763e+90"},
+		{"sqrt(16/9)", "1", "4/3", "1.333333333333333333333333333333333333333333333333333333333333333333333333333"},
+		{"sqrt(2)", "1", "", "1.414213562373095048801688724209698078569671875376948073176679737990732478462"},
+		{"abs(-5)", "5", "5", "5"},
+		{"factorial(25)", "15511210043330985984000000", "15511210043330985984000000", "15511210043330985984000000"},
+		{"factorial(0)", "1", "1", "1"},
+		{"binomial(50, 25)", "126410606437752", "126410606437752", "126410606437752"},
+		{"binomial(3, 5)", "0", "0", "0"},
+		{"gcd(12, 18, 27)", "3", "3", "3"},
+		{"gcd(-4, 6)", "2", "2", "2"},
+		{"factorial(2.5)", "", "", ""},
+	}
+
+	modes := []Mode{ModeInt, ModeRat, ModeFloat}
+	for _, tt := range tests {
+		for i, want := range []string{tt.int, tt.rat, tt.flt} {
+			c, err := New(modes[i], 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.Eval(tt.expr)
+			switch {
+			case want == "" && err == nil:
+				t.Errorf("%s %q = %s, ожидалась ошибка", modes[i], tt.expr, got)
+			case want != "" && err != nil:
+				t.Errorf("%s %q: %v", modes[i], tt.expr, err)
+			case got != want:
+				t.Errorf("%s %q = %s, ожидалось %s", modes[i], tt.expr, got, want)
+			}
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+
+	c, _ := New(ModeRat, 0)
+	for _, step := range []struct{ line, want string }{
+		{"x = 2^64", "18446744073709551616"},
+		{"y = x / 3", "18446744073709551616/3"},
+		{"y * 3 - x", "0"},
+		{"_ + 1", "1"},
+		{"x = -x", "-18446744073709551616"},
+		{"x_2 = x * 2", "-36893488147419103232"},
+	} {
+		got, err := c.Eval(step.line)
+		if err != nil || got != step.want {
+			t.Fatalf("%q = %s, %v; ожидалось %s", step.line, got, err, step.want)
+		}
+	}
+
+	if got := strings.Join(c.Vars(), ","); got != "x,x_2,y" {
+		t.Errorf("Vars() = %s", got)
+	}
+
+	// ошибка не меняет переменные
+	if _, err := c.Eval("x = 1/0"); err == nil {
+		t.Fatal("ожидалась ошибка")
+	}
+	if got, _ := c.Eval("x"); got != "-18446744073709551616" {
+		t.Errorf("x = %s после ошибки", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+
+	tests := []struct {
+		mode Mode
+		expr string
+		pos  int
+		is   error  // ожидаемая причина, если есть
+		msg  string // фрагмент текста ошибки
+	}{
+		{ModeRat, "", 1, nil, "пустое выражение"},
+		{ModeRat, "2 + * 3", 5, nil, `неожиданное "*"`},
+		{ModeRat, "(1 + 2", 7, nil, "ожидалась ')' к '(' в позиции 1"},
+		{ModeRat, "1 + 2)", 6, nil, `лишнее ")"`},
+		{ModeRat, "2 3", 3, nil, `лишнее "3"`},
+		{ModeRat, "1 # 2", 3, nil, "неожиданный символ '#'"},
+		{ModeRat, "1e", 1, nil, "показателя степени"},
+		{ModeRat, "1 +", 4, nil, "выражение оборвано"},
+		{ModeRat, "10 / (5 - 5)", 4, ErrDivisionByZero, ""},
+		{ModeRat, "1 % 0", 3, ErrDivisionByZero, ""},
+		{ModeRat, "0^-1", 2, ErrDivisionByZero, ""},
+		{ModeRat, "y + 1", 1, nil, "неизвестная переменная y"},
+		{ModeRat, "1 + sqrt", 5, nil, "нужны скобки"},
+		{ModeRat, "log(2)", 1, nil, "неизвестная функция log"},
+		{ModeRat, "gcd(1)", 1, nil, "не меньше 2"},
+		{ModeRat, "binomial(1, 2, 3)", 1, nil, "ожидается аргументов 2, передано 3"},
+		{ModeRat, "gcd(4, 1/2)", 8, ErrNotInteger, ""},
+		{ModeRat, "2^(1/2)", 2, ErrNotInteger, "показатель степени"},
+		{ModeRat, "sqrt(-4)", 1, nil, "отрицательного"},
+		{ModeRat, "sqrt = 4", 1, nil, "занято функцией"},
+		{ModeRat, "factorial(-1)", 1, nil, "отрицательного"},
+		{ModeRat, "factorial(10^6)", 1, ErrTooLarge, ""},
+		{ModeRat, "9^9^9^9", 4, ErrTooLarge, ""},
+		{ModeRat, "1e1000000", 1, nil, "показатель"},
+		{ModeInt, "2^-1", 2, nil, "отрицательная степень"},
+		{ModeInt, "1 + 0.5", 5, nil, "только целые"},
+		{ModeInt, "2^(2^30)", 2, ErrTooLarge, ""},
+		{ModeFloat, "1/0", 2, ErrDivisionByZero, ""},
+		{ModeFloat, "10^(10^10)", 3, ErrTooLarge, ""},
+		{ModeFloat, "2^-200000", 2, ErrTooLarge, ""},
+		{ModeFloat, "1e-50000", 1, ErrTooLarge, ""},
+		{ModeFloat, "factorial(50000)", 1, ErrTooLarge, ""},
+		{ModeRat, "binomial(10^18, 20000)", 1, ErrTooLarge, "binomial"},
+		{ModeRat, "sqrt(2)", 1, nil, "нужен режим float"},
+		{ModeFloat, "sqrt(-1)", 1, nil, "отрицательного"},
+		{ModeFloat, "привет + 1", 1, nil, "неизвестная переменная привет"},
+		{ModeFloat, "«1»", 1, nil, "неожиданный символ"},
+		{ModeRat, "x = (1 + (2 * ", 15, nil, "выражение оборвано"},
+		{ModeRat, strings.Repeat("(", 2000) + "1" + strings.Repeat(")", 2000), maxDepth + 1, nil, "вложенность"},
+	}
+
+	for _, tt := range tests {
+		c, _ := New(tt.mode, 0)
+		_, err := c.Eval(tt.expr)
+		var calcErr *Error
+		if !errors.As(err, &calcErr) {
+			t.Errorf("%s %q: ошибка %v, ожидалась *Error", tt.mode, tt.expr, err)
+			continue
+		}
+		if calcErr.Pos != tt.pos {
+			t.Errorf("%s %q: позиция %d, ожидалась %d (%v)", tt.mode, tt.expr, calcErr.Pos, tt.pos, err)
+		}
+		if tt.is != nil && !errors.Is(err, tt.is) {
+			t.Errorf("%s %q: %v не %v", tt.mode, tt.expr, err, tt.is)
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s %q: %q не содержит %q", tt.mode, tt.expr, err, tt.msg)
+		}
+	}
+}
+
+// TestLimits проверяет, что оценка размера степени не отвергает результаты у самой границы
+func TestLimits(t *testing.T) {
+
+	c, _ := New(ModeInt, 0)
+	res, err := c.Eval("2^(2^20 - 1)")
+	if err != nil {
+		t.Fatalf("2^(2^20 - 1): %v", err)
+	}
+	if len(res) != 315653 {
+		t.Errorf("2^(2^20 - 1): %d цифр, ожидалось 315653", len(res))
+	}
+	if _, err := c.Eval("2^(2^20)"); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("2^(2^20): %v, ожидалась ErrTooLarge", err)
+	}
+	if _, err := c.Eval("3^661000"); err != nil {
+		t.Errorf("3^661000: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+
+	if _, err := New(ModeFloat, MaxPrec+1); err == nil {
+		t.Error("точность больше MaxPrec принята")
+	}
+	if _, err := New(Mode(7), 0); err == nil {
+		t.Error("неизвестный режим принят")
+	}
+
+	for _, name := range []string{"int", "rat", "float"} {
+		m, err := ParseMode(name)
+		if err != nil || m.String() != name {
+			t.Errorf("ParseMode(%q) = %v, %v", name, m, err)
+		}
+	}
+	if _, err := ParseMode("complex"); err == nil {
+		t.Error("ParseMode(complex) без ошибки")
+	}
+
+	// точность float задаёт число выводимых цифр
+	c, _ := New(ModeFloat, 64)
+	if got, _ := c.Eval("1/3"); got != "0.333333333333333333" {
+		t.Errorf("1/3 при 64 битах = %s", got)
+	}
+}
@@ -0,0 +1,160 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzEval: любая строка разбирается без паники, позиция ошибки - внутри строки или сразу за ней
+func FuzzEval(f *testing.F) {
+
+	for _, seed := range []string{"1 + 2 * 3", "-(2^3^2) % 7", "x = sqrt(16/9)", "gcd(12, 18, 27)",
+		"factorial(20) / binomial(20, 10)", "((1)", "2 + * 3", "1e5.5", "«»", "9^9^9^9", "-1^-1", "0^0"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, expr string) {
+		for _, mode := range []Mode{ModeInt, ModeRat, ModeFloat} {
+			c, _ := New(mode, 64)
+			_, err := c.Eval(expr)
+			if err == nil {
+				continue
+			}
+			var calcErr *Error
+			if !errors.As(err, &calcErr) {
+				t.Fatalf("%s %q: ошибка без позиции: %v", mode, expr, err)
+			}
+			if n := utf8.RuneCountInString(expr); calcErr.Pos < 1 || calcErr.Pos > n+1 {
+				t.Fatalf("%s %q: позиция %d вне строки длиной %d", mode, expr, calcErr.Pos, n)
+			}
+		}
+	})
+}
+
+// FuzzInt сверяет режим int с big.Int
+func FuzzInt(f *testing.F) {
+
+	f.Add(int64(123456789), int64(-987), uint8(0))
+	f.Add(int64(-7), int64(2), uint8(3))
+	f.Add(int64(3), int64(40), uint8(5))
+	f.Add(int64(1), int64(0), uint8(3))
+
+	f.Fuzz(func(t *testing.T, a, b int64, op uint8) {
+		x, y := big.NewInt(a), big.NewInt(b)
+		ops := []struct {
+			sym  string
+			want func() *big.Int // nil - ожидается ошибка
+		}{
+			{"+", func() *big.Int { return new(big.Int).Add(x, y) }},
+			{"-", func() *big.Int { return new(big.Int).Sub(x, y) }},
+			{"*", func() *big.Int { return new(big.Int).Mul(x, y) }},
+			{"/", func() *big.Int {
+				if b == 0 {
+					return nil
+				}
+				return new(big.Int).Quo(x, y)
+			}},
+			{"%", func() *big.Int {
+				if b == 0 {
+					return nil
+				}
+				return new(big.Int).Rem(x, y)
+			}},
+			{"^", func() *big.Int {
+				if b < 0 || b > 200 {
+					return nil // отрицательные и большие показатели здесь не проверяем
+				}
+				return new(big.Int).Exp(x, y, nil)
+			}},
+		}
+		o := ops[int(op)%len(ops)]
+		want := o.want()
+		if o.sym == "^" && want == nil {
+			return
+		}
+
+		c, _ := New(ModeInt, 0)
+		expr := fmt.Sprintf("(%d) %s (%d)", a, o.sym, b)
+		got, err := c.Eval(expr)
+		switch {
+		case want == nil && !errors.Is(err, ErrDivisionByZero):
+			t.Fatalf("%s = %s, %v; ожидалось деление на ноль", expr, got, err)
+		case want != nil && (err != nil || got != want.String()):
+			t.Fatalf("%s = %s, %v; ожидалось %s", expr, got, err, want)
+		}
+	})
+}
+
+// FuzzRat сверяет режим rat с big.Rat на дробях a/b и c/d
+func FuzzRat(f *testing.F) {
+
+	f.Add(int64(1), int64(3), int64(1), int64(6), uint8(0))
+	f.Add(int64(-22), int64(7), int64(355), int64(113), uint8(2))
+	f.Add(int64(5), int64(2), int64(0), int64(1), uint8(3))
+
+	f.Fuzz(func(t *testing.T, a, b, c, d int64, op uint8) {
+		if b == 0 || d == 0 {
+			return
+		}
+		x, y := big.NewRat(a, b), big.NewRat(c, d)
+		syms := []string{"+", "-", "*", "/"}
+		sym := syms[int(op)%len(syms)]
+
+		var want *big.Rat
+		switch sym {
+		case "+":
+			want = new(big.Rat).Add(x, y)
+		case "-":
+			want = new(big.Rat).Sub(x, y)
+		case "*":
+			want = new(big.Rat).Mul(x, y)
+		case "/":
+			if y.Sign() != 0 {
+				want = new(big.Rat).Quo(x, y)
+			}
+		}
+
+		calc, _ := New(ModeRat, 0)
+		expr := fmt.Sprintf("(%d)/(%d) %s ((%d)/(%d))", a, b, sym, c, d)
+		got, err := calc.Eval(expr)
+		switch {
+		case want == nil && !errors.Is(err, ErrDivisionByZero):
+			t.Fatalf("%s = %s, %v; ожидалось деление на ноль", expr, got, err)
+		case want != nil && (err != nil || got != want.RatString()):
+			t.Fatalf("%s = %s, %v; ожидалось %s", expr, got, err, want.RatString())
+		}
+	})
+}
+
+// FuzzFloat сверяет режим float с big.Float той же точности: корень и частное
+func FuzzFloat(f *testing.F) {
+
+	f.Add(uint64(2), uint64(3), uint16(256))
+	f.Add(uint64(1<<63), uint64(7), uint16(53))
+	f.Add(uint64(0), uint64(1), uint16(1000))
+
+	f.Fuzz(func(t *testing.T, a, b uint64, prec uint16) {
+		if prec == 0 || uint(prec) > MaxPrec || b == 0 {
+			return
+		}
+		p := uint(prec)
+		arith := floatArith{prec: p}
+		x := new(big.Float).SetPrec(p).SetUint64(a)
+		y := new(big.Float).SetPrec(p).SetUint64(b)
+
+		c, _ := New(ModeFloat, p)
+		for expr, want := range map[string]*big.Float{
+			fmt.Sprintf("sqrt(%d)", a):    new(big.Float).SetPrec(p).Sqrt(x),
+			fmt.Sprintf("%d / %d", a, b):  new(big.Float).SetPrec(p).Quo(x, y),
+			fmt.Sprintf("-%d * %d", a, b): new(big.Float).SetPrec(p).Neg(new(big.Float).SetPrec(p).Mul(x, y)),
+		} {
+			got, err := c.Eval(expr)
+			if err != nil || got != arith.format(want) {
+				t.Fatalf("prec %d: %s = %s, %v; ожидалось %s", p, expr, got, err, arith.format(want))
+			}
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package calc
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// kind - вид лексемы
+type kind int
+
+const (
+	tokEOF    kind = iota
+	tokNumber      // 123, 1.5, 2e10
+	tokIdent       // имя переменной или функции
+	tokOp          // + - * / % ^
+	tokLParen
+	tokRParen
+	tokComma
+	tokAssign
+)
+
+// token - лексема и её позиция в строке (номер символа с 1)
+type token struct {
+	kind kind
+	text string
+	pos  int
+}
+
+// lex разбивает строку на лексемы; последняя - tokEOF с позицией за концом строки
+func lex(src string) ([]token, error) {
+
+	var tokens []token
+	col := 1 // номер символа (руны), а не байта: позиция ошибки должна совпадать с тем, что видит пользователь
+
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		start, startCol := i, col
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			col++
+			continue
+		case r >= '0' && r <= '9' || r == '.':
+			n, err := scanNumber(src[i:])
+			if err != nil {
+				return nil, &Error{Pos: startCol, Err: err}
+			}
+			tokens = append(tokens, token{tokNumber, src[i : i+n], startCol})
+			i += n
+			col += n // число состоит из ASCII
+			continue
+		case r == '_' || unicode.IsLetter(r):
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+				col++
+			}
+			tokens = append(tokens, token{tokIdent, src[start:i], startCol})
+			continue
+		}
+
+		var k kind
+		switch r {
+		case '+', '-', '*', '/', '%', '^':
+			k = tokOp
+		case '(':
+			k = tokLParen
+		case ')':
+			k = tokRParen
+		case ',':
+			k = tokComma
+		case '=':
+			k = tokAssign
+		default:
+			return nil, &Error{Pos: startCol, Err: fmt.Errorf("неожиданный символ %q", r)}
+		}
+		tokens = append(tokens, token{k, src[i : i+size], startCol})
+		i += size
+		col++
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: col}), nil
+}
+
+// scanNumber возвращает длину числа в начале s: цифры, необязательная дробная часть
+// и показатель степени (1.5e-3)
+func scanNumber(s string) (int, error) {
+
+	i := digits(s, 0)
+	intDigits := i
+	if i < len(s) && s[i] == '.' {
+		i = digits(s, i+1)
+		if intDigits == 0 && i == 1 {
+			return 0, fmt.Errorf("ожидалась цифра после точки")
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		end := digits(s, j)
+		if end == j {
+			return 0, fmt.Errorf("ожидались цифры показателя степени в %q", s[:j])
+		}
+		i = end
+	}
+
+	return i, nil
+}
+
+func digits(s string, i int) int {
+
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+
+	return i
+}
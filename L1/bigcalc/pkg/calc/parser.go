@@ -0,0 +1,225 @@
+package calc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// узлы дерева выражения; pos - позиция, на которую указывает ошибка вычисления узла
+type (
+	node interface{ position() int }
+
+	number struct {
+		pos  int
+		text string
+	}
+	variable struct {
+		pos  int
+		name string
+	}
+	unary struct {
+		pos int
+		op  string
+		x   node
+	}
+	binary struct {
+		pos  int // позиция оператора: "деление на ноль" указывает на "/"
+		op   string
+		x, y node
+	}
+	call struct {
+		pos  int
+		name string
+		args []node
+	}
+)
+
+func (n *number) position() int   { return n.pos }
+func (n *variable) position() int { return n.pos }
+func (n *unary) position() int    { return n.pos }
+func (n *binary) position() int   { return n.pos }
+func (n *call) position() int     { return n.pos }
+
+// start - позиция начала выражения: у бинарного узла это начало левого операнда
+func start(n node) int {
+
+	for {
+		b, ok := n.(*binary)
+		if !ok {
+			return n.position()
+		}
+		n = b.x
+	}
+}
+
+// сила связывания операторов: чем больше, тем сильнее оператор держит операнды
+const (
+	bpNone    = 0
+	bpSum     = 10 // + -
+	bpProduct = 20 // * / %
+	bpUnary   = 30 // унарный минус: -2^2 = -(2^2), но -2*3 = (-2)*3
+	bpPower   = 40 // ^, правоассоциативный: 2^3^2 = 2^(3^2)
+)
+
+var infix = map[string]int{
+	"+": bpSum, "-": bpSum,
+	"*": bpProduct, "/": bpProduct, "%": bpProduct,
+	"^": bpPower,
+}
+
+// statement - разобранная строка: выражение и имя переменной, если это присваивание
+type statement struct {
+	assign    string
+	assignPos int
+	expr      node
+}
+
+// parser - парсер Пратта: каждая лексема знает, как разобрать выражение, которое
+// с неё начинается (prefix), и как продолжить уже разобранное левое выражение (infix)
+type parser struct {
+	tokens []token
+	i      int
+	depth  int // вложенность expr: ограничивает рекурсию на строках вида "((((...)))"
+}
+
+// maxDepth - наибольшая вложенность скобок и унарных операторов
+const maxDepth = 1000
+
+// parse разбирает строку "выражение" или "имя = выражение"
+func parse(src string) (statement, error) {
+
+	tokens, err := lex(src)
+	if err != nil {
+		return statement{}, err
+	}
+	p := &parser{tokens: tokens}
+
+	var st statement
+	if len(tokens) > 2 && tokens[0].kind == tokIdent && tokens[1].kind == tokAssign {
+		st.assign, st.assignPos = tokens[0].text, tokens[0].pos
+		p.i = 2
+	}
+	if p.peek().kind == tokEOF {
+		return st, &Error{Pos: p.peek().pos, Err: errors.New("пустое выражение")}
+	}
+
+	if st.expr, err = p.expr(bpNone); err != nil {
+		return st, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return st, &Error{Pos: tok.pos, Err: fmt.Errorf("лишнее %q после выражения", tok.text)}
+	}
+
+	return st, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+
+	return tok
+}
+
+// expr разбирает выражение, пока следующий оператор связывает сильнее minBP
+func (p *parser) expr(minBP int) (node, error) {
+
+	if p.depth++; p.depth > maxDepth {
+		return nil, &Error{Pos: p.peek().pos, Err: fmt.Errorf("вложенность выражения больше %d", maxDepth)}
+	}
+	defer func() { p.depth-- }()
+
+	left, err := p.prefix()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		bp, ok := infix[tok.text]
+		if tok.kind != tokOp || !ok || bp <= minBP {
+			return left, nil
+		}
+		p.next()
+
+		rightBP := bp
+		if tok.text == "^" {
+			rightBP = bp - 1 // правая ассоциативность
+		}
+		right, err := p.expr(rightBP)
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{pos: tok.pos, op: tok.text, x: left, y: right}
+	}
+}
+
+// prefix разбирает то, с чего начинается выражение: число, переменную, вызов функции,
+// скобки или унарный оператор
+func (p *parser) prefix() (node, error) {
+
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		return &number{pos: tok.pos, text: tok.text}, nil
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.call(tok)
+		}
+		return &variable{pos: tok.pos, name: tok.text}, nil
+	case tokLParen:
+		x, err := p.expr(bpNone)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &Error{Pos: closing.pos, Err: fmt.Errorf("ожидалась ')' к '(' в позиции %d", tok.pos)}
+		}
+		return x, nil
+	case tokOp:
+		if tok.text == "-" || tok.text == "+" {
+			x, err := p.expr(bpUnary)
+			if err != nil {
+				return nil, err
+			}
+			return &unary{pos: tok.pos, op: tok.text, x: x}, nil
+		}
+	case tokEOF:
+		return nil, &Error{Pos: tok.pos, Err: errors.New("выражение оборвано: ожидалось число, переменная или '('")}
+	}
+
+	return nil, &Error{Pos: tok.pos, Err: fmt.Errorf("неожиданное %q: ожидалось число, переменная или '('", tok.text)}
+}
+
+// call разбирает аргументы функции name(арг, ...)
+func (p *parser) call(name token) (node, error) {
+
+	open := p.next()
+	c := &call{pos: name.pos, name: name.text}
+	if p.peek().kind == tokRParen {
+		p.next()
+		return c, nil
+	}
+
+	for {
+		arg, err := p.expr(bpNone)
+		if err != nil {
+			return nil, err
+		}
+		c.args = append(c.args, arg)
+
+		switch tok := p.next(); tok.kind {
+		case tokComma:
+		case tokRParen:
+			return c, nil
+		default:
+			return nil, &Error{Pos: tok.pos, Err: fmt.Errorf("ожидалась ',' или ')' к '(' в позиции %d", open.pos)}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package concurrency
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// work - небольшая вычислительная нагрузка на одно значение
+func work(_ context.Context, v int) (int, error) {
+
+	x := v
+	for range 200 {
+		x = x*1103515245 + 12345
+	}
+
+	return x, nil
+}
+
+// BenchmarkPool сравнивает пул с горутиной на каждое значение (как в l1.3)
+func BenchmarkPool(b *testing.B) {
+
+	inputs := make([]int, 10000)
+	for i := range inputs {
+		inputs[i] = i
+	}
+
+	b.Run("Pool", func(b *testing.B) {
+		pool := NewPool(0, work)
+		for b.Loop() {
+			if _, err := pool.Map(context.Background(), inputs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("горутина на значение", func(b *testing.B) {
+		for b.Loop() {
+			results := make([]int, len(inputs))
+			var wg sync.WaitGroup
+			for i, v := range inputs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					results[i], _ = work(context.Background(), v)
+				}()
+			}
+			wg.Wait()
+		}
+	})
+}
+
+// mutexMap - мапа под одним мьютексом (как в l1.7)
+type mutexMap struct {
+	mu sync.RWMutex
+	m  map[string]int
+}
+
+func (mm *mutexMap) load(key string) (int, bool) {
+
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	v, ok := mm.m[key]
+
+	return v, ok
+}
+
+func (mm *mutexMap) store(key string, value int) {
+
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.m[key] = value
+}
+
+// BenchmarkMap - 90% чтений и 10% записей из всех процессоров
+func BenchmarkMap(b *testing.B) {
+
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	b.Run("ConcurrentMap", func(b *testing.B) {
+		m := NewConcurrentMap[string, int](0)
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				key := keys[i%len(keys)]
+				if i%10 == 0 {
+					m.Store(key, i)
+				} else {
+					m.Load(key)
+				}
+			}
+		})
+	})
+
+	b.Run("map+RWMutex", func(b *testing.B) {
+		m := &mutexMap{m: make(map[string]int)}
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				key := keys[i%len(keys)]
+				if i%10 == 0 {
+					m.store(key, i)
+				} else {
+					m.load(key)
+				}
+			}
+		})
+	})
+
+	b.Run("sync.Map", func(b *testing.B) {
+		var m sync.Map
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				key := keys[i%len(keys)]
+				if i%10 == 0 {
+					m.Store(key, i)
+				} else {
+					m.Load(key)
+				}
+			}
+		})
+	})
+}
+
+// BenchmarkCounter сравнивает атомарный счётчик со счётчиком под мьютексом (как в l1.18)
+func BenchmarkCounter(b *testing.B) {
+
+	b.Run("AtomicCounter", func(b *testing.B) {
+		var c AtomicCounter
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				c.Inc()
+			}
+		})
+	})
+
+	b.Run("Mutex", func(b *testing.B) {
+		var mu sync.Mutex
+		var n int64
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				mu.Lock()
+				n++
+				mu.Unlock()
+			}
+		})
+	})
+}
+
+// BenchmarkFanIn - пропускная способность слияния четырёх каналов
+func BenchmarkFanIn(b *testing.B) {
+
+	for b.Loop() {
+		for range FanIn(context.Background(), produce(0, 1000), produce(0, 1000), produce(0, 1000), produce(0, 1000)) {
+		}
+	}
+}
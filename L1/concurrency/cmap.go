@@ -0,0 +1,170 @@
+package concurrency
+
+import (
+	"hash/maphash"
+	"iter"
+	"runtime"
+	"sync"
+)
+
+// ConcurrentMap - мапа, безопасная для одновременного использования: ключи распределены
+// по сегментам (шардам) со своими RWMutex, поэтому запись в разные сегменты не конкурирует
+type ConcurrentMap[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []shard[K, V]
+}
+
+type shard[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+	_  [32]byte // дополнение до 64 байт (строка кэша): соседние сегменты не делят её между ядрами
+}
+
+// NewConcurrentMap создаёт мапу из shards сегментов; 0 - по 4 сегмента на процессор
+func NewConcurrentMap[K comparable, V any](shards int) *ConcurrentMap[K, V] {
+
+	if shards <= 0 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+
+	cm := &ConcurrentMap[K, V]{seed: maphash.MakeSeed(), shards: make([]shard[K, V], shards)}
+	for i := range cm.shards {
+		cm.shards[i].m = make(map[K]V)
+	}
+
+	return cm
+}
+
+func (cm *ConcurrentMap[K, V]) shard(key K) *shard[K, V] {
+	return &cm.shards[maphash.Comparable(cm.seed, key)%uint64(len(cm.shards))]
+}
+
+// Load возвращает значение по ключу
+func (cm *ConcurrentMap[K, V]) Load(key K) (V, bool) {
+
+	s := cm.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.m[key]
+
+	return v, ok
+}
+
+// Store записывает значение
+func (cm *ConcurrentMap[K, V]) Store(key K, value V) {
+
+	s := cm.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.m[key] = value
+}
+
+// LoadOrStore возвращает имеющееся значение (loaded == true) или записывает value
+func (cm *ConcurrentMap[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+
+	s := cm.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.m[key]; ok {
+		return v, true
+	}
+	s.m[key] = value
+
+	return value, false
+}
+
+// LoadAndDelete удаляет ключ и возвращает его прежнее значение
+func (cm *ConcurrentMap[K, V]) LoadAndDelete(key K) (V, bool) {
+
+	s := cm.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.m[key]
+	delete(s.m, key)
+
+	return v, ok
+}
+
+// Delete удаляет ключ
+func (cm *ConcurrentMap[K, V]) Delete(key K) {
+	cm.LoadAndDelete(key)
+}
+
+// Compute атомарно изменяет значение ключа: fn получает текущее значение (loaded - есть ли оно)
+// и возвращает новое; keep == false удаляет ключ. Так считается, например, число повторов:
+//
+//	m.Compute(word, func(n int, _ bool) (int, bool) { return n + 1, true })
+//
+// fn выполняется под блокировкой сегмента и не должна обращаться к этой же мапе
+func (cm *ConcurrentMap[K, V]) Compute(key K, fn func(old V, loaded bool) (value V, keep bool)) (V, bool) {
+
+	s := cm.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, loaded := s.m[key]
+	value, keep := fn(old, loaded)
+	if !keep {
+		delete(s.m, key)
+		var zero V
+		return zero, false
+	}
+	s.m[key] = value
+
+	return value, true
+}
+
+// Len возвращает число ключей (сегменты считаются по очереди, при одновременной записи - приблизительно)
+func (cm *ConcurrentMap[K, V]) Len() int {
+
+	n := 0
+	for i := range cm.shards {
+		s := &cm.shards[i]
+		s.mu.RLock()
+		n += len(s.m)
+		s.mu.RUnlock()
+	}
+
+	return n
+}
+
+// Clear удаляет все ключи
+func (cm *ConcurrentMap[K, V]) Clear() {
+
+	for i := range cm.shards {
+		s := &cm.shards[i]
+		s.mu.Lock()
+		clear(s.m)
+		s.mu.Unlock()
+	}
+}
+
+// All перебирает пары ключ-значение. Каждый сегмент копируется под блокировкой, поэтому
+// тело цикла может менять мапу; изменения, сделанные во время перебора, могут быть не видны
+func (cm *ConcurrentMap[K, V]) All() iter.Seq2[K, V] {
+
+	return func(yield func(K, V) bool) {
+		for i := range cm.shards {
+			s := &cm.shards[i]
+
+			s.mu.RLock()
+			keys := make([]K, 0, len(s.m))
+			values := make([]V, 0, len(s.m))
+			for k, v := range s.m {
+				keys = append(keys, k)
+				values = append(values, v)
+			}
+			s.mu.RUnlock()
+
+			for j := range keys {
+				if !yield(keys[j], values[j]) {
+					return
+				}
+			}
+		}
+	}
+}
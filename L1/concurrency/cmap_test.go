@@ -0,0 +1,97 @@
+package concurrency
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrentMap(t *testing.T) {
+
+	m := NewConcurrentMap[string, int](0)
+
+	_, ok := m.Load("a")
+	assert.False(t, ok)
+
+	m.Store("a", 1)
+	v, ok := m.Load("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	actual, loaded := m.LoadOrStore("a", 2)
+	assert.True(t, loaded)
+	assert.Equal(t, 1, actual)
+	actual, loaded = m.LoadOrStore("b", 2)
+	assert.False(t, loaded)
+	assert.Equal(t, 2, actual)
+	assert.Equal(t, 2, m.Len())
+
+	v, ok = m.LoadAndDelete("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	_, ok = m.LoadAndDelete("a")
+	assert.False(t, ok)
+
+	v, ok = m.Compute("b", func(old int, loaded bool) (int, bool) { return old + 10, true })
+	assert.True(t, ok)
+	assert.Equal(t, 12, v)
+	_, ok = m.Compute("b", func(int, bool) (int, bool) { return 0, false })
+	assert.False(t, ok)
+	_, ok = m.Load("b")
+	assert.False(t, ok, "keep == false удаляет ключ")
+
+	for i := range 100 {
+		m.Store(fmt.Sprint(i), i)
+	}
+	m.Delete("0")
+	got := maps.Collect(m.All())
+	assert.Len(t, got, 99)
+	assert.Equal(t, 42, got["42"])
+
+	// тело перебора может менять мапу без взаимной блокировки
+	for k := range m.All() {
+		m.Delete(k)
+	}
+	assert.Equal(t, 0, m.Len())
+
+	m.Store("x", 1)
+	m.Clear()
+	assert.Equal(t, 0, m.Len())
+}
+
+// TestConcurrentMapRace - подсчёт букв одновременно из многих горутин (l1.7)
+func TestConcurrentMapRace(t *testing.T) {
+
+	m := NewConcurrentMap[rune, int](4)
+	const goroutines, perGoroutine = 16, 1000
+	letters := []rune("abcdefghij")
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perGoroutine {
+				letter := letters[(g+i)%len(letters)]
+				m.Compute(letter, func(n int, _ bool) (int, bool) { return n + 1, true })
+				m.Load(letter)
+				if i%100 == 0 {
+					m.Len()
+					for range m.All() {
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range m.All() {
+		total += n
+	}
+	assert.Equal(t, goroutines*perGoroutine, total)
+	assert.Equal(t, len(letters), m.Len())
+}
@@ -0,0 +1,39 @@
+package concurrency
+
+import "sync/atomic"
+
+// AtomicCounter - счётчик без блокировок (l1.18); нулевое значение готово к работе.
+// Копировать счётчик после начала использования нельзя (go vet это проверяет)
+type AtomicCounter struct {
+	n atomic.Int64
+}
+
+// Add прибавляет delta и возвращает новое значение
+func (c *AtomicCounter) Add(delta int64) int64 {
+	return c.n.Add(delta)
+}
+
+// Inc увеличивает счётчик на единицу
+func (c *AtomicCounter) Inc() int64 {
+	return c.n.Add(1)
+}
+
+// Dec уменьшает счётчик на единицу
+func (c *AtomicCounter) Dec() int64 {
+	return c.n.Add(-1)
+}
+
+// Load возвращает текущее значение
+func (c *AtomicCounter) Load() int64 {
+	return c.n.Load()
+}
+
+// Reset обнуляет счётчик и возвращает прежнее значение
+func (c *AtomicCounter) Reset() int64 {
+	return c.n.Swap(0)
+}
+
+// CompareAndSwap заменяет old на new, если счётчик равен old
+func (c *AtomicCounter) CompareAndSwap(old, new int64) bool {
+	return c.n.CompareAndSwap(old, new)
+}
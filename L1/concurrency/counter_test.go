@@ -0,0 +1,43 @@
+package concurrency
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicCounter(t *testing.T) {
+
+	var c AtomicCounter
+	assert.Equal(t, int64(0), c.Load())
+	assert.Equal(t, int64(1), c.Inc())
+	assert.Equal(t, int64(11), c.Add(10))
+	assert.Equal(t, int64(10), c.Dec())
+	assert.False(t, c.CompareAndSwap(1, 5))
+	assert.True(t, c.CompareAndSwap(10, 5))
+	assert.Equal(t, int64(5), c.Reset())
+	assert.Equal(t, int64(0), c.Load())
+}
+
+// TestAtomicCounterRace - конкурентный счётчик из l1.18 без мьютекса
+func TestAtomicCounterRace(t *testing.T) {
+
+	var c AtomicCounter
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				c.Inc()
+			}
+			for range 500 {
+				c.Dec()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(50*500), c.Load())
+}
@@ -0,0 +1,65 @@
+package concurrency_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/IPampurin/concurrency"
+)
+
+func ExamplePool_Map() {
+
+	pool := concurrency.NewPool(3, func(_ context.Context, word string) (int, error) {
+		return len(word), nil
+	})
+
+	lengths, err := pool.Map(context.Background(), []string{"один", "два", "три", "четыре"})
+	fmt.Println(lengths, err)
+	// Output: [8 6 6 12] <nil>
+}
+
+func ExampleStage() {
+
+	p := concurrency.NewPipeline(context.Background())
+	src := concurrency.Source(p, 0, slices.Values([]int{1, 2, 3, 4, 5}))
+	squares := concurrency.Stage(p, src, 1, 0, func(_ context.Context, x int) (int, bool, error) {
+		return x * x, x%2 == 1, nil // нечётные в квадрат, чётные отбрасываем
+	})
+
+	var sum int
+	concurrency.Sink(p, squares, 1, func(_ context.Context, x int) error {
+		sum += x
+		return nil
+	})
+	fmt.Println(sum, p.Wait())
+	// Output: 35 <nil>
+}
+
+func ExampleOr() {
+
+	sig := func(after time.Duration) <-chan struct{} {
+		ch := make(chan struct{})
+		time.AfterFunc(after, func() { close(ch) })
+		return ch
+	}
+
+	start := time.Now()
+	<-concurrency.Or(sig(time.Hour), sig(time.Minute), sig(10*time.Millisecond))
+	fmt.Println(time.Since(start) < time.Second)
+	// Output: true
+}
+
+func ExampleConcurrentMap_Compute() {
+
+	m := concurrency.NewConcurrentMap[rune, int](0)
+	for _, r := range strings.ToLower("Ааббв") {
+		m.Compute(r, func(n int, _ bool) (int, bool) { return n + 1, true })
+	}
+
+	n, _ := m.Load('б')
+	fmt.Println(m.Len(), n)
+	// Output: 3 2
+}
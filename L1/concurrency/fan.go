@@ -0,0 +1,88 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+)
+
+// FanIn сливает каналы в один; он закрывается, когда закрыты все входные каналы или отменён ctx
+func FanIn[T any](ctx context.Context, channels ...<-chan T) <-chan T {
+
+	out := make(chan T)
+
+	var wg sync.WaitGroup
+	for _, ch := range channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range OrDone(ctx, ch) {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// FanOut раздаёт значения in между n каналами: каждое значение получает один из них,
+// тот, чей читатель свободен (конкурирующие потребители). Каналы закрываются, когда
+// закрыт in или отменён ctx; читать нужно все каналы, иначе раздача встанет
+func FanOut[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+
+	outs := make([]<-chan T, max(n, 1))
+	for i := range outs {
+		out := make(chan T)
+		outs[i] = out
+		go func() {
+			defer close(out)
+			for v := range OrDone(ctx, in) {
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	return outs
+}
+
+// Broadcast отдаёт каждое значение in во все n каналов; следующее значение читается,
+// когда предыдущее получили все читатели
+func Broadcast[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+
+	chans := make([]chan T, max(n, 1))
+	outs := make([]<-chan T, len(chans))
+	for i := range chans {
+		chans[i] = make(chan T)
+		outs[i] = chans[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, ch := range chans {
+				close(ch)
+			}
+		}()
+		for v := range OrDone(ctx, in) {
+			for _, ch := range chans {
+				select {
+				case ch <- v:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return outs
+}
@@ -0,0 +1,107 @@
+package concurrency
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// produce отдаёт в канал значения from..to-1 и закрывает его
+func produce(from, to int) <-chan int {
+
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := from; i < to; i++ {
+			ch <- i
+		}
+	}()
+
+	return ch
+}
+
+func TestFanIn(t *testing.T) {
+
+	t.Run("все значения всех каналов", func(t *testing.T) {
+		noLeaks(t)
+		got := collect(FanIn(context.Background(), produce(0, 100), produce(100, 200), produce(200, 300)))
+		slices.Sort(got)
+		assert.Len(t, got, 300)
+		for i, v := range got {
+			assert.Equal(t, i, v)
+		}
+	})
+
+	t.Run("без каналов", func(t *testing.T) {
+		assert.Empty(t, collect(FanIn[int](context.Background())))
+	})
+
+	t.Run("отмена", func(t *testing.T) {
+		noLeaks(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		never := make(chan int)
+		out := FanIn(ctx, never, produce(0, 1))
+		assert.Equal(t, 0, <-out)
+		cancel()
+		collect(out) // закрывается, хотя never открыт
+	})
+}
+
+func TestFanOut(t *testing.T) {
+
+	noLeaks(t)
+
+	outs := FanOut(context.Background(), produce(0, 1000), 4)
+	assert.Len(t, outs, 4)
+
+	var mu sync.Mutex
+	var got []int
+	perWorker := make([]int, len(outs))
+	var wg sync.WaitGroup
+	for i, out := range outs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range out {
+				time.Sleep(10 * time.Microsecond)
+				mu.Lock()
+				got = append(got, v)
+				perWorker[i]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.Sort(got)
+	assert.Len(t, got, 1000, "каждое значение получено ровно один раз")
+	assert.Equal(t, slices.Compact(slices.Clone(got)), got)
+	for i, n := range perWorker {
+		assert.Positive(t, n, "читатель %d не получил значений", i)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+
+	noLeaks(t)
+
+	outs := Broadcast(context.Background(), produce(0, 50), 3)
+	results := make([][]int, len(outs))
+	var wg sync.WaitGroup
+	for i, out := range outs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = collect(out)
+		}()
+	}
+	wg.Wait()
+
+	for _, got := range results {
+		assert.Equal(t, collect(produce(0, 50)), got, "каждый читатель получает все значения по порядку")
+	}
+}
@@ -0,0 +1,48 @@
+package concurrency
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// noLeaks проверяет по завершении теста, что число горутин вернулось к исходному
+func noLeaks(t *testing.T) {
+
+	t.Helper()
+
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		// свой цикл вместо assert.Eventually: тот проверяет условие в отдельной горутине
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.LessOrEqual(t, runtime.NumGoroutine(), before, "горутины не завершились")
+	})
+}
+
+// collect читает канал до закрытия
+func collect[T any](ch <-chan T) []T {
+
+	var values []T
+	for v := range ch {
+		values = append(values, v)
+	}
+
+	return values
+}
+
+// closedAfter возвращает канал, который закроется через d
+func closedAfter(d time.Duration) <-chan struct{} {
+
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		time.Sleep(d)
+	}()
+
+	return ch
+}
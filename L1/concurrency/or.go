@@ -0,0 +1,99 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Or возвращает канал, который закрывается, как только любой из channels закрыт
+// или отдал значение. Без каналов возвращается закрытый канал.
+// Горутины наблюдения завершаются вместе с возвращённым каналом
+func Or[T any](channels ...<-chan T) <-chan struct{} {
+
+	out := make(chan struct{})
+	if len(channels) == 0 {
+		close(out)
+		return out
+	}
+
+	var once sync.Once
+	for _, ch := range channels {
+		go func() {
+			select {
+			case <-ch:
+				once.Do(func() { close(out) })
+			case <-out:
+			}
+		}()
+	}
+
+	return out
+}
+
+// And возвращает канал, который закрывается, когда каждый из channels закрыт
+// или отдал значение. Без каналов возвращается закрытый канал
+func And[T any](channels ...<-chan T) <-chan struct{} {
+
+	out := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for _, ch := range channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-ch
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// OrDone отдаёт значения in, пока он не закрыт и не отменён ctx: чтение
+// "for v := range OrDone(ctx, in)" не зависает на канале, в который больше не пишут
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// WithTimeout выполняет fn с ограничением времени: по истечении timeout контекст fn
+// отменяется, а WithTimeout сразу возвращает context.DeadlineExceeded, не дожидаясь fn
+func WithTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1) // буфер: fn, завершившаяся после таймаута, не зависнет
+	go func() { done <- fn(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,100 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOr(t *testing.T) {
+
+	t.Run("нет каналов", func(t *testing.T) {
+		_, ok := <-Or[struct{}]()
+		assert.False(t, ok)
+	})
+
+	t.Run("первый закрывшийся", func(t *testing.T) {
+		noLeaks(t)
+		start := time.Now()
+		<-Or(closedAfter(300*time.Millisecond), closedAfter(10*time.Millisecond), closedAfter(200*time.Millisecond))
+		elapsed := time.Since(start)
+		assert.GreaterOrEqual(t, elapsed, 10*time.Millisecond)
+		assert.Less(t, elapsed, 150*time.Millisecond)
+	})
+
+	t.Run("значение вместо закрытия", func(t *testing.T) {
+		ch := make(chan int, 1)
+		ch <- 1
+		<-Or(ch, make(chan int))
+	})
+}
+
+func TestAnd(t *testing.T) {
+
+	t.Run("нет каналов", func(t *testing.T) {
+		_, ok := <-And[struct{}]()
+		assert.False(t, ok)
+	})
+
+	t.Run("последний закрывшийся", func(t *testing.T) {
+		noLeaks(t)
+		start := time.Now()
+		<-And(closedAfter(30*time.Millisecond), closedAfter(time.Millisecond), closedAfter(10*time.Millisecond))
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("не закрывается раньше всех", func(t *testing.T) {
+		never := make(chan struct{})
+		done := And(closedAfter(time.Millisecond), never)
+		select {
+		case <-done:
+			t.Fatal("And закрылся, хотя не все каналы закрыты")
+		case <-time.After(20 * time.Millisecond):
+		}
+		close(never)
+		<-done
+	})
+}
+
+func TestOrDone(t *testing.T) {
+
+	noLeaks(t)
+
+	in := make(chan int) // в него больше никто не пишет и не закроет
+	ctx, cancel := context.WithCancel(context.Background())
+	out := OrDone(ctx, in)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	assert.Empty(t, collect(out), "чтение должно закончиться отменой, а не зависнуть")
+
+	in2 := make(chan int, 3)
+	in2 <- 1
+	in2 <- 2
+	in2 <- 3
+	close(in2)
+	assert.Equal(t, []int{1, 2, 3}, collect(OrDone(context.Background(), in2)))
+}
+
+func TestWithTimeout(t *testing.T) {
+
+	noLeaks(t)
+
+	err := WithTimeout(context.Background(), 10*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond) // не смотрит на ctx: WithTimeout не должен её ждать
+		return nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	boom := errors.New("boom")
+	err = WithTimeout(context.Background(), time.Second, func(ctx context.Context) error { return boom })
+	assert.ErrorIs(t, err, boom)
+
+	require.NoError(t, WithTimeout(context.Background(), time.Second, func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok, "у контекста функции есть срок")
+		return nil
+	}))
+}
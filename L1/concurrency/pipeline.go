@@ -0,0 +1,130 @@
+package concurrency
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// Pipeline - группа этапов конвейера с общим контекстом: первая ошибка любого этапа
+// отменяет все этапы, Wait возвращает её. Этапы соединяются каналами с ограниченным
+// буфером, поэтому медленный этап притормаживает предыдущие (backpressure), а не копит данные
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	fail   failure
+}
+
+// NewPipeline создаёт конвейер; его этапы останавливаются при отмене ctx
+func NewPipeline(ctx context.Context) *Pipeline {
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &Pipeline{ctx: ctx, cancel: cancel}
+}
+
+// Context возвращает контекст конвейера: отменяется при ошибке этапа или отмене родителя
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Wait ждёт завершения всех этапов и возвращает первую ошибку или ошибку контекста
+func (p *Pipeline) Wait() error {
+
+	p.wg.Wait()
+	err := p.ctx.Err()
+	p.cancel()
+
+	if ferr := p.fail.get(); ferr != nil {
+		return ferr
+	}
+
+	return err
+}
+
+// Go запускает горутину в группе конвейера; ошибка fn останавливает конвейер
+func (p *Pipeline) Go(fn func(ctx context.Context) error) {
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := fn(p.ctx); err != nil {
+			p.fail.set(err, p.cancel)
+		}
+	}()
+}
+
+// Source - первый этап: отдаёт значения seq в канал с буфером buffer
+func Source[T any](p *Pipeline, buffer int, seq iter.Seq[T]) <-chan T {
+
+	out := make(chan T, max(buffer, 0))
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		for v := range seq {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+
+	return out
+}
+
+// Stage - промежуточный этап: workers горутин применяют fn к значениям in и пишут
+// результаты в канал с буфером buffer. При workers > 1 порядок значений не сохраняется.
+// fn может отбросить значение, вернув keep == false
+func Stage[In, Out any](p *Pipeline, in <-chan In, workers, buffer int,
+	fn func(ctx context.Context, v In) (out Out, keep bool, err error)) <-chan Out {
+
+	out := make(chan Out, max(buffer, 0))
+
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		p.Go(func(ctx context.Context) error {
+			defer wg.Done()
+			for v := range OrDone(ctx, in) {
+				r, keep, err := fn(ctx, v)
+				if err != nil {
+					return err
+				}
+				if !keep {
+					continue
+				}
+				select {
+				case out <- r:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+			return nil
+		})
+	}
+	// канал закрывает последний завершившийся воркер этапа
+	p.Go(func(context.Context) error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+
+	return out
+}
+
+// Sink - последний этап: workers горутин передают значения in в fn
+func Sink[T any](p *Pipeline, in <-chan T, workers int, fn func(ctx context.Context, v T) error) {
+
+	for range max(workers, 1) {
+		p.Go(func(ctx context.Context) error {
+			for v := range OrDone(ctx, in) {
+				if err := fn(ctx, v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
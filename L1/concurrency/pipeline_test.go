@@ -0,0 +1,149 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// numbers - последовательность 1..n
+func numbers(n int) func(func(int) bool) {
+
+	return func(yield func(int) bool) {
+		for i := 1; i <= n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+
+	t.Run("конвейер чисел (l1.9)", func(t *testing.T) {
+		noLeaks(t)
+		p := NewPipeline(context.Background())
+		src := Source(p, 4, numbers(1000))
+		doubled := Stage(p, src, 4, 4, func(_ context.Context, v int) (int, bool, error) {
+			return v * 2, true, nil
+		})
+		even := Stage(p, doubled, 1, 0, func(_ context.Context, v int) (int, bool, error) {
+			return v, v%4 == 0, nil // отбрасываем часть значений
+		})
+
+		var mu sync.Mutex
+		var got []int
+		Sink(p, even, 2, func(_ context.Context, v int) error {
+			mu.Lock()
+			got = append(got, v)
+			mu.Unlock()
+			return nil
+		})
+		require.NoError(t, p.Wait())
+
+		slices.Sort(got)
+		require.Len(t, got, 500)
+		for i, v := range got {
+			assert.Equal(t, (i+1)*4, v)
+		}
+	})
+
+	t.Run("порядок при одном воркере", func(t *testing.T) {
+		noLeaks(t)
+		p := NewPipeline(context.Background())
+		out := Stage(p, Source(p, 0, numbers(100)), 1, 0, func(_ context.Context, v int) (int, bool, error) {
+			return v, true, nil
+		})
+		got := collect(out)
+		require.NoError(t, p.Wait())
+		assert.True(t, slices.IsSorted(got))
+		assert.Len(t, got, 100)
+	})
+
+	t.Run("ошибка этапа останавливает все этапы", func(t *testing.T) {
+		noLeaks(t)
+		boom := errors.New("boom")
+		var produced atomic.Int32
+		p := NewPipeline(context.Background())
+		src := Source(p, 0, func(yield func(int) bool) {
+			for i := 0; ; i++ { // бесконечный источник
+				produced.Add(1)
+				if !yield(i) {
+					return
+				}
+			}
+		})
+		out := Stage(p, src, 2, 0, func(_ context.Context, v int) (int, bool, error) {
+			if v == 100 {
+				return 0, false, boom
+			}
+			return v, true, nil
+		})
+		Sink(p, out, 1, func(context.Context, int) error { return nil })
+
+		assert.ErrorIs(t, p.Wait(), boom)
+		assert.Less(t, produced.Load(), int32(1000))
+		assert.Error(t, p.Context().Err(), "контекст конвейера отменён")
+	})
+
+	t.Run("ошибка приёмника", func(t *testing.T) {
+		noLeaks(t)
+		boom := errors.New("sink")
+		p := NewPipeline(context.Background())
+		Sink(p, Source(p, 0, numbers(1000)), 3, func(_ context.Context, v int) error {
+			if v == 7 {
+				return boom
+			}
+			return nil
+		})
+		assert.ErrorIs(t, p.Wait(), boom)
+	})
+
+	t.Run("backpressure", func(t *testing.T) {
+		noLeaks(t)
+		// медленный приёмник: источник не должен уходить вперёд больше, чем позволяют буферы
+		var produced, consumed atomic.Int32
+		var maxLead atomic.Int32
+		p := NewPipeline(context.Background())
+		src := Source(p, 2, func(yield func(int) bool) {
+			for i := range 50 {
+				produced.Add(1)
+				if lead := produced.Load() - consumed.Load(); lead > maxLead.Load() {
+					maxLead.Store(lead)
+				}
+				if !yield(i) {
+					return
+				}
+			}
+		})
+		mid := Stage(p, src, 1, 2, func(_ context.Context, v int) (int, bool, error) { return v, true, nil })
+		Sink(p, mid, 1, func(context.Context, int) error {
+			time.Sleep(time.Millisecond)
+			consumed.Add(1)
+			return nil
+		})
+		require.NoError(t, p.Wait())
+		assert.Equal(t, int32(50), consumed.Load())
+		// источник опережает приёмник не больше чем на буферы 2 + 2 и значения в руках горутин
+		assert.LessOrEqual(t, maxLead.Load(), int32(10))
+	})
+
+	t.Run("отмена родительского контекста", func(t *testing.T) {
+		noLeaks(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		p := NewPipeline(ctx)
+		Sink(p, Source(p, 0, numbers(1<<30)), 1, func(context.Context, int) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+		time.AfterFunc(10*time.Millisecond, cancel)
+		assert.ErrorIs(t, p.Wait(), context.Canceled)
+	})
+}
@@ -0,0 +1,139 @@
+// Package concurrency - обобщённые конкурентные примитивы, собранные из решений L1:
+// пул воркеров (l1.3), конвейер (l1.9), fan-in/fan-out и or-каналы (l2.14, l4.1),
+// счётчик (l1.18) и конкурентная мапа (l1.7). Всё, что запускает горутины, останавливается
+// отменой контекста (l1.5, l1.6) и не оставляет горутин после завершения.
+package concurrency
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool обрабатывает значения типа T функцией fn не более чем в workers горутинах
+type Pool[T, R any] struct {
+	workers int
+	fn      func(context.Context, T) (R, error)
+}
+
+// NewPool создаёт пул; workers меньше 1 считается равным 1
+func NewPool[T, R any](workers int, fn func(context.Context, T) (R, error)) *Pool[T, R] {
+	return &Pool[T, R]{workers: max(workers, 1), fn: fn}
+}
+
+// Map обрабатывает inputs и возвращает результаты в том же порядке.
+// Первая ошибка отменяет контекст остальных вызовов fn и возвращается; новые значения
+// после неё в работу не берутся. При отмене ctx возвращается ctx.Err()
+func (p *Pool[T, R]) Map(ctx context.Context, inputs []T) ([]R, error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]R, len(inputs))
+	var fail failure
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(p.workers, len(inputs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r, err := p.fn(ctx, inputs[i])
+				if err != nil {
+					fail.set(err, cancel)
+					continue
+				}
+				results[i] = r
+			}
+		}()
+	}
+
+	// раздаём индексы, пока никто не ошибся и контекст жив
+feed:
+	for i := range inputs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := fail.get(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Stream обрабатывает значения из in по мере поступления; результаты приходят в out
+// в порядке готовности. out закрывается, когда in закрыт и обработка закончена, после
+// первой ошибки или отмены ctx. wait ждёт остановки воркеров и возвращает первую ошибку
+// (или ctx.Err()); out нужно дочитать либо отменить ctx, иначе воркеры будут ждать читателя
+func (p *Pool[T, R]) Stream(ctx context.Context, in <-chan T) (out <-chan R, wait func() error) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	results := make(chan R, p.workers)
+	var fail failure
+
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range OrDone(ctx, in) {
+				r, err := p.fn(ctx, v)
+				if err != nil {
+					fail.set(err, cancel)
+					return
+				}
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	var ctxErr error
+	go func() {
+		wg.Wait()
+		ctxErr = ctx.Err()
+		cancel()
+		close(results)
+		close(done)
+	}()
+
+	return results, func() error {
+		<-done
+		if err := fail.get(); err != nil {
+			return err
+		}
+		return ctxErr
+	}
+}
+
+// failure хранит первую ошибку группы горутин
+type failure struct {
+	once sync.Once
+	err  error
+}
+
+// set запоминает err, если ошибки ещё не было, и отменяет работу остальных
+func (f *failure) set(err error, cancel context.CancelFunc) {
+	f.once.Do(func() {
+		f.err = err
+		cancel()
+	})
+}
+
+// get возвращает ошибку; вызывается после остановки горутин
+func (f *failure) get() error {
+	return f.err
+}
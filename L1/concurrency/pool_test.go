@@ -0,0 +1,151 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func square(_ context.Context, v int) (int, error) { return v * v, nil }
+
+func TestPoolMap(t *testing.T) {
+
+	t.Run("порядок результатов", func(t *testing.T) {
+		noLeaks(t)
+		inputs := make([]int, 1000)
+		for i := range inputs {
+			inputs[i] = i
+		}
+		got, err := NewPool(8, square).Map(context.Background(), inputs)
+		require.NoError(t, err)
+		for i, v := range got {
+			assert.Equal(t, i*i, v)
+		}
+	})
+
+	t.Run("пустой вход", func(t *testing.T) {
+		got, err := NewPool(4, square).Map(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("ограничение числа воркеров", func(t *testing.T) {
+		noLeaks(t)
+		var running, peak atomic.Int32
+		pool := NewPool(3, func(_ context.Context, v int) (int, error) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			return v, nil
+		})
+		_, err := pool.Map(context.Background(), make([]int, 50))
+		require.NoError(t, err)
+		assert.LessOrEqual(t, peak.Load(), int32(3))
+		assert.Equal(t, int32(3), peak.Load(), "воркеры должны работать одновременно")
+	})
+
+	t.Run("первая ошибка останавливает пул", func(t *testing.T) {
+		noLeaks(t)
+		boom := errors.New("boom")
+		var calls atomic.Int32
+		pool := NewPool(2, func(ctx context.Context, v int) (int, error) {
+			calls.Add(1)
+			if v == 5 {
+				return 0, boom
+			}
+			select {
+			case <-time.After(time.Millisecond):
+			case <-ctx.Done():
+			}
+			return v, nil
+		})
+		inputs := make([]int, 1000)
+		inputs[10] = 5
+		got, err := pool.Map(context.Background(), inputs)
+		assert.ErrorIs(t, err, boom)
+		assert.Nil(t, got)
+		assert.Less(t, calls.Load(), int32(100), "после ошибки новые значения не берутся")
+	})
+
+	t.Run("отмена контекста", func(t *testing.T) {
+		noLeaks(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		pool := NewPool(4, func(ctx context.Context, v int) (int, error) {
+			time.Sleep(time.Millisecond)
+			return v, nil
+		})
+		_, err := pool.Map(ctx, make([]int, 100000))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestPoolStream(t *testing.T) {
+
+	t.Run("все значения", func(t *testing.T) {
+		noLeaks(t)
+		in := make(chan int)
+		go func() {
+			defer close(in)
+			for i := range 100 {
+				in <- i
+			}
+		}()
+		out, wait := NewPool(4, square).Stream(context.Background(), in)
+		got := collect(out)
+		require.NoError(t, wait())
+		slices.Sort(got)
+		for i, v := range got {
+			assert.Equal(t, i*i, v)
+		}
+		assert.Len(t, got, 100)
+	})
+
+	t.Run("ошибка закрывает выход", func(t *testing.T) {
+		noLeaks(t)
+		boom := errors.New("boom")
+		in := make(chan int) // никогда не закрывается: остановить должна ошибка
+		go func() {
+			for i := 0; ; i++ {
+				select {
+				case in <- i:
+				case <-time.After(time.Second):
+					return
+				}
+			}
+		}()
+		out, wait := NewPool(2, func(_ context.Context, v int) (int, error) {
+			if v == 10 {
+				return 0, boom
+			}
+			return v, nil
+		}).Stream(context.Background(), in)
+		collect(out)
+		assert.ErrorIs(t, wait(), boom)
+	})
+
+	t.Run("отмена без читателя", func(t *testing.T) {
+		noLeaks(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		in := make(chan int, 10)
+		for i := range 10 {
+			in <- i
+		}
+		_, wait := NewPool(2, square).Stream(ctx, in)
+		time.Sleep(10 * time.Millisecond) // воркеры ждут читателя
+		cancel()
+		assert.ErrorIs(t, wait(), context.Canceled)
+	})
+}
@@ -0,0 +1,163 @@
+package set
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// sizes - размеры множеств для бенчмарков (как в l1.11/3main_benchtest.go)
+var sizes = []int{1000, 10000, 100000}
+
+// testData - два массива случайных чисел размера size (generateTestData из l1.11)
+func testData(size int) ([]int, []int) {
+
+	rng := rand.New(rand.NewPCG(uint64(size), 1))
+	a := make([]int, size)
+	b := make([]int, size)
+	for i := range size {
+		a[i] = rng.IntN(size)
+		b[i] = rng.IntN(size)
+	}
+
+	return a, b
+}
+
+// intersectionLine - линейное пересечение слайсов на мапе из l1.11/1main.go
+func intersectionLine(arrA, arrB []int) []int {
+
+	numsMap := make(map[int]struct{})
+	intersection := make([]int, 0)
+
+	for _, a := range arrA {
+		numsMap[a] = struct{}{}
+	}
+	for _, b := range arrB {
+		if _, ok := numsMap[b]; ok {
+			intersection = append(intersection, b)
+			delete(numsMap, b)
+		}
+	}
+
+	return intersection
+}
+
+func BenchmarkAdd(b *testing.B) {
+
+	for _, size := range sizes {
+		data, _ := testData(size)
+		b.Run(fmt.Sprintf("Set/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				New(data...)
+			}
+		})
+		b.Run(fmt.Sprintf("Sorted/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				NewSorted(data...)
+			}
+		})
+		b.Run(fmt.Sprintf("Sync/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				s := NewSync[int]()
+				for _, v := range data {
+					s.Add(v)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkContains(b *testing.B) {
+
+	for _, size := range sizes {
+		data, probes := testData(size)
+		set, srt := New(data...), NewSorted(data...)
+		b.Run(fmt.Sprintf("Set/%d", size), func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				set.Contains(probes[i%size])
+			}
+		})
+		b.Run(fmt.Sprintf("Sorted/%d", size), func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				srt.Contains(probes[i%size])
+			}
+		})
+	}
+}
+
+func BenchmarkIntersection(b *testing.B) {
+
+	for _, size := range sizes {
+		x, y := testData(size)
+		b.Run(fmt.Sprintf("l1.11/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				intersectionLine(x, y)
+			}
+		})
+		sx, sy := New(x...), New(y...)
+		b.Run(fmt.Sprintf("Set/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				sx.Intersection(sy)
+			}
+		})
+		ox, oy := NewSorted(x...), NewSorted(y...)
+		b.Run(fmt.Sprintf("Sorted/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				ox.Intersection(oy)
+			}
+		})
+	}
+}
+
+func BenchmarkUnion(b *testing.B) {
+
+	for _, size := range sizes {
+		x, y := testData(size)
+		sx, sy := New(x...), New(y...)
+		b.Run(fmt.Sprintf("Set/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				sx.Union(sy)
+			}
+		})
+		ox, oy := NewSorted(x...), NewSorted(y...)
+		b.Run(fmt.Sprintf("Sorted/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				ox.Union(oy)
+			}
+		})
+	}
+}
+
+// BenchmarkAllSorted - получение элементов по возрастанию: Set требует сортировки, Sorted - нет
+func BenchmarkAllSorted(b *testing.B) {
+
+	for _, size := range sizes {
+		data, _ := testData(size)
+		set, srt := New(data...), NewSorted(data...)
+		b.Run(fmt.Sprintf("Set/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				_ = slices.Sorted(set.All())
+			}
+		})
+		b.Run(fmt.Sprintf("Sorted/%d", size), func(b *testing.B) {
+			for b.Loop() {
+				srt.items()
+			}
+		})
+	}
+}
+
+func BenchmarkSyncParallel(b *testing.B) {
+
+	s := NewSync[int]()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if i%10 == 0 {
+				s.Add(i % 1024)
+			} else {
+				s.Contains(i % 1024)
+			}
+		}
+	})
+}
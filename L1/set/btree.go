@@ -0,0 +1,226 @@
+package set
+
+import (
+	"cmp"
+	"slices"
+)
+
+// degree - минимальная степень B-дерева: в узле от degree-1 до 2*degree-1 ключей
+// (кроме корня). 32 ключа на узел удерживают дерево неглубоким и дружественным к кэшу
+const degree = 32
+
+const maxItems = 2*degree - 1
+
+// node - узел B-дерева; у листа children пуст, у внутреннего узла len(children) == len(items)+1
+type node[T cmp.Ordered] struct {
+	items    []T
+	children []*node[T]
+}
+
+func (n *node[T]) leaf() bool {
+	return len(n.children) == 0
+}
+
+// find ищет ключ в узле: индекс ключа или потомка, в котором его искать
+func (n *node[T]) find(v T) (int, bool) {
+	return slices.BinarySearch(n.items, v)
+}
+
+// splitChild делит переполненного потомка i пополам, средний ключ поднимается в n
+func (n *node[T]) splitChild(i int) {
+
+	child := n.children[i]
+	mid := child.items[degree-1]
+
+	right := &node[T]{items: slices.Clone(child.items[degree:])}
+	if !child.leaf() {
+		right.children = slices.Clone(child.children[degree:])
+		clear(child.children[degree:])
+		child.children = child.children[:degree]
+	}
+	clear(child.items[degree-1:])
+	child.items = child.items[:degree-1]
+
+	n.items = slices.Insert(n.items, i, mid)
+	n.children = slices.Insert(n.children, i+1, right)
+}
+
+// insert добавляет ключ в поддерево с неполным корнем n (вставка за один проход вниз)
+func (n *node[T]) insert(v T) bool {
+
+	for {
+		i, found := n.find(v)
+		if found {
+			return false
+		}
+		if n.leaf() {
+			n.items = slices.Insert(n.items, i, v)
+			return true
+		}
+		if len(n.children[i].items) == maxItems {
+			n.splitChild(i)
+			switch c := cmp.Compare(v, n.items[i]); {
+			case c == 0:
+				return false
+			case c > 0:
+				i++
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// remove удаляет ключ из поддерева n; у каждого узла на пути вниз не меньше degree ключей
+// (кроме корня), поэтому удаление из листа не нарушает свойств дерева
+func (n *node[T]) remove(v T) bool {
+
+	for {
+		i, found := n.find(v)
+		if n.leaf() {
+			if !found {
+				return false
+			}
+			n.items = slices.Delete(n.items, i, i+1)
+			return true
+		}
+
+		if found {
+			switch {
+			case len(n.children[i].items) >= degree:
+				// заменяем ключ предшественником и удаляем предшественника из левого поддерева
+				pred := n.children[i].max()
+				n.items[i] = pred
+				n, v = n.children[i], pred
+			case len(n.children[i+1].items) >= degree:
+				succ := n.children[i+1].min()
+				n.items[i] = succ
+				n, v = n.children[i+1], succ
+			default:
+				// оба соседа минимальны: сливаем их вместе с ключом и удаляем из слитого узла
+				n.merge(i)
+				if len(n.items) == 0 {
+					*n = *n.children[0] // корень опустел - дерево стало ниже
+					continue
+				}
+				n = n.children[i]
+			}
+			continue
+		}
+
+		// ключа в узле нет: перед спуском дополняем потомка до degree ключей
+		if len(n.children[i].items) < degree {
+			i = n.fill(i)
+			if len(n.items) == 0 {
+				*n = *n.children[0]
+				continue
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// fill дополняет потомка i до degree ключей за счёт соседа или слиянием;
+// возвращает индекс потомка, в который теперь нужно спускаться
+func (n *node[T]) fill(i int) int {
+
+	child := n.children[i]
+	switch {
+	case i > 0 && len(n.children[i-1].items) >= degree:
+		// берём ключ через родителя у левого соседа
+		left := n.children[i-1]
+		child.items = slices.Insert(child.items, 0, n.items[i-1])
+		n.items[i-1] = left.items[len(left.items)-1]
+		left.items = left.items[:len(left.items)-1]
+		if !left.leaf() {
+			child.children = slices.Insert(child.children, 0, left.children[len(left.children)-1])
+			left.children = left.children[:len(left.children)-1]
+		}
+		return i
+	case i < len(n.children)-1 && len(n.children[i+1].items) >= degree:
+		right := n.children[i+1]
+		child.items = append(child.items, n.items[i])
+		n.items[i] = right.items[0]
+		right.items = slices.Delete(right.items, 0, 1)
+		if !right.leaf() {
+			child.children = append(child.children, right.children[0])
+			right.children = slices.Delete(right.children, 0, 1)
+		}
+		return i
+	case i < len(n.children)-1:
+		n.merge(i)
+		return i
+	default:
+		n.merge(i - 1)
+		return i - 1
+	}
+}
+
+// merge сливает потомков i и i+1 и разделяющий их ключ в потомка i
+func (n *node[T]) merge(i int) {
+
+	left, right := n.children[i], n.children[i+1]
+	left.items = append(append(left.items, n.items[i]), right.items...)
+	left.children = append(left.children, right.children...)
+
+	n.items = slices.Delete(n.items, i, i+1)
+	n.children = slices.Delete(n.children, i+1, i+2)
+}
+
+func (n *node[T]) min() T {
+
+	for !n.leaf() {
+		n = n.children[0]
+	}
+
+	return n.items[0]
+}
+
+func (n *node[T]) max() T {
+
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+
+	return n.items[len(n.items)-1]
+}
+
+// ascend перебирает ключи от from (включительно) по возрастанию; false - перебор прерван
+func (n *node[T]) ascend(from T, bounded bool, yield func(T) bool) bool {
+
+	i := 0
+	if bounded {
+		i, _ = n.find(from)
+	}
+	for ; i < len(n.items); i++ {
+		if !n.leaf() && !n.children[i].ascend(from, bounded, yield) {
+			return false
+		}
+		if !yield(n.items[i]) {
+			return false
+		}
+		bounded = false // правее from все ключи подходят
+	}
+	if !n.leaf() {
+		return n.children[i].ascend(from, bounded, yield)
+	}
+
+	return true
+}
+
+// descend перебирает ключи по убыванию
+func (n *node[T]) descend(yield func(T) bool) bool {
+
+	for i := len(n.items) - 1; i >= 0; i-- {
+		if !n.leaf() && !n.children[i+1].descend(yield) {
+			return false
+		}
+		if !yield(n.items[i]) {
+			return false
+		}
+	}
+	if !n.leaf() {
+		return n.children[0].descend(yield)
+	}
+
+	return true
+}
@@ -0,0 +1,65 @@
+/* Сравнительное измерение времени операций Set и Sorted (продолжение l1.11/3main_benchtest.go) */
+
+// Примечание: точные цифры дают бенчмарки (go test -bench .), здесь - наглядная таблица
+// по размерам множеств, как в 3main_benchtest.go
+
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/IPampurin/set"
+)
+
+// generateTestData - генератор тестовых массивов
+func generateTestData(size int) ([]int, []int) {
+
+	arrA := make([]int, size)
+	arrB := make([]int, size)
+
+	for i := 0; i < size; i++ {
+		arrA[i] = rand.IntN(size)
+		arrB[i] = rand.IntN(size)
+	}
+
+	return arrA, arrB
+}
+
+// measure возвращает время выполнения fn в миллисекундах
+func measure(fn func()) float64 {
+
+	start := time.Now()
+	fn()
+
+	return time.Since(start).Seconds() * 1000
+}
+
+func main() {
+
+	sizes := []int{1000, 10000, 50000, 100000, 500000, 1000000} // размеры тестовых массивов
+
+	fmt.Println("\nСравнение времени выполнения (мс), Set - мапа, Sorted - B-дерево:")
+	fmt.Printf("\n%15s %16s %16s %16s %16s %16s %16s\n", "Размер массивов",
+		"Set: созд.", "Sorted: созд.", "Set: пересеч.", "Sorted: пересеч.", "Set: объедин.", "Sorted: объедин.")
+
+	// производим замер для тестируемых размеров по очереди
+	for _, size := range sizes {
+
+		arrA, arrB := generateTestData(size) // генерируем массивы
+
+		var setA, setB *set.Set[int]
+		var sortedA, sortedB *set.Sorted[int]
+
+		setBuild := measure(func() { setA, setB = set.New(arrA...), set.New(arrB...) })
+		sortedBuild := measure(func() { sortedA, sortedB = set.NewSorted(arrA...), set.NewSorted(arrB...) })
+		setInter := measure(func() { setA.Intersection(setB) })
+		sortedInter := measure(func() { sortedA.Intersection(sortedB) })
+		setUnion := measure(func() { setA.Union(setB) })
+		sortedUnion := measure(func() { sortedA.Union(sortedB) })
+
+		fmt.Printf("%15d %16.4f %16.4f %16.4f %16.4f %16.4f %16.4f\n", size,
+			setBuild, sortedBuild, setInter, sortedInter, setUnion, sortedUnion)
+	}
+}
@@ -0,0 +1,48 @@
+package set_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/IPampurin/set"
+)
+
+func ExampleSet_Intersection() {
+
+	// задача l1.11
+	a := set.New(1, 2, 3, 1, 2, 3, 6)
+	b := set.New(6, 6, 6, 2, 3, 4, 15, 15, 25)
+
+	fmt.Println(slices.Sorted(a.Intersection(b).All()))
+	// Output: [2 3 6]
+}
+
+func ExampleNew() {
+
+	// задача l1.12
+	words := set.New("cat", "cat", "dog", "cat", "tree")
+
+	fmt.Println(words.Len(), slices.Sorted(words.All()))
+	// Output: 3 [cat dog tree]
+}
+
+func ExampleSorted_Range() {
+
+	s := set.NewSorted(15, 3, 8, 42, 23, 4, 16)
+
+	fmt.Println(slices.Collect(s.Range(4, 20)))
+	first, _ := s.Min()
+	last, _ := s.Max()
+	fmt.Println(first, last)
+	// Output:
+	// [4 8 15 16]
+	// 3 42
+}
+
+func ExampleSorted_MarshalJSON() {
+
+	data, _ := json.Marshal(set.NewSorted("tree", "cat", "dog", "cat"))
+	fmt.Println(string(data))
+	// Output: ["cat","dog","tree"]
+}
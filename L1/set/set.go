@@ -0,0 +1,231 @@
+// Package set - обобщённые множества, выросшие из решений l1.11 (пересечение) и l1.12
+// (собственное множество строк):
+//   - Set[T] - множество на мапе, операции за O(1) в среднем, порядок перебора не определён;
+//   - Sorted[T] - упорядоченное множество на B-дереве: перебор по возрастанию, Min/Max,
+//     диапазоны и операции над множествами слиянием за O(n + m);
+//   - Sync[T] - Set[T] под RWMutex для одновременного использования из горутин.
+package set
+
+import (
+	"encoding/json"
+	"iter"
+	"maps"
+)
+
+// Set - множество значений типа T на мапе; нулевое значение - пустое множество, готовое к работе
+type Set[T comparable] struct {
+	m map[T]struct{}
+}
+
+// New создаёт множество из значений (повторы отбрасываются)
+func New[T comparable](items ...T) *Set[T] {
+
+	s := &Set[T]{m: make(map[T]struct{}, len(items))}
+	for _, v := range items {
+		s.m[v] = struct{}{}
+	}
+
+	return s
+}
+
+// Collect создаёт множество из последовательности
+func Collect[T comparable](seq iter.Seq[T]) *Set[T] {
+
+	s := New[T]()
+	for v := range seq {
+		s.m[v] = struct{}{}
+	}
+
+	return s
+}
+
+// Add добавляет значения и возвращает число действительно добавленных
+func (s *Set[T]) Add(items ...T) int {
+
+	if s.m == nil {
+		s.m = make(map[T]struct{}, len(items))
+	}
+
+	added := 0
+	for _, v := range items {
+		if _, ok := s.m[v]; !ok {
+			s.m[v] = struct{}{}
+			added++
+		}
+	}
+
+	return added
+}
+
+// Remove удаляет значения и возвращает число действительно удалённых
+func (s *Set[T]) Remove(items ...T) int {
+
+	removed := 0
+	for _, v := range items {
+		if _, ok := s.m[v]; ok {
+			delete(s.m, v)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// Contains сообщает, есть ли значение в множестве
+func (s *Set[T]) Contains(v T) bool {
+
+	_, ok := s.m[v]
+
+	return ok
+}
+
+// Len возвращает число элементов
+func (s *Set[T]) Len() int {
+	return len(s.m)
+}
+
+// Clear удаляет все элементы
+func (s *Set[T]) Clear() {
+	clear(s.m)
+}
+
+// Clone возвращает независимую копию
+func (s *Set[T]) Clone() *Set[T] {
+
+	c := &Set[T]{m: maps.Clone(s.m)}
+	if c.m == nil {
+		c.m = make(map[T]struct{})
+	}
+
+	return c
+}
+
+// All перебирает элементы в неопределённом порядке; тело цикла может менять множество
+// по правилам range по мапе
+func (s *Set[T]) All() iter.Seq[T] {
+	return maps.Keys(s.m)
+}
+
+// Equal сообщает, совпадают ли множества
+func (s *Set[T]) Equal(other *Set[T]) bool {
+	return s.Len() == other.Len() && s.IsSubset(other)
+}
+
+// Union возвращает объединение: элементы, которые есть хотя бы в одном множестве
+func (s *Set[T]) Union(other *Set[T]) *Set[T] {
+
+	res := s.Clone()
+	for v := range other.m {
+		res.m[v] = struct{}{}
+	}
+
+	return res
+}
+
+// Intersection возвращает пересечение: элементы, которые есть в обоих множествах (задача l1.11)
+func (s *Set[T]) Intersection(other *Set[T]) *Set[T] {
+
+	// перебираем меньшее множество, проверяем по большему
+	small, large := s, other
+	if small.Len() > large.Len() {
+		small, large = large, small
+	}
+
+	res := New[T]()
+	for v := range small.m {
+		if large.Contains(v) {
+			res.m[v] = struct{}{}
+		}
+	}
+
+	return res
+}
+
+// Difference возвращает разность: элементы s, которых нет в other
+func (s *Set[T]) Difference(other *Set[T]) *Set[T] {
+
+	res := New[T]()
+	for v := range s.m {
+		if !other.Contains(v) {
+			res.m[v] = struct{}{}
+		}
+	}
+
+	return res
+}
+
+// SymmetricDifference возвращает элементы, которые есть ровно в одном из множеств
+func (s *Set[T]) SymmetricDifference(other *Set[T]) *Set[T] {
+
+	res := s.Difference(other)
+	for v := range other.m {
+		if !s.Contains(v) {
+			res.m[v] = struct{}{}
+		}
+	}
+
+	return res
+}
+
+// IsSubset сообщает, входят ли все элементы s в other
+func (s *Set[T]) IsSubset(other *Set[T]) bool {
+
+	if s.Len() > other.Len() {
+		return false
+	}
+	for v := range s.m {
+		if !other.Contains(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsSuperset сообщает, входят ли все элементы other в s
+func (s *Set[T]) IsSuperset(other *Set[T]) bool {
+	return other.IsSubset(s)
+}
+
+// IsDisjoint сообщает, что у множеств нет общих элементов
+func (s *Set[T]) IsDisjoint(other *Set[T]) bool {
+
+	small, large := s, other
+	if small.Len() > large.Len() {
+		small, large = large, small
+	}
+	for v := range small.m {
+		if large.Contains(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MarshalJSON кодирует множество массивом JSON (порядок элементов не определён)
+func (s Set[T]) MarshalJSON() ([]byte, error) {
+
+	items := make([]T, 0, s.Len())
+	for v := range s.m {
+		items = append(items, v)
+	}
+
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON заменяет содержимое множества элементами массива JSON (повторы допустимы)
+func (s *Set[T]) UnmarshalJSON(data []byte) error {
+
+	var items []T
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	s.m = make(map[T]struct{}, len(items))
+	for _, v := range items {
+		s.m[v] = struct{}{}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package set
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sorted возвращает элементы множества по возрастанию для сравнения в тестах
+func sorted(s *Set[int]) []int {
+	return slices.Sorted(s.All())
+}
+
+func TestSetBasic(t *testing.T) {
+
+	var s Set[string] // нулевое значение готово к работе
+	assert.Equal(t, 0, s.Len())
+	assert.False(t, s.Contains("cat"))
+	assert.Equal(t, 0, s.Remove("cat"))
+
+	// задача l1.12: множество из последовательности строк
+	assert.Equal(t, 3, s.Add("cat", "cat", "dog", "cat", "tree"))
+	assert.Equal(t, 3, s.Len())
+	assert.True(t, s.Contains("dog"))
+	assert.Equal(t, []string{"cat", "dog", "tree"}, slices.Sorted(s.All()))
+
+	assert.Equal(t, 1, s.Remove("dog", "fox"))
+	assert.False(t, s.Contains("dog"))
+
+	c := s.Clone()
+	c.Add("fox")
+	assert.False(t, s.Contains("fox"), "копия независима")
+
+	s.Clear()
+	assert.Equal(t, 0, s.Len())
+	assert.Equal(t, 0, (&Set[int]{}).Clone().Len())
+
+	assert.Equal(t, []int{1, 2, 3}, sorted(Collect(slices.Values([]int{3, 1, 2, 3}))))
+}
+
+func TestSetAlgebra(t *testing.T) {
+
+	// задача l1.11: пересечение неупорядоченных множеств
+	a := New(1, 2, 3, 1, 2, 3, 6)
+	b := New(6, 6, 6, 2, 3, 4, 15, 15, 25)
+
+	tests := []struct {
+		name string
+		got  *Set[int]
+		want []int
+	}{
+		{"объединение", a.Union(b), []int{1, 2, 3, 4, 6, 15, 25}},
+		{"пересечение", a.Intersection(b), []int{2, 3, 6}},
+		{"пересечение наоборот", b.Intersection(a), []int{2, 3, 6}},
+		{"разность", a.Difference(b), []int{1}},
+		{"разность наоборот", b.Difference(a), []int{4, 15, 25}},
+		{"симметрическая разность", a.SymmetricDifference(b), []int{1, 4, 15, 25}},
+		{"с пустым", a.Intersection(New[int]()), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sorted(tt.got))
+		})
+	}
+
+	assert.Equal(t, []int{1, 2, 3, 6}, sorted(a), "операции не меняют исходные множества")
+}
+
+func TestSetRelations(t *testing.T) {
+
+	a := New(1, 2)
+	b := New(1, 2, 3)
+	empty := New[int]()
+
+	assert.True(t, a.IsSubset(b))
+	assert.False(t, b.IsSubset(a))
+	assert.True(t, b.IsSuperset(a))
+	assert.True(t, a.IsSubset(a))
+	assert.True(t, empty.IsSubset(a))
+	assert.True(t, a.IsDisjoint(New(7, 8)))
+	assert.False(t, a.IsDisjoint(b))
+	assert.True(t, empty.IsDisjoint(empty))
+	assert.True(t, a.Equal(New(2, 1)))
+	assert.False(t, a.Equal(b))
+	assert.False(t, New(1, 4).Equal(New(1, 5)))
+}
+
+func TestSetJSON(t *testing.T) {
+
+	type doc struct {
+		Tags  Set[string]  `json:"tags"`
+		Other *Set[string] `json:"other"`
+	}
+
+	in := doc{Other: New("b")}
+	in.Tags.Add("go", "set", "go")
+	data, err := json.Marshal(in)
+	require.NoError(t, err)
+
+	var out doc
+	require.NoError(t, json.Unmarshal(data, &out))
+	assert.True(t, out.Tags.Equal(&in.Tags))
+	assert.True(t, out.Other.Equal(in.Other))
+
+	data, err = json.Marshal(New[int]())
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, string(data))
+
+	var s Set[int]
+	require.NoError(t, json.Unmarshal([]byte(`[3, 1, 3]`), &s))
+	assert.Equal(t, []int{1, 3}, sorted(&s))
+	assert.Error(t, json.Unmarshal([]byte(`{"a": 1}`), &s))
+}
@@ -0,0 +1,297 @@
+package set
+
+import (
+	"cmp"
+	"encoding/json"
+	"iter"
+)
+
+// Sorted - упорядоченное множество на B-дереве; нулевое значение - пустое множество.
+// Поиск, добавление и удаление за O(log n), перебор - по возрастанию
+type Sorted[T cmp.Ordered] struct {
+	root *node[T]
+	n    int
+}
+
+// NewSorted создаёт упорядоченное множество из значений (повторы отбрасываются)
+func NewSorted[T cmp.Ordered](items ...T) *Sorted[T] {
+
+	s := &Sorted[T]{}
+	s.Add(items...)
+
+	return s
+}
+
+// CollectSorted создаёт упорядоченное множество из последовательности
+func CollectSorted[T cmp.Ordered](seq iter.Seq[T]) *Sorted[T] {
+
+	s := &Sorted[T]{}
+	for v := range seq {
+		s.Add(v)
+	}
+
+	return s
+}
+
+// Add добавляет значения и возвращает число действительно добавленных
+func (s *Sorted[T]) Add(items ...T) int {
+
+	added := 0
+	for _, v := range items {
+		if s.root == nil {
+			s.root = &node[T]{}
+		}
+		if len(s.root.items) == maxItems {
+			// полный корень делится заранее: вставка идёт за один проход вниз
+			s.root = &node[T]{children: []*node[T]{s.root}}
+			s.root.splitChild(0)
+		}
+		if s.root.insert(v) {
+			s.n++
+			added++
+		}
+	}
+
+	return added
+}
+
+// Remove удаляет значения и возвращает число действительно удалённых
+func (s *Sorted[T]) Remove(items ...T) int {
+
+	removed := 0
+	for _, v := range items {
+		if s.root != nil && s.root.remove(v) {
+			s.n--
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// Contains сообщает, есть ли значение в множестве
+func (s *Sorted[T]) Contains(v T) bool {
+
+	n := s.root
+	for n != nil {
+		i, found := n.find(v)
+		if found {
+			return true
+		}
+		if n.leaf() {
+			return false
+		}
+		n = n.children[i]
+	}
+
+	return false
+}
+
+// Len возвращает число элементов
+func (s *Sorted[T]) Len() int {
+	return s.n
+}
+
+// Clear удаляет все элементы
+func (s *Sorted[T]) Clear() {
+	s.root, s.n = nil, 0
+}
+
+// Clone возвращает независимую копию
+func (s *Sorted[T]) Clone() *Sorted[T] {
+	return build(s.All())
+}
+
+// Min возвращает наименьший элемент; false - множество пусто
+func (s *Sorted[T]) Min() (T, bool) {
+
+	if s.n == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return s.root.min(), true
+}
+
+// Max возвращает наибольший элемент; false - множество пусто
+func (s *Sorted[T]) Max() (T, bool) {
+
+	if s.n == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return s.root.max(), true
+}
+
+// All перебирает элементы по возрастанию; менять множество в теле цикла нельзя
+func (s *Sorted[T]) All() iter.Seq[T] {
+
+	return func(yield func(T) bool) {
+		if s.root != nil {
+			var zero T
+			s.root.ascend(zero, false, yield)
+		}
+	}
+}
+
+// Backward перебирает элементы по убыванию
+func (s *Sorted[T]) Backward() iter.Seq[T] {
+
+	return func(yield func(T) bool) {
+		if s.root != nil {
+			s.root.descend(yield)
+		}
+	}
+}
+
+// Range перебирает по возрастанию элементы из полуинтервала [from, to)
+func (s *Sorted[T]) Range(from, to T) iter.Seq[T] {
+
+	return func(yield func(T) bool) {
+		if s.root == nil {
+			return
+		}
+		s.root.ascend(from, true, func(v T) bool {
+			return cmp.Less(v, to) && yield(v)
+		})
+	}
+}
+
+// Equal сообщает, совпадают ли множества
+func (s *Sorted[T]) Equal(other *Sorted[T]) bool {
+	return s.Len() == other.Len() && s.IsSubset(other)
+}
+
+// Union возвращает объединение: элементы, которые есть хотя бы в одном множестве
+func (s *Sorted[T]) Union(other *Sorted[T]) *Sorted[T] {
+	return build(merge(s.items(), other.items(), true, true, true))
+}
+
+// Intersection возвращает пересечение: элементы, которые есть в обоих множествах
+func (s *Sorted[T]) Intersection(other *Sorted[T]) *Sorted[T] {
+	return build(merge(s.items(), other.items(), false, true, false))
+}
+
+// Difference возвращает разность: элементы s, которых нет в other
+func (s *Sorted[T]) Difference(other *Sorted[T]) *Sorted[T] {
+	return build(merge(s.items(), other.items(), true, false, false))
+}
+
+// SymmetricDifference возвращает элементы, которые есть ровно в одном из множеств
+func (s *Sorted[T]) SymmetricDifference(other *Sorted[T]) *Sorted[T] {
+	return build(merge(s.items(), other.items(), true, false, true))
+}
+
+// IsSubset сообщает, входят ли все элементы s в other
+func (s *Sorted[T]) IsSubset(other *Sorted[T]) bool {
+
+	if s.Len() > other.Len() {
+		return false
+	}
+	for range merge(s.items(), other.items(), true, false, false) {
+		return false // нашёлся элемент s, которого нет в other
+	}
+
+	return true
+}
+
+// IsSuperset сообщает, входят ли все элементы other в s
+func (s *Sorted[T]) IsSuperset(other *Sorted[T]) bool {
+	return other.IsSubset(s)
+}
+
+// IsDisjoint сообщает, что у множеств нет общих элементов
+func (s *Sorted[T]) IsDisjoint(other *Sorted[T]) bool {
+
+	for range merge(s.items(), other.items(), false, true, false) {
+		return false
+	}
+
+	return true
+}
+
+// items возвращает элементы по возрастанию
+func (s *Sorted[T]) items() []T {
+
+	items := make([]T, 0, s.Len())
+	for v := range s.All() {
+		items = append(items, v)
+	}
+
+	return items
+}
+
+// build собирает множество из возрастающей последовательности
+func build[T cmp.Ordered](seq iter.Seq[T]) *Sorted[T] {
+
+	res := &Sorted[T]{}
+	for v := range seq {
+		res.Add(v)
+	}
+
+	return res
+}
+
+// merge сливает два возрастающих слайса и отдаёт элементы только из a (onlyA),
+// из обоих (both) и только из b (onlyB) - по возрастанию, за O(n + m)
+func merge[T cmp.Ordered](a, b []T, onlyA, both, onlyB bool) iter.Seq[T] {
+
+	return func(yield func(T) bool) {
+		i, j := 0, 0
+		for i < len(a) && j < len(b) {
+			var v T
+			var emit bool
+			switch c := cmp.Compare(a[i], b[j]); {
+			case c < 0:
+				v, emit = a[i], onlyA
+				i++
+			case c > 0:
+				v, emit = b[j], onlyB
+				j++
+			default:
+				v, emit = a[i], both
+				i++
+				j++
+			}
+			if emit && !yield(v) {
+				return
+			}
+		}
+		// остаток одного из слайсов
+		if onlyA {
+			for _, v := range a[i:] {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+		if onlyB {
+			for _, v := range b[j:] {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// MarshalJSON кодирует множество массивом JSON по возрастанию
+func (s Sorted[T]) MarshalJSON() ([]byte, error) {
+
+	return json.Marshal(s.items())
+}
+
+// UnmarshalJSON заменяет содержимое множества элементами массива JSON (порядок и повторы не важны)
+func (s *Sorted[T]) UnmarshalJSON(data []byte) error {
+
+	var items []T
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	s.Clear()
+	s.Add(items...)
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package set
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkTree проверяет свойства B-дерева: ключи упорядочены, листья на одной глубине,
+// в узлах (кроме корня) от degree-1 до 2*degree-1 ключей, Len совпадает с числом ключей
+func checkTree[T int | string](t *testing.T, s *Sorted[T]) {
+
+	t.Helper()
+
+	if s.root == nil {
+		require.Equal(t, 0, s.Len())
+		return
+	}
+
+	count, leafDepth := 0, -1
+	var walk func(n *node[T], depth int, root bool)
+	walk = func(n *node[T], depth int, root bool) {
+		require.LessOrEqual(t, len(n.items), maxItems)
+		if !root {
+			require.GreaterOrEqual(t, len(n.items), degree-1)
+		}
+		require.True(t, slices.IsSorted(n.items))
+		count += len(n.items)
+		if n.leaf() {
+			if leafDepth < 0 {
+				leafDepth = depth
+			}
+			require.Equal(t, leafDepth, depth, "листья на разной глубине")
+			return
+		}
+		require.Len(t, n.children, len(n.items)+1)
+		for i, child := range n.children {
+			if i > 0 {
+				require.Less(t, n.items[i-1], child.items[0])
+			}
+			if i < len(n.items) {
+				require.Less(t, child.items[len(child.items)-1], n.items[i])
+			}
+			walk(child, depth+1, false)
+		}
+	}
+	walk(s.root, 0, true)
+
+	require.Equal(t, s.Len(), count)
+	require.True(t, slices.IsSorted(slices.Collect(s.All())))
+}
+
+func TestSortedBasic(t *testing.T) {
+
+	var s Sorted[string]
+	_, ok := s.Min()
+	assert.False(t, ok)
+	_, ok = s.Max()
+	assert.False(t, ok)
+	assert.False(t, s.Contains("cat"))
+	assert.Equal(t, 0, s.Remove("cat"))
+	assert.Empty(t, slices.Collect(s.All()))
+
+	assert.Equal(t, 3, s.Add("tree", "cat", "cat", "dog", "cat"))
+	assert.Equal(t, []string{"cat", "dog", "tree"}, slices.Collect(s.All()))
+	assert.Equal(t, []string{"tree", "dog", "cat"}, slices.Collect(s.Backward()))
+	first, _ := s.Min()
+	last, _ := s.Max()
+	assert.Equal(t, "cat", first)
+	assert.Equal(t, "tree", last)
+
+	c := s.Clone()
+	c.Remove("cat")
+	assert.True(t, s.Contains("cat"), "копия независима")
+
+	s.Clear()
+	assert.Equal(t, 0, s.Len())
+	assert.False(t, s.Contains("dog"))
+}
+
+// TestSortedRandom сверяет B-дерево с мапой на случайных вставках и удалениях:
+// при тысячах ключей дерево многократно делится, заимствует ключи у соседей и сливается
+func TestSortedRandom(t *testing.T) {
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	var s Sorted[int]
+	ref := make(map[int]bool)
+
+	for round := range 20 {
+		for range 2000 {
+			v := rng.IntN(5000)
+			if rng.IntN(3) == 0 {
+				assert.Equal(t, ref[v], s.Remove(v) == 1)
+				delete(ref, v)
+			} else {
+				assert.Equal(t, !ref[v], s.Add(v) == 1)
+				ref[v] = true
+			}
+		}
+		checkTree(t, &s)
+		require.Len(t, ref, s.Len(), "раунд %d", round)
+	}
+
+	for v := range 5000 {
+		assert.Equal(t, ref[v], s.Contains(v))
+	}
+
+	// удаляем всё в случайном порядке - дерево должно схлопнуться до пустого корня
+	keys := slices.Collect(s.All())
+	rng.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	for i, v := range keys {
+		require.Equal(t, 1, s.Remove(v))
+		if i%500 == 0 {
+			checkTree(t, &s)
+		}
+	}
+	checkTree(t, &s)
+	assert.Equal(t, 0, s.Len())
+	assert.Empty(t, s.root.items)
+}
+
+func TestSortedSequential(t *testing.T) {
+
+	// возрастающие и убывающие вставки - худший случай для несбалансированных деревьев
+	up := NewSorted[int]()
+	down := NewSorted[int]()
+	for i := range 10000 {
+		up.Add(i)
+		down.Add(10000 - i)
+	}
+	checkTree(t, up)
+	checkTree(t, down)
+
+	for i := 0; i < 10000; i += 2 {
+		up.Remove(i)
+	}
+	checkTree(t, up)
+	assert.Equal(t, 5000, up.Len())
+	first, _ := up.Min()
+	last, _ := up.Max()
+	assert.Equal(t, 1, first)
+	assert.Equal(t, 9999, last)
+}
+
+func TestSortedRange(t *testing.T) {
+
+	s := NewSorted[int]()
+	for i := range 1000 {
+		s.Add(i * 2) // чётные 0..1998
+	}
+
+	assert.Equal(t, []int{10, 12, 14}, slices.Collect(s.Range(10, 16)))
+	assert.Equal(t, []int{10, 12, 14}, slices.Collect(s.Range(9, 15)))
+	assert.Empty(t, slices.Collect(s.Range(11, 12)))
+	assert.Empty(t, slices.Collect(s.Range(5000, 6000)))
+	assert.Len(t, slices.Collect(s.Range(-100, 5000)), 1000)
+
+	var got []int
+	for v := range s.Range(100, 2000) {
+		if len(got) == 3 {
+			break // прерывание перебора
+		}
+		got = append(got, v)
+	}
+	assert.Equal(t, []int{100, 102, 104}, got)
+}
+
+func TestSortedAlgebra(t *testing.T) {
+
+	a := NewSorted(1, 2, 3, 1, 2, 3, 6)
+	b := NewSorted(6, 6, 6, 2, 3, 4, 15, 15, 25)
+
+	tests := []struct {
+		name string
+		got  *Sorted[int]
+		want []int
+	}{
+		{"объединение", a.Union(b), []int{1, 2, 3, 4, 6, 15, 25}},
+		{"пересечение", a.Intersection(b), []int{2, 3, 6}},
+		{"разность", a.Difference(b), []int{1}},
+		{"разность наоборот", b.Difference(a), []int{4, 15, 25}},
+		{"симметрическая разность", a.SymmetricDifference(b), []int{1, 4, 15, 25}},
+		{"с пустым", a.Union(NewSorted[int]()), []int{1, 2, 3, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, slices.Collect(tt.got.All()))
+			assert.Equal(t, len(tt.want), tt.got.Len())
+		})
+	}
+
+	assert.True(t, NewSorted(2, 3).IsSubset(a))
+	assert.False(t, NewSorted(2, 4).IsSubset(a))
+	assert.True(t, a.IsSuperset(NewSorted(6)))
+	assert.True(t, a.IsDisjoint(NewSorted(100)))
+	assert.False(t, a.IsDisjoint(b))
+	assert.True(t, a.Equal(NewSorted(6, 3, 2, 1)))
+	assert.False(t, a.Equal(b))
+
+	// на больших множествах сверяем с Set
+	rng := rand.New(rand.NewPCG(3, 4))
+	var x, y []int
+	for range 5000 {
+		x = append(x, rng.IntN(8000))
+		y = append(y, rng.IntN(8000))
+	}
+	sx, sy := NewSorted(x...), NewSorted(y...)
+	mx, my := New(x...), New(y...)
+	union := sx.Union(sy)
+	checkTree(t, union)
+	assert.Equal(t, sorted(mx.Union(my)), slices.Collect(union.All()))
+	assert.Equal(t, sorted(mx.Intersection(my)), slices.Collect(sx.Intersection(sy).All()))
+	assert.Equal(t, sorted(mx.SymmetricDifference(my)), slices.Collect(sx.SymmetricDifference(sy).All()))
+}
+
+func TestSortedJSON(t *testing.T) {
+
+	s := NewSorted(3, 1, 2)
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	assert.Equal(t, `[1,2,3]`, string(data), "элементы по возрастанию")
+
+	data, err = json.Marshal(struct{ S Sorted[int] }{*NewSorted(2, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, `{"S":[1,2]}`, string(data))
+
+	var out Sorted[string]
+	require.NoError(t, json.Unmarshal([]byte(`["b", "a", "b"]`), &out))
+	assert.Equal(t, []string{"a", "b"}, slices.Collect(out.All()))
+	assert.Error(t, json.Unmarshal([]byte(`[1]`), &out))
+}
@@ -0,0 +1,156 @@
+package set
+
+import (
+	"encoding/json"
+	"iter"
+	"sync"
+)
+
+// Sync - множество на мапе под RWMutex, безопасное для одновременного использования
+// из горутин; нулевое значение - пустое множество. Копировать после использования нельзя
+type Sync[T comparable] struct {
+	mu  sync.RWMutex
+	set Set[T]
+}
+
+// NewSync создаёт потокобезопасное множество из значений
+func NewSync[T comparable](items ...T) *Sync[T] {
+
+	s := &Sync[T]{}
+	s.set.Add(items...)
+
+	return s
+}
+
+// Add добавляет значения и возвращает число действительно добавленных
+func (s *Sync[T]) Add(items ...T) int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.set.Add(items...)
+}
+
+// Remove удаляет значения и возвращает число действительно удалённых
+func (s *Sync[T]) Remove(items ...T) int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.set.Remove(items...)
+}
+
+// Contains сообщает, есть ли значение в множестве
+func (s *Sync[T]) Contains(v T) bool {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.set.Contains(v)
+}
+
+// Len возвращает число элементов
+func (s *Sync[T]) Len() int {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.set.Len()
+}
+
+// Clear удаляет все элементы
+func (s *Sync[T]) Clear() {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set.Clear()
+}
+
+// Snapshot возвращает копию содержимого на текущий момент
+func (s *Sync[T]) Snapshot() *Set[T] {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.set.Clone()
+}
+
+// All перебирает снимок множества: блокировка не удерживается во время перебора,
+// поэтому тело цикла может менять множество
+func (s *Sync[T]) All() iter.Seq[T] {
+	return s.Snapshot().All()
+}
+
+// Union возвращает объединение с other. Как и остальные операции над двумя множествами,
+// сначала снимает копию other, затем блокирует s: два множества никогда не заблокированы
+// одновременно, и встречные вызовы a.Union(b) и b.Union(a) не приводят к взаимной блокировке
+func (s *Sync[T]) Union(other *Sync[T]) *Set[T] {
+	return with(s, other, (*Set[T]).Union)
+}
+
+// Intersection возвращает пересечение с other
+func (s *Sync[T]) Intersection(other *Sync[T]) *Set[T] {
+	return with(s, other, (*Set[T]).Intersection)
+}
+
+// Difference возвращает элементы s, которых нет в other
+func (s *Sync[T]) Difference(other *Sync[T]) *Set[T] {
+	return with(s, other, (*Set[T]).Difference)
+}
+
+// SymmetricDifference возвращает элементы, которые есть ровно в одном из множеств
+func (s *Sync[T]) SymmetricDifference(other *Sync[T]) *Set[T] {
+	return with(s, other, (*Set[T]).SymmetricDifference)
+}
+
+// IsSubset сообщает, входят ли все элементы s в other
+func (s *Sync[T]) IsSubset(other *Sync[T]) bool {
+	return with(s, other, (*Set[T]).IsSubset)
+}
+
+// IsSuperset сообщает, входят ли все элементы other в s
+func (s *Sync[T]) IsSuperset(other *Sync[T]) bool {
+	return with(s, other, (*Set[T]).IsSuperset)
+}
+
+// IsDisjoint сообщает, что у множеств нет общих элементов
+func (s *Sync[T]) IsDisjoint(other *Sync[T]) bool {
+	return with(s, other, (*Set[T]).IsDisjoint)
+}
+
+// with применяет операцию к s (под блокировкой на чтение) и снимку other
+func with[T comparable, R any](s, other *Sync[T], op func(*Set[T], *Set[T]) R) R {
+
+	snap := other.Snapshot()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return op(&s.set, snap)
+}
+
+// MarshalJSON кодирует множество массивом JSON (порядок элементов не определён)
+func (s *Sync[T]) MarshalJSON() ([]byte, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.set.MarshalJSON()
+}
+
+// UnmarshalJSON заменяет содержимое множества элементами массива JSON
+func (s *Sync[T]) UnmarshalJSON(data []byte) error {
+
+	var set Set[T]
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set = set
+
+	return nil
+}
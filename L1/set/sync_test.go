@@ -0,0 +1,83 @@
+package set
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSync(t *testing.T) {
+
+	var s Sync[int]
+	assert.Equal(t, 2, s.Add(1, 2, 2))
+	assert.True(t, s.Contains(1))
+	assert.Equal(t, 1, s.Remove(1, 5))
+	assert.Equal(t, 1, s.Len())
+
+	snap := s.Snapshot()
+	s.Add(10)
+	assert.False(t, snap.Contains(10), "снимок не меняется вместе с множеством")
+
+	// тело перебора может менять множество: перебирается снимок
+	for v := range s.All() {
+		s.Remove(v)
+		s.Add(v + 100)
+	}
+	assert.Equal(t, []int{102, 110}, slices.Sorted(s.All()))
+
+	a, b := NewSync(1, 2, 3), NewSync(2, 3, 4)
+	assert.Equal(t, []int{1, 2, 3, 4}, sorted(a.Union(b)))
+	assert.Equal(t, []int{2, 3}, sorted(a.Intersection(b)))
+	assert.Equal(t, []int{1}, sorted(a.Difference(b)))
+	assert.Equal(t, []int{1, 4}, sorted(a.SymmetricDifference(b)))
+	assert.True(t, a.IsSubset(a), "операция множества с самим собой не блокируется")
+	assert.False(t, a.IsSuperset(b))
+	assert.False(t, a.IsDisjoint(b))
+
+	data, err := json.Marshal(a)
+	require.NoError(t, err)
+	var out Sync[int]
+	require.NoError(t, json.Unmarshal(data, &out))
+	assert.True(t, out.Snapshot().Equal(a.Snapshot()))
+
+	s.Clear()
+	assert.Equal(t, 0, s.Len())
+}
+
+// TestSyncRace - одновременные чтения, записи и операции над двумя множествами навстречу друг другу
+func TestSyncRace(t *testing.T) {
+
+	a, b := NewSync[int](), NewSync[int]()
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				v := g*1000 + i
+				a.Add(v)
+				b.Add(v / 2)
+				a.Contains(v - 1)
+				if i%100 == 0 {
+					// встречные вызовы не должны приводить к взаимной блокировке
+					a.Union(b)
+					b.Intersection(a)
+					_, _ = json.Marshal(a)
+					for range b.All() {
+					}
+				}
+				if i%3 == 0 {
+					a.Remove(v)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 8*1000-8*334, a.Len())
+	assert.Equal(t, 4000, b.Len())
+}
@@ -0,0 +1,3 @@
+module l2.11
+
+go 1.24.1
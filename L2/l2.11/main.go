@@ -3,9 +3,16 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
 	"slices"
 	"strings"
+
+	"l2.11/pkg/anagram"
 )
 
 // sortAnagram распределяет анаграммы по первому встреченному слову
@@ -24,20 +31,17 @@ func sortAnagram(words []string) map[string][]string {
 	// проходим по словам поступившего слайса
 	for _, word := range words {
 
-		// приводим слово к нижнему регистру
-		word = strings.ToLower(word)
+		// приводим слово к нижнему регистру (с учётом вариантов написания букв)
+		word = anagram.Fold(word)
 
 		// пропускаем дубликаты
 		if _, ok := unique[word]; ok {
 			continue
 		}
+		unique[word] = struct{}{}
 
-		// разбиваем слово на руны
-		runes := []rune(word)
-		// сортируем руны
-		slices.Sort(runes)
 		// делаем из сортированных рун строку - ключ вспомогательной мапы
-		key := string(runes)
+		key := anagram.Key(word, false)
 
 		// проверяем есть ли такой ключ в anaKeys
 		if _, ok := anaKeys[key]; !ok {
@@ -61,14 +65,236 @@ func sortAnagram(words []string) map[string][]string {
 	return result
 }
 
+// Config - параметры командной строки
+type Config struct {
+	dict   string // текстовый словарь (по слову в строке)
+	index  string // файл сохранённого индекса
+	save   string // куда сохранить индекс по завершении
+	repl   bool   // интерактивный режим
+	foldYo bool   // считать "ё" и "е" одной буквой
+}
+
+// parseFlags парсит флаги командной строки
+func parseFlags() Config {
+
+	config := Config{}
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Используйте: anagram [-dict file] [-index file] [-save file] [-yo] [-repl] [word ...]\n")
+		fmt.Fprintf(os.Stderr, "Без флагов и слов выполняется пример из условия задачи.\n")
+		fmt.Fprintf(os.Stderr, "Флаги:\n")
+		flag.PrintDefaults()
+	}
+
+	flag.StringVar(&config.dict, "dict", "", "load dictionary file (one word per line)")
+	flag.StringVar(&config.index, "index", "", "load saved index file")
+	flag.StringVar(&config.save, "save", "", "save index to file on exit")
+	flag.BoolVar(&config.repl, "repl", false, "interactive mode")
+	flag.BoolVar(&config.foldYo, "yo", false, "treat ё and е as the same letter")
+
+	flag.Parse()
+
+	return config
+}
+
+// buildIndex собирает индекс из сохранённого файла и/или словаря
+func buildIndex(config Config) (*anagram.AnagramIndex, error) {
+
+	idx := anagram.NewAnagramIndex(anagram.Options{FoldYo: config.foldYo})
+
+	if config.index != "" {
+		loaded, err := anagram.LoadFile(config.index)
+		switch {
+		case err == nil:
+			idx = loaded
+		case errors.Is(err, os.ErrNotExist) && config.save == config.index:
+			// индекса ещё нет, но он будет создан при сохранении
+		default:
+			return nil, err
+		}
+	}
+
+	if config.dict != "" {
+		file, err := os.Open(config.dict)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка открытия словаря: %w", err)
+		}
+		defer file.Close()
+		if _, err := idx.AddFrom(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return idx, nil
+}
+
+// replHelp - справка интерактивного режима
+const replHelp = `Команды:
+  <слово>            найти анаграммы слова (то же, что find)
+  find <слово>       найти анаграммы слова
+  sub <буквы>        слова, которые можно составить из букв
+  add <слово ...>    добавить слова
+  remove <слово ...> удалить слова
+  groups             все множества анаграмм
+  size               количество слов в индексе
+  save [файл]        сохранить индекс
+  help               эта справка
+  quit               выход
+`
+
+// runREPL выполняет команды из in и пишет ответы в out, savePath - файл для команды save по умолчанию
+func runREPL(idx *anagram.AnagramIndex, in io.Reader, out io.Writer, savePath string, prompt bool) error {
+
+	scanner := bufio.NewScanner(in)
+
+	for {
+		if prompt {
+			fmt.Fprint(out, "> ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		command, args := fields[0], fields[1:]
+
+		switch command {
+		case "quit", "exit":
+			return nil
+
+		case "help":
+			fmt.Fprint(out, replHelp)
+
+		case "find":
+			for _, word := range args {
+				printLookup(idx, word, out)
+			}
+
+		case "sub":
+			if len(args) == 0 {
+				fmt.Fprintln(out, "укажите буквы")
+				continue
+			}
+			fmt.Fprintln(out, strings.Join(idx.SubAnagrams(strings.Join(args, "")), " "))
+
+		case "add":
+			added := 0
+			for _, word := range args {
+				if idx.Add(word) {
+					added++
+				}
+			}
+			fmt.Fprintf(out, "добавлено: %d\n", added)
+
+		case "remove":
+			removed := 0
+			for _, word := range args {
+				if idx.Remove(word) {
+					removed++
+				}
+			}
+			fmt.Fprintf(out, "удалено: %d\n", removed)
+
+		case "groups":
+			printGroups(idx.Groups(), out)
+
+		case "size":
+			fmt.Fprintln(out, idx.Len())
+
+		case "save":
+			path := savePath
+			if len(args) > 0 {
+				path = args[0]
+			}
+			if path == "" {
+				fmt.Fprintln(out, "укажите файл: save <файл>")
+				continue
+			}
+			if err := idx.SaveFile(path); err != nil {
+				fmt.Fprintf(out, "ошибка: %v\n", err)
+				continue
+			}
+			fmt.Fprintf(out, "сохранено в %s\n", path)
+
+		default:
+			// одиночное слово без команды - поиск анаграмм
+			for _, word := range fields {
+				printLookup(idx, word, out)
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// printLookup выводит анаграммы слова
+func printLookup(idx *anagram.AnagramIndex, word string, out io.Writer) {
+
+	words := idx.Lookup(word)
+	if len(words) == 0 {
+		fmt.Fprintf(out, "%s: анаграмм нет\n", anagram.Fold(word))
+		return
+	}
+	fmt.Fprintf(out, "%s: %v\n", anagram.Fold(word), words)
+}
+
+// printGroups выводит множества анаграмм в алфавитном порядке ключей
+func printGroups(groups map[string][]string, out io.Writer) {
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(out, "%s: %v\n", key, groups[key])
+	}
+}
+
 func main() {
 
-	input := []string{"пятак", "пятка", "тяпка", "листок", "слиток", "столик", "стол"}
+	config := parseFlags()
+
+	// без параметров работаем как в исходной задаче
+	if config.dict == "" && config.index == "" && !config.repl && flag.NArg() == 0 {
 
-	anagrams := sortAnagram(input) // получаем мапу с множествами
+		input := []string{"пятак", "пятка", "тяпка", "листок", "слиток", "столик", "стол"}
+
+		anagrams := sortAnagram(input) // получаем мапу с множествами
+
+		// итерируемся по мапе и выводим результат
+		for key, val := range anagrams {
+			fmt.Printf("%s: %v\n", key, val)
+		}
+		return
+	}
 
-	// итерируемся по мапе и выводим результат
-	for key, val := range anagrams {
-		fmt.Printf("%s: %v\n", key, val)
+	idx, err := buildIndex(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	// слова из аргументов ищем сразу
+	for _, word := range flag.Args() {
+		printLookup(idx, word, os.Stdout)
+	}
+
+	if config.repl {
+		if err := runREPL(idx, os.Stdin, os.Stdout, config.save, true); err != nil {
+			fmt.Fprintf(os.Stderr, "ошибка чтения: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if config.save != "" {
+		if err := idx.SaveFile(config.save); err != nil {
+			fmt.Fprintf(os.Stderr, "ошибка: %v\n", err)
+			os.Exit(1)
+		}
 	}
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"l2.11/pkg/anagram"
+)
+
+// TestSortAnagram проверяет пример из условия задачи
+func TestSortAnagram(t *testing.T) {
+
+	input := []string{"пятак", "ПЯТКА", "тяпка", "листок", "слиток", "столик", "стол", "пятак"}
+
+	result := sortAnagram(input)
+
+	if len(result) != 2 {
+		t.Fatalf("sortAnagram() returned %d groups, want 2: %v", len(result), result)
+	}
+	if !slices.Equal(result["пятак"], []string{"пятак", "пятка", "тяпка"}) {
+		t.Errorf("sortAnagram()[пятак] = %v", result["пятак"])
+	}
+	if !slices.Equal(result["листок"], []string{"листок", "слиток", "столик"}) {
+		t.Errorf("sortAnagram()[листок] = %v", result["листок"])
+	}
+}
+
+// TestRunREPL проверяет команды интерактивного режима
+func TestRunREPL(t *testing.T) {
+
+	idx := anagram.NewAnagramIndex(anagram.Options{})
+	path := filepath.Join(t.TempDir(), "index.bin")
+
+	script := strings.Join([]string{
+		"add пятак пятка тяпка кот",
+		"ТЯПКА",
+		"find ток",
+		"sub к о т ы",
+		"remove пятка",
+		"groups",
+		"size",
+		"save",
+		"quit",
+		"size", // после quit команды не выполняются
+	}, "\n")
+
+	var out strings.Builder
+	if err := runREPL(idx, strings.NewReader(script), &out, path, false); err != nil {
+		t.Fatalf("runREPL() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"добавлено: 4",
+		"тяпка: [пятак пятка тяпка]",
+		"ток: [кот]",
+		"кот",
+		"удалено: 1",
+		"пятак: [пятак тяпка]",
+		"3",
+		"сохранено в " + path,
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("runREPL() output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	loaded, err := anagram.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if loaded.Len() != 3 {
+		t.Errorf("saved index Len() = %d, want 3", loaded.Len())
+	}
+}
@@ -0,0 +1,271 @@
+package anagram
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Options - настройки нормализации слов в индексе
+type Options struct {
+	FoldYo bool // считать "ё" и "е" одной буквой при поиске анаграмм
+}
+
+// AnagramIndex - переиспользуемый индекс анаграмм: слова сгруппированы по ключу
+// (отсортированные руны слова после приведения регистра), безопасен для конкурентного доступа
+type AnagramIndex struct {
+	mu     sync.RWMutex
+	opts   Options
+	groups map[string]map[string]struct{} // ключ -> множество слов с этим ключом
+	size   int                            // общее количество слов
+}
+
+// NewAnagramIndex создаёт пустой индекс
+func NewAnagramIndex(opts Options) *AnagramIndex {
+
+	return &AnagramIndex{
+		opts:   opts,
+		groups: make(map[string]map[string]struct{}),
+	}
+}
+
+// Fold приводит слово к единому регистру. В отличие от strings.ToLower, руна проходит
+// через верхний регистр, поэтому варианты одной буквы (например, "ſ" и "s", "ς" и "σ")
+// схлопываются, а каждая руна отображается ровно в одну руну
+func Fold(word string) string {
+
+	return strings.Map(func(r rune) rune {
+		return unicode.ToLower(unicode.ToUpper(r))
+	}, strings.TrimSpace(word))
+}
+
+// Key возвращает ключ группы анаграмм для уже приведённого через Fold слова
+func Key(folded string, foldYo bool) string {
+
+	runes := []rune(folded)
+	if foldYo {
+		for i, r := range runes {
+			if r == 'ё' {
+				runes[i] = 'е'
+			}
+		}
+	}
+	slices.Sort(runes)
+
+	return string(runes)
+}
+
+// key вычисляет ключ с настройками индекса
+func (idx *AnagramIndex) key(folded string) string {
+	return Key(folded, idx.opts.FoldYo)
+}
+
+// Options возвращает настройки индекса
+func (idx *AnagramIndex) Options() Options {
+	return idx.opts
+}
+
+// Add добавляет слово в индекс, возвращает false, если слово пустое или уже было
+func (idx *AnagramIndex) Add(word string) bool {
+
+	word = Fold(word)
+	if word == "" {
+		return false
+	}
+	key := idx.key(word)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	group, ok := idx.groups[key]
+	if !ok {
+		group = make(map[string]struct{})
+		idx.groups[key] = group
+	}
+	if _, ok := group[word]; ok {
+		return false
+	}
+	group[word] = struct{}{}
+	idx.size++
+
+	return true
+}
+
+// Remove удаляет слово из индекса, возвращает false, если слова не было
+func (idx *AnagramIndex) Remove(word string) bool {
+
+	word = Fold(word)
+	key := idx.key(word)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	group, ok := idx.groups[key]
+	if !ok {
+		return false
+	}
+	if _, ok := group[word]; !ok {
+		return false
+	}
+	delete(group, word)
+	idx.size--
+
+	// пустые группы не храним
+	if len(group) == 0 {
+		delete(idx.groups, key)
+	}
+
+	return true
+}
+
+// Contains сообщает, есть ли слово в индексе
+func (idx *AnagramIndex) Contains(word string) bool {
+
+	word = Fold(word)
+	key := idx.key(word)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	_, ok := idx.groups[key][word]
+
+	return ok
+}
+
+// Len возвращает количество слов в индексе
+func (idx *AnagramIndex) Len() int {
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.size
+}
+
+// Lookup возвращает отсортированный список слов словаря, являющихся анаграммами word
+// (включая само слово, если оно есть в словаре)
+func (idx *AnagramIndex) Lookup(word string) []string {
+
+	key := idx.key(Fold(word))
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(idx.groups[key]))
+}
+
+// SubAnagrams возвращает слова словаря, которые можно составить из букв letters
+// (каждую букву можно использовать не больше раз, чем она встречается в letters).
+// Результат отсортирован по убыванию длины, затем по алфавиту
+func (idx *AnagramIndex) SubAnagrams(letters string) []string {
+
+	// считаем доступные буквы
+	available := make(map[rune]int)
+	for _, r := range idx.key(Fold(letters)) {
+		available[r]++
+	}
+	total := utf8.RuneCountInString(Fold(letters))
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var result []string
+	used := make(map[rune]int) // переиспользуемый счётчик букв проверяемого ключа
+	for key, group := range idx.groups {
+
+		if utf8.RuneCountInString(key) > total {
+			continue
+		}
+
+		clear(used)
+		fits := true
+		for _, r := range key {
+			used[r]++
+			if used[r] > available[r] {
+				fits = false
+				break
+			}
+		}
+		if !fits {
+			continue
+		}
+
+		for word := range group {
+			result = append(result, word)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b string) int {
+		if c := cmp.Compare(utf8.RuneCountInString(b), utf8.RuneCountInString(a)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	return result
+}
+
+// Groups возвращает все множества анаграмм из двух и более слов: ключ - первое по алфавиту
+// слово множества, значение - отсортированные слова множества
+func (idx *AnagramIndex) Groups() map[string][]string {
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	result := make(map[string][]string)
+	for _, group := range idx.groups {
+		if len(group) < 2 {
+			continue
+		}
+		words := slices.Sorted(maps.Keys(group))
+		result[words[0]] = words
+	}
+
+	return result
+}
+
+// AddFrom читает словарь (по слову в строке, строки с "#" в начале - комментарии)
+// и добавляет слова в индекс, возвращает количество новых слов
+func (idx *AnagramIndex) AddFrom(r io.Reader) (int, error) {
+
+	scanner := bufio.NewScanner(r)
+	added := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if idx.Add(line) {
+			added++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return added, fmt.Errorf("ошибка чтения словаря: %w", err)
+	}
+
+	return added, nil
+}
+
+// LoadDictionary создаёт индекс из текстового файла словаря
+func LoadDictionary(path string, opts Options) (*AnagramIndex, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия словаря: %w", err)
+	}
+	defer file.Close()
+
+	idx := NewAnagramIndex(opts)
+	if _, err := idx.AddFrom(file); err != nil {
+		return nil, err
+	}
+
+	return idx, nil
+}
@@ -0,0 +1,254 @@
+package anagram
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestIndex - индекс со словами из условия задачи
+func newTestIndex(t *testing.T, opts Options) *AnagramIndex {
+
+	t.Helper()
+
+	idx := NewAnagramIndex(opts)
+	dict := "# словарь из условия\nпятак\nПятка\nТЯПКА\n\nлисток\nслиток\nстолик\nстол\nток\nкот\n"
+	if _, err := idx.AddFrom(strings.NewReader(dict)); err != nil {
+		t.Fatalf("AddFrom() error = %v", err)
+	}
+
+	return idx
+}
+
+func TestFold(t *testing.T) {
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"ПЯТАК", "пятак"},
+		{"  Ёлка ", "ёлка"},
+		{"ΣΊΣΥΦΟΣ", "σίσυφοσ"}, // конечная сигма схлопывается с обычной
+		{"ſun", "sun"},         // длинная s
+		{"Kelvin", "kelvin"},   // знак Кельвина
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Fold(tt.input); got != tt.want {
+				t.Errorf("Fold(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+
+	idx := newTestIndex(t, Options{})
+
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"КАТЯП", []string{"пятак", "пятка", "тяпка"}},
+		{"столик", []string{"листок", "слиток", "столик"}},
+		{"лост", []string{"стол"}},
+		{"окт", []string{"кот", "ток"}},
+		{"нет", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := idx.Lookup(tt.word); !slices.Equal(got, tt.want) {
+				t.Errorf("Lookup(%q) = %v, want %v", tt.word, got, tt.want)
+			}
+		})
+	}
+
+	if idx.Len() != 9 {
+		t.Errorf("Len() = %d, want 9", idx.Len())
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+
+	idx := NewAnagramIndex(Options{})
+
+	if !idx.Add("Кот") {
+		t.Error("Add() first time = false")
+	}
+	if idx.Add("КОТ") {
+		t.Error("Add() duplicate in other case = true")
+	}
+	if idx.Add("   ") {
+		t.Error("Add() empty word = true")
+	}
+	idx.Add("ток")
+
+	if !idx.Contains("кОт") {
+		t.Error("Contains() = false")
+	}
+	if !idx.Remove("КОТ") {
+		t.Error("Remove() = false")
+	}
+	if idx.Remove("кот") {
+		t.Error("Remove() twice = true")
+	}
+	if got := idx.Lookup("кот"); !slices.Equal(got, []string{"ток"}) {
+		t.Errorf("Lookup() after remove = %v", got)
+	}
+
+	idx.Remove("ток")
+	if idx.Len() != 0 || len(idx.groups) != 0 {
+		t.Errorf("index not empty after removing everything: len=%d groups=%d", idx.Len(), len(idx.groups))
+	}
+}
+
+func TestFoldYo(t *testing.T) {
+
+	idx := NewAnagramIndex(Options{FoldYo: true})
+	idx.Add("ёлка")
+	idx.Add("колье")
+
+	if got := idx.Lookup("елка"); !slices.Equal(got, []string{"ёлка"}) {
+		t.Errorf("Lookup() with FoldYo = %v", got)
+	}
+
+	plain := NewAnagramIndex(Options{})
+	plain.Add("ёлка")
+	if got := plain.Lookup("елка"); len(got) != 0 {
+		t.Errorf("Lookup() without FoldYo = %v", got)
+	}
+}
+
+func TestSubAnagrams(t *testing.T) {
+
+	idx := newTestIndex(t, Options{})
+
+	got := idx.SubAnagrams("СЛИТОК")
+	want := []string{"листок", "слиток", "столик", "стол", "кот", "ток"}
+	if !slices.Equal(got, want) {
+		t.Errorf("SubAnagrams() = %v, want %v", got, want)
+	}
+
+	// буква "т" одна, поэтому слова с двумя "т" не подходят
+	idx.Add("тот")
+	if got := idx.SubAnagrams("тко"); slices.Contains(got, "тот") {
+		t.Errorf("SubAnagrams() uses letter twice: %v", got)
+	}
+
+	if got := idx.SubAnagrams("я"); len(got) != 0 {
+		t.Errorf("SubAnagrams() = %v, want empty", got)
+	}
+}
+
+func TestGroups(t *testing.T) {
+
+	idx := newTestIndex(t, Options{})
+	groups := idx.Groups()
+
+	if len(groups) != 3 {
+		t.Fatalf("Groups() returned %d groups, want 3: %v", len(groups), groups)
+	}
+	if !slices.Equal(groups["пятак"], []string{"пятак", "пятка", "тяпка"}) {
+		t.Errorf("Groups()[пятак] = %v", groups["пятак"])
+	}
+	if _, ok := groups["стол"]; ok {
+		t.Error("Groups() contains single-word group")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+
+	idx := newTestIndex(t, Options{FoldYo: true})
+	idx.Add("ёж")
+
+	path := filepath.Join(t.TempDir(), "index.bin")
+	if err := idx.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	if loaded.Len() != idx.Len() {
+		t.Errorf("loaded Len() = %d, want %d", loaded.Len(), idx.Len())
+	}
+	if !loaded.Options().FoldYo {
+		t.Error("loaded index lost FoldYo option")
+	}
+	if got := loaded.Lookup("тяпка"); !slices.Equal(got, idx.Lookup("тяпка")) {
+		t.Errorf("loaded Lookup() = %v", got)
+	}
+	if !loaded.Contains("ёж") {
+		t.Error("loaded index lost word")
+	}
+}
+
+func TestPersistenceCompact(t *testing.T) {
+
+	// словарь из множества похожих слов должен сжиматься заметно лучше, чем хранится в тексте
+	idx := NewAnagramIndex(Options{})
+	var plain strings.Builder
+	for _, a := range "абвгдежзик" {
+		for _, b := range "лмнопрсту" {
+			for _, c := range "фхцчшщэюя" {
+				word := string([]rune{a, b, c, 'а'})
+				idx.Add(word)
+				plain.WriteString(word + "\n")
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	n, err := idx.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo() = %d, written %d", n, buf.Len())
+	}
+	if buf.Len() >= plain.Len()/2 {
+		t.Errorf("index size %d is not compact compared to %d bytes of plain text", buf.Len(), plain.Len())
+	}
+}
+
+func TestReadIndexBadFormat(t *testing.T) {
+
+	for _, data := range []string{"", "not an index", magic + "garbage"} {
+		if _, err := ReadIndex(strings.NewReader(data)); !errors.Is(err, ErrBadFormat) {
+			t.Errorf("ReadIndex(%q) error = %v, want ErrBadFormat", data, err)
+		}
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+
+	idx := newTestIndex(t, Options{})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				word := string([]rune{'а' + rune(i), 'б' + rune(j%20)})
+				idx.Add(word)
+				idx.Lookup(word)
+				idx.SubAnagrams("абвгд")
+				idx.Remove(word)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if idx.Len() != 9 {
+		t.Errorf("Len() = %d after concurrent add/remove, want 9", idx.Len())
+	}
+}
@@ -0,0 +1,179 @@
+package anagram
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// формат файла индекса:
+//
+//	magic "ANAGIDX1"
+//	далее поток gzip:
+//	  флаги (1 байт, бит 0 - Options.FoldYo)
+//	  uvarint - количество групп
+//	  для каждой группы: uvarint - количество слов, для каждого слова uvarint длина и байты слова
+//
+// ключи групп не хранятся - они вычисляются заново при загрузке,
+// а сортировка групп и слов делает поток хорошо сжимаемым
+const magic = "ANAGIDX1"
+
+// флаги заголовка
+const flagFoldYo = 1 << 0
+
+// ErrBadFormat - файл не является индексом анаграмм
+var ErrBadFormat = errors.New("неверный формат файла индекса")
+
+// WriteTo сохраняет индекс в компактном формате
+func (idx *AnagramIndex) WriteTo(w io.Writer) (int64, error) {
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	cw := &countingWriter{w: w}
+	if _, err := io.WriteString(cw, magic); err != nil {
+		return cw.n, err
+	}
+
+	zw := gzip.NewWriter(cw)
+	bw := bufio.NewWriter(zw)
+
+	var flags byte
+	if idx.opts.FoldYo {
+		flags |= flagFoldYo
+	}
+	bw.WriteByte(flags)
+
+	var buf [binary.MaxVarintLen64]byte
+	writeUvarint := func(v uint64) {
+		n := binary.PutUvarint(buf[:], v)
+		bw.Write(buf[:n])
+	}
+
+	writeUvarint(uint64(len(idx.groups)))
+	for _, key := range slices.Sorted(maps.Keys(idx.groups)) {
+		words := slices.Sorted(maps.Keys(idx.groups[key]))
+		writeUvarint(uint64(len(words)))
+		for _, word := range words {
+			writeUvarint(uint64(len(word)))
+			bw.WriteString(word)
+		}
+	}
+
+	// ошибки bufio.Writer "залипают", поэтому достаточно проверить их при сбросе
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	if err := zw.Close(); err != nil {
+		return cw.n, err
+	}
+
+	return cw.n, nil
+}
+
+// ReadIndex загружает индекс, сохранённый через WriteTo
+func ReadIndex(r io.Reader) (*AnagramIndex, error) {
+
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, head); err != nil || string(head) != magic {
+		return nil, ErrBadFormat
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadFormat, err)
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+
+	flags, err := br.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadFormat, err)
+	}
+	idx := NewAnagramIndex(Options{FoldYo: flags&flagFoldYo != 0})
+
+	groups, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadFormat, err)
+	}
+	for range groups {
+		count, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadFormat, err)
+		}
+		for range count {
+			length, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrBadFormat, err)
+			}
+			// защищаемся от испорченного файла с огромной длиной
+			if length > 1<<16 {
+				return nil, fmt.Errorf("%w: слово длиной %d байт", ErrBadFormat, length)
+			}
+			word := make([]byte, length)
+			if _, err := io.ReadFull(br, word); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrBadFormat, err)
+			}
+			idx.Add(string(word))
+		}
+	}
+
+	return idx, nil
+}
+
+// SaveFile атомарно сохраняет индекс в файл (через временный файл и переименование)
+func (idx *AnagramIndex) SaveFile(path string) error {
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла индекса: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // после успешного переименования удалять уже нечего
+
+	if _, err := idx.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ошибка записи индекса: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ошибка записи индекса: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("ошибка записи индекса: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile загружает индекс из файла
+func LoadFile(path string) (*AnagramIndex, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла индекса: %w", err)
+	}
+	defer file.Close()
+
+	return ReadIndex(bufio.NewReader(file))
+}
+
+// countingWriter считает записанные байты для WriteTo
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+
+	return n, err
+}
@@ -4,6 +4,9 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -12,14 +15,33 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
+)
+
+// cutMode - режим выборки (поля, байты или символы)
+type cutMode int
+
+const (
+	modeFields cutMode = iota // выборка полей по разделителю (флаг -f), режим по умолчанию
+	modeBytes                 // выборка байтов (флаг -b)
+	modeChars                 // выборка символов (флаг -c)
 )
 
 // Config - конфигурация фильтрации
 type Config struct {
-	fields          string // строковое представление полей (колонок), которые нужно вывести (флаг -f)
-	delimiter       string // использовать другой разделитель (символ) (флаг -d)
-	separated       bool   // только строки, содержащие разделитель (флаг -s)
-	selectedColumns []int  // разобранные номера столбцов для вывода
+	fields             string  // строковое представление полей (колонок), которые нужно вывести (флаг -f)
+	bytesList          string  // строковое представление позиций байтов (флаг -b)
+	charsList          string  // строковое представление позиций символов (флаг -c)
+	delimiter          string  // использовать другой разделитель (символ) (флаг -d)
+	outputDelimiter    string  // разделитель при выводе (флаг --output-delimiter)
+	outputDelimiterSet bool    // указан ли флаг --output-delimiter явно
+	separated          bool    // только строки, содержащие разделитель (флаг -s)
+	complement         bool    // инвертировать выборку (флаг --complement)
+	zeroTerminated     bool    // строки завершаются NUL, а не переводом строки (флаг -z)
+	csvMode            bool    // учитывать кавычки CSV при разбиении на поля (флаг --csv)
+	mode               cutMode // выбранный режим
+	selectedColumns    []int   // разобранные номера столбцов (байтов, символов) для вывода
+	openFrom           int     // начало открытого диапазона "N-" (0, если такого диапазона нет)
 }
 
 // parseFlags парсит флаги командной строки
@@ -29,19 +51,32 @@ func parseFlags() Config {
 
 	// устанавливаем сообщение об использовании на случай ошибки в написании флагов при запуске программы
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Используйте: cut [-f fields] [-d delimiter] [-s] [file]\n")
+		fmt.Fprintf(os.Stderr, "Используйте: cut (-f fields | -b list | -c list) [-d delimiter] [-s] [--complement] [--output-delimiter string] [-z] [--csv] [file]\n")
 		fmt.Fprintf(os.Stderr, "Флаги:\n")
 		flag.PrintDefaults()
 	}
 
-	flag.StringVar(&config.fields, "f", "", "select only these fields (e.g., 1,3-5)")           // по умолчанию все столбцы
-	flag.StringVar(&config.delimiter, "d", "\t", "use specified delimiter instead of TAB")      // разделитель по умолчанию (табуляция)
-	flag.BoolVar(&config.separated, "s", false, "do not print lines not containing delimiters") // только строки, содержащие разделитель
+	flag.StringVar(&config.fields, "f", "", "select only these fields (e.g., 1,3-5)")                           // по умолчанию все столбцы
+	flag.StringVar(&config.bytesList, "b", "", "select only these bytes, multibyte characters are not split")   // выборка байтов
+	flag.StringVar(&config.charsList, "c", "", "select only these characters")                                  // выборка символов
+	flag.StringVar(&config.delimiter, "d", "\t", "use specified delimiter instead of TAB")                      // разделитель по умолчанию (табуляция)
+	flag.BoolVar(&config.separated, "s", false, "do not print lines not containing delimiters")                 // только строки, содержащие разделитель
+	flag.BoolVar(&config.complement, "complement", false, "complement the set of selected fields or positions") // инверсия выборки
+	flag.StringVar(&config.outputDelimiter, "output-delimiter", "", "use string as the output delimiter")       // разделитель при выводе
+	flag.BoolVar(&config.zeroTerminated, "z", false, "line delimiter is NUL, not newline")                      // строки, завершённые NUL
+	flag.BoolVar(&config.csvMode, "csv", false, "parse fields as CSV (quoted fields may contain delimiters and newlines)")
 
 	flag.Parse() // парсим флаги из командной строки (Must be called after all flags are defined and before flags are accessed by the program)
 
+	// пустой --output-delimiter допустим, поэтому отдельно запоминаем, был ли флаг указан
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "output-delimiter" {
+			config.outputDelimiterSet = true
+		}
+	})
+
 	// если вообще ничего не указано при запуске
-	if config.fields == "" && len(flag.Args()) == 0 {
+	if config.fields == "" && config.bytesList == "" && config.charsList == "" && len(flag.Args()) == 0 {
 		flag.Usage()
 		os.Exit(1)
 	}
@@ -49,15 +84,75 @@ func parseFlags() Config {
 	return config
 }
 
+// validateConfig проверяет совместимость флагов, определяет режим и разбирает список позиций
+func validateConfig(config *Config) error {
+
+	// считаем, сколько режимов указано одновременно
+	list := config.fields
+	modes := 0
+	if config.fields != "" {
+		modes++
+	}
+	if config.bytesList != "" {
+		config.mode = modeBytes
+		list = config.bytesList
+		modes++
+	}
+	if config.charsList != "" {
+		config.mode = modeChars
+		list = config.charsList
+		modes++
+	}
+	if modes > 1 {
+		return errors.New("можно указать только один из флагов -b, -c, -f")
+	}
+
+	// разделитель - ровно один символ (руна)
+	if utf8.RuneCountInString(config.delimiter) != 1 {
+		return fmt.Errorf("разделитель должен быть одним символом: %q", config.delimiter)
+	}
+
+	// флаги, имеющие смысл только для полей
+	if config.mode != modeFields {
+		if config.separated {
+			return errors.New("флаг -s используется только вместе с -f")
+		}
+		if config.csvMode {
+			return errors.New("флаг --csv используется только вместе с -f")
+		}
+	}
+
+	if config.csvMode {
+		if config.zeroTerminated {
+			return errors.New("флаги --csv и -z несовместимы")
+		}
+		// csv.Writer умеет писать только односимвольный разделитель
+		if config.outputDelimiterSet && utf8.RuneCountInString(config.outputDelimiter) != 1 {
+			return fmt.Errorf("в режиме --csv разделитель вывода должен быть одним символом: %q", config.outputDelimiter)
+		}
+	}
+
+	selectedColumns, openFrom, err := parseFields(list)
+	if err != nil {
+		return err
+	}
+	config.selectedColumns = selectedColumns
+	config.openFrom = openFrom
+
+	return nil
+}
+
 // parseFields парсит строку с номерами полей и возвращает отсортированный список уникальных номеров
-func parseFields(fieldsStr string) ([]int, error) {
+// и начало открытого диапазона вида "N-" (0, если такого нет)
+func parseFields(fieldsStr string) ([]int, int, error) {
 
 	if fieldsStr == "" {
 		// если поля не указаны, просто возвращаем пустой слайс
-		return []int{}, nil
+		return []int{}, 0, nil
 	}
 
 	uniqueFields := make(map[int]struct{}) // номера столбцов
+	openFrom := 0                          // начало открытого диапазона
 	parts := strings.Split(fieldsStr, ",") // для начала смотрим перечисление через запятую
 
 	for _, part := range parts {
@@ -72,24 +167,44 @@ func parseFields(fieldsStr string) ([]int, error) {
 
 			// если возле "-" не два числа, считаем это неправильным
 			if len(rangeParts) != 2 {
-				return nil, fmt.Errorf("неверный формат диапазона: %s", part)
+				return nil, 0, fmt.Errorf("неверный формат диапазона: %s", part)
+			}
+
+			startStr := strings.TrimSpace(rangeParts[0])
+			endStr := strings.TrimSpace(rangeParts[1])
+
+			// с обеих сторон пусто ("-") - это не диапазон
+			if startStr == "" && endStr == "" {
+				return nil, 0, fmt.Errorf("неверный формат диапазона: %s", part)
+			}
+
+			// получаем первое число и проверяем на адекватность ("-N" означает "1-N")
+			start := 1
+			if startStr != "" {
+				var err error
+				start, err = strconv.Atoi(startStr)
+				if err != nil || start < 1 {
+					return nil, 0, fmt.Errorf("неверный номер поля: %s", rangeParts[0])
+				}
 			}
 
-			// получаем первое число и проверяем на адекватность
-			start, err := strconv.Atoi(strings.TrimSpace(rangeParts[0]))
-			if err != nil || start < 1 {
-				return nil, fmt.Errorf("неверный номер поля: %s", rangeParts[0])
+			// "N-" означает "с N-го до конца строки"
+			if endStr == "" {
+				if openFrom == 0 || start < openFrom {
+					openFrom = start
+				}
+				continue
 			}
 
 			// получаем второе число и проверяем на адекватность
-			end, err := strconv.Atoi(strings.TrimSpace(rangeParts[1]))
+			end, err := strconv.Atoi(endStr)
 			if err != nil || end < 1 {
-				return nil, fmt.Errorf("неверный номер поля: %s", rangeParts[1])
+				return nil, 0, fmt.Errorf("неверный номер поля: %s", rangeParts[1])
 			}
 
 			// проверяем на адекватность вводящего команду
 			if start > end {
-				return nil, fmt.Errorf("неверный диапазон: начало %d больше конца %d", start, end)
+				return nil, 0, fmt.Errorf("неверный диапазон: начало %d больше конца %d", start, end)
 			}
 
 			// переносим весь диапазон в мапу с номерами столбцов
@@ -101,21 +216,57 @@ func parseFields(fieldsStr string) ([]int, error) {
 			// иначе, если поле указано через запятую, обрабатываем отдельное поле
 			field, err := strconv.Atoi(part)
 			if err != nil || field < 1 {
-				return nil, fmt.Errorf("неверный номер поля: %s", part)
+				return nil, 0, fmt.Errorf("неверный номер поля: %s", part)
 			}
 			uniqueFields[field] = struct{}{}
 		}
 	}
 
+	// номера, покрытые открытым диапазоном, хранить отдельно не нужно
+	if openFrom > 0 {
+		maps.DeleteFunc(uniqueFields, func(k int, _ struct{}) bool { return k >= openFrom })
+	}
+
 	// собираем ключи из мапы номеров столбцов и сортируем
 	result := slices.Sorted(maps.Keys(uniqueFields))
 
-	return result, nil
+	return result, openFrom, nil
+}
+
+// isSelected сообщает, нужно ли выводить позицию pos (нумерация с 1) с учётом --complement
+func isSelected(pos int, config Config) bool {
+
+	_, found := slices.BinarySearch(config.selectedColumns, pos)
+	inList := found || (config.openFrom > 0 && pos >= config.openFrom)
+
+	return inList != config.complement
+}
+
+// hasSelection сообщает, задана ли вообще какая-либо выборка
+func hasSelection(config Config) bool {
+	return len(config.selectedColumns) > 0 || config.openFrom > 0
+}
+
+// fieldsOutputDelimiter возвращает разделитель для вывода полей (по умолчанию - входной разделитель)
+func fieldsOutputDelimiter(config Config) string {
+
+	if config.outputDelimiterSet {
+		return config.outputDelimiter
+	}
+
+	return config.delimiter
 }
 
 // processLine обрабатывает одну строку
 func processLine(line string, config Config) (string, bool) {
 
+	switch config.mode {
+	case modeBytes:
+		return cutRunes(line, true, config), true
+	case modeChars:
+		return cutRunes(line, false, config), true
+	}
+
 	// если установлен флаг -s и строка не содержит разделитель, пропускаем её
 	if config.separated && !strings.Contains(line, config.delimiter) {
 		return "", false
@@ -124,34 +275,119 @@ func processLine(line string, config Config) (string, bool) {
 	// разбиваем строку на поля
 	fields := strings.Split(line, config.delimiter)
 
+	output, hasValidFields := selectFields(fields, config)
+
+	// если ни одно из указанных полей не существует в строке, возвращаем пустую строку
+	if !hasValidFields {
+		return "", true
+	}
+
 	// если нет выбранных полей, возвращаем исходную строку
-	if len(config.selectedColumns) == 0 {
+	if !hasSelection(config) {
 		return line, true
 	}
 
+	// собираем строку обратно с разделителем вывода
+	return strings.Join(output, fieldsOutputDelimiter(config)), true
+}
+
+// selectFields отбирает поля согласно выборке, второе значение - нашлось ли хотя бы одно поле
+func selectFields(fields []string, config Config) ([]string, bool) {
+
+	// если нет выбранных полей, отдаём всё как есть
+	if !hasSelection(config) {
+		return fields, true
+	}
+
 	// собираем только выбранные поля
 	var outputFields []string
-	hasValidFields := false
-	for _, col := range config.selectedColumns {
+	for i, field := range fields {
 		// нумерация полей начинается с 1, а индексы с 0
-		if col-1 < len(fields) {
-			outputFields = append(outputFields, fields[col-1])
-			hasValidFields = true
+		if isSelected(i+1, config) {
+			outputFields = append(outputFields, field)
 		}
-		// если поле выходит за границы, просто игнорируем (не добавляем)
+		// поля за границами выборки просто игнорируем (не добавляем)
 	}
 
-	// если ни одно из указанных полей не существует в строке, возвращаем пустую строку
-	if !hasValidFields {
-		return "", true
+	return outputFields, len(outputFields) > 0
+}
+
+// cutRunes выбирает из строки байты (byteMode) или символы, не разрезая многобайтовые символы UTF-8:
+// в режиме байтов символ выводится целиком, если выбран его первый байт.
+// Между несмежными выбранными участками вставляется --output-delimiter (если он указан)
+func cutRunes(line string, byteMode bool, config Config) string {
+
+	// без выборки строка выводится целиком
+	if !hasSelection(config) {
+		return line
 	}
 
-	// собираем строку обратно с тем же разделителем
-	return strings.Join(outputFields, config.delimiter), true
+	var sb strings.Builder
+	pos := 0              // номер текущей позиции (байта или символа), с 1
+	prevSelected := false // был ли выбран предыдущий символ
+	wroteAny := false     // выведено ли уже что-нибудь
+	for i := 0; i < len(line); {
+
+		_, size := utf8.DecodeRuneInString(line[i:])
+
+		if byteMode {
+			pos = i + 1
+		} else {
+			pos++
+		}
+
+		if isSelected(pos, config) {
+			// начало нового участка - разделяем его с предыдущим
+			if !prevSelected && wroteAny && config.outputDelimiterSet {
+				sb.WriteString(config.outputDelimiter)
+			}
+			// пишем исходные байты (а не руну), чтобы не искажать некорректный UTF-8
+			sb.WriteString(line[i : i+size])
+			prevSelected = true
+			wroteAny = true
+		} else {
+			prevSelected = false
+		}
+
+		i += size
+	}
+
+	return sb.String()
+}
+
+// splitOn возвращает функцию разбиения для bufio.Scanner по заданному байту-терминатору
+func splitOn(sep byte) bufio.SplitFunc {
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		if i := bytes.IndexByte(data, sep); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+
+		// последняя запись без терминатора
+		if atEOF {
+			return len(data), data, nil
+		}
+
+		// запрашиваем больше данных
+		return 0, nil, nil
+	}
 }
 
-// processInput обрабатывает входные данные
-func processInput(config Config, input io.Reader) error {
+// processInput обрабатывает входные данные и пишет результат в output
+func processInput(config Config, input io.Reader, output io.Writer) error {
+
+	// CSV-записи могут занимать несколько строк, поэтому обрабатываются отдельно
+	if config.csvMode {
+		return processCSV(config, input, output)
+	}
+
+	// буферизуем вывод, чтобы не делать системный вызов на каждую строку
+	writer := bufio.NewWriter(output)
 
 	// создаем сканер с увеличенным буфером для обработки длинных строк
 	scanner := bufio.NewScanner(input)
@@ -161,6 +397,13 @@ func processInput(config Config, input io.Reader) error {
 	buf := make([]byte, 0, 64*1024)        // начальный буфер 64 КБ
 	scanner.Buffer(buf, maxCapacity)
 
+	// с флагом -z строки разделяются NUL
+	terminator := byte('\n')
+	if config.zeroTerminated {
+		terminator = 0
+		scanner.Split(splitOn(terminator))
+	}
+
 	// обрабатываем каждую строку
 	for scanner.Scan() {
 
@@ -169,11 +412,12 @@ func processInput(config Config, input io.Reader) error {
 		// разбиваем строку по разделителю и выбираем нужные поля
 		// output - обработанная строка для вывода
 		// nextOutput - нужно ли выводить эту строку (false если пропускаем из-за флага -s)
-		output, nextOutput := processLine(line, config)
+		out, nextOutput := processLine(line, config)
 
 		// выводим строку, если она не пустая и nextOutput == true
-		if nextOutput && output != "" {
-			fmt.Println(output)
+		if nextOutput && out != "" {
+			writer.WriteString(out)
+			writer.WriteByte(terminator)
 		}
 	}
 
@@ -182,6 +426,56 @@ func processInput(config Config, input io.Reader) error {
 		return fmt.Errorf("ошибка чтения: %v", err)
 	}
 
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("ошибка записи: %v", err)
+	}
+
+	return nil
+}
+
+// processCSV обрабатывает вход как CSV: поля в кавычках могут содержать разделитель и переводы строк
+func processCSV(config Config, input io.Reader, output io.Writer) error {
+
+	delimiter, _ := utf8.DecodeRuneInString(config.delimiter)
+	outDelimiter, _ := utf8.DecodeRuneInString(fieldsOutputDelimiter(config))
+
+	reader := csv.NewReader(input)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1 // количество полей в записях может различаться
+	reader.ReuseRecord = true
+
+	writer := csv.NewWriter(output)
+	writer.Comma = outDelimiter
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка чтения CSV: %v", err)
+		}
+
+		// запись из одного поля не содержит разделителя
+		if config.separated && len(record) < 2 {
+			continue
+		}
+
+		out, hasValidFields := selectFields(record, config)
+		if !hasValidFields {
+			continue
+		}
+
+		if err := writer.Write(out); err != nil {
+			return fmt.Errorf("ошибка записи CSV: %v", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("ошибка записи CSV: %v", err)
+	}
+
 	return nil
 }
 
@@ -189,13 +483,11 @@ func main() {
 
 	config := parseFlags()
 
-	// парсим поля для вывода
-	selectedColumns, err := parseFields(config.fields)
-	if err != nil {
+	// проверяем флаги и парсим поля для вывода
+	if err := validateConfig(&config); err != nil {
 		fmt.Fprintf(os.Stderr, "ошибка парсинга полей: %v\n", err)
 		os.Exit(1)
 	}
-	config.selectedColumns = selectedColumns
 
 	// определяем источник ввода
 	var input io.Reader
@@ -220,7 +512,7 @@ func main() {
 	}
 
 	// обрабатываем ввод
-	if err := processInput(config, input); err != nil {
+	if err := processInput(config, input, os.Stdout); err != nil {
 		fmt.Fprintf(os.Stderr, "ошибка обработки: %v\n", err)
 		os.Exit(1)
 	}
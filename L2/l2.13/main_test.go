@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -11,6 +13,7 @@ func TestParseFields(t *testing.T) {
 		name     string // название теста
 		input    string // входная строка для парсинга
 		expected []int  // ожидаемый результат
+		openFrom int    // ожидаемое начало открытого диапазона
 		wantErr  bool   // должна ли функция вернуть ошибку
 	}{
 		{
@@ -62,8 +65,46 @@ func TestParseFields(t *testing.T) {
 			wantErr:  true,
 		},
 		{
-			name:     "отрицательный номер",
+			name:     "открытый диапазон в начале",
 			input:    "-1",
+			expected: []int{1},
+			wantErr:  false,
+		},
+		{
+			name:     "открытый диапазон в начале до трёх",
+			input:    "-3,5",
+			expected: []int{1, 2, 3, 5},
+			wantErr:  false,
+		},
+		{
+			name:     "открытый диапазон в конце",
+			input:    "2,5-",
+			expected: []int{2},
+			openFrom: 5,
+			wantErr:  false,
+		},
+		{
+			name:     "номера внутри открытого диапазона поглощаются",
+			input:    "7,3-,9-,1",
+			expected: []int{1},
+			openFrom: 3,
+			wantErr:  false,
+		},
+		{
+			name:     "дефис без чисел",
+			input:    "-",
+			expected: nil,
+			wantErr:  true,
+		},
+		{
+			name:     "нулевой номер",
+			input:    "0",
+			expected: nil,
+			wantErr:  true,
+		},
+		{
+			name:     "отрицательный номер в открытом диапазоне",
+			input:    "--1",
 			expected: nil,
 			wantErr:  true,
 		},
@@ -85,7 +126,7 @@ func TestParseFields(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// вызываем тестируемую функцию
-			result, err := parseFields(tt.input)
+			result, openFrom, err := parseFields(tt.input)
 
 			// проверяем соответствие ошибки ожиданиям
 			if (err != nil) != tt.wantErr {
@@ -97,6 +138,11 @@ func TestParseFields(t *testing.T) {
 			if !slicesEqual(result, tt.expected) {
 				t.Errorf("parseFields() = %v, expected %v", result, tt.expected)
 			}
+
+			// проверяем начало открытого диапазона
+			if openFrom != tt.openFrom {
+				t.Errorf("parseFields() openFrom = %d, expected %d", openFrom, tt.openFrom)
+			}
 		})
 	}
 }
@@ -225,6 +271,279 @@ func TestProcessLine(t *testing.T) {
 	}
 }
 
+// TestProcessLineModes тестирует режимы -b, -c, --complement и --output-delimiter
+func TestProcessLineModes(t *testing.T) {
+
+	tests := []struct {
+		name           string // название теста
+		line           string // входная строка
+		config         Config // конфигурация для обработки
+		expectedOutput string // ожидаемый вывод
+	}{
+		{
+			name: "байты ASCII",
+			line: "abcdef",
+			config: Config{
+				mode:            modeBytes,
+				selectedColumns: []int{1, 2, 5},
+			},
+			expectedOutput: "abe",
+		},
+		{
+			name: "байты не разрезают многобайтовый символ",
+			line: "пример",
+			config: Config{
+				mode:            modeBytes,
+				selectedColumns: []int{1, 2, 3},
+			},
+			expectedOutput: "пр",
+		},
+		{
+			name: "байт в середине символа не выводит его",
+			line: "пример",
+			config: Config{
+				mode:            modeBytes,
+				selectedColumns: []int{2},
+			},
+			expectedOutput: "",
+		},
+		{
+			name: "символы кириллицы",
+			line: "привет, мир",
+			config: Config{
+				mode:            modeChars,
+				selectedColumns: []int{1, 2, 3},
+			},
+			expectedOutput: "при",
+		},
+		{
+			name: "символы с открытым диапазоном",
+			line: "привет, мир",
+			config: Config{
+				mode:     modeChars,
+				openFrom: 9,
+			},
+			expectedOutput: "мир",
+		},
+		{
+			name: "символы с --complement",
+			line: "abcdef",
+			config: Config{
+				mode:            modeChars,
+				selectedColumns: []int{2, 3},
+				complement:      true,
+			},
+			expectedOutput: "adef",
+		},
+		{
+			name: "символы с --output-delimiter между участками",
+			line: "abcdef",
+			config: Config{
+				mode:               modeChars,
+				selectedColumns:    []int{1, 2, 4, 6},
+				outputDelimiter:    ":",
+				outputDelimiterSet: true,
+			},
+			expectedOutput: "ab:d:f",
+		},
+		{
+			name: "поля с --complement",
+			line: "a\tb\tc\td",
+			config: Config{
+				delimiter:       "\t",
+				selectedColumns: []int{2},
+				complement:      true,
+			},
+			expectedOutput: "a\tc\td",
+		},
+		{
+			name: "поля с открытым диапазоном",
+			line: "a,b,c,d",
+			config: Config{
+				delimiter:       ",",
+				selectedColumns: []int{1},
+				openFrom:        3,
+			},
+			expectedOutput: "a,c,d",
+		},
+		{
+			name: "поля с --output-delimiter",
+			line: "a,b,c",
+			config: Config{
+				delimiter:          ",",
+				selectedColumns:    []int{1, 3},
+				outputDelimiter:    " | ",
+				outputDelimiterSet: true,
+			},
+			expectedOutput: "a | c",
+		},
+		{
+			name: "поля с пустым --output-delimiter",
+			line: "a,b,c",
+			config: Config{
+				delimiter:          ",",
+				selectedColumns:    []int{1, 2},
+				outputDelimiterSet: true,
+			},
+			expectedOutput: "ab",
+		},
+		{
+			name: "разделитель кириллицей",
+			line: "одинЖдваЖтри",
+			config: Config{
+				delimiter:       "Ж",
+				selectedColumns: []int{2},
+			},
+			expectedOutput: "два",
+		},
+	}
+
+	// запускаем каждый тест
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, _ := processLine(tt.line, tt.config)
+			if output != tt.expectedOutput {
+				t.Errorf("processLine() output = %q, expected %q", output, tt.expectedOutput)
+			}
+		})
+	}
+}
+
+// TestValidateConfig тестирует проверку совместимости флагов
+func TestValidateConfig(t *testing.T) {
+
+	tests := []struct {
+		name         string  // название теста
+		config       Config  // конфигурация до проверки
+		expectedMode cutMode // ожидаемый режим
+		wantErr      bool    // должна ли функция вернуть ошибку
+	}{
+		{"поля", Config{fields: "1", delimiter: "\t"}, modeFields, false},
+		{"байты", Config{bytesList: "1-3", delimiter: "\t"}, modeBytes, false},
+		{"символы", Config{charsList: "2-", delimiter: "\t"}, modeChars, false},
+		{"два режима сразу", Config{fields: "1", charsList: "1", delimiter: "\t"}, modeFields, true},
+		{"разделитель из двух символов", Config{fields: "1", delimiter: "ab"}, modeFields, true},
+		{"разделитель-руна", Config{fields: "1", delimiter: "ё"}, modeFields, false},
+		{"-s с байтами", Config{bytesList: "1", delimiter: "\t", separated: true}, modeBytes, true},
+		{"--csv с символами", Config{charsList: "1", delimiter: "\t", csvMode: true}, modeChars, true},
+		{"--csv с -z", Config{fields: "1", delimiter: ",", csvMode: true, zeroTerminated: true}, modeFields, true},
+		{"--csv с длинным разделителем вывода", Config{fields: "1", delimiter: ",", csvMode: true, outputDelimiter: "::", outputDelimiterSet: true}, modeFields, true},
+		{"неверный список", Config{bytesList: "x", delimiter: "\t"}, modeBytes, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			err := validateConfig(&config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && config.mode != tt.expectedMode {
+				t.Errorf("validateConfig() mode = %v, expected %v", config.mode, tt.expectedMode)
+			}
+		})
+	}
+}
+
+// TestProcessInput тестирует обработку потока целиком, включая -z и --csv
+func TestProcessInput(t *testing.T) {
+
+	tests := []struct {
+		name     string // название теста
+		input    string // входные данные
+		config   Config // конфигурация для обработки
+		expected string // ожидаемый вывод
+	}{
+		{
+			name:  "строки через перевод строки",
+			input: "a\tb\nc\td\n",
+			config: Config{
+				delimiter:       "\t",
+				selectedColumns: []int{2},
+			},
+			expected: "b\nd\n",
+		},
+		{
+			name:  "строки, завершённые NUL",
+			input: "a,b\nx\x00c,d\x00e,f",
+			config: Config{
+				delimiter:       ",",
+				selectedColumns: []int{2},
+				zeroTerminated:  true,
+			},
+			expected: "b\nx\x00d\x00f\x00",
+		},
+		{
+			name:  "CSV с разделителем внутри кавычек",
+			input: "name,city\n\"Smith, John\",London\n",
+			config: Config{
+				delimiter:       ",",
+				selectedColumns: []int{1},
+				csvMode:         true,
+			},
+			expected: "name\n\"Smith, John\"\n",
+		},
+		{
+			name:  "CSV с переводом строки внутри кавычек",
+			input: "id,note\n1,\"first\nsecond\"\n2,plain\n",
+			config: Config{
+				delimiter:       ",",
+				selectedColumns: []int{2},
+				csvMode:         true,
+			},
+			expected: "note\n\"first\nsecond\"\nplain\n",
+		},
+		{
+			name:  "CSV с --complement и -s",
+			input: "a;b;c\nsingle\n\"x;y\";z;w\n",
+			config: Config{
+				delimiter:       ";",
+				selectedColumns: []int{2},
+				complement:      true,
+				separated:       true,
+				csvMode:         true,
+			},
+			expected: "a;c\n\"x;y\";w\n",
+		},
+		{
+			name:  "CSV с другим разделителем вывода",
+			input: "a,\"b\tc\",d\n",
+			config: Config{
+				delimiter:          ",",
+				openFrom:           1,
+				outputDelimiter:    "\t",
+				outputDelimiterSet: true,
+				csvMode:            true,
+			},
+			expected: "a\t\"b\tc\"\td\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := processInput(tt.config, strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("processInput() error = %v", err)
+			}
+			if out.String() != tt.expected {
+				t.Errorf("processInput() = %q, expected %q", out.String(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestProcessInputCSVError проверяет, что незакрытая кавычка в режиме --csv - это ошибка
+func TestProcessInputCSVError(t *testing.T) {
+
+	config := Config{delimiter: ",", selectedColumns: []int{1}, csvMode: true}
+
+	var out bytes.Buffer
+	if err := processInput(config, strings.NewReader("\"unterminated,a\n"), &out); err == nil {
+		t.Error("processInput() expected error for unterminated quote")
+	}
+}
+
 // slicesEqual вспомогательная функция для сравнения двух слайсов int
 func slicesEqual(a, b []int) bool {
 
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// complete дополняет слово перед курсором для редактора строки:
+// в позиции команды - встроенные команды, функции и программы из $PATH, иначе - пути к файлам
+func (shell *Shell) complete(line []rune, pos int) (int, []string) {
+
+	start := pos
+	for start > 0 && !isWordBreak(line, start-1) {
+		start--
+	}
+	word := unescapeWord(string(line[start:pos]))
+
+	if !strings.Contains(word, "/") && isCommandPosition(string(line[:start])) {
+		return start, shell.completeCommand(word)
+	}
+
+	return start, shell.completeFile(word)
+}
+
+// isWordBreak сообщает, что символ line[i] разделяет слова (экранированный пробел - часть слова)
+func isWordBreak(line []rune, i int) bool {
+
+	if !strings.ContainsRune(" \t;|&()<>", line[i]) {
+		return false
+	}
+
+	return !(line[i] == ' ' && i > 0 && line[i-1] == '\\')
+}
+
+// isCommandPosition сообщает, что слово после текста before - имя команды
+func isCommandPosition(before string) bool {
+
+	before = strings.TrimRight(before, " \t")
+	if before == "" {
+		return true
+	}
+
+	return strings.ContainsRune(";|&(!{", rune(before[len(before)-1]))
+}
+
+// completeCommand ищет встроенные команды, функции и исполняемые файлы из $PATH, начинающиеся с prefix
+func (shell *Shell) completeCommand(prefix string) []string {
+
+	seen := make(map[string]bool)
+	for name := range builtins {
+		if strings.HasPrefix(name, prefix) {
+			seen[name] = true
+		}
+	}
+	for name := range shell.funcs {
+		if strings.HasPrefix(name, prefix) {
+			seen[name] = true
+		}
+	}
+
+	path, _ := shell.getVar("PATH")
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		dir = shell.resolvePath(dir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if seen[name] || !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			info, err := os.Stat(filepath.Join(dir, name))
+			if err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+				seen[name] = true
+			}
+		}
+	}
+
+	result := make([]string, 0, len(seen))
+	for name := range seen {
+		result = append(result, escapeWord(name))
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// completeFile ищет файлы по началу пути word; к директориям добавляется /
+func (shell *Shell) completeFile(word string) []string {
+
+	dir, base := "", word
+	if i := strings.LastIndexByte(word, '/'); i >= 0 {
+		dir, base = word[:i+1], word[i+1:]
+	}
+
+	listDir := dir
+	if home, rest := expandTilde(shell, dir); home != "" {
+		listDir = home + rest
+	}
+	if listDir == "" {
+		listDir = "."
+	}
+	listDir = shell.resolvePath(listDir)
+
+	entries, err := os.ReadDir(listDir)
+	if err != nil {
+		return nil
+	}
+
+	var result []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		candidate := escapeWord(dir + name)
+		if isDir(filepath.Join(listDir, name)) {
+			candidate += "/"
+		}
+		result = append(result, candidate)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// escapeWord экранирует символы, которые шелл иначе понял бы как разделители, кавычки или шаблоны
+func escapeWord(s string) string {
+
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(" \t'\"\\$`|&;<>()*?[]#!{}", r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// unescapeWord убирает экранирование из набираемого слова
+func unescapeWord(s string) string {
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+
+	return sb.String()
+}
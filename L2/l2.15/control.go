@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"l2.15/pkg/parser"
+)
+
+// controlFlow - break, continue или return, который прерывает выполнение списков команд
+// до цикла или функции
+type controlFlow int
+
+const (
+	flowNone controlFlow = iota
+	flowBreak
+	flowContinue
+	flowReturn
+)
+
+// maxCallDepth ограничивает вложенность вызовов функций и source (бесконечная рекурсия)
+const maxCallDepth = 1000
+
+// unwinding сообщает, что выполнение текущего списка нужно прервать: exit, break, continue или return
+func (shell *Shell) unwinding() bool {
+	return shell.exiting || shell.flow != flowNone
+}
+
+// withRedirects выполняет составную команду с её редиректами
+func withRedirects(shell *Shell, redirects []parser.Redirect, stdio Stdio, run func(Stdio) int) int {
+
+	stdio, closeFiles, err := applyRedirects(shell, redirects, stdio)
+	defer closeFiles()
+	if err != nil {
+		fmt.Fprintf(stdio.Err, "mysh: %v\n", err)
+		return 1
+	}
+
+	return run(stdio)
+}
+
+// executeIf выполняет первую ветку, условие которой завершилось успешно (или else).
+// Если ни одна ветка не выполнялась, статус 0
+func executeIf(shell *Shell, n *parser.If, stdio Stdio) int {
+
+	for _, clause := range n.Clauses {
+		status := execute(shell, clause.Cond, stdio)
+		if shell.unwinding() {
+			return status
+		}
+		if status == 0 {
+			return execute(shell, clause.Body, stdio)
+		}
+	}
+
+	if n.Else != nil {
+		return execute(shell, n.Else, stdio)
+	}
+
+	return 0
+}
+
+// executeWhile выполняет тело, пока условие успешно (для until - пока неуспешно).
+// Статус - статус последнего выполнения тела, 0 - если тело не выполнялось
+func executeWhile(shell *Shell, n *parser.While, stdio Stdio) int {
+
+	shell.loopDepth++
+	defer func() { shell.loopDepth-- }()
+
+	status := 0
+	for {
+		cond := execute(shell, n.Cond, stdio)
+		if shell.unwinding() {
+			if shell.endIteration() {
+				return cond
+			}
+			continue
+		}
+		if (cond == 0) == n.Until {
+			return status
+		}
+
+		status = execute(shell, n.Body, stdio)
+		if shell.unwinding() && shell.endIteration() {
+			return status
+		}
+	}
+}
+
+// executeFor выполняет тело для каждого слова (без in - для каждого позиционного параметра)
+func executeFor(shell *Shell, n *parser.For, stdio Stdio) int {
+
+	values := shell.args
+	if n.HasIn {
+		values = expandArgs(shell, n.Words, stdio)
+	}
+
+	shell.loopDepth++
+	defer func() { shell.loopDepth-- }()
+
+	status := 0
+	for _, value := range values {
+		shell.setVar(n.Var, value)
+		status = execute(shell, n.Body, stdio)
+		if shell.unwinding() && shell.endIteration() {
+			break
+		}
+	}
+
+	return status
+}
+
+// endIteration обрабатывает break и continue после итерации цикла. Возвращает true,
+// если цикл нужно покинуть (break, continue для внешнего цикла, return или exit)
+func (shell *Shell) endIteration() bool {
+
+	switch shell.flow {
+	case flowBreak:
+		shell.flowLevels--
+		if shell.flowLevels == 0 {
+			shell.flow = flowNone
+		}
+		return true
+
+	case flowContinue:
+		shell.flowLevels--
+		if shell.flowLevels == 0 {
+			shell.flow = flowNone
+			return false
+		}
+		return true
+	}
+
+	return true
+}
+
+// callFunction выполняет функцию; аргументы args[1:] на время вызова становятся позиционными параметрами
+func (shell *Shell) callFunction(fn *parser.FuncDef, args []string, stdio Stdio) int {
+
+	if shell.callDepth >= maxCallDepth {
+		fmt.Fprintf(stdio.Err, "mysh: %s: слишком глубокая рекурсия\n", args[0])
+		return 1
+	}
+
+	// break и continue внутри функции не действуют на циклы вызывающего кода
+	savedArgs, savedLoops := shell.args, shell.loopDepth
+	shell.args, shell.loopDepth = args[1:], 0
+	shell.callDepth++
+
+	status := execute(shell, fn.Body, stdio)
+
+	shell.args, shell.loopDepth = savedArgs, savedLoops
+	shell.callDepth--
+	if shell.flow == flowReturn {
+		shell.flow = flowNone
+	}
+
+	return status
+}
+
+// встроенные команды управления выполнением
+
+// loopLevels разбирает необязательное число циклов для break и continue
+func loopLevels(shell *Shell, args []string) (int, error) {
+
+	if shell.loopDepth == 0 {
+		return 0, fmt.Errorf("%s: можно использовать только в цикле", args[0])
+	}
+	if len(args) < 2 {
+		return 1, nil
+	}
+
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s: %s: требуется положительное число", args[0], args[1])
+	}
+
+	return min(n, shell.loopDepth), nil
+}
+
+// breakCom - break [N]: выход из N вложенных циклов
+func breakCom(shell *Shell, args []string, stdio Stdio) error {
+
+	n, err := loopLevels(shell, args)
+	if err != nil {
+		return err
+	}
+	shell.flow, shell.flowLevels = flowBreak, n
+
+	return nil
+}
+
+// continueCom - continue [N]: переход к следующей итерации N-го объемлющего цикла
+func continueCom(shell *Shell, args []string, stdio Stdio) error {
+
+	n, err := loopLevels(shell, args)
+	if err != nil {
+		return err
+	}
+	shell.flow, shell.flowLevels = flowContinue, n
+
+	return nil
+}
+
+// returnCom - return [N]: выход из функции или файла source со статусом N (по умолчанию - статус последней команды)
+func returnCom(shell *Shell, args []string, stdio Stdio) error {
+
+	if shell.callDepth == 0 {
+		return fmt.Errorf("return: можно использовать только в функции или файле source")
+	}
+
+	status := shell.lastStatus
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("return: %s: требуется числовой аргумент", args[1])
+		}
+		status = n & 0xff
+	}
+	shell.flow = flowReturn
+
+	if status != 0 {
+		return exitStatusError(status)
+	}
+
+	return nil
+}
+
+// shiftCom - shift [N]: сдвигает позиционные параметры на N (по умолчанию 1)
+func shiftCom(shell *Shell, args []string, stdio Stdio) error {
+
+	n := 1
+	if len(args) > 1 {
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 0 {
+			return fmt.Errorf("shift: %s: требуется неотрицательное число", args[1])
+		}
+	}
+	if n > len(shell.args) {
+		return exitStatusError(1)
+	}
+	shell.args = shell.args[n:]
+
+	return nil
+}
+
+// source выполняет команды, а значит, обращается к builtins - регистрируем её отдельно,
+// иначе получится цикл инициализации
+func init() {
+	builtins["source"] = sourceCom
+	builtins["."] = sourceCom
+}
+
+// sourceCom - source файл [аргументы] (или . файл): выполняет команды файла в текущем шелле.
+// Аргументы на время выполнения становятся позиционными параметрами
+func sourceCom(shell *Shell, args []string, stdio Stdio) error {
+
+	if len(args) < 2 {
+		return fmt.Errorf("%s: требуется имя файла", args[0])
+	}
+	if shell.callDepth >= maxCallDepth {
+		return fmt.Errorf("%s: %s: слишком глубокая вложенность", args[0], args[1])
+	}
+
+	data, err := os.ReadFile(shell.resolvePath(args[1]))
+	if err != nil {
+		return fmt.Errorf("%s: %s: %v", args[0], args[1], unwrapPathError(err))
+	}
+	list, err := parser.Parse(string(data))
+	if err != nil {
+		fmt.Fprintf(stdio.Err, "mysh: %s: %v\n", args[1], err)
+		return exitStatusError(2)
+	}
+
+	savedArgs := shell.args
+	if len(args) > 2 {
+		shell.args = args[2:]
+	}
+	shell.callDepth++
+
+	status := execute(shell, list, stdio)
+
+	shell.callDepth--
+	if len(args) > 2 {
+		shell.args = savedArgs
+	}
+	if shell.flow == flowReturn {
+		shell.flow = flowNone
+	}
+
+	if status != 0 {
+		return exitStatusError(status)
+	}
+
+	return nil
+}
@@ -0,0 +1,601 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"l2.15/pkg/parser"
+)
+
+// Stdio - стандартные потоки команды
+type Stdio struct {
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
+}
+
+// stdStreams возвращает стандартные потоки процесса шелла
+func stdStreams() Stdio {
+	return Stdio{In: os.Stdin, Out: os.Stdout, Err: os.Stderr}
+}
+
+// syncBuffer - буфер, в который можно писать из нескольких горутин (вывод подстановки $(...)
+// собирается со всех команд конвейера)
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// execute выполняет узел синтаксического дерева и возвращает статус завершения
+func execute(shell *Shell, node parser.Node, stdio Stdio) int {
+
+	// после exit больше ничего не выполняем
+	if shell.exiting {
+		return shell.exitCode
+	}
+
+	switch n := node.(type) {
+	case *parser.List:
+		status := 0
+		for _, item := range n.Items {
+			status = execute(shell, item, stdio)
+			shell.lastStatus = status
+			if shell.exiting {
+				return shell.exitCode
+			}
+			// break, continue и return прерывают список до цикла или функции
+			if shell.flow != flowNone {
+				return status
+			}
+		}
+		return status
+
+	case *parser.AndOr:
+		status := execute(shell, n.Left, stdio)
+		shell.lastStatus = status
+		if shell.exiting {
+			return shell.exitCode
+		}
+		if shell.flow != flowNone {
+			return status
+		}
+		// && выполняет правую часть после успеха, || - после ошибки
+		if (n.Op == "&&") == (status == 0) {
+			status = execute(shell, n.Right, stdio)
+		}
+		return status
+
+	case *parser.Pipeline:
+		return executePipeline(shell, n, stdio)
+
+	case *parser.Background:
+		return shell.runBackground(n.Node, stdio)
+
+	case *parser.SimpleCommand:
+		return executeSimpleCommand(shell, n, stdio)
+
+	case *parser.Subshell:
+		stdio, closeFiles, err := applyRedirects(shell, n.Redirects, stdio)
+		defer closeFiles()
+		if err != nil {
+			fmt.Fprintf(stdio.Err, "mysh: %v\n", err)
+			return 1
+		}
+		sub := shell.subshell()
+		status := execute(sub, n.Body, stdio)
+		if sub.exiting {
+			status = sub.exitCode
+		}
+		return status
+
+	case *parser.Group:
+		stdio, closeFiles, err := applyRedirects(shell, n.Redirects, stdio)
+		defer closeFiles()
+		if err != nil {
+			fmt.Fprintf(stdio.Err, "mysh: %v\n", err)
+			return 1
+		}
+		return execute(shell, n.Body, stdio)
+
+	case *parser.If:
+		return withRedirects(shell, n.Redirects, stdio, func(stdio Stdio) int { return executeIf(shell, n, stdio) })
+
+	case *parser.While:
+		return withRedirects(shell, n.Redirects, stdio, func(stdio Stdio) int { return executeWhile(shell, n, stdio) })
+
+	case *parser.For:
+		return withRedirects(shell, n.Redirects, stdio, func(stdio Stdio) int { return executeFor(shell, n, stdio) })
+
+	case *parser.FuncDef:
+		shell.funcs[n.Name] = n
+		return 0
+	}
+
+	fmt.Fprintf(stdio.Err, "mysh: неизвестный узел %T\n", node)
+	return 1
+}
+
+// executeSimpleCommand выполняет единичную команду
+func executeSimpleCommand(shell *Shell, cmd *parser.SimpleCommand, stdio Stdio) int {
+
+	// раскрываем присваивания и аргументы: подстановки, разбиение на поля, шаблоны, снятие кавычек
+	assigns := expandAssigns(shell, cmd.Assigns, stdio)
+	args := expandArgs(shell, cmd.Args, stdio)
+
+	// присваивания без команды задают переменные шелла; статус - статус последней подстановки $(...)
+	if len(args) == 0 && len(assigns) > 0 {
+		status := 0
+		if hasCommandSubstitution(cmd) {
+			status = shell.lastStatus
+		}
+		if status := executeCommandArgs(shell, nil, nil, cmd.Redirects, stdio); status != 0 {
+			return status
+		}
+		for _, assign := range assigns {
+			name, value, _ := strings.Cut(assign, "=")
+			shell.setVar(name, value)
+		}
+		return status
+	}
+
+	return executeCommandArgs(shell, args, assigns, cmd.Redirects, stdio)
+}
+
+// exitStatusError - встроенная команда завершилась с ненулевым статусом, сообщение уже выведено
+type exitStatusError int
+
+func (e exitStatusError) Error() string {
+	return "статус " + strconv.Itoa(int(e))
+}
+
+// executeCommandArgs выполняет команду с уже раскрытыми аргументами.
+// assigns (NAME=value) действуют только на время выполнения команды
+func executeCommandArgs(shell *Shell, args, assigns []string, redirects []parser.Redirect, stdio Stdio) int {
+
+	// открываем файлы редиректов
+	stdio, closeFiles, err := applyRedirects(shell, redirects, stdio)
+	defer closeFiles()
+	if err != nil {
+		fmt.Fprintf(stdio.Err, "mysh: %v\n", err)
+		return 1
+	}
+
+	// если команда пустая (только редиректы), ничего не делаем
+	if len(args) == 0 {
+		return 0
+	}
+
+	// функции важнее встроенных команд: их можно переопределить
+	if fn, ok := shell.funcs[args[0]]; ok {
+		restore := shell.assignTemporarily(assigns)
+		status := shell.callFunction(fn, args, stdio)
+		restore()
+		return status
+	}
+
+	// проверяем, является ли команда встроенной (cd, echo ...)
+	if builtin, ok := builtins[args[0]]; ok {
+		restore := shell.assignTemporarily(assigns)
+		err := builtin(shell, args, stdio)
+		restore()
+
+		var statusErr exitStatusError
+		if errors.As(err, &statusErr) {
+			return int(statusErr)
+		}
+		if err != nil {
+			fmt.Fprintf(stdio.Err, "ошибка: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	// если команда не встроенная, запускаем внешнюю команду
+	return executeExternalCommand(shell, args, assigns, stdio)
+}
+
+// assignTemporarily задаёт переменные на время выполнения встроенной команды
+// и возвращает функцию, восстанавливающую прежние значения
+func (shell *Shell) assignTemporarily(assigns []string) func() {
+
+	saved := make(map[string]*shellVar, len(assigns))
+	for _, assign := range assigns {
+		name, value, _ := strings.Cut(assign, "=")
+		if _, done := saved[name]; !done {
+			if v, ok := shell.vars[name]; ok {
+				saved[name] = &v
+			} else {
+				saved[name] = nil
+			}
+		}
+		shell.setVar(name, value)
+	}
+
+	return func() {
+		for name, v := range saved {
+			if v == nil {
+				shell.unsetVar(name)
+			} else {
+				shell.vars[name] = *v
+			}
+		}
+	}
+}
+
+// executeExternalCommand выполняет внешнюю команду на переднем плане и ждёт её завершения (или остановки)
+func executeExternalCommand(shell *Shell, args, assigns []string, stdio Stdio) int {
+
+	return shell.runForeground(formatArgs(args), stdio, func() func() int {
+		return startExternalCommand(shell, args, assigns, stdio)
+	})
+}
+
+// formatArgs собирает аргументы в строку команды, заключая в кавычки те, что иначе разбились бы
+func formatArgs(args []string) string {
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$|&;<>()*?[]#~") {
+			quoted[i] = quoteValue(arg)
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// startExternalCommand запускает внешнюю команду в текущем задании, не дожидаясь завершения,
+// и возвращает функцию ожидания её статуса.
+// Окружение команды - экспортированные переменные шелла и присваивания assigns
+func startExternalCommand(shell *Shell, args, assigns []string, stdio Stdio) func() int {
+
+	// создаём команду для выполнения: первый аргумент - имя программы, остальные - её аргументы
+	cmd := newExternalCommand(shell, args[0], args[1:], assigns, stdio)
+
+	// процесс попадает в группу задания - ей достаются Ctrl+C, Ctrl+Z и SIGCONT
+	j := shell.job
+	err := j.start(cmd, shell.jobs.tty)
+
+	// исполняемый файл без #! выполняем как скрипт этого шелла, как делает sh
+	if errors.Is(err, syscall.ENOEXEC) {
+		if self, selfErr := os.Executable(); selfErr == nil {
+			cmd = newExternalCommand(shell, self, append([]string{cmd.Path}, args[1:]...), assigns, stdio)
+			err = j.start(cmd, shell.jobs.tty)
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			fmt.Fprintf(stdio.Err, "mysh: %s: команда не найдена\n", args[0])
+			return func() int { return 127 }
+		}
+		fmt.Fprintf(stdio.Err, "ошибка запуска: %v\n", err)
+		return func() int { return 126 }
+	}
+
+	return func() int { return waitExternalCommand(j, cmd) }
+}
+
+// newExternalCommand создаёт процесс для программы name в директории шелла
+func newExternalCommand(shell *Shell, name string, args, assigns []string, stdio Stdio) *exec.Cmd {
+
+	cmd := exec.Command(name, args...)
+	cmd.Dir = shell.dir
+	cmd.Env = shell.environ(assigns)
+	cmd.Stdin = stdio.In
+	cmd.Stdout = stdio.Out
+	cmd.Stderr = stdio.Err
+
+	return cmd
+}
+
+// waitExternalCommand ждёт завершения запущенной команды и возвращает её статус.
+// Остановки и продолжения процесса передаются заданию
+func waitExternalCommand(j *job, cmd *exec.Cmd) int {
+
+	pid := cmd.Process.Pid
+	for {
+		event, sig := waitEvent(pid)
+		if event == procExited {
+			break
+		}
+		j.update(pid, event, sig)
+	}
+	j.exited(pid)
+
+	return exitStatus(cmd.Wait())
+}
+
+// exitStatus переводит результат Wait в статус завершения (128+N для убитых сигналом N)
+func exitStatus(err error) int {
+
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return 128 + int(ws.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+
+	fmt.Fprintf(os.Stderr, "ошибка выполнения: %v\n", err)
+	return 1
+}
+
+// executePipeline обрабатывает конвейер команд: все команды запускаются одновременно,
+// вывод каждой через pipe идёт на ввод следующей
+func executePipeline(shell *Shell, pipeline *parser.Pipeline, stdio Stdio) int {
+
+	// одиночная команда с ! выполняется в текущем окружении
+	if len(pipeline.Commands) == 1 {
+		return negate(execute(shell, pipeline.Commands[0], stdio), pipeline.Negated)
+	}
+
+	status := shell.runForeground(parser.Format(pipeline), stdio, func() func() int {
+		return startPipeline(shell, pipeline, stdio)
+	})
+
+	return negate(status, pipeline.Negated)
+}
+
+// startPipeline запускает все команды конвейера и возвращает функцию ожидания статуса последней
+func startPipeline(shell *Shell, pipeline *parser.Pipeline, stdio Stdio) func() int {
+
+	waits := make([]func() int, 0, len(pipeline.Commands))
+	in := stdio.In
+	var inPipe *os.File // читающий конец pipe от предыдущей команды
+
+	for i, node := range pipeline.Commands {
+		cmdStdio := Stdio{In: in, Out: stdio.Out, Err: stdio.Err}
+		var owned []*os.File // концы pipe, которые принадлежат этой команде
+		if inPipe != nil {
+			owned = append(owned, inPipe)
+		}
+
+		var nextIn *os.File
+		if i < len(pipeline.Commands)-1 {
+			reader, writer, err := os.Pipe()
+			if err != nil {
+				fmt.Fprintf(stdio.Err, "ошибка создания pipe: %v\n", err)
+				closeAll(owned)
+				break
+			}
+			cmdStdio.Out = writer
+			owned = append(owned, writer)
+			nextIn = reader
+		}
+
+		waits = append(waits, startPipelineCommand(shell, node, cmdStdio, owned))
+
+		in, inPipe = nextIn, nextIn
+	}
+
+	// ждем завершения всех команд, статус конвейера - статус последней
+	return func() int {
+		status := 1
+		for _, wait := range waits {
+			status = wait()
+		}
+		return status
+	}
+}
+
+// negate инвертирует статус для конвейера с !
+func negate(status int, negated bool) int {
+
+	if !negated {
+		return status
+	}
+	if status == 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// startPipelineCommand запускает команду конвейера и возвращает функцию ожидания её статуса.
+// Внешние команды запускаются процессами, остальные (встроенные, функции, составные) - в горутине
+// с копией окружения, как подоболочки. owned - концы pipe, которые нужно закрыть после запуска
+func startPipelineCommand(shell *Shell, node parser.Node, stdio Stdio, owned []*os.File) func() int {
+
+	// аргументы простой команды раскрываем один раз, до выбора способа запуска
+	run := func(sub *Shell) int { return execute(sub, node, stdio) }
+	if simple, ok := node.(*parser.SimpleCommand); ok {
+		assigns := expandAssigns(shell, simple.Assigns, stdio)
+		args := expandArgs(shell, simple.Args, stdio)
+		if len(args) > 0 {
+			if _, builtin := builtins[args[0]]; !builtin && shell.funcs[args[0]] == nil {
+				return startPipelineExternal(shell, args, assigns, simple.Redirects, stdio, owned)
+			}
+		}
+		run = func(sub *Shell) int {
+			if len(args) == 0 {
+				// присваивания в элементе конвейера действуют только в его подоболочке
+				for _, assign := range assigns {
+					name, value, _ := strings.Cut(assign, "=")
+					sub.setVar(name, value)
+				}
+				assigns = nil
+			}
+			return executeCommandArgs(sub, args, assigns, simple.Redirects, stdio)
+		}
+	}
+
+	done := make(chan int, 1)
+	sub := shell.subshell()
+	go func() {
+		defer closeAll(owned)
+		status := run(sub)
+		if sub.exiting {
+			status = sub.exitCode
+		}
+		done <- status
+	}()
+
+	return func() int { return <-done }
+}
+
+// startPipelineExternal запускает внешнюю команду конвейера
+func startPipelineExternal(shell *Shell, args, assigns []string, redirects []parser.Redirect, stdio Stdio, owned []*os.File) func() int {
+
+	// процесс получает свои копии дескрипторов, наши концы pipe закрываем сразу после запуска,
+	// иначе читающая команда не увидит конец ввода
+	defer closeAll(owned)
+
+	stdio, closeFiles, err := applyRedirects(shell, redirects, stdio)
+	if err != nil {
+		closeFiles()
+		fmt.Fprintf(stdio.Err, "mysh: %v\n", err)
+		return func() int { return 1 }
+	}
+
+	wait := startExternalCommand(shell, args, assigns, stdio)
+
+	return func() int {
+		defer closeFiles()
+		return wait()
+	}
+}
+
+// closeAll закрывает файлы
+func closeAll(files []*os.File) {
+
+	for _, file := range files {
+		file.Close()
+	}
+}
+
+// applyRedirects применяет редиректы к потокам команды. Возвращает новые потоки
+// и функцию закрытия открытых файлов (её нужно вызвать и при ошибке)
+func applyRedirects(shell *Shell, redirects []parser.Redirect, stdio Stdio) (Stdio, func(), error) {
+
+	var opened []*os.File
+	closeFiles := func() { closeAll(opened) }
+
+	for _, r := range redirects {
+		target := expandWord(shell, r.Target, stdio)
+
+		switch r.Op {
+		case parser.RedirDupIn, parser.RedirDupOut:
+			// 2>&1, >&2: дескриптор становится копией другого
+			fd, err := strconv.Atoi(target)
+			if err != nil {
+				return stdio, closeFiles, fmt.Errorf("%s: неверный дескриптор", target)
+			}
+			stream, err := stdio.stream(fd)
+			if err != nil {
+				return stdio, closeFiles, err
+			}
+			if err := stdio.set(r.Fd, stream); err != nil {
+				return stdio, closeFiles, err
+			}
+			continue
+		}
+
+		var flags int
+		switch r.Op {
+		case parser.RedirIn:
+			flags = os.O_RDONLY
+		case parser.RedirOut, parser.RedirAll:
+			flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		case parser.RedirAppend, parser.RedirAllAppend:
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+
+		file, err := os.OpenFile(shell.resolvePath(target), flags, 0644)
+		if err != nil {
+			return stdio, closeFiles, fmt.Errorf("%s: %v", target, unwrapPathError(err))
+		}
+		opened = append(opened, file)
+
+		if r.Op == parser.RedirAll || r.Op == parser.RedirAllAppend {
+			stdio.Out, stdio.Err = file, file
+			continue
+		}
+		if err := stdio.set(r.Fd, file); err != nil {
+			return stdio, closeFiles, err
+		}
+	}
+
+	return stdio, closeFiles, nil
+}
+
+// unwrapPathError убирает из ошибки открытия файла повтор имени файла
+func unwrapPathError(err error) error {
+
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+
+	return err
+}
+
+// stream возвращает поток по номеру дескриптора
+func (s Stdio) stream(fd int) (any, error) {
+
+	switch fd {
+	case 0:
+		return s.In, nil
+	case 1:
+		return s.Out, nil
+	case 2:
+		return s.Err, nil
+	}
+
+	return nil, fmt.Errorf("%d: дескриптор не поддерживается", fd)
+}
+
+// set подменяет поток с номером fd
+func (s *Stdio) set(fd int, stream any) error {
+
+	switch fd {
+	case 0:
+		r, ok := stream.(io.Reader)
+		if !ok {
+			return errors.New("поток нельзя использовать для чтения")
+		}
+		s.In = r
+	case 1, 2:
+		w, ok := stream.(io.Writer)
+		if !ok {
+			return errors.New("поток нельзя использовать для записи")
+		}
+		if fd == 1 {
+			s.Out = w
+		} else {
+			s.Err = w
+		}
+	default:
+		return fmt.Errorf("%d: дескриптор не поддерживается", fd)
+	}
+
+	return nil
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"l2.15/pkg/parser"
+)
+
+// Порядок раскрытия слова, как в POSIX sh:
+//  1. тильда в начале слова (~, ~/path, ~user);
+//  2. подстановка переменных ($NAME, ${NAME}, $?, $$, $1, $#, $@) и команд $(...);
+//  3. разбиение результатов подстановок без кавычек на поля по пробелам, табуляциям и переводам строк;
+//  4. шаблоны имён файлов (*, ?, [...]) в символах без кавычек;
+//  5. снятие кавычек.
+
+// expanded - результат подстановок в слове с пометками для каждого байта
+type expanded struct {
+	buf       []byte
+	quoted    []bool // байт в кавычках: не участвует в шаблонах имён файлов
+	split     []bool // байт получен подстановкой без кавычек: по нему разбиваем на поля
+	breaks    []int  // позиции, с которых начинается новое поле независимо от кавычек ("$@")
+	hasQuotes bool   // в слове были кавычки: пустой результат даёт пустой аргумент, а не пропадает
+}
+
+func (e *expanded) add(s string, quoted, split bool) {
+
+	for i := 0; i < len(s); i++ {
+		e.buf = append(e.buf, s[i])
+		e.quoted = append(e.quoted, quoted)
+		e.split = append(e.split, split)
+	}
+}
+
+// breakField начинает новое поле с текущей позиции
+func (e *expanded) breakField() {
+	e.breaks = append(e.breaks, len(e.buf))
+}
+
+// field - поле после разбиения слова
+type field struct {
+	buf    []byte
+	quoted []bool
+}
+
+// expandArgs раскрывает аргументы команды; одно слово может дать несколько аргументов или ни одного
+func expandArgs(shell *Shell, words []parser.Word, stdio Stdio) []string {
+
+	args := make([]string, 0, len(words))
+	for _, word := range words {
+		for _, f := range expand(shell, word, stdio).fields() {
+			args = append(args, f.glob(shell)...)
+		}
+	}
+
+	return args
+}
+
+// expandWord раскрывает слово в одну строку без разбиения на поля и шаблонов
+// (цели редиректов, значения присваиваний)
+func expandWord(shell *Shell, word parser.Word, stdio Stdio) string {
+	return string(expand(shell, word, stdio).buf)
+}
+
+// expandAssigns раскрывает присваивания перед командой в строки NAME=value
+func expandAssigns(shell *Shell, assigns []parser.Assignment, stdio Stdio) []string {
+
+	result := make([]string, 0, len(assigns))
+	for _, assign := range assigns {
+		result = append(result, assign.Name+"="+expandWord(shell, assign.Value, stdio))
+	}
+
+	return result
+}
+
+// expand выполняет подстановки в слове
+func expand(shell *Shell, word parser.Word, stdio Stdio) *expanded {
+
+	e := &expanded{}
+
+	for i, part := range word.Parts {
+		switch part.Kind {
+		case parser.Quoted:
+			e.hasQuotes = true
+			e.add(part.Text, true, false)
+
+		case parser.DQuoted:
+			// "$@" без позиционных параметров не даёт ни одного аргумента
+			if !isAllArgs(part.Text) || len(shell.args) > 0 {
+				e.hasQuotes = true
+			}
+			expandParams(shell, part.Text, func(s string) { e.add(s, true, false) }, func(s string) { e.add(s, true, false) }, e.breakField)
+
+		case parser.CmdSubst:
+			out := commandSubstitution(shell, part.Cmd, stdio)
+			if part.InQuotes {
+				e.hasQuotes = true
+			}
+			e.add(out, part.InQuotes, !part.InQuotes)
+
+		default:
+			text := part.Text
+			if i == 0 {
+				var tilde string
+				tilde, text = expandTilde(shell, text)
+				e.add(tilde, true, false)
+			}
+			expandParams(shell, text, func(s string) { e.add(s, false, false) }, func(s string) { e.add(s, false, true) }, nil)
+		}
+	}
+
+	return e
+}
+
+// expandParams находит в тексте $NAME, ${NAME} и специальные параметры: обычный текст передаёт в literal,
+// значения - в value. Если задана nextField, $@ передаёт параметры по одному, вызывая её между ними
+// (так "$@" даёт отдельный аргумент на каждый параметр), иначе - через пробел
+func expandParams(shell *Shell, s string, literal, value func(string), nextField func()) {
+
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			continue
+		}
+
+		name, n := "", 0
+		switch c := s[i+1]; {
+		case strings.IndexByte("?$#@*", c) >= 0 || (c >= '0' && c <= '9'):
+			name, n = string(c), 2
+		case c == '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				continue
+			}
+			name, n = s[i+2:i+2+end], end+3
+		default:
+			length := nameLength(s[i+1:])
+			if length == 0 {
+				// одиночный $ остаётся как есть
+				continue
+			}
+			name, n = s[i+1:i+1+length], length+1
+		}
+
+		literal(s[start:i])
+		if name == "@" && nextField != nil {
+			for j, arg := range shell.args {
+				if j > 0 {
+					nextField()
+				}
+				value(arg)
+			}
+		} else {
+			value(shell.param(name))
+		}
+		i += n - 1
+		start = i + 1
+	}
+	literal(s[start:])
+}
+
+// param возвращает значение параметра: специального ($?, $$, $#, $@, $*), позиционного ($0, $1, ${10})
+// или переменной (пусто, если не задана)
+func (shell *Shell) param(name string) string {
+
+	switch name {
+	case "?":
+		return strconv.Itoa(shell.lastStatus)
+	case "$":
+		return strconv.Itoa(os.Getpid())
+	case "#":
+		return strconv.Itoa(len(shell.args))
+	case "@", "*":
+		return strings.Join(shell.args, " ")
+	}
+
+	if n, err := strconv.Atoi(name); err == nil && n >= 0 {
+		if n == 0 {
+			return shell.name
+		}
+		if n <= len(shell.args) {
+			return shell.args[n-1]
+		}
+		return ""
+	}
+
+	value, _ := shell.getVar(name)
+
+	return value
+}
+
+// isAllArgs сообщает, что текст в кавычках - ровно $@ или ${@}
+func isAllArgs(s string) bool {
+	return s == "$@" || s == "${@}"
+}
+
+// nameLength возвращает длину имени переменной в начале строки (буквы, цифры, _; не с цифры)
+func nameLength(s string) int {
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		isLetter := ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+		isDigit := ch >= '0' && ch <= '9'
+		if !isLetter && !(isDigit && i > 0) {
+			return i
+		}
+	}
+
+	return len(s)
+}
+
+// expandTilde раскрывает ~ и ~user в начале слова. Возвращает домашнюю директорию
+// (или пустую строку) и остаток текста
+func expandTilde(shell *Shell, s string) (string, string) {
+
+	if !strings.HasPrefix(s, "~") {
+		return "", s
+	}
+
+	name, rest := s[1:], ""
+	if slash := strings.IndexByte(name, '/'); slash >= 0 {
+		name, rest = name[:slash], name[slash:]
+	}
+
+	if name == "" {
+		home, ok := shell.getVar("HOME")
+		if !ok {
+			var err error
+			if home, err = os.UserHomeDir(); err != nil {
+				return "", s
+			}
+		}
+		return home, rest
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		// неизвестный пользователь - слово остаётся как есть
+		return "", s
+	}
+
+	return u.HomeDir, rest
+}
+
+// commandSubstitution выполняет $(...) в подоболочке и возвращает вывод без завершающих переводов строк.
+// Статус команды становится значением $?
+func commandSubstitution(shell *Shell, cmd *parser.List, stdio Stdio) string {
+
+	var out syncBuffer
+	sub := shell.subshell()
+	status := execute(sub, cmd, Stdio{In: stdio.In, Out: &out, Err: stdio.Err})
+	if sub.exiting {
+		status = sub.exitCode
+	}
+	shell.lastStatus = status
+
+	return strings.TrimRight(out.String(), "\n")
+}
+
+// hasCommandSubstitution сообщает, есть ли в присваиваниях или аргументах команды $(...)
+func hasCommandSubstitution(cmd *parser.SimpleCommand) bool {
+
+	words := append([]parser.Word{}, cmd.Args...)
+	for _, assign := range cmd.Assigns {
+		words = append(words, assign.Value)
+	}
+
+	for _, word := range words {
+		for _, part := range word.Parts {
+			if part.Kind == parser.CmdSubst {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isIFS сообщает, что байт разделяет поля
+func isIFS(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// fields разбивает результат подстановок на поля по разделителям, полученным из подстановок без кавычек
+func (e *expanded) fields() []field {
+
+	var result []field
+	var cur field
+	inField := false
+	breaks := e.breaks
+
+	for i := 0; i <= len(e.buf); i++ {
+		// на границе из "$@" поле завершается, даже пустое
+		for len(breaks) > 0 && breaks[0] == i {
+			result = append(result, cur)
+			cur, inField = field{}, true
+			breaks = breaks[1:]
+		}
+		if i == len(e.buf) {
+			break
+		}
+
+		c := e.buf[i]
+		if e.split[i] && isIFS(c) {
+			if inField {
+				result = append(result, cur)
+				cur, inField = field{}, false
+			}
+			continue
+		}
+		cur.buf = append(cur.buf, c)
+		cur.quoted = append(cur.quoted, e.quoted[i])
+		inField = true
+	}
+	if inField {
+		result = append(result, cur)
+	}
+
+	// "" и "$EMPTY" дают пустой аргумент, $EMPTY без кавычек - ничего
+	if len(result) == 0 && e.hasQuotes {
+		result = append(result, field{})
+	}
+
+	return result
+}
+
+// glob раскрывает шаблон имён файлов в поле; если совпадений нет, поле остаётся как есть
+func (f field) glob(shell *Shell) []string {
+
+	hasMeta := false
+	var pattern strings.Builder
+	for i, c := range f.buf {
+		switch {
+		case isGlobMeta(c) && !f.quoted[i]:
+			hasMeta = true
+			pattern.WriteByte(c)
+		case isGlobMeta(c) || c == '\\':
+			// символы в кавычках и обратная косая черта совпадают буквально
+			pattern.WriteByte('\\')
+			pattern.WriteByte(c)
+		default:
+			pattern.WriteByte(c)
+		}
+	}
+
+	if hasMeta {
+		if matches := glob(shell.dir, pattern.String()); len(matches) > 0 {
+			return matches
+		}
+	}
+
+	return []string{string(f.buf)}
+}
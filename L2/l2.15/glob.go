@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isGlobMeta сообщает, что символ - часть шаблона имён файлов
+func isGlobMeta(c byte) bool {
+	return c == '*' || c == '?' || c == '[' || c == ']'
+}
+
+// hasGlobMeta сообщает, что в компоненте пути есть неэкранированные *, ? или [
+func hasGlobMeta(s string) bool {
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+
+	return false
+}
+
+// unescapeGlob убирает экранирование из компонента пути без шаблонов
+func unescapeGlob(s string) string {
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+
+	return sb.String()
+}
+
+// glob ищет файлы по шаблону относительно директории dir. Имена, начинающиеся с точки,
+// совпадают только с компонентом шаблона, который тоже начинается с точки.
+// Пути возвращаются в том виде, как записан шаблон (относительные остаются относительными), отсортированными
+func glob(dir, pattern string) []string {
+
+	prefix := ""
+	if strings.HasPrefix(pattern, "/") {
+		dir, prefix = "/", "/"
+		pattern = strings.TrimLeft(pattern, "/")
+	}
+
+	var matches []string
+	globWalk(dir, prefix, strings.Split(pattern, "/"), &matches)
+	sort.Strings(matches)
+
+	return matches
+}
+
+// globWalk сопоставляет очередной компонент шаблона с содержимым директории dir
+func globWalk(dir, prefix string, comps []string, matches *[]string) {
+
+	comp, rest := comps[0], comps[1:]
+	last := len(rest) == 0
+
+	if comp == "" {
+		// шаблон заканчивается на / (подходят только директории) или содержит //
+		if last {
+			*matches = append(*matches, prefix)
+		} else {
+			globWalk(dir, prefix, rest, matches)
+		}
+		return
+	}
+
+	if !hasGlobMeta(comp) {
+		name := unescapeGlob(comp)
+		path := filepath.Join(dir, name)
+		if last {
+			if _, err := os.Lstat(path); err == nil {
+				*matches = append(*matches, prefix+name)
+			}
+		} else if isDir(path) {
+			globWalk(path, prefix+name+"/", rest, matches)
+		}
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(comp, ".") {
+			continue
+		}
+		if ok, _ := filepath.Match(comp, name); !ok {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		if last {
+			*matches = append(*matches, prefix+name)
+		} else if isDir(path) {
+			globWalk(path, prefix+name+"/", rest, matches)
+		}
+	}
+}
+
+// isDir сообщает, что путь - директория (или ссылка на неё)
+func isDir(path string) bool {
+
+	info, err := os.Stat(path)
+
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,3 @@
+module l2.15
+
+go 1.24.1
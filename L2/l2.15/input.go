@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"l2.15/pkg/lineedit"
+	"l2.15/pkg/parser"
+)
+
+// historySize - сколько команд хранится в истории
+const historySize = 1000
+
+// lineReader - источник команд: редактор строки на терминале или построчное чтение
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+
+	// interrupted вызывается по Ctrl+C, когда нет задания переднего плана
+	interrupted()
+}
+
+// newLineReader выбирает способ чтения команд: редактор строки, если ввод и вывод - терминал,
+// иначе (скрипты, тесты) - построчное чтение
+func newLineReader(shell *Shell) lineReader {
+
+	if !lineedit.IsTerminal(0) || !lineedit.IsTerminal(1) {
+		return &plainReader{scanner: bufio.NewScanner(os.Stdin), out: os.Stdout}
+	}
+
+	editor := lineedit.New(os.Stdin, os.Stdout, 0)
+	editor.Complete = shell.complete
+	if path := historyPath(shell); path != "" {
+		history, err := lineedit.LoadHistory(path, historySize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "mysh: история: %v\n", err)
+		}
+		editor.History = history
+	}
+	shell.history = editor.History
+
+	return editorReader{editor}
+}
+
+// historyPath возвращает файл истории: $HISTFILE или ~/.minishell_history
+func historyPath(shell *Shell) string {
+
+	if path, ok := shell.getVar("HISTFILE"); ok {
+		return path
+	}
+	home, ok := shell.getVar("HOME")
+	if !ok || home == "" {
+		return ""
+	}
+
+	return filepath.Join(home, ".minishell_history")
+}
+
+// editorReader читает команды редактором строки. Ctrl+C на вводе обрабатывает сам редактор
+type editorReader struct {
+	*lineedit.Editor
+}
+
+func (r editorReader) interrupted() {
+	fmt.Println("^C")
+}
+
+// plainReader читает команды построчно, печатая приглашение
+type plainReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+
+	mu      sync.Mutex
+	prompt  string // приглашение, если шелл сейчас ждёт ввода
+	waiting bool
+}
+
+func (r *plainReader) ReadLine(prompt string) (string, error) {
+
+	r.mu.Lock()
+	r.prompt, r.waiting = prompt, true
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.waiting = false
+		r.mu.Unlock()
+	}()
+
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+
+	return r.scanner.Text(), nil
+}
+
+// interrupted печатает ^C и, если шелл ждёт ввода, приглашение заново
+func (r *plainReader) interrupted() {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fmt.Fprintln(r.out, "\n^C")
+	if r.waiting {
+		fmt.Fprint(r.out, r.prompt)
+	}
+}
+
+// readCommand читает команду: незакрытые кавычки, | или && в конце строки - дочитывает продолжение
+// с приглашением PS2. Ctrl+C на продолжении отменяет всю команду
+func readCommand(shell *Shell, reader lineReader) (string, error) {
+
+	line, err := reader.ReadLine(shell.prompt("PS1", defaultPS1))
+	if err != nil {
+		return "", err
+	}
+
+	for _, perr := parser.Parse(line); isIncomplete(perr); _, perr = parser.Parse(line) {
+		next, err := reader.ReadLine(shell.prompt("PS2", defaultPS2))
+		if errors.Is(err, io.EOF) {
+			// выполняем то, что есть - парсер сообщит об ошибке
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		line += "\n" + next
+	}
+
+	return line, nil
+}
+
+// historyCom - history [N]: печатает историю команд (последние N)
+func historyCom(shell *Shell, args []string, stdio Stdio) error {
+
+	if shell.history == nil {
+		return nil
+	}
+
+	entries := shell.history.Entries()
+	first := 0
+	if len(args) > 1 {
+		var n int
+		if _, err := fmt.Sscan(args[1], &n); err != nil || n < 0 {
+			return fmt.Errorf("history: %s: требуется числовой аргумент", args[1])
+		}
+		first = max(0, len(entries)-n)
+	}
+
+	for i := first; i < len(entries); i++ {
+		fmt.Fprintf(stdio.Out, "%5d  %s\n", i+1, entries[i])
+	}
+
+	return nil
+}
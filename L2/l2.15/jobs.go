@@ -0,0 +1,632 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"l2.15/pkg/lineedit"
+	"l2.15/pkg/parser"
+)
+
+// jobState - состояние задания
+type jobState int
+
+const (
+	jobRunning jobState = iota
+	jobStopped
+	jobDone
+)
+
+// job - задание: конвейер или команда переднего плана либо команда, запущенная через &.
+// Внешние процессы задания собираются в одну группу, чтобы сигналы терминала
+// (Ctrl+C, Ctrl+Z) и SIGCONT доставались им всем сразу
+type job struct {
+	// поля под защитой мьютекса таблицы заданий
+	id       int      // номер в таблице (0 - задание переднего плана, пока оно не остановлено)
+	seq      int      // порядок запуска/остановки: по нему выбираются текущее (+) и предыдущее (-) задания
+	reported jobState // состояние, о котором уже сообщили пользователю
+
+	text     string // текст команды для jobs
+	ownGroup bool   // процессы задания в отдельной группе (без управления заданиями - только фоновые)
+
+	mu         sync.Mutex
+	changed    *sync.Cond
+	pgid       int          // группа процессов (0 - живых процессов нет)
+	procs      map[int]bool // живые процессы: pid -> остановлен
+	stopSig    syscall.Signal
+	foreground bool // задание владеет терминалом
+	done       bool
+	status     int
+}
+
+func newJob(text string, foreground, ownGroup bool) *job {
+
+	j := &job{
+		text:       text,
+		ownGroup:   ownGroup,
+		procs:      make(map[int]bool),
+		foreground: foreground,
+	}
+	j.changed = sync.NewCond(&j.mu)
+
+	return j
+}
+
+// start запускает процесс в группе задания. tty - терминал шелла (или -1):
+// первый процесс задания переднего плана сам становится активной группой терминала ещё до exec,
+// иначе он мог бы успеть прочитать терминал из фоновой группы и получить SIGTTIN
+func (j *job) start(cmd *exec.Cmd, tty int) error {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.ownGroup {
+		attr := &syscall.SysProcAttr{Setpgid: true, Pgid: j.pgid}
+		if j.foreground && j.pgid == 0 && tty >= 0 {
+			attr.Foreground = true
+			attr.Ctty = tty
+		}
+		cmd.SysProcAttr = attr
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	pid := cmd.Process.Pid
+	j.procs[pid] = false
+	if j.ownGroup && j.pgid == 0 {
+		j.pgid = pid
+	}
+
+	return nil
+}
+
+// update отмечает остановку или продолжение процесса задания
+func (j *job) update(pid int, event procEvent, sig syscall.Signal) {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.procs[pid]; !ok {
+		return
+	}
+	j.procs[pid] = event == procStopped
+	if event == procStopped {
+		j.stopSig = sig
+	}
+	j.changed.Broadcast()
+}
+
+// exited убирает завершившийся процесс. Вызывается до освобождения процесса,
+// поэтому группа с pgid существует, пока в задании есть хотя бы один процесс
+func (j *job) exited(pid int) {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	delete(j.procs, pid)
+	if len(j.procs) == 0 {
+		// следующий процесс задания создаст новую группу
+		j.pgid = 0
+	}
+	j.changed.Broadcast()
+}
+
+// complete отмечает завершение задания со статусом status
+func (j *job) complete(status int) {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.done = true
+	j.status = status
+	j.changed.Broadcast()
+}
+
+// stateLocked возвращает состояние задания: остановлено, если все его живые процессы остановлены
+func (j *job) stateLocked() jobState {
+
+	if j.done {
+		return jobDone
+	}
+	if len(j.procs) == 0 {
+		return jobRunning
+	}
+	for _, stopped := range j.procs {
+		if !stopped {
+			return jobRunning
+		}
+	}
+
+	return jobStopped
+}
+
+func (j *job) state() jobState {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.stateLocked()
+}
+
+// waitChange ждёт, пока задание завершится или остановится
+func (j *job) waitChange() jobState {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for j.stateLocked() == jobRunning {
+		j.changed.Wait()
+	}
+
+	return j.stateLocked()
+}
+
+// signal отправляет сигнал группе задания (или каждому процессу, если отдельной группы нет)
+func (j *job) signal(sig syscall.Signal) error {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.signalLocked(sig)
+}
+
+func (j *job) signalLocked(sig syscall.Signal) error {
+
+	if j.pgid > 0 {
+		return syscall.Kill(-j.pgid, sig)
+	}
+
+	var err error
+	for pid := range j.procs {
+		if e := syscall.Kill(pid, sig); e != nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// resume продолжает остановленное задание
+func (j *job) resume() error {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for pid := range j.procs {
+		j.procs[pid] = false
+	}
+
+	return j.signalLocked(syscall.SIGCONT)
+}
+
+func (j *job) setForeground(foreground bool) {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.foreground = foreground
+}
+
+// describe возвращает состояние задания словами
+func (j *job) describe(state jobState) string {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch {
+	case state == jobRunning:
+		return "Выполняется"
+	case state == jobStopped:
+		return "Остановлено"
+	case j.status == 0:
+		return "Завершено"
+	}
+
+	return "Выход " + strconv.Itoa(j.status)
+}
+
+// jobTable - задания шелла. Общая для шелла и его подоболочек
+type jobTable struct {
+	mu         sync.Mutex
+	jobs       []*job // задания с номерами (фоновые и остановленные) по возрастанию номера
+	seq        int
+	foreground *job
+
+	tty  int // терминал при интерактивной работе (управление заданиями включено), иначе -1
+	pgid int // группа процессов шелла
+}
+
+func newJobTable() *jobTable {
+	return &jobTable{tty: -1}
+}
+
+// enableJobControl включает управление заданиями, если ввод шелла - терминал:
+// шелл становится лидером своей группы и активной группой терминала
+func (t *jobTable) enableJobControl() {
+
+	if !lineedit.IsTerminal(0) {
+		return
+	}
+
+	// ошибка означает, что шелл уже лидер сессии и группы
+	syscall.Setpgid(0, 0)
+	t.pgid = syscall.Getpgrp()
+	if err := tcsetpgrp(0, t.pgid); err != nil {
+		fmt.Fprintf(os.Stderr, "mysh: управление заданиями недоступно: %v\n", err)
+		return
+	}
+	t.tty = 0
+}
+
+// interactive сообщает, что управление заданиями включено
+func (t *jobTable) interactive() bool {
+	return t.tty >= 0
+}
+
+// giveTerminal делает задание активной группой терминала
+func (t *jobTable) giveTerminal(j *job) {
+
+	j.mu.Lock()
+	pgid := j.pgid
+	j.mu.Unlock()
+
+	if t.interactive() && pgid > 0 {
+		tcsetpgrp(t.tty, pgid)
+	}
+}
+
+// reclaimTerminal возвращает терминал шеллу
+func (t *jobTable) reclaimTerminal() {
+
+	if t.interactive() {
+		tcsetpgrp(t.tty, t.pgid)
+	}
+}
+
+// add заносит задание в таблицу (номер - на единицу больше наибольшего) и делает его текущим
+func (t *jobTable) add(j *job) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if j.id == 0 {
+		j.id = 1
+		if len(t.jobs) > 0 {
+			j.id = t.jobs[len(t.jobs)-1].id + 1
+		}
+		t.jobs = append(t.jobs, j)
+	}
+	t.seq++
+	j.seq = t.seq
+}
+
+func (t *jobTable) remove(j *job) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeLocked(j)
+}
+
+func (t *jobTable) removeLocked(j *job) {
+
+	for i, other := range t.jobs {
+		if other == j {
+			t.jobs = append(t.jobs[:i], t.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *jobTable) setForeground(j *job) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.foreground = j
+}
+
+// signalForeground отправляет сигнал заданию переднего плана, возвращает false, если его нет
+func (t *jobTable) signalForeground(sig syscall.Signal) bool {
+
+	t.mu.Lock()
+	j := t.foreground
+	t.mu.Unlock()
+
+	if j == nil {
+		return false
+	}
+	j.signal(sig)
+
+	return true
+}
+
+// setReported запоминает состояние, о котором сообщили пользователю
+func (t *jobTable) setReported(j *job, state jobState) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	j.reported = state
+}
+
+// currentLocked возвращает текущее (+) и предыдущее (-) задания
+func (t *jobTable) currentLocked() (*job, *job) {
+
+	var current, previous *job
+	for _, j := range t.jobs {
+		switch {
+		case current == nil || j.seq > current.seq:
+			current, previous = j, current
+		case previous == nil || j.seq > previous.seq:
+			previous = j
+		}
+	}
+
+	return current, previous
+}
+
+// find ищет задание по описанию: %N или N - по номеру, %%, %+ или пусто - текущее,
+// %- - предыдущее, %текст - по началу команды
+func (t *jobTable) find(spec string) (*job, error) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current, previous := t.currentLocked()
+	var found *job
+
+	switch name := strings.TrimPrefix(spec, "%"); {
+	case name == "" || name == "%" || name == "+":
+		found = current
+	case name == "-":
+		found = previous
+	default:
+		if id, err := strconv.Atoi(name); err == nil {
+			for _, j := range t.jobs {
+				if j.id == id {
+					found = j
+				}
+			}
+			break
+		}
+		for _, j := range t.jobs {
+			if strings.HasPrefix(j.text, name) {
+				if found != nil {
+					return nil, fmt.Errorf("%s: неоднозначное описание задания", spec)
+				}
+				found = j
+			}
+		}
+	}
+
+	if found == nil {
+		if spec == "" {
+			return nil, fmt.Errorf("нет текущего задания")
+		}
+		return nil, fmt.Errorf("%s: нет такого задания", spec)
+	}
+
+	return found, nil
+}
+
+// formatLocked печатает строку задания: [1]+  Остановлено  vim
+func (t *jobTable) formatLocked(j *job, state jobState, long bool) string {
+
+	mark := " "
+	current, previous := t.currentLocked()
+	switch j {
+	case current:
+		mark = "+"
+	case previous:
+		mark = "-"
+	}
+
+	pgid := ""
+	if long {
+		j.mu.Lock()
+		pgid = " " + strconv.Itoa(j.pgid)
+		j.mu.Unlock()
+	}
+
+	text := j.text
+	if state == jobRunning {
+		text += " &"
+	}
+
+	return fmt.Sprintf("[%d]%s%s  %-12s %s", j.id, mark, pgid, j.describe(state), text)
+}
+
+// report печатает задания, состояние которых изменилось с прошлого сообщения (все, если all).
+// Завершённые задания после этого удаляются из таблицы
+func (t *jobTable) report(out io.Writer, all, long bool) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var lines []string
+	var finished []*job
+	for _, j := range t.jobs {
+		state := j.state()
+		if !all && state == j.reported {
+			continue
+		}
+		lines = append(lines, t.formatLocked(j, state, long))
+		j.reported = state
+		if state == jobDone {
+			finished = append(finished, j)
+		}
+	}
+	for _, j := range finished {
+		t.removeLocked(j)
+	}
+
+	for _, line := range lines {
+		fmt.Fprintln(out, line)
+	}
+}
+
+// runForeground выполняет команду как задание переднего плана: start запускает её процессы
+// и возвращает функцию ожидания статуса. Шелл ждёт, пока задание завершится или будет остановлено.
+// Внутри другого задания (в фоне, в конвейере) новое задание не создаётся
+func (shell *Shell) runForeground(text string, stdio Stdio, start func() func() int) int {
+
+	if shell.job != nil {
+		return start()()
+	}
+
+	j := newJob(text, true, shell.jobs.interactive())
+	shell.job = j
+	wait := start()
+	shell.job = nil
+
+	go func() { j.complete(wait()) }()
+
+	return shell.waitForeground(j, stdio)
+}
+
+// waitForeground ждёт задание переднего плана и возвращает терминал шеллу.
+// Остановленное задание попадает в таблицу заданий, статус - 128+номер сигнала
+func (shell *Shell) waitForeground(j *job, stdio Stdio) int {
+
+	t := shell.jobs
+	j.setForeground(true)
+	t.setForeground(j)
+
+	state := j.waitChange()
+
+	t.setForeground(nil)
+	j.setForeground(false)
+	t.reclaimTerminal()
+
+	if state == jobDone {
+		t.remove(j)
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		// после ^C, прервавшего задание, приглашение должно начаться с новой строки
+		if t.interactive() && j.status == 128+int(syscall.SIGINT) {
+			fmt.Fprintln(stdio.Err)
+		}
+		return j.status
+	}
+
+	t.add(j)
+	t.mu.Lock()
+	j.reported = jobStopped
+	line := t.formatLocked(j, state, false)
+	t.mu.Unlock()
+	fmt.Fprintf(stdio.Err, "\n%s\n", line)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return 128 + int(j.stopSig)
+}
+
+// runBackground запускает команду в фоне как новое задание и сразу возвращает управление
+func (shell *Shell) runBackground(node parser.Node, stdio Stdio) int {
+
+	t := shell.jobs
+	j := newJob(parser.Format(node), false, true)
+	t.add(j)
+
+	sub := shell.subshell()
+	sub.job = j
+	if !t.interactive() {
+		// без управления заданиями фоновые команды не читают ввод шелла
+		stdio.In = strings.NewReader("")
+	}
+
+	// процессы простой команды и конвейера запускаются сразу, как при fork в bash,
+	// чтобы kill %N сразу после запуска застал их; остальное выполняется в подоболочке
+	var wait func() int
+	if pipeline, ok := node.(*parser.Pipeline); ok && len(pipeline.Commands) > 1 {
+		waitPipeline := startPipeline(sub, pipeline, stdio)
+		wait = func() int { return negate(waitPipeline(), pipeline.Negated) }
+	} else {
+		wait = startPipelineCommand(sub, node, stdio, nil)
+	}
+
+	go func() { j.complete(wait()) }()
+
+	if t.interactive() {
+		j.mu.Lock()
+		pgid := j.pgid
+		j.mu.Unlock()
+		if pgid > 0 {
+			fmt.Fprintf(stdio.Err, "[%d] %d\n", j.id, pgid)
+		} else {
+			fmt.Fprintf(stdio.Err, "[%d]\n", j.id)
+		}
+	}
+
+	return 0
+}
+
+// встроенные команды управления заданиями
+
+// jobsCom - jobs [-l]: список заданий (с -l - с номерами групп процессов)
+func jobsCom(shell *Shell, args []string, stdio Stdio) error {
+
+	long := len(args) > 1 && args[1] == "-l"
+	shell.jobs.report(stdio.Out, true, long)
+
+	return nil
+}
+
+// fgCom - fg [%N]: продолжает задание на переднем плане и ждёт его
+func fgCom(shell *Shell, args []string, stdio Stdio) error {
+
+	j, err := shell.jobs.find(jobSpec(args))
+	if err != nil {
+		return fmt.Errorf("fg: %v", err)
+	}
+
+	fmt.Fprintln(stdio.Out, j.text)
+
+	j.setForeground(true)
+	shell.jobs.giveTerminal(j)
+	j.resume()
+
+	if status := shell.waitForeground(j, stdio); status != 0 {
+		return exitStatusError(status)
+	}
+
+	return nil
+}
+
+// bgCom - bg [%N]: продолжает остановленное задание в фоне
+func bgCom(shell *Shell, args []string, stdio Stdio) error {
+
+	j, err := shell.jobs.find(jobSpec(args))
+	if err != nil {
+		return fmt.Errorf("bg: %v", err)
+	}
+	if j.state() != jobStopped {
+		return fmt.Errorf("bg: задание %d уже выполняется в фоне", j.id)
+	}
+
+	j.resume()
+	shell.jobs.setReported(j, jobRunning)
+	fmt.Fprintf(stdio.Out, "[%d] %s &\n", j.id, j.text)
+
+	return nil
+}
+
+// jobSpec возвращает описание задания из аргументов fg и bg
+func jobSpec(args []string) string {
+
+	if len(args) < 2 {
+		return ""
+	}
+
+	return args[1]
+}
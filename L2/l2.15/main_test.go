@@ -0,0 +1,664 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"l2.15/pkg/parser"
+)
+
+// runScript разбирает и выполняет текст в шелле, возвращает stdout, stderr и статус
+func runScript(t *testing.T, shell *Shell, src string) (string, string, int) {
+
+	t.Helper()
+
+	list, err := parser.Parse(src)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", src, err)
+	}
+
+	var stdout, stderr syncBuffer
+	status := execute(shell, list, Stdio{In: strings.NewReader(""), Out: &stdout, Err: &stderr})
+
+	return stdout.String(), stderr.String(), status
+}
+
+// newTestShell создаёт шелл во временной директории
+func newTestShell(t *testing.T) *Shell {
+
+	t.Helper()
+
+	shell := newShell()
+	shell.dir = t.TempDir()
+
+	return shell
+}
+
+func TestExecute(t *testing.T) {
+
+	t.Setenv("MYSH_TEST", "value")
+
+	tests := []struct {
+		name       string
+		src        string
+		wantOut    string
+		wantStatus int
+	}{
+		{"echo", "echo hello   world", "hello world\n", 0},
+		{"кавычки сохраняют пробелы", `echo "a   b" 'c  d'`, "a   b c  d\n", 0},
+		{"| в кавычках внутри конвейера", `echo "a|b" | cat`, "a|b\n", 0},
+		{"конвейер и &&", "echo abc | grep b && echo found", "abc\nfound\n", 0},
+		{"конвейер и || при ошибке", "echo abc | grep x || echo missing", "missing\n", 0},
+		{"цепочка", "false && echo no || echo yes", "yes\n", 0},
+		{"; и статус последней", "echo a; false", "a\n", 1},
+		{"отрицание", "! false && echo ok", "ok\n", 0},
+		{"встроенная команда в конвейере", "echo one two | wc -w", "2\n", 0},
+		{"длинный конвейер", "printf 'c\\nb\\na\\n' | sort | head -n 2", "a\nb\n", 0},
+		{"подоболочка в конвейере", "(echo b; echo a) | sort", "a\nb\n", 0},
+		{"группа", "{ echo a; echo b; }", "a\nb\n", 0},
+		{"stderr в stdout", "sh -c 'echo err >&2' 2>&1 | cat", "err\n", 0},
+		{"переменные окружения", `echo $MYSH_TEST "${MYSH_TEST}" '$MYSH_TEST' \$MYSH_TEST`, "value value $MYSH_TEST $MYSH_TEST\n", 0},
+		{"неизвестная переменная", "echo [$MYSH_NO_SUCH_VAR]", "[]\n", 0},
+		{"команда не найдена", "no-such-command-mysh", "", 127},
+		{"статус внешней команды", "sh -c 'exit 7'", "", 7},
+		{"exit в подоболочке", "(exit 3)", "", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell := newTestShell(t)
+			out, errOut, status := runScript(t, shell, tt.src)
+			if out != tt.wantOut {
+				t.Errorf("stdout = %q, want %q (stderr %q)", out, tt.wantOut, errOut)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d (stderr %q)", status, tt.wantStatus, errOut)
+			}
+			if shell.exiting {
+				t.Error("parent shell is exiting")
+			}
+		})
+	}
+}
+
+func TestExecuteRedirects(t *testing.T) {
+
+	shell := newTestShell(t)
+
+	out, errOut, status := runScript(t, shell, `
+echo first > out.txt
+echo second >> out.txt
+cat < out.txt
+{ echo a; echo b; } > group.txt
+(echo c) >> group.txt
+cat group.txt
+ls no-such-file 2> err.txt || cat err.txt | wc -l
+`)
+	if status != 0 {
+		t.Fatalf("status = %d, stderr %q", status, errOut)
+	}
+	if want := "first\nsecond\na\nb\nc\n1\n"; out != want {
+		t.Errorf("stdout = %q, want %q", out, want)
+	}
+
+	// файлы создаются в директории шелла
+	if _, err := os.Stat(filepath.Join(shell.dir, "out.txt")); err != nil {
+		t.Error(err)
+	}
+
+	_, errOut, status = runScript(t, shell, "cat < missing.txt")
+	if status != 1 || !strings.Contains(errOut, "missing.txt") {
+		t.Errorf("status = %d, stderr = %q", status, errOut)
+	}
+}
+
+func TestSubshellIsolation(t *testing.T) {
+
+	shell := newTestShell(t)
+	start := shell.dir
+	if err := os.Mkdir(filepath.Join(start, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _, _ := runScript(t, shell, "(cd sub; pwd); pwd; cd sub | cat; pwd")
+	want := filepath.Join(start, "sub") + "\n" + start + "\n" + start + "\n"
+	if out != want {
+		t.Errorf("stdout = %q, want %q", out, want)
+	}
+
+	// cd в группе меняет директорию самого шелла
+	runScript(t, shell, "{ cd sub; }")
+	if shell.dir != filepath.Join(start, "sub") {
+		t.Errorf("dir = %q after group cd", shell.dir)
+	}
+}
+
+func TestExit(t *testing.T) {
+
+	shell := newTestShell(t)
+
+	out, _, status := runScript(t, shell, "echo before; exit 4; echo after")
+	if out != "before\n" || status != 4 {
+		t.Errorf("stdout = %q, status = %d", out, status)
+	}
+	if !shell.exiting || shell.exitCode != 4 {
+		t.Errorf("exiting = %v, code = %d", shell.exiting, shell.exitCode)
+	}
+}
+
+func TestExpansions(t *testing.T) {
+
+	t.Setenv("MYSH_TEST", "value")
+
+	tests := []struct {
+		name       string
+		src        string
+		wantOut    string
+		wantStatus int
+	}{
+		{"присваивание", "A=1; echo $A ${A}x", "1 1x\n", 0},
+		{"присваивание без кавычек не разбивается", "A='a  b'; B=$A; echo \"$B\"", "a  b\n", 0},
+		{"разбиение на поля", "A='x  y'; printf '[%s]' $A \"$A\"; echo", "[x][y][x  y]\n", 0},
+		{"пустая переменная", "E=; printf '[%s]' $E \"$E\" ''x$E; echo", "[][x]\n", 0},
+		{"$?", "false; echo $?; sh -c 'exit 5'; echo $?", "1\n5\n", 0},
+		{"$$", "echo $$ | grep -c '^[0-9][0-9]*$'", "1\n", 0},
+		{"одинокий $", "echo $ a$ '$'", "$ a$ $\n", 0},
+		{"подстановка команды", "echo [$(echo hi)]", "[hi]\n", 0},
+		{"вложенная подстановка", "echo $(echo $(echo deep))", "deep\n", 0},
+		{"подстановка с конвейером", "echo $(printf 'b\\na\\n' | sort | head -n 1)", "a\n", 0},
+		{"подстановка разбивается на поля", "printf '[%s]' $(echo 'a b'); echo", "[a][b]\n", 0},
+		{"подстановка в кавычках", "printf '[%s]' \"$(printf 'a b\\n\\n')\"; echo", "[a b]\n", 0},
+		{"статус подстановки", "A=$(false); echo $?; A=$(true) B=1; echo $?", "1\n0\n", 0},
+		{"подстановка видит переменные", "A=in; echo $(echo $A)", "in\n", 0},
+		{"присваивание в подстановке не видно снаружи", "A=out; B=$(A=in; echo $A); echo $A $B", "out in\n", 0},
+		{"временное окружение", "A=tmp sh -c 'echo $A'; echo [$A]", "tmp\n[]\n", 0},
+		{"временная переменная у встроенной команды", "A=1; A=2 export B=$A; echo $A $B", "1 1\n", 0},
+		{"export", "A=1; sh -c 'echo [$A]'; export A; sh -c 'echo [$A]'; export B=2; sh -c 'echo $B'", "[]\n[1]\n2\n", 0},
+		{"unset", "unset MYSH_TEST; echo [$MYSH_TEST]; sh -c 'echo [$MYSH_TEST]'", "[]\n[]\n", 0},
+		{"env", "export X_MYSH=1; env | grep '^X_MYSH='", "X_MYSH=1\n", 0},
+		{"env с командой", "env X_MYSH=2 sh -c 'echo $X_MYSH'", "2\n", 0},
+		{"неверное имя", "export 1A=2", "", 1},
+		{"переменные в конвейере изолированы", "A=1; A=2 | cat; echo $A", "1\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell := newTestShell(t)
+			out, errOut, status := runScript(t, shell, tt.src)
+			if out != tt.wantOut {
+				t.Errorf("stdout = %q, want %q (stderr %q)", out, tt.wantOut, errOut)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d (stderr %q)", status, tt.wantStatus, errOut)
+			}
+		})
+	}
+}
+
+func TestExpandTildeAndCd(t *testing.T) {
+
+	shell := newTestShell(t)
+	home := t.TempDir()
+	shell.setVar("HOME", home)
+	start := shell.dir
+
+	out, errOut, _ := runScript(t, shell, "echo ~ ~/x '~' a~; A=~/y; echo $A; cd; pwd; cd -; echo $OLDPWD")
+	want := home + " " + home + "/x ~ a~\n" + home + "/y\n" + home + "\n" + start + "\n" + home + "\n"
+	if out != want {
+		t.Errorf("stdout = %q, want %q (stderr %q)", out, want, errOut)
+	}
+	if pwd, _ := shell.getVar("PWD"); pwd != start {
+		t.Errorf("PWD = %q, want %q", pwd, start)
+	}
+}
+
+func TestGlob(t *testing.T) {
+
+	shell := newTestShell(t)
+	for _, name := range []string{"b.txt", "a.txt", "c.log", ".hidden.txt", "dir/x.txt", "dir/y.log"} {
+		path := filepath.Join(shell.dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"echo *.txt", "a.txt b.txt\n"},
+		{"echo ?.log", "c.log\n"},
+		{"echo [ab].*", "a.txt b.txt\n"},
+		{"echo .*.txt", ".hidden.txt\n"},
+		{"echo */*.txt", "dir/x.txt\n"},
+		{"echo */", "dir/\n"},
+		{"echo *.none", "*.none\n"},
+		{`echo "*.txt" '*'.txt \*.txt`, "*.txt *.txt *.txt\n"},
+		{"P='*.log'; echo $P \"$P\"", "c.log *.log\n"},
+		{"echo " + shell.dir + "/d*/y.*", shell.dir + "/dir/y.log\n"},
+	}
+
+	for _, tt := range tests {
+		out, errOut, _ := runScript(t, shell, tt.src)
+		if out != tt.want {
+			t.Errorf("%s: stdout = %q, want %q (stderr %q)", tt.src, out, tt.want, errOut)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+
+	shell := newTestShell(t)
+	for _, name := range []string{"alpha.txt", "alpha two.txt", ".hidden", "beta/inner.txt"} {
+		path := filepath.Join(shell.dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "mysh-tool"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "mysh-data"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	shell.setVar("PATH", bin)
+	shell.setVar("HOME", shell.dir)
+
+	tests := []struct {
+		line      string
+		wantStart int
+		want      string
+	}{
+		{"ec", 0, "echo"},
+		{"mysh-", 0, "mysh-tool"},
+		{"ls | mysh", 5, "mysh-tool"},
+		{"cat al", 4, `alpha.txt alpha\ two.txt`},
+		{`cat alpha\ t`, 4, `alpha\ two.txt`},
+		{"cat b", 4, "beta/"},
+		{"cat beta/", 4, "beta/inner.txt"},
+		{"cat .h", 4, ".hidden"},
+		{"cat ~/be", 4, "~/beta/"},
+		{"./be", 0, "./beta/"},
+		{"cat zz", 4, ""},
+	}
+
+	for _, tt := range tests {
+		line := []rune(tt.line)
+		start, got := shell.complete(line, len(line))
+		if start != tt.wantStart || strings.Join(got, " ") != tt.want {
+			t.Errorf("complete(%q) = %d, %q, want %d, %q", tt.line, start, got, tt.wantStart, tt.want)
+		}
+	}
+}
+
+func TestPrompt(t *testing.T) {
+
+	shell := newTestShell(t)
+	home := shell.dir
+	shell.dir = filepath.Join(home, "sub")
+	shell.setVar("HOME", home)
+	shell.lastStatus = 3
+
+	tests := []struct {
+		ps   string
+		want string
+	}{
+		{defaultPS1, "mysh:~/sub [3]> "},
+		{`\W\n\\> `, "sub\n\\> "},
+		{`\[\e[1m\]x\[\e[0m\]`, "\x1b[1mx\x1b[0m"},
+		{`\q`, `\q`},
+	}
+
+	for _, tt := range tests {
+		if got := expandPrompt(shell, tt.ps); got != tt.want {
+			t.Errorf("expandPrompt(%q) = %q, want %q", tt.ps, got, tt.want)
+		}
+	}
+
+	shell.setVar("PS1", "$ ")
+	if got := shell.prompt("PS1", defaultPS1); got != "$ " {
+		t.Errorf("prompt(PS1) = %q", got)
+	}
+}
+
+func TestControlFlow(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		src    string
+		want   string
+		status int
+	}{
+		{"if", "if true; then echo yes; else echo no; fi", "yes\n", 0},
+		{"else", "if false; then echo yes; else echo no; fi", "no\n", 0},
+		{"elif", "if false; then echo 1; elif true; then echo 2; else echo 3; fi", "2\n", 0},
+		{"if без веток", "if false; then echo 1; fi", "", 0},
+		{"статус ветки", "if true; then false; fi", "", 1},
+		{"if и $?", "false; if true; then echo $?; fi", "0\n", 0},
+		{"while", "n=; while [ \"$n\" != xxx ]; do n=x$n; echo $n; done", "x\nxx\nxxx\n", 0},
+		{"until", "n=; until [ \"$n\" = xx ]; do n=x$n; done; echo $n", "xx\n", 0},
+		{"while не выполнялся", "while false; do echo x; done", "", 0},
+		{"for", "for i in a 'b c' d; do echo \"<$i>\"; done", "<a>\n<b c>\n<d>\n", 0},
+		{"for с шаблоном и подстановкой", "X='1 2'; for i in $X $(echo 3); do echo $i; done", "1\n2\n3\n", 0},
+		{"переменная for остаётся", "for i in a b; do :; done 2>/dev/null; echo $i", "b\n", 0},
+		{"break", "for i in 1 2 3; do if [ $i = 2 ]; then break; fi; echo $i; done", "1\n", 0},
+		{"continue", "for i in 1 2 3; do if [ $i = 2 ]; then continue; fi; echo $i; done", "1\n3\n", 0},
+		{"break 2", "for i in 1 2; do for j in a b; do echo $i$j; break 2; done; done; echo end", "1a\nend\n", 0},
+		{"continue 2", "for i in 1 2; do for j in a b; do continue 2; echo no; done; echo no; done; echo end", "end\n", 0},
+		{"break в while", "while true; do echo once; break; done", "once\n", 0},
+		{"цикл в конвейере", "for i in c a b; do echo $i; done | sort", "a\nb\nc\n", 0},
+		{"редирект цикла", "for i in 1 2; do echo $i; done > out; cat out", "1\n2\n", 0},
+		{"exit в цикле", "for i in 1 2; do echo $i; exit 5; done; echo no", "1\n", 5},
+		{"многострочный скрипт", "for i in 1 2\ndo\n  if [ $i = 1 ]\n  then\n    echo one\n  fi\ndone", "one\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell := newTestShell(t)
+			out, errOut, status := runScript(t, shell, tt.src)
+			if shell.exiting {
+				status = shell.exitCode
+			}
+			if out != tt.want || status != tt.status {
+				t.Errorf("stdout = %q, status %d, want %q, %d (stderr %q)", out, status, tt.want, tt.status, errOut)
+			}
+		})
+	}
+}
+
+func TestFunctions(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		src    string
+		want   string
+		status int
+	}{
+		{"вызов", "f() { echo in f; }; f; f", "in f\nin f\n", 0},
+		{"аргументы", "f() { echo $# $1 $2; }; f a 'b c'", "2 a b c\n", 0},
+		{"$@ в кавычках", "f() { for a in \"$@\"; do echo \"[$a]\"; done; }; f 'a b' '' c", "[a b]\n[]\n[c]\n", 0},
+		{"$* в кавычках", "f() { for a in \"$*\"; do echo \"[$a]\"; done; }; f a b", "[a b]\n", 0},
+		{"пустой $@", "f() { for a in \"$@\"; do echo x; done; echo $#; }; f", "0\n", 0},
+		{"return", "f() { echo a; return 3; echo b; }; f; echo $?", "a\n3\n", 0},
+		{"return из цикла", "f() { for i in 1 2; do return $i; done; }; f; echo $?", "1\n", 0},
+		{"статус последней команды", "f() { false; }; f", "", 1},
+		{"переменные общие", "f() { X=inner; }; X=outer; f; echo $X", "inner\n", 0},
+		{"параметры восстанавливаются", "f() { echo $1; }; g() { f x; echo $1; }; g y", "x\ny\n", 0},
+		{"рекурсия", "count() { if [ $1 != xxx ]; then echo $1; count x$1; fi; }; count x", "x\nxx\n", 0},
+		{"function", "function f { echo kw; }; f", "kw\n", 0},
+		{"тело - подоболочка", "f() ( cd /; X=1 ); X=0; f; echo $X", "0\n", 0},
+		{"функция важнее встроенной", "echo() { printf 'fn\\n'; }; echo hidden; unset -f echo; echo builtin", "fn\nbuiltin\n", 0},
+		{"функция в конвейере", "f() { echo b; echo a; }; f | sort", "a\nb\n", 0},
+		{"функция в подстановке", "f() { echo sub; }; echo $(f)", "sub\n", 0},
+		{"временное присваивание", "f() { echo $V; }; V=1 f; echo \"[$V]\"", "1\n[]\n", 0},
+		{"break не выходит из функции", "f() { break; }; for i in 1 2; do f 2>/dev/null; echo $i; done", "1\n2\n", 0},
+		{"exit из функции", "f() { exit 7; }; f; echo no", "", 7},
+		{"shift", "f() { shift; echo $*; shift 2; echo $# $1; }; f a b c d", "b c d\n1 d\n", 0},
+		{"$0", "echo $0", "mysh\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell := newTestShell(t)
+			out, errOut, status := runScript(t, shell, tt.src)
+			if shell.exiting {
+				status = shell.exitCode
+			}
+			if out != tt.want || status != tt.status {
+				t.Errorf("stdout = %q, status %d, want %q, %d (stderr %q)", out, status, tt.want, tt.status, errOut)
+			}
+		})
+	}
+}
+
+func TestControlFlowErrors(t *testing.T) {
+
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{"break", "break: можно использовать только в цикле"},
+		{"continue", "continue: можно использовать только в цикле"},
+		{"return", "return: можно использовать только в функции или файле source"},
+		{"for i in 1; do break x; done", "break: x: требуется положительное число"},
+		{"f() { f; }; f", "f: слишком глубокая рекурсия"},
+	}
+
+	for _, tt := range tests {
+		shell := newTestShell(t)
+		_, errOut, status := runScript(t, shell, tt.src)
+		if !strings.Contains(errOut, tt.wantErr) || status == 0 {
+			t.Errorf("%s: stderr = %q, status %d, want %q", tt.src, errOut, status, tt.wantErr)
+		}
+	}
+}
+
+func TestScriptMode(t *testing.T) {
+
+	shell := newTestShell(t)
+	write := func(name, text string) string {
+		path := filepath.Join(shell.dir, name)
+		if err := os.WriteFile(path, []byte(text), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	write("lib.sh", "greet() { echo \"hello $1\"; }\nLIB=loaded\nreturn 4\necho unreachable\n")
+	script := write("script.sh", "#!/usr/bin/env mysh\n# комментарий\n. ./lib.sh\necho \"source: $?\"\ngreet \"$1\"\necho \"$0 $# $LIB\"\nsource ./args.sh x y\necho \"after: $1\"\nexit 3\necho no\n")
+	write("args.sh", "echo \"args: $*\"\n")
+	write("bad.sh", "echo a\nif true; then\n  echo b\nfi fi\n")
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+		status  int
+	}{
+		{"скрипт", []string{"script.sh", "world", "2"}, "source: 4\nhello world\nscript.sh 2 loaded\nargs: x y\nafter: world\n", "", 3},
+		{"-c", []string{"-c", "echo $0 $1; false"}, "mysh\n", "", 1},
+		{"-c с именем", []string{"-c", "echo $0 $#", "name", "a", "b"}, "name 2\n", "", 0},
+		{"синтаксическая ошибка", []string{"bad.sh"}, "", "mysh: bad.sh: синтаксическая ошибка (строка 4, позиция 4): неожиданный токен fi", 2},
+		{"нет файла", []string{"none.sh"}, "", "mysh: none.sh: no such file or directory", 127},
+		{"нет аргумента -c", []string{"-c"}, "", "mysh: -c: требуется аргумент", 2},
+		{"неизвестный ключ", []string{"-x"}, "", "mysh: -x: неизвестный ключ", 2},
+		{"source без файла", []string{"-c", "source missing.sh"}, "", "source: missing.sh: no such file or directory", 1},
+		{"ошибка в source", []string{"-c", "source bad.sh; echo $?"}, "2\n", "mysh: bad.sh: синтаксическая ошибка (строка 4", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := shell.subshell()
+			var stdout, stderr syncBuffer
+			status := runArgs(sub, tt.args, Stdio{In: strings.NewReader(""), Out: &stdout, Err: &stderr})
+			if stdout.String() != tt.want || status != tt.status {
+				t.Errorf("stdout = %q, status %d, want %q, %d (stderr %q)", stdout.String(), status, tt.want, tt.status, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+
+	// скрипт можно запустить по полному пути
+	if status := runArgs(shell.subshell(), []string{script}, Stdio{In: strings.NewReader(""), Out: io.Discard, Err: io.Discard}); status != 3 {
+		t.Errorf("status = %d, want 3", status)
+	}
+}
+
+// waitJobs ждёт, пока все задания шелла завершатся
+func waitJobs(t *testing.T, shell *Shell) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		running := false
+		shell.jobs.mu.Lock()
+		for _, j := range shell.jobs.jobs {
+			if j.state() != jobDone {
+				running = true
+			}
+		}
+		shell.jobs.mu.Unlock()
+
+		if !running {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("jobs did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBackgroundJobs(t *testing.T) {
+
+	shell := newTestShell(t)
+
+	start := time.Now()
+	out, errOut, status := runScript(t, shell, "sleep 0.3 > done.txt & echo started")
+	if out != "started\n" || status != 0 {
+		t.Fatalf("stdout = %q, status = %d (stderr %q)", out, status, errOut)
+	}
+	if time.Since(start) > 250*time.Millisecond {
+		t.Error("background job blocked the shell")
+	}
+
+	out, _, _ = runScript(t, shell, "jobs")
+	if want := "[1]+  Выполняется  sleep 0.3 >done.txt &\n"; out != want {
+		t.Errorf("jobs = %q, want %q", out, want)
+	}
+
+	// Ctrl+C достаётся только заданию переднего плана
+	if shell.jobs.signalForeground(syscall.SIGINT) {
+		t.Error("SIGINT delivered while no foreground job")
+	}
+
+	// о завершении сообщается один раз, после этого задание удаляется
+	waitJobs(t, shell)
+	var report bytes.Buffer
+	shell.jobs.report(&report, false, false)
+	if want := "[1]+  Завершено    sleep 0.3 >done.txt\n"; report.String() != want {
+		t.Errorf("report = %q, want %q", report.String(), want)
+	}
+	report.Reset()
+	shell.jobs.report(&report, false, false)
+	if report.Len() != 0 {
+		t.Errorf("second report = %q", report.String())
+	}
+	if _, err := os.Stat(filepath.Join(shell.dir, "done.txt")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBackgroundJobGroupAndKill(t *testing.T) {
+
+	shell := newTestShell(t)
+
+	runScript(t, shell, "sleep 10 & sleep 10 | cat &")
+	out, _, _ := runScript(t, shell, "jobs -l")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("jobs -l = %q", out)
+	}
+
+	// у каждого фонового задания своя группа процессов, отличная от группы шелла
+	groups := map[string]bool{strconv.Itoa(syscall.Getpgrp()): true}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if groups[fields[1]] {
+			t.Errorf("job shares process group: %q", line)
+		}
+		groups[fields[1]] = true
+	}
+
+	// kill %N отправляет сигнал всей группе задания
+	_, errOut, status := runScript(t, shell, "kill %2; kill -KILL %1")
+	if status != 0 {
+		t.Fatalf("kill status = %d (stderr %q)", status, errOut)
+	}
+	waitJobs(t, shell)
+	out, _, _ = runScript(t, shell, "jobs")
+	want := "[1]-  Выход 137    sleep 10\n[2]+  Выход 143    sleep 10 | cat\n"
+	if out != want {
+		t.Errorf("jobs = %q, want %q", out, want)
+	}
+}
+
+func TestStoppedJobFgBg(t *testing.T) {
+
+	shell := newTestShell(t)
+
+	// команда останавливает сама себя, как при Ctrl+Z
+	out, errOut, status := runScript(t, shell, `sh -c 'kill -STOP $$; echo resumed' > fg.txt`)
+	if out != "" || status != 128+int(syscall.SIGSTOP) {
+		t.Fatalf("stdout = %q, status = %d", out, status)
+	}
+	if want := "\n[1]+  Остановлено  sh -c 'kill -STOP $$; echo resumed'\n"; errOut != want {
+		t.Errorf("stderr = %q, want %q", errOut, want)
+	}
+
+	out, _, _ = runScript(t, shell, "jobs")
+	if want := "[1]+  Остановлено  sh -c 'kill -STOP $$; echo resumed'\n"; out != want {
+		t.Errorf("jobs = %q, want %q", out, want)
+	}
+
+	// fg продолжает задание и ждёт его
+	out, errOut, status = runScript(t, shell, "fg %1")
+	if want := "sh -c 'kill -STOP $$; echo resumed'\n"; out != want || status != 0 {
+		t.Errorf("fg: stdout = %q, status = %d (stderr %q)", out, status, errOut)
+	}
+	if data, err := os.ReadFile(filepath.Join(shell.dir, "fg.txt")); err != nil || string(data) != "resumed\n" {
+		t.Errorf("fg.txt = %q, %v", data, err)
+	}
+	if out, _, _ = runScript(t, shell, "jobs"); out != "" {
+		t.Errorf("jobs after fg = %q", out)
+	}
+
+	// bg продолжает задание в фоне
+	runScript(t, shell, `sh -c 'kill -STOP $$; echo bg > bg.txt'`)
+	out, errOut, status = runScript(t, shell, "bg")
+	if want := "[1] sh -c 'kill -STOP $$; echo bg > bg.txt' &\n"; out != want || status != 0 {
+		t.Errorf("bg: stdout = %q, status = %d (stderr %q)", out, status, errOut)
+	}
+	waitJobs(t, shell)
+	if data, err := os.ReadFile(filepath.Join(shell.dir, "bg.txt")); err != nil || string(data) != "bg\n" {
+		t.Errorf("bg.txt = %q, %v", data, err)
+	}
+}
+
+func TestJobErrors(t *testing.T) {
+
+	shell := newTestShell(t)
+
+	for _, src := range []string{"fg", "bg", "fg %3", "kill %1"} {
+		_, errOut, status := runScript(t, shell, src)
+		if status != 1 || !strings.Contains(errOut, "задани") {
+			t.Errorf("%s: status = %d, stderr = %q", src, status, errOut)
+		}
+	}
+
+	runScript(t, shell, "sleep 10 &")
+	_, errOut, status := runScript(t, shell, "bg")
+	if status != 1 || !strings.Contains(errOut, "уже выполняется") {
+		t.Errorf("bg running job: status = %d, stderr = %q", status, errOut)
+	}
+	runScript(t, shell, "kill %1")
+	waitJobs(t, shell)
+}
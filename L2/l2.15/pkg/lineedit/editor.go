@@ -0,0 +1,638 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+)
+
+// ErrInterrupted - ввод строки прерван Ctrl+C
+var ErrInterrupted = errors.New("ввод прерван")
+
+// Completer возвращает варианты дополнения слова перед курсором: позицию начала слова в line
+// и строки, которыми можно заменить line[start:pos]
+type Completer func(line []rune, pos int) (start int, candidates []string)
+
+// Editor - редактор строки для терминала: перемещение курсора, история (стрелки, Ctrl+R),
+// дополнение по Tab. Терминал переводится в посимвольный режим только на время ReadLine
+type Editor struct {
+	History  *History
+	Complete Completer
+
+	in    *bufio.Reader
+	out   io.Writer
+	fd    int              // терминал (-1 - режим терминала не переключается, например в тестах)
+	saved *syscall.Termios // режим терминала на момент создания редактора
+
+	// состояние редактируемой строки
+	prompt  string // последняя строка приглашения
+	buf     []rune
+	pos     int
+	offset  int    // первый видимый символ, если строка не помещается в ширину терминала
+	histIdx int    // позиция в истории (History.Len() - новая строка)
+	draft   []rune // новая строка, пока листаем историю
+	pending []keyPress
+}
+
+// New создаёт редактор, читающий клавиши из in и рисующий строку в out.
+// fd - дескриптор терминала для переключения режима или -1
+func New(in io.Reader, out io.Writer, fd int) *Editor {
+
+	e := &Editor{
+		History: NewHistory(1000),
+		in:      bufio.NewReader(in),
+		out:     out,
+		fd:      -1,
+	}
+
+	if fd >= 0 {
+		if saved, err := getTermios(fd); err == nil {
+			e.fd, e.saved = fd, saved
+		}
+	}
+
+	return e
+}
+
+// keyCode - распознанная клавиша
+type keyCode int
+
+const (
+	keyNone keyCode = iota
+	keyRune         // печатный символ
+	keyEnter
+	keyBackspace
+	keyDelete
+	keyTab
+	keyLeft
+	keyRight
+	keyWordLeft
+	keyWordRight
+	keyHome
+	keyEnd
+	keyUp
+	keyDown
+	keyKillStart // Ctrl+U
+	keyKillEnd   // Ctrl+K
+	keyKillWord  // Ctrl+W
+	keyClear     // Ctrl+L
+	keySearch    // Ctrl+R
+	keyInterrupt // Ctrl+C
+	keyCancel    // Ctrl+G
+	keyEOF       // Ctrl+D
+)
+
+type keyPress struct {
+	code keyCode
+	r    rune
+}
+
+// управляющие символы (Ctrl+буква) и соответствующие им клавиши
+var controlKeys = map[rune]keyCode{
+	'\r': keyEnter,
+	'\n': keyEnter,
+	'\t': keyTab,
+	127:  keyBackspace,
+	8:    keyBackspace,
+	1:    keyHome,      // Ctrl+A
+	5:    keyEnd,       // Ctrl+E
+	2:    keyLeft,      // Ctrl+B
+	6:    keyRight,     // Ctrl+F
+	16:   keyUp,        // Ctrl+P
+	14:   keyDown,      // Ctrl+N
+	21:   keyKillStart, // Ctrl+U
+	11:   keyKillEnd,   // Ctrl+K
+	23:   keyKillWord,  // Ctrl+W
+	12:   keyClear,     // Ctrl+L
+	18:   keySearch,    // Ctrl+R
+	3:    keyInterrupt, // Ctrl+C
+	7:    keyCancel,    // Ctrl+G
+	4:    keyEOF,       // Ctrl+D
+}
+
+// readKey читает одну клавишу (символ, управляющий символ или escape-последовательность)
+func (e *Editor) readKey() (keyPress, error) {
+
+	if len(e.pending) > 0 {
+		k := e.pending[0]
+		e.pending = e.pending[1:]
+		return k, nil
+	}
+
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return keyPress{}, err
+	}
+
+	if r == 27 {
+		return e.readEscape()
+	}
+	if code, ok := controlKeys[r]; ok {
+		return keyPress{code: code}, nil
+	}
+	if r < 32 {
+		// прочие управляющие символы игнорируем
+		return keyPress{code: keyNone}, nil
+	}
+
+	return keyPress{code: keyRune, r: r}, nil
+}
+
+// readEscape разбирает последовательность после ESC: стрелки, Home/End/Delete, Alt+b/f
+func (e *Editor) readEscape() (keyPress, error) {
+
+	r, _, err := e.in.ReadRune()
+	if err != nil {
+		return keyPress{}, err
+	}
+
+	switch r {
+	case 'b':
+		return keyPress{code: keyWordLeft}, nil
+	case 'f':
+		return keyPress{code: keyWordRight}, nil
+	case 127:
+		return keyPress{code: keyKillWord}, nil
+	case '[', 'O':
+	default:
+		return keyPress{code: keyNone}, nil
+	}
+
+	// CSI: числовые параметры через ; и завершающий символ
+	var params strings.Builder
+	var final rune
+	for {
+		c, _, err := e.in.ReadRune()
+		if err != nil {
+			return keyPress{}, err
+		}
+		if (c >= '0' && c <= '9') || c == ';' {
+			params.WriteRune(c)
+			continue
+		}
+		final = c
+		break
+	}
+
+	// ;5 - с Ctrl, ;3 - с Alt: стрелки влево/вправо двигают по словам
+	modified := strings.HasSuffix(params.String(), ";5") || strings.HasSuffix(params.String(), ";3")
+
+	switch final {
+	case 'A':
+		return keyPress{code: keyUp}, nil
+	case 'B':
+		return keyPress{code: keyDown}, nil
+	case 'C':
+		if modified {
+			return keyPress{code: keyWordRight}, nil
+		}
+		return keyPress{code: keyRight}, nil
+	case 'D':
+		if modified {
+			return keyPress{code: keyWordLeft}, nil
+		}
+		return keyPress{code: keyLeft}, nil
+	case 'H':
+		return keyPress{code: keyHome}, nil
+	case 'F':
+		return keyPress{code: keyEnd}, nil
+	case '~':
+		switch strings.Split(params.String(), ";")[0] {
+		case "1", "7":
+			return keyPress{code: keyHome}, nil
+		case "4", "8":
+			return keyPress{code: keyEnd}, nil
+		case "3":
+			return keyPress{code: keyDelete}, nil
+		}
+	}
+
+	return keyPress{code: keyNone}, nil
+}
+
+// ReadLine читает строку с редактированием. Ctrl+D на пустой строке возвращает io.EOF,
+// Ctrl+C - ErrInterrupted. Приглашение может быть многострочным и содержать ANSI-цвета
+func (e *Editor) ReadLine(prompt string) (string, error) {
+
+	if e.fd >= 0 {
+		if err := makeRaw(e.fd, e.saved); err == nil {
+			defer setTermios(e.fd, e.saved)
+		}
+	}
+
+	// строки приглашения до последней печатаем один раз, перерисовывается только последняя
+	if i := strings.LastIndexByte(prompt, '\n'); i >= 0 {
+		fmt.Fprint(e.out, prompt[:i+1])
+		prompt = prompt[i+1:]
+	}
+
+	e.prompt = prompt
+	e.buf, e.pos, e.offset = nil, 0, 0
+	e.histIdx, e.draft = e.History.Len(), nil
+	e.refresh()
+
+	lastTab := false
+	for {
+		k, err := e.readKey()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(e.buf) > 0 {
+				e.finish()
+				return string(e.buf), nil
+			}
+			return "", err
+		}
+
+		switch k.code {
+		case keyEnter:
+			e.finish()
+			return string(e.buf), nil
+
+		case keyInterrupt:
+			e.pos = len(e.buf)
+			e.refresh()
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupted
+
+		case keyEOF:
+			if len(e.buf) == 0 {
+				return "", io.EOF
+			}
+			e.edit(keyPress{code: keyDelete})
+
+		case keySearch:
+			enter, err := e.reverseSearch()
+			if err != nil {
+				return "", err
+			}
+			if enter {
+				e.finish()
+				return string(e.buf), nil
+			}
+
+		case keyTab:
+			e.complete(lastTab)
+
+		default:
+			e.edit(k)
+		}
+
+		lastTab = k.code == keyTab
+	}
+}
+
+// finish ставит курсор в конец строки и переводит строку
+func (e *Editor) finish() {
+
+	e.pos = len(e.buf)
+	e.refresh()
+	fmt.Fprint(e.out, "\r\n")
+}
+
+// edit выполняет клавишу редактирования
+func (e *Editor) edit(k keyPress) {
+
+	switch k.code {
+	case keyRune:
+		e.buf = append(e.buf[:e.pos], append([]rune{k.r}, e.buf[e.pos:]...)...)
+		e.pos++
+	case keyBackspace:
+		if e.pos > 0 {
+			e.buf = append(e.buf[:e.pos-1], e.buf[e.pos:]...)
+			e.pos--
+		}
+	case keyDelete:
+		if e.pos < len(e.buf) {
+			e.buf = append(e.buf[:e.pos], e.buf[e.pos+1:]...)
+		}
+	case keyLeft:
+		if e.pos > 0 {
+			e.pos--
+		}
+	case keyRight:
+		if e.pos < len(e.buf) {
+			e.pos++
+		}
+	case keyHome:
+		e.pos = 0
+	case keyEnd:
+		e.pos = len(e.buf)
+	case keyWordLeft:
+		e.pos = e.wordStart()
+	case keyWordRight:
+		for e.pos < len(e.buf) && e.buf[e.pos] == ' ' {
+			e.pos++
+		}
+		for e.pos < len(e.buf) && e.buf[e.pos] != ' ' {
+			e.pos++
+		}
+	case keyKillStart:
+		e.buf = append([]rune(nil), e.buf[e.pos:]...)
+		e.pos = 0
+	case keyKillEnd:
+		e.buf = e.buf[:e.pos]
+	case keyKillWord:
+		start := e.wordStart()
+		e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+		e.pos = start
+	case keyUp:
+		e.historyPrev()
+	case keyDown:
+		e.historyNext()
+	case keyClear:
+		fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+	default:
+		return
+	}
+
+	e.refresh()
+}
+
+// wordStart возвращает начало слова перед курсором
+func (e *Editor) wordStart() int {
+
+	i := e.pos
+	for i > 0 && e.buf[i-1] == ' ' {
+		i--
+	}
+	for i > 0 && e.buf[i-1] != ' ' {
+		i--
+	}
+
+	return i
+}
+
+// setLine заменяет редактируемую строку, курсор - в конец
+func (e *Editor) setLine(s string) {
+
+	e.buf = []rune(s)
+	e.pos = len(e.buf)
+}
+
+// historyPrev показывает предыдущую команду из истории
+func (e *Editor) historyPrev() {
+
+	if e.histIdx == 0 {
+		return
+	}
+	if e.histIdx == e.History.Len() {
+		e.draft = append([]rune(nil), e.buf...)
+	}
+	e.histIdx--
+	e.setLine(e.History.At(e.histIdx))
+}
+
+// historyNext показывает следующую команду из истории или возвращает новую строку
+func (e *Editor) historyNext() {
+
+	if e.histIdx >= e.History.Len() {
+		return
+	}
+	e.histIdx++
+	if e.histIdx == e.History.Len() {
+		e.setLine(string(e.draft))
+		return
+	}
+	e.setLine(e.History.At(e.histIdx))
+}
+
+// reverseSearch - поиск по истории (Ctrl+R): набранный текст ищется в командах от новых к старым,
+// повторный Ctrl+R ищет более старое совпадение. Enter выполняет найденную команду,
+// другие клавиши переносят её в строку для редактирования, Ctrl+C и Ctrl+G отменяют поиск.
+// Возвращает true, если команду нужно выполнить сразу
+func (e *Editor) reverseSearch() (bool, error) {
+
+	origBuf, origPos := append([]rune(nil), e.buf...), e.pos
+	var query []rune
+	match, found, failed := e.History.Len(), "", false
+
+	search := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(e.History.At(i), string(query)) {
+				match, found, failed = i, e.History.At(i), false
+				return
+			}
+		}
+		failed = true
+	}
+
+	for {
+		status := "reverse-i-search"
+		if failed {
+			status = "failed reverse-i-search"
+		}
+		line := fmt.Sprintf("(%s)`%s': %s", status, string(query), display([]rune(found)))
+		fmt.Fprint(e.out, "\r"+line+"\x1b[K")
+		// курсор - на начале совпадения
+		if i := strings.Index(found, string(query)); found != "" && i >= 0 {
+			if back := len([]rune(found[i:])); back > 0 {
+				fmt.Fprintf(e.out, "\x1b[%dD", back)
+			}
+		}
+
+		k, err := e.readKey()
+		if err != nil {
+			return false, err
+		}
+
+		switch k.code {
+		case keyRune:
+			query = append(query, k.r)
+			search(min(match, e.History.Len()-1))
+
+		case keyBackspace:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+			match, found, failed = e.History.Len(), "", false
+			if len(query) > 0 {
+				search(e.History.Len() - 1)
+			}
+
+		case keySearch:
+			if len(query) > 0 && match > 0 {
+				search(match - 1)
+			}
+
+		case keyInterrupt, keyCancel:
+			e.buf, e.pos = origBuf, origPos
+			e.refresh()
+			return false, nil
+
+		case keyEnter:
+			if found != "" {
+				e.setLine(found)
+				e.histIdx = match
+			}
+			return true, nil
+
+		default:
+			if found != "" {
+				e.setLine(found)
+				e.histIdx = match
+			}
+			e.refresh()
+			if k.code != keyNone {
+				e.pending = append(e.pending, k)
+			}
+			return false, nil
+		}
+	}
+}
+
+// complete дополняет слово перед курсором: единственный вариант подставляется целиком,
+// общее начало нескольких - до места расхождения, повторный Tab печатает все варианты
+func (e *Editor) complete(again bool) {
+
+	if e.Complete == nil {
+		return
+	}
+
+	start, candidates := e.Complete(e.buf, e.pos)
+	if len(candidates) == 0 {
+		fmt.Fprint(e.out, "\a")
+		return
+	}
+
+	if len(candidates) == 1 {
+		text := candidates[0]
+		if !strings.HasSuffix(text, "/") {
+			text += " "
+		}
+		e.replace(start, text)
+		return
+	}
+
+	if prefix := commonPrefix(candidates); len([]rune(prefix)) > e.pos-start {
+		e.replace(start, prefix)
+		return
+	}
+	if !again {
+		fmt.Fprint(e.out, "\a")
+		return
+	}
+
+	fmt.Fprint(e.out, "\r\n"+e.columns(candidates))
+	e.refresh()
+}
+
+// replace заменяет текст от start до курсора
+func (e *Editor) replace(start int, text string) {
+
+	tail := append([]rune(nil), e.buf[e.pos:]...)
+	e.buf = append(append(e.buf[:start], []rune(text)...), tail...)
+	e.pos = start + len([]rune(text))
+	e.refresh()
+}
+
+// columns раскладывает варианты дополнения по колонкам (для путей - только последний компонент)
+func (e *Editor) columns(candidates []string) string {
+
+	names := make([]string, len(candidates))
+	width := 0
+	for i, c := range candidates {
+		trimmed := strings.TrimSuffix(c, "/")
+		names[i] = c[strings.LastIndexByte(trimmed, '/')+1:]
+		width = max(width, len([]rune(names[i])))
+	}
+	width += 2
+
+	cols := max(1, e.width()/width)
+	if e.width() == 0 {
+		cols = max(1, 80/width)
+	}
+
+	var sb strings.Builder
+	for i, name := range names {
+		sb.WriteString(name)
+		if (i+1)%cols == 0 || i == len(names)-1 {
+			sb.WriteString("\r\n")
+			continue
+		}
+		sb.WriteString(strings.Repeat(" ", width-len([]rune(name))))
+	}
+
+	return sb.String()
+}
+
+// width возвращает ширину терминала (0 - неизвестна, строка не прокручивается)
+func (e *Editor) width() int {
+
+	if e.fd < 0 {
+		return 0
+	}
+
+	return termWidth(e.fd)
+}
+
+// refresh перерисовывает строку приглашения с текстом и ставит курсор.
+// Если строка не помещается в ширину терминала, показывается окно вокруг курсора
+func (e *Editor) refresh() {
+
+	text, pos := e.buf, e.pos
+	if width := e.width(); width > 0 {
+		avail := max(1, width-visibleWidth(e.prompt)-1)
+		if e.pos < e.offset {
+			e.offset = e.pos
+		}
+		if e.pos > e.offset+avail {
+			e.offset = e.pos - avail
+		}
+		e.offset = min(e.offset, len(e.buf))
+		text = e.buf[e.offset:min(len(e.buf), e.offset+avail)]
+		pos = e.pos - e.offset
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\r" + e.prompt + display(text) + "\x1b[K")
+	if back := len(text) - pos; back > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dD", back)
+	}
+
+	fmt.Fprint(e.out, sb.String())
+}
+
+// display заменяет переводы строк многострочных команд из истории на видимый символ
+func display(text []rune) string {
+	return strings.ReplaceAll(string(text), "\n", "↵")
+}
+
+// visibleWidth возвращает ширину строки на экране без ANSI-последовательностей
+// и маркеров \001 \002, которыми в приглашении отмечают непечатаемые символы
+func visibleWidth(s string) int {
+
+	width := 0
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == 27 && i+1 < len(runes) && runes[i+1] == '[':
+			i += 2
+			for i < len(runes) && !(runes[i] >= '@' && runes[i] <= '~') {
+				i++
+			}
+		case r == 1 || r == 2:
+		default:
+			width++
+		}
+	}
+
+	return width
+}
+
+// commonPrefix возвращает общее начало строк
+func commonPrefix(items []string) string {
+
+	prefix := []rune(items[0])
+	for _, item := range items[1:] {
+		r := []rune(item)
+		n := 0
+		for n < len(prefix) && n < len(r) && prefix[n] == r[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+
+	return string(prefix)
+}
@@ -0,0 +1,253 @@
+package lineedit
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// клавиши в виде байтов терминала
+const (
+	up        = "\x1b[A"
+	down      = "\x1b[B"
+	left      = "\x1b[D"
+	right     = "\x1b[C"
+	home      = "\x1b[H"
+	del       = "\x1b[3~"
+	wordLeft  = "\x1b[1;5D"
+	backspace = "\x7f"
+	ctrlC     = "\x03"
+	ctrlD     = "\x04"
+	ctrlR     = "\x12"
+	ctrlU     = "\x15"
+	ctrlK     = "\x0b"
+	ctrlW     = "\x17"
+)
+
+// readLines читает строки редактором из input, пока не кончится ввод
+func readLines(t *testing.T, e *Editor) []string {
+
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := e.ReadLine("> ")
+		if errors.Is(err, io.EOF) {
+			return lines
+		}
+		if errors.Is(err, ErrInterrupted) {
+			lines = append(lines, "<^C>")
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func newTestEditor(input string, history ...string) (*Editor, *strings.Builder) {
+
+	var out strings.Builder
+	e := New(strings.NewReader(input), &out, -1)
+	for _, line := range history {
+		e.History.Add(line)
+	}
+
+	return e, &out
+}
+
+func TestReadLineEditing(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"текст", "echo hi\r", "echo hi"},
+		{"вставка в середину", "ech hi" + left + left + left + "o\r", "echo hi"},
+		{"backspace и delete", "echoo" + backspace + " hx" + left + del + "i\r", "echo hi"},
+		{"home", "cho" + home + "e\r", "echo"},
+		{"Ctrl+A и Ctrl+E", "b\x01a\x05c\r", "abc"},
+		{"Ctrl+U", "garbage" + ctrlU + "ls\r", "ls"},
+		{"Ctrl+K", "ls -la" + left + left + left + left + ctrlK + "\r", "ls"},
+		{"Ctrl+W", "echo one two" + ctrlW + "three\r", "echo one three"},
+		{"по словам", "one three" + wordLeft + "two \r", "one two three"},
+		{"юникод", "echo привт" + left + "е\r", "echo привет"},
+		{"Ctrl+D удаляет символ", "ab" + left + ctrlD + "\r", "a"},
+		{"перевод строки", "ls\n", "ls"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := newTestEditor(tt.input)
+			got, err := e.ReadLine("> ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLineControl(t *testing.T) {
+
+	e, out := newTestEditor("abc" + ctrlC + "ls\r" + ctrlD)
+	got := readLines(t, e)
+	if want := []string{"<^C>", "ls"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+	if !strings.Contains(out.String(), "abc\x1b[K^C\r\n") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	// конец ввода без Enter возвращает набранное
+	e, _ = newTestEditor("tail")
+	if line, err := e.ReadLine("> "); line != "tail" || err != nil {
+		t.Errorf("ReadLine() = %q, %v", line, err)
+	}
+}
+
+func TestReadLineHistory(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"стрелка вверх", up + "\r", "third"},
+		{"дважды вверх", up + up + "\r", "second"},
+		{"за начало истории", up + up + up + up + up + "\r", "first"},
+		{"вверх и вниз", up + up + down + "\r", "third"},
+		{"черновик сохраняется", "new" + up + up + down + down + "\r", "new"},
+		{"правка найденной", up + " x\r", "third x"},
+		{"поиск", ctrlR + "sec\r", "second"},
+		{"поиск дальше", ctrlR + "i" + ctrlR + "\r", "first"},
+		{"поиск и правка", ctrlR + "fir" + right + "!\r", "first!"},
+		{"отмена поиска", "keep" + ctrlR + "th" + ctrlC + "\r", "keep"},
+		{"backspace в поиске", ctrlR + "thx" + backspace + "\r", "third"},
+		{"ничего не найдено", ctrlR + "zzz\r", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := newTestEditor(tt.input, "first", "second", "third")
+			got, err := e.ReadLine("> ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLineComplete(t *testing.T) {
+
+	words := []string{"build", "bundle", "bg", "cat"}
+	complete := func(line []rune, pos int) (int, []string) {
+		start := strings.LastIndexByte(string(line[:pos]), ' ') + 1
+		start = len([]rune(string(line[:pos])[:start]))
+		var result []string
+		for _, w := range words {
+			if strings.HasPrefix(w, string(line[start:pos])) {
+				result = append(result, w)
+			}
+		}
+		return start, result
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"единственный вариант", "ca\t\r", "cat "},
+		{"общее начало", "bu\t\r", "bu"},
+		{"общее начало дописывается", "bui\t\r", "build "},
+		{"второе слово", "cat bun\tx\r", "cat bundle x"},
+		{"нет вариантов", "zz\t\r", "zz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := newTestEditor(tt.input)
+			e.Complete = complete
+			got, err := e.ReadLine("> ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// повторный Tab печатает варианты
+	e, out := newTestEditor("b\t\t\r")
+	e.Complete = complete
+	e.ReadLine("> ")
+	if !strings.Contains(out.String(), "build   bundle  bg") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestReadLinePrompt(t *testing.T) {
+
+	e, out := newTestEditor("x\r")
+	e.ReadLine("line1\n\x1b[32mgreen\x1b[0m> ")
+
+	// первые строки приглашения печатаются один раз, последняя - при каждой перерисовке
+	if n := strings.Count(out.String(), "line1"); n != 1 {
+		t.Errorf("first prompt line printed %d times: %q", n, out.String())
+	}
+	if w := visibleWidth("\x1b[32mgreen\x1b[0m> "); w != 7 {
+		t.Errorf("visibleWidth = %d, want 7", w)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "history")
+
+	h, err := LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"one", "", "two", "two", "multi\nline \\n", "four"} {
+		if err := h.Add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(h.Entries(), "|"); got != "two|multi\nline \\n|four" {
+		t.Errorf("entries = %q", got)
+	}
+
+	// файл дописывается, при загрузке лишнее отбрасывается и файл сжимается
+	h, err = LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(h.Entries(), "|"); got != "two|multi\nline \\n|four" {
+		t.Errorf("loaded entries = %q", got)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "two\nmulti\\nline \\\\n\nfour\n"; string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+
+	// отсутствующий файл - пустая история
+	h, err = LoadHistory(filepath.Join(t.TempDir(), "none"), 10)
+	if err != nil || h.Len() != 0 {
+		t.Errorf("LoadHistory(missing) = %d entries, %v", h.Len(), err)
+	}
+}
@@ -0,0 +1,135 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+)
+
+// History - история введённых команд. Если задан файл, каждая команда сразу дописывается в него,
+// поэтому история не теряется при аварийном завершении и общая у нескольких шеллов
+type History struct {
+	entries []string
+	path    string // файл истории ("" - только в памяти)
+	max     int    // сколько команд хранить
+}
+
+// NewHistory создаёт историю в памяти на max команд
+func NewHistory(max int) *History {
+	return &History{max: max}
+}
+
+// LoadHistory читает историю из файла (отсутствующий файл - пустая история).
+// Если в файле накопилось больше max команд, он переписывается последними max
+func LoadHistory(path string, max int) (*History, error) {
+
+	h := &History{path: path, max: max}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+
+	var lines int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+		h.entries = append(h.entries, decodeEntry(scanner.Text()))
+	}
+	file.Close()
+	if err := scanner.Err(); err != nil {
+		return h, err
+	}
+
+	if len(h.entries) > max {
+		h.entries = h.entries[len(h.entries)-max:]
+	}
+	if lines > max {
+		return h, h.rewrite()
+	}
+
+	return h, nil
+}
+
+// Len возвращает число команд в истории
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// At возвращает команду с номером i (0 - самая старая)
+func (h *History) At(i int) string {
+	return h.entries[i]
+}
+
+// Entries возвращает копию истории
+func (h *History) Entries() []string {
+	return append([]string(nil), h.entries...)
+}
+
+// Add добавляет команду в историю. Пустые строки и повтор предыдущей команды не сохраняются
+func (h *History) Add(line string) error {
+
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == line {
+		return nil
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > h.max {
+		h.entries = h.entries[len(h.entries)-h.max:]
+	}
+
+	if h.path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(encodeEntry(line) + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// rewrite записывает файл истории заново
+func (h *History) rewrite() error {
+
+	var sb strings.Builder
+	for _, entry := range h.entries {
+		sb.WriteString(encodeEntry(entry) + "\n")
+	}
+
+	return os.WriteFile(h.path, []byte(sb.String()), 0600)
+}
+
+// encodeEntry записывает многострочную команду одной строкой файла: \ -> \\, перевод строки -> \n
+func encodeEntry(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func decodeEntry(s string) string {
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' {
+				sb.WriteByte('\n')
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+//go:build linux
+
+package lineedit
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// IsTerminal сообщает, что дескриптор - терминал
+func IsTerminal(fd int) bool {
+
+	_, err := getTermios(fd)
+
+	return err == nil
+}
+
+func getTermios(fd int) (*syscall.Termios, error) {
+
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCGETS, uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		return nil, errno
+	}
+
+	return &t, nil
+}
+
+func setTermios(fd int, t *syscall.Termios) error {
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCSETS, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// makeRaw переводит терминал в посимвольный режим без эха и без сигналов от Ctrl+C/Ctrl+Z
+// (их обрабатывает редактор). Вывод не трогаем: \n по-прежнему переводит строку
+func makeRaw(fd int, saved *syscall.Termios) error {
+
+	raw := *saved
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON | syscall.INLCR | syscall.IGNCR | syscall.ISTRIP
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	return setTermios(fd, &raw)
+}
+
+// termWidth возвращает ширину терминала в колонках (0, если неизвестна)
+func termWidth(fd int) int {
+
+	var ws struct{ rows, cols, x, y uint16 }
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0
+	}
+
+	return int(ws.cols)
+}
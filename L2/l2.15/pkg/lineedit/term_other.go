@@ -0,0 +1,30 @@
+//go:build !linux
+
+package lineedit
+
+import (
+	"errors"
+	"syscall"
+)
+
+// без Linux редактор не переключает терминал: шелл читает строки построчно
+
+func IsTerminal(fd int) bool {
+	return false
+}
+
+func getTermios(fd int) (*syscall.Termios, error) {
+	return nil, errors.New("терминал не поддерживается")
+}
+
+func setTermios(fd int, t *syscall.Termios) error {
+	return errors.New("терминал не поддерживается")
+}
+
+func makeRaw(fd int, saved *syscall.Termios) error {
+	return errors.New("терминал не поддерживается")
+}
+
+func termWidth(fd int) int {
+	return 0
+}
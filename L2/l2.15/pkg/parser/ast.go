@@ -0,0 +1,346 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PartKind - вид части слова, от него зависят подстановки при выполнении
+type PartKind int
+
+const (
+	Lit      PartKind = iota // текст без кавычек
+	Quoted                   // текст в одинарных кавычках или экранированный символ - подстановок нет
+	DQuoted                  // текст в двойных кавычках
+	CmdSubst                 // подстановка команды $( ... )
+)
+
+// WordPart - непрерывный кусок слова одного вида
+type WordPart struct {
+	Kind     PartKind
+	Text     string
+	InQuotes bool  // для CmdSubst: подстановка внутри двойных кавычек (без разбиения на слова)
+	Cmd      *List // для CmdSubst: разобранная команда
+}
+
+// Word - слово командной строки (аргумент, имя файла редиректа), кавычки сохранены в частях
+type Word struct {
+	Parts []WordPart
+}
+
+// Value возвращает значение слова без кавычек (без подстановок)
+func (w Word) Value() string {
+
+	var sb strings.Builder
+	for _, part := range w.Parts {
+		if part.Kind == CmdSubst {
+			sb.WriteString("$(" + part.Text + ")")
+			continue
+		}
+		sb.WriteString(part.Text)
+	}
+
+	return sb.String()
+}
+
+// IsLiteral сообщает, что слово целиком записано без кавычек и равно s (так распознаются { } !)
+func (w Word) IsLiteral(s string) bool {
+	return len(w.Parts) == 1 && w.Parts[0].Kind == Lit && w.Parts[0].Text == s
+}
+
+// String возвращает слово в виде исходного текста с кавычками
+func (w Word) String() string {
+
+	var sb strings.Builder
+	for _, part := range w.Parts {
+		switch part.Kind {
+		case Quoted:
+			sb.WriteString("'" + part.Text + "'")
+		case DQuoted:
+			sb.WriteString(`"` + part.Text + `"`)
+		case CmdSubst:
+			if part.InQuotes {
+				sb.WriteString(`"$(` + part.Text + `)"`)
+			} else {
+				sb.WriteString("$(" + part.Text + ")")
+			}
+		default:
+			sb.WriteString(part.Text)
+		}
+	}
+
+	return sb.String()
+}
+
+// RedirOp - вид редиректа
+type RedirOp int
+
+const (
+	RedirIn        RedirOp = iota // <
+	RedirOut                      // >
+	RedirAppend                   // >>
+	RedirDupIn                    // <&
+	RedirDupOut                   // >&
+	RedirAll                      // &> (stdout и stderr в файл)
+	RedirAllAppend                // &>>
+)
+
+var redirOpNames = map[RedirOp]string{
+	RedirIn:        "<",
+	RedirOut:       ">",
+	RedirAppend:    ">>",
+	RedirDupIn:     "<&",
+	RedirDupOut:    ">&",
+	RedirAll:       "&>",
+	RedirAllAppend: "&>>",
+}
+
+func (op RedirOp) String() string {
+	return redirOpNames[op]
+}
+
+// Redirect - перенаправление потока: Fd Op Target (например 2>err.log, 2>&1)
+type Redirect struct {
+	Fd     int // номер дескриптора слева (для < по умолчанию 0, для > - 1)
+	Op     RedirOp
+	Target Word
+}
+
+func (r Redirect) String() string {
+
+	fd := ""
+	if r.Op != RedirAll && r.Op != RedirAllAppend {
+		defaultFd := 1
+		if r.Op == RedirIn || r.Op == RedirDupIn {
+			defaultFd = 0
+		}
+		if r.Fd != defaultFd {
+			fd = strconv.Itoa(r.Fd)
+		}
+	}
+
+	return fd + r.Op.String() + r.Target.String()
+}
+
+// Node - узел синтаксического дерева
+type Node interface {
+	String() string
+	node()
+}
+
+// List - последовательность команд через ;, & или перевод строки
+type List struct {
+	Items []Node
+}
+
+// AndOr - условное выполнение: Left && Right или Left || Right
+type AndOr struct {
+	Op    string // "&&" или "||"
+	Left  Node
+	Right Node
+}
+
+// Pipeline - конвейер из двух и более команд (или одна команда с !)
+type Pipeline struct {
+	Negated  bool // ! инвертирует статус конвейера
+	Commands []Node
+}
+
+// Assignment - присваивание NAME=value перед командой
+type Assignment struct {
+	Name  string
+	Value Word
+}
+
+func (a Assignment) String() string {
+	return a.Name + "=" + a.Value.String()
+}
+
+// SimpleCommand - простая команда: присваивания, аргументы и редиректы.
+// Присваивания без аргументов задают переменные шелла, с аргументами - окружение команды
+type SimpleCommand struct {
+	Assigns   []Assignment
+	Args      []Word
+	Redirects []Redirect
+}
+
+// Subshell - ( список ), выполняется в отдельном окружении
+type Subshell struct {
+	Body      *List
+	Redirects []Redirect
+}
+
+// Group - { список; }, выполняется в текущем окружении
+type Group struct {
+	Body      *List
+	Redirects []Redirect
+}
+
+// Background - команда, запущенная в фоне через &
+type Background struct {
+	Node Node
+}
+
+// IfClause - условие и ветка if или elif
+type IfClause struct {
+	Cond *List
+	Body *List
+}
+
+// If - if условие; then ...; elif ...; else ...; fi
+type If struct {
+	Clauses   []IfClause // if и следующие за ним elif
+	Else      *List      // nil - ветки else нет
+	Redirects []Redirect
+}
+
+// While - while условие; do ...; done (с Until - until: цикл, пока условие ложно)
+type While struct {
+	Until     bool
+	Cond      *List
+	Body      *List
+	Redirects []Redirect
+}
+
+// For - for NAME in слова; do ...; done. Без in перебираются позиционные параметры
+type For struct {
+	Var       string
+	HasIn     bool
+	Words     []Word
+	Body      *List
+	Redirects []Redirect
+}
+
+// FuncDef - определение функции name() { ...; }; тело - составная команда
+type FuncDef struct {
+	Name string
+	Body Node
+}
+
+func (*List) node()          {}
+func (*AndOr) node()         {}
+func (*Pipeline) node()      {}
+func (*SimpleCommand) node() {}
+func (*Subshell) node()      {}
+func (*Group) node()         {}
+func (*Background) node()    {}
+func (*If) node()            {}
+func (*While) node()         {}
+func (*For) node()           {}
+func (*FuncDef) node()       {}
+
+// String-методы печатают дерево в компактном виде, удобном для тестов и отладки
+
+func (n *List) String() string {
+
+	items := make([]string, len(n.Items))
+	for i, item := range n.Items {
+		items[i] = item.String()
+	}
+
+	return strings.Join(items, "; ")
+}
+
+func (n *AndOr) String() string {
+	return "(" + n.Op + " " + n.Left.String() + " " + n.Right.String() + ")"
+}
+
+func (n *Pipeline) String() string {
+
+	var sb strings.Builder
+	sb.WriteString("(")
+	if n.Negated {
+		sb.WriteString("! ")
+	}
+	sb.WriteString("pipe")
+	for _, cmd := range n.Commands {
+		sb.WriteString(" " + cmd.String())
+	}
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+func (n *SimpleCommand) String() string {
+
+	parts := make([]string, 0, len(n.Assigns)+len(n.Args))
+	for _, assign := range n.Assigns {
+		parts = append(parts, assign.String())
+	}
+	for _, arg := range n.Args {
+		parts = append(parts, arg.String())
+	}
+
+	return "[" + strings.Join(parts, " ") + "]" + redirectsString(n.Redirects)
+}
+
+func (n *Subshell) String() string {
+	return "(sub " + n.Body.String() + ")" + redirectsString(n.Redirects)
+}
+
+func (n *Group) String() string {
+	return "{" + n.Body.String() + "}" + redirectsString(n.Redirects)
+}
+
+func (n *Background) String() string {
+	return "(bg " + n.Node.String() + ")"
+}
+
+func (n *If) String() string {
+
+	var sb strings.Builder
+	for i, clause := range n.Clauses {
+		if i == 0 {
+			sb.WriteString("(if ")
+		} else {
+			sb.WriteString(" elif ")
+		}
+		sb.WriteString(clause.Cond.String() + " then " + clause.Body.String())
+	}
+	if n.Else != nil {
+		sb.WriteString(" else " + n.Else.String())
+	}
+	sb.WriteString(")")
+
+	return sb.String() + redirectsString(n.Redirects)
+}
+
+func (n *While) String() string {
+
+	keyword := "while"
+	if n.Until {
+		keyword = "until"
+	}
+
+	return "(" + keyword + " " + n.Cond.String() + " do " + n.Body.String() + ")" + redirectsString(n.Redirects)
+}
+
+func (n *For) String() string {
+
+	var sb strings.Builder
+	sb.WriteString("(for " + n.Var)
+	if n.HasIn {
+		sb.WriteString(" in")
+		for _, word := range n.Words {
+			sb.WriteString(" " + word.String())
+		}
+	}
+	sb.WriteString(" do " + n.Body.String() + ")")
+
+	return sb.String() + redirectsString(n.Redirects)
+}
+
+func (n *FuncDef) String() string {
+	return "(func " + n.Name + " " + n.Body.String() + ")"
+}
+
+// redirectsString печатает редиректы после команды
+func redirectsString(redirects []Redirect) string {
+
+	var sb strings.Builder
+	for _, r := range redirects {
+		sb.WriteString(" " + r.String())
+	}
+
+	return sb.String()
+}
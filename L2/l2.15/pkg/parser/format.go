@@ -0,0 +1,110 @@
+package parser
+
+import "strings"
+
+// Format печатает дерево обратно в виде команды шелла (так задания показываются в jobs)
+func Format(node Node) string {
+
+	switch n := node.(type) {
+	case *List:
+		var sb strings.Builder
+		for i, item := range n.Items {
+			if i > 0 {
+				// после & точка с запятой не нужна
+				if _, bg := n.Items[i-1].(*Background); bg {
+					sb.WriteString(" ")
+				} else {
+					sb.WriteString("; ")
+				}
+			}
+			sb.WriteString(Format(item))
+		}
+		return sb.String()
+
+	case *AndOr:
+		return Format(n.Left) + " " + n.Op + " " + Format(n.Right)
+
+	case *Pipeline:
+		cmds := make([]string, len(n.Commands))
+		for i, cmd := range n.Commands {
+			cmds[i] = Format(cmd)
+		}
+		text := strings.Join(cmds, " | ")
+		if n.Negated {
+			text = "! " + text
+		}
+		return text
+
+	case *SimpleCommand:
+		parts := make([]string, 0, len(n.Assigns)+len(n.Args)+len(n.Redirects))
+		for _, assign := range n.Assigns {
+			parts = append(parts, assign.String())
+		}
+		for _, arg := range n.Args {
+			parts = append(parts, arg.String())
+		}
+		for _, r := range n.Redirects {
+			parts = append(parts, r.String())
+		}
+		return strings.Join(parts, " ")
+
+	case *Subshell:
+		return "(" + Format(n.Body) + ")" + redirectsString(n.Redirects)
+
+	case *Group:
+		return "{ " + formatBody(n.Body) + " }" + redirectsString(n.Redirects)
+
+	case *Background:
+		return Format(n.Node) + " &"
+
+	case *If:
+		var sb strings.Builder
+		for i, clause := range n.Clauses {
+			if i == 0 {
+				sb.WriteString("if ")
+			} else {
+				sb.WriteString(" elif ")
+			}
+			sb.WriteString(formatBody(clause.Cond) + " then " + formatBody(clause.Body))
+		}
+		if n.Else != nil {
+			sb.WriteString(" else " + formatBody(n.Else))
+		}
+		return sb.String() + " fi" + redirectsString(n.Redirects)
+
+	case *While:
+		keyword := "while "
+		if n.Until {
+			keyword = "until "
+		}
+		return keyword + formatBody(n.Cond) + " do " + formatBody(n.Body) + " done" + redirectsString(n.Redirects)
+
+	case *For:
+		var sb strings.Builder
+		sb.WriteString("for " + n.Var)
+		if n.HasIn {
+			sb.WriteString(" in")
+			for _, word := range n.Words {
+				sb.WriteString(" " + word.String())
+			}
+		}
+		sb.WriteString("; do " + formatBody(n.Body) + " done")
+		return sb.String() + redirectsString(n.Redirects)
+
+	case *FuncDef:
+		return n.Name + "() " + Format(n.Body)
+	}
+
+	return ""
+}
+
+// formatBody печатает тело блока с завершающей ; (после & она не нужна)
+func formatBody(list *List) string {
+
+	body := Format(list)
+	if !strings.HasSuffix(body, "&") {
+		body += ";"
+	}
+
+	return body
+}
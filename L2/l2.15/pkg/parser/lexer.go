@@ -0,0 +1,417 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenKind - вид лексемы
+type TokenKind int
+
+const (
+	EOF     TokenKind = iota
+	WORD              // слово
+	NEWLINE           // перевод строки
+	SEMI              // ;
+	AMP               // &
+	AND               // &&
+	OR                // ||
+	PIPE              // |
+	LPAREN            // (
+	RPAREN            // )
+	REDIR             // < > >> <& >& &> &>> (с необязательным номером дескриптора)
+)
+
+var tokenNames = map[TokenKind]string{
+	EOF:     "конец ввода",
+	WORD:    "слово",
+	NEWLINE: "перевод строки",
+	SEMI:    ";",
+	AMP:     "&",
+	AND:     "&&",
+	OR:      "||",
+	PIPE:    "|",
+	LPAREN:  "(",
+	RPAREN:  ")",
+	REDIR:   "редирект",
+}
+
+func (k TokenKind) String() string {
+	return tokenNames[k]
+}
+
+// Pos - позиция в исходном тексте (строка и столбец с 1)
+type Pos struct {
+	Line int
+	Col  int
+}
+
+// Token - лексема
+type Token struct {
+	Kind TokenKind
+	Pos  Pos
+	Word Word    // для WORD
+	Op   RedirOp // для REDIR
+	Fd   int     // для REDIR: номер дескриптора, -1 - по умолчанию
+	Text string  // исходный текст оператора (для сообщений об ошибках)
+}
+
+// SyntaxError - синтаксическая ошибка
+type SyntaxError struct {
+	Pos        Pos
+	Msg        string
+	Incomplete bool // ввод оборвался (незакрытая кавычка, | в конце строки) - можно дочитать продолжение
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("синтаксическая ошибка (строка %d, позиция %d): %s", e.Pos.Line, e.Pos.Col, e.Msg)
+}
+
+// lexer разбивает исходный текст на лексемы
+type lexer struct {
+	src  string
+	pos  int // текущее смещение в байтах
+	line int
+	col  int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1, col: 1}
+}
+
+// peekByte возвращает байт со смещением off от текущей позиции (0 - конец ввода)
+func (l *lexer) peekByte(off int) byte {
+
+	if l.pos+off >= len(l.src) {
+		return 0
+	}
+
+	return l.src[l.pos+off]
+}
+
+// advance сдвигает позицию на n байт, считая строки и столбцы
+func (l *lexer) advance(n int) {
+
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		ch := l.src[l.pos]
+		l.pos++
+		if ch == '\n' {
+			l.line++
+			l.col = 1
+		} else if utf8.RuneStart(ch) {
+			l.col++
+		}
+	}
+}
+
+func (l *lexer) position() Pos {
+	return Pos{Line: l.line, Col: l.col}
+}
+
+func (l *lexer) errorf(pos Pos, incomplete bool, format string, args ...any) error {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...), Incomplete: incomplete}
+}
+
+// isMeta сообщает, завершает ли символ слово
+func isMeta(ch byte) bool {
+	switch ch {
+	case ' ', '\t', '\n', ';', '&', '|', '(', ')', '<', '>':
+		return true
+	}
+	return false
+}
+
+// next возвращает следующую лексему
+func (l *lexer) next() (Token, error) {
+
+	// пропускаем пробелы, продолжения строк и комментарии
+	for {
+		ch := l.peekByte(0)
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r':
+			l.advance(1)
+		case ch == '\\' && l.peekByte(1) == '\n':
+			l.advance(2)
+		case ch == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance(1)
+			}
+		default:
+			goto token
+		}
+	}
+
+token:
+	pos := l.position()
+	if l.pos >= len(l.src) {
+		return Token{Kind: EOF, Pos: pos}, nil
+	}
+
+	// номер дескриптора перед редиректом: 2>file, 2>&1
+	digits := 0
+	for l.peekByte(digits) >= '0' && l.peekByte(digits) <= '9' {
+		digits++
+	}
+	if digits > 0 && (l.peekByte(digits) == '<' || l.peekByte(digits) == '>') {
+		fd := 0
+		for i := 0; i < digits; i++ {
+			fd = fd*10 + int(l.peekByte(i)-'0')
+		}
+		l.advance(digits)
+		tok := l.redirect(pos)
+		tok.Fd = fd
+		return tok, nil
+	}
+
+	switch ch := l.peekByte(0); ch {
+	case '\n':
+		l.advance(1)
+		return Token{Kind: NEWLINE, Pos: pos, Text: "\\n"}, nil
+	case ';':
+		l.advance(1)
+		return Token{Kind: SEMI, Pos: pos, Text: ";"}, nil
+	case '(':
+		l.advance(1)
+		return Token{Kind: LPAREN, Pos: pos, Text: "("}, nil
+	case ')':
+		l.advance(1)
+		return Token{Kind: RPAREN, Pos: pos, Text: ")"}, nil
+	case '|':
+		if l.peekByte(1) == '|' {
+			l.advance(2)
+			return Token{Kind: OR, Pos: pos, Text: "||"}, nil
+		}
+		l.advance(1)
+		return Token{Kind: PIPE, Pos: pos, Text: "|"}, nil
+	case '&':
+		switch l.peekByte(1) {
+		case '&':
+			l.advance(2)
+			return Token{Kind: AND, Pos: pos, Text: "&&"}, nil
+		case '>':
+			if l.peekByte(2) == '>' {
+				l.advance(3)
+				return Token{Kind: REDIR, Pos: pos, Op: RedirAllAppend, Fd: -1, Text: "&>>"}, nil
+			}
+			l.advance(2)
+			return Token{Kind: REDIR, Pos: pos, Op: RedirAll, Fd: -1, Text: "&>"}, nil
+		}
+		l.advance(1)
+		return Token{Kind: AMP, Pos: pos, Text: "&"}, nil
+	case '<', '>':
+		return l.redirect(pos), nil
+	}
+
+	return l.word(pos)
+}
+
+// redirect читает оператор редиректа, начинающийся с < или >
+func (l *lexer) redirect(pos Pos) Token {
+
+	tok := Token{Kind: REDIR, Pos: pos, Fd: -1}
+
+	if l.peekByte(0) == '<' {
+		if l.peekByte(1) == '&' {
+			tok.Op, tok.Text = RedirDupIn, "<&"
+			l.advance(2)
+		} else {
+			tok.Op, tok.Text = RedirIn, "<"
+			l.advance(1)
+		}
+		return tok
+	}
+
+	switch l.peekByte(1) {
+	case '>':
+		tok.Op, tok.Text = RedirAppend, ">>"
+		l.advance(2)
+	case '&':
+		tok.Op, tok.Text = RedirDupOut, ">&"
+		l.advance(2)
+	default:
+		tok.Op, tok.Text = RedirOut, ">"
+		l.advance(1)
+	}
+
+	return tok
+}
+
+// wordBuilder собирает слово из частей, склеивая соседние части одного вида
+type wordBuilder struct {
+	parts []WordPart
+}
+
+func (b *wordBuilder) add(kind PartKind, s string) {
+
+	if n := len(b.parts); n > 0 && b.parts[n-1].Kind == kind && kind != CmdSubst {
+		b.parts[n-1].Text += s
+		return
+	}
+	b.parts = append(b.parts, WordPart{Kind: kind, Text: s})
+}
+
+// mark отмечает открытие кавычек: "" и ” - это пустой аргумент, а не отсутствие аргумента
+func (b *wordBuilder) mark(kind PartKind) {
+	b.add(kind, "")
+}
+
+func (b *wordBuilder) word() Word {
+
+	var parts []WordPart
+	for _, part := range b.parts {
+		if part.Kind == CmdSubst {
+			parts = append(parts, part)
+			continue
+		}
+		if part.Text == "" {
+			continue
+		}
+		if n := len(parts); n > 0 && parts[n-1].Kind == part.Kind {
+			parts[n-1].Text += part.Text
+			continue
+		}
+		parts = append(parts, part)
+	}
+
+	// слово из одних пустых кавычек
+	if len(parts) == 0 && len(b.parts) > 0 {
+		parts = []WordPart{{Kind: b.parts[0].Kind}}
+	}
+
+	return Word{Parts: parts}
+}
+
+// word читает слово с учётом кавычек и экранирования
+func (l *lexer) word(pos Pos) (Token, error) {
+
+	var b wordBuilder
+
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		if isMeta(ch) || ch == '\r' {
+			break
+		}
+
+		switch ch {
+		case '\'':
+			start := l.position()
+			l.advance(1)
+			end := strings.IndexByte(l.src[l.pos:], '\'')
+			if end < 0 {
+				return Token{}, l.errorf(start, true, "незакрытая кавычка '")
+			}
+			b.mark(Quoted)
+			b.add(Quoted, l.src[l.pos:l.pos+end])
+			l.advance(end + 1)
+
+		case '"':
+			if err := l.doubleQuoted(&b); err != nil {
+				return Token{}, err
+			}
+
+		case '$':
+			if l.peekByte(1) != '(' {
+				b.add(Lit, "$")
+				l.advance(1)
+				continue
+			}
+			if err := l.commandSubstitution(&b, false); err != nil {
+				return Token{}, err
+			}
+
+		case '\\':
+			switch next := l.peekByte(1); next {
+			case 0:
+				// одиночный \ в конце ввода - ждём продолжения
+				return Token{}, l.errorf(l.position(), true, "ввод оборвался после \\")
+			case '\n':
+				// продолжение строки
+				l.advance(2)
+			default:
+				_, size := utf8.DecodeRuneInString(l.src[l.pos+1:])
+				b.add(Quoted, l.src[l.pos+1:l.pos+1+size])
+				l.advance(1 + size)
+			}
+
+		default:
+			_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			b.add(Lit, l.src[l.pos:l.pos+size])
+			l.advance(size)
+		}
+	}
+
+	return Token{Kind: WORD, Pos: pos, Word: b.word()}, nil
+}
+
+// doubleQuoted читает текст в двойных кавычках: \ экранирует только $ ` " \ и перевод строки
+func (l *lexer) doubleQuoted(b *wordBuilder) error {
+
+	start := l.position()
+	l.advance(1)
+	b.mark(DQuoted)
+
+	for {
+		if l.pos >= len(l.src) {
+			return l.errorf(start, true, "незакрытая кавычка \"")
+		}
+
+		ch := l.src[l.pos]
+		switch {
+		case ch == '"':
+			l.advance(1)
+			return nil
+
+		case ch == '\\' && l.peekByte(1) == '\n':
+			l.advance(2)
+
+		case ch == '\\' && strings.IndexByte("$`\"\\", l.peekByte(1)) >= 0:
+			b.add(Quoted, string(l.peekByte(1)))
+			l.advance(2)
+
+		case ch == '$' && l.peekByte(1) == '(':
+			if err := l.commandSubstitution(b, true); err != nil {
+				return err
+			}
+
+		default:
+			_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			b.add(DQuoted, l.src[l.pos:l.pos+size])
+			l.advance(size)
+		}
+	}
+}
+
+// commandSubstitution читает подстановку команды $( ... ): содержимое разбирается
+// тем же парсером, поэтому внутри работают кавычки, вложенные $( ) и скобки
+func (l *lexer) commandSubstitution(b *wordBuilder, inQuotes bool) error {
+
+	start := l.position()
+	l.advance(2)
+	begin := l.pos
+
+	p := &Parser{lex: l}
+	if err := p.advance(); err != nil {
+		return err
+	}
+	body, err := p.parseList(func(Token) bool { return false })
+	if err != nil {
+		return err
+	}
+	if p.tok.Kind != RPAREN {
+		if p.tok.Kind == EOF {
+			return l.errorf(start, true, "нет закрывающей ) для $(")
+		}
+		return p.unexpected()
+	}
+
+	// лексер уже стоит сразу после )
+	b.parts = append(b.parts, WordPart{
+		Kind:     CmdSubst,
+		Text:     l.src[begin : l.pos-1],
+		InQuotes: inQuotes,
+		Cmd:      body,
+	})
+
+	return nil
+}
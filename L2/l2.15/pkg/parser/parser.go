@@ -0,0 +1,710 @@
+package parser
+
+import "strings"
+
+// Грамматика (рекурсивный спуск):
+//
+//	list     := and_or ((';' | '&' | '\n') and_or)* [';' | '&' | '\n']
+//	and_or   := pipeline (('&&' | '||') '\n'* pipeline)*
+//	pipeline := ['!'] command ('|' '\n'* command)*
+//	command  := simple | compound redirect* | funcdef
+//	compound := '(' list ')' | '{' list '}' | if | while | for
+//	if       := 'if' list 'then' list ('elif' list 'then' list)* ['else' list] 'fi'
+//	while    := ('while' | 'until') list 'do' list 'done'
+//	for      := 'for' NAME ['\n'*] ['in' WORD* (';' | '\n')] '\n'* 'do' list 'done'
+//	funcdef  := NAME '(' ')' '\n'* compound redirect* | 'function' NAME ['(' ')'] '\n'* compound redirect*
+//	simple   := (ASSIGNMENT | redirect)* (WORD | redirect)*   (хотя бы что-то одно)
+//	redirect := REDIR WORD
+//
+// Служебные слова (if, then, do ...) распознаются только в начале команды и без кавычек
+
+// Parser - синтаксический анализатор командной строки
+type Parser struct {
+	lex *lexer
+	tok Token // текущая лексема
+}
+
+// Parse разбирает текст команды (одну или несколько строк) в синтаксическое дерево
+func Parse(src string) (*List, error) {
+
+	p := &Parser{lex: newLexer(src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	list, err := p.parseList(func(Token) bool { return false })
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.Kind != EOF {
+		return nil, p.unexpected()
+	}
+
+	return list, nil
+}
+
+// advance переходит к следующей лексеме
+func (p *Parser) advance() error {
+
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+
+	return nil
+}
+
+// skipNewlines пропускает переводы строк (после |, && и || команда может продолжаться на следующей строке)
+func (p *Parser) skipNewlines() error {
+
+	for p.tok.Kind == NEWLINE {
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unexpected возвращает ошибку о неожиданной лексеме
+func (p *Parser) unexpected() error {
+
+	if p.tok.Kind == EOF {
+		return &SyntaxError{Pos: p.tok.Pos, Msg: "неожиданный конец ввода", Incomplete: true}
+	}
+
+	text := p.tok.Text
+	if p.tok.Kind == WORD {
+		text = p.tok.Word.String()
+	}
+
+	return &SyntaxError{Pos: p.tok.Pos, Msg: "неожиданный токен " + text}
+}
+
+// isWord сообщает, что текущая лексема - слово без кавычек s (служебные слова { } !)
+func (p *Parser) isWord(s string) bool {
+	return p.tok.Kind == WORD && p.tok.Word.IsLiteral(s)
+}
+
+// closingKeywords - служебные слова, которые не могут начинать команду
+var closingKeywords = map[string]bool{
+	"then": true, "elif": true, "else": true, "fi": true, "do": true, "done": true,
+}
+
+// isKeyword сообщает, что лексема - служебное слово из words
+func isKeyword(tok Token, words ...string) bool {
+
+	if tok.Kind != WORD {
+		return false
+	}
+	for _, word := range words {
+		if tok.Word.IsLiteral(word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseList разбирает последовательность команд до конца ввода, ) или лексемы, на которой stop вернёт true
+func (p *Parser) parseList(stop func(Token) bool) (*List, error) {
+
+	list := &List{}
+
+	for {
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+		if p.tok.Kind == EOF || p.tok.Kind == RPAREN || stop(p.tok) {
+			return list, nil
+		}
+
+		node, err := p.parseAndOr()
+		if err != nil {
+			return nil, err
+		}
+
+		// & запускает команду в фоне и сам служит разделителем
+		if p.tok.Kind == AMP {
+			list.Items = append(list.Items, &Background{Node: node})
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		list.Items = append(list.Items, node)
+
+		if p.tok.Kind != SEMI && p.tok.Kind != NEWLINE {
+			return list, nil
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseAndOr разбирает цепочку конвейеров через && и || (операторы равноправны, группировка слева)
+func (p *Parser) parseAndOr() (Node, error) {
+
+	left, err := p.parsePipeline()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.tok.Kind == AND || p.tok.Kind == OR {
+		op := p.tok.Text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+
+		right, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		left = &AndOr{Op: op, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+// parsePipeline разбирает конвейер; одиночная команда без ! возвращается как есть
+func (p *Parser) parsePipeline() (Node, error) {
+
+	pipeline := &Pipeline{}
+	if p.isWord("!") {
+		pipeline.Negated = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Commands = append(pipeline.Commands, cmd)
+
+		if p.tok.Kind != PIPE {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(pipeline.Commands) == 1 && !pipeline.Negated {
+		return pipeline.Commands[0], nil
+	}
+
+	return pipeline, nil
+}
+
+// parseCommand разбирает команду: простую, составную или определение функции
+func (p *Parser) parseCommand() (Node, error) {
+
+	if p.tok.Kind == WORD && closingKeywords[p.tok.Word.Value()] && isKeyword(p.tok, p.tok.Word.Value()) {
+		return nil, p.unexpected()
+	}
+	if p.isWord("function") {
+		return p.parseFunction()
+	}
+	if !p.isCompoundStart() {
+		return p.parseSimpleCommand()
+	}
+
+	return p.parseCompound()
+}
+
+// isCompoundStart сообщает, что с текущей лексемы начинается составная команда
+func (p *Parser) isCompoundStart() bool {
+	return p.tok.Kind == LPAREN || isKeyword(p.tok, "{", "if", "while", "until", "for")
+}
+
+// parseCompound разбирает составную команду с редиректами после неё
+func (p *Parser) parseCompound() (Node, error) {
+
+	var (
+		node Node
+		err  error
+	)
+	switch {
+	case p.tok.Kind == LPAREN:
+		var body *List
+		body, err = p.parseBlock(func(tok Token) bool { return tok.Kind == RPAREN }, ")")
+		node = &Subshell{Body: body}
+	case p.isWord("{"):
+		var body *List
+		body, err = p.parseBlock(func(tok Token) bool { return isKeyword(tok, "}") }, "}")
+		node = &Group{Body: body}
+	case p.isWord("if"):
+		node, err = p.parseIf()
+	case p.isWord("while"), p.isWord("until"):
+		node, err = p.parseWhile()
+	case p.isWord("for"):
+		node, err = p.parseFor()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	redirects, err := p.parseRedirects()
+	if err != nil {
+		return nil, err
+	}
+	switch n := node.(type) {
+	case *Subshell:
+		n.Redirects = redirects
+	case *Group:
+		n.Redirects = redirects
+	case *If:
+		n.Redirects = redirects
+	case *While:
+		n.Redirects = redirects
+	case *For:
+		n.Redirects = redirects
+	}
+
+	return node, nil
+}
+
+// parseIf разбирает if ... then ... [elif ... then ...] [else ...] fi
+func (p *Parser) parseIf() (Node, error) {
+
+	open := p.tok
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	n := &If{}
+	for {
+		cond, err := p.parseBody(open, "then", "then")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("then", open); err != nil {
+			return nil, err
+		}
+		body, err := p.parseBody(open, "fi", "elif", "else", "fi")
+		if err != nil {
+			return nil, err
+		}
+		n.Clauses = append(n.Clauses, IfClause{Cond: cond, Body: body})
+
+		if !p.isWord("elif") {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isWord("else") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		body, err := p.parseBody(open, "fi", "fi")
+		if err != nil {
+			return nil, err
+		}
+		n.Else = body
+	}
+
+	if err := p.expectKeyword("fi", open); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// parseWhile разбирает while/until ... do ... done
+func (p *Parser) parseWhile() (Node, error) {
+
+	open := p.tok
+	n := &While{Until: p.isWord("until")}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	cond, err := p.parseBody(open, "do", "do")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("do", open); err != nil {
+		return nil, err
+	}
+	n.Cond = cond
+
+	if n.Body, err = p.parseLoopBody(open); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// parseFor разбирает for NAME [in слова]; do ... done
+func (p *Parser) parseFor() (Node, error) {
+
+	open := p.tok
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.Kind == EOF {
+		return nil, &SyntaxError{Pos: open.Pos, Msg: "нет имени переменной после for", Incomplete: true}
+	}
+	if p.tok.Kind != WORD || len(p.tok.Word.Parts) != 1 || p.tok.Word.Parts[0].Kind != Lit || !IsName(p.tok.Word.Value()) {
+		return nil, &SyntaxError{Pos: p.tok.Pos, Msg: "неверное имя переменной в for"}
+	}
+	n := &For{Var: p.tok.Word.Value()}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if err := p.skipNewlines(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.isWord("in"):
+		n.HasIn = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for p.tok.Kind == WORD {
+			n.Words = append(n.Words, p.tok.Word)
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if p.tok.Kind == EOF {
+			return nil, &SyntaxError{Pos: open.Pos, Msg: "нет do для for", Incomplete: true}
+		}
+		if p.tok.Kind != SEMI && p.tok.Kind != NEWLINE {
+			return nil, p.unexpected()
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+	case p.tok.Kind == SEMI:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.skipNewlines(); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("do", open); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseLoopBody(open)
+	if err != nil {
+		return nil, err
+	}
+	n.Body = body
+
+	return n, nil
+}
+
+// parseLoopBody разбирает тело цикла после do до done
+func (p *Parser) parseLoopBody(open Token) (*List, error) {
+
+	body, err := p.parseBody(open, "done", "done")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("done", open); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// parseBody разбирает непустой список команд до одного из служебных слов stop.
+// open - начало конструкции, closing - слово, которого не хватает, если ввод кончился
+func (p *Parser) parseBody(open Token, closing string, stop ...string) (*List, error) {
+
+	body, err := p.parseList(func(tok Token) bool { return isKeyword(tok, stop...) })
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body.Items) == 0 {
+		if p.tok.Kind == EOF {
+			return nil, &SyntaxError{Pos: open.Pos, Msg: "нет " + closing + " для " + open.Word.Value(), Incomplete: true}
+		}
+		if p.tok.Kind == WORD {
+			return nil, &SyntaxError{Pos: p.tok.Pos, Msg: "пустой блок перед " + p.tok.Word.Value()}
+		}
+		return nil, p.unexpected()
+	}
+
+	return body, nil
+}
+
+// expectKeyword проверяет, что текущая лексема - служебное слово word, и переходит к следующей
+func (p *Parser) expectKeyword(word string, open Token) error {
+
+	if p.isWord(word) {
+		return p.advance()
+	}
+	if p.tok.Kind == EOF {
+		return &SyntaxError{Pos: open.Pos, Msg: "нет " + word + " для " + open.Word.Value(), Incomplete: true}
+	}
+
+	return p.unexpected()
+}
+
+// parseFunction разбирает function NAME [()] составная_команда
+func (p *Parser) parseFunction() (Node, error) {
+
+	open := p.tok
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.Kind == EOF {
+		return nil, &SyntaxError{Pos: open.Pos, Msg: "нет имени функции", Incomplete: true}
+	}
+	if !isFuncName(p.tok) {
+		return nil, &SyntaxError{Pos: p.tok.Pos, Msg: "неверное имя функции"}
+	}
+	name := p.tok
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.Kind == LPAREN {
+		return p.parseFuncDef(name)
+	}
+
+	return p.parseFuncBody(name)
+}
+
+// isFuncName сообщает, что лексема может быть именем функции: слово без кавычек и подстановок,
+// не служебное
+func isFuncName(tok Token) bool {
+
+	if tok.Kind != WORD || len(tok.Word.Parts) != 1 || tok.Word.Parts[0].Kind != Lit {
+		return false
+	}
+	name := tok.Word.Value()
+	if strings.ContainsAny(name, "$=") {
+		return false
+	}
+
+	return !isKeyword(tok, "if", "then", "elif", "else", "fi", "while", "until", "for", "do", "done", "in", "function", "{", "}", "!")
+}
+
+// parseFuncDef разбирает ( ) после имени функции и её тело
+func (p *Parser) parseFuncDef(name Token) (Node, error) {
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.Kind != RPAREN {
+		if p.tok.Kind == EOF {
+			return nil, &SyntaxError{Pos: name.Pos, Msg: "нет ) после имени функции", Incomplete: true}
+		}
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	return p.parseFuncBody(name)
+}
+
+// parseFuncBody разбирает тело функции - составную команду (можно с новой строки)
+func (p *Parser) parseFuncBody(name Token) (Node, error) {
+
+	if err := p.skipNewlines(); err != nil {
+		return nil, err
+	}
+	if p.tok.Kind == EOF {
+		return nil, &SyntaxError{Pos: name.Pos, Msg: "нет тела функции " + name.Word.Value(), Incomplete: true}
+	}
+	if !p.isCompoundStart() {
+		return nil, &SyntaxError{Pos: p.tok.Pos, Msg: "тело функции должно быть составной командой"}
+	}
+
+	body, err := p.parseCompound()
+	if err != nil {
+		return nil, err
+	}
+
+	return &FuncDef{Name: name.Word.Value(), Body: body}, nil
+}
+
+// parseBlock разбирает тело ( ) или { } от открывающей лексемы до закрывающей close
+func (p *Parser) parseBlock(isClose func(Token) bool, close string) (*List, error) {
+
+	open := p.tok
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseList(isClose)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isClose(p.tok) {
+		if p.tok.Kind == EOF {
+			return nil, &SyntaxError{Pos: open.Pos, Msg: "нет закрывающей " + close, Incomplete: true}
+		}
+		return nil, p.unexpected()
+	}
+	if len(body.Items) == 0 {
+		return nil, &SyntaxError{Pos: p.tok.Pos, Msg: "пустой блок перед " + close}
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// parseSimpleCommand разбирает слова и редиректы простой команды
+func (p *Parser) parseSimpleCommand() (Node, error) {
+
+	cmd := &SimpleCommand{}
+	first := p.tok
+
+	for {
+		switch p.tok.Kind {
+		case WORD:
+			// NAME=value до первого аргумента - присваивание
+			if len(cmd.Args) == 0 {
+				if assign, ok := asAssignment(p.tok.Word); ok {
+					cmd.Assigns = append(cmd.Assigns, assign)
+					if err := p.advance(); err != nil {
+						return nil, err
+					}
+					continue
+				}
+			}
+			cmd.Args = append(cmd.Args, p.tok.Word)
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			continue
+
+		case REDIR:
+			redirect, err := p.parseRedirect()
+			if err != nil {
+				return nil, err
+			}
+			cmd.Redirects = append(cmd.Redirects, redirect)
+			continue
+		}
+		break
+	}
+
+	if len(cmd.Assigns) == 0 && len(cmd.Args) == 0 && len(cmd.Redirects) == 0 {
+		return nil, p.unexpected()
+	}
+
+	// name ( ) - определение функции
+	if p.tok.Kind == LPAREN && len(cmd.Args) == 1 && len(cmd.Assigns) == 0 && len(cmd.Redirects) == 0 && isFuncName(first) {
+		return p.parseFuncDef(first)
+	}
+
+	return cmd, nil
+}
+
+// parseRedirects разбирает редиректы после ( ) или { }
+func (p *Parser) parseRedirects() ([]Redirect, error) {
+
+	var redirects []Redirect
+	for p.tok.Kind == REDIR {
+		redirect, err := p.parseRedirect()
+		if err != nil {
+			return nil, err
+		}
+		redirects = append(redirects, redirect)
+	}
+
+	return redirects, nil
+}
+
+// parseRedirect разбирает оператор редиректа и его цель
+func (p *Parser) parseRedirect() (Redirect, error) {
+
+	op := p.tok
+	redirect := Redirect{Op: op.Op, Fd: op.Fd}
+	if redirect.Fd < 0 {
+		redirect.Fd = 1
+		if op.Op == RedirIn || op.Op == RedirDupIn {
+			redirect.Fd = 0
+		}
+	}
+
+	if err := p.advance(); err != nil {
+		return Redirect{}, err
+	}
+	if p.tok.Kind != WORD {
+		if p.tok.Kind == EOF || p.tok.Kind == NEWLINE {
+			return Redirect{}, &SyntaxError{Pos: op.Pos, Msg: "нет имени файла после " + op.Text}
+		}
+		return Redirect{}, p.unexpected()
+	}
+	redirect.Target = p.tok.Word
+
+	if err := p.advance(); err != nil {
+		return Redirect{}, err
+	}
+
+	return redirect, nil
+}
+
+// IsName сообщает, является ли строка именем переменной (буквы, цифры, _; не с цифры)
+func IsName(s string) bool {
+
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		isLetter := ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+		isDigit := ch >= '0' && ch <= '9'
+		if !isLetter && !(isDigit && i > 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// asAssignment распознаёт слово вида NAME=value (имя и = должны быть без кавычек)
+func asAssignment(word Word) (Assignment, bool) {
+
+	if len(word.Parts) == 0 || word.Parts[0].Kind != Lit {
+		return Assignment{}, false
+	}
+
+	first := word.Parts[0].Text
+	eq := strings.IndexByte(first, '=')
+	if eq <= 0 || !IsName(first[:eq]) {
+		return Assignment{}, false
+	}
+
+	value := Word{}
+	if rest := first[eq+1:]; rest != "" {
+		value.Parts = append(value.Parts, WordPart{Kind: Lit, Text: rest})
+	}
+	value.Parts = append(value.Parts, word.Parts[1:]...)
+
+	return Assignment{Name: first[:eq], Value: value}, true
+}
@@ -0,0 +1,258 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"пустая строка", "", ""},
+		{"простая команда", "ls -la /tmp", "[ls -la /tmp]"},
+		{"лишние пробелы", "  echo   a\tb  ", "[echo a b]"},
+		{"двойные кавычки", `echo "hello world"`, `[echo "hello world"]`},
+		{"одинарные кавычки", `echo 'a b'`, `[echo 'a b']`},
+		{"кавычки внутри слова", `echo a"b c"d`, `[echo a"b c"d]`},
+		{"пустой аргумент", `echo "" ''`, `[echo "" '']`},
+		{"экранирование", `echo a\ b`, `[echo a' 'b]`},
+		{"экранирование в двойных кавычках", `echo "a\"b\$c\d"`, `[echo "a"'"'"b"'$'"c\d"]`},
+		{"перевод строки в кавычках", "echo \"a\nb\"", "[echo \"a\nb\"]"},
+		{"продолжение строки", "echo a \\\n b", "[echo a b]"},
+		{"комментарий", "echo a # comment", "[echo a]"},
+		{"решётка внутри слова", "echo a#b", "[echo a#b]"},
+		{"юникод", "echo привет 'мир'", "[echo привет 'мир']"},
+
+		{"точка с запятой", "echo a; echo b", "[echo a]; [echo b]"},
+		{"точка с запятой в конце", "echo a;", "[echo a]"},
+		{"переводы строк", "\necho a\n\necho b\n", "[echo a]; [echo b]"},
+		{"точка с запятой в кавычках", `echo "a; b"`, `[echo "a; b"]`},
+
+		{"конвейер", "ps aux | grep go | wc -l", "(pipe [ps aux] [grep go] [wc -l])"},
+		{"| в кавычках", `echo "a|b" | cat`, `(pipe [echo "a|b"] [cat])`},
+		{"отрицание", "! grep x f", "(! pipe [grep x f])"},
+		{"конвейер после перевода строки", "echo a |\n cat", "(pipe [echo a] [cat])"},
+
+		{"и", "true && echo ok", "(&& [true] [echo ok])"},
+		{"или", "false || echo fail", "(|| [false] [echo fail])"},
+		{"цепочка слева направо", "a && b || c && d", "(&& (|| (&& [a] [b]) [c]) [d])"},
+		{"конвейер внутри &&", "echo a | grep a && echo found", "(&& (pipe [echo a] [grep a]) [echo found])"},
+		{"&& в кавычках", `echo "a && b"`, `[echo "a && b"]`},
+		{"&& и ;", "a && b; c || d", "(&& [a] [b]); (|| [c] [d])"},
+
+		{"редирект вывода", "echo a > out.txt", "[echo a] >out.txt"},
+		{"редирект без пробела", "echo a >out.txt", "[echo a] >out.txt"},
+		{"дописывание", "echo a >> log", "[echo a] >>log"},
+		{"ввод", "sort < in.txt", "[sort] <in.txt"},
+		{"stderr", "cmd 2> err.txt", "[cmd] 2>err.txt"},
+		{"stderr в stdout", "cmd > all 2>&1", "[cmd] >all 2>&1"},
+		{"stdout и stderr", "cmd &> all", "[cmd] &>all"},
+		{"редирект в начале", "< in sort -r", "[sort -r] <in"},
+		{"цифра без редиректа", "echo 2 > f", "[echo 2] >f"},
+		{"редиректы в конвейере", "sort < in | uniq > out", "(pipe [sort] <in [uniq] >out)"},
+		{"только редирект", "> file", "[] >file"},
+
+		{"подоболочка", "(cd /tmp; ls)", "(sub [cd /tmp]; [ls])"},
+		{"подоболочка с редиректом", "(echo a; echo b) > out", "(sub [echo a]; [echo b]) >out"},
+		{"подоболочка в конвейере", "(echo a; echo b) | sort", "(pipe (sub [echo a]; [echo b]) [sort])"},
+		{"группа", "{ echo a; echo b; }", "{[echo a]; [echo b]}"},
+		{"группа с переводами строк", "{\necho a\necho b\n}", "{[echo a]; [echo b]}"},
+		{"группа и ||", "test -f x || { echo no; exit 1; }", "(|| [test -f x] {[echo no]; [exit 1]})"},
+		{"вложенные блоки", "( { a; } | b ) && c", "(&& (sub (pipe {[a]} [b])) [c])"},
+		{"скобка как аргумент", "echo { }", "[echo { }]"},
+
+		{"присваивание", "A=1", "[A=1]"},
+		{"несколько присваиваний", "A=1 B='x y' C=", "[A=1 B='x y' C=]"},
+		{"присваивание перед командой", "LANG=C sort f", "[LANG=C sort f]"},
+		{"= в аргументе - не присваивание", "echo A=1", "[echo A=1]"},
+		{"имя в кавычках - не присваивание", `"A"=1`, `["A"=1]`},
+		{"неверное имя - не присваивание", "1A=x", "[1A=x]"},
+		{"присваивание с редиректом", "A=1 > f", "[A=1] >f"},
+		{"$ без скобки", "echo $HOME ${X}", "[echo $HOME ${X}]"},
+		{"подстановка команды", "echo $(ls -l | wc -l)", "[echo $(ls -l | wc -l)]"},
+		{"подстановка внутри слова", "echo a$(pwd)b", "[echo a$(pwd)b]"},
+		{"подстановка в кавычках", `echo "n=$(echo ")")"`, `[echo "n=""$(echo ")")"]`},
+		{"вложенная подстановка", "echo $(echo $(echo a))", "[echo $(echo $(echo a))]"},
+		{"подстановка с переводом строки", "echo $(\necho a\n)", "[echo $(\necho a\n)]"},
+		{"подстановка в присваивании", "D=$(date)", "[D=$(date)]"},
+
+		{"фоновый режим", "sleep 1 &", "(bg [sleep 1])"},
+		{"& как разделитель", "sleep 1 & echo a; b & c &", "(bg [sleep 1]); [echo a]; (bg [b]); (bg [c])"},
+		{"фоновый конвейер и &&", "a | b && c &", "(bg (&& (pipe [a] [b]) [c]))"},
+		{"фон в подоболочке", "(a &)", "(sub (bg [a]))"},
+		{"& и перевод строки", "a &\nb", "(bg [a]); [b]"},
+
+		{"if", "if true; then echo a; fi", "(if [true] then [echo a])"},
+		{"if else", "if a; then b; else c; fi", "(if [a] then [b] else [c])"},
+		{"elif", "if a; then b; elif c; then d; else e; fi", "(if [a] then [b] elif [c] then [d] else [e])"},
+		{"if на нескольких строках", "if a\nthen\n  b\n  c\nfi", "(if [a] then [b]; [c])"},
+		{"if с && и редиректом", "if a && b; then c; fi > out", "(if (&& [a] [b]) then [c]) >out"},
+		{"if в конвейере", "if a; then b; fi | cat", "(pipe (if [a] then [b]) [cat])"},
+		{"служебное слово как аргумент", "echo if then fi", "[echo if then fi]"},
+		{"служебное слово в кавычках", "'if' a", "['if' a]"},
+		{"while", "while a; do b; done", "(while [a] do [b])"},
+		{"until", "until a\ndo b\ndone", "(until [a] do [b])"},
+		{"вложенные циклы", "while a; do for i in 1 2; do b; done; done", "(while [a] do (for i in 1 2 do [b]))"},
+		{"for", "for i in a 'b c' $X; do echo $i; done", "(for i in a 'b c' $X do [echo $i])"},
+		{"for без in", "for arg; do echo $arg; done", "(for arg do [echo $arg])"},
+		{"for с переводами строк", "for i in a b\ndo\n echo $i\ndone", "(for i in a b do [echo $i])"},
+		{"for с пустым in", "for i in; do a; done", "(for i in do [a])"},
+		{"функция", "f() { echo a; }", "(func f {[echo a]})"},
+		{"функция с пробелом", "f () {\n echo a\n}", "(func f {[echo a]})"},
+		{"function", "function greet { echo hi; }", "(func greet {[echo hi]})"},
+		{"function со скобками", "function greet() ( echo hi )", "(func greet (sub [echo hi]))"},
+		{"функция с if", "f() if a; then b; fi", "(func f (if [a] then [b]))"},
+		{"функция и вызов", "f() { a; }; f x", "(func f {[a]}); [f x]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			if got := list.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWordParts(t *testing.T) {
+
+	list, err := Parse(`echo pre"$HOME"'$x'\$y`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	arg := list.Items[0].(*SimpleCommand).Args[1]
+	want := []WordPart{
+		{Kind: Lit, Text: "pre"},
+		{Kind: DQuoted, Text: "$HOME"},
+		{Kind: Quoted, Text: "$x$"},
+		{Kind: Lit, Text: "y"},
+	}
+	if len(arg.Parts) != len(want) {
+		t.Fatalf("parts = %+v, want %+v", arg.Parts, want)
+	}
+	for i := range want {
+		if arg.Parts[i] != want[i] {
+			t.Errorf("part %d = %+v, want %+v", i, arg.Parts[i], want[i])
+		}
+	}
+	if v := arg.Value(); v != "pre$HOME$x$y" {
+		t.Errorf("Value() = %q", v)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		input      string
+		incomplete bool
+		line, col  int
+	}{
+		{"незакрытая двойная кавычка", `echo "abc`, true, 1, 6},
+		{"незакрытая одинарная кавычка", `echo 'abc`, true, 1, 6},
+		{"| в конце", "echo a |", true, 1, 9},
+		{"&& в конце", "echo a &&", true, 1, 10},
+		{"незакрытая скобка", "(echo a", true, 1, 1},
+		{"незакрытая группа", "{ echo a; ", true, 1, 1},
+		{"\\ в конце", `echo a\`, true, 1, 7},
+		{"| в начале", "| cat", false, 1, 1},
+		{"двойная точка с запятой", "echo a;;", false, 1, 8},
+		{"лишняя скобка", "echo a )", false, 1, 8},
+		{"пустые скобки", "( )", false, 1, 3},
+		{"нет файла после редиректа", "echo >", false, 1, 6},
+		{"редирект перед |", "echo > | cat", false, 1, 8},
+		{"&& подряд", "a && && b", false, 1, 6},
+		{"ошибка на второй строке", "echo a\necho b |; c", false, 2, 9},
+		{"& в начале", "& a", false, 1, 1},
+		{"; после &", "a & ; b", false, 1, 5},
+		{"незакрытая подстановка", "echo $(ls", true, 1, 6},
+		{"ошибка внутри подстановки", "echo $(ls |)", false, 1, 12},
+		{"незакрытый if", "if a; then b", true, 1, 1},
+		{"if без then", "if a", true, 1, 1},
+		{"пустое условие", "if then a; fi", false, 1, 4},
+		{"пустая ветка", "if a; then fi", false, 1, 12},
+		{"fi без if", "echo a; fi", false, 1, 9},
+		{"незакрытый while", "while a; do\nb", true, 1, 1},
+		{"done вместо fi", "if a; then b; done", false, 1, 15},
+		{"неверное имя в for", "for 1 in a; do b; done", false, 1, 5},
+		{"for без do", "for i in a b", true, 1, 1},
+		{"for без ;", "for i in a b do c; done", false, 1, 20},
+		{"незакрытая функция", "f() {", true, 1, 5},
+		{"функция без тела", "f()", true, 1, 1},
+		{"простое тело функции", "f() echo a", false, 1, 5},
+		{"ошибка в скрипте", "echo a\nif b; then\n  c\nfi fi", false, 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("Parse(%q) error = %v, want *SyntaxError", tt.input, err)
+			}
+			if se.Incomplete != tt.incomplete {
+				t.Errorf("Incomplete = %v, want %v (%v)", se.Incomplete, tt.incomplete, se)
+			}
+			if se.Pos.Line != tt.line || se.Pos.Col != tt.col {
+				t.Errorf("Pos = %d:%d, want %d:%d (%v)", se.Pos.Line, se.Pos.Col, tt.line, tt.col, se)
+			}
+		})
+	}
+}
+
+func TestParseCommandSubstitution(t *testing.T) {
+
+	list, err := Parse(`echo x"$(echo a; echo b)"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := list.Items[0].(*SimpleCommand).Args[1].Parts
+	if len(parts) != 2 || parts[1].Kind != CmdSubst || !parts[1].InQuotes {
+		t.Fatalf("parts = %+v", parts)
+	}
+	if got := parts[1].Cmd.String(); got != "[echo a]; [echo b]" {
+		t.Errorf("Cmd = %s", got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+
+	tests := []string{
+		"sleep 10",
+		"A=1 cmd 'a b' \"$x\" >out 2>&1",
+		"ps aux | grep go | wc -l",
+		"! a && b || c",
+		"(cd /tmp; ls) | sort",
+		"{ a; b; } >f",
+		"sleep 1 & echo a",
+		"{ a & }",
+		"echo $(date)",
+		"if a; then b; elif c; then d; else e; fi",
+		"while a && b; do c & done >log",
+		"until a; do b; done",
+		"for i in a 'b c'; do echo $i; done",
+		"for i; do echo $i; done | sort",
+		"f() { echo a; }",
+	}
+
+	for _, src := range tests {
+		list, err := Parse(src)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", src, err)
+		}
+		if got := Format(list); got != src {
+			t.Errorf("Format(Parse(%q)) = %q", src, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// приглашения по умолчанию; переопределяются переменными шелла PS1 и PS2
+const (
+	defaultPS1 = `mysh:\w [\?]> `
+	defaultPS2 = "> "
+)
+
+// prompt возвращает раскрытое приглашение из переменной name или def, если она не задана
+func (shell *Shell) prompt(name, def string) string {
+
+	ps, ok := shell.getVar(name)
+	if !ok {
+		ps = def
+	}
+
+	return expandPrompt(shell, ps)
+}
+
+// expandPrompt раскрывает escape-последовательности приглашения (как в bash):
+// \w - текущая директория (~ вместо домашней), \W - её последний компонент, \? - статус последней команды,
+// \u - пользователь, \h - имя хоста, \$ - # для root, иначе $, \n - перевод строки,
+// \e - ESC для цветов, \[ и \] - границы непечатаемых символов (редактор определяет их сам), \\ - \
+func expandPrompt(shell *Shell, ps string) string {
+
+	var sb strings.Builder
+	for i := 0; i < len(ps); i++ {
+		if ps[i] != '\\' || i+1 >= len(ps) {
+			sb.WriteByte(ps[i])
+			continue
+		}
+
+		i++
+		switch ps[i] {
+		case 'w':
+			sb.WriteString(shell.displayDir())
+		case 'W':
+			if dir := shell.displayDir(); dir == "~" || dir == "/" {
+				sb.WriteString(dir)
+			} else {
+				sb.WriteString(filepath.Base(dir))
+			}
+		case '?':
+			sb.WriteString(strconv.Itoa(shell.lastStatus))
+		case 'u':
+			sb.WriteString(userName(shell))
+		case 'h':
+			host, _ := os.Hostname()
+			host, _, _ = strings.Cut(host, ".")
+			sb.WriteString(host)
+		case '$':
+			if os.Geteuid() == 0 {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('$')
+			}
+		case 'n':
+			sb.WriteByte('\n')
+		case 'e':
+			sb.WriteByte(27)
+		case '[', ']':
+		case '\\':
+			sb.WriteByte('\\')
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(ps[i])
+		}
+	}
+
+	return sb.String()
+}
+
+// displayDir возвращает текущую директорию, заменяя домашнюю на ~
+func (shell *Shell) displayDir() string {
+
+	home, _ := shell.getVar("HOME")
+	home = strings.TrimSuffix(home, "/")
+	if home != "" && (shell.dir == home || strings.HasPrefix(shell.dir, home+"/")) {
+		return "~" + shell.dir[len(home):]
+	}
+
+	return shell.dir
+}
+
+// userName возвращает имя пользователя
+func userName(shell *Shell) string {
+
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	name, _ := shell.getVar("USER")
+
+	return name
+}
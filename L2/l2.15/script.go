@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"l2.15/pkg/parser"
+)
+
+// runArgs выполняет шелл в неинтерактивном режиме по аргументам командной строки:
+//
+//	mysh -c 'команды' [имя [аргументы]]  - команды из строки, имя становится $0
+//	mysh script.sh [аргументы]           - команды из файла (в том числе по #!)
+//
+// Возвращает код завершения шелла
+func runArgs(shell *Shell, args []string, stdio Stdio) int {
+
+	if args[0] == "-c" {
+		if len(args) < 2 {
+			fmt.Fprintln(stdio.Err, "mysh: -c: требуется аргумент")
+			return 2
+		}
+		if len(args) > 2 {
+			shell.name, shell.args = args[2], args[3:]
+		}
+		return runSource(shell, "-c", args[1], stdio)
+	}
+
+	if len(args[0]) > 1 && args[0][0] == '-' {
+		fmt.Fprintf(stdio.Err, "mysh: %s: неизвестный ключ\nиспользование: mysh [-c команды | файл] [аргументы]\n", args[0])
+		return 2
+	}
+
+	data, err := os.ReadFile(shell.resolvePath(args[0]))
+	if err != nil {
+		fmt.Fprintf(stdio.Err, "mysh: %s: %v\n", args[0], unwrapPathError(err))
+		return 127
+	}
+	shell.name, shell.args = args[0], args[1:]
+
+	return runSource(shell, args[0], string(data), stdio)
+}
+
+// runSource разбирает и выполняет текст скрипта целиком. При синтаксической ошибке ничего
+// не выполняется: сообщение содержит имя скрипта и номер строки, код завершения - 2
+func runSource(shell *Shell, name, src string, stdio Stdio) int {
+
+	list, err := parser.Parse(src)
+	if err != nil {
+		fmt.Fprintf(stdio.Err, "mysh: %s: %v\n", name, err)
+		return 2
+	}
+
+	status := execute(shell, list, stdio)
+	if shell.exiting {
+		return shell.exitCode
+	}
+
+	return status
+}
@@ -0,0 +1,107 @@
+//go:build linux
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// tcsetpgrp делает группу pgid активной группой терминала.
+// Шелл вызывает её, находясь в фоновой группе, поэтому на время вызова блокирует SIGTTOU,
+// иначе ядро остановило бы сам шелл
+func tcsetpgrp(fd, pgid int) error {
+
+	// маска сигналов у каждого потока своя - не даём планировщику сменить поток
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	const (
+		sigBlock   = 0
+		sigSetmask = 2
+	)
+	set := uint64(1) << (uint(syscall.SIGTTOU) - 1)
+	var old uint64
+	syscall.RawSyscall6(syscall.SYS_RT_SIGPROCMASK, sigBlock, uintptr(unsafe.Pointer(&set)), uintptr(unsafe.Pointer(&old)), 8, 0, 0)
+	defer syscall.RawSyscall6(syscall.SYS_RT_SIGPROCMASK, sigSetmask, uintptr(unsafe.Pointer(&old)), 0, 8, 0, 0)
+
+	pgrp := int32(pgid)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&pgrp)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// procEvent - изменение состояния дочернего процесса
+type procEvent int
+
+const (
+	procExited    procEvent = iota // завершился (ещё не освобождён)
+	procStopped                    // остановлен сигналом
+	procContinued                  // продолжил работу после SIGCONT
+)
+
+// siginfo - начало структуры siginfo_t, которую заполняет waitid
+type siginfo struct {
+	signo  int32
+	errno  int32
+	code   int32
+	_      int32
+	pid    int32
+	uid    uint32
+	status int32
+	_      [100]byte
+}
+
+// значения si_code для SIGCHLD
+const (
+	cldStopped   = 5
+	cldContinued = 6
+)
+
+// waitEvent ждёт изменения состояния процесса. Завершившийся процесс не освобождается -
+// это делает cmd.Wait, который заодно дожидается копирования вывода.
+// Для остановки возвращает сигнал, которым процесс остановлен
+func waitEvent(pid int) (procEvent, syscall.Signal) {
+
+	const (
+		pPid       = 1
+		wStopped   = 0x2
+		wExited    = 0x4
+		wContinued = 0x8
+		wNohang    = 0x1
+		wNowait    = 0x1000000
+	)
+
+	var info siginfo
+	for {
+		_, _, errno := syscall.Syscall6(syscall.SYS_WAITID, pPid, uintptr(pid), uintptr(unsafe.Pointer(&info)),
+			wExited|wStopped|wContinued|wNowait, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			// ошибку получит cmd.Wait
+			return procExited, 0
+		}
+		break
+	}
+
+	if info.code != cldStopped && info.code != cldContinued {
+		return procExited, 0
+	}
+
+	// остановку и продолжение считываем, чтобы следующий waitid ждал нового события
+	var consumed siginfo
+	syscall.Syscall6(syscall.SYS_WAITID, pPid, uintptr(pid), uintptr(unsafe.Pointer(&consumed)),
+		wStopped|wContinued|wNohang, 0, 0)
+
+	if info.code == cldContinued {
+		return procContinued, 0
+	}
+
+	return procStopped, syscall.Signal(info.status)
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// без Linux управление терминалом и отслеживание остановок не поддерживаются:
+// задания выполняются в фоне, но Ctrl+Z, fg и bg для остановленных недоступны
+
+func tcsetpgrp(fd, pgid int) error {
+	return errors.New("управление терминалом не поддерживается")
+}
+
+type procEvent int
+
+const (
+	procExited procEvent = iota
+	procStopped
+	procContinued
+)
+
+func waitEvent(pid int) (procEvent, syscall.Signal) {
+	return procExited, 0
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"l2.15/pkg/parser"
+)
+
+// shellVar - переменная шелла; экспортированные попадают в окружение внешних команд
+type shellVar struct {
+	value    string
+	exported bool
+}
+
+// envVars создаёт таблицу переменных из окружения процесса (все экспортированы)
+func envVars() map[string]shellVar {
+
+	vars := make(map[string]shellVar)
+	for _, kv := range os.Environ() {
+		name, value, ok := strings.Cut(kv, "=")
+		if ok && parser.IsName(name) {
+			vars[name] = shellVar{value: value, exported: true}
+		}
+	}
+
+	return vars
+}
+
+// copyVars копирует таблицу переменных для подоболочки
+func copyVars(vars map[string]shellVar) map[string]shellVar {
+
+	result := make(map[string]shellVar, len(vars))
+	for name, v := range vars {
+		result[name] = v
+	}
+
+	return result
+}
+
+// getVar возвращает значение переменной
+func (shell *Shell) getVar(name string) (string, bool) {
+
+	v, ok := shell.vars[name]
+
+	return v.value, ok
+}
+
+// setVar задаёт значение переменной, сохраняя признак экспорта
+func (shell *Shell) setVar(name, value string) {
+
+	v := shell.vars[name]
+	v.value = value
+	shell.vars[name] = v
+}
+
+// exportVar помечает переменную для передачи во внешние команды
+func (shell *Shell) exportVar(name string) {
+
+	v := shell.vars[name]
+	v.exported = true
+	shell.vars[name] = v
+}
+
+// unsetVar удаляет переменную
+func (shell *Shell) unsetVar(name string) {
+	delete(shell.vars, name)
+}
+
+// environ возвращает окружение для внешней команды: экспортированные переменные
+// и временные присваивания вида NAME=value, записанные перед командой
+func (shell *Shell) environ(assigns []string) []string {
+
+	env := make(map[string]string)
+	for name, v := range shell.vars {
+		if v.exported {
+			env[name] = v.value
+		}
+	}
+	for _, assign := range assigns {
+		name, value, _ := strings.Cut(assign, "=")
+		env[name] = value
+	}
+
+	result := make([]string, 0, len(env))
+	for name, value := range env {
+		result = append(result, name+"="+value)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// встроенные команды для переменных
+
+// exportCom - export NAME[=value]...: помечает переменные для передачи во внешние команды,
+// без аргументов печатает экспортированные переменные
+func exportCom(shell *Shell, args []string, stdio Stdio) error {
+
+	if len(args) < 2 || (len(args) == 2 && args[1] == "-p") {
+		for _, name := range sortedVarNames(shell, true) {
+			fmt.Fprintf(stdio.Out, "export %s=%s\n", name, quoteValue(shell.vars[name].value))
+		}
+		return nil
+	}
+
+	for _, arg := range args[1:] {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !parser.IsName(name) {
+			return fmt.Errorf("export: '%s': неверное имя переменной", arg)
+		}
+		if hasValue {
+			shell.setVar(name, value)
+		}
+		shell.exportVar(name)
+	}
+
+	return nil
+}
+
+// unsetCom - unset [-v] NAME... удаляет переменные, unset -f NAME... - функции
+func unsetCom(shell *Shell, args []string, stdio Stdio) error {
+
+	funcs := false
+	for _, name := range args[1:] {
+		switch name {
+		case "-v":
+			funcs = false
+			continue
+		case "-f":
+			funcs = true
+			continue
+		}
+		if funcs {
+			delete(shell.funcs, name)
+			continue
+		}
+		if !parser.IsName(name) {
+			return fmt.Errorf("unset: '%s': неверное имя переменной", name)
+		}
+		shell.unsetVar(name)
+	}
+
+	return nil
+}
+
+// envCom - env: печатает окружение внешних команд; с аргументами запускает системный env
+// (env NAME=value cmd) с окружением шелла
+func envCom(shell *Shell, args []string, stdio Stdio) error {
+
+	if len(args) > 1 {
+		if status := executeExternalCommand(shell, args, nil, stdio); status != 0 {
+			return exitStatusError(status)
+		}
+		return nil
+	}
+
+	for _, kv := range shell.environ(nil) {
+		fmt.Fprintln(stdio.Out, kv)
+	}
+
+	return nil
+}
+
+// sortedVarNames возвращает отсортированные имена переменных (только экспортированных, если exported)
+func sortedVarNames(shell *Shell, exported bool) []string {
+
+	names := make([]string, 0, len(shell.vars))
+	for name, v := range shell.vars {
+		if !exported || v.exported {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// quoteValue заключает значение в одинарные кавычки так, чтобы его можно было ввести обратно
+func quoteValue(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
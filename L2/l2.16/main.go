@@ -2,20 +2,235 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"mywget/pkg/loader"
 )
 
+// defaultRecursiveDepth - глубина для -r без -l (как у wget)
+const defaultRecursiveDepth = 5
+
+// Config - разобранные аргументы командной строки
+type Config struct {
+	URL   string
+	Depth int
+	Opts  loader.Options
+}
+
+// listValue - флаг со списком через запятую, может повторяться
+type listValue struct {
+	list *[]string
+}
+
+func (v listValue) String() string {
+	if v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, ",")
+}
+
+func (v listValue) Set(s string) error {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*v.list = append(*v.list, item)
+		}
+	}
+	return nil
+}
+
+// secondsValue - длительность в секундах ("2", "0.5") или в формате Go ("500ms")
+type secondsValue struct {
+	d *time.Duration
+}
+
+func (v secondsValue) String() string {
+	if v.d == nil {
+		return ""
+	}
+	return v.d.String()
+}
+
+func (v secondsValue) Set(s string) error {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		if seconds < 0 {
+			return errors.New("длительность не может быть отрицательной")
+		}
+		*v.d = time.Duration(seconds * float64(time.Second))
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return fmt.Errorf("неверная длительность: %q", s)
+	}
+	*v.d = d
+	return nil
+}
+
+// levelValue - глубина рекурсии, "inf" - без ограничения
+type levelValue struct {
+	level *int
+}
+
+func (v levelValue) String() string {
+	if v.level == nil {
+		return ""
+	}
+	return strconv.Itoa(*v.level)
+}
+
+func (v levelValue) Set(s string) error {
+	if s == "inf" || s == "0" {
+		*v.level = math.MaxInt32
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return fmt.Errorf("неверная глубина: %q", s)
+	}
+	*v.level = n
+	return nil
+}
+
+// parseArgs разбирает аргументы командной строки в стиле wget: флаги можно писать
+// и до, и после URL, у большинства флагов есть короткая и длинная форма
+func parseArgs(args []string, output io.Writer) (Config, error) {
+
+	config := Config{Opts: loader.DefaultOptions()}
+	opts := &config.Opts
+
+	fs := flag.NewFlagSet("mywget", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintln(output, "Использование: mywget [флаги] <URL> [глубина]")
+		fmt.Fprintln(output, "       mywget replay [-listen адрес] <архив.warc[.gz]>")
+		fs.PrintDefaults()
+	}
+
+	var recursive bool
+	level := -1
+	tries := 0
+
+	// обход
+	fs.BoolVar(&recursive, "r", false, "recursive download (default depth 5)")
+	fs.BoolVar(&recursive, "recursive", false, "same as -r")
+	fs.Var(levelValue{&level}, "l", "maximum recursion depth (inf or 0 - unlimited)")
+	fs.Var(levelValue{&level}, "level", "same as -l")
+	fs.BoolVar(&opts.NoParent, "np", false, "don't ascend to the parent directory")
+	fs.BoolVar(&opts.NoParent, "no-parent", false, "same as -np")
+	fs.Var(listValue{&opts.Domains}, "D", "comma-separated list of additional domains to follow")
+	fs.Var(listValue{&opts.Domains}, "domains", "same as -D")
+	fs.Var(listValue{&opts.ExcludeDomains}, "exclude-domains", "comma-separated list of domains to skip")
+	fs.Var(listValue{&opts.Accept}, "A", "comma-separated list of accepted extensions or MIME types (image/*)")
+	fs.Var(listValue{&opts.Accept}, "accept", "same as -A")
+	fs.Var(listValue{&opts.Reject}, "R", "comma-separated list of rejected extensions or MIME types")
+	fs.Var(listValue{&opts.Reject}, "reject", "same as -R")
+	fs.BoolVar(&opts.Sitemaps, "sitemaps", false, "also crawl URLs from sitemaps listed in robots.txt")
+
+	// загрузка
+	fs.StringVar(&opts.UserAgent, "U", opts.UserAgent, "User-Agent header (also used for robots.txt)")
+	fs.StringVar(&opts.UserAgent, "user-agent", opts.UserAgent, "same as -U")
+	fs.Var(secondsValue{&opts.Wait}, "w", "wait between requests, seconds")
+	fs.Var(secondsValue{&opts.Wait}, "wait", "same as -w")
+	fs.BoolVar(&opts.RandomWait, "random-wait", false, "wait from 0.5 to 1.5 of --wait between requests")
+	fs.Var(secondsValue{&opts.MaxCrawlDelay}, "max-crawl-delay", "maximum honored robots.txt Crawl-delay, seconds (0 - ignore Crawl-delay)")
+	fs.Var(secondsValue{&opts.Timeout}, "T", "request timeout, seconds")
+	fs.Var(secondsValue{&opts.Timeout}, "timeout", "same as -T")
+	fs.IntVar(&tries, "t", 0, "number of tries (default 1 + -retries)")
+	fs.IntVar(&tries, "tries", 0, "same as -t")
+	fs.IntVar(&opts.Workers, "workers", opts.Workers, "number of parallel downloads")
+	fs.IntVar(&opts.MaxRetries, "retries", opts.MaxRetries, "number of retries on 5xx and network errors")
+	fs.DurationVar(&opts.BackoffBase, "backoff", opts.BackoffBase, "initial retry backoff (doubles on every retry)")
+	fs.DurationVar(&opts.BackoffMax, "backoff-max", opts.BackoffMax, "maximum retry backoff")
+	fs.Float64Var(&opts.RateLimit, "rate", 0, "global request rate limit, requests per second (0 - unlimited)")
+	fs.Float64Var(&opts.HostRateLimit, "host-rate", 0, "per-host request rate limit, requests per second (0 - unlimited)")
+	fs.StringVar(&opts.Proxy, "proxy", "", "HTTP proxy for all requests, e.g. http://127.0.0.1:8080 of mywget replay")
+
+	// сохранение
+	fs.StringVar(&opts.Dir, "P", "", "directory prefix for the mirror")
+	fs.StringVar(&opts.Dir, "directory-prefix", "", "same as -P")
+	fs.BoolVar(&opts.ConvertLinks, "k", false, "convert links to local ones for offline viewing")
+	fs.BoolVar(&opts.ConvertLinks, "convert-links", false, "same as -k")
+	fs.BoolVar(&opts.Timestamping, "N", false, "don't re-download files that are not newer than local ones")
+	fs.BoolVar(&opts.Timestamping, "timestamping", false, "same as -N")
+	fs.StringVar(&opts.StateFile, "state", "", "crawl state file (default <dir>/<host>/.mywget-state.json, \"-\" - disabled)")
+	fs.StringVar(&opts.ManifestFile, "manifest", "", "mirror manifest for incremental re-crawls (default <dir>/<host>/.mywget-manifest.jsonl, \"-\" - disabled)")
+	fs.StringVar(&opts.WARCFile, "warc", "", "write HTTP exchanges to a WARC 1.1 archive (.gz - compressed), appends to an existing one")
+
+	// флаги могут идти после позиционных аргументов - разбираем по кускам
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return config, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(positional) < 1 || len(positional) > 2 {
+		fs.Usage()
+		return config, errors.New("нужно указать URL и, возможно, глубину")
+	}
+	config.URL = positional[0]
+
+	// -t задаёт общее число попыток (0 - без ограничения, как у wget)
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "t" || f.Name == "tries" {
+			if tries <= 0 {
+				opts.MaxRetries = math.MaxInt32
+			} else {
+				opts.MaxRetries = tries - 1
+			}
+		}
+	})
+
+	// глубина: по умолчанию 1, -r - 5, -l N - N, старый позиционный аргумент важнее всего
+	config.Depth = 1
+	if recursive {
+		config.Depth = defaultRecursiveDepth
+	}
+	if level >= 0 {
+		config.Depth = level
+	}
+	if len(positional) == 2 {
+		d, err := strconv.Atoi(positional[1])
+		if err != nil || d < 0 {
+			return config, fmt.Errorf("неверная глубина: %q", positional[1])
+		}
+		config.Depth = d
+	}
+
+	if opts.Workers < 1 {
+		return config, errors.New("--workers должно быть больше 0")
+	}
+
+	return config, nil
+}
+
 func main() {
 
-	// проверяем аргументы запуска программы
-	if len(os.Args) < 2 {
-		fmt.Println("Использование: mywget <URL> [глубина]")
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(replayMain(os.Args[2:]))
+	}
+
+	config, err := parseArgs(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mywget:", err)
 		os.Exit(1)
 	}
 
@@ -34,14 +249,9 @@ func main() {
 		cancel()
 	}()
 
-	url := os.Args[1]
-	depth := 1
-	if len(os.Args) >= 3 {
-		d, _ := strconv.Atoi(os.Args[2])
-		depth = d
-	}
+	url := config.URL
 
-	err := loader.Load(ctx, url, depth)
+	err = loader.Load(ctx, url, config.Depth, config.Opts)
 	if err != nil {
 		fmt.Printf("Загрузка %s завершилась с ошибкой: %v\n", url, err)
 		os.Exit(1)
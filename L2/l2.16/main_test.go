@@ -0,0 +1,172 @@
+package main
+
+import (
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseArgs(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		args  []string
+		check func(t *testing.T, c Config)
+	}{
+		{
+			name: "только URL",
+			args: []string{"http://example.com"},
+			check: func(t *testing.T, c Config) {
+				if c.URL != "http://example.com" || c.Depth != 1 {
+					t.Errorf("URL = %q, depth = %d", c.URL, c.Depth)
+				}
+				if c.Opts.UserAgent != "mywget-bot" || c.Opts.Workers != 10 || c.Opts.ConvertLinks ||
+					c.Opts.Sitemaps || c.Opts.MaxCrawlDelay != 30*time.Second {
+					t.Errorf("unexpected defaults: %+v", c.Opts)
+				}
+			},
+		},
+		{
+			name: "позиционная глубина",
+			args: []string{"http://example.com", "3"},
+			check: func(t *testing.T, c Config) {
+				if c.Depth != 3 {
+					t.Errorf("depth = %d, want 3", c.Depth)
+				}
+			},
+		},
+		{
+			name: "-r без -l",
+			args: []string{"-r", "http://example.com"},
+			check: func(t *testing.T, c Config) {
+				if c.Depth != defaultRecursiveDepth {
+					t.Errorf("depth = %d, want %d", c.Depth, defaultRecursiveDepth)
+				}
+			},
+		},
+		{
+			name: "-l inf",
+			args: []string{"-r", "--level", "inf", "http://example.com"},
+			check: func(t *testing.T, c Config) {
+				if c.Depth != math.MaxInt32 {
+					t.Errorf("depth = %d, want unlimited", c.Depth)
+				}
+			},
+		},
+		{
+			name: "флаги после URL",
+			args: []string{"http://example.com", "-l", "2", "-np", "-k", "-N", "-P", "out"},
+			check: func(t *testing.T, c Config) {
+				if c.Depth != 2 || !c.Opts.NoParent || !c.Opts.ConvertLinks || !c.Opts.Timestamping || c.Opts.Dir != "out" {
+					t.Errorf("config = %+v", c)
+				}
+			},
+		},
+		{
+			name: "списки доменов и типов",
+			args: []string{"-D", "a.com,b.com", "--domains", "c.com", "--exclude-domains", "ads.a.com",
+				"-A", "png,jpg", "--reject", "image/gif", "http://example.com"},
+			check: func(t *testing.T, c Config) {
+				if !reflect.DeepEqual(c.Opts.Domains, []string{"a.com", "b.com", "c.com"}) {
+					t.Errorf("domains = %v", c.Opts.Domains)
+				}
+				if !reflect.DeepEqual(c.Opts.ExcludeDomains, []string{"ads.a.com"}) {
+					t.Errorf("exclude = %v", c.Opts.ExcludeDomains)
+				}
+				if !reflect.DeepEqual(c.Opts.Accept, []string{"png", "jpg"}) || !reflect.DeepEqual(c.Opts.Reject, []string{"image/gif"}) {
+					t.Errorf("accept = %v, reject = %v", c.Opts.Accept, c.Opts.Reject)
+				}
+			},
+		},
+		{
+			name: "ожидание, таймаут и User-Agent",
+			args: []string{"--wait", "1.5", "--random-wait", "-T", "500ms", "-U", "agent/1.0", "http://example.com"},
+			check: func(t *testing.T, c Config) {
+				if c.Opts.Wait != 1500*time.Millisecond || !c.Opts.RandomWait {
+					t.Errorf("wait = %v, random = %v", c.Opts.Wait, c.Opts.RandomWait)
+				}
+				if c.Opts.Timeout != 500*time.Millisecond || c.Opts.UserAgent != "agent/1.0" {
+					t.Errorf("timeout = %v, user agent = %q", c.Opts.Timeout, c.Opts.UserAgent)
+				}
+			},
+		},
+		{
+			name: "карты сайта и Crawl-delay",
+			args: []string{"--sitemaps", "--max-crawl-delay", "5", "http://example.com"},
+			check: func(t *testing.T, c Config) {
+				if !c.Opts.Sitemaps || c.Opts.MaxCrawlDelay != 5*time.Second {
+					t.Errorf("sitemaps = %v, max crawl delay = %v", c.Opts.Sitemaps, c.Opts.MaxCrawlDelay)
+				}
+			},
+		},
+		{
+			name: "архив WARC, прокси и манифест",
+			args: []string{"--warc", "site.warc.gz", "--proxy", "http://127.0.0.1:8080", "--manifest", "-", "http://example.com"},
+			check: func(t *testing.T, c Config) {
+				if c.Opts.WARCFile != "site.warc.gz" || c.Opts.Proxy != "http://127.0.0.1:8080" {
+					t.Errorf("warc = %q, proxy = %q", c.Opts.WARCFile, c.Opts.Proxy)
+				}
+				if c.Opts.ManifestFile != "-" {
+					t.Errorf("manifest = %q", c.Opts.ManifestFile)
+				}
+			},
+		},
+		{
+			name: "-t задаёт общее число попыток",
+			args: []string{"-t", "4", "http://example.com"},
+			check: func(t *testing.T, c Config) {
+				if c.Opts.MaxRetries != 3 {
+					t.Errorf("retries = %d, want 3", c.Opts.MaxRetries)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseArgs(tt.args, io.Discard)
+			if err != nil {
+				t.Fatalf("parseArgs() error = %v", err)
+			}
+			tt.check(t, config)
+		})
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+
+	tests := [][]string{
+		{},
+		{"http://a", "1", "extra"},
+		{"http://a", "x"},
+		{"-l", "-2", "http://a"},
+		{"--wait", "soon", "http://a"},
+		{"--workers", "0", "http://a"},
+		{"--unknown", "http://a"},
+	}
+
+	for _, args := range tests {
+		if _, err := parseArgs(args, io.Discard); err == nil {
+			t.Errorf("parseArgs(%q) expected error", args)
+		}
+	}
+}
+
+func TestParseReplayArgs(t *testing.T) {
+
+	config, err := parseReplayArgs([]string{"-listen", ":9000", "site.warc.gz"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parseReplayArgs() error = %v", err)
+	}
+	if config.Listen != ":9000" || config.Archive != "site.warc.gz" {
+		t.Errorf("config = %+v", config)
+	}
+
+	for _, args := range [][]string{{}, {"a.warc", "b.warc"}, {"-port", "1", "a.warc"}} {
+		if _, err := parseReplayArgs(args, io.Discard); err == nil {
+			t.Errorf("parseReplayArgs(%q) expected error", args)
+		}
+	}
+}
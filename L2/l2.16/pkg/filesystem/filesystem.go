@@ -9,18 +9,19 @@ import (
 	"strings"
 )
 
-// SaveFile сохраняет тело ответа в файловую систему
-func SaveFile(fileURL string, baseURL *url.URL, body io.Reader, contentType string) (string, error) {
+// SaveFile сохраняет тело ответа в файловую систему (в директорию root, "" - текущая)
+func SaveFile(root, fileURL string, body io.Reader, contentType string) (string, error) {
 
 	u, err := url.Parse(fileURL)
 	if err != nil {
 		return "", fmt.Errorf("parse file URL: %w", err)
 	}
 
-	localPath, err := GetLocalPath(u, baseURL, contentType)
+	localPath, err := GetLocalPath(u, contentType)
 	if err != nil {
 		return "", fmt.Errorf("get local path: %w", err)
 	}
+	localPath = filepath.Join(root, localPath)
 
 	// создаем директорию
 	dir := filepath.Dir(localPath)
@@ -43,11 +44,11 @@ func SaveFile(fileURL string, baseURL *url.URL, body io.Reader, contentType stri
 	return localPath, nil
 }
 
-// GetLocalPath преобразует URL в локальный путь (относительно текущей директории)
-func GetLocalPath(fileURL, baseURL *url.URL, contentType string) (string, error) {
+// GetLocalPath преобразует URL в локальный путь вида host/path (относительно директории зеркала)
+func GetLocalPath(fileURL *url.URL, contentType string) (string, error) {
 
-	if fileURL.Hostname() != baseURL.Hostname() {
-		return "", fmt.Errorf("несоответствие хоста: %s != %s", fileURL.Hostname(), baseURL.Hostname())
+	if fileURL.Hostname() == "" {
+		return "", fmt.Errorf("в URL нет хоста: %s", fileURL)
 	}
 
 	path := fileURL.Path
@@ -0,0 +1,135 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// PartialMeta - сведения о недокачанном файле, нужные для продолжения загрузки через Range
+type PartialMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// Validator возвращает значение для заголовка If-Range (сильный ETag или Last-Modified),
+// пустая строка означает, что безопасно продолжить загрузку нельзя
+func (m PartialMeta) Validator() string {
+
+	// слабый ETag ("W/...") не годится для If-Range (RFC 9110, 13.1.5)
+	if m.ETag != "" && len(m.ETag) > 2 && m.ETag[:2] != "W/" {
+		return m.ETag
+	}
+
+	return m.LastModified
+}
+
+// PartialPath возвращает путь недокачанного файла для URL в директории root.
+// Тип содержимого до ответа сервера неизвестен, поэтому путь строится без него
+func PartialPath(root string, fileURL *url.URL) (string, error) {
+
+	localPath, err := GetLocalPath(fileURL, "")
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(root, localPath) + ".part", nil
+}
+
+// metaPath - путь файла со сведениями о недокачанном файле
+func metaPath(partPath string) string {
+	return partPath + ".meta"
+}
+
+// LoadPartial возвращает размер уже скачанной части и сведения о ней.
+// Если части нет или она относится к другому URL, возвращается нулевой размер
+func LoadPartial(partPath, fileURL string) (int64, PartialMeta, error) {
+
+	var meta PartialMeta
+
+	data, err := os.ReadFile(metaPath(partPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, meta, nil
+	}
+	if err != nil {
+		return 0, meta, fmt.Errorf("read partial meta: %w", err)
+	}
+	if err := json.Unmarshal(data, &meta); err != nil || meta.URL != fileURL {
+		// испорченные или чужие сведения - качаем заново
+		return 0, PartialMeta{}, nil
+	}
+
+	info, err := os.Stat(partPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, meta, nil
+	}
+	if err != nil {
+		return 0, meta, fmt.Errorf("stat partial file: %w", err)
+	}
+
+	return info.Size(), meta, nil
+}
+
+// CreatePartial начинает загрузку заново: создаёт (обрезает) файл части и записывает сведения о ней
+func CreatePartial(partPath string, meta PartialMeta) (*os.File, error) {
+
+	if err := os.MkdirAll(filepath.Dir(partPath), 0755); err != nil {
+		return nil, fmt.Errorf("create directory: %w", err)
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("encode partial meta: %w", err)
+	}
+	if err := os.WriteFile(metaPath(partPath), data, 0644); err != nil {
+		return nil, fmt.Errorf("write partial meta: %w", err)
+	}
+
+	file, err := os.Create(partPath)
+	if err != nil {
+		return nil, fmt.Errorf("create file %s: %w", partPath, err)
+	}
+
+	return file, nil
+}
+
+// AppendPartial открывает файл части для дописывания
+func AppendPartial(partPath string) (*os.File, error) {
+
+	file, err := os.OpenFile(partPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open file %s: %w", partPath, err)
+	}
+
+	return file, nil
+}
+
+// CommitPartial переносит докачанный файл на его окончательное место
+func CommitPartial(partPath, localPath string) error {
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	if err := os.Rename(partPath, localPath); err != nil {
+		return fmt.Errorf("rename %s: %w", partPath, err)
+	}
+
+	return RemovePartial(partPath)
+}
+
+// RemovePartial удаляет недокачанный файл и сведения о нём
+func RemovePartial(partPath string) error {
+
+	for _, path := range []string{partPath, metaPath(partPath)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
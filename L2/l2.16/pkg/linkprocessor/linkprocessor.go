@@ -11,8 +11,9 @@ import (
 	"golang.org/x/net/html"
 )
 
-// ReplaceLinks заменяет все ссылки в HTML на локальные
-func ReplaceLinks(htmlContent []byte, pageURL, baseURL *url.URL) ([]byte, error) {
+// ReplaceLinks заменяет все ссылки в HTML на локальные.
+// isLocal решает, какие URL сохраняются в зеркале (остальные ссылки не меняются)
+func ReplaceLinks(htmlContent []byte, pageURL *url.URL, isLocal func(*url.URL) bool) ([]byte, error) {
 
 	doc, err := html.Parse(bytes.NewReader(htmlContent))
 	if err != nil {
@@ -25,11 +26,11 @@ func ReplaceLinks(htmlContent []byte, pageURL, baseURL *url.URL) ([]byte, error)
 			// список атрибутов, которые могут содержать ссылки
 			attrs := []string{"href", "src", "srcset", "data-src", "action", "background", "poster"}
 			for _, attr := range attrs {
-				replaceAttr(n, attr, pageURL, baseURL)
+				replaceAttr(n, attr, pageURL, isLocal)
 			}
 			// обработка стилей (style attribute)
 			if n.Data == "style" {
-				replaceStyleContent(n, pageURL, baseURL)
+				replaceStyleContent(n, pageURL, isLocal)
 			}
 		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
@@ -48,7 +49,7 @@ func ReplaceLinks(htmlContent []byte, pageURL, baseURL *url.URL) ([]byte, error)
 }
 
 // ReplaceCSSLinks заменяет все ссылки в CSS на локальные
-func ReplaceCSSLinks(cssContent []byte, pageURL, baseURL *url.URL) ([]byte, error) {
+func ReplaceCSSLinks(cssContent []byte, pageURL *url.URL, isLocal func(*url.URL) bool) ([]byte, error) {
 
 	cssStr := string(cssContent)
 
@@ -67,7 +68,7 @@ func ReplaceCSSLinks(cssContent []byte, pageURL, baseURL *url.URL) ([]byte, erro
 			return match
 		}
 
-		newLink := replaceLink(link, pageURL, baseURL)
+		newLink := replaceLink(link, pageURL, isLocal)
 		return strings.Replace(match, link, newLink, 1)
 	})
 
@@ -80,7 +81,7 @@ func ReplaceCSSLinks(cssContent []byte, pageURL, baseURL *url.URL) ([]byte, erro
 		}
 
 		link := submatch[1]
-		newLink := replaceLink(link, pageURL, baseURL)
+		newLink := replaceLink(link, pageURL, isLocal)
 		return strings.Replace(match, link, newLink, 1)
 	})
 
@@ -88,16 +89,16 @@ func ReplaceCSSLinks(cssContent []byte, pageURL, baseURL *url.URL) ([]byte, erro
 }
 
 // replaceAttr заменяет ссылку в атрибуте
-func replaceAttr(n *html.Node, attrName string, pageURL, baseURL *url.URL) {
+func replaceAttr(n *html.Node, attrName string, pageURL *url.URL, isLocal func(*url.URL) bool) {
 
 	for i, attr := range n.Attr {
 		if attr.Key == attrName {
 			// в srcset может быть несколько ссылок, разделенных запятыми
 			if attrName == "srcset" {
-				newVal := replaceSrcset(attr.Val, pageURL, baseURL)
+				newVal := replaceSrcset(attr.Val, pageURL, isLocal)
 				n.Attr[i].Val = newVal
 			} else {
-				newVal := replaceLink(attr.Val, pageURL, baseURL)
+				newVal := replaceLink(attr.Val, pageURL, isLocal)
 				n.Attr[i].Val = newVal
 			}
 		}
@@ -105,7 +106,7 @@ func replaceAttr(n *html.Node, attrName string, pageURL, baseURL *url.URL) {
 }
 
 // replaceLink заменяет одну ссылку
-func replaceLink(link string, pageURL, baseURL *url.URL) string {
+func replaceLink(link string, pageURL *url.URL, isLocal func(*url.URL) bool) string {
 
 	if link == "" {
 		return link
@@ -125,19 +126,19 @@ func replaceLink(link string, pageURL, baseURL *url.URL) string {
 		return link
 	}
 
-	// если ссылка ведет на другой домен, оставляем как есть
-	if absoluteURL.Hostname() != baseURL.Hostname() {
+	// если ссылка ведет на ресурс, которого не будет в зеркале, оставляем как есть
+	if !isLocal(absoluteURL) {
 		return link
 	}
 
 	// вычисляем локальный путь для абсолютного URL
-	localPath, err := getLocalPath(absoluteURL, baseURL)
+	localPath, err := getLocalPath(absoluteURL)
 	if err != nil {
 		return link
 	}
 
 	// вычисляем локальный путь для текущей страницы
-	currentPageLocalPath, err := getLocalPath(pageURL, baseURL)
+	currentPageLocalPath, err := getLocalPath(pageURL)
 	if err != nil {
 		return link
 	}
@@ -151,11 +152,12 @@ func replaceLink(link string, pageURL, baseURL *url.URL) string {
 	return relativePath
 }
 
-// getLocalPath возвращает локальный путь для URL (без домена)
-func getLocalPath(fileURL, baseURL *url.URL) (string, error) {
+// getLocalPath возвращает локальный путь для URL вида host/path
+// (хост нужен, чтобы ссылки между разными хостами зеркала тоже были относительными)
+func getLocalPath(fileURL *url.URL) (string, error) {
 
-	if fileURL.Hostname() != baseURL.Hostname() {
-		return "", fmt.Errorf("host mismatch")
+	if fileURL.Hostname() == "" {
+		return "", fmt.Errorf("empty host")
 	}
 
 	path := fileURL.Path
@@ -187,7 +189,7 @@ func getLocalPath(fileURL, baseURL *url.URL) (string, error) {
 		path = filepath.Join(dir, name+"_"+encodedQuery+ext)
 	}
 
-	return path, nil
+	return filepath.Join(fileURL.Hostname(), path), nil
 }
 
 // getRelativePath возвращает относительный путь от source к target
@@ -221,14 +223,14 @@ func getRelativePath(source, target string) (string, error) {
 }
 
 // replaceSrcset заменяет ссылки в атрибуте srcset
-func replaceSrcset(srcset string, pageURL, baseURL *url.URL) string {
+func replaceSrcset(srcset string, pageURL *url.URL, isLocal func(*url.URL) bool) string {
 
 	parts := strings.Split(srcset, ",")
 	for i, part := range parts {
 		subparts := strings.Split(strings.TrimSpace(part), " ")
 		if len(subparts) > 0 {
 			link := subparts[0]
-			newLink := replaceLink(link, pageURL, baseURL)
+			newLink := replaceLink(link, pageURL, isLocal)
 			subparts[0] = newLink
 			parts[i] = strings.Join(subparts, " ")
 		}
@@ -238,17 +240,17 @@ func replaceSrcset(srcset string, pageURL, baseURL *url.URL) string {
 }
 
 // replaceStyleContent заменяет url() в стилях
-func replaceStyleContent(n *html.Node, pageURL, baseURL *url.URL) {
+func replaceStyleContent(n *html.Node, pageURL *url.URL, isLocal func(*url.URL) bool) {
 
 	if n.Type == html.ElementNode && n.Data == "style" && n.FirstChild != nil {
 		content := n.FirstChild.Data
-		newContent := replaceCSSUrls(content, pageURL, baseURL)
+		newContent := replaceCSSUrls(content, pageURL, isLocal)
 		n.FirstChild.Data = newContent
 	}
 }
 
 // replaceCSSUrls заменяет url() в CSS
-func replaceCSSUrls(css string, pageURL, baseURL *url.URL) string {
+func replaceCSSUrls(css string, pageURL *url.URL, isLocal func(*url.URL) bool) string {
 
 	start := 0
 	var result strings.Builder
@@ -273,7 +275,7 @@ func replaceCSSUrls(css string, pageURL, baseURL *url.URL) string {
 
 		// убираем кавычки и пробелы
 		urlContent = strings.Trim(urlContent, " '\"")
-		newURL := replaceLink(urlContent, pageURL, baseURL)
+		newURL := replaceLink(urlContent, pageURL, isLocal)
 		result.WriteString("url(")
 		result.WriteString(newURL)
 		result.WriteString(")")
@@ -0,0 +1,446 @@
+package loader
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"mywget/pkg/filesystem"
+)
+
+// statusError - ответ сервера с неуспешным статусом
+type statusError struct {
+	code       int
+	status     string
+	retryAfter time.Duration // значение заголовка Retry-After (0, если его нет)
+}
+
+func (e *statusError) Error() string {
+	return "статус " + e.status
+}
+
+// retryableError помечает ошибку, после которой запрос стоит повторить
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// isRetryable сообщает, стоит ли повторять запрос после ошибки
+func isRetryable(err error) bool {
+
+	// отмену пользователем не повторяем
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+
+	var re *retryableError
+	return errors.As(err, &re)
+}
+
+// backoff вычисляет паузу перед попыткой attempt (с 1): экспоненциальный рост со случайным разбросом,
+// но не меньше Retry-After, если сервер его прислал
+func (l *Loader) backoff(attempt int, err error) time.Duration {
+
+	delay := l.opts.BackoffBase << (attempt - 1)
+	if delay <= 0 || delay > l.opts.BackoffMax {
+		delay = l.opts.BackoffMax
+	}
+	// разброс ±25%, чтобы воркеры не ломились на сервер одновременно
+	delay = delay*3/4 + time.Duration(rand.Int64N(int64(delay/2)+1))
+
+	var se *statusError
+	if errors.As(err, &se) && se.retryAfter > delay {
+		delay = min(se.retryAfter, l.opts.BackoffMax)
+	}
+
+	return delay
+}
+
+// sleep ждёт delay или отмены контекста
+func (l *Loader) sleep(delay time.Duration) error {
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-l.ctx.Done():
+		return l.ctx.Err()
+	}
+}
+
+// parseRetryAfter разбирает заголовок Retry-After (секунды или HTTP-дата)
+func parseRetryAfter(value string) time.Duration {
+
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// downloadResult - итог загрузки ресурса
+type downloadResult struct {
+	localPath    string
+	mimeType     string
+	notModified  bool   // ресурс не изменился (304, совпал хеш или при -N не новее локального) - файл не перезаписан
+	etag         string // валидаторы и хеш содержимого для манифеста
+	lastModified string
+	hash         string
+}
+
+// localCopy - уже скачанный ранее файл (из манифеста или для -N)
+type localCopy struct {
+	path    string
+	modTime time.Time
+	size    int64
+	entry   *manifestEntry // запись манифеста, nil - копия найдена по -N
+}
+
+// manifestCopy ищет файл ресурса, записанный в манифесте. При -k страницу без оригинала
+// (.orig) не проверяем: ссылки из неё пришлось бы брать уже заменёнными
+func (l *Loader) manifestCopy(rawURL string) (localCopy, bool) {
+
+	entry, ok := l.manifest.lookup(rawURL)
+	if !ok {
+		return localCopy{}, false
+	}
+
+	localPath := filepath.Join(l.opts.Dir, entry.Path)
+	info, err := os.Stat(localPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return localCopy{}, false
+	}
+	if l.opts.ConvertLinks && (entry.MIME == "text/html" || entry.MIME == "text/css") {
+		if _, err := os.Stat(localPath + origSuffix); err != nil {
+			return localCopy{}, false
+		}
+	}
+
+	return localCopy{path: localPath, modTime: info.ModTime(), size: info.Size(), entry: &entry}, true
+}
+
+// findLocalCopy ищет сохранённую ранее копию ресурса. Тип содержимого до ответа
+// неизвестен, поэтому проверяем и путь как есть, и путь HTML-страницы (с .html)
+func (l *Loader) findLocalCopy(fileURL *url.URL) (localCopy, bool) {
+
+	for _, contentType := range []string{"", "text/html"} {
+		localPath, err := filesystem.GetLocalPath(fileURL, contentType)
+		if err != nil {
+			return localCopy{}, false
+		}
+		localPath = filepath.Join(l.opts.Dir, localPath)
+
+		info, err := os.Stat(localPath)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		local := localCopy{path: localPath, modTime: info.ModTime(), size: info.Size()}
+		// при -k ссылки в файле заменены, размер сравниваем с оригиналом
+		if orig, err := os.Stat(localPath + origSuffix); err == nil {
+			local.size = orig.Size()
+		}
+		return local, true
+	}
+
+	return localCopy{}, false
+}
+
+// download скачивает ресурс задачи на диск с повторами и докачкой
+func (l *Loader) download(task Task, workerID int) (downloadResult, error) {
+
+	fileURL, err := url.Parse(task.URL)
+	if err != nil {
+		return downloadResult{}, fmt.Errorf("неверный URL: %w", err)
+	}
+
+	partPath, err := filesystem.PartialPath(l.opts.Dir, fileURL)
+	if err != nil {
+		return downloadResult{}, err
+	}
+
+	// сравниваем с локальной копией, если файл не недокачан: записанной в манифесте,
+	// а при -N - просто лежащей на месте
+	var existing *localCopy
+	if _, err := os.Stat(partPath); err != nil {
+		local, ok := l.manifestCopy(task.URL)
+		if !ok && l.opts.Timestamping {
+			local, ok = l.findLocalCopy(fileURL)
+		}
+		if ok {
+			existing = &local
+		}
+	}
+
+	// ограничиваем размер загружаемого файла
+	var maxSize int64
+	switch task.Type {
+	case "html", "css", "js":
+		maxSize = 10 * 1024 * 1024 // 10 МБ
+	default:
+		maxSize = 100 * 1024 * 1024 // 100 МБ
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		if attempt > 0 {
+			delay := l.backoff(attempt, err)
+			fmt.Printf("[воркер %d] повтор %d/%d через %v: %s (%v)\n",
+				workerID, attempt, l.opts.MaxRetries, delay.Round(time.Millisecond), task.URL, err)
+
+			if err := l.sleep(delay); err != nil {
+				return downloadResult{}, err
+			}
+		}
+
+		var result downloadResult
+		result, err = l.downloadOnce(fileURL, partPath, maxSize, existing)
+		if err == nil {
+			return result, nil
+		}
+
+		if l.ctx.Err() != nil {
+			return downloadResult{}, l.ctx.Err()
+		}
+		if !isRetryable(err) || attempt >= l.opts.MaxRetries {
+			return downloadResult{}, err
+		}
+	}
+}
+
+// downloadOnce выполняет одну попытку загрузки, продолжая ранее скачанную часть через Range.
+// existing - локальная копия из манифеста или для -N (nil, если сравнивать не с чем)
+func (l *Loader) downloadOnce(fileURL *url.URL, partPath string, maxSize int64, existing *localCopy) (downloadResult, error) {
+
+	offset, meta, err := filesystem.LoadPartial(partPath, fileURL.String())
+	if err != nil {
+		return downloadResult{}, err
+	}
+
+	// без валидатора нельзя убедиться, что на сервере тот же файл - качаем сначала
+	validator := meta.Validator()
+	if validator == "" {
+		offset = 0
+	}
+
+	if err := l.limiter.Wait(l.ctx, fileURL.Host); err != nil {
+		return downloadResult{}, err
+	}
+
+	req, err := http.NewRequestWithContext(l.ctx, "GET", fileURL.String(), nil)
+	if err != nil {
+		return downloadResult{}, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("User-Agent", l.opts.UserAgent)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	} else if existing != nil && existing.entry != nil {
+		// валидаторы, которые сервер прислал в прошлый раз
+		if existing.entry.ETag != "" {
+			req.Header.Set("If-None-Match", existing.entry.ETag)
+		}
+		if existing.entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", existing.entry.LastModified)
+		}
+	} else if existing != nil {
+		req.Header.Set("If-Modified-Since", existing.modTime.UTC().Format(http.TimeFormat))
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		// сетевые ошибки повторяем
+		return downloadResult{}, &retryableError{err}
+	}
+	defer resp.Body.Close()
+
+	notModified := downloadResult{notModified: true}
+	if existing != nil {
+		notModified.localPath = existing.path
+		notModified.mimeType = mimeByPath(existing.path)
+	}
+	if existing != nil && existing.entry != nil {
+		notModified.mimeType = existing.entry.MIME
+		notModified.etag = cmp.Or(resp.Header.Get("ETag"), existing.entry.ETag)
+		notModified.lastModified = cmp.Or(resp.Header.Get("Last-Modified"), existing.entry.LastModified)
+		notModified.hash = existing.entry.SHA256
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// сервер прислал файл целиком (не поддерживает Range или файл изменился)
+		offset = 0
+
+		// сервер мог проигнорировать If-Modified-Since - сравниваем сами, как wget
+		// (копию из манифеста сравним по хешу, когда скачаем)
+		if existing != nil && existing.entry == nil && !isNewer(resp, existing) {
+			return notModified, nil
+		}
+
+	case http.StatusNotModified:
+		if existing == nil {
+			return downloadResult{}, &statusError{code: resp.StatusCode, status: resp.Status}
+		}
+		return notModified, nil
+
+	case http.StatusPartialContent:
+		start, ok := parseContentRangeStart(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			// сервер вернул не тот диапазон - выбрасываем часть и начинаем сначала
+			if err := filesystem.RemovePartial(partPath); err != nil {
+				return downloadResult{}, err
+			}
+			return downloadResult{}, &retryableError{fmt.Errorf("неожиданный Content-Range: %q", resp.Header.Get("Content-Range"))}
+		}
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		// часть не соответствует файлу на сервере - начинаем сначала
+		if err := filesystem.RemovePartial(partPath); err != nil {
+			return downloadResult{}, err
+		}
+		return downloadResult{}, &retryableError{fmt.Errorf("статус %s", resp.Status)}
+
+	default:
+		return downloadResult{}, &statusError{
+			code:       resp.StatusCode,
+			status:     resp.Status,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	// проверяем заявленный размер
+	if resp.ContentLength > 0 && offset+resp.ContentLength > maxSize {
+		return downloadResult{}, fmt.Errorf("файл слишком большой: %d байт", offset+resp.ContentLength)
+	}
+
+	var file io.WriteCloser
+	if offset == 0 {
+		meta = filesystem.PartialMeta{
+			URL:          fileURL.String(),
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		}
+		file, err = filesystem.CreatePartial(partPath, meta)
+	} else {
+		file, err = filesystem.AppendPartial(partPath)
+	}
+	if err != nil {
+		return downloadResult{}, err
+	}
+
+	// пишем тело прямо на диск, чтобы при обрыве уже скачанное сохранилось
+	written, copyErr := io.Copy(file, io.LimitReader(resp.Body, maxSize-offset+1))
+	closeErr := file.Close()
+	if copyErr != nil {
+		return downloadResult{}, &retryableError{fmt.Errorf("ошибка чтения тела: %w", copyErr)}
+	}
+	if closeErr != nil {
+		return downloadResult{}, fmt.Errorf("ошибка записи: %w", closeErr)
+	}
+	if offset+written > maxSize {
+		filesystem.RemovePartial(partPath)
+		return downloadResult{}, fmt.Errorf("файл слишком большой: больше %d байт", maxSize)
+	}
+
+	hash, err := hashFile(partPath)
+	if err != nil {
+		return downloadResult{}, fmt.Errorf("ошибка чтения %s: %w", partPath, err)
+	}
+
+	// сервер не поддерживает условные запросы, но содержимое то же - файл не трогаем
+	if existing != nil && existing.entry != nil && existing.entry.SHA256 == hash {
+		if err := filesystem.RemovePartial(partPath); err != nil {
+			return downloadResult{}, err
+		}
+		return notModified, nil
+	}
+
+	// определяем Content-Type
+	contentType := resp.Header.Get("Content-Type")
+	mimeType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mimeType = contentType
+	}
+
+	localPath, err := filesystem.GetLocalPath(fileURL, mimeType)
+	if err != nil {
+		return downloadResult{}, err
+	}
+	localPath = filepath.Join(l.opts.Dir, localPath)
+	if err := filesystem.CommitPartial(partPath, localPath); err != nil {
+		return downloadResult{}, err
+	}
+
+	// время модификации файла берём с сервера - по нему -N решает, качать ли файл снова
+	if modTime, err := http.ParseTime(meta.LastModified); err == nil {
+		os.Chtimes(localPath, modTime, modTime)
+	}
+
+	return downloadResult{
+		localPath:    localPath,
+		mimeType:     mimeType,
+		etag:         meta.ETag,
+		lastModified: meta.LastModified,
+		hash:         hash,
+	}, nil
+}
+
+// isNewer сообщает, новее ли файл на сервере локальной копии (по Last-Modified и размеру, как wget -N)
+func isNewer(resp *http.Response, existing *localCopy) bool {
+
+	modTime, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		// без даты сравнить нельзя - качаем
+		return true
+	}
+	if modTime.After(existing.modTime) {
+		return true
+	}
+
+	return resp.ContentLength >= 0 && resp.ContentLength != existing.size
+}
+
+// parseContentRangeStart возвращает начало диапазона из заголовка "bytes start-end/total"
+func parseContentRangeStart(value string) (int64, bool) {
+
+	value, ok := strings.CutPrefix(value, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	startStr, _, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(startStr), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return start, true
+}
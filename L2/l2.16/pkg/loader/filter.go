@@ -0,0 +1,175 @@
+package loader
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// matchDomain проверяет, совпадает ли хост с доменом или является его поддоменом
+func matchDomain(host, domain string) bool {
+
+	host = strings.ToLower(host)
+	domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+	if domain == "" {
+		return false
+	}
+
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// isAllowedHost проверяет политику хостов: стартовый хост и --domains разрешены,
+// --exclude-domains запрещены в любом случае
+func (l *Loader) isAllowedHost(u *url.URL) bool {
+
+	host := u.Hostname()
+
+	for _, domain := range l.opts.ExcludeDomains {
+		if matchDomain(host, domain) {
+			return false
+		}
+	}
+
+	if host == l.baseURL.Hostname() {
+		return true
+	}
+
+	for _, domain := range l.opts.Domains {
+		if matchDomain(host, domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parentDir возвращает директорию стартового URL (для --no-parent)
+func (l *Loader) parentDir() string {
+
+	dir := l.baseURL.Path
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+		if !strings.HasSuffix(dir, "/") {
+			dir += "/"
+		}
+	}
+
+	return dir
+}
+
+// isBelowParent проверяет ограничение --no-parent: не подниматься выше директории стартового URL
+// (ограничение действует только на стартовом хосте)
+func (l *Loader) isBelowParent(u *url.URL) bool {
+
+	if !l.opts.NoParent || u.Hostname() != l.baseURL.Hostname() {
+		return true
+	}
+
+	p := u.Path
+	if p == "" {
+		p = "/"
+	}
+
+	return strings.HasPrefix(p, l.parentDir())
+}
+
+// isLocal сообщает, попадёт ли URL в зеркало (нужно для замены ссылок)
+func (l *Loader) isLocal(u *url.URL) bool {
+
+	return (u.Scheme == "http" || u.Scheme == "https") && l.isAllowedHost(u) && l.isBelowParent(u)
+}
+
+// isMIMEPattern отличает шаблон MIME-типа ("image/png", "image/*") от расширения
+func isMIMEPattern(pattern string) bool {
+	return strings.Contains(pattern, "/")
+}
+
+// matchExtension проверяет расширение пути URL по шаблону ("jpg", ".jpg", "*.jpg")
+func matchExtension(u *url.URL, pattern string) bool {
+
+	pattern = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(pattern, "*"), "."))
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+
+	return pattern != "" && ext == pattern
+}
+
+// matchMIME проверяет MIME-тип по шаблону ("image/png", "image/*")
+func matchMIME(mimeType, pattern string) bool {
+
+	mimeType = strings.ToLower(mimeType)
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mimeType, prefix+"/")
+	}
+
+	return mimeType == pattern
+}
+
+// acceptedByURL применяет --accept/--reject по расширению до загрузки.
+// Шаблоны MIME-типов на этом этапе не проверяются (тип ещё неизвестен)
+func (l *Loader) acceptedByURL(u *url.URL) bool {
+
+	for _, pattern := range l.opts.Reject {
+		if !isMIMEPattern(pattern) && matchExtension(u, pattern) {
+			return false
+		}
+	}
+
+	accept := false
+	hasExtPatterns := false
+	for _, pattern := range l.opts.Accept {
+		if isMIMEPattern(pattern) {
+			// тип узнаем только из ответа - решение откладываем
+			accept = true
+			continue
+		}
+		hasExtPatterns = true
+		if matchExtension(u, pattern) {
+			accept = true
+		}
+	}
+
+	return !hasExtPatterns || accept
+}
+
+// accepted применяет --accept/--reject к скачанному файлу с известным MIME-типом
+func (l *Loader) accepted(u *url.URL, mimeType string) bool {
+
+	for _, pattern := range l.opts.Reject {
+		if isMIMEPattern(pattern) && matchMIME(mimeType, pattern) {
+			return false
+		}
+		if !isMIMEPattern(pattern) && matchExtension(u, pattern) {
+			return false
+		}
+	}
+
+	if len(l.opts.Accept) == 0 {
+		return true
+	}
+
+	for _, pattern := range l.opts.Accept {
+		if isMIMEPattern(pattern) && matchMIME(mimeType, pattern) {
+			return true
+		}
+		if !isMIMEPattern(pattern) && matchExtension(u, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mimeByPath определяет MIME-тип уже сохранённого файла по расширению
+func mimeByPath(localPath string) string {
+
+	mimeType, _, err := mime.ParseMediaType(mime.TypeByExtension(filepath.Ext(localPath)))
+	if err != nil {
+		return ""
+	}
+
+	return mimeType
+}
@@ -3,11 +3,11 @@ package loader
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"mime"
 	"net/http"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -16,14 +16,70 @@ import (
 	"mywget/pkg/filesystem"
 	"mywget/pkg/linkprocessor"
 	"mywget/pkg/parser"
-	"mywget/pkg/robots"
+	"mywget/pkg/ratelimit"
+	"mywget/pkg/warc"
 )
 
+// defaultUserAgent - имя, под которым загрузчик представляется серверу и robots.txt
+const defaultUserAgent = "mywget-bot"
+
+// stateSaveInterval - как часто состояние обхода сбрасывается на диск
+const stateSaveInterval = 5 * time.Second
+
 // task - задача для загрузки
 type Task struct {
-	URL   string
-	Depth int
-	Type  string // "html", "css", "js", "image", "font"
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+	Type  string `json:"type"` // "html", "css", "js", "image", "font"
+}
+
+// Options - настройки загрузки, обхода и сохранения
+type Options struct {
+	// загрузка
+	Workers       int           // количество параллельных воркеров
+	Timeout       time.Duration // таймаут одного запроса (-T)
+	UserAgent     string        // заголовок User-Agent, он же имя для robots.txt (-U)
+	MaxRetries    int           // сколько раз повторять запрос после 5xx/429 и сетевых ошибок
+	BackoffBase   time.Duration // пауза перед первым повтором (дальше удваивается)
+	BackoffMax    time.Duration // максимальная пауза между повторами
+	RateLimit     float64       // общий лимит запросов в секунду (0 - без ограничений)
+	HostRateLimit float64       // лимит запросов в секунду на один хост (0 - без ограничений)
+	Wait          time.Duration // пауза между запросами (--wait)
+	RandomWait    bool          // случайная пауза 0.5..1.5 Wait (--random-wait)
+	MaxCrawlDelay time.Duration // наибольшая соблюдаемая пауза Crawl-delay из robots.txt (0 - не соблюдать)
+
+	// обход
+	NoParent       bool     // не подниматься выше директории стартового URL (-np)
+	Domains        []string // дополнительные разрешённые домены (--domains)
+	ExcludeDomains []string // запрещённые домены (--exclude-domains)
+	Accept         []string // сохранять только эти расширения или MIME-типы (--accept)
+	Reject         []string // не сохранять эти расширения или MIME-типы (--reject)
+	Sitemaps       bool     // добавить в обход адреса из карт сайта, указанных в robots.txt (--sitemaps)
+
+	// сохранение
+	Dir          string // директория зеркала (-P), "" - текущая
+	ConvertLinks bool   // заменять ссылки на локальные (-k)
+	Timestamping bool   // не качать файлы, которые не новее локальных (-N)
+	StateFile    string // файл состояния обхода ("" - <dir>/<хост>/.mywget-state.json, "-" - не сохранять)
+	ManifestFile string // манифест зеркала для повторных обходов ("" - <dir>/<хост>/.mywget-manifest.jsonl, "-" - не вести)
+	WARCFile     string // записывать HTTP-обмены в архив WARC (--warc), имя на .gz - со сжатием
+
+	// сеть
+	Proxy string // HTTP-прокси для всех запросов (--proxy), например mywget replay
+}
+
+// DefaultOptions возвращает настройки по умолчанию
+func DefaultOptions() Options {
+
+	return Options{
+		Workers:       10,
+		Timeout:       30 * time.Second,
+		UserAgent:     defaultUserAgent,
+		MaxRetries:    3,
+		BackoffBase:   500 * time.Millisecond,
+		BackoffMax:    30 * time.Second,
+		MaxCrawlDelay: 30 * time.Second,
+	}
 }
 
 // loader - структура загрузчика, управляющая процессом загрузки ресурса
@@ -35,13 +91,18 @@ type Loader struct {
 	taskChan   chan Task       // канал задач для воркеров
 	client     *http.Client    // HTTP клиент с таймаутами
 	numWorkers int             // количество обработчиков задач
-	robot      *robots.Robot   // парсер robots.txt
+	robots     *sync.Map       // robots.txt по хостам: "схема://хост" -> *hostRobots
 	wg         *sync.WaitGroup // для воркеров
 	taskWg     *sync.WaitGroup // для отслеживания выполнения задач
+
+	opts     Options                // повторы, лимиты, состояние
+	limiter  *ratelimit.HostLimiter // ограничитель частоты запросов
+	state    *crawlState            // состояние обхода для продолжения после прерывания
+	manifest *manifest              // скачанные ресурсы с валидаторами для повторных обходов
 }
 
 // load запускает процесс загрузки сайта
-func Load(ctx context.Context, startURL string, depth int) error {
+func Load(ctx context.Context, startURL string, depth int, opts Options) error {
 
 	// 1. валидируем и парсим URL
 	baseURL, err := url.Parse(startURL)
@@ -57,6 +118,26 @@ func Load(ctx context.Context, startURL string, depth int) error {
 		return fmt.Errorf("глубина не может быть отрицательной")
 	}
 
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = defaultUserAgent
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("неверный адрес прокси: %q", opts.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
 	// 2. создаем экземпляр загрузчика
 	loader := &Loader{
 		ctx:      ctx,
@@ -65,55 +146,83 @@ func Load(ctx context.Context, startURL string, depth int) error {
 		visited:  &sync.Map{},
 		taskChan: make(chan Task, 1000),
 		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 100,
-				IdleConnTimeout:     90 * time.Second,
-			},
+			Timeout:   opts.Timeout,
+			Transport: transport,
 		},
-		numWorkers: 10,
-		robot:      robots.New(),
+		numWorkers: opts.Workers,
+		robots:     &sync.Map{},
 		wg:         &sync.WaitGroup{},
 		taskWg:     &sync.WaitGroup{},
+		opts:       opts,
+		limiter:    ratelimit.NewHostLimiter(opts.RateLimit, opts.HostRateLimit),
 	}
+	loader.limiter.SetPause(opts.Wait, opts.RandomWait)
 
-	// 3. загружаем robots.txt
-	robotsURL := fmt.Sprintf("%s://%s/robots.txt", baseURL.Scheme, baseURL.Hostname())
-	if err := loader.loadRobotsTxt(robotsURL); err != nil {
-		fmt.Printf("предупреждение: не удалось загрузить robots.txt: %v\n", err)
+	// 3. поднимаем состояние прерванного обхода, если оно есть
+	statePath := opts.StateFile
+	switch statePath {
+	case "":
+		statePath = filepath.Join(opts.Dir, baseURL.Hostname(), ".mywget-state.json")
+	case "-":
+		statePath = ""
 	}
-
-	// 4. запускаем загрузчик
-	return loader.run()
-}
-
-// loadRobotsTxt загружает и парсит robots.txt
-func (l *Loader) loadRobotsTxt(robotsURL string) error {
-
-	req, err := http.NewRequestWithContext(l.ctx, "GET", robotsURL, nil)
+	state, resumed, err := loadCrawlState(statePath, baseURL.String(), depth)
 	if err != nil {
 		return err
 	}
+	loader.state = state
+	if resumed {
+		fmt.Printf("продолжаем прерванную загрузку: обработано %d, в очереди %d\n",
+			len(state.done), len(state.pending))
+	}
 
-	resp, err := l.client.Do(req)
+	// 4. читаем манифест зеркала: по нему повторный обход качает только изменившееся
+	manifestPath := opts.ManifestFile
+	switch manifestPath {
+	case "":
+		manifestPath = filepath.Join(opts.Dir, baseURL.Hostname(), ".mywget-manifest.jsonl")
+	case "-":
+		manifestPath = ""
+	}
+	loader.manifest, err = loadManifest(manifestPath, opts.Dir, baseURL.String(), depth)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("robots.txt недоступен: статус %d", resp.StatusCode)
+	// 5. открываем архив WARC: все запросы, включая robots.txt и карты сайта, пишутся в него
+	var archive *warc.Writer
+	if opts.WARCFile != "" {
+		archive, err = warc.Create(opts.WARCFile, warc.Header{
+			{Name: "software", Value: "mywget"},
+			{Name: "format", Value: "WARC File Format 1.1"},
+			{Name: "conformsTo", Value: "https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/"},
+			{Name: "robots", Value: "obey"},
+			{Name: "http-header-user-agent", Value: opts.UserAgent},
+			{Name: "description", Value: "mywget " + baseURL.String()},
+		})
+		if err != nil {
+			return fmt.Errorf("не удалось открыть архив WARC: %w", err)
+		}
+		loader.client.Transport = warc.NewRecorder(archive, loader.client.Transport)
 	}
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024)) // 64KB максимум
-	if err != nil {
-		return err
+	// 6. загружаем robots.txt стартового хоста (остальных - при первом обращении к ним)
+	loader.robotsFor(baseURL)
+
+	// 7. запускаем загрузчик
+	err = loader.run()
+
+	if cerr := loader.manifest.close(); cerr != nil && err == nil {
+		err = cerr
 	}
 
-	l.robot.Parse(string(body))
+	if archive != nil {
+		if cerr := archive.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("ошибка записи WARC: %w", cerr)
+		}
+	}
 
-	return nil
+	return err
 }
 
 // run запускает основной цикл загрузки
@@ -121,45 +230,143 @@ func (l *Loader) run() error {
 
 	fmt.Printf("начинаем загрузку %s (глубина: %d)\n", l.baseURL.String(), l.maxDepth)
 
+	// уже обработанные в прошлый раз URL повторно не качаем
+	for _, u := range l.state.doneURLs() {
+		l.visited.Store(u, true)
+	}
+
+	// стартовые задачи: очередь прерванного обхода или начальная страница
+	// (и при --sitemaps адреса из карт сайта - они попадут в очередь состояния)
+	tasks := l.state.pendingTasks()
+	fresh := len(tasks) == 0 && len(l.state.doneURLs()) == 0
+	if fresh {
+		tasks = []Task{{URL: l.baseURL.String(), Depth: 0, Type: "html"}}
+	}
+	seedSitemaps := fresh && l.opts.Sitemaps
+
+	// учитываем стартовые задачи до запуска ожидающей горутины, иначе она может закрыть канал раньше времени;
+	// разбор карт сайта тоже считается задачей, пока из них добавляются адреса
+	l.taskWg.Add(len(tasks))
+	if seedSitemaps {
+		l.taskWg.Add(1)
+	}
+	for _, task := range tasks {
+		l.state.addPending(task)
+	}
+
 	// запускаем воркеры для параллельной загрузки
 	for i := 0; i < l.numWorkers; i++ {
 		l.wg.Add(1)
 		go l.worker(i + 1)
 	}
 
+	// периодически сбрасываем состояние на диск
+	stopSaver := make(chan struct{})
+	saverDone := make(chan struct{})
+	go l.saveStatePeriodically(stopSaver, saverDone)
+
 	// ждём завершения всех задач и закрываем канал
 	go func() {
 		l.taskWg.Wait()
 		close(l.taskChan)
 	}()
 
-	// добавляем начальную страницу в канал задач
-	l.taskWg.Add(1)
-	select {
-	case l.taskChan <- Task{
-		URL:   l.baseURL.String(),
-		Depth: 0,
-		Type:  "html"}:
-	case <-l.ctx.Done():
-		l.taskWg.Done()
-		close(l.taskChan)
-		l.wg.Wait()
-		return fmt.Errorf("загрузка прервана: %w", l.ctx.Err())
-	}
+	// добавляем стартовые задачи в канал (в отдельной горутине - их может быть больше буфера)
+	go func() {
+		if seedSitemaps {
+			defer l.taskWg.Done()
+		}
+		for i, task := range tasks {
+			select {
+			case l.taskChan <- task:
+			case <-l.ctx.Done():
+				// задачи, которые не успели отправить, всё равно нужно снять со счётчика
+				for range tasks[i:] {
+					l.taskWg.Done()
+				}
+				return
+			}
+		}
+		if seedSitemaps {
+			l.seedFromSitemaps()
+		}
+	}()
 
 	// ждем завершения всех воркеров
 	l.wg.Wait()
 
+	close(stopSaver)
+	<-saverDone
+
 	// проверяем, не был ли отменен контекст
 	if l.ctx.Err() != nil {
+		l.printSummary()
+		if err := l.state.save(); err != nil {
+			fmt.Printf("предупреждение: не удалось сохранить состояние: %v\n", err)
+		} else if l.state.path != "" {
+			fmt.Printf("состояние сохранено в %s, повторный запуск продолжит загрузку\n", l.state.path)
+		}
 		return fmt.Errorf("загрузка прервана: %w", l.ctx.Err())
 	}
 
+	l.sweepManifest()
+
+	if err := l.state.remove(); err != nil {
+		fmt.Printf("предупреждение: не удалось удалить файл состояния: %v\n", err)
+	}
+
 	fmt.Println("загрузка завершена")
+	l.printSummary()
 
 	return nil
 }
 
+// sweepManifest удаляет из зеркала ресурсы, до которых обход с теми же URL и глубиной
+// больше не дошёл. После ошибок загрузки ничего не удаляется: обход мог не дойти
+// до страниц из-за сбоя, а не потому, что они исчезли
+func (l *Loader) sweepManifest() {
+
+	if !l.manifest.sameCrawl {
+		return
+	}
+	if l.state.hasFailed() {
+		fmt.Println("были ошибки загрузки, ненайденные страницы не удаляются")
+		return
+	}
+
+	for _, localPath := range l.manifest.sweep(l.state.started) {
+		fmt.Printf("удалён: %s (больше не найден при обходе)\n", localPath)
+	}
+}
+
+// printSummary печатает итоги обхода по файлам
+func (l *Loader) printSummary() {
+
+	stats := l.manifest.summary()
+	fmt.Printf("файлы: добавлено %d, изменено %d, удалено %d, без изменений %d\n",
+		stats.added, stats.changed, stats.removed, stats.unchanged)
+}
+
+// saveStatePeriodically сбрасывает состояние обхода на диск, пока не закрыт stop
+func (l *Loader) saveStatePeriodically(stop <-chan struct{}, done chan<- struct{}) {
+
+	defer close(done)
+
+	ticker := time.NewTicker(stateSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := l.state.save(); err != nil {
+				fmt.Printf("предупреждение: не удалось сохранить состояние: %v\n", err)
+			}
+		}
+	}
+}
+
 // worker обрабатывает задачи из канала
 func (l *Loader) worker(id int) {
 
@@ -168,6 +375,8 @@ func (l *Loader) worker(id int) {
 	for {
 		select {
 		case <-l.ctx.Done():
+			// снимаем со счётчика оставшиеся в канале задачи - они останутся в сохранённой очереди
+			l.drainTasks()
 			return
 		case task, ok := <-l.taskChan:
 			if !ok {
@@ -178,17 +387,40 @@ func (l *Loader) worker(id int) {
 	}
 }
 
+// drainTasks забирает из канала задачи, которые уже не будут выполнены
+func (l *Loader) drainTasks() {
+
+	for {
+		select {
+		case _, ok := <-l.taskChan:
+			if !ok {
+				return
+			}
+			l.taskWg.Done()
+		default:
+			return
+		}
+	}
+}
+
 // processTask обрабатывает одну задачу загрузки
 func (l *Loader) processTask(task Task, workerID int) {
 
 	defer l.taskWg.Done() // уменьшаем счетчик задач при завершении
 
-	// проверяем, не посещали ли уже этот URL
-	if _, visited := l.visited.Load(task.URL); visited {
+	// проверяем, не посещали ли уже этот URL (и сразу помечаем его, чтобы не качать дважды)
+	if _, visited := l.visited.LoadOrStore(task.URL, true); visited {
 		fmt.Printf("[воркер %d] пропускаем уже посещенный URL: %s\n", workerID, task.URL)
 		return
 	}
 
+	// задача, прерванная отменой, остаётся в очереди сохранённого состояния
+	defer func() {
+		if l.ctx.Err() == nil {
+			l.state.complete(task.URL)
+		}
+	}()
+
 	// проверяем глубину
 	if task.Depth > l.maxDepth {
 		fmt.Printf("[воркер %d] пропускаем, превышена глубина: %s (глубина %d)\n",
@@ -196,119 +428,162 @@ func (l *Loader) processTask(task Task, workerID int) {
 		return
 	}
 
-	// проверяем, что URL принадлежит тому же домену
-	if !l.isSameDomain(task.URL) {
-		fmt.Printf("[воркер %d] пропускаем внешний домен: %s\n", workerID, task.URL)
-		return
-	}
-
-	// проверяем robots.txt
 	parsedURL, err := url.Parse(task.URL)
 	if err != nil {
 		fmt.Printf("[воркер %d] ошибка парсинга URL: %v\n", workerID, err)
 		return
 	}
 
-	if !l.robot.IsAllowed("mywget-bot", parsedURL) {
-		fmt.Printf("[воркер %d] заблокировано robots.txt: %s\n", workerID, task.URL)
+	// проверяем политику хостов и --no-parent (стартовый URL качаем всегда)
+	if task.URL != l.baseURL.String() && !l.isLocal(parsedURL) {
+		fmt.Printf("[воркер %d] пропускаем URL вне зеркала: %s\n", workerID, task.URL)
 		return
 	}
 
-	// помечаем URL как посещенный
-	l.visited.Store(task.URL, true)
-
-	fmt.Printf("[воркер %d] начинаю загрузку: %s (тип: %s, глубина: %d)\n",
-		workerID, task.URL, task.Type, task.Depth)
-
-	// выполняем запрос
-	req, err := http.NewRequestWithContext(l.ctx, "GET", task.URL, nil)
-	if err != nil {
-		fmt.Printf("[воркер %d] ошибка создания запроса: %v\n", workerID, err)
+	// ресурсы, отклонённые по расширению, даже не качаем (страницы качаем ради ссылок)
+	if task.Type != "html" && !l.acceptedByURL(parsedURL) {
+		fmt.Printf("[воркер %d] пропускаем по --accept/--reject: %s\n", workerID, task.URL)
 		return
 	}
 
-	resp, err := l.client.Do(req)
-	if err != nil {
-		fmt.Printf("[воркер %d] ошибка загрузки: %v\n", workerID, err)
+	// проверяем robots.txt
+	if !l.robotsFor(parsedURL).IsAllowed(l.opts.UserAgent, parsedURL) {
+		fmt.Printf("[воркер %d] заблокировано robots.txt: %s\n", workerID, task.URL)
 		return
 	}
-	defer resp.Body.Close()
 
-	// проверяем статус
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[воркер %d] пропускаем, статус: %s\n", workerID, resp.Status)
+	fmt.Printf("[воркер %d] начинаю загрузку: %s (тип: %s, глубина: %d)\n",
+		workerID, task.URL, task.Type, task.Depth)
+
+	// скачиваем на диск с повторами и докачкой
+	result, err := l.download(task, workerID)
+	if err != nil {
+		if l.ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("[воркер %d] ошибка загрузки %s: %v\n", workerID, task.URL, err)
+
+		// ресурс исчез с сервера - убираем его и из зеркала
+		var se *statusError
+		if errors.As(err, &se) && (se.code == http.StatusNotFound || se.code == http.StatusGone) {
+			if localPath, ok := l.manifest.drop(task.URL); ok {
+				fmt.Printf("[воркер %d] удалён исчезнувший: %s\n", workerID, localPath)
+			}
+			return
+		}
+		l.state.markFailed()
 		return
 	}
+	localPath, mimeType := result.localPath, result.mimeType
 
-	// ограничиваем размер загружаемого файла
-	var maxSize int64
-	switch task.Type {
-	case "html", "css", "js":
-		maxSize = 10 * 1024 * 1024 // 10 МБ
-	default:
-		maxSize = 100 * 1024 * 1024 // 100 МБ
+	if result.notModified {
+		fmt.Printf("[воркер %d] не изменился: %s -> %s\n", workerID, task.URL, localPath)
+	} else {
+		fmt.Printf("[воркер %d] сохранен: %s -> %s\n", workerID, task.URL, localPath)
 	}
 
-	// читаем тело с ограничением
-	limitedBody := io.LimitReader(resp.Body, maxSize)
-	bodyBytes, err := io.ReadAll(limitedBody)
-	if err != nil {
-		fmt.Printf("[воркер %d] ошибка чтения тела: %v\n", workerID, err)
-		return
+	// файлы, не прошедшие --accept/--reject, удаляем (страницы - после извлечения ссылок)
+	keep := l.accepted(parsedURL, mimeType)
+	if keep {
+		l.manifest.record(task.URL, localPath, result)
+	} else {
+		l.manifest.forget(task.URL)
+		defer func() {
+			if err := os.Remove(localPath); err == nil {
+				fmt.Printf("[воркер %d] удалён по --accept/--reject: %s\n", workerID, localPath)
+			}
+		}()
 	}
 
-	// также проверяем, не превышен ли лимит
-	if resp.ContentLength > maxSize {
-		fmt.Printf("[воркер %d] файл слишком большой: %d байт\n", workerID, resp.ContentLength)
+	// HTML и CSS нужно разобрать (и при -k переписать ссылки) - читаем их обратно с диска
+	if mimeType != "text/html" && mimeType != "text/css" {
 		return
 	}
 
-	// определяем Content-Type
-	contentType := resp.Header.Get("Content-Type")
-	mimeType, _, err := mime.ParseMediaType(contentType)
-	if err != nil {
-		mimeType = contentType
+	// при -N и -k ссылки в сохранённом файле уже заменены, поэтому разбираем оригинал
+	sourcePath := localPath
+	if result.notModified && l.opts.ConvertLinks {
+		if _, err := os.Stat(localPath + origSuffix); err == nil {
+			sourcePath = localPath + origSuffix
+		}
 	}
-
-	// сохраняем файл
-	bodyReader := bytes.NewReader(bodyBytes)
-	localPath, err := filesystem.SaveFile(task.URL, l.baseURL, bodyReader, mimeType)
+	bodyBytes, err := os.ReadFile(sourcePath)
 	if err != nil {
-		fmt.Printf("[воркер %d] ошибка сохранения: %v\n", workerID, err)
+		fmt.Printf("[воркер %d] ошибка чтения %s: %v\n", workerID, sourcePath, err)
 		return
 	}
 
-	fmt.Printf("[воркер %d] сохранен: %s -> %s\n", workerID, task.URL, localPath)
-
 	// обрабатываем разные типы файлов
 	switch {
 	case mimeType == "text/html":
-		l.processHTMLFile(task, bodyBytes, mimeType, workerID)
+		l.processHTMLFile(task, bodyBytes, mimeType, keep && !result.notModified, workerID)
 	case mimeType == "text/css":
-		l.processCSSFile(task, bodyBytes, mimeType, workerID)
+		l.processCSSFile(task, bodyBytes, mimeType, keep && !result.notModified, workerID)
 	}
 }
 
-// processHTMLFile обрабатывает HTML файл
-func (l *Loader) processHTMLFile(task Task, bodyBytes []byte, mimeType string, workerID int) {
+// origSuffix - суффикс копии файла до замены ссылок (при -k вместе с манифестом или -N, как wget -K)
+const origSuffix = ".orig"
 
-	// заменяем ссылки в HTML на локальные
-	pageURL, _ := url.Parse(task.URL)
-	newBodyBytes, err := linkprocessor.ReplaceLinks(bodyBytes, pageURL, l.baseURL)
+// rewriteFile сохраняет файл с заменёнными ссылками. При манифесте или -N сохраняет оригинал
+// рядом (из него повторный обход берёт ссылки, если файл не изменился) и возвращает файлу
+// время модификации с сервера
+func (l *Loader) rewriteFile(task Task, original, rewritten []byte, mimeType string) (string, error) {
+
+	u, err := url.Parse(task.URL)
 	if err != nil {
-		fmt.Printf("[воркер %d] ошибка замены ссылок: %v\n", workerID, err)
-		return
+		return "", err
+	}
+	localPath, err := filesystem.GetLocalPath(u, mimeType)
+	if err != nil {
+		return "", err
+	}
+	localPath = filepath.Join(l.opts.Dir, localPath)
+
+	info, statErr := os.Stat(localPath)
+
+	if l.opts.Timestamping || l.manifest.path != "" {
+		if err := os.WriteFile(localPath+origSuffix, original, 0644); err != nil {
+			return "", err
+		}
 	}
 
-	// перезаписываем файл с замененными ссылками
-	newBodyReader := bytes.NewReader(newBodyBytes)
-	localPath, err := filesystem.SaveFile(task.URL, l.baseURL, newBodyReader, mimeType)
+	savedPath, err := filesystem.SaveFile(l.opts.Dir, task.URL, bytes.NewReader(rewritten), mimeType)
 	if err != nil {
-		fmt.Printf("[воркер %d] ошибка перезаписи файла: %v\n", workerID, err)
-		return
+		return "", err
 	}
 
-	fmt.Printf("[воркер %d] перезаписан с замененными ссылками: %s\n", workerID, localPath)
+	// время модификации нужно -N, чтобы при следующем запуске сравнить его с сервером
+	if statErr == nil {
+		os.Chtimes(savedPath, info.ModTime(), info.ModTime())
+		os.Chtimes(savedPath+origSuffix, info.ModTime(), info.ModTime())
+	}
+
+	return savedPath, nil
+}
+
+// processHTMLFile обрабатывает HTML файл, convert - заменить в нём ссылки (при -k)
+func (l *Loader) processHTMLFile(task Task, bodyBytes []byte, mimeType string, convert bool, workerID int) {
+
+	pageURL, _ := url.Parse(task.URL)
+
+	// заменяем ссылки в HTML на локальные
+	if convert && l.opts.ConvertLinks {
+		newBodyBytes, err := linkprocessor.ReplaceLinks(bodyBytes, pageURL, l.isLocal)
+		if err != nil {
+			fmt.Printf("[воркер %d] ошибка замены ссылок: %v\n", workerID, err)
+			return
+		}
+
+		// перезаписываем файл с замененными ссылками
+		localPath, err := l.rewriteFile(task, bodyBytes, newBodyBytes, mimeType)
+		if err != nil {
+			fmt.Printf("[воркер %d] ошибка перезаписи файла: %v\n", workerID, err)
+			return
+		}
+
+		fmt.Printf("[воркер %d] перезаписан с замененными ссылками: %s\n", workerID, localPath)
+	}
 
 	// парсим и добавляем новые ссылки, если не превышена глубина
 	if task.Depth < l.maxDepth {
@@ -330,26 +605,28 @@ func (l *Loader) processHTMLFile(task Task, bodyBytes []byte, mimeType string, w
 	}
 }
 
-// processCSSFile обрабатывает CSS файл
-func (l *Loader) processCSSFile(task Task, bodyBytes []byte, mimeType string, workerID int) {
+// processCSSFile обрабатывает CSS файл, convert - заменить в нём ссылки (при -k)
+func (l *Loader) processCSSFile(task Task, bodyBytes []byte, mimeType string, convert bool, workerID int) {
 
-	// заменяем ссылки в CSS на локальные
 	pageURL, _ := url.Parse(task.URL)
-	newBodyBytes, err := linkprocessor.ReplaceCSSLinks(bodyBytes, pageURL, l.baseURL)
-	if err != nil {
-		fmt.Printf("[воркер %d] ошибка замены ссылок в CSS: %v\n", workerID, err)
-		return
-	}
 
-	// перезаписываем файл с замененными ссылками
-	newBodyReader := bytes.NewReader(newBodyBytes)
-	localPath, err := filesystem.SaveFile(task.URL, l.baseURL, newBodyReader, mimeType)
-	if err != nil {
-		fmt.Printf("[воркер %d] ошибка перезаписи CSS файла: %v\n", workerID, err)
-		return
-	}
+	// заменяем ссылки в CSS на локальные
+	if convert && l.opts.ConvertLinks {
+		newBodyBytes, err := linkprocessor.ReplaceCSSLinks(bodyBytes, pageURL, l.isLocal)
+		if err != nil {
+			fmt.Printf("[воркер %d] ошибка замены ссылок в CSS: %v\n", workerID, err)
+			return
+		}
+
+		// перезаписываем файл с замененными ссылками
+		localPath, err := l.rewriteFile(task, bodyBytes, newBodyBytes, mimeType)
+		if err != nil {
+			fmt.Printf("[воркер %d] ошибка перезаписи CSS файла: %v\n", workerID, err)
+			return
+		}
 
-	fmt.Printf("[воркер %d] перезаписан CSS с замененными ссылками: %s\n", workerID, localPath)
+		fmt.Printf("[воркер %d] перезаписан CSS с замененными ссылками: %s\n", workerID, localPath)
+	}
 
 	// извлекаем ссылки из CSS и добавляем их как задачи
 	cssLinks := parser.ExtractCSSLinks(string(bodyBytes), pageURL)
@@ -358,25 +635,34 @@ func (l *Loader) processCSSFile(task Task, bodyBytes []byte, mimeType string, wo
 	}
 }
 
-// addTask добавляет новую задачу в канал
-func (l *Loader) addTask(url string, depth int, resType string) {
+// addTask добавляет новую задачу в канал и сообщает, добавлена ли она
+func (l *Loader) addTask(rawURL string, depth int, resType string) bool {
 
-	if !l.isSameDomain(url) {
-		return
+	// хосты вне --domains и пути выше стартовой директории (при -np) не качаем
+	u, err := url.Parse(rawURL)
+	if err != nil || !l.isLocal(u) {
+		return false
 	}
 
-	if _, visited := l.visited.Load(url); visited {
-		return
+	if _, visited := l.visited.Load(rawURL); visited {
+		return false
 	}
 
-	select {
-	case l.taskChan <- Task{
-		URL:   url,
+	task := Task{
+		URL:   rawURL,
 		Depth: depth,
-		Type:  resType}:
-		l.taskWg.Add(1)
+		Type:  resType,
+	}
+	l.state.addPending(task)
+
+	// счётчик увеличиваем до отправки, иначе воркер может выполнить задачу раньше Add
+	l.taskWg.Add(1)
+	select {
+	case l.taskChan <- task:
+		return true
 	case <-l.ctx.Done():
-		return
+		l.taskWg.Done()
+		return false
 	}
 }
 
@@ -410,15 +696,3 @@ func (l *Loader) getResourceType(urlStr string) string {
 		return "unknown"
 	}
 }
-
-// isSameDomain проверяет, принадлежит ли URL тому же домену
-func (l *Loader) isSameDomain(urlStr string) bool {
-
-	u, err := url.Parse(urlStr)
-	if err != nil {
-		return false
-	}
-
-	// сравниваем домены (без учета протокола и порта)
-	return u.Hostname() == l.baseURL.Hostname()
-}
@@ -0,0 +1,1199 @@
+package loader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mywget/pkg/filesystem"
+	"mywget/pkg/ratelimit"
+	"mywget/pkg/warc"
+)
+
+// newTestLoader создаёт загрузчик для тестов, файлы пишутся во временную директорию
+func newTestLoader(t *testing.T, rawURL string, opts Options) *Loader {
+
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	baseURL, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Loader{
+		ctx:        context.Background(),
+		baseURL:    baseURL,
+		maxDepth:   1,
+		visited:    &sync.Map{},
+		taskChan:   make(chan Task, 1000),
+		client:     &http.Client{Timeout: 5 * time.Second},
+		numWorkers: 2,
+		robots:     &sync.Map{},
+		wg:         &sync.WaitGroup{},
+		taskWg:     &sync.WaitGroup{},
+		opts:       opts,
+		limiter:    ratelimit.NewHostLimiter(0, 0),
+		state:      newCrawlState("", rawURL, 1),
+		manifest:   &manifest{entries: map[string]manifestEntry{}},
+	}
+}
+
+// testOptions - быстрые повторы для тестов
+func testOptions() Options {
+
+	opts := DefaultOptions()
+	opts.BackoffBase = time.Millisecond
+	opts.BackoffMax = 5 * time.Millisecond
+	opts.StateFile = "-"
+	opts.ManifestFile = "-"
+
+	return opts
+}
+
+func TestDownloadResumesWithRange(t *testing.T) {
+
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	var gotRange, gotIfRange string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange = r.Header.Get("Range")
+		gotIfRange = r.Header.Get("If-Range")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	l := newTestLoader(t, srv.URL+"/", testOptions())
+	fileURL := srv.URL + "/file.bin"
+	u, _ := url.Parse(fileURL)
+
+	// имитируем прерванную загрузку: на диске первая половина файла
+	partPath, err := filesystem.PartialPath("", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := filesystem.CreatePartial(partPath, filesystem.PartialMeta{URL: fileURL, ETag: `"v1"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(content[:4000])
+	file.Close()
+
+	result, err := l.download(Task{URL: fileURL, Type: "unknown"}, 1)
+	if err != nil {
+		t.Fatalf("download() error = %v", err)
+	}
+
+	if gotRange != "bytes=4000-" || gotIfRange != `"v1"` {
+		t.Errorf("request headers Range=%q If-Range=%q", gotRange, gotIfRange)
+	}
+
+	data, err := os.ReadFile(result.localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Errorf("resumed file differs: got %d bytes, want %d", len(data), len(content))
+	}
+	if _, err := os.Stat(partPath); !os.IsNotExist(err) {
+		t.Errorf("partial file was not removed: %v", err)
+	}
+}
+
+func TestDownloadRestartsWhenFileChanged(t *testing.T) {
+
+	content := []byte("new content of the file")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ETag изменился, поэтому If-Range не совпадёт и сервер отдаст файл целиком
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "file.txt", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	l := newTestLoader(t, srv.URL+"/", testOptions())
+	fileURL := srv.URL + "/file.txt"
+	u, _ := url.Parse(fileURL)
+
+	partPath, _ := filesystem.PartialPath("", u)
+	file, err := filesystem.CreatePartial(partPath, filesystem.PartialMeta{URL: fileURL, ETag: `"v1"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("old"))
+	file.Close()
+
+	result, err := l.download(Task{URL: fileURL, Type: "unknown"}, 1)
+	if err != nil {
+		t.Fatalf("download() error = %v", err)
+	}
+	data, _ := os.ReadFile(result.localPath)
+	if !bytes.Equal(data, content) {
+		t.Errorf("file = %q, want %q", data, content)
+	}
+}
+
+func TestDownloadRetriesServerErrors(t *testing.T) {
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if calls.Add(1) < 3 {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("ok"))
+		case "/missing":
+			calls.Add(1)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	l := newTestLoader(t, srv.URL+"/", testOptions())
+
+	if _, err := l.download(Task{URL: srv.URL + "/flaky", Type: "unknown"}, 1); err != nil {
+		t.Fatalf("download() error = %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("server called %d times, want 3", calls.Load())
+	}
+
+	// 4xx не повторяем
+	calls.Store(0)
+	if _, err := l.download(Task{URL: srv.URL + "/missing", Type: "unknown"}, 1); err == nil {
+		t.Error("download() expected error for 404")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("404 requested %d times, want 1", calls.Load())
+	}
+}
+
+func TestDownloadGivesUpAfterMaxRetries(t *testing.T) {
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	opts := testOptions()
+	opts.MaxRetries = 2
+	l := newTestLoader(t, srv.URL+"/", opts)
+
+	if _, err := l.download(Task{URL: srv.URL + "/x", Type: "unknown"}, 1); err == nil {
+		t.Error("download() expected error")
+	}
+	if calls.Load() != 3 {
+		t.Errorf("server called %d times, want 3 (1 + 2 retries)", calls.Load())
+	}
+}
+
+func TestDownloadResumesAfterBrokenConnection(t *testing.T) {
+
+	content := bytes.Repeat([]byte("abcdefgh"), 4096)
+	var calls atomic.Int32
+	var secondRange atomic.Value
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// первый ответ обрываем на середине
+			w.Header().Set("ETag", `"same"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		secondRange.Store(r.Header.Get("Range"))
+		w.Header().Set("ETag", `"same"`)
+		http.ServeContent(w, r, "big.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	l := newTestLoader(t, srv.URL+"/", testOptions())
+
+	result, err := l.download(Task{URL: srv.URL + "/big.bin", Type: "unknown"}, 1)
+	if err != nil {
+		t.Fatalf("download() error = %v", err)
+	}
+
+	if r, _ := secondRange.Load().(string); r != "bytes="+strconv.Itoa(len(content)/2)+"-" {
+		t.Errorf("second request Range = %q", r)
+	}
+	data, _ := os.ReadFile(result.localPath)
+	if !bytes.Equal(data, content) {
+		t.Errorf("file size %d, want %d", len(data), len(content))
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("parseRetryAfter(3) = %v", got)
+	}
+	if got := parseRetryAfter(""); got != 0 {
+		t.Errorf("parseRetryAfter(\"\") = %v", got)
+	}
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got < 50*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(date) = %v", got)
+	}
+}
+
+func TestCrawlStateRoundTrip(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	state := newCrawlState(path, "http://example.com/", 2)
+	state.addPending(Task{URL: "http://example.com/a", Depth: 2, Type: "html"})
+	state.addPending(Task{URL: "http://example.com/a", Depth: 1, Type: "html"})
+	state.addPending(Task{URL: "http://example.com/b", Depth: 1, Type: "html"})
+	state.complete("http://example.com/b")
+	if err := state.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, ok, err := loadCrawlState(path, "http://example.com/", 2)
+	if err != nil || !ok {
+		t.Fatalf("loadCrawlState() = %v, %v", ok, err)
+	}
+	tasks := loaded.pendingTasks()
+	if len(tasks) != 1 || tasks[0].URL != "http://example.com/a" || tasks[0].Depth != 1 {
+		t.Errorf("pending = %+v", tasks)
+	}
+	if done := loaded.doneURLs(); len(done) != 1 || done[0] != "http://example.com/b" {
+		t.Errorf("done = %v", done)
+	}
+
+	// состояние другого обхода не подхватываем
+	if _, ok, _ := loadCrawlState(path, "http://other.com/", 2); ok {
+		t.Error("loadCrawlState() accepted state of another crawl")
+	}
+}
+
+func TestLoadResumesFromState(t *testing.T) {
+
+	var mu sync.Mutex
+	requested := map[string]int{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/a">a</a><a href="/b">b</a>`))
+		case "/a", "/b":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<p>" + r.URL.Path + "</p>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	t.Chdir(t.TempDir())
+	base := srv.URL + "/"
+
+	// прошлый запуск успел обработать стартовую страницу и /a, в очереди осталась /b
+	statePath := "state.json"
+	data, _ := json.Marshal(stateFile{
+		BaseURL:  base,
+		MaxDepth: 1,
+		Done:     []string{base, srv.URL + "/a"},
+		Pending:  []Task{{URL: srv.URL + "/b", Depth: 1, Type: "html"}},
+	})
+	if err := os.WriteFile(statePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := testOptions()
+	opts.StateFile = statePath
+	if err := Load(context.Background(), base, 1, opts); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requested["/"] != 0 || requested["/a"] != 0 {
+		t.Errorf("already done pages requested again: %v", requested)
+	}
+	if requested["/b"] != 1 {
+		t.Errorf("pending page /b requested %d times, want 1", requested["/b"])
+	}
+
+	// после успешного завершения файл состояния удаляется
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("state file still exists: %v", err)
+	}
+}
+
+func TestLoadSavesStateOnCancel(t *testing.T) {
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/slow">slow</a>`))
+		case "/slow":
+			// страница "висит", пока тест не отменит загрузку
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	t.Chdir(t.TempDir())
+	base := srv.URL + "/"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// даём загрузчику дойти до /slow
+		for {
+			time.Sleep(10 * time.Millisecond)
+			if _, err := os.Stat(filepath.Join("127.0.0.1", "index.html")); err == nil {
+				time.Sleep(50 * time.Millisecond)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	opts := testOptions()
+	opts.StateFile = "state.json"
+	if err := Load(ctx, base, 1, opts); err == nil {
+		t.Fatal("Load() expected cancellation error")
+	}
+
+	state, ok, err := loadCrawlState("state.json", base, 1)
+	if err != nil || !ok {
+		t.Fatalf("state was not saved: %v, %v", ok, err)
+	}
+	pending := state.pendingTasks()
+	if len(pending) != 1 || !strings.HasSuffix(pending[0].URL, "/slow") {
+		t.Errorf("pending = %+v, want /slow", pending)
+	}
+	if _, ok := state.done[base]; !ok {
+		t.Errorf("done = %v, want start page", state.doneURLs())
+	}
+}
+
+// testSite - тестовый сайт из набора страниц, запоминает запросы
+type testSite struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests map[string]int
+	headers  map[string]http.Header
+}
+
+// sitePage - страница тестового сайта
+type sitePage struct {
+	contentType string
+	body        string
+}
+
+// newTestSite поднимает сайт; ссылки в body могут содержать {{host}} - адрес сервера
+func newTestSite(t *testing.T, pages map[string]sitePage) *testSite {
+
+	t.Helper()
+
+	site := &testSite{requests: map[string]int{}, headers: map[string]http.Header{}}
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	site.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		site.requests[r.URL.Path]++
+		site.headers[r.URL.Path] = r.Header.Clone()
+		site.mu.Unlock()
+
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", page.contentType)
+		body := strings.ReplaceAll(page.body, "{{host}}", site.URL)
+		http.ServeContent(w, r, "", modTime, strings.NewReader(body))
+	}))
+	t.Cleanup(site.Close)
+
+	return site
+}
+
+// count возвращает, сколько раз запрашивался путь
+func (s *testSite) count(path string) int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[path]
+}
+
+// header возвращает заголовки последнего запроса пути
+func (s *testSite) header(path string) http.Header {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.headers[path]
+}
+
+func TestLoadDirectoryPrefix(t *testing.T) {
+
+	site := newTestSite(t, map[string]sitePage{
+		"/":         {"text/html", `<a href="/page">page</a><img src="/logo.png">`},
+		"/page":     {"text/html", "<p>page</p>"},
+		"/logo.png": {"image/png", "png"},
+	})
+	t.Chdir(t.TempDir())
+
+	opts := testOptions()
+	opts.Dir = "mirror"
+	if err := Load(context.Background(), site.URL+"/", 1, opts); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for _, name := range []string{"index.html", "page.html", "logo.png"} {
+		if _, err := os.Stat(filepath.Join("mirror", "127.0.0.1", name)); err != nil {
+			t.Errorf("%s not saved under -P directory: %v", name, err)
+		}
+	}
+	if _, err := os.Stat("127.0.0.1"); !os.IsNotExist(err) {
+		t.Errorf("files written outside -P directory: %v", err)
+	}
+}
+
+func TestLoadNoParent(t *testing.T) {
+
+	site := newTestSite(t, map[string]sitePage{
+		"/docs/":      {"text/html", `<a href="/docs/intro">intro</a><a href="/blog">blog</a><a href="/">home</a>`},
+		"/docs/intro": {"text/html", "<p>intro</p>"},
+		"/blog":       {"text/html", "<p>blog</p>"},
+		"/":           {"text/html", "<p>home</p>"},
+	})
+	t.Chdir(t.TempDir())
+
+	opts := testOptions()
+	opts.NoParent = true
+	if err := Load(context.Background(), site.URL+"/docs/", 1, opts); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if site.count("/docs/intro") != 1 {
+		t.Errorf("/docs/intro requested %d times, want 1", site.count("/docs/intro"))
+	}
+	if site.count("/blog") != 0 || site.count("/") != 0 {
+		t.Errorf("pages above start directory requested: /blog=%d, /=%d", site.count("/blog"), site.count("/"))
+	}
+}
+
+func TestIsAllowedHost(t *testing.T) {
+
+	base, _ := url.Parse("http://example.com/")
+	l := &Loader{baseURL: base, opts: Options{
+		Domains:        []string{"cdn.example.net", ".static.org"},
+		ExcludeDomains: []string{"ads.cdn.example.net"},
+	}}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"http://example.com/a", true},
+		{"http://other.com/", false},
+		{"http://cdn.example.net/x.png", true},
+		{"http://img.cdn.example.net/x.png", true},
+		{"http://ads.cdn.example.net/x.js", false},
+		{"http://www.static.org/x.css", true},
+		{"http://notcdn.example.net/", false},
+		{"ftp://example.com/file", false},
+	}
+
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := l.isLocal(u); got != tt.want {
+			t.Errorf("isLocal(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestAccepted(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		accept       []string
+		reject       []string
+		url          string
+		mimeType     string
+		wantByURL    bool
+		wantAccepted bool
+	}{
+		{"без правил", nil, nil, "http://h/a.png", "image/png", true, true},
+		{"accept по расширению", []string{"png"}, nil, "http://h/a.png", "image/png", true, true},
+		{"accept отклоняет другое расширение", []string{"png"}, nil, "http://h/a.js", "text/javascript", false, false},
+		{"accept с точкой и звёздочкой", []string{"*.PNG"}, nil, "http://h/a.png", "image/png", true, true},
+		{"accept по MIME решается после загрузки", []string{"image/*"}, nil, "http://h/pic", "image/jpeg", true, true},
+		{"accept по MIME отклоняет", []string{"image/*"}, nil, "http://h/a.css", "text/css", true, false},
+		{"reject по расширению", nil, []string{"js"}, "http://h/a.js", "text/javascript", false, false},
+		{"reject по MIME", nil, []string{"text/css"}, "http://h/a.css", "text/css", true, false},
+		{"reject важнее accept", []string{"png"}, []string{"png"}, "http://h/a.png", "image/png", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Loader{opts: Options{Accept: tt.accept, Reject: tt.reject}}
+			u, _ := url.Parse(tt.url)
+
+			if got := l.acceptedByURL(u); got != tt.wantByURL {
+				t.Errorf("acceptedByURL() = %v, want %v", got, tt.wantByURL)
+			}
+			if got := l.accepted(u, tt.mimeType); got != tt.wantAccepted {
+				t.Errorf("accepted() = %v, want %v", got, tt.wantAccepted)
+			}
+		})
+	}
+}
+
+func TestLoadAcceptKeepsOnlyMatchingFiles(t *testing.T) {
+
+	site := newTestSite(t, map[string]sitePage{
+		"/":          {"text/html", `<a href="/page">page</a><img src="/logo.png"><script src="/app.js"></script>`},
+		"/page":      {"text/html", `<img src="/photo.png">`},
+		"/logo.png":  {"image/png", "png"},
+		"/photo.png": {"image/png", "png"},
+		"/app.js":    {"text/javascript", "js"},
+	})
+	t.Chdir(t.TempDir())
+
+	opts := testOptions()
+	opts.Accept = []string{"png"}
+	if err := Load(context.Background(), site.URL+"/", 2, opts); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// страницы качаются ради ссылок, но не сохраняются
+	if site.count("/page") != 1 {
+		t.Errorf("/page requested %d times, want 1 (pages are crawled for links)", site.count("/page"))
+	}
+	for _, name := range []string{"index.html", "page.html"} {
+		if _, err := os.Stat(filepath.Join("127.0.0.1", name)); !os.IsNotExist(err) {
+			t.Errorf("%s kept, want removed by --accept", name)
+		}
+	}
+	for _, name := range []string{"logo.png", "photo.png"} {
+		if _, err := os.Stat(filepath.Join("127.0.0.1", name)); err != nil {
+			t.Errorf("%s not saved: %v", name, err)
+		}
+	}
+	if site.count("/app.js") != 0 {
+		t.Error("/app.js downloaded, want skipped by extension before download")
+	}
+}
+
+func TestLoadUserAgent(t *testing.T) {
+
+	site := newTestSite(t, map[string]sitePage{
+		"/robots.txt": {"text/plain", "User-agent: custom-agent\nDisallow: /private\n"},
+		"/":           {"text/html", `<a href="/private">p</a><a href="/public">p</a>`},
+		"/private":    {"text/html", "secret"},
+		"/public":     {"text/html", "public"},
+	})
+	t.Chdir(t.TempDir())
+
+	opts := testOptions()
+	opts.UserAgent = "custom-agent"
+	if err := Load(context.Background(), site.URL+"/", 1, opts); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for _, path := range []string{"/robots.txt", "/", "/public"} {
+		if got := site.header(path).Get("User-Agent"); got != "custom-agent" {
+			t.Errorf("User-Agent for %s = %q, want custom-agent", path, got)
+		}
+	}
+	// правила robots.txt применяются для нашего User-Agent
+	if site.count("/private") != 0 {
+		t.Error("/private downloaded despite robots.txt rule for custom-agent")
+	}
+}
+
+func TestLoadConvertLinks(t *testing.T) {
+
+	pages := map[string]sitePage{
+		"/":     {"text/html", `<a href="{{host}}/page">page</a>`},
+		"/page": {"text/html", "<p>page</p>"},
+	}
+
+	for _, convert := range []bool{false, true} {
+		t.Run(strconv.FormatBool(convert), func(t *testing.T) {
+			site := newTestSite(t, pages)
+			t.Chdir(t.TempDir())
+
+			opts := testOptions()
+			opts.ConvertLinks = convert
+			if err := Load(context.Background(), site.URL+"/", 1, opts); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join("127.0.0.1", "index.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			hasAbsolute := strings.Contains(string(data), site.URL+"/page")
+			if convert && hasAbsolute {
+				t.Errorf("-k: link not converted: %s", data)
+			}
+			if !convert && !hasAbsolute {
+				t.Errorf("without -k page was rewritten: %s", data)
+			}
+		})
+	}
+}
+
+func TestLoadTimestamping(t *testing.T) {
+
+	site := newTestSite(t, map[string]sitePage{
+		"/":         {"text/html", `<a href="{{host}}/page">page</a><img src="/logo.png">`},
+		"/page":     {"text/html", "<p>page</p>"},
+		"/logo.png": {"image/png", "png"},
+	})
+	t.Chdir(t.TempDir())
+
+	opts := testOptions()
+	opts.Timestamping = true
+	opts.ConvertLinks = true
+
+	// первый запуск качает всё
+	if err := Load(context.Background(), site.URL+"/", 1, opts); err != nil {
+		t.Fatalf("first Load() error = %v", err)
+	}
+	logoPath := filepath.Join("127.0.0.1", "logo.png")
+	info, err := os.Stat(logoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !info.ModTime().Equal(want) {
+		t.Errorf("mtime = %v, want Last-Modified %v", info.ModTime(), want)
+	}
+	converted, _ := os.ReadFile(filepath.Join("127.0.0.1", "index.html"))
+
+	// второй запуск: сервер отвечает 304, файлы не перезаписываются,
+	// а ссылки берутся из оригинала страницы
+	if err := Load(context.Background(), site.URL+"/", 1, opts); err != nil {
+		t.Fatalf("second Load() error = %v", err)
+	}
+	if site.header("/logo.png").Get("If-Modified-Since") == "" {
+		t.Error("second run sent no If-Modified-Since")
+	}
+	if site.count("/page") != 2 {
+		t.Errorf("/page requested %d times, want 2 (links must be read from the original)", site.count("/page"))
+	}
+	after, _ := os.ReadFile(filepath.Join("127.0.0.1", "index.html"))
+	if !bytes.Equal(converted, after) {
+		t.Errorf("index.html changed on 304:\n%s\n%s", converted, after)
+	}
+}
+
+func TestLoadWait(t *testing.T) {
+
+	site := newTestSite(t, map[string]sitePage{
+		"/":  {"text/html", `<a href="/a">a</a><a href="/b">b</a>`},
+		"/a": {"text/html", "a"},
+		"/b": {"text/html", "b"},
+	})
+	t.Chdir(t.TempDir())
+
+	opts := testOptions()
+	opts.Wait = 40 * time.Millisecond
+
+	start := time.Now()
+	if err := Load(context.Background(), site.URL+"/", 1, opts); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// robots.txt не считается, три страницы - минимум две паузы
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("3 requests with --wait 40ms took %v", elapsed)
+	}
+}
+
+func TestLoadRobotsRules(t *testing.T) {
+
+	site := newTestSite(t, map[string]sitePage{
+		"/robots.txt": {"text/plain", "User-agent: *\nDisallow: /private\nAllow: /private/open\nDisallow: /*.pdf$\n\n" +
+			"User-agent: otherbot\nDisallow: /\n"},
+		"/": {"text/html", `<a href="/private/secret">s</a><a href="/private/open">o</a>` +
+			`<a href="/doc.pdf">pdf</a><a href="/doc.pdf?v=2">pdf</a><a href="/public">p</a>`},
+		"/private/secret": {"text/html", "secret"},
+		"/private/open":   {"text/html", "open"},
+		"/doc.pdf":        {"application/pdf", "pdf"},
+		"/public":         {"text/html", "public"},
+	})
+	t.Chdir(t.TempDir())
+
+	// группа otherbot к mywget-bot не относится, действует группа "*"
+	if err := Load(context.Background(), site.URL+"/", 1, testOptions()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// /doc.pdf запрашивается один раз - с параметром: правило с $ запрещает ровно /doc.pdf
+	want := map[string]int{"/private/secret": 0, "/private/open": 1, "/doc.pdf": 1, "/public": 1}
+	for path, n := range want {
+		if got := site.count(path); got != n {
+			t.Errorf("%s requested %d times, want %d", path, got, n)
+		}
+	}
+}
+
+func TestLoadRobotsUnavailable(t *testing.T) {
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/robots.txt" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("page"))
+	}))
+	defer srv.Close()
+	t.Chdir(t.TempDir())
+
+	opts := testOptions()
+	opts.MaxRetries = 1
+	if err := Load(context.Background(), srv.URL+"/", 1, opts); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// по RFC 9309 ошибка сервера на robots.txt запрещает весь хост: только два запроса robots.txt
+	if got := requests.Load(); got != 2 {
+		t.Errorf("server got %d requests, want 2 (robots.txt with one retry)", got)
+	}
+}
+
+func TestLoadCrawlDelay(t *testing.T) {
+
+	pages := map[string]sitePage{
+		"/robots.txt": {"text/plain", "User-agent: mywget-bot\nCrawl-delay: 0.05\n"},
+		"/":           {"text/html", `<a href="/a">a</a><a href="/b">b</a>`},
+		"/a":          {"text/html", "a"},
+		"/b":          {"text/html", "b"},
+	}
+
+	tests := []struct {
+		name          string
+		maxCrawlDelay time.Duration
+		min, max      time.Duration
+	}{
+		{"соблюдается", 30 * time.Second, 100 * time.Millisecond, time.Second},
+		{"ограничена --max-crawl-delay", 10 * time.Millisecond, 20 * time.Millisecond, 90 * time.Millisecond},
+		{"отключена", 0, 0, 50 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := newTestSite(t, pages)
+			t.Chdir(t.TempDir())
+
+			opts := testOptions()
+			opts.MaxCrawlDelay = tt.maxCrawlDelay
+
+			start := time.Now()
+			if err := Load(context.Background(), site.URL+"/", 1, opts); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			// после robots.txt три страницы - две паузы, сколько бы ни было воркеров
+			if elapsed := time.Since(start); elapsed < tt.min || elapsed > tt.max {
+				t.Errorf("3 pages took %v, want %v..%v", elapsed, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestLoadSitemaps(t *testing.T) {
+
+	pages := map[string]sitePage{
+		"/robots.txt":  {"text/plain", "User-agent: *\nDisallow: /hidden\n\nSitemap: {{host}}/sitemap-index.xml\n"},
+		"/":            {"text/html", `<a href="/page">page</a>`},
+		"/page":        {"text/html", "page"},
+		"/orphan":      {"text/html", "only in sitemap"},
+		"/hidden":      {"text/html", "disallowed"},
+		"/sitemap.xml": {"application/xml", `<urlset><url><loc>{{host}}/</loc></url></urlset>`},
+		"/sitemap-index.xml": {"application/xml", `<sitemapindex>
+			<sitemap><loc>{{host}}/sitemap.xml</loc></sitemap>
+			<sitemap><loc>{{host}}/pages.xml.gz</loc></sitemap>
+		</sitemapindex>`},
+	}
+	site := newTestSite(t, pages)
+
+	// сжатая карта: шаблон {{host}} внутри gzip не подставится, адреса пишем сразу
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("<urlset><url><loc>" + site.URL + "/orphan</loc></url><url><loc>" + site.URL +
+		"/hidden</loc></url><url><loc>http://elsewhere.example/</loc></url></urlset>"))
+	zw.Close()
+	pages["/pages.xml.gz"] = sitePage{"application/gzip", gz.String()}
+
+	for _, useSitemaps := range []bool{false, true} {
+		t.Run(strconv.FormatBool(useSitemaps), func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			before := site.count("/orphan")
+
+			opts := testOptions()
+			opts.Sitemaps = useSitemaps
+			// глубина 0: со стартовой страницы ссылки не берутся, адреса из карт - тоже стартовые
+			if err := Load(context.Background(), site.URL+"/", 0, opts); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			orphan := site.count("/orphan") - before
+			if useSitemaps && orphan != 1 {
+				t.Errorf("/orphan requested %d times, want 1 (it is in the gzipped sitemap)", orphan)
+			}
+			if !useSitemaps && orphan != 0 {
+				t.Errorf("/orphan requested %d times without --sitemaps", orphan)
+			}
+			if _, err := os.Stat(filepath.Join("127.0.0.1", "orphan.html")); useSitemaps && err != nil {
+				t.Errorf("orphan.html not saved: %v", err)
+			}
+		})
+	}
+
+	if site.count("/page") != 0 || site.count("/hidden") != 0 {
+		t.Errorf("/page = %d, /hidden = %d requests, want 0", site.count("/page"), site.count("/hidden"))
+	}
+	if site.count("/") != 2 {
+		t.Errorf("/ requested %d times, want once per run (sitemap duplicate skipped)", site.count("/"))
+	}
+}
+
+func TestLoadWARCReplay(t *testing.T) {
+
+	site := newTestSite(t, map[string]sitePage{
+		"/robots.txt": {"text/plain", "User-agent: *\nDisallow: /private\n"},
+		"/":           {"text/html", `<a href="/page?id=1">page</a><a href="/private">private</a><img src="/logo.png">`},
+		"/page":       {"text/html", `<link rel="stylesheet" href="/style.css"><p>page</p>`},
+		"/style.css":  {"text/css", "body { background: url(/bg.png) }"},
+		"/logo.png":   {"image/png", "png"},
+		"/bg.png":     {"image/png", "background"},
+		"/private":    {"text/html", "secret"},
+	})
+	t.Chdir(t.TempDir())
+
+	// 1. обходим сайт с записью в архив
+	opts := testOptions()
+	opts.Dir = "live"
+	opts.WARCFile = "site.warc.gz"
+	if err := Load(context.Background(), site.URL+"/", 2, opts); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	site.Close()
+
+	// 2. отдаём архив через replay и обходим его как прокси
+	f, err := os.Open("site.warc.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := warc.NewReplay(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("NewReplay() error = %v", err)
+	}
+	proxy := httptest.NewServer(replay)
+	defer proxy.Close()
+
+	opts = testOptions()
+	opts.Dir = "replayed"
+	opts.Proxy = proxy.URL
+	if err := Load(context.Background(), site.URL+"/", 2, opts); err != nil {
+		t.Fatalf("Load() through replay error = %v", err)
+	}
+
+	// 3. зеркала совпадают
+	var files []string
+	filepath.WalkDir("live", func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel("live", path)
+			files = append(files, rel)
+		}
+		return err
+	})
+	if len(files) != 5 {
+		t.Errorf("live mirror has %d files, want 5: %v", len(files), files)
+	}
+	for _, name := range files {
+		live, _ := os.ReadFile(filepath.Join("live", name))
+		replayed, err := os.ReadFile(filepath.Join("replayed", name))
+		if err != nil || !bytes.Equal(live, replayed) {
+			t.Errorf("%s differs after replay: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join("replayed", "127.0.0.1", "private.html")); !os.IsNotExist(err) {
+		t.Errorf("/private saved from replay (robots.txt not replayed): %v", err)
+	}
+}
+
+func TestLoadBadProxy(t *testing.T) {
+
+	opts := testOptions()
+	opts.Proxy = "::bad"
+	if err := Load(context.Background(), "http://example.com/", 0, opts); err == nil {
+		t.Error("Load() with bad proxy: no error")
+	}
+}
+
+// changingSite - сайт, страницы которого меняются между обходами; отвечает с ETag
+type changingSite struct {
+	*httptest.Server
+	mu      sync.Mutex
+	pages   map[string]string // путь -> тело, {{host}} - адрес сервера
+	broken  map[string]bool   // пути, которые отвечают 503
+	headers map[string]http.Header
+	status  map[string]int // последний статус ответа по пути
+}
+
+func newChangingSite(t *testing.T, pages map[string]string) *changingSite {
+
+	t.Helper()
+
+	site := &changingSite{pages: pages, broken: map[string]bool{}, headers: map[string]http.Header{}, status: map[string]int{}}
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	site.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		defer site.mu.Unlock()
+
+		site.headers[r.URL.Path] = r.Header.Clone()
+		body, ok := site.pages[r.URL.Path]
+		switch {
+		case site.broken[r.URL.Path]:
+			site.status[r.URL.Path] = http.StatusServiceUnavailable
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		case !ok:
+			site.status[r.URL.Path] = http.StatusNotFound
+			http.NotFound(w, r)
+			return
+		}
+		body = strings.ReplaceAll(body, "{{host}}", site.URL)
+
+		if strings.HasPrefix(r.URL.Path, "/noetag") {
+			// сервер без валидаторов: условные запросы игнорирует
+			site.status[r.URL.Path] = http.StatusOK
+			w.Write([]byte(body))
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		rec.Header().Set("ETag", strconv.Quote(strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte(body))), 16)))
+		http.ServeContent(rec, r, r.URL.Path, modTime, strings.NewReader(body))
+		site.status[r.URL.Path] = rec.Code
+		for name, values := range rec.Header() {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	t.Cleanup(site.Close)
+
+	return site
+}
+
+// set меняет страницу ("" - удаляет)
+func (s *changingSite) set(path, body string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if body == "" {
+		delete(s.pages, path)
+	} else {
+		s.pages[path] = body
+	}
+}
+
+// header возвращает заголовки последнего запроса пути
+func (s *changingSite) header(path string) http.Header {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.headers[path]
+}
+
+// lastStatus возвращает статус последнего ответа по пути
+func (s *changingSite) lastStatus(path string) int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.status[path]
+}
+
+func TestLoadIncremental(t *testing.T) {
+
+	site := newChangingSite(t, map[string]string{
+		"/":            `<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a><a href="/noetag.html">n</a><img src="/logo.png">`,
+		"/a":           `<p>a1</p><a href="/deep">deep</a>`,
+		"/b":           "<p>b</p>",
+		"/c":           "<p>c</p>",
+		"/deep":        "<p>deep</p>",
+		"/noetag.html": "<p>no validators</p>",
+		"/logo.png":    "png",
+	})
+	t.Chdir(t.TempDir())
+
+	opts := testOptions()
+	opts.ManifestFile = ""
+	opts.ConvertLinks = true
+	mirror := func(name string) string { return filepath.Join("127.0.0.1", name) }
+	exists := func(name string) bool {
+		_, err := os.Stat(mirror(name))
+		return err == nil
+	}
+
+	// 1. первый обход качает всё и заводит манифест
+	if err := Load(context.Background(), site.URL+"/", 2, opts); err != nil {
+		t.Fatalf("first Load() error = %v", err)
+	}
+	for _, name := range []string{"index.html", "a.html", "b.html", "c.html", "deep.html", "noetag.html", "logo.png", ".mywget-manifest.jsonl"} {
+		if !exists(name) {
+			t.Fatalf("%s not saved", name)
+		}
+	}
+
+	// 2. /a изменилась, /b исчезла (404), на /c больше нет ссылок
+	site.set("/", `<a href="/a">a</a><a href="/b">b</a><a href="/noetag.html">n</a><img src="/logo.png">`)
+	site.set("/a", `<p>a2</p><a href="/deep">deep</a>`)
+	site.set("/b", "")
+
+	if err := Load(context.Background(), site.URL+"/", 2, opts); err != nil {
+		t.Fatalf("second Load() error = %v", err)
+	}
+	if h := site.header("/logo.png"); h.Get("If-None-Match") == "" || h.Get("If-Modified-Since") == "" {
+		t.Errorf("no conditional request for logo.png: %v", h)
+	}
+	if site.lastStatus("/logo.png") != http.StatusNotModified || site.lastStatus("/deep") != http.StatusNotModified {
+		t.Errorf("unchanged resources: status %d, %d, want 304", site.lastStatus("/logo.png"), site.lastStatus("/deep"))
+	}
+	if data, _ := os.ReadFile(mirror("a.html")); !strings.Contains(string(data), "a2") {
+		t.Errorf("changed page not updated: %s", data)
+	}
+	if exists("b.html") || exists("b.html.orig") {
+		t.Error("page that returned 404 was not removed")
+	}
+	if exists("c.html") || exists("c.html.orig") {
+		t.Error("page that is no longer linked was not removed")
+	}
+	if !exists("deep.html") || !exists("noetag.html") {
+		t.Error("unchanged pages removed")
+	}
+
+	// ссылки из неизменившихся страниц берутся из оригинала, а не из заменённых
+	if data, _ := os.ReadFile(mirror("a.html")); !strings.Contains(string(data), `deep.html"`) {
+		t.Errorf("links not converted: %s", data)
+	}
+
+	// 3. /a недоступна: до /deep обход не дошёл, но после ошибки ничего не удаляется
+	site.mu.Lock()
+	site.broken["/a"] = true
+	site.mu.Unlock()
+	opts.MaxRetries = 0
+
+	if err := Load(context.Background(), site.URL+"/", 2, opts); err != nil {
+		t.Fatalf("third Load() error = %v", err)
+	}
+	if !exists("a.html") || !exists("deep.html") {
+		t.Error("pages removed after a failed crawl")
+	}
+
+	// 4. другая глубина - другой обход: недостигнутые страницы не удаляются
+	site.mu.Lock()
+	site.broken["/a"] = false
+	site.mu.Unlock()
+	if err := Load(context.Background(), site.URL+"/", 0, opts); err != nil {
+		t.Fatalf("fourth Load() error = %v", err)
+	}
+	if !exists("a.html") || !exists("deep.html") {
+		t.Error("pages removed by a crawl with another depth")
+	}
+}
+
+func TestManifestSurvivesTornWrite(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "manifest.jsonl")
+
+	m, err := loadManifest(path, "", "http://example.com/", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.record("http://example.com/a", "example.com/a.html", downloadResult{mimeType: "text/html", etag: `"1"`, hash: "aa"})
+	m.record("http://example.com/b", "example.com/b.html", downloadResult{mimeType: "text/html", hash: "bb"})
+	m.forget("http://example.com/b")
+	m.journal.Close() // падение: журнал не переписан снимком
+
+	// оборванная последняя строка
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.WriteString(`{"url":"http://example.com/c","pa`)
+	f.Close()
+
+	m, err = loadManifest(path, "", "http://example.com/", 1)
+	if err != nil {
+		t.Fatalf("loadManifest() after torn write error = %v", err)
+	}
+	defer m.close()
+
+	if !m.sameCrawl {
+		t.Error("sameCrawl = false for the same base URL and depth")
+	}
+	entry, ok := m.lookup("http://example.com/a")
+	if !ok || entry.ETag != `"1"` || entry.Path != filepath.Join("example.com", "a.html") {
+		t.Errorf("entry a = %+v, %v", entry, ok)
+	}
+	if _, ok := m.lookup("http://example.com/b"); ok {
+		t.Error("forgotten entry b restored")
+	}
+	if _, ok := m.lookup("http://example.com/c"); ok {
+		t.Error("torn entry c restored")
+	}
+
+	// после открытия файл переписан целиком: новые строки не склеиваются с оборванной
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 2 || !strings.HasSuffix(string(data), "\n") {
+		t.Errorf("manifest after load:\n%s", data)
+	}
+
+	// поврежденная строка в середине - ошибка, а не молчаливая потеря записей
+	os.WriteFile(path, []byte("{\"base_url\":\"x\"}\nnot json\n{\"url\":\"u\"}\n"), 0644)
+	if _, err := loadManifest(path, "", "x", 0); err == nil {
+		t.Error("loadManifest() with a corrupted line: no error")
+	}
+}
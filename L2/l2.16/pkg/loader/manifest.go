@@ -0,0 +1,335 @@
+package loader
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// manifestEntry - сведения о скачанном ресурсе, по которым повторный обход
+// спрашивает сервер, изменился ли он (If-None-Match / If-Modified-Since)
+type manifestEntry struct {
+	URL          string    `json:"url"`
+	Path         string    `json:"path,omitempty"` // путь от GetLocalPath - относительно директории зеркала
+	MIME         string    `json:"mime,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	SHA256       string    `json:"sha256,omitempty"`  // хеш содержимого с сервера (до замены ссылок)
+	Checked      time.Time `json:"checked,omitzero"`  // когда ресурс последний раз проверялся
+	Removed      bool      `json:"removed,omitempty"` // строка журнала: ресурс удалён из зеркала
+}
+
+// manifestHeader - первая строка манифеста: обход, которому он принадлежит
+type manifestHeader struct {
+	BaseURL  string `json:"base_url"`
+	MaxDepth int    `json:"max_depth"`
+}
+
+// manifestStats - итоги обхода по файлам
+type manifestStats struct {
+	added, changed, unchanged, removed int
+}
+
+// manifest - манифест зеркала в формате JSON Lines: заголовок и по строке на ресурс.
+// Во время обхода изменения дописываются в конец (журнал), при открытии и закрытии
+// манифест атомарно переписывается снимком. Оборванная при падении последняя строка
+// отбрасывается, поэтому прерывание теряет не больше одной записи
+type manifest struct {
+	mu        sync.Mutex
+	path      string // файл манифеста ("" - манифест не сохраняется)
+	dir       string // директория зеркала (-P)
+	header    manifestHeader
+	sameCrawl bool // манифест от обхода с теми же URL и глубиной
+	entries   map[string]manifestEntry
+	journal   *os.File
+	stats     manifestStats
+	err       error // первая ошибка записи журнала
+}
+
+// loadManifest читает манифест (если он есть) и открывает его для дописывания
+func loadManifest(path, dir, baseURL string, maxDepth int) (*manifest, error) {
+
+	m := &manifest{
+		path:    path,
+		dir:     dir,
+		header:  manifestHeader{BaseURL: baseURL, MaxDepth: maxDepth},
+		entries: make(map[string]manifestEntry),
+	}
+	if path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("чтение манифеста: %w", err)
+	}
+	if err == nil {
+		old, err := m.parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("разбор манифеста %s: %w", path, err)
+		}
+		m.sameCrawl = old == m.header
+	}
+
+	// снимок заодно убирает оборванную строку, к которой нельзя дописывать
+	if err := m.compact(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// parse применяет строки манифеста и возвращает его заголовок
+func (m *manifest) parse(data []byte) (manifestHeader, error) {
+
+	var header manifestHeader
+
+	// последняя строка без перевода строки оборвана при записи
+	if i := bytes.LastIndexByte(data, '\n'); i < len(data)-1 {
+		data = data[:i+1]
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		if n == 1 {
+			if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+				return header, fmt.Errorf("заголовок: %w", err)
+			}
+			continue
+		}
+
+		var entry manifestEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.URL == "" {
+			return header, fmt.Errorf("строка %d: неверная запись", n)
+		}
+		if entry.Removed {
+			delete(m.entries, entry.URL)
+		} else {
+			m.entries[entry.URL] = entry
+		}
+	}
+
+	return header, scanner.Err()
+}
+
+// compact атомарно переписывает манифест текущим содержимым и заново открывает журнал
+func (m *manifest) compact() error {
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.Encode(m.header)
+	for _, u := range slices.Sorted(maps.Keys(m.entries)) {
+		enc.Encode(m.entries[u])
+	}
+
+	if m.journal != nil {
+		m.journal.Close()
+		m.journal = nil
+	}
+	if err := writeFileAtomic(m.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("запись манифеста: %w", err)
+	}
+
+	journal, err := os.OpenFile(m.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("открытие манифеста: %w", err)
+	}
+	m.journal = journal
+
+	return nil
+}
+
+// append дописывает запись в журнал одним вызовом write; вызывается под m.mu
+func (m *manifest) append(entry manifestEntry) {
+
+	if m.journal == nil || m.err != nil {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err == nil {
+		_, err = m.journal.Write(append(line, '\n'))
+	}
+	if err != nil {
+		m.err = fmt.Errorf("запись манифеста: %w", err)
+	}
+}
+
+// lookup возвращает запись о ресурсе
+func (m *manifest) lookup(u string) (manifestEntry, bool) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[u]
+
+	return entry, ok
+}
+
+// record запоминает скачанный или проверенный ресурс. localPath - путь файла в зеркале,
+// notModified - сервер подтвердил, что ресурс не изменился
+func (m *manifest) record(u, localPath string, result downloadResult) {
+
+	rel, err := filepath.Rel(cmp.Or(m.dir, "."), localPath)
+	if err != nil {
+		rel = localPath
+	}
+	entry := manifestEntry{
+		URL:          u,
+		Path:         rel,
+		MIME:         result.mimeType,
+		ETag:         result.etag,
+		LastModified: result.lastModified,
+		SHA256:       result.hash,
+		Checked:      time.Now(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prev, existed := m.entries[u]
+	switch {
+	case result.notModified, existed && prev.SHA256 != "" && prev.SHA256 == entry.SHA256:
+		m.stats.unchanged++
+	case existed:
+		m.stats.changed++
+	default:
+		m.stats.added++
+	}
+
+	// сменился тип содержимого - сменился и путь, старый файл больше не нужен
+	if existed && prev.Path != "" && prev.Path != entry.Path {
+		m.removeFiles(prev.Path)
+	}
+
+	m.entries[u] = entry
+	m.append(entry)
+}
+
+// forget убирает ресурс из манифеста, не трогая файлы
+func (m *manifest) forget(u string) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.entries[u]; ok {
+		delete(m.entries, u)
+		m.append(manifestEntry{URL: u, Removed: true})
+	}
+}
+
+// drop удаляет исчезнувший с сервера ресурс из зеркала и возвращает путь его файла
+func (m *manifest) drop(u string) (string, bool) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[u]
+	if !ok {
+		return "", false
+	}
+	m.dropEntry(entry)
+
+	return filepath.Join(m.dir, entry.Path), true
+}
+
+// sweep удаляет из зеркала ресурсы, которые не проверялись с момента since, и возвращает их пути
+func (m *manifest) sweep(since time.Time) []string {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var removed []string
+	for _, u := range slices.Sorted(maps.Keys(m.entries)) {
+		if entry := m.entries[u]; entry.Checked.Before(since) {
+			m.dropEntry(entry)
+			removed = append(removed, filepath.Join(m.dir, entry.Path))
+		}
+	}
+
+	return removed
+}
+
+// dropEntry удаляет файлы ресурса и запись о нём; вызывается под m.mu
+func (m *manifest) dropEntry(entry manifestEntry) {
+
+	m.removeFiles(entry.Path)
+	delete(m.entries, entry.URL)
+	m.append(manifestEntry{URL: entry.URL, Removed: true})
+	m.stats.removed++
+}
+
+// removeFiles удаляет файл зеркала и его оригинал (.orig)
+func (m *manifest) removeFiles(rel string) {
+
+	if rel == "" {
+		return
+	}
+	localPath := filepath.Join(m.dir, rel)
+	if err := os.Remove(localPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Printf("предупреждение: не удалось удалить %s: %v\n", localPath, err)
+	}
+	os.Remove(localPath + origSuffix)
+}
+
+// summary возвращает итоги обхода
+func (m *manifest) summary() manifestStats {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stats
+}
+
+// close переписывает манифест снимком и возвращает первую ошибку записи
+func (m *manifest) close() error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.path == "" {
+		return nil
+	}
+
+	err := m.err
+	if cerr := m.compact(); err == nil {
+		err = cerr
+	}
+	if m.journal != nil {
+		m.journal.Close()
+		m.journal = nil
+	}
+
+	return err
+}
+
+// hashFile возвращает SHA-256 содержимого файла
+func hashFile(path string) (string, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
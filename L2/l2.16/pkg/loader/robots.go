@@ -0,0 +1,202 @@
+package loader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"mywget/pkg/robots"
+	"mywget/pkg/sitemap"
+)
+
+// ограничения служебных файлов
+const (
+	maxRobotsSize   = 500 * 1024 // RFC 9309 требует разбирать не меньше 500 КиБ robots.txt
+	maxSitemapFiles = 100        // сколько файлов карт сайта загружать за один обход
+	maxSitemapDepth = 2          // вложенность индексов: robots.txt -> индекс -> индекс -> карта
+)
+
+// hostRobots - robots.txt одного хоста, загружается один раз при первом обращении к хосту
+type hostRobots struct {
+	once  sync.Once
+	robot *robots.Robot
+}
+
+// robotsFor возвращает правила robots.txt хоста, к которому относится u
+func (l *Loader) robotsFor(u *url.URL) *robots.Robot {
+
+	origin := u.Scheme + "://" + u.Host
+	v, _ := l.robots.LoadOrStore(origin, &hostRobots{})
+	hr := v.(*hostRobots)
+	hr.once.Do(func() {
+		hr.robot = l.loadRobotsTxt(origin, u.Host)
+	})
+
+	return hr.robot
+}
+
+// loadRobotsTxt загружает и парсит robots.txt хоста. По RFC 9309 отсутствие файла (4xx)
+// снимает все ограничения, а ошибка сервера или сети запрещает весь хост
+func (l *Loader) loadRobotsTxt(origin, host string) *robots.Robot {
+
+	robot := robots.New()
+
+	body, err := l.fetch(origin+"/robots.txt", maxRobotsSize)
+	if err != nil {
+		var se *statusError
+		switch {
+		case errors.As(err, &se) && se.code >= 400 && se.code < 500 && se.code != http.StatusTooManyRequests:
+			// файла нет - ограничений нет
+		case l.ctx.Err() != nil:
+			robot.DisallowAll()
+		default:
+			fmt.Printf("предупреждение: robots.txt %s недоступен (%v), хост пропускается\n", origin, err)
+			robot.DisallowAll()
+			l.state.markFailed()
+		}
+		return robot
+	}
+	robot.Parse(string(body))
+
+	// Crawl-delay соблюдаем через ограничитель хоста - он общий для всех воркеров
+	delay := robot.CrawlDelay(l.opts.UserAgent)
+	if delay > 0 && l.opts.MaxCrawlDelay > 0 {
+		if delay > l.opts.MaxCrawlDelay {
+			fmt.Printf("предупреждение: Crawl-delay %v для %s больше --max-crawl-delay, пауза %v\n",
+				delay, host, l.opts.MaxCrawlDelay)
+			delay = l.opts.MaxCrawlDelay
+		}
+		l.limiter.SetHostDelay(host, delay)
+		fmt.Printf("robots.txt %s: пауза между запросами %v\n", origin, delay)
+	}
+
+	return robot
+}
+
+// seedFromSitemaps добавляет в обход адреса из карт сайта, перечисленных в robots.txt
+// стартового хоста; индексы карт раскрываются, адреса вне зеркала отбрасывает addTask
+func (l *Loader) seedFromSitemaps() {
+
+	type sitemapRef struct {
+		url   string
+		depth int
+	}
+
+	var queue []sitemapRef
+	for _, s := range l.robotsFor(l.baseURL).Sitemaps() {
+		queue = append(queue, sitemapRef{url: s})
+	}
+
+	seen := make(map[string]bool)
+	added, files := 0, 0
+	for len(queue) > 0 && files < maxSitemapFiles && l.ctx.Err() == nil {
+		ref := queue[0]
+		queue = queue[1:]
+
+		u, err := l.baseURL.Parse(ref.url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || seen[u.String()] {
+			continue
+		}
+		seen[u.String()] = true
+		files++
+
+		body, err := l.fetch(u.String(), sitemap.MaxSize)
+		if err != nil {
+			if l.ctx.Err() == nil {
+				fmt.Printf("предупреждение: карта сайта %s: %v\n", u, err)
+			}
+			continue
+		}
+		sm, err := sitemap.Parse(bytes.NewReader(body))
+		if err != nil {
+			fmt.Printf("предупреждение: карта сайта %s: %v\n", u, err)
+			continue
+		}
+
+		if ref.depth < maxSitemapDepth {
+			for _, s := range sm.Sitemaps {
+				queue = append(queue, sitemapRef{url: s, depth: ref.depth + 1})
+			}
+		}
+		for _, pageURL := range sm.URLs {
+			if l.addTask(pageURL, 0, l.getResourceType(pageURL)) {
+				added++
+			}
+		}
+	}
+
+	if files > 0 {
+		fmt.Printf("из карт сайта (%d файлов) добавлено адресов: %d\n", files, added)
+	}
+}
+
+// fetch загружает небольшой служебный файл (robots.txt, карту сайта) в память с повторами;
+// читается не больше maxSize байт, ответ с неуспешным статусом возвращается как *statusError
+func (l *Loader) fetch(rawURL string, maxSize int64) ([]byte, error) {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		if attempt > 0 {
+			if err := l.sleep(l.backoff(attempt, err)); err != nil {
+				return nil, err
+			}
+		}
+
+		var body []byte
+		body, err = l.fetchOnce(u, maxSize)
+		if err == nil {
+			return body, nil
+		}
+
+		if l.ctx.Err() != nil {
+			return nil, l.ctx.Err()
+		}
+		if !isRetryable(err) || attempt >= l.opts.MaxRetries {
+			return nil, err
+		}
+	}
+}
+
+// fetchOnce выполняет одну попытку fetch
+func (l *Loader) fetchOnce(u *url.URL, maxSize int64) ([]byte, error) {
+
+	if err := l.limiter.Wait(l.ctx, u.Host); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(l.ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", l.opts.UserAgent)
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, &retryableError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{
+			code:       resp.StatusCode,
+			status:     resp.Status,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize))
+	if err != nil {
+		return nil, &retryableError{fmt.Errorf("ошибка чтения тела: %w", err)}
+	}
+
+	return body, nil
+}
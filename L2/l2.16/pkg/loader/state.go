@@ -0,0 +1,238 @@
+package loader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// crawlState - состояние обхода, которое сохраняется на диск, чтобы прерванное
+// зеркалирование можно было продолжить: обработанные URL и очередь ожидающих задач
+type crawlState struct {
+	mu       sync.Mutex
+	path     string              // файл состояния ("" - состояние не сохраняется)
+	baseURL  string              // стартовый URL обхода
+	maxDepth int                 // глубина обхода
+	done     map[string]struct{} // полностью обработанные URL
+	pending  map[string]Task     // задачи в очереди или в работе
+	started  time.Time           // начало обхода (с учётом прерванных запусков)
+	failed   bool                // были ошибки загрузки - зеркало может быть неполным
+	dirty    bool                // есть несохранённые изменения
+}
+
+// stateFile - формат файла состояния
+type stateFile struct {
+	BaseURL  string   `json:"base_url"`
+	MaxDepth int      `json:"max_depth"`
+	Done     []string `json:"done"`
+	Pending  []Task   `json:"pending"`
+	// для сверки с манифестом: ресурсы, не проверенные с начала обхода, удаляются
+	Started time.Time `json:"started"`
+	Failed  bool      `json:"failed,omitempty"`
+}
+
+// newCrawlState создаёт пустое состояние обхода
+func newCrawlState(path, baseURL string, maxDepth int) *crawlState {
+
+	return &crawlState{
+		path:     path,
+		baseURL:  baseURL,
+		maxDepth: maxDepth,
+		done:     make(map[string]struct{}),
+		pending:  make(map[string]Task),
+		started:  time.Now(),
+	}
+}
+
+// loadCrawlState читает сохранённое состояние. Если файла нет или он от другого обхода,
+// возвращается пустое состояние и false
+func loadCrawlState(path, baseURL string, maxDepth int) (*crawlState, bool, error) {
+
+	state := newCrawlState(path, baseURL, maxDepth)
+	if path == "" {
+		return state, false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("чтение состояния: %w", err)
+	}
+
+	var file stateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, false, fmt.Errorf("разбор состояния %s: %w", path, err)
+	}
+	if file.BaseURL != baseURL || file.MaxDepth != maxDepth {
+		return state, false, nil
+	}
+
+	for _, u := range file.Done {
+		state.done[u] = struct{}{}
+	}
+	for _, task := range file.Pending {
+		state.pending[task.URL] = task
+	}
+	// в старом файле начала нет - нулевое время, и удалять по манифесту будет нечего
+	state.started = file.Started
+	state.failed = file.Failed
+
+	return state, true, nil
+}
+
+// addPending запоминает задачу в очереди (при повторном добавлении оставляем меньшую глубину)
+func (s *crawlState) addPending(task Task) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.pending[task.URL]; ok && old.Depth <= task.Depth {
+		return
+	}
+	s.pending[task.URL] = task
+	s.dirty = true
+}
+
+// complete переносит URL из очереди в обработанные
+func (s *crawlState) complete(u string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pending, u)
+	s.done[u] = struct{}{}
+	s.dirty = true
+}
+
+// markFailed отмечает ошибку загрузки
+func (s *crawlState) markFailed() {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.failed {
+		s.failed = true
+		s.dirty = true
+	}
+}
+
+// hasFailed сообщает, были ли ошибки загрузки
+func (s *crawlState) hasFailed() bool {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.failed
+}
+
+// doneURLs возвращает обработанные URL
+func (s *crawlState) doneURLs() []string {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Collect(maps.Keys(s.done))
+}
+
+// pendingTasks возвращает задачи из очереди (по возрастанию глубины)
+func (s *crawlState) pendingTasks() []Task {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := slices.Collect(maps.Values(s.pending))
+	slices.SortFunc(tasks, func(a, b Task) int { return a.Depth - b.Depth })
+
+	return tasks
+}
+
+// save атомарно записывает состояние на диск, если оно изменилось
+func (s *crawlState) save() error {
+
+	if s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	file := stateFile{
+		BaseURL:  s.baseURL,
+		MaxDepth: s.maxDepth,
+		Done:     slices.Sorted(maps.Keys(s.done)),
+		Pending:  slices.Collect(maps.Values(s.pending)),
+		Started:  s.started,
+		Failed:   s.failed,
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("кодирование состояния: %w", err)
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		// не получилось - попробуем в следующий раз
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// remove удаляет файл состояния после успешного завершения обхода
+func (s *crawlState) remove() error {
+
+	if s.path == "" {
+		return nil
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// writeFileAtomic записывает файл через временный файл, fsync и переименование,
+// чтобы при падении на диске оставалась либо старая, либо новая версия целиком
+func writeFileAtomic(path string, data []byte) error {
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("создание директории %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("создание временного файла: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // после переименования удалять уже нечего
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("запись %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("синхронизация %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("закрытие %s: %w", tmp.Name(), err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -10,6 +10,7 @@ import (
 )
 
 // ExtractLinks извлекает все ссылки из HTML документа
+// (какие хосты обходить, решает загрузчик)
 func ExtractLinks(pageURL *url.URL, body io.Reader) (pageLinks, resourceLinks []string, err error) {
 
 	doc, err := html.Parse(body)
@@ -25,7 +26,7 @@ func ExtractLinks(pageURL *url.URL, body io.Reader) (pageLinks, resourceLinks []
 				// ссылки на страницы
 				if href := getAttr(n, "href"); href != "" {
 					absolute := normalizeLink(pageURL, href)
-					if absolute != "" && !isExternalLink(pageURL, absolute) {
+					if absolute != "" {
 						pageLinks = append(pageLinks, absolute)
 					}
 				}
@@ -51,7 +52,7 @@ func ExtractLinks(pageURL *url.URL, body io.Reader) (pageLinks, resourceLinks []
 					strings.Contains(rel, "manifest") ||
 					as != "" {
 					absolute := normalizeLink(pageURL, href)
-					if absolute != "" && !isExternalLink(pageURL, absolute) {
+					if absolute != "" {
 						resourceLinks = append(resourceLinks, absolute)
 					}
 				}
@@ -60,7 +61,7 @@ func ExtractLinks(pageURL *url.URL, body io.Reader) (pageLinks, resourceLinks []
 				// JavaScript файлы
 				if src := getAttr(n, "src"); src != "" {
 					absolute := normalizeLink(pageURL, src)
-					if absolute != "" && !isExternalLink(pageURL, absolute) {
+					if absolute != "" {
 						resourceLinks = append(resourceLinks, absolute)
 					}
 				}
@@ -73,7 +74,7 @@ func ExtractLinks(pageURL *url.URL, body io.Reader) (pageLinks, resourceLinks []
 				// встроенный контент
 				if src := getAttr(n, "src"); src != "" {
 					absolute := normalizeLink(pageURL, src)
-					if absolute != "" && !isExternalLink(pageURL, absolute) {
+					if absolute != "" {
 						resourceLinks = append(resourceLinks, absolute)
 					}
 				}
@@ -82,7 +83,7 @@ func ExtractLinks(pageURL *url.URL, body io.Reader) (pageLinks, resourceLinks []
 				// источники для picture, audio, video
 				if src := getAttr(n, "src"); src != "" {
 					absolute := normalizeLink(pageURL, src)
-					if absolute != "" && !isExternalLink(pageURL, absolute) {
+					if absolute != "" {
 						resourceLinks = append(resourceLinks, absolute)
 					}
 				}
@@ -94,13 +95,13 @@ func ExtractLinks(pageURL *url.URL, body io.Reader) (pageLinks, resourceLinks []
 				// медиа элементы
 				if src := getAttr(n, "src"); src != "" {
 					absolute := normalizeLink(pageURL, src)
-					if absolute != "" && !isExternalLink(pageURL, absolute) {
+					if absolute != "" {
 						resourceLinks = append(resourceLinks, absolute)
 					}
 				}
 				if src := getAttr(n, "poster"); src != "" {
 					absolute := normalizeLink(pageURL, src)
-					if absolute != "" && !isExternalLink(pageURL, absolute) {
+					if absolute != "" {
 						resourceLinks = append(resourceLinks, absolute)
 					}
 				}
@@ -109,7 +110,7 @@ func ExtractLinks(pageURL *url.URL, body io.Reader) (pageLinks, resourceLinks []
 				// встроенные объекты
 				if data := getAttr(n, "data"); data != "" {
 					absolute := normalizeLink(pageURL, data)
-					if absolute != "" && !isExternalLink(pageURL, absolute) {
+					if absolute != "" {
 						resourceLinks = append(resourceLinks, absolute)
 					}
 				}
@@ -121,13 +122,13 @@ func ExtractLinks(pageURL *url.URL, body io.Reader) (pageLinks, resourceLinks []
 				// SVG элементы
 				if href := getAttr(n, "href"); href != "" {
 					absolute := normalizeLink(pageURL, href)
-					if absolute != "" && !isExternalLink(pageURL, absolute) {
+					if absolute != "" {
 						resourceLinks = append(resourceLinks, absolute)
 					}
 				}
 				if xlink := getAttr(n, "xlink:href"); xlink != "" {
 					absolute := normalizeLink(pageURL, xlink)
-					if absolute != "" && !isExternalLink(pageURL, absolute) {
+					if absolute != "" {
 						resourceLinks = append(resourceLinks, absolute)
 					}
 				}
@@ -139,7 +140,7 @@ func ExtractLinks(pageURL *url.URL, body io.Reader) (pageLinks, resourceLinks []
 						property == "twitter:image" || property == "twitter:image:src" {
 						if content := getAttr(n, "content"); content != "" {
 							absolute := normalizeLink(pageURL, content)
-							if absolute != "" && !isExternalLink(pageURL, absolute) {
+							if absolute != "" {
 								resourceLinks = append(resourceLinks, absolute)
 							}
 						}
@@ -181,7 +182,7 @@ func ExtractCSSLinks(cssContent string, baseURL *url.URL) []string {
 			// игнорируем data: URL, base64 и т.д.
 			if !strings.HasPrefix(link, "data:") && !strings.HasPrefix(link, "#") {
 				absolute := normalizeLink(baseURL, link)
-				if absolute != "" && !isExternalLink(baseURL, absolute) {
+				if absolute != "" {
 					links = append(links, absolute)
 				}
 			}
@@ -194,7 +195,7 @@ func ExtractCSSLinks(cssContent string, baseURL *url.URL) []string {
 		if len(match) > 1 && match[1] != "" {
 			link := match[1]
 			absolute := normalizeLink(baseURL, link)
-			if absolute != "" && !isExternalLink(baseURL, absolute) {
+			if absolute != "" {
 				links = append(links, absolute)
 			}
 		}
@@ -220,7 +221,7 @@ func extractImageLinks(n *html.Node, pageURL *url.URL, resourceLinks *[]string)
 	// обычный src
 	if src := getAttr(n, "src"); src != "" {
 		absolute := normalizeLink(pageURL, src)
-		if absolute != "" && !isExternalLink(pageURL, absolute) {
+		if absolute != "" {
 			*resourceLinks = append(*resourceLinks, absolute)
 		}
 	}
@@ -242,13 +243,13 @@ func extractSrcsetLinks(srcset string, pageURL *url.URL, resourceLinks *[]string
 		if idx := strings.Index(part, " "); idx > 0 {
 			urlStr := part[:idx]
 			absolute := normalizeLink(pageURL, urlStr)
-			if absolute != "" && !isExternalLink(pageURL, absolute) {
+			if absolute != "" {
 				*resourceLinks = append(*resourceLinks, absolute)
 			}
 		} else if part != "" {
 			// если нет дескриптора, то весь part - это URL
 			absolute := normalizeLink(pageURL, part)
-			if absolute != "" && !isExternalLink(pageURL, absolute) {
+			if absolute != "" {
 				*resourceLinks = append(*resourceLinks, absolute)
 			}
 		}
@@ -280,7 +281,7 @@ func extractStyleUrls(style string, pageURL *url.URL, resourceLinks *[]string) {
 
 		if urlContent != "" {
 			absolute := normalizeLink(pageURL, urlContent)
-			if absolute != "" && !isExternalLink(pageURL, absolute) {
+			if absolute != "" {
 				*resourceLinks = append(*resourceLinks, absolute)
 			}
 		}
@@ -318,19 +319,3 @@ func normalizeLink(base *url.URL, link string) string {
 
 	return absURL.String()
 }
-
-// isExternalLink проверяет, является ли ссылка внешней
-func isExternalLink(base *url.URL, link string) bool {
-
-	parsed, err := url.Parse(link)
-	if err != nil {
-		return true
-	}
-
-	// если хост пустой или совпадает - это внутренняя ссылка
-	if parsed.Host == "" || parsed.Host == base.Host {
-		return false
-	}
-
-	return true
-}
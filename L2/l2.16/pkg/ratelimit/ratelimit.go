@@ -0,0 +1,165 @@
+package ratelimit
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Limiter - ограничитель частоты запросов (token bucket в форме GCRA):
+// не больше rate запросов в секунду с допустимым всплеском burst
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration // минимальный интервал между запросами
+	burst    int           // сколько запросов можно сделать подряд без ожидания
+	jitter   bool          // случайно менять интервал в пределах 0.5..1.5
+	tat      time.Time     // теоретическое время прибытия следующего запроса
+}
+
+// NewLimiter создаёт ограничитель на rate запросов в секунду, rate <= 0 - без ограничений
+func NewLimiter(rate float64, burst int) *Limiter {
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	l := &Limiter{burst: burst}
+	if rate > 0 {
+		l.interval = time.Duration(float64(time.Second) / rate)
+	}
+
+	return l
+}
+
+// NewIntervalLimiter создаёт ограничитель с паузой interval между запросами,
+// jitter - каждый раз случайно менять паузу в пределах 0.5..1.5 interval (как wget --random-wait)
+func NewIntervalLimiter(interval time.Duration, jitter bool) *Limiter {
+
+	return &Limiter{interval: interval, burst: 1, jitter: jitter}
+}
+
+// SetMinInterval увеличивает интервал между запросами до d, если он был меньше
+// (так к лимиту из настроек добавляется Crawl-delay из robots.txt)
+func (l *Limiter) SetMinInterval(d time.Duration) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if d > l.interval {
+		l.interval = d
+	}
+}
+
+// reserve резервирует место под запрос и возвращает, сколько нужно подождать
+func (l *Limiter) reserve(now time.Time) time.Duration {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.interval <= 0 {
+		return 0
+	}
+
+	if l.tat.Before(now) {
+		l.tat = now
+	}
+	interval := l.interval
+	if l.jitter {
+		interval = interval/2 + time.Duration(rand.Int64N(int64(interval)+1))
+	}
+
+	allowAt := l.tat.Add(-time.Duration(l.burst-1) * interval)
+	l.tat = l.tat.Add(interval)
+
+	if allowAt.After(now) {
+		return allowAt.Sub(now)
+	}
+
+	return 0
+}
+
+// Wait блокируется, пока не будет разрешён очередной запрос, или до отмены контекста
+func (l *Limiter) Wait(ctx context.Context) error {
+
+	if l == nil {
+		return ctx.Err()
+	}
+
+	delay := l.reserve(time.Now())
+	if delay == 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HostLimiter - общий ограничитель плюс отдельный ограничитель для каждого хоста
+type HostLimiter struct {
+	global   *Limiter
+	pause    *Limiter // пауза между любыми запросами (wget --wait), может быть nil
+	hostRate float64
+
+	mu    sync.Mutex
+	hosts map[string]*Limiter
+}
+
+// NewHostLimiter создаёт ограничитель с общим лимитом globalRate и лимитом hostRate на каждый хост
+// (значения <= 0 отключают соответствующий лимит)
+func NewHostLimiter(globalRate, hostRate float64) *HostLimiter {
+
+	return &HostLimiter{
+		global:   NewLimiter(globalRate, 1),
+		hostRate: hostRate,
+		hosts:    make(map[string]*Limiter),
+	}
+}
+
+// Host возвращает ограничитель конкретного хоста (создаёт его при первом обращении)
+func (h *HostLimiter) Host(host string) *Limiter {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	l, ok := h.hosts[host]
+	if !ok {
+		l = NewLimiter(h.hostRate, 1)
+		h.hosts[host] = l
+	}
+
+	return l
+}
+
+// SetHostDelay задаёт минимальную паузу между запросами к хосту (Crawl-delay)
+func (h *HostLimiter) SetHostDelay(host string, d time.Duration) {
+	h.Host(host).SetMinInterval(d)
+}
+
+// SetPause задаёт паузу между любыми двумя запросами (wget --wait/--random-wait)
+func (h *HostLimiter) SetPause(interval time.Duration, jitter bool) {
+
+	if interval > 0 {
+		h.pause = NewIntervalLimiter(interval, jitter)
+	}
+}
+
+// Wait ждёт разрешения сначала от ограничителя хоста, затем от общего
+func (h *HostLimiter) Wait(ctx context.Context, host string) error {
+
+	if err := h.Host(host).Wait(ctx); err != nil {
+		return err
+	}
+	if err := h.pause.Wait(ctx); err != nil {
+		return err
+	}
+
+	return h.global.Wait(ctx)
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterSpacesRequests(t *testing.T) {
+
+	l := NewLimiter(100, 1) // не чаще раза в 10 мс
+
+	start := time.Now()
+	for range 5 {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// первый запрос проходит сразу, остальные четыре - через 10 мс
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("5 requests took %v, want at least 40ms", elapsed)
+	}
+}
+
+func TestLimiterBurst(t *testing.T) {
+
+	l := NewLimiter(1, 3)
+
+	start := time.Now()
+	for range 3 {
+		l.Wait(context.Background())
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("burst of 3 took %v, want no waiting", elapsed)
+	}
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+
+	l := NewLimiter(0, 1)
+
+	start := time.Now()
+	for range 1000 {
+		l.Wait(context.Background())
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("unlimited limiter waited %v", elapsed)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+
+	l := NewLimiter(0.1, 1) // раз в 10 секунд
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx); err == nil {
+		t.Error("Wait() expected context error")
+	}
+}
+
+func TestHostLimiterSeparatesHosts(t *testing.T) {
+
+	h := NewHostLimiter(0, 10) // 100 мс между запросами к одному хосту
+
+	start := time.Now()
+	h.Wait(context.Background(), "a.example")
+	h.Wait(context.Background(), "b.example")
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("different hosts waited %v", elapsed)
+	}
+
+	h.Wait(context.Background(), "a.example")
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("same host waited only %v", elapsed)
+	}
+}
+
+func TestHostDelay(t *testing.T) {
+
+	h := NewHostLimiter(0, 100) // 10 мс между запросами к хосту
+	h.SetHostDelay("slow.example", 50*time.Millisecond)
+	h.SetHostDelay("fast.example", time.Millisecond) // меньше лимита - лимит не меняется
+
+	start := time.Now()
+	for range 3 {
+		h.Wait(context.Background(), "slow.example")
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 requests with 50ms Crawl-delay took %v", elapsed)
+	}
+
+	start = time.Now()
+	for range 3 {
+		h.Wait(context.Background(), "fast.example")
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond || elapsed > 45*time.Millisecond {
+		t.Errorf("3 requests with 10ms limit took %v", elapsed)
+	}
+}
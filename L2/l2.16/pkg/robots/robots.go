@@ -1,23 +1,41 @@
+// Package robots разбирает robots.txt по RFC 9309: группы правил по имени робота,
+// Allow/Disallow с шаблонами * и $ (побеждает самое длинное совпадение), а также
+// нестандартные, но распространённые строки Crawl-delay и Sitemap
 package robots
 
 import (
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
+// rule - одно правило Allow или Disallow
+type rule struct {
+	pattern string // нормализованный шаблон пути
+	allow   bool
+}
+
+// group - группа правил для одного или нескольких роботов
+type group struct {
+	agents     []string // имена роботов в нижнем регистре, "*" - все остальные
+	rules      []rule
+	crawlDelay time.Duration
+}
+
 // robot - парсер robots.txt
 type Robot struct {
-	rules map[string][]string // правила: user-agent -> запрещенные пути
-	mu    sync.RWMutex
+	groups   []*group
+	sitemaps []string // адреса из строк Sitemap (относятся ко всему файлу, а не к группе)
+	mu       sync.RWMutex
 }
 
 // new создает новый экземпляр Robot
 func New() *Robot {
 
-	return &Robot{
-		rules: make(map[string][]string),
-	}
+	return &Robot{}
 }
 
 // parse парсит файл robots.txt
@@ -26,56 +44,230 @@ func (r *Robot) Parse(robotsContent string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	robotsContent = strings.TrimPrefix(robotsContent, "\ufeff") // BOM
 	lines := strings.Split(robotsContent, "\n")
-	var currentAgent string
 
-	for _, line := range lines {
-		line = strings.TrimSpace(strings.Split(line, "#")[0]) // убираем комментарии
-		if line == "" {
-			continue
-		}
+	// current - группа, в которую идут правила; agentsDone - после строк User-agent уже были
+	// правила, и следующая строка User-agent начинает новую группу
+	var current *group
+	agentsDone := false
 
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
+	for _, line := range lines {
+		line, _, _ = strings.Cut(line, "#") // убираем комментарии
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
 			continue
 		}
-
-		key := strings.TrimSpace(strings.ToLower(parts[0]))
-		value := strings.TrimSpace(parts[1])
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
 
 		switch key {
 		case "user-agent":
-			// задаем текущего user-agent для следующих правил
-			currentAgent = value
-		case "disallow":
-			// добавляем запрещенный путь для текущего user-agent
-			if currentAgent != "" && value != "" {
-				r.rules[currentAgent] = append(r.rules[currentAgent], value)
+			// несколько строк User-agent подряд относятся к одной группе
+			if current == nil || agentsDone {
+				current = &group{}
+				r.groups = append(r.groups, current)
+				agentsDone = false
+			}
+			current.agents = append(current.agents, agentName(value))
+		case "allow", "disallow":
+			// правила до первой строки User-agent ни к кому не относятся
+			if current == nil {
+				continue
+			}
+			agentsDone = true
+			// пустой Disallow ничего не запрещает
+			if value != "" {
+				current.rules = append(current.rules, rule{pattern: normalize(value), allow: key == "allow"})
+			}
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			agentsDone = true
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			if value != "" {
+				r.sitemaps = append(r.sitemaps, value)
 			}
 		}
 	}
 }
 
+// DisallowAll запрещает всё: так по RFC 9309 нужно поступать, если robots.txt
+// недоступен из-за ошибки сервера или сети
+func (r *Robot) DisallowAll() {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.groups = []*group{{agents: []string{"*"}, rules: []rule{{pattern: "/"}}}}
+}
+
 // isAllowed проверяет, разрешен ли данный URL для указанного user-agent
 func (r *Robot) IsAllowed(agent string, url *url.URL) bool {
 
+	// сам robots.txt доступен всегда
+	if url.Path == "/robots.txt" {
+		return true
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	// проверяем все правила для агента и для "*"
-	agents := []string{agent, "*"}
-
-	for _, a := range agents {
-		if disallows, ok := r.rules[a]; ok {
-			path := url.Path
-			for _, disallow := range disallows {
-				// если путь начинается с запрещенного, доступ запрещен
-				if strings.HasPrefix(path, disallow) {
-					return false
-				}
+	path := normalize(url.EscapedPath())
+	if path == "" {
+		path = "/"
+	}
+	if url.RawQuery != "" {
+		path += "?" + url.RawQuery
+	}
+
+	// побеждает правило с самым длинным шаблоном, при равенстве - Allow
+	best, allowed := -1, true
+	for _, g := range r.match(agent) {
+		for _, rl := range g.rules {
+			if len(rl.pattern) < best || len(rl.pattern) == best && !rl.allow {
+				continue
+			}
+			if matchPattern(rl.pattern, path) {
+				best, allowed = len(rl.pattern), rl.allow
 			}
 		}
 	}
 
-	return true
+	return allowed
+}
+
+// CrawlDelay возвращает паузу между запросами, которую robots.txt просит соблюдать агента
+// (0 - не задана)
+func (r *Robot) CrawlDelay(agent string) time.Duration {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var delay time.Duration
+	for _, g := range r.match(agent) {
+		delay = max(delay, g.crawlDelay)
+	}
+
+	return delay
+}
+
+// Sitemaps возвращает адреса карт сайта из строк Sitemap
+func (r *Robot) Sitemaps() []string {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]string(nil), r.sitemaps...)
+}
+
+// match возвращает группы агента: все группы с его именем, а если таких нет - группы "*"
+func (r *Robot) match(agent string) []*group {
+
+	name := agentName(agent)
+
+	var named, common []*group
+	for _, g := range r.groups {
+		switch {
+		case name != "" && slices.Contains(g.agents, name):
+			named = append(named, g)
+		case slices.Contains(g.agents, "*"):
+			common = append(common, g)
+		}
+	}
+	if len(named) > 0 {
+		return named
+	}
+
+	return common
+}
+
+// agentName выделяет имя робота (product token) из User-Agent или значения строки
+// User-agent: "Mozilla/5.0 (compatible)" -> "mozilla". Сравнение без учёта регистра
+func agentName(s string) string {
+
+	s = strings.TrimSpace(s)
+	if s == "*" {
+		return s
+	}
+	end := strings.IndexFunc(s, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-' || c == '_')
+	})
+	if end >= 0 {
+		s = s[:end]
+	}
+
+	return strings.ToLower(s)
+}
+
+// normalize приводит путь или шаблон к одному виду: байты вне ASCII и пробелы
+// кодируются %XX, шестнадцатеричные цифры в %xx - в верхнем регистре
+func normalize(s string) string {
+
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+			i += 2
+		case c <= ' ' || c >= 0x7f:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xf])
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// matchPattern сопоставляет путь с шаблоном: * - любая последовательность символов,
+// $ в конце - конец пути; без $ шаблон совпадает с началом пути
+func matchPattern(pattern, path string) bool {
+
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+
+	// первая часть привязана к началу пути
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	if len(parts) == 1 {
+		return !anchored || len(path) == len(parts[0])
+	}
+	path = path[len(parts[0]):]
+
+	// средние части ищем слева направо: самое раннее вхождение оставляет больше места остальным
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(path, part)
+		if i < 0 {
+			return false
+		}
+		path = path[i+len(part):]
+	}
+
+	// последняя часть: при $ - суффикс, иначе достаточно вхождения
+	if anchored {
+		return strings.HasSuffix(path, last)
+	}
+
+	return strings.Contains(path, last)
 }
@@ -0,0 +1,162 @@
+package robots
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestIsAllowed(t *testing.T) {
+
+	const content = `# комментарий
+User-agent: *
+Disallow: /private
+Allow: /private/open
+Disallow: /*.pdf$
+Disallow: /search?q=
+
+user-agent: MyWget-Bot
+user-agent: other
+Disallow: /bot-only
+Allow: /bot-only/page$
+Crawl-delay: 2.5
+
+User-agent: picky
+Disallow: /
+Allow: /$
+Allow: /shop/*/items
+
+User-agent: empty
+Disallow:
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+	r := New()
+	r.Parse(content)
+
+	tests := []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		// группа "*"
+		{"somebot", "/", true},
+		{"somebot", "/private", false},
+		{"somebot", "/private/secret", false},
+		{"somebot", "/private/open/doc", true}, // более длинное Allow важнее Disallow
+		{"somebot", "/files/a.pdf", false},
+		{"somebot", "/files/a.pdf?x=1", true}, // $ - конец пути с запросом
+		{"somebot", "/search?q=go", false},
+		{"somebot", "/search", true},
+		{"somebot", "/robots.txt", true},
+
+		// группа агента: имя без учёта регистра и версии, группа "*" не применяется
+		{"mywget-bot/1.0 (+https://example.com)", "/private", true},
+		{"MYWGET-BOT", "/bot-only/x", false},
+		{"mywget-bot", "/bot-only/page", true},
+		{"mywget-bot", "/bot-only/page/2", false},
+		{"Other", "/bot-only", false},
+
+		// Disallow: / с исключениями
+		{"picky", "/", true},
+		{"picky", "/about", false},
+		{"picky", "/shop/books/items/1", true},
+		{"picky", "/shop/books", false},
+
+		// пустой Disallow ничего не запрещает
+		{"empty", "/private", true},
+	}
+
+	for _, tt := range tests {
+		u, _ := url.Parse("https://example.com" + tt.path)
+		if got := r.IsAllowed(tt.agent, u); got != tt.want {
+			t.Errorf("IsAllowed(%q, %s) = %v, want %v", tt.agent, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLongestMatch(t *testing.T) {
+
+	tests := []struct {
+		rules string
+		path  string
+		want  bool
+	}{
+		{"Allow: /p\nDisallow: /", "/page", true},
+		{"Allow: /folder\nDisallow: /folder", "/folder/page", true}, // при равной длине - Allow
+		{"Allow: /page\nDisallow: /*.htm", "/page.htm", false},
+		{"Allow: /$\nDisallow: /", "/", true},
+		{"Allow: /$\nDisallow: /", "/page.htm", false},
+		{"Disallow: /*/secret/*.js", "/a/b/secret/c/d.js", false},
+		{"Disallow: /a*b*c$", "/a-c-b", true},
+		{"Disallow: /foo/bar/ツ", "/foo/bar/%E3%83%84", false}, // примеры кодирования из RFC 9309
+		{"Disallow: /foo/bar/%e3%83%84", "/foo/bar/ツ", false},
+		{"Disallow: /foo/bar/%62%61%7A", "/foo/bar/baz", true},
+	}
+
+	for _, tt := range tests {
+		r := New()
+		r.Parse("User-agent: *\n" + tt.rules)
+		u, _ := url.Parse("https://example.com" + tt.path)
+		if got := r.IsAllowed("bot", u); got != tt.want {
+			t.Errorf("%q: IsAllowed(%s) = %v, want %v", tt.rules, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestGroups(t *testing.T) {
+
+	// группы одного агента объединяются; правила до User-agent ни к кому не относятся
+	r := New()
+	r.Parse("Disallow: /orphan\r\nUser-agent: a\r\nDisallow: /one\r\n\r\nUser-agent: b\r\nDisallow: /two\r\n\r\nUser-agent: a\r\nDisallow: /three\r\n")
+
+	for path, want := range map[string]bool{"/one": false, "/two": true, "/three": false, "/orphan": true} {
+		u, _ := url.Parse("http://h" + path)
+		if got := r.IsAllowed("a", u); got != want {
+			t.Errorf("IsAllowed(a, %s) = %v, want %v", path, got, want)
+		}
+	}
+
+	// без подходящей группы и без "*" разрешено всё
+	u, _ := url.Parse("http://h/one")
+	if !r.IsAllowed("c", u) {
+		t.Error("agent without a group is disallowed")
+	}
+}
+
+func TestCrawlDelayAndSitemaps(t *testing.T) {
+
+	r := New()
+	r.Parse("User-agent: *\nCrawl-delay: 1\n\nUser-agent: slow\nCrawl-delay: 10\nCrawl-delay: oops\n" +
+		"Sitemap: https://example.com/a.xml\nSitemap: https://example.com/b.xml.gz\n")
+
+	if got := r.CrawlDelay("anybot"); got != time.Second {
+		t.Errorf("CrawlDelay(anybot) = %v, want 1s", got)
+	}
+	if got := r.CrawlDelay("slow/2.0"); got != 10*time.Second {
+		t.Errorf("CrawlDelay(slow) = %v, want 10s", got)
+	}
+	want := []string{"https://example.com/a.xml", "https://example.com/b.xml.gz"}
+	if got := r.Sitemaps(); !slices.Equal(got, want) {
+		t.Errorf("Sitemaps() = %v, want %v", got, want)
+	}
+}
+
+func TestDisallowAll(t *testing.T) {
+
+	r := New()
+	r.Parse("User-agent: bot\nAllow: /\n")
+	r.DisallowAll()
+
+	for _, path := range []string{"/", "/page"} {
+		u, _ := url.Parse("http://h" + path)
+		if r.IsAllowed("bot", u) {
+			t.Errorf("%s allowed after DisallowAll", path)
+		}
+	}
+	if u, _ := url.Parse("http://h/robots.txt"); !r.IsAllowed("bot", u) {
+		t.Error("/robots.txt disallowed")
+	}
+}
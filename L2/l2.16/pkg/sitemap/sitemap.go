@@ -0,0 +1,124 @@
+// Package sitemap разбирает карты сайта (sitemaps.org): XML со списком страниц (urlset),
+// индекс карт (sitemapindex) и текстовый вариант - по адресу на строку. Файлы, сжатые gzip,
+// распаковываются автоматически
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ограничения протокола sitemaps.org
+const (
+	MaxURLs = 50000            // адресов в одном файле
+	MaxSize = 50 * 1024 * 1024 // размер файла без сжатия
+)
+
+// Sitemap - содержимое одного файла карты сайта
+type Sitemap struct {
+	URLs     []string // адреса страниц
+	Sitemaps []string // адреса вложенных карт (у индекса карт)
+}
+
+// document - корневой элемент XML: urlset или sitemapindex
+type document struct {
+	XMLName  xml.Name
+	URLs     []entry `xml:"url"`
+	Sitemaps []entry `xml:"sitemap"`
+}
+
+// entry - элемент url или sitemap, из него нужен только адрес
+type entry struct {
+	Loc string `xml:"loc"`
+}
+
+// Parse разбирает карту сайта, при необходимости распаковывая gzip
+func Parse(r io.Reader) (*Sitemap, error) {
+
+	br := bufio.NewReader(r)
+
+	// gzip определяем по сигнатуре: сервер может отдать .xml.gz и как
+	// application/gzip, и с Content-Encoding, который клиент уже снял
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка распаковки gzip: %w", err)
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(br, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("карта сайта больше %d байт", MaxSize)
+	}
+
+	trimmed := bytes.TrimLeft(data, "\ufeff \t\r\n") // BOM и пробелы
+	if len(trimmed) > 0 && trimmed[0] != '<' {
+		return parseText(trimmed), nil
+	}
+
+	return parseXML(data)
+}
+
+// parseXML разбирает XML-вариант карты
+func parseXML(data []byte) (*Sitemap, error) {
+
+	var doc document
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("ошибка разбора XML: %w", err)
+	}
+
+	sm := &Sitemap{}
+	switch doc.XMLName.Local {
+	case "urlset":
+		sm.URLs = locations(doc.URLs)
+	case "sitemapindex":
+		sm.Sitemaps = locations(doc.Sitemaps)
+	default:
+		return nil, errors.New("не карта сайта: корневой элемент <" + doc.XMLName.Local + ">")
+	}
+
+	return sm, nil
+}
+
+// locations возвращает непустые адреса, не больше MaxURLs
+func locations(entries []entry) []string {
+
+	var urls []string
+	for _, e := range entries {
+		if loc := strings.TrimSpace(e.Loc); loc != "" {
+			urls = append(urls, loc)
+		}
+		if len(urls) == MaxURLs {
+			break
+		}
+	}
+
+	return urls
+}
+
+// parseText разбирает текстовый вариант карты: по адресу на строку
+func parseText(data []byte) *Sitemap {
+
+	sm := &Sitemap{}
+	for line := range strings.Lines(string(data)) {
+		if line = strings.TrimSpace(line); line != "" {
+			sm.URLs = append(sm.URLs, line)
+		}
+		if len(sm.URLs) == MaxURLs {
+			break
+		}
+	}
+
+	return sm
+}
@@ -0,0 +1,96 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		content      string
+		wantURLs     []string
+		wantSitemaps []string
+	}{
+		{"urlset", `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc><lastmod>2024-01-02</lastmod></url>
+  <url><loc>
+    https://example.com/about
+  </loc></url>
+  <url><loc></loc></url>
+</urlset>`, []string{"https://example.com/", "https://example.com/about"}, nil},
+		{"sitemapindex", `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/a.xml</loc></sitemap>
+  <sitemap><loc>https://example.com/b.xml.gz</loc></sitemap>
+</sitemapindex>`, nil, []string{"https://example.com/a.xml", "https://example.com/b.xml.gz"}},
+		{"текст", "https://example.com/\r\n\r\nhttps://example.com/page\n",
+			[]string{"https://example.com/", "https://example.com/page"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm, err := Parse(strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !slices.Equal(sm.URLs, tt.wantURLs) {
+				t.Errorf("URLs = %q, want %q", sm.URLs, tt.wantURLs)
+			}
+			if !slices.Equal(sm.Sitemaps, tt.wantSitemaps) {
+				t.Errorf("Sitemaps = %q, want %q", sm.Sitemaps, tt.wantSitemaps)
+			}
+		})
+	}
+}
+
+func TestParseGzip(t *testing.T) {
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`<urlset><url><loc>https://example.com/z</loc></url></urlset>`))
+	zw.Close()
+
+	sm, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !slices.Equal(sm.URLs, []string{"https://example.com/z"}) {
+		t.Errorf("URLs = %q", sm.URLs)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	for _, content := range []string{
+		"<html><body>not a sitemap</body></html>",
+		"<urlset><url><loc>https://example.com/",
+		"\x1f\x8bnot gzip",
+	} {
+		if _, err := Parse(strings.NewReader(content)); err == nil {
+			t.Errorf("Parse(%q): no error", content)
+		}
+	}
+}
+
+func TestParseLimitsURLs(t *testing.T) {
+
+	var b strings.Builder
+	b.WriteString("<urlset>")
+	for range MaxURLs + 10 {
+		b.WriteString("<url><loc>https://example.com/p</loc></url>")
+	}
+	b.WriteString("</urlset>")
+
+	sm, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sm.URLs) != MaxURLs {
+		t.Errorf("len(URLs) = %d, want %d", len(sm.URLs), MaxURLs)
+	}
+}
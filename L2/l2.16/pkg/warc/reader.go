@@ -0,0 +1,102 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reader читает записи архива по очереди; сжатые архивы (.warc.gz) распознаются сами
+type Reader struct {
+	br      *bufio.Reader
+	block   *io.LimitedReader // блок текущей записи
+	started bool
+}
+
+// NewReader создаёт читатель архива
+func NewReader(r io.Reader) (*Reader, error) {
+
+	br := bufio.NewReader(r)
+
+	// gzip.Reader по умолчанию читает подряд все члены - записи, сжатые по отдельности
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(zr)
+	}
+
+	return &Reader{br: br}, nil
+}
+
+// Next возвращает следующую запись или io.EOF. Блок предыдущей записи
+// становится недоступен
+func (r *Reader) Next() (*Record, error) {
+
+	// дочитываем блок предыдущей записи и два перевода строки после него
+	if r.started {
+		if _, err := io.Copy(io.Discard, r.block); err != nil {
+			return nil, err
+		}
+		if err := r.expect("\r\n\r\n"); err != nil {
+			return nil, err
+		}
+	}
+
+	line, err := r.br.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("обрыв архива: %w", err)
+	}
+	if !strings.HasPrefix(line, "WARC/1.") {
+		return nil, fmt.Errorf("ожидалось начало записи WARC, а не %q", strings.TrimSpace(line))
+	}
+
+	var h Header
+	for {
+		line, err := r.br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("обрыв заголовка записи: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("неверное поле заголовка %q", line)
+		}
+		h = append(h, Field{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+
+	length, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("неверный Content-Length записи: %q", h.Get("Content-Length"))
+	}
+
+	r.block = &io.LimitedReader{R: r.br, N: length}
+	r.started = true
+
+	return &Record{Header: h, Block: r.block}, nil
+}
+
+// expect читает ровно s
+func (r *Reader) expect(s string) error {
+
+	buf := make([]byte, len(s))
+	if _, err := io.ReadFull(r.br, buf); err != nil {
+		return fmt.Errorf("обрыв архива: %w", err)
+	}
+	if string(buf) != s {
+		return errors.New("после блока записи нет пустой строки")
+	}
+
+	return nil
+}
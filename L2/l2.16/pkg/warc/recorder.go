@@ -0,0 +1,255 @@
+package warc
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"os"
+	"sync"
+	"time"
+)
+
+// spoolMemory - сколько байт тела ответа держать в памяти, дальше - во временном файле
+const spoolMemory = 1 << 20
+
+// Recorder - http.RoundTripper, который записывает каждый обмен в архив: request, response
+// и metadata со временем загрузки. Ответ пишется, когда тело дочитано или закрыто
+// (закрытое раньше конца помечается WARC-Truncated). Тело записывается таким, каким
+// его получил клиент: без chunked-кодирования и сжатия, которое снял транспорт
+type Recorder struct {
+	w         *Writer
+	transport http.RoundTripper
+}
+
+// NewRecorder оборачивает transport (nil - http.DefaultTransport) записью в w
+func NewRecorder(w *Writer, transport http.RoundTripper) *Recorder {
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{w: w, transport: transport}
+}
+
+// exchange - один записываемый обмен
+type exchange struct {
+	uri     string
+	start   time.Time
+	ip      string
+	request []byte // запрос в том виде, в каком его отправляет транспорт
+	head    []byte // строка статуса и заголовки ответа
+}
+
+// RoundTrip выполняет запрос и подменяет тело ответа записывающим
+func (rec *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	ex := &exchange{uri: req.URL.String(), start: time.Now()}
+
+	dump, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return nil, err
+	}
+	ex.request = dump
+
+	// адрес сервера для WARC-IP-Address
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok {
+				ex.ip = addr.IP.String()
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	resp, err := rec.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "%s %s\r\n", resp.Proto, resp.Status)
+	resp.Header.Write(&head)
+	head.WriteString("\r\n")
+	ex.head = head.Bytes()
+
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		spool:      newSpool(ex.head),
+		length:     resp.ContentLength,
+		done: func(s *spool, truncated bool) {
+			rec.write(ex, s, truncated)
+		},
+	}
+
+	return resp, nil
+}
+
+// write пишет записи request, response и metadata обмена
+func (rec *Recorder) write(ex *exchange, body *spool, truncated bool) {
+
+	defer body.close()
+
+	responseID := NewRecordID()
+	date := FormatDate(ex.start)
+
+	rec.w.WriteRecord(Header{
+		{"WARC-Type", TypeRequest},
+		{"WARC-Record-ID", NewRecordID()},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", ex.uri},
+		{"WARC-Concurrent-To", responseID},
+		{"Content-Type", "application/http;msgtype=request"},
+		{"WARC-Block-Digest", Digest(ex.request)},
+	}, bytes.NewReader(ex.request), int64(len(ex.request)))
+
+	block, size := io.Reader(nil), body.size
+	blockDigest, payloadDigest := body.blockDigest(), body.payloadDigest()
+	if r, err := body.reader(); err == nil && body.err == nil {
+		block = r
+	} else {
+		// тело не удалось сохранить - записываем только заголовки ответа
+		block, size, truncated = bytes.NewReader(nil), 0, true
+		blockDigest, payloadDigest = Digest(ex.head), ""
+	}
+
+	h := Header{
+		{"WARC-Type", TypeResponse},
+		{"WARC-Record-ID", responseID},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", ex.uri},
+	}
+	if ex.ip != "" {
+		h = append(h, Field{"WARC-IP-Address", ex.ip})
+	}
+	h = append(h,
+		Field{"Content-Type", "application/http;msgtype=response"},
+		Field{"WARC-Block-Digest", blockDigest},
+	)
+	if payloadDigest != "" {
+		h = append(h, Field{"WARC-Payload-Digest", payloadDigest})
+	}
+	if truncated {
+		h = append(h, Field{"WARC-Truncated", "unspecified"})
+	}
+	rec.w.WriteRecord(h, io.MultiReader(bytes.NewReader(ex.head), block), int64(len(ex.head))+size)
+
+	fields := fmt.Appendf(nil, "fetchTimeMs: %d\r\n", time.Since(ex.start).Milliseconds())
+	rec.w.WriteRecord(Header{
+		{"WARC-Type", TypeMetadata},
+		{"WARC-Record-ID", NewRecordID()},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", ex.uri},
+		{"WARC-Concurrent-To", responseID},
+		{"Content-Type", "application/warc-fields"},
+	}, bytes.NewReader(fields), int64(len(fields)))
+}
+
+// recordingBody копит прочитанное тело ответа и по его окончании отдаёт обмен на запись
+type recordingBody struct {
+	io.ReadCloser
+	spool  *spool
+	length int64 // Content-Length ответа, -1 - неизвестна
+	done   func(s *spool, truncated bool)
+	once   sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+
+	n, err := b.ReadCloser.Read(p)
+	b.spool.write(p[:n])
+	if err == io.EOF {
+		b.finish(false)
+	}
+
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+
+	err := b.ReadCloser.Close()
+	// тело без длины, закрытое до EOF, неизвестно, полное ли
+	b.finish(b.length < 0 || b.spool.size < b.length)
+
+	return err
+}
+
+func (b *recordingBody) finish(truncated bool) {
+	b.once.Do(func() { b.done(b.spool, truncated) })
+}
+
+// spool - тело ответа: в памяти до spoolMemory байт, дальше во временном файле.
+// Заодно считает дайджесты блока (заголовки и тело) и тела
+type spool struct {
+	mem     bytes.Buffer
+	file    *os.File
+	size    int64
+	block   hash.Hash
+	payload hash.Hash
+	err     error
+}
+
+func newSpool(head []byte) *spool {
+
+	s := &spool{block: sha1.New(), payload: sha1.New()}
+	s.block.Write(head)
+
+	return s
+}
+
+func (s *spool) write(p []byte) {
+
+	if len(p) == 0 || s.err != nil {
+		return
+	}
+	s.block.Write(p)
+	s.payload.Write(p)
+	s.size += int64(len(p))
+
+	if s.file == nil && s.mem.Len()+len(p) > spoolMemory {
+		if s.file, s.err = os.CreateTemp("", "mywget-warc-*"); s.err != nil {
+			return
+		}
+		_, s.err = s.file.Write(s.mem.Bytes())
+		s.mem = bytes.Buffer{}
+	}
+	if s.err != nil {
+		return
+	}
+	if s.file != nil {
+		_, s.err = s.file.Write(p)
+		return
+	}
+	s.mem.Write(p)
+}
+
+func (s *spool) blockDigest() string   { return digestOf(s.block) }
+func (s *spool) payloadDigest() string { return digestOf(s.payload) }
+
+// reader возвращает накопленное тело с начала
+func (s *spool) reader() (io.Reader, error) {
+
+	if s.file == nil {
+		return bytes.NewReader(s.mem.Bytes()), nil
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return s.file, nil
+}
+
+// close удаляет временный файл
+func (s *spool) close() {
+
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+		s.file = nil
+	}
+}
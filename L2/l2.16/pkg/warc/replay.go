@@ -0,0 +1,133 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// hopByHop - заголовки одного соединения: из записанного ответа их не повторяем
+var hopByHop = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"TE", "Trailer", "Transfer-Encoding", "Upgrade", "Content-Length",
+}
+
+// capture - записанный ответ
+type capture struct {
+	block    []byte // HTTP-ответ целиком: строка статуса, заголовки, тело
+	date     time.Time
+	complete bool // полный ответ (не 206, не 304, не обрезанный) - его предпочитаем остальным
+}
+
+// Replay - http.Handler, который отдаёт ответы из архива. Запросы в форме прокси
+// (GET http://host/path, клиент с --proxy) ищутся по полному адресу, обычные (GET /path,
+// браузер) - по пути и параметрам среди всех хостов архива
+type Replay struct {
+	byURI  map[string]*capture
+	byPath map[string]string // путь с параметрами -> первый записанный адрес
+}
+
+// NewReplay читает архив и запоминает записи response. Если адрес записан несколько раз,
+// отдаётся последний полный ответ
+func NewReplay(r io.Reader) (*Replay, error) {
+
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Replay{byURI: make(map[string]*capture), byPath: make(map[string]string)}
+	for {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rec.Type() != TypeResponse {
+			continue
+		}
+
+		uri := rec.Header.Get("WARC-Target-URI")
+		u, err := url.Parse(uri)
+		if err != nil {
+			continue
+		}
+		block, err := io.ReadAll(rec.Block)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+		if err != nil {
+			return nil, fmt.Errorf("запись %s: %w", uri, err)
+		}
+		resp.Body.Close()
+
+		c := &capture{
+			block: block,
+			complete: resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusNotModified &&
+				rec.Header.Get("WARC-Truncated") == "",
+		}
+		c.date, _ = time.Parse(time.RFC3339, rec.Header.Get("WARC-Date"))
+
+		if old, ok := p.byURI[uri]; !ok || c.complete || !old.complete {
+			p.byURI[uri] = c
+		}
+		if _, ok := p.byPath[u.RequestURI()]; !ok {
+			p.byPath[u.RequestURI()] = uri
+		}
+	}
+
+	return p, nil
+}
+
+// Len возвращает число записанных адресов
+func (p *Replay) Len() int {
+	return len(p.byURI)
+}
+
+// ServeHTTP отдаёт записанный ответ или 404
+func (p *Replay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	var c *capture
+	if r.URL.IsAbs() {
+		c = p.byURI[r.URL.String()]
+	} else if uri, ok := p.byPath[r.URL.RequestURI()]; ok {
+		c = p.byURI[uri]
+	}
+	if c == nil {
+		http.Error(w, "нет в архиве: "+r.RequestURI, http.StatusNotFound)
+		return
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(c.block)), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	// обрезанное тело отдаём как есть
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	for _, name := range hopByHop {
+		w.Header().Del(name)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if !c.date.IsZero() {
+		w.Header().Set("Memento-Datetime", c.date.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
@@ -0,0 +1,246 @@
+// Package warc пишет и читает архивы WARC 1.1 (ISO 28500): HTTP-запросы и ответы
+// в том виде, в каком они прошли по сети, с метаданными обхода. Recorder записывает
+// обмены HTTP-клиента, Replay отдаёт записанные ответы по HTTP
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// типы записей WARC
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+	TypeMetadata = "metadata"
+	TypeResource = "resource"
+)
+
+// version - версия формата в первой строке записи
+const version = "WARC/1.1"
+
+// Field - поле заголовка записи
+type Field struct {
+	Name, Value string
+}
+
+// Header - поля заголовка записи в порядке записи
+type Header []Field
+
+// Get возвращает значение поля (имя без учёта регистра), "" - поля нет
+func (h Header) Get(name string) string {
+
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+
+	return ""
+}
+
+// Set заменяет значение поля или добавляет поле в конец
+func (h *Header) Set(name, value string) {
+
+	for i, f := range *h {
+		if strings.EqualFold(f.Name, name) {
+			(*h)[i].Value = value
+			return
+		}
+	}
+	*h = append(*h, Field{Name: name, Value: value})
+}
+
+// Record - запись архива: заголовок и содержимое (блок) длиной Content-Length
+type Record struct {
+	Header Header
+	Block  io.Reader
+}
+
+// Type возвращает тип записи (WARC-Type)
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// NewRecordID создаёт идентификатор записи вида <urn:uuid:...> (UUID версии 4)
+func NewRecordID() string {
+
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40 // версия 4
+	u[8] = u[8]&0x3f | 0x80 // вариант RFC 4122
+
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+// FormatDate форматирует время для WARC-Date (UTC, ISO 8601)
+func FormatDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Digest возвращает значение WARC-Block-Digest / WARC-Payload-Digest: "sha1:" и SHA-1 в base32
+func Digest(data []byte) string {
+
+	h := sha1.New()
+	h.Write(data)
+
+	return digestOf(h)
+}
+
+func digestOf(h hash.Hash) string {
+	return "sha1:" + base32.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Writer пишет записи в архив; безопасен для одновременного использования из горутин.
+// Первая ошибка записи запоминается и возвращается из Close
+type Writer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	closer   io.Closer // файл архива, nil - писатель не владеет w
+	compress bool      // каждая запись - отдельный член gzip (формат .warc.gz)
+	infoID   string    // идентификатор записи warcinfo для WARC-Warcinfo-ID
+	err      error
+}
+
+// NewWriter создаёт писатель поверх w; compress - сжимать каждую запись отдельно
+func NewWriter(w io.Writer, compress bool) *Writer {
+
+	return &Writer{w: w, compress: compress}
+}
+
+// Create открывает архив для дописывания (при продолжении обхода записи добавляются
+// в конец) и пишет запись warcinfo с полями info. Архив с именем на .gz сжимается
+func Create(path string, info Header) (*Writer, error) {
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	w := NewWriter(f, strings.HasSuffix(path, ".gz"))
+	w.closer = f
+
+	if err := w.WriteInfo(info); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// WriteInfo пишет запись warcinfo; её идентификатор попадает в WARC-Warcinfo-ID следующих записей
+func (w *Writer) WriteInfo(info Header) error {
+
+	var body bytes.Buffer
+	for _, f := range info {
+		fmt.Fprintf(&body, "%s: %s\r\n", f.Name, f.Value)
+	}
+
+	id := NewRecordID()
+	h := Header{
+		{"WARC-Type", TypeWarcinfo},
+		{"WARC-Record-ID", id},
+		{"WARC-Date", FormatDate(time.Now())},
+		{"Content-Type", "application/warc-fields"},
+	}
+	if err := w.WriteRecord(h, bytes.NewReader(body.Bytes()), int64(body.Len())); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.infoID = id
+	w.mu.Unlock()
+
+	return nil
+}
+
+// WriteRecord пишет запись: заголовок h (Content-Length и WARC-Warcinfo-ID добавляются сами)
+// и блок длиной length байт
+func (w *Writer) WriteRecord(h Header, block io.Reader, length int64) error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+
+	h = append(Header(nil), h...)
+	if w.infoID != "" && h.Get("WARC-Type") != TypeWarcinfo && h.Get("WARC-Warcinfo-ID") == "" {
+		h.Set("WARC-Warcinfo-ID", w.infoID)
+	}
+	h.Set("Content-Length", strconv.FormatInt(length, 10))
+
+	w.err = w.writeRecord(h, block, length)
+
+	return w.err
+}
+
+func (w *Writer) writeRecord(h Header, block io.Reader, length int64) error {
+
+	out := w.w
+	var zw *gzip.Writer
+	if w.compress {
+		zw = gzip.NewWriter(w.w)
+		out = zw
+	}
+	bw := bufio.NewWriter(out)
+
+	bw.WriteString(version + "\r\n")
+	for _, f := range h {
+		bw.WriteString(f.Name + ": " + f.Value + "\r\n")
+	}
+	bw.WriteString("\r\n")
+
+	n, err := io.Copy(bw, block)
+	if err != nil {
+		return err
+	}
+	if n != length {
+		return fmt.Errorf("блок записи: %d байт вместо %d", n, length)
+	}
+	bw.WriteString("\r\n\r\n")
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if zw != nil {
+		return zw.Close()
+	}
+
+	return nil
+}
+
+// Close закрывает архив и возвращает первую ошибку записи
+func (w *Writer) Close() error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.err
+	if w.closer != nil {
+		if cerr := w.closer.Close(); err == nil {
+			err = cerr
+		}
+		w.closer = nil
+	}
+	if w.err == nil {
+		w.err = errors.New("архив закрыт")
+	}
+
+	return err
+}
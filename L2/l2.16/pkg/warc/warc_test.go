@@ -0,0 +1,313 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readAll читает все записи архива: заголовки и блоки
+func readAll(t *testing.T, r io.Reader) ([]Header, [][]byte) {
+
+	t.Helper()
+
+	reader, err := NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var headers []Header
+	var blocks [][]byte
+	for {
+		rec, err := reader.Next()
+		if err == io.EOF {
+			return headers, blocks
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		block, err := io.ReadAll(rec.Block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers = append(headers, rec.Header)
+		blocks = append(blocks, block)
+	}
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+
+	for _, name := range []string{"test.warc", "test.warc.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+
+			// архив дописывается: два открытия - две записи warcinfo
+			for i := range 2 {
+				w, err := Create(path, Header{{"software", "test"}})
+				if err != nil {
+					t.Fatal(err)
+				}
+				block := []byte("block " + strings.Repeat("x", i*10))
+				h := Header{{"WARC-Type", TypeResource}, {"WARC-Record-ID", NewRecordID()}}
+				if err := w.WriteRecord(h, bytes.NewReader(block), int64(len(block))); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			data, _ := os.ReadFile(path)
+			if isGzip := bytes.HasPrefix(data, []byte{0x1f, 0x8b}); isGzip != strings.HasSuffix(name, ".gz") {
+				t.Errorf("gzip = %v for %s", isGzip, name)
+			}
+
+			headers, blocks := readAll(t, bytes.NewReader(data))
+			if len(headers) != 4 {
+				t.Fatalf("got %d records, want 4", len(headers))
+			}
+			if headers[0].Get("WARC-Type") != TypeWarcinfo || string(blocks[0]) != "software: test\r\n" {
+				t.Errorf("warcinfo = %v %q", headers[0], blocks[0])
+			}
+			if got := headers[1].Get("WARC-Warcinfo-ID"); got != headers[0].Get("WARC-Record-ID") {
+				t.Errorf("WARC-Warcinfo-ID = %q, want id of warcinfo", got)
+			}
+			if string(blocks[3]) != "block xxxxxxxxxx" {
+				t.Errorf("second block = %q", blocks[3])
+			}
+		})
+	}
+}
+
+func TestWriterRejectsWrongLength(t *testing.T) {
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, false)
+	if err := w.WriteRecord(Header{{"WARC-Type", TypeResource}}, strings.NewReader("abc"), 5); err == nil {
+		t.Fatal("WriteRecord() with short block: no error")
+	}
+	// ошибка запоминается
+	if err := w.Close(); err == nil {
+		t.Error("Close() after failed write: no error")
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+
+	for _, content := range []string{
+		"HTTP/1.1 200 OK\r\n\r\n",
+		"WARC/1.1\r\nContent-Length: 10\r\n\r\nshort",
+		"WARC/1.1\r\nContent-Length: x\r\n\r\n",
+		"WARC/1.1\r\nWARC-Type: resource\r\n",
+	} {
+		reader, err := NewReader(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = reader.Next()
+		if err == nil {
+			_, err = reader.Next()
+		}
+		if err == nil || err == io.EOF {
+			t.Errorf("%q: error = %v, want parse error", content, err)
+		}
+	}
+}
+
+func TestRecorder(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Test", "yes")
+		if r.URL.Path == "/big" {
+			w.Write(bytes.Repeat([]byte("0123456789"), spoolMemory/5)) // больше памяти - во временный файл
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	var archive bytes.Buffer
+	w := NewWriter(&archive, false)
+	client := &http.Client{Transport: NewRecorder(w, nil)}
+
+	for _, path := range []string{"/hello?x=1", "/big"} {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// тело закрыто, не дочитанным - ответ помечается обрезанным
+	resp, err := client.Get(srv.URL + "/big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Read(make([]byte, 10))
+	resp.Body.Close()
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	headers, blocks := readAll(t, &archive)
+	if len(headers) != 9 {
+		t.Fatalf("got %d records, want 9 (request, response, metadata x3)", len(headers))
+	}
+
+	req, resp0, meta := headers[0], headers[1], headers[2]
+	if req.Get("WARC-Type") != TypeRequest || resp0.Get("WARC-Type") != TypeResponse || meta.Get("WARC-Type") != TypeMetadata {
+		t.Fatalf("record types = %s %s %s", req.Get("WARC-Type"), resp0.Get("WARC-Type"), meta.Get("WARC-Type"))
+	}
+	if req.Get("WARC-Concurrent-To") != resp0.Get("WARC-Record-ID") || meta.Get("WARC-Concurrent-To") != resp0.Get("WARC-Record-ID") {
+		t.Error("request and metadata are not linked to the response")
+	}
+	if got := resp0.Get("WARC-Target-URI"); got != srv.URL+"/hello?x=1" {
+		t.Errorf("WARC-Target-URI = %q", got)
+	}
+	if !strings.HasPrefix(string(blocks[0]), "GET /hello?x=1 HTTP/1.1\r\n") {
+		t.Errorf("request block = %q", blocks[0])
+	}
+	if !strings.Contains(string(blocks[1]), "X-Test: yes\r\n") || !strings.HasSuffix(string(blocks[1]), "\r\n\r\nhello") {
+		t.Errorf("response block = %q", blocks[1])
+	}
+	if resp0.Get("WARC-IP-Address") != "127.0.0.1" {
+		t.Errorf("WARC-IP-Address = %q", resp0.Get("WARC-IP-Address"))
+	}
+	if !strings.HasPrefix(string(blocks[2]), "fetchTimeMs: ") {
+		t.Errorf("metadata block = %q", blocks[2])
+	}
+
+	// дайджесты сходятся с содержимым, в том числе для тела из временного файла
+	for _, i := range []int{1, 4} {
+		if got, want := headers[i].Get("WARC-Block-Digest"), Digest(blocks[i]); got != want {
+			t.Errorf("record %d: block digest %s, want %s", i, got, want)
+		}
+		_, body, _ := bytes.Cut(blocks[i], []byte("\r\n\r\n"))
+		if got, want := headers[i].Get("WARC-Payload-Digest"), Digest(body); got != want {
+			t.Errorf("record %d: payload digest %s, want %s", i, got, want)
+		}
+	}
+	if n := len(blocks[4]); n < 2*spoolMemory {
+		t.Errorf("big response block is %d bytes", n)
+	}
+	if headers[4].Get("WARC-Truncated") != "" || headers[7].Get("WARC-Truncated") == "" {
+		t.Errorf("WARC-Truncated = %q (full), %q (closed early)", headers[4].Get("WARC-Truncated"), headers[7].Get("WARC-Truncated"))
+	}
+}
+
+func TestReplay(t *testing.T) {
+
+	var archive bytes.Buffer
+	w := NewWriter(&archive, true)
+	response := func(uri, status, body string, truncated bool) {
+		block := "HTTP/1.1 " + status + "\r\nContent-Type: text/plain\r\nConnection: keep-alive\r\n\r\n" + body
+		h := Header{{"WARC-Type", TypeResponse}, {"WARC-Target-URI", uri}, {"WARC-Date", "2024-01-02T03:04:05Z"}}
+		if truncated {
+			h = append(h, Field{"WARC-Truncated", "length"})
+		}
+		w.WriteRecord(h, strings.NewReader(block), int64(len(block)))
+	}
+	response("http://a.example/page?x=1", "200 OK", "first", false)
+	response("http://a.example/page?x=1", "304 Not Modified", "", false) // полный ответ важнее 304
+	response("http://a.example/doc", "200 OK", "cut", true)
+	response("http://a.example/doc", "200 OK", "whole", false)
+	response("http://b.example/page?x=1", "200 OK", "other host", false)
+	response("http://b.example/gone", "404 Not Found", "gone", false)
+
+	replay, err := NewReplay(&archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", replay.Len())
+	}
+
+	srv := httptest.NewServer(replay)
+	defer srv.Close()
+
+	tests := []struct {
+		target string // адрес в форме прокси или путь
+		code   int
+		body   string
+	}{
+		{"http://a.example/page?x=1", 200, "first"},
+		{"http://b.example/page?x=1", 200, "other host"},
+		{"/page?x=1", 200, "first"}, // по пути - первый записанный хост
+		{"http://a.example/doc", 200, "whole"},
+		{"/gone", 404, "gone"},
+		{"http://a.example/missing", 404, "нет в архиве"},
+	}
+
+	for _, tt := range tests {
+		resp := rawGet(t, srv.URL, tt.target)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.code || !strings.Contains(string(body), tt.body) {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.target, resp.StatusCode, body, tt.code, tt.body)
+		}
+		if tt.code == 200 {
+			if resp.Header.Get("Memento-Datetime") != "Tue, 02 Jan 2024 03:04:05 GMT" || resp.Header.Get("Connection") != "" {
+				t.Errorf("GET %s: headers %v", tt.target, resp.Header)
+			}
+		}
+	}
+}
+
+// rawGet отправляет запрос с заданной строкой запроса (в том числе в форме прокси)
+func rawGet(t *testing.T, server, target string) *http.Response {
+
+	t.Helper()
+
+	u, _ := url.Parse(server)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(u)}}
+	if strings.HasPrefix(target, "/") {
+		client, target = http.DefaultClient, server+target
+	}
+
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp
+}
+
+// проверка, что записанный ответ разбирается обычным HTTP-парсером
+func TestRecordedResponseIsHTTP(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush() // chunked: в записи тело должно быть уже без кодирования
+		w.Write([]byte("chunked body"))
+	}))
+	defer srv.Close()
+
+	var archive bytes.Buffer
+	w := NewWriter(&archive, false)
+	resp, err := (&http.Client{Transport: NewRecorder(w, nil)}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	_, blocks := readAll(t, &archive)
+	parsed, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(blocks[1])), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(parsed.Body)
+	if string(body) != "chunked body" {
+		t.Errorf("body = %q", body)
+	}
+}
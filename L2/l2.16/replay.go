@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"mywget/pkg/warc"
+)
+
+// ReplayConfig - аргументы команды mywget replay
+type ReplayConfig struct {
+	Listen  string
+	Archive string
+}
+
+// parseReplayArgs разбирает аргументы mywget replay [-listen адрес] <архив>
+func parseReplayArgs(args []string, output io.Writer) (ReplayConfig, error) {
+
+	config := ReplayConfig{}
+
+	fs := flag.NewFlagSet("mywget replay", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintln(output, "Использование: mywget replay [флаги] <архив.warc[.gz]>")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&config.Listen, "listen", "127.0.0.1:8080", "address to serve the archive on (use it as --proxy or open in a browser)")
+
+	if err := fs.Parse(args); err != nil {
+		return config, err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return config, errors.New("нужно указать один архив WARC")
+	}
+	config.Archive = fs.Arg(0)
+
+	return config, nil
+}
+
+// replayMain отдаёт ответы из архива WARC по HTTP до Ctrl+C, возвращает код выхода
+func replayMain(args []string) int {
+
+	config, err := parseReplayArgs(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mywget replay:", err)
+		return 1
+	}
+
+	f, err := os.Open(config.Archive)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mywget replay:", err)
+		return 1
+	}
+	replay, err := warc.NewReplay(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mywget replay: архив %s: %v\n", config.Archive, err)
+		return 1
+	}
+
+	server := &http.Server{Addr: config.Listen, Handler: replay}
+
+	// останавливаем сервер по Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Printf("архив %s: %d адресов, слушаем http://%s\n", config.Archive, replay.Len(), config.Listen)
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, "mywget replay:", err)
+		return 1
+	}
+
+	return 0
+}
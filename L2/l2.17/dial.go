@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"l2.17/pkg/proxy"
+	"l2.17/pkg/record"
+)
+
+// starttlsProtocols - протоколы, для которых поддерживается --starttls
+var starttlsProtocols = map[string]func(conn net.Conn, host string, out io.Writer) error{
+	"smtp": startTLSSMTP,
+}
+
+// dial устанавливает соединение по параметрам запуска: напрямую или через прокси,
+// затем при необходимости включает TLS (сразу или через STARTTLS). Ответы сервера,
+// полученные до STARTTLS, выводятся в out. Если rec не nil, в него пишутся открытые данные
+// сеанса (включая диалог до STARTTLS, но не сам обмен TLS)
+func dial(ctx context.Context, opts options, out io.Writer, rec *record.Writer) (net.Conn, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	var dialer proxy.Dialer = &net.Dialer{}
+	if opts.proxy != "" {
+		var err error
+		if dialer, err = proxy.FromURL(opts.proxy, dialer); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := dialer.DialContext(ctx, networkDefault, opts.address)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.tls && opts.starttls == "" {
+		return recordConn(conn, rec), nil
+	}
+
+	cfg, err := tlsConfig(opts)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if opts.starttls != "" {
+		if err := starttlsProtocols[opts.starttls](recordConn(conn, rec), cfg.ServerName, out); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("tls: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return recordConn(tlsConn, rec), nil
+}
+
+// recordConn оборачивает соединение для записи сеанса, если запись включена
+func recordConn(conn net.Conn, rec *record.Writer) net.Conn {
+
+	if rec == nil {
+		return conn
+	}
+
+	return record.Conn(conn, rec)
+}
+
+// tlsConfig собирает настройки TLS: проверку сертификата сервера (системные корни или --ca),
+// сертификат клиента (--cert и --key) и имя сервера для SNI
+func tlsConfig(opts options) (*tls.Config, error) {
+
+	host, _, err := net.SplitHostPort(opts.address)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: opts.insecure,
+	}
+	if opts.serverName != "" {
+		cfg.ServerName = opts.serverName
+	}
+
+	if opts.caFile != "" {
+		pem, err := os.ReadFile(opts.caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("в файле %s нет сертификатов в формате PEM", opts.caFile)
+		}
+	}
+
+	if opts.certFile != "" || opts.keyFile != "" {
+		if opts.certFile == "" || opts.keyFile == "" {
+			return nil, errors.New("сертификат клиента задаётся флагами --cert и --key вместе")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.certFile, opts.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// startTLSSMTP переводит SMTP-соединение в TLS (RFC 3207): приветствие сервера, EHLO, STARTTLS.
+// Ответы сервера выводятся в out, после STARTTLS клиент сам повторяет EHLO
+func startTLSSMTP(conn net.Conn, host string, out io.Writer) error {
+
+	// читаем побайтно: после ответа на STARTTLS начинается TLS, лишнего забирать нельзя
+	r := bufio.NewReaderSize(oneByteReader{conn}, 16)
+
+	if _, err := smtpResponse(r, out, 220); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(conn, "EHLO %s\r\n", clientName()); err != nil {
+		return err
+	}
+	lines, err := smtpResponse(r, out, 250)
+	if err != nil {
+		return err
+	}
+	supported := false
+	for _, line := range lines[1:] {
+		if strings.EqualFold(strings.TrimSpace(line), "STARTTLS") {
+			supported = true
+		}
+	}
+	if !supported {
+		return errors.New("сервер не поддерживает STARTTLS")
+	}
+
+	if _, err := io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+		return err
+	}
+	_, err = smtpResponse(r, out, 220)
+
+	return err
+}
+
+// smtpResponse читает (возможно многострочный) ответ SMTP и проверяет код;
+// возвращает текст строк без кода
+func smtpResponse(r *bufio.Reader, out io.Writer, code int) ([]string, error) {
+
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprint(out, line)
+		line = strings.TrimRight(line, "\r\n")
+
+		if len(line) < 3 {
+			return nil, fmt.Errorf("неверный ответ сервера %q", line)
+		}
+		got, err := strconv.Atoi(line[:3])
+		if err != nil {
+			return nil, fmt.Errorf("неверный ответ сервера %q", line)
+		}
+		if got != code {
+			return nil, fmt.Errorf("сервер ответил %q", line)
+		}
+		lines = append(lines, line[min(4, len(line)):])
+		// "250-..." - продолжение ответа, "250 ..." - последняя строка
+		if len(line) == 3 || line[3] != '-' {
+			return lines, nil
+		}
+	}
+}
+
+// clientName - имя клиента для EHLO
+func clientName() string {
+
+	if name, err := os.Hostname(); err == nil && name != "" {
+		return name
+	}
+
+	return "localhost"
+}
+
+// oneByteReader читает не больше одного байта за раз
+type oneByteReader struct {
+	r io.Reader
+}
+
+func (o oneByteReader) Read(b []byte) (int, error) {
+
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	return o.r.Read(b[:1])
+}
+
+// tlsState возвращает состояние TLS соединения, если оно защищено
+func tlsState(conn net.Conn) (tls.ConnectionState, bool) {
+
+	for conn != nil {
+		if c, ok := conn.(*tls.Conn); ok {
+			return c.ConnectionState(), true
+		}
+		inner, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			break
+		}
+		conn = inner.NetConn()
+	}
+
+	return tls.ConnectionState{}, false
+}
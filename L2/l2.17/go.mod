@@ -0,0 +1,3 @@
+module l2.17
+
+go 1.24.1
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"l2.17/pkg/record"
+)
+
+const (
+	hostDefault    = "tcpbin.com"     // хост по умолчанию для теста
+	portDefault    = "4242"           // порт по умолчанию для теста
+	timeoutDefault = 10 * time.Second // таймаут по умолчанию
+	networkDefault = "tcp"            // протокол по умолчанию
+)
+
+// startRead возвращает параметры, назначенные при запуске, либо значения по умолчанию
+func startRead() options {
+
+	// функция комментариев
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Использование: %s [--timeout=10s] [--mode=auto|line|char] [--plain] [--term=xterm]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "    [--tls [--insecure] [--ca=файл] [--cert=файл --key=файл]] [--starttls=smtp]\n")
+		fmt.Fprintf(os.Stderr, "    [--proxy=socks5://хост:порт|http://хост:порт] [--record=файл] [хост порт]\n")
+		fmt.Fprintf(os.Stderr, "       %s --replay=файл [--listen=%s]\n", os.Args[0], listenDefault)
+		fmt.Fprintf(os.Stderr, "Примеры:\n")
+		fmt.Fprintf(os.Stderr, "  %s --timeout=10s tcpbin.com 4242\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --plain --tls example.com 443\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --plain --starttls=smtp --record=smtp.log smtp.gmail.com 587\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s tcpbin.com 4242 (использует значение по умолчанию: --timeout=%v)\n",
+			os.Args[0], timeoutDefault)
+		fmt.Fprintf(os.Stderr, "  %s (использует значения по умолчанию: --timeout=%v %s:%s)\n",
+			os.Args[0], timeoutDefault, hostDefault, portDefault)
+		fmt.Fprintf(os.Stderr, "Флаги:\n")
+		flag.PrintDefaults()
+	}
+
+	// устанавливаем значения по умолчанию
+	host := hostDefault
+	port := portDefault
+	termDefault := os.Getenv("TERM")
+	if termDefault == "" {
+		termDefault = "xterm"
+	}
+
+	// парсим ввод команды
+	timeout := flag.Duration("timeout", timeoutDefault, "Таймаут соединения")
+	mode := flag.String("mode", modeAuto, "Режим ввода: auto (посимвольный, если эхо выполняет сервер), line или char")
+	plain := flag.Bool("plain", false, "Без протокола telnet: байты передаются как есть")
+	termType := flag.String("term", termDefault, "Тип терминала, сообщаемый серверу")
+	useTLS := flag.Bool("tls", false, "Соединение через TLS")
+	insecure := flag.Bool("insecure", false, "Не проверять сертификат сервера")
+	caFile := flag.String("ca", "", "Файл корневых сертификатов (PEM) для проверки сервера")
+	certFile := flag.String("cert", "", "Файл сертификата клиента (PEM)")
+	keyFile := flag.String("key", "", "Файл ключа сертификата клиента (PEM)")
+	serverName := flag.String("servername", "", "Имя сервера для SNI и проверки сертификата (по умолчанию - хост)")
+	starttls := flag.String("starttls", "", "Включить TLS командой STARTTLS протокола: smtp")
+	proxyURL := flag.String("proxy", "", "Прокси: socks5://[логин:пароль@]хост:порт или http://[логин:пароль@]хост:порт")
+	recordFile := flag.String("record", "", "Записать сеанс в файл с отметками времени")
+	replayFile := flag.String("replay", "", "Воспроизвести запись сеанса для клиентов, подключившихся к --listen")
+	listen := flag.String("listen", listenDefault, "Адрес для --replay")
+
+	// парсим флаг
+	flag.Parse()
+
+	if *mode != modeAuto && *mode != modeLine && *mode != modeChar {
+		fmt.Fprintf(os.Stderr, "Ошибка: неизвестный режим %q.\n", *mode)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *starttls != "" && starttlsProtocols[*starttls] == nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: STARTTLS не поддерживается для протокола %q.\n", *starttls)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// получаем позиционные аргументы
+	args := flag.Args()
+
+	if *replayFile != "" {
+		if len(args) != 0 {
+			fmt.Fprintf(os.Stderr, "Ошибка: с --replay хост и порт не указываются.\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+		return options{replay: *replayFile, listen: *listen}
+	}
+
+	// обрабатываем переданные аргументы
+	switch len(args) {
+	case 0:
+		// ничего не передано - используем значения по умолчанию
+		fmt.Printf("Используются значения по умолчанию: --timeout=%v %s:%s\n", *timeout, host, port)
+	case 2:
+		// только хост и порт
+		host = args[0]
+		port = args[1]
+	default:
+		// другое количество аргументов
+		fmt.Fprintf(os.Stderr, "Ошибка: не корректные аргументы.\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	return options{
+		address:  net.JoinHostPort(host, port), // формируем адрес
+		timeout:  *timeout,
+		plain:    *plain,
+		mode:     *mode,
+		termType: *termType,
+
+		tls:        *useTLS,
+		insecure:   *insecure,
+		caFile:     *caFile,
+		certFile:   *certFile,
+		keyFile:    *keyFile,
+		serverName: *serverName,
+		starttls:   *starttls,
+		proxy:      *proxyURL,
+		record:     *recordFile,
+	}
+}
+
+// sigScan слушает сигналы отмены
+func sigScan(ctx context.Context, cancel context.CancelFunc, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+
+	// заводим и регистрируем канал для обработки Ctrl+C
+	// (в посимвольном режиме Ctrl+C уходит серверу и сигнала не будет)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT)
+	defer signal.Stop(sigChan)
+
+	select {
+	case <-ctx.Done():
+		return
+	case sig := <-sigChan:
+		fmt.Printf("sigScan: Сигнал завершения программы: %v.\n", sig)
+		cancel()
+		return
+	}
+}
+
+// resizeScan сообщает серверу новый размер окна терминала
+func resizeScan(ctx context.Context, s *session, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+
+	if s.term == nil || s.telnet == nil {
+		return
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	notifyResize(sigChan)
+	defer signal.Stop(sigChan)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigChan:
+			width, height := s.term.size()
+			s.telnet.SetWindowSize(width, height)
+		}
+	}
+}
+
+// replay воспроизводит запись сеанса до Ctrl+C
+func replay(opts options) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go sigScan(ctx, cancel, &wg)
+
+	if err := runReplay(ctx, opts.replay, opts.listen, os.Stdout); err != nil {
+		fmt.Printf("Ошибка воспроизведения: %v.\n", err)
+	}
+	cancel()
+	wg.Wait()
+
+	fmt.Println("Программа завершена.")
+}
+
+func main() {
+
+	// считываем данные запуска
+	opts := startRead()
+
+	if opts.replay != "" {
+		replay(opts)
+		return
+	}
+
+	// открываем файл записи сеанса
+	var rec *record.Writer
+	if opts.record != "" {
+		f, err := os.Create(opts.record)
+		if err != nil {
+			fmt.Printf("Не удалось создать файл записи: %v.\n", err)
+			return
+		}
+		defer f.Close()
+		rec = record.NewWriter(f, opts.address)
+	}
+
+	// устанавливаем соединение
+	conn, err := dial(context.Background(), opts, os.Stdout, rec)
+	if err != nil {
+		fmt.Printf("Не удалось подключиться по указанному адресу %s, ошибка:%v.\n", opts.address, err)
+		return
+	}
+	defer conn.Close()
+
+	fmt.Printf("Подключено к %s\n", opts.address)
+	fmt.Println("Ctrl+] - командная строка клиента, Ctrl+D для выхода, Ctrl+C для прерывания")
+
+	s := newSession(conn, opts, os.Stdin, os.Stdout, openTerminal(int(os.Stdin.Fd())))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	// запускаем обработку сигналов отмены
+	wg.Add(1)
+	go sigScan(ctx, cancel, &wg)
+
+	// следим за размером окна
+	wg.Add(1)
+	go resizeScan(ctx, s, &wg)
+
+	// передаём данные, пока сеанс не завершится
+	s.run(ctx, cancel)
+	cancel()
+
+	// дожидаемся завершения
+	wg.Wait()
+
+	fmt.Println("Программа завершена.")
+}
@@ -1,4 +1,6 @@
-/* ВАРИАНТ №2 - решение задачи l2.17 */
+//go:build ignore
+
+/* ВАРИАНТ №2 - решение задачи l2.17 (запуск: go run main2.go [хост порт]) */
 
 package main
 
@@ -12,6 +14,8 @@ import (
 	"sync"
 	"syscall"
 	"time"
+
+	"l2.17/pkg/telnet"
 )
 
 const (
@@ -83,6 +87,10 @@ func main() {
 	}
 	defer conn.Close()
 
+	// команды протокола telnet (согласование опций) обрабатывает telnet.Conn,
+	// в вывод попадают только данные
+	tc := telnet.NewConn(conn, telnet.Config{})
+
 	done := make(chan struct{}) // канал для закрытия горутины обработки сигналов
 
 	// обеспечиваем закрытие соединения, хотя
@@ -123,7 +131,7 @@ func main() {
 		defer wg.Done()
 		// перекладываем данные в conn из os.Stdin
 		// при любых ошибках завершаем работу
-		_, _ = io.Copy(conn, os.Stdin)
+		_, _ = io.Copy(tc, os.Stdin)
 		closeOnce.Do(closeConn)
 	}()
 
@@ -133,7 +141,7 @@ func main() {
 		defer wg.Done()
 		// перекладываем данные в os.Stdin из conn
 		// при любых ошибках завершаем работу
-		_, _ = io.Copy(os.Stdout, conn)
+		_, _ = io.Copy(os.Stdout, tc)
 		closeOnce.Do(closeConn)
 	}()
 
@@ -0,0 +1,714 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"l2.17/pkg/record"
+	"l2.17/pkg/telnet"
+)
+
+// syncBuffer - буфер вывода, который пишет сеанс и читает тест
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// fakeServer - telnet-сервер в том же процессе: отправляет приветствие и отвечает "echo: строка"
+// на каждую строку, заканчивающуюся CR LF. Всё полученное копится в received
+type fakeServer struct {
+	addr     string
+	received syncBuffer
+	conn     chan net.Conn
+}
+
+func startFakeServer(t *testing.T, greeting string) *fakeServer {
+
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	srv := &fakeServer{addr: ln.Addr().String(), conn: make(chan net.Conn, 1)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		srv.conn <- conn
+		conn.Write([]byte(greeting))
+
+		var line []byte
+		// команды протокола при сборке строки пропускаем, IAC IAC - байт 255
+		iac, skip, inSB := false, 0, false
+		buf := make([]byte, 256)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			srv.received.Write(buf[:n])
+			for _, b := range buf[:n] {
+				switch {
+				case skip > 0:
+					skip--
+					continue
+				case iac:
+					iac = false
+					switch b {
+					case telnet.WILL, telnet.WONT, telnet.DO, telnet.DONT:
+						skip = 1
+						continue
+					case telnet.SB:
+						inSB = true
+						continue
+					case telnet.SE:
+						inSB = false
+						continue
+					case telnet.IAC:
+						if inSB {
+							continue
+						}
+					default:
+						continue
+					}
+				case b == telnet.IAC:
+					iac = true
+					continue
+				case inSB:
+					continue
+				}
+				line = append(line, b)
+				if bytes.HasSuffix(line, []byte("\r\n")) {
+					conn.Write(bytes.ReplaceAll([]byte("echo: "+string(line)), []byte{telnet.IAC}, []byte{telnet.IAC, telnet.IAC}))
+					line = line[:0]
+				}
+			}
+		}
+	}()
+
+	return srv
+}
+
+// waitFor ждёт выполнения условия
+func waitFor(t *testing.T, what string, cond func() bool) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("не дождались: %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// startSession подключается к серверу и запускает сеанс; ввод пишется в возвращаемый pipe
+func startSession(t *testing.T, srv *fakeServer, opts options) (*session, *io.PipeWriter, *syncBuffer, chan struct{}) {
+
+	t.Helper()
+
+	conn, err := net.Dial("tcp", srv.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.address = srv.addr
+
+	inR, inW := io.Pipe()
+	out := &syncBuffer{}
+	s := newSession(conn, opts, inR, out, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.run(ctx, cancel)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		inW.Close()
+		<-done
+	})
+
+	return s, inW, out, done
+}
+
+func cmd(b ...byte) string {
+	return string(b)
+}
+
+func TestSessionNegotiationAndEscape(t *testing.T) {
+
+	greeting := cmd(telnet.IAC, telnet.WILL, byte(telnet.Echo), telnet.IAC, telnet.WILL, byte(telnet.SuppressGoAhead)) +
+		cmd(telnet.IAC, telnet.DO, byte(telnet.TerminalType)) +
+		cmd(telnet.IAC, telnet.SB, byte(telnet.TerminalType), 1, telnet.IAC, telnet.SE) +
+		"Welcome\r\n"
+	srv := startFakeServer(t, greeting)
+	s, in, out, done := startSession(t, srv, options{mode: modeLine, termType: "xterm"})
+
+	// команды протокола не попадают в вывод, клиент отвечает на согласование
+	waitFor(t, "приветствие", func() bool { return strings.Contains(out.String(), "Welcome") })
+	if strings.ContainsRune(out.String(), rune(telnet.IAC)) || strings.Contains(out.String(), "\xff") {
+		t.Errorf("output contains IAC: %q", out.String())
+	}
+	ttype := cmd(telnet.IAC, telnet.SB, byte(telnet.TerminalType), 0) + "xterm" + cmd(telnet.IAC, telnet.SE)
+	waitFor(t, "ответ на TERMINAL-TYPE", func() bool { return strings.Contains(srv.received.String(), ttype) })
+	want := cmd(telnet.IAC, telnet.DO, byte(telnet.Echo), telnet.IAC, telnet.DO, byte(telnet.SuppressGoAhead),
+		telnet.IAC, telnet.WILL, byte(telnet.TerminalType))
+	if !strings.HasPrefix(srv.received.String(), want) {
+		t.Errorf("negotiation = %v, want prefix %v", []byte(srv.received.String()), []byte(want))
+	}
+
+	// построчный режим задан явно: перевод строки уходит как CR LF, IAC удваивается
+	in.Write([]byte("hello \xff\n"))
+	waitFor(t, "эхо строки", func() bool { return strings.Contains(out.String(), "echo: hello \xff\r\n") })
+	if !strings.Contains(srv.received.String(), "hello \xff\xff\r\n") {
+		t.Errorf("received = %q", srv.received.String())
+	}
+	if s.isCharMode() {
+		t.Error("line mode expected")
+	}
+
+	// Ctrl+] открывает командную строку, текст после него - команда клиента
+	in.Write([]byte("partial\x1d"))
+	waitFor(t, "приглашение", func() bool { return strings.Contains(out.String(), "telnet> ") })
+	in.Write([]byte("status\n"))
+	waitFor(t, "status", func() bool { return strings.Contains(out.String(), "Escape-символ: ^]") })
+	for _, line := range []string{
+		"Подключено к " + srv.addr,
+		"Режим ввода: построчный (line)",
+		"Опции сервера: ECHO, SUPPRESS-GO-AHEAD",
+		"Опции клиента: TERMINAL-TYPE",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("status output has no %q: %q", line, out.String())
+		}
+	}
+
+	in.Write([]byte("\x1dsend brk\n"))
+	waitFor(t, "IAC BRK", func() bool { return strings.Contains(srv.received.String(), cmd(telnet.IAC, telnet.BRK)) })
+	if strings.Contains(srv.received.String(), "status") || !strings.Contains(srv.received.String(), "partial") {
+		t.Errorf("received = %q", srv.received.String())
+	}
+
+	in.Write([]byte("\x1dbogus\n"))
+	waitFor(t, "ошибка команды", func() bool { return strings.Contains(out.String(), `неизвестная команда "bogus"`) })
+
+	// режим можно сменить: в auto при эхе сервера ввод посимвольный
+	in.Write([]byte("\x1dmode auto\n"))
+	waitFor(t, "посимвольный режим", s.isCharMode)
+
+	in.Write([]byte("\x1dclose\n"))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not finish after close")
+	}
+	if !strings.Contains(out.String(), "Соединение закрыто.") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestSessionPlain(t *testing.T) {
+
+	// без протокола байты IAC выводятся как есть, перевод строки не меняется
+	srv := startFakeServer(t, cmd(telnet.IAC, telnet.DO, byte(telnet.NAWS))+"raw\n")
+	s, in, out, done := startSession(t, srv, options{mode: modeAuto, plain: true})
+
+	waitFor(t, "данные", func() bool { return strings.Contains(out.String(), "raw\n") })
+	if !strings.HasPrefix(out.String(), cmd(telnet.IAC, telnet.DO, byte(telnet.NAWS))) {
+		t.Errorf("output = %q", out.String())
+	}
+
+	in.Write([]byte("ping\n\x1dsend brk\n"))
+	waitFor(t, "отказ send", func() bool { return strings.Contains(out.String(), "недоступны в режиме --plain") })
+	waitFor(t, "строка ping", func() bool { return srv.received.String() == "ping\n" })
+	if s.isCharMode() {
+		t.Error("line mode expected")
+	}
+
+	// сервер закрыл соединение - сеанс завершается
+	(<-srv.conn).Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not finish")
+	}
+	if !strings.Contains(out.String(), "Соединение закрыто сервером.") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestSessionEOF(t *testing.T) {
+
+	srv := startFakeServer(t, "hi\r\n")
+	_, in, out, done := startSession(t, srv, options{mode: modeAuto})
+
+	waitFor(t, "приветствие", func() bool { return strings.Contains(out.String(), "hi") })
+	in.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session did not finish after EOF")
+	}
+	if !strings.Contains(out.String(), "Выход из программы.") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+// testPKI - удостоверяющий центр и выпущенные им сертификаты для тестов TLS
+type testPKI struct {
+	dir    string
+	caFile string
+	pool   *x509.CertPool
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(der)
+
+	p := &testPKI{dir: t.TempDir(), pool: x509.NewCertPool(), ca: ca, caKey: key}
+	p.pool.AddCert(ca)
+	p.caFile = filepath.Join(p.dir, "ca.pem")
+	writePEM(t, p.caFile, "CERTIFICATE", der)
+
+	return p
+}
+
+// issue выпускает сертификат с именем name и возвращает его вместе с путями к PEM-файлам
+func (p *testPKI) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (tls.Certificate, string, string) {
+
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.ca, &key.PublicKey, p.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(p.dir, name+".pem")
+	keyFile := filepath.Join(p.dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, certFile, keyFile
+}
+
+func writePEM(t *testing.T, file, typ string, der []byte) {
+
+	t.Helper()
+
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveOnce принимает одно соединение и обрабатывает его функцией serve
+func serveOnce(t *testing.T, serve func(conn net.Conn)) string {
+
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serve(conn)
+	}()
+
+	return ln.Addr().String()
+}
+
+// tlsEcho - TLS-сервер: отвечает "hello, <CN клиента>"
+func tlsEcho(t *testing.T, cfg *tls.Config) string {
+
+	return serveOnce(t, func(conn net.Conn) {
+		tc := tls.Server(conn, cfg)
+		if err := tc.Handshake(); err != nil {
+			return
+		}
+		client := "anonymous"
+		if certs := tc.ConnectionState().PeerCertificates; len(certs) > 0 {
+			client = certs[0].Subject.CommonName
+		}
+		io.WriteString(tc, "hello, "+client+"\r\n")
+		tc.Close()
+	})
+}
+
+func TestDialTLS(t *testing.T) {
+
+	pki := newTestPKI(t)
+	serverCert, _, _ := pki.issue(t, "server.test", x509.ExtKeyUsageServerAuth)
+	_, clientCert, clientKey := pki.issue(t, "client.test", x509.ExtKeyUsageClientAuth)
+
+	tests := []struct {
+		name    string
+		opts    options
+		mtls    bool // сервер требует сертификат клиента
+		want    string
+		wantErr string
+	}{
+		{name: "CA", opts: options{caFile: pki.caFile, serverName: "server.test"}, want: "hello, anonymous"},
+		{name: "неизвестный CA", opts: options{serverName: "server.test"}, wantErr: "certificate"},
+		{name: "insecure", opts: options{insecure: true}, want: "hello, anonymous"},
+		{name: "чужое имя", opts: options{caFile: pki.caFile, serverName: "other.test"}, wantErr: "other.test"},
+		{name: "сертификат клиента", mtls: true,
+			opts: options{caFile: pki.caFile, serverName: "server.test", certFile: clientCert, keyFile: clientKey},
+			want: "hello, client.test"},
+		{name: "без ключа", opts: options{insecure: true, certFile: clientCert}, wantErr: "--cert и --key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &tls.Config{Certificates: []tls.Certificate{serverCert}}
+			if tt.mtls {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = pki.pool
+			}
+			opts := tt.opts
+			opts.address = tlsEcho(t, cfg)
+			opts.tls = true
+			opts.timeout = 5 * time.Second
+
+			conn, err := dial(context.Background(), opts, io.Discard, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			data, _ := io.ReadAll(conn)
+			if got := strings.TrimSpace(string(data)); got != tt.want {
+				t.Errorf("data = %q, want %q", got, tt.want)
+			}
+			if _, ok := tlsState(conn); !ok {
+				t.Error("tlsState: connection is not TLS")
+			}
+		})
+	}
+}
+
+// smtpServer - SMTP-сервер с поддержкой STARTTLS (если starttls): после перехода
+// на TLS отвечает на EHLO и QUIT
+func smtpServer(t *testing.T, cfg *tls.Config, starttls bool) string {
+
+	return serveOnce(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		io.WriteString(conn, "220 mail.test ESMTP\r\n")
+		if line, _ := r.ReadString('\n'); !strings.HasPrefix(line, "EHLO ") {
+			return
+		}
+		if !starttls {
+			io.WriteString(conn, "250-mail.test\r\n250 8BITMIME\r\n")
+			r.ReadString('\n')
+			return
+		}
+		io.WriteString(conn, "250-mail.test\r\n250-PIPELINING\r\n250 STARTTLS\r\n")
+		if line, _ := r.ReadString('\n'); line != "STARTTLS\r\n" {
+			return
+		}
+		io.WriteString(conn, "220 2.0.0 Ready to start TLS\r\n")
+
+		tc := tls.Server(conn, cfg)
+		tr := bufio.NewReader(tc)
+		for {
+			line, err := tr.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if strings.HasPrefix(line, "QUIT") {
+				io.WriteString(tc, "221 bye\r\n")
+				tc.Close()
+				return
+			}
+			io.WriteString(tc, "250 secure "+strings.TrimSpace(line)+"\r\n")
+		}
+	})
+}
+
+func TestDialSTARTTLS(t *testing.T) {
+
+	pki := newTestPKI(t)
+	serverCert, _, _ := pki.issue(t, "mail.test", x509.ExtKeyUsageServerAuth)
+	cfg := &tls.Config{Certificates: []tls.Certificate{serverCert}}
+
+	recFile := filepath.Join(t.TempDir(), "smtp.log")
+	f, err := os.Create(recFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	opts := options{
+		address:    smtpServer(t, cfg, true),
+		starttls:   "smtp",
+		caFile:     pki.caFile,
+		serverName: "mail.test",
+		timeout:    5 * time.Second,
+	}
+	var out bytes.Buffer
+	conn, err := dial(context.Background(), opts, &out, record.NewWriter(f, opts.address))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, line := range []string{"220 mail.test ESMTP", "250 STARTTLS", "220 2.0.0 Ready to start TLS"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output has no %q: %q", line, out.String())
+		}
+	}
+
+	io.WriteString(conn, "EHLO me\r\nQUIT\r\n")
+	data, _ := io.ReadAll(conn)
+	if string(data) != "250 secure EHLO me\r\n221 bye\r\n" {
+		t.Errorf("data = %q", data)
+	}
+
+	// в записи - диалог до STARTTLS и открытые данные после него, но не обмен TLS
+	events, err := record.Read(strings.NewReader(readFile(t, recFile)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent, received strings.Builder
+	for _, ev := range events {
+		if ev.Dir == record.Sent {
+			sent.Write(ev.Data)
+		} else {
+			received.Write(ev.Data)
+		}
+	}
+	if !strings.HasPrefix(sent.String(), "EHLO ") || !strings.HasSuffix(sent.String(), "STARTTLS\r\nEHLO me\r\nQUIT\r\n") {
+		t.Errorf("sent = %q", sent.String())
+	}
+	if !strings.HasPrefix(received.String(), "220 mail.test ESMTP\r\n") || !strings.HasSuffix(received.String(), "221 bye\r\n") {
+		t.Errorf("received = %q", received.String())
+	}
+
+	// сервер без STARTTLS
+	opts.address = smtpServer(t, cfg, false)
+	if _, err := dial(context.Background(), opts, io.Discard, nil); err == nil || !strings.Contains(err.Error(), "не поддерживает STARTTLS") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func readFile(t *testing.T, file string) string {
+
+	t.Helper()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestDialProxy(t *testing.T) {
+
+	// HTTP-прокси соединяется с сервером сам и связывает соединения
+	srv := startFakeServer(t, "Welcome\r\n")
+	proxyAddr := serveOnce(t, func(conn net.Conn) {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		target, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+			return
+		}
+		defer target.Close()
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\n\r\n")
+		go io.Copy(target, conn)
+		io.Copy(conn, target)
+	})
+
+	opts := options{address: srv.addr, proxy: "http://" + proxyAddr, timeout: 5 * time.Second, mode: modeLine}
+	conn, err := dial(context.Background(), opts, io.Discard, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inR, in := io.Pipe()
+	out := &syncBuffer{}
+	s := newSession(conn, opts, inR, out, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.run(ctx, cancel)
+		close(done)
+	}()
+	defer func() {
+		in.Close()
+		<-done
+	}()
+
+	waitFor(t, "приветствие", func() bool { return strings.Contains(out.String(), "Welcome") })
+	in.Write([]byte("\x1dstatus\n"))
+	waitFor(t, "status", func() bool { return strings.Contains(out.String(), "Прокси: http://"+proxyAddr) })
+}
+
+func TestRecordReplay(t *testing.T) {
+
+	// записываем сеанс с сервером
+	srv := startFakeServer(t, "Welcome\r\n")
+	recFile := filepath.Join(t.TempDir(), "session.log")
+	f, err := os.Create(recFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := options{address: srv.addr, timeout: 5 * time.Second, mode: modeLine, record: recFile}
+	conn, err := dial(context.Background(), opts, io.Discard, record.NewWriter(f, opts.address))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inR, in := io.Pipe()
+	out := &syncBuffer{}
+	s := newSession(conn, opts, inR, out, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.run(ctx, cancel)
+		close(done)
+	}()
+
+	waitFor(t, "приветствие", func() bool { return strings.Contains(out.String(), "Welcome") })
+	in.Write([]byte("ping\n"))
+	waitFor(t, "эхо", func() bool { return strings.Contains(out.String(), "echo: ping") })
+	in.Write([]byte("\x1dstatus\n"))
+	waitFor(t, "status", func() bool { return strings.Contains(out.String(), "Запись сеанса: "+recFile) })
+	in.Close()
+	<-done
+	f.Close()
+
+	text := readFile(t, recFile)
+	if !strings.Contains(text, `< "Welcome\r\n"`) || !strings.Contains(text, `> "ping\r\n"`) {
+		t.Errorf("record:\n%s", text)
+	}
+
+	// воспроизводим запись: клиент получает то же, что и от сервера
+	replayOut := &syncBuffer{}
+	rctx, rcancel := context.WithCancel(context.Background())
+	rdone := make(chan error, 1)
+	go func() { rdone <- runReplay(rctx, recFile, "127.0.0.1:0", replayOut) }()
+
+	var addr string
+	waitFor(t, "адрес воспроизведения", func() bool {
+		_, after, ok := strings.Cut(replayOut.String(), " на ")
+		addr = strings.TrimSpace(after)
+		return ok && strings.HasSuffix(after, "\n")
+	})
+
+	client, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(client, "typed\r\n")
+	data, _ := io.ReadAll(client)
+	client.Close()
+	if string(data) != "Welcome\r\necho: ping\r\n" {
+		t.Errorf("replayed = %q", data)
+	}
+	waitFor(t, "ввод клиента", func() bool { return strings.Contains(replayOut.String(), `> "typed\r\n"`) })
+
+	rcancel()
+	if err := <-rdone; err != nil {
+		t.Fatal(err)
+	}
+}
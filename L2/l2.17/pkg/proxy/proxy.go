@@ -0,0 +1,319 @@
+// Package proxy устанавливает TCP-соединения через прокси-серверы:
+// SOCKS5 (RFC 1928, вход по логину и паролю - RFC 1929) и HTTP CONNECT
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Dialer устанавливает соединения; *net.Dialer ему удовлетворяет
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// FromURL возвращает Dialer, соединяющийся через прокси, заданный адресом вида
+// socks5://[логин:пароль@]хост:порт или http://[логин:пароль@]хост:порт;
+// до самого прокси соединение устанавливает forward
+func FromURL(rawURL string, forward Dialer) (Dialer, error) {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy: в адресе %q нет хоста", rawURL)
+	}
+
+	var user, password string
+	if u.User != nil {
+		user = u.User.Username()
+		password, _ = u.User.Password()
+	}
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		return &socks5{address: withPort(u.Host, "1080"), user: user, password: password, forward: forward}, nil
+	case "http":
+		return &httpConnect{address: withPort(u.Host, "8080"), user: user, password: password, forward: forward}, nil
+	default:
+		return nil, fmt.Errorf("proxy: неподдерживаемая схема %q (socks5 или http)", u.Scheme)
+	}
+}
+
+// withPort добавляет порт по умолчанию к адресу без порта
+func withPort(host, port string) string {
+
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+// handshake выполняет согласование с прокси на соединении conn с учётом срока ctx
+func handshake(ctx context.Context, conn net.Conn, f func() error) error {
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	// отмена контекста прерывает ожидание ответа прокси
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	if err := f(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SOCKS5
+
+const (
+	socksVersion     = 5
+	socksAuthNone    = 0
+	socksAuthPass    = 2
+	socksAuthNoMatch = 0xff
+	socksConnect     = 1
+	socksIPv4        = 1
+	socksDomain      = 3
+	socksIPv6        = 4
+)
+
+// socksReplies - описания кодов ответа SOCKS5
+var socksReplies = map[byte]string{
+	1: "общая ошибка сервера",
+	2: "соединение запрещено правилами",
+	3: "сеть недоступна",
+	4: "хост недоступен",
+	5: "в соединении отказано",
+	6: "истёк TTL",
+	7: "команда не поддерживается",
+	8: "тип адреса не поддерживается",
+}
+
+type socks5 struct {
+	address        string
+	user, password string
+	forward        Dialer
+}
+
+func (s *socks5) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("socks5: неверный порт %q", portStr)
+	}
+
+	conn, err := s.forward.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return nil, err
+	}
+
+	err = handshake(ctx, conn, func() error { return s.connect(conn, host, uint16(port)) })
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("socks5 %s: %w", s.address, err)
+	}
+
+	return conn, nil
+}
+
+// connect выбирает способ входа и просит прокси соединиться с host:port
+func (s *socks5) connect(conn net.Conn, host string, port uint16) error {
+
+	methods := []byte{socksAuthNone}
+	if s.user != "" {
+		methods = []byte{socksAuthPass}
+	}
+	greeting := append([]byte{socksVersion, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socksVersion {
+		return fmt.Errorf("неверная версия протокола %d", reply[0])
+	}
+	switch reply[1] {
+	case socksAuthNone:
+	case socksAuthPass:
+		if err := s.authenticate(conn); err != nil {
+			return err
+		}
+	case socksAuthNoMatch:
+		return errors.New("прокси не принял ни один способ входа")
+	default:
+		return fmt.Errorf("неподдерживаемый способ входа %d", reply[1])
+	}
+
+	// адрес передаём как есть: имя хоста разрешает прокси
+	req := []byte{socksVersion, socksConnect, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("слишком длинное имя хоста %q", host)
+		}
+		req = append(req, socksDomain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socksIPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socksIPv6)
+		req = append(req, ip.To16()...)
+	}
+	req = binary.BigEndian.AppendUint16(req, port)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// ответ: VER REP RSV ATYP BND.ADDR BND.PORT
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return err
+	}
+	if head[1] != 0 {
+		if msg, ok := socksReplies[head[1]]; ok {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("ошибка %d", head[1])
+	}
+
+	var skip int
+	switch head[3] {
+	case socksIPv4:
+		skip = net.IPv4len
+	case socksIPv6:
+		skip = net.IPv6len
+	case socksDomain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		skip = int(l[0])
+	default:
+		return fmt.Errorf("неизвестный тип адреса %d", head[3])
+	}
+	_, err := io.ReadFull(conn, make([]byte, skip+2))
+
+	return err
+}
+
+// authenticate - вход по логину и паролю (RFC 1929)
+func (s *socks5) authenticate(conn net.Conn) error {
+
+	if len(s.user) > 255 || len(s.password) > 255 {
+		return errors.New("слишком длинный логин или пароль")
+	}
+
+	req := []byte{1, byte(len(s.user))}
+	req = append(req, s.user...)
+	req = append(req, byte(len(s.password)))
+	req = append(req, s.password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0 {
+		return errors.New("неверный логин или пароль")
+	}
+
+	return nil
+}
+
+// HTTP CONNECT
+
+type httpConnect struct {
+	address        string
+	user, password string
+	forward        Dialer
+}
+
+func (h *httpConnect) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+
+	conn, err := h.forward.DialContext(ctx, "tcp", h.address)
+	if err != nil {
+		return nil, err
+	}
+
+	var result net.Conn
+	err = handshake(ctx, conn, func() error {
+		var err error
+		result, err = h.connect(conn, address)
+		return err
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("http-прокси %s: %w", h.address, err)
+	}
+
+	return result, nil
+}
+
+// connect отправляет запрос CONNECT и читает ответ прокси
+func (h *httpConnect) connect(conn net.Conn, address string) (net.Conn, error) {
+
+	req := "CONNECT " + address + " HTTP/1.1\r\nHost: " + address + "\r\n"
+	if h.user != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(h.user + ":" + h.password))
+		req += "Proxy-Authorization: Basic " + auth + "\r\n"
+	}
+	req += "\r\n"
+	if _, err := io.WriteString(conn, req); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return nil, err
+	}
+	// тело ответа не читаем: после успешного CONNECT дальше идут данные сервера
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("ответ %s", resp.Status)
+	}
+
+	// сервер мог начать передачу сразу за ответом прокси - прочитанное не теряем
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+
+	return conn, nil
+}
+
+// bufferedConn - соединение, часть входящих данных которого уже прочитана в буфер
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
@@ -0,0 +1,251 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listen запускает сервер, обрабатывающий одно соединение функцией serve
+func listen(t *testing.T, serve func(conn net.Conn)) string {
+
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serve(conn)
+	}()
+
+	return ln.Addr().String()
+}
+
+// socksServer - SOCKS5-прокси: проверяет логин и пароль (если заданы), запоминает запрошенный адрес,
+// отвечает кодом rep и при успехе пишет клиенту "hello"
+func socksServer(t *testing.T, user, password string, rep byte, target chan<- string) string {
+
+	return listen(t, func(conn net.Conn) {
+		head := make([]byte, 2)
+		io.ReadFull(conn, head)
+		methods := make([]byte, head[1])
+		io.ReadFull(conn, methods)
+
+		if user == "" {
+			conn.Write([]byte{5, 0})
+		} else {
+			conn.Write([]byte{5, 2})
+			b := make([]byte, 2)
+			io.ReadFull(conn, b)
+			u := make([]byte, b[1])
+			io.ReadFull(conn, u)
+			io.ReadFull(conn, b[:1])
+			p := make([]byte, b[0])
+			io.ReadFull(conn, p)
+			if string(u) != user || string(p) != password {
+				conn.Write([]byte{1, 1})
+				return
+			}
+			conn.Write([]byte{1, 0})
+		}
+
+		req := make([]byte, 4)
+		io.ReadFull(conn, req)
+		var host string
+		switch req[3] {
+		case 1:
+			ip := make([]byte, 4)
+			io.ReadFull(conn, ip)
+			host = net.IP(ip).String()
+		case 3:
+			l := make([]byte, 1)
+			io.ReadFull(conn, l)
+			name := make([]byte, l[0])
+			io.ReadFull(conn, name)
+			host = string(name)
+		}
+		port := make([]byte, 2)
+		io.ReadFull(conn, port)
+		target <- net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+
+		conn.Write([]byte{5, rep, 0, 1, 127, 0, 0, 1, 0, 80})
+		if rep == 0 {
+			conn.Write([]byte("hello"))
+		}
+	})
+}
+
+func dial(t *testing.T, proxyURL, address string) (net.Conn, error) {
+
+	t.Helper()
+
+	d, err := FromURL(proxyURL, &net.Dialer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return d.DialContext(ctx, "tcp", address)
+}
+
+func readAll(t *testing.T, conn net.Conn) string {
+
+	t.Helper()
+
+	defer conn.Close()
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestSOCKS5(t *testing.T) {
+
+	tests := []struct {
+		name, user, password, url, address, wantTarget, wantErr string
+		rep                                                     byte
+	}{
+		{name: "имя хоста", url: "socks5://%s", address: "example.com:25", wantTarget: "example.com:25"},
+		{name: "IPv4", url: "socks5://%s", address: "10.0.0.1:6379", wantTarget: "10.0.0.1:6379"},
+		{name: "логин", user: "u", password: "p", url: "socks5://u:p@%s", address: "h:1", wantTarget: "h:1"},
+		{name: "неверный пароль", user: "u", password: "p", url: "socks5://u:x@%s", address: "h:1",
+			wantErr: "неверный логин или пароль"},
+		{name: "отказ", rep: 5, url: "socks5://%s", address: "h:1", wantTarget: "h:1", wantErr: "в соединении отказано"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := make(chan string, 1)
+			addr := socksServer(t, tt.user, tt.password, tt.rep, target)
+
+			conn, err := dial(t, strings.Replace(tt.url, "%s", addr, 1), tt.address)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := readAll(t, conn); got != "hello" {
+					t.Errorf("data = %q", got)
+				}
+			}
+			if tt.wantTarget != "" {
+				if got := <-target; got != tt.wantTarget {
+					t.Errorf("target = %q, want %q", got, tt.wantTarget)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPConnect(t *testing.T) {
+
+	requests := make(chan *http.Request, 1)
+	addr := listen(t, func(conn net.Conn) {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		requests <- req
+		// данные сервера идут в том же пакете, что и ответ прокси
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n220 smtp ready\r\n"))
+	})
+
+	conn, err := dial(t, "http://user:secret@"+addr, "mail.example.com:25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, conn); got != "220 smtp ready\r\n" {
+		t.Errorf("data = %q", got)
+	}
+
+	req := <-requests
+	if req.Method != http.MethodConnect || req.Host != "mail.example.com:25" {
+		t.Errorf("request = %s %s", req.Method, req.Host)
+	}
+	if user, password, ok := parseProxyAuth(req); !ok || user != "user" || password != "secret" {
+		t.Errorf("auth = %q %q %v", user, password, ok)
+	}
+}
+
+// parseProxyAuth разбирает заголовок Proxy-Authorization
+func parseProxyAuth(req *http.Request) (string, string, bool) {
+
+	r := &http.Request{Header: http.Header{"Authorization": req.Header["Proxy-Authorization"]}}
+
+	return r.BasicAuth()
+}
+
+func TestHTTPConnectRefused(t *testing.T) {
+
+	addr := listen(t, func(conn net.Conn) {
+		http.ReadRequest(bufio.NewReader(conn))
+		conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n"))
+	})
+
+	_, err := dial(t, "http://"+addr, "h:1")
+	if err == nil || !strings.Contains(err.Error(), "407") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+
+	// прокси принимает соединение и молчит
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+	addr := listen(t, func(conn net.Conn) { <-block })
+
+	d, err := FromURL("socks5://"+addr, &net.Dialer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := d.DialContext(ctx, "tcp", "h:1"); err == nil {
+		t.Fatal("expected timeout")
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("timeout took %v", time.Since(start))
+	}
+}
+
+func TestFromURL(t *testing.T) {
+
+	for _, bad := range []string{"ftp://h:1", "socks5://", "://x"} {
+		if _, err := FromURL(bad, &net.Dialer{}); err == nil {
+			t.Errorf("FromURL(%q): expected error", bad)
+		}
+	}
+
+	d, err := FromURL("socks5://proxy", &net.Dialer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.(*socks5).address; got != "proxy:1080" {
+		t.Errorf("address = %q", got)
+	}
+}
@@ -0,0 +1,219 @@
+// Package record записывает сеанс связи в текстовый файл с отметками времени
+// и воспроизводит запись, играя роль сервера.
+//
+// Формат файла: строки-комментарии начинаются с '#', каждая запись события имеет вид
+//
+//	<секунды от начала> <направление> <данные в кавычках Go>
+//
+// где направление '>' - от клиента серверу, '<' - от сервера клиенту, например
+//
+//	0.031245 < "220 mail.example.com ESMTP\r\n"
+package record
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Direction - направление передачи данных
+type Direction byte
+
+const (
+	Sent     Direction = '>' // от клиента серверу
+	Received Direction = '<' // от сервера клиенту
+)
+
+// Event - событие записи: данные, переданные в одну сторону
+type Event struct {
+	Offset time.Duration // время от начала сеанса
+	Dir    Direction
+	Data   []byte
+}
+
+// Writer пишет события сеанса; безопасен для одновременного использования
+type Writer struct {
+	mu    sync.Mutex
+	w     *bufio.Writer
+	start time.Time
+	err   error
+	now   func() time.Time
+}
+
+// NewWriter начинает запись сеанса с адресом address
+func NewWriter(w io.Writer, address string) *Writer {
+
+	rw := &Writer{w: bufio.NewWriter(w), now: time.Now}
+	rw.start = rw.now()
+	fmt.Fprintf(rw.w, "# сеанс telnet: %s\n", address)
+	fmt.Fprintf(rw.w, "# начало: %s\n", rw.start.Format(time.RFC3339Nano))
+	rw.err = rw.w.Flush()
+
+	return rw
+}
+
+// Record записывает событие; первая ошибка записи сохраняется и возвращается дальше
+func (rw *Writer) Record(dir Direction, data []byte) error {
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.err != nil {
+		return rw.err
+	}
+	offset := rw.now().Sub(rw.start)
+	fmt.Fprintf(rw.w, "%.6f %c %s\n", offset.Seconds(), dir, strconv.Quote(string(data)))
+	// сбрасываем сразу: запись должна сохраниться, даже если клиент завершится аварийно
+	rw.err = rw.w.Flush()
+
+	return rw.err
+}
+
+// Conn возвращает соединение, записывающее всё переданное через conn
+func Conn(conn net.Conn, rw *Writer) net.Conn {
+	return &recordConn{Conn: conn, rw: rw}
+}
+
+type recordConn struct {
+	net.Conn
+	rw *Writer
+}
+
+func (c *recordConn) Read(b []byte) (int, error) {
+
+	n, err := c.Conn.Read(b)
+	c.rw.Record(Received, b[:n])
+
+	return n, err
+}
+
+func (c *recordConn) Write(b []byte) (int, error) {
+
+	n, err := c.Conn.Write(b)
+	c.rw.Record(Sent, b[:n])
+
+	return n, err
+}
+
+// NetConn возвращает исходное соединение
+func (c *recordConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// Read читает все события записи
+func Read(r io.Reader) ([]Event, error) {
+
+	var events []Event
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		ev, err := parseEvent(text)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", line, err)
+		}
+		events = append(events, ev)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// parseEvent разбирает строку события
+func parseEvent(text string) (Event, error) {
+
+	offsetStr, rest, ok := strings.Cut(text, " ")
+	if !ok {
+		return Event{}, errors.New("нет направления")
+	}
+	seconds, err := strconv.ParseFloat(offsetStr, 64)
+	if err != nil || seconds < 0 {
+		return Event{}, fmt.Errorf("неверное время %q", offsetStr)
+	}
+
+	dirStr, quoted, ok := strings.Cut(rest, " ")
+	if !ok || (dirStr != string(Sent) && dirStr != string(Received)) {
+		return Event{}, fmt.Errorf("неверное направление %q", dirStr)
+	}
+	data, err := strconv.Unquote(quoted)
+	if err != nil {
+		return Event{}, fmt.Errorf("неверные данные %s", quoted)
+	}
+
+	return Event{
+		Offset: time.Duration(seconds * float64(time.Second)),
+		Dir:    Direction(dirStr[0]),
+		Data:   []byte(data),
+	}, nil
+}
+
+// Replay воспроизводит запись на соединении conn от имени сервера: данные сервера
+// отправляются с исходными интервалами, данные клиента читаются и передаются в onInput
+// (может быть nil). Воспроизведение заканчивается после последнего события сервера
+// или когда клиент закрывает соединение; само соединение не закрывается
+func Replay(ctx context.Context, conn net.Conn, events []Event, onInput func([]byte)) error {
+
+	// читаем клиента всё время воспроизведения, чтобы он не заблокировался на записи
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 4096)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 && onInput != nil {
+				onInput(buf[:n])
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// по окончании прерываем чтение и дожидаемся горутины: после возврата onInput не вызывается
+	defer func() {
+		conn.SetReadDeadline(time.Unix(1, 0))
+		<-done
+	}()
+
+	start := time.Now()
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for _, ev := range events {
+		if ev.Dir != Received {
+			continue
+		}
+		if wait := ev.Offset - time.Since(start); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-done:
+				return nil // клиент закрыл соединение
+			case <-timer.C:
+			}
+		}
+		if _, err := conn.Write(ev.Data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
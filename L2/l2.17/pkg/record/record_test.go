@@ -0,0 +1,178 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteRead(t *testing.T) {
+
+	var buf bytes.Buffer
+	rw := NewWriter(&buf, "mail.example.com:25")
+	start := rw.start
+	offsets := []time.Duration{31245 * time.Microsecond, 1500 * time.Millisecond, 2 * time.Second}
+	i := 0
+	rw.now = func() time.Time {
+		i++
+		return start.Add(offsets[i-1])
+	}
+
+	rw.Record(Received, []byte("220 ready\r\n"))
+	rw.Record(Sent, []byte("EHLO \"x\"\r\n"))
+	rw.Record(Sent, nil) // пустые данные не пишутся
+	rw.Record(Received, []byte{0xff, 0xfb, 0x01, 0})
+
+	text := buf.String()
+	for _, line := range []string{
+		"# сеанс telnet: mail.example.com:25\n",
+		"0.031245 < \"220 ready\\r\\n\"\n",
+		"1.500000 > \"EHLO \\\"x\\\"\\r\\n\"\n",
+		"2.000000 < \"\\xff\\xfb\\x01\\x00\"\n",
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("no %q in\n%s", line, text)
+		}
+	}
+
+	events, err := Read(strings.NewReader(text + "\n# комментарий\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Event{
+		{Offset: offsets[0], Dir: Received, Data: []byte("220 ready\r\n")},
+		{Offset: offsets[1], Dir: Sent, Data: []byte("EHLO \"x\"\r\n")},
+		{Offset: offsets[2], Dir: Received, Data: []byte{0xff, 0xfb, 0x01, 0}},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %v", events)
+	}
+	for i := range want {
+		if events[i].Offset != want[i].Offset || events[i].Dir != want[i].Dir || !bytes.Equal(events[i].Data, want[i].Data) {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+
+	tests := []struct{ text, want string }{
+		{"1.0\n", "строка 1: нет направления"},
+		{"# x\nabc < \"a\"\n", "строка 2: неверное время"},
+		{"-1 < \"a\"\n", "неверное время"},
+		{"1.0 ? \"a\"\n", "неверное направление"},
+		{"1.0 < a\n", "неверные данные"},
+	}
+	for _, tt := range tests {
+		if _, err := Read(strings.NewReader(tt.text)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Read(%q) err = %v, want %q", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestConn(t *testing.T) {
+
+	client, server := net.Pipe()
+	defer server.Close()
+
+	var buf bytes.Buffer
+	conn := Conn(client, NewWriter(&buf, "pipe"))
+	defer conn.Close()
+
+	go func() {
+		server.Write([]byte("hi"))
+		io.ReadFull(server, make([]byte, 3))
+	}()
+
+	io.ReadFull(conn, make([]byte, 2))
+	conn.Write([]byte("yes"))
+
+	events, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Dir != Received || string(events[0].Data) != "hi" ||
+		events[1].Dir != Sent || string(events[1].Data) != "yes" {
+		t.Errorf("events = %+v", events)
+	}
+	if conn.(interface{ NetConn() net.Conn }).NetConn() != client {
+		t.Error("NetConn must return the wrapped connection")
+	}
+}
+
+func TestReplay(t *testing.T) {
+
+	events := []Event{
+		{Offset: 0, Dir: Received, Data: []byte("hello\r\n")},
+		{Offset: 10 * time.Millisecond, Dir: Sent, Data: []byte("ignored\r\n")},
+		{Offset: 100 * time.Millisecond, Dir: Received, Data: []byte("bye\r\n")},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	var mu sync.Mutex
+	var input []byte
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Replay(context.Background(), server, events, func(b []byte) {
+			mu.Lock()
+			input = append(input, b...)
+			mu.Unlock()
+		})
+		server.Close()
+	}()
+
+	buf := make([]byte, 7)
+	io.ReadFull(client, buf)
+	if string(buf) != "hello\r\n" {
+		t.Errorf("first = %q", buf)
+	}
+	client.Write([]byte("typed"))
+
+	rest, _ := io.ReadAll(client)
+	if string(rest) != "bye\r\n" {
+		t.Errorf("rest = %q", rest)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("replay took %v, want at least 100ms", elapsed)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if string(input) != "typed" {
+		t.Errorf("input = %q", input)
+	}
+}
+
+func TestReplayCancel(t *testing.T) {
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Replay(ctx, server, []Event{{Offset: time.Hour, Dir: Received, Data: []byte("x")}}, nil)
+	}()
+
+	cancel()
+	select {
+	case err := <-errc:
+		if err != context.Canceled {
+			t.Errorf("err = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Replay did not stop")
+	}
+}
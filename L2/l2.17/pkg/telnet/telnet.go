@@ -0,0 +1,475 @@
+// Package telnet реализует протокол telnet (RFC 854) поверх потокового соединения:
+// отделяет команды от данных, согласовывает опции (RFC 1143, без очереди запросов),
+// сообщает размер окна (NAWS, RFC 1073) и тип терминала (RFC 1091)
+package telnet
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// команды протокола
+const (
+	SE   byte = 240 // конец подсогласования
+	NOP  byte = 241 // пустая операция
+	DM   byte = 242 // метка данных
+	BRK  byte = 243 // break
+	IP   byte = 244 // прервать процесс
+	AO   byte = 245 // прервать вывод
+	AYT  byte = 246 // are you there
+	EC   byte = 247 // стереть символ
+	EL   byte = 248 // стереть строку
+	GA   byte = 249 // go ahead
+	SB   byte = 250 // начало подсогласования
+	WILL byte = 251
+	WONT byte = 252
+	DO   byte = 253
+	DONT byte = 254
+	IAC  byte = 255 // признак команды
+)
+
+// Option - код опции telnet
+type Option byte
+
+// поддерживаемые опции
+const (
+	Echo            Option = 1  // эхо (его выполняет сервер)
+	SuppressGoAhead Option = 3  // без GA - полнодуплексный режим
+	TerminalType    Option = 24 // тип терминала
+	NAWS            Option = 31 // размер окна
+)
+
+// подкоманды TERMINAL-TYPE
+const (
+	ttypeIs   byte = 0
+	ttypeSend byte = 1
+)
+
+var optionNames = map[Option]string{
+	Echo:            "ECHO",
+	SuppressGoAhead: "SUPPRESS-GO-AHEAD",
+	TerminalType:    "TERMINAL-TYPE",
+	NAWS:            "NAWS",
+}
+
+func (o Option) String() string {
+
+	if name, ok := optionNames[o]; ok {
+		return name
+	}
+
+	return "OPTION-" + strconv.Itoa(int(o))
+}
+
+// Options возвращает поддерживаемые опции в порядке кодов
+func Options() []Option {
+	return []Option{Echo, SuppressGoAhead, TerminalType, NAWS}
+}
+
+// optState - состояние опции на одной стороне соединения (RFC 1143)
+type optState int
+
+const (
+	optNo      optState = iota
+	optYes              // включена
+	optWantNo           // мы попросили выключить, ждём ответа
+	optWantYes          // мы попросили включить, ждём ответа
+)
+
+// Config - параметры согласования
+type Config struct {
+	TerminalType string // ответ на запрос типа терминала ("" - опция не поддерживается)
+	Width        int    // размер окна для NAWS (0 - опция не поддерживается)
+	Height       int
+
+	// Initiate - сразу предложить свои опции и попросить SUPPRESS-GO-AHEAD у сервера.
+	// Не-telnet серверам (SMTP, HTTP) байты согласования мешают, поэтому по умолчанию клиент
+	// только отвечает на запросы сервера
+	Initiate bool
+
+	// OnChange вызывается из читающей горутины, когда опция включается или выключается.
+	// remote - опция на стороне сервера (WILL/WONT), иначе - на нашей (DO/DONT)
+	OnChange func(opt Option, remote, enabled bool)
+}
+
+// readState - состояние разбора входящего потока
+type readState int
+
+const (
+	stData   readState = iota
+	stIAC              // после IAC
+	stOption           // после WILL/WONT/DO/DONT
+	stSB               // внутри подсогласования
+	stSBIAC            // IAC внутри подсогласования
+)
+
+// maxSubnegotiation ограничивает длину подсогласования
+const maxSubnegotiation = 1024
+
+// Conn - соединение telnet. Read возвращает только данные, отвечая на команды сервера,
+// Write экранирует IAC и одиночный CR
+type Conn struct {
+	rw  io.ReadWriteCloser
+	r   *bufio.Reader
+	cfg Config
+
+	wmu sync.Mutex // данные и ответы на согласование пишутся из разных горутин
+
+	mu            sync.Mutex
+	local         [256]optState // наши опции (мы отвечаем WILL/WONT)
+	remote        [256]optState // опции сервера (мы отвечаем DO/DONT)
+	width, height int
+
+	// разбор входящего потока - только в читающей горутине
+	state readState
+	verb  byte
+	sb    []byte
+	cr    bool // предыдущий байт данных - CR (за ним может идти NUL)
+}
+
+// NewConn оборачивает соединение протоколом telnet
+func NewConn(rw io.ReadWriteCloser, cfg Config) *Conn {
+
+	c := &Conn{
+		rw:     rw,
+		r:      bufio.NewReader(rw),
+		cfg:    cfg,
+		width:  cfg.Width,
+		height: cfg.Height,
+	}
+
+	if cfg.Initiate {
+		c.RequestRemote(SuppressGoAhead)
+		if c.supportsLocal(NAWS) {
+			c.requestLocal(NAWS)
+		}
+		if c.supportsLocal(TerminalType) {
+			c.requestLocal(TerminalType)
+		}
+	}
+
+	return c
+}
+
+// Read читает данные от сервера, обрабатывая команды протокола
+func (c *Conn) Read(p []byte) (int, error) {
+
+	n := 0
+	for n < len(p) {
+		// не блокируемся, если что-то уже прочитано
+		if n > 0 && c.r.Buffered() == 0 {
+			break
+		}
+		b, err := c.r.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if data, ok := c.receive(b); ok {
+			p[n] = data
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// receive обрабатывает входящий байт; возвращает байт данных, если он есть
+func (c *Conn) receive(b byte) (byte, bool) {
+
+	switch c.state {
+	case stData:
+		if b == IAC {
+			c.state = stIAC
+			return 0, false
+		}
+		// CR NUL означает одиночный CR
+		if c.cr {
+			c.cr = false
+			if b == 0 {
+				return 0, false
+			}
+		}
+		c.cr = b == '\r'
+		return b, true
+
+	case stIAC:
+		c.state = stData
+		switch b {
+		case IAC:
+			return IAC, true
+		case WILL, WONT, DO, DONT:
+			c.verb = b
+			c.state = stOption
+		case SB:
+			c.sb = c.sb[:0]
+			c.state = stSB
+		}
+		// остальные команды (NOP, GA, DM ...) клиенту не нужны
+
+	case stOption:
+		c.state = stData
+		c.negotiate(c.verb, Option(b))
+
+	case stSB:
+		if b == IAC {
+			c.state = stSBIAC
+		} else if len(c.sb) < maxSubnegotiation {
+			c.sb = append(c.sb, b)
+		}
+
+	case stSBIAC:
+		switch b {
+		case SE:
+			c.state = stData
+			c.subnegotiate(c.sb)
+		case IAC:
+			c.state = stSB
+			if len(c.sb) < maxSubnegotiation {
+				c.sb = append(c.sb, IAC)
+			}
+		default:
+			// подсогласование оборвано другой командой
+			c.state = stIAC
+			return c.receive(b)
+		}
+	}
+
+	return 0, false
+}
+
+// supportsLocal сообщает, согласны ли мы включить опцию на своей стороне
+func (c *Conn) supportsLocal(opt Option) bool {
+
+	switch opt {
+	case SuppressGoAhead:
+		return true
+	case TerminalType:
+		return c.cfg.TerminalType != ""
+	case NAWS:
+		return c.cfg.Width > 0 && c.cfg.Height > 0
+	}
+
+	return false
+}
+
+// supportsRemote сообщает, согласны ли мы, чтобы сервер включил опцию
+func supportsRemote(opt Option) bool {
+	return opt == Echo || opt == SuppressGoAhead
+}
+
+// negotiate обрабатывает WILL, WONT, DO или DONT от сервера
+func (c *Conn) negotiate(verb byte, opt Option) {
+
+	c.mu.Lock()
+	var reply byte
+	var changed, enabled, remote bool
+	switch verb {
+	case WILL:
+		reply, changed = onEnable(&c.remote[opt], supportsRemote(opt), DO, DONT)
+		enabled, remote = true, true
+	case WONT:
+		reply, changed = onDisable(&c.remote[opt], DONT)
+		remote = true
+	case DO:
+		reply, changed = onEnable(&c.local[opt], c.supportsLocal(opt), WILL, WONT)
+		enabled = true
+	case DONT:
+		reply, changed = onDisable(&c.local[opt], WONT)
+	}
+	c.mu.Unlock()
+
+	if reply != 0 {
+		c.send(IAC, reply, byte(opt))
+	}
+	if !changed {
+		return
+	}
+
+	// размер окна сообщаем сразу после согласия сервера
+	if opt == NAWS && !remote && enabled {
+		c.sendWindowSize()
+	}
+	if c.cfg.OnChange != nil {
+		c.cfg.OnChange(opt, remote, enabled)
+	}
+}
+
+// onEnable обрабатывает WILL (для опции сервера) или DO (для нашей).
+// Возвращает ответ (0 - отвечать не нужно) и признак включения опции
+func onEnable(s *optState, accept bool, yes, no byte) (byte, bool) {
+
+	switch *s {
+	case optNo:
+		if accept {
+			*s = optYes
+			return yes, true
+		}
+		return no, false
+	case optWantYes:
+		*s = optYes
+		return 0, true
+	case optWantNo:
+		// сервер не согласился выключить - по RFC 1143 считаем опцию выключенной
+		*s = optNo
+	}
+
+	return 0, false
+}
+
+// onDisable обрабатывает WONT или DONT: опция выключается, включённая - с подтверждением
+func onDisable(s *optState, no byte) (byte, bool) {
+
+	switch *s {
+	case optYes:
+		*s = optNo
+		return no, true
+	case optWantNo:
+		*s = optNo
+		return 0, true
+	case optWantYes:
+		*s = optNo
+	}
+
+	return 0, false
+}
+
+// subnegotiate обрабатывает подсогласование IAC SB ... IAC SE
+func (c *Conn) subnegotiate(sb []byte) {
+
+	if len(sb) < 2 || Option(sb[0]) != TerminalType || sb[1] != ttypeSend || !c.LocalEnabled(TerminalType) {
+		return
+	}
+
+	msg := []byte{IAC, SB, byte(TerminalType), ttypeIs}
+	msg = append(msg, c.cfg.TerminalType...)
+	msg = append(msg, IAC, SE)
+	c.send(msg...)
+}
+
+// RequestRemote просит сервер включить опцию (DO)
+func (c *Conn) RequestRemote(opt Option) error {
+
+	c.mu.Lock()
+	if c.remote[opt] != optNo {
+		c.mu.Unlock()
+		return nil
+	}
+	c.remote[opt] = optWantYes
+	c.mu.Unlock()
+
+	return c.send(IAC, DO, byte(opt))
+}
+
+// requestLocal предлагает серверу включить нашу опцию (WILL)
+func (c *Conn) requestLocal(opt Option) error {
+
+	c.mu.Lock()
+	if c.local[opt] != optNo {
+		c.mu.Unlock()
+		return nil
+	}
+	c.local[opt] = optWantYes
+	c.mu.Unlock()
+
+	return c.send(IAC, WILL, byte(opt))
+}
+
+// RemoteEnabled сообщает, что сервер включил опцию
+func (c *Conn) RemoteEnabled(opt Option) bool {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.remote[opt] == optYes
+}
+
+// LocalEnabled сообщает, что опция включена на нашей стороне
+func (c *Conn) LocalEnabled(opt Option) bool {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.local[opt] == optYes
+}
+
+// SetWindowSize запоминает новый размер окна и сообщает его серверу, если NAWS включена
+func (c *Conn) SetWindowSize(width, height int) error {
+
+	c.mu.Lock()
+	c.width, c.height = width, height
+	c.mu.Unlock()
+
+	if !c.LocalEnabled(NAWS) {
+		return nil
+	}
+
+	return c.sendWindowSize()
+}
+
+// sendWindowSize отправляет IAC SB NAWS ширина высота IAC SE (байты 255 удваиваются)
+func (c *Conn) sendWindowSize() error {
+
+	c.mu.Lock()
+	width, height := c.width, c.height
+	c.mu.Unlock()
+
+	msg := []byte{IAC, SB, byte(NAWS)}
+	for _, v := range []int{width, height} {
+		for _, b := range []byte{byte(v >> 8), byte(v)} {
+			msg = append(msg, b)
+			if b == IAC {
+				msg = append(msg, IAC)
+			}
+		}
+	}
+	msg = append(msg, IAC, SE)
+
+	return c.send(msg...)
+}
+
+// SendCommand отправляет команду протокола: BRK, IP, AYT, AO, EC, EL, NOP
+func (c *Conn) SendCommand(cmd byte) error {
+	return c.send(IAC, cmd)
+}
+
+// Write отправляет данные: IAC удваивается, CR без LF передаётся как CR NUL
+func (c *Conn) Write(p []byte) (int, error) {
+
+	buf := make([]byte, 0, len(p)+8)
+	for i, b := range p {
+		buf = append(buf, b)
+		switch {
+		case b == IAC:
+			buf = append(buf, IAC)
+		case b == '\r' && (i+1 == len(p) || p[i+1] != '\n'):
+			buf = append(buf, 0)
+		}
+	}
+
+	if err := c.send(buf...); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// send записывает байты в соединение целиком
+func (c *Conn) send(b ...byte) error {
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	_, err := c.rw.Write(b)
+
+	return err
+}
+
+// Close закрывает соединение
+func (c *Conn) Close() error {
+	return c.rw.Close()
+}
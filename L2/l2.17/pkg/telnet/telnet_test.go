@@ -0,0 +1,172 @@
+package telnet
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// pipe - соединение в памяти: сервер заранее записал входящие байты, ответы клиента копятся в out
+type pipe struct {
+	io.Reader
+	out bytes.Buffer
+}
+
+func (p *pipe) Write(b []byte) (int, error) { return p.out.Write(b) }
+func (p *pipe) Close() error                { return nil }
+
+// cmd собирает последовательность команды протокола
+func cmd(b ...byte) string {
+	return string(b)
+}
+
+func TestNegotiation(t *testing.T) {
+
+	in := cmd(IAC, DO, byte(NAWS)) +
+		cmd(IAC, DO, byte(TerminalType)) +
+		cmd(IAC, WILL, byte(Echo)) +
+		cmd(IAC, WILL, byte(SuppressGoAhead)) +
+		cmd(IAC, DO, 99) + cmd(IAC, WILL, 98) + cmd(IAC, DO, byte(Echo)) +
+		cmd(IAC, SB, byte(TerminalType), ttypeSend, IAC, SE) +
+		"hi" + cmd(IAC, IAC) + "\r\x00x" + cmd(IAC, NOP, IAC, GA) +
+		cmd(IAC, WILL, byte(Echo)) + // повтор - без ответа
+		cmd(IAC, WONT, byte(Echo)) +
+		"\r\n"
+
+	type change struct {
+		opt             Option
+		remote, enabled bool
+	}
+	var changes []change
+
+	p := &pipe{Reader: iotest.OneByteReader(strings.NewReader(in))}
+	c := NewConn(p, Config{TerminalType: "xterm", Width: 80, Height: 24, OnChange: func(opt Option, remote, enabled bool) {
+		changes = append(changes, change{opt, remote, enabled})
+	}})
+
+	data, err := io.ReadAll(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "hi\xff\rx\r\n"; string(data) != want {
+		t.Errorf("data = %q, want %q", data, want)
+	}
+
+	want := cmd(IAC, WILL, byte(NAWS)) + cmd(IAC, SB, byte(NAWS), 0, 80, 0, 24, IAC, SE) +
+		cmd(IAC, WILL, byte(TerminalType)) +
+		cmd(IAC, DO, byte(Echo)) +
+		cmd(IAC, DO, byte(SuppressGoAhead)) +
+		cmd(IAC, WONT, 99) + cmd(IAC, DONT, 98) + cmd(IAC, WONT, byte(Echo)) +
+		cmd(IAC, SB, byte(TerminalType), ttypeIs) + "xterm" + cmd(IAC, SE) +
+		cmd(IAC, DONT, byte(Echo))
+	if got := p.out.String(); got != want {
+		t.Errorf("replies = %v, want %v", []byte(got), []byte(want))
+	}
+
+	wantChanges := []change{
+		{NAWS, false, true}, {TerminalType, false, true}, {Echo, true, true}, {SuppressGoAhead, true, true}, {Echo, true, false},
+	}
+	if len(changes) != len(wantChanges) {
+		t.Fatalf("changes = %v, want %v", changes, wantChanges)
+	}
+	for i := range wantChanges {
+		if changes[i] != wantChanges[i] {
+			t.Errorf("change %d = %v, want %v", i, changes[i], wantChanges[i])
+		}
+	}
+
+	if !c.RemoteEnabled(SuppressGoAhead) || c.RemoteEnabled(Echo) || !c.LocalEnabled(NAWS) || c.LocalEnabled(Echo) {
+		t.Errorf("option state is wrong")
+	}
+}
+
+func TestUnsupportedOptions(t *testing.T) {
+
+	// без размера окна и типа терминала эти опции отклоняются
+	p := &pipe{Reader: strings.NewReader(cmd(IAC, DO, byte(NAWS), IAC, DO, byte(TerminalType)))}
+	c := NewConn(p, Config{})
+	io.ReadAll(c)
+
+	if want := cmd(IAC, WONT, byte(NAWS), IAC, WONT, byte(TerminalType)); p.out.String() != want {
+		t.Errorf("replies = %v, want %v", p.out.Bytes(), []byte(want))
+	}
+}
+
+func TestInitiate(t *testing.T) {
+
+	in := cmd(IAC, DO, byte(NAWS)) + // ответ на наш WILL - без повторного WILL
+		cmd(IAC, WONT, byte(SuppressGoAhead)) + // отказ на наш DO - без ответа
+		cmd(IAC, DONT, byte(TerminalType))
+
+	p := &pipe{Reader: strings.NewReader(in)}
+	c := NewConn(p, Config{TerminalType: "vt100", Width: 100, Height: 300, Initiate: true})
+	io.ReadAll(c)
+
+	want := cmd(IAC, DO, byte(SuppressGoAhead), IAC, WILL, byte(NAWS), IAC, WILL, byte(TerminalType)) +
+		cmd(IAC, SB, byte(NAWS), 0, 100, 1, 44, IAC, SE)
+	if got := p.out.String(); got != want {
+		t.Errorf("replies = %v, want %v", []byte(got), []byte(want))
+	}
+	if c.RemoteEnabled(SuppressGoAhead) || c.LocalEnabled(TerminalType) || !c.LocalEnabled(NAWS) {
+		t.Errorf("option state is wrong")
+	}
+
+	// новый размер окна: байт 255 удваивается
+	p.out.Reset()
+	if err := c.SetWindowSize(255, 24); err != nil {
+		t.Fatal(err)
+	}
+	if want := cmd(IAC, SB, byte(NAWS), 0, 255, 255, 0, 24, IAC, SE); p.out.String() != want {
+		t.Errorf("NAWS = %v, want %v", p.out.Bytes(), []byte(want))
+	}
+}
+
+func TestWrite(t *testing.T) {
+
+	p := &pipe{Reader: strings.NewReader("")}
+	c := NewConn(p, Config{})
+
+	if _, err := c.Write([]byte("a\xffb\rc\r\nd\r")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SendCommand(BRK); err != nil {
+		t.Fatal(err)
+	}
+	// NAWS не включена - размер окна не отправляется
+	c.SetWindowSize(10, 10)
+
+	if want := "a\xff\xffb\r\x00c\r\nd\r\x00" + cmd(IAC, BRK); p.out.String() != want {
+		t.Errorf("written = %q, want %q", p.out.String(), want)
+	}
+}
+
+func TestSubnegotiationEdgeCases(t *testing.T) {
+
+	in := cmd(IAC, SB, byte(TerminalType), ttypeSend, IAC, SE) + // TERMINAL-TYPE не включена - без ответа
+		cmd(IAC, SB, 1, IAC, IAC, 2, IAC, SE) + // IAC IAC внутри подсогласования
+		cmd(IAC, SB, 5, 6, IAC, WILL, 98) + // подсогласование оборвано командой
+		"ok"
+
+	p := &pipe{Reader: iotest.OneByteReader(strings.NewReader(in))}
+	c := NewConn(p, Config{TerminalType: "xterm"})
+
+	data, _ := io.ReadAll(c)
+	if string(data) != "ok" {
+		t.Errorf("data = %q", data)
+	}
+	if want := cmd(IAC, DONT, 98); p.out.String() != want {
+		t.Errorf("replies = %v, want %v", p.out.Bytes(), []byte(want))
+	}
+}
+
+func TestOptionString(t *testing.T) {
+
+	if s := NAWS.String(); s != "NAWS" {
+		t.Errorf("NAWS = %q", s)
+	}
+	if s := Option(99).String(); s != "OPTION-99" {
+		t.Errorf("Option(99) = %q", s)
+	}
+}
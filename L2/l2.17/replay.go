@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"l2.17/pkg/record"
+)
+
+// listenDefault - адрес, на котором --replay ждёт клиентов
+const listenDefault = "127.0.0.1:2323"
+
+// runReplay воспроизводит запись сеанса file для каждого клиента, подключившегося к listen,
+// пока не будет отменён контекст. Ввод клиентов выводится в out
+func runReplay(ctx context.Context, file, listen string, out io.Writer) error {
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	events, err := record.Read(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	ln, err := net.Listen(networkDefault, listen)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+
+	fmt.Fprintf(out, "Воспроизведение %s (%d событий) на %s\n", file, len(events), ln.Addr())
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex // вывод разных клиентов не перемешиваем
+	logf := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(out, format, args...)
+	}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			wg.Wait()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+
+			peer := conn.RemoteAddr()
+			logf("%s: подключился\n", peer)
+			err := record.Replay(ctx, conn, events, func(data []byte) {
+				logf("%s > %q\n", peer, data)
+			})
+			if err != nil && ctx.Err() == nil {
+				logf("%s: %v\n", peer, err)
+				return
+			}
+			logf("%s: воспроизведение завершено\n", peer)
+		}()
+	}
+}
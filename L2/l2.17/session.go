@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"l2.17/pkg/telnet"
+)
+
+// escapeChar - Ctrl+]: открывает локальную командную строку клиента
+const escapeChar = 0x1d
+
+// режимы ввода
+const (
+	modeAuto = "auto" // посимвольный, если эхо и SUPPRESS-GO-AHEAD включил сервер, иначе построчный
+	modeLine = "line" // строка уходит серверу по Enter, редактирует её локальный терминал
+	modeChar = "char" // каждая клавиша сразу уходит серверу
+)
+
+// options - параметры запуска клиента
+type options struct {
+	address  string
+	timeout  time.Duration
+	plain    bool   // без протокола telnet: байты передаются как есть
+	mode     string // modeAuto, modeLine или modeChar
+	termType string // тип терминала для сервера
+
+	tls        bool   // соединение через TLS
+	insecure   bool   // не проверять сертификат сервера
+	caFile     string // корневые сертификаты для проверки сервера (PEM)
+	certFile   string // сертификат клиента (PEM)
+	keyFile    string // ключ сертификата клиента (PEM)
+	serverName string // имя сервера для SNI и проверки сертификата (по умолчанию - хост)
+	starttls   string // протокол, в котором TLS включается командой STARTTLS
+	proxy      string // адрес прокси: socks5://... или http://...
+	record     string // файл записи сеанса
+
+	replay string // файл записи для воспроизведения
+	listen string // адрес, на котором воспроизводится запись
+}
+
+// session - сеанс связи с сервером
+type session struct {
+	opts   options
+	conn   net.Conn
+	telnet *telnet.Conn // nil в режиме --plain
+	data   io.ReadWriter
+
+	in   *bufio.Reader
+	out  io.Writer
+	term *terminal // nil - ввод не терминал
+
+	mu        sync.Mutex
+	mode      string
+	charMode  bool // текущий режим ввода (для modeAuto зависит от опций сервера)
+	prompting bool // открыта командная строка клиента - режим терминала не трогаем
+
+	started  time.Time
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+// newSession создаёт сеанс на установленном соединении; term - терминал ввода или nil
+func newSession(conn net.Conn, opts options, in io.Reader, out io.Writer, term *terminal) *session {
+
+	s := &session{
+		opts:    opts,
+		conn:    conn,
+		data:    conn,
+		in:      bufio.NewReader(in),
+		out:     out,
+		term:    term,
+		mode:    opts.mode,
+		started: time.Now(),
+	}
+
+	if !opts.plain {
+		cfg := telnet.Config{
+			TerminalType: opts.termType,
+			OnChange:     func(telnet.Option, bool, bool) { s.updateMode() },
+		}
+		if term != nil {
+			cfg.Width, cfg.Height = term.size()
+		}
+		// на стандартный порт telnet предлагаем опции сами, остальным серверам только отвечаем
+		if _, port, err := net.SplitHostPort(opts.address); err == nil && port == "23" {
+			cfg.Initiate = true
+		}
+		s.telnet = telnet.NewConn(conn, cfg)
+		s.data = s.telnet
+	}
+
+	s.updateMode()
+
+	return s
+}
+
+// run передаёт данные в обе стороны, пока сервер не закроет соединение или не будет отменён контекст
+func (s *session) run(ctx context.Context, cancel context.CancelFunc) {
+
+	var wg sync.WaitGroup
+
+	// горутину чтения ввода не ждём: она может быть заблокирована в чтении терминала
+	go s.inputReadAndSend(cancel)
+
+	// запускаем горутину чтения данных с сервера
+	wg.Add(1)
+	go s.serverReadAndWrite(cancel, &wg)
+
+	<-ctx.Done()
+	s.conn.Close()
+
+	wg.Wait()
+
+	if s.term != nil {
+		s.term.restore()
+	}
+}
+
+// updateMode выбирает режим ввода по настройке и опциям сервера и переключает терминал
+func (s *session) updateMode() {
+
+	remoteEcho := s.telnet != nil && s.telnet.RemoteEnabled(telnet.Echo)
+	sga := s.telnet != nil && s.telnet.RemoteEnabled(telnet.SuppressGoAhead)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.mode {
+	case modeChar:
+		s.charMode = true
+	case modeLine:
+		s.charMode = false
+	default:
+		s.charMode = remoteEcho && sga
+	}
+
+	if s.term != nil && !s.prompting {
+		// если эхо выполняет сервер, локальное выключаем (например, при вводе пароля)
+		s.term.setMode(s.charMode, !remoteEcho && !s.charMode)
+	}
+}
+
+// isCharMode сообщает, что ввод передаётся посимвольно
+func (s *session) isCharMode() bool {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.charMode
+}
+
+// inputReadAndSend читает ввод и отправляет серверу; Ctrl+] открывает командную строку клиента
+func (s *session) inputReadAndSend(cancel context.CancelFunc) {
+
+	buf := make([]byte, 1024) // буфер
+
+	for {
+		n, err := s.in.Read(buf)
+		if n > 0 {
+			data := buf[:n]
+			escape := bytes.IndexByte(data, escapeChar)
+			if escape >= 0 {
+				// прочитанное после Ctrl+] - начало команды клиента
+				rest := bytes.Clone(data[escape+1:])
+				s.in = bufio.NewReader(io.MultiReader(bytes.NewReader(rest), s.in))
+				data = data[:escape]
+			}
+			if err := s.send(data); err != nil {
+				fmt.Fprintf(os.Stderr, "inputReadAndSend: Ошибка записи в соединение: %v\n", err)
+				cancel()
+				return
+			}
+			if escape >= 0 && s.command() {
+				cancel()
+				return
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				// если словили io.EOF, значит получили Ctrl+D
+				fmt.Fprintln(s.out, "\ninputReadAndSend: Выход из программы.")
+			} else {
+				fmt.Fprintf(os.Stderr, "inputReadAndSend: Ошибка чтения из консоли: %v\n", err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// send отправляет введённые данные. В построчном режиме протокол telnet требует CR LF в конце строки
+func (s *session) send(data []byte) error {
+
+	if len(data) == 0 {
+		return nil
+	}
+	if s.telnet != nil && !s.isCharMode() {
+		data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	}
+
+	if _, err := s.data.Write(data); err != nil {
+		return err
+	}
+	s.sent.Add(int64(len(data)))
+
+	return nil
+}
+
+// serverReadAndWrite принимает данные с сервера и выводит их
+func (s *session) serverReadAndWrite(cancel context.CancelFunc, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+
+	buf := make([]byte, 4096) // буфер
+
+	for {
+		n, err := s.data.Read(buf)
+		if n > 0 {
+			s.received.Add(int64(n))
+			if _, err := s.out.Write(buf[:n]); err != nil {
+				fmt.Fprintf(os.Stderr, "serverReadAndWrite: Ошибка вывода в Stdout: %v\n", err)
+				cancel()
+				return
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				fmt.Fprintln(s.out, "\r\nserverReadAndWrite: Соединение закрыто сервером.")
+			} else if !isClosedConn(err) {
+				fmt.Fprintf(os.Stderr, "serverReadAndWrite: Ошибка чтения соединения: %v\n", err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// isClosedConn сообщает, что соединение закрыли мы сами (выход по команде или Ctrl+D)
+func isClosedConn(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
+
+// команды протокола, которые можно отправить командой send
+var sendCommands = map[string]byte{
+	"brk": telnet.BRK,
+	"ip":  telnet.IP,
+	"ayt": telnet.AYT,
+	"ao":  telnet.AO,
+	"ec":  telnet.EC,
+	"el":  telnet.EL,
+	"nop": telnet.NOP,
+}
+
+// command читает и выполняет одну команду клиента после Ctrl+]. Возвращает true, если нужно завершиться
+func (s *session) command() bool {
+
+	// командную строку вводим в обычном режиме терминала
+	s.mu.Lock()
+	s.prompting = true
+	if s.term != nil {
+		s.term.setMode(false, true)
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.prompting = false
+		s.mu.Unlock()
+		s.updateMode()
+	}()
+
+	fmt.Fprint(s.out, "\r\ntelnet> ")
+	line, err := s.in.ReadString('\n')
+	if err != nil && line == "" {
+		return true
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "close", "quit":
+		fmt.Fprintln(s.out, "Соединение закрыто.")
+		return true
+
+	case "status":
+		s.printStatus()
+
+	case "send":
+		if len(fields) < 2 || sendCommands[fields[1]] == 0 {
+			fmt.Fprintf(s.out, "использование: send %s\n", strings.Join(sortedKeys(sendCommands), "|"))
+			break
+		}
+		if s.telnet == nil {
+			fmt.Fprintln(s.out, "send: команды протокола недоступны в режиме --plain")
+			break
+		}
+		if err := s.telnet.SendCommand(sendCommands[fields[1]]); err != nil {
+			fmt.Fprintf(s.out, "send: %v\n", err)
+		}
+
+	case "mode":
+		if len(fields) < 2 || (fields[1] != modeAuto && fields[1] != modeLine && fields[1] != modeChar) {
+			fmt.Fprintln(s.out, "использование: mode auto|line|char")
+			break
+		}
+		s.mu.Lock()
+		s.mode = fields[1]
+		s.mu.Unlock()
+
+	case "help", "?":
+		fmt.Fprintln(s.out, "Команды:")
+		fmt.Fprintln(s.out, "  close, quit            закрыть соединение и выйти")
+		fmt.Fprintln(s.out, "  status                 состояние соединения и опций")
+		fmt.Fprintln(s.out, "  send brk|ip|ayt|...    отправить команду протокола")
+		fmt.Fprintln(s.out, "  mode auto|line|char    режим ввода")
+		fmt.Fprintln(s.out, "  пустая строка          вернуться к сеансу")
+
+	default:
+		fmt.Fprintf(s.out, "неизвестная команда %q, help - список команд\n", fields[0])
+	}
+
+	return false
+}
+
+// printStatus печатает состояние сеанса
+func (s *session) printStatus() {
+
+	fmt.Fprintf(s.out, "Подключено к %s (%v)\n", s.opts.address, time.Since(s.started).Round(time.Second))
+	if s.opts.proxy != "" {
+		fmt.Fprintf(s.out, "Прокси: %s\n", redactURL(s.opts.proxy))
+	}
+	if state, ok := tlsState(s.conn); ok {
+		peer := "нет сертификата"
+		if len(state.PeerCertificates) > 0 {
+			peer = state.PeerCertificates[0].Subject.String()
+		}
+		fmt.Fprintf(s.out, "TLS: %s, %s, сервер: %s\n",
+			tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite), peer)
+	}
+	if s.opts.record != "" {
+		fmt.Fprintf(s.out, "Запись сеанса: %s\n", s.opts.record)
+	}
+
+	s.mu.Lock()
+	mode, charMode := s.mode, s.charMode
+	s.mu.Unlock()
+	current := "построчный"
+	if charMode {
+		current = "посимвольный"
+	}
+	fmt.Fprintf(s.out, "Режим ввода: %s (%s)\n", current, mode)
+
+	if s.telnet == nil {
+		fmt.Fprintln(s.out, "Протокол telnet: выключен (--plain)")
+	} else {
+		var remote, local []string
+		for _, opt := range telnet.Options() {
+			if s.telnet.RemoteEnabled(opt) {
+				remote = append(remote, opt.String())
+			}
+			if s.telnet.LocalEnabled(opt) {
+				local = append(local, opt.String())
+			}
+		}
+		fmt.Fprintf(s.out, "Опции сервера: %s\n", joinOrNone(remote))
+		fmt.Fprintf(s.out, "Опции клиента: %s\n", joinOrNone(local))
+	}
+
+	fmt.Fprintf(s.out, "Отправлено: %d байт, получено: %d байт\n", s.sent.Load(), s.received.Load())
+	fmt.Fprintln(s.out, "Escape-символ: ^]")
+}
+
+// redactURL скрывает пароль в адресе прокси
+func redactURL(rawURL string) string {
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	return u.Redacted()
+}
+
+func joinOrNone(items []string) string {
+
+	if len(items) == 0 {
+		return "нет"
+	}
+
+	return strings.Join(items, ", ")
+}
+
+func sortedKeys(m map[string]byte) []string {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// terminal - терминал ввода: режимы для построчной и посимвольной работы
+type terminal struct {
+	fd    int
+	saved syscall.Termios // режим на момент запуска - в него возвращаемся при выходе
+}
+
+// openTerminal возвращает терминал для дескриптора или nil, если это не терминал
+func openTerminal(fd int) *terminal {
+
+	t := &terminal{fd: fd}
+	if ioctl(fd, syscall.TCGETS, unsafe.Pointer(&t.saved)) != nil {
+		return nil
+	}
+
+	return t
+}
+
+// setMode переключает терминал. В посимвольном режиме каждая клавиша сразу уходит серверу,
+// Ctrl+C и Ctrl+Z - тоже (их обрабатывает удалённая сторона). echo - локальное эхо ввода
+func (t *terminal) setMode(char, echo bool) error {
+
+	termios := t.saved
+	// в построчном режиме Ctrl+] завершает чтение строки, как Enter, - командная строка
+	// открывается сразу, а не после Enter
+	termios.Cc[syscall.VEOL] = escapeChar
+	if char {
+		termios.Iflag &^= syscall.ICRNL | syscall.INLCR | syscall.IGNCR | syscall.IXON
+		termios.Lflag &^= syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+		termios.Cc[syscall.VMIN] = 1
+		termios.Cc[syscall.VTIME] = 0
+	}
+	if !echo {
+		termios.Lflag &^= syscall.ECHO | syscall.ECHONL
+	}
+
+	return ioctl(t.fd, syscall.TCSETS, unsafe.Pointer(&termios))
+}
+
+// restore возвращает режим терминала, который был при запуске
+func (t *terminal) restore() error {
+	return ioctl(t.fd, syscall.TCSETS, unsafe.Pointer(&t.saved))
+}
+
+// size возвращает ширину и высоту окна терминала (0, 0 - если неизвестны)
+func (t *terminal) size() (int, int) {
+
+	var ws struct{ rows, cols, x, y uint16 }
+	if ioctl(t.fd, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)) != nil {
+		return 0, 0
+	}
+
+	return int(ws.cols), int(ws.rows)
+}
+
+func ioctl(fd int, req uintptr, arg unsafe.Pointer) error {
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// notifyResize подписывает канал на изменение размера окна терминала
+func notifyResize(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
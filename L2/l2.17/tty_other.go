@@ -0,0 +1,27 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+// без Linux режимы терминала не переключаются: клиент работает построчно
+
+type terminal struct{}
+
+func openTerminal(fd int) *terminal {
+	return nil
+}
+
+func (t *terminal) setMode(char, echo bool) error {
+	return nil
+}
+
+func (t *terminal) restore() error {
+	return nil
+}
+
+func (t *terminal) size() (int, int) {
+	return 0, 0
+}
+
+func notifyResize(c chan<- os.Signal) {}
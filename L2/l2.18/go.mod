@@ -2,7 +2,10 @@ module github.com/IPampurin/calendar-server
 
 go 1.24.1
 
-require github.com/stretchr/testify v1.11.1
+require (
+	github.com/mattn/go-sqlite3 v1.14.33
+	github.com/stretchr/testify v1.11.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/IPampurin/calendar-server/pkg/server"
 	"github.com/IPampurin/calendar-server/pkg/storage"
@@ -9,8 +10,21 @@ import (
 
 func main() {
 
-	// создаём хранилище
-	db := storage.NewStorage()
+	// создаём хранилище (вид задаётся переменной окружения CALENDAR_STORAGE)
+	db, err := storage.FromEnv()
+	if err != nil {
+		fmt.Printf("Ошибка открытия хранилища: %v\n", err)
+		return
+	}
+
+	// хранилища на диске сохраняют данные при закрытии
+	if closer, ok := db.(io.Closer); ok {
+		defer func() {
+			if err := closer.Close(); err != nil {
+				fmt.Printf("Ошибка закрытия хранилища: %v\n", err)
+			}
+		}()
+	}
 
 	// запускаем сервер
 	if err := server.Run(db); err != nil {
@@ -24,4 +24,9 @@ func Init(db storage.Repository) {
 	http.HandleFunc("GET /events_for_day", api.GetEventsForDayHandler)     // GET — события на день
 	http.HandleFunc("GET /events_for_week", api.GetEventsForWeekHandler)   // GET — события на неделю
 	http.HandleFunc("GET /events_for_month", api.GetEventsForMonthHandler) // GET — события на месяц
+	http.HandleFunc("GET /export.ics", api.ExportICSHandler)               // GET — экспорт в iCalendar
+	http.HandleFunc("POST /import_ics", api.ImportICSHandler)              // POST — импорт из iCalendar
+
+	// API v2: ресурсы /v2/users/{id}/events, ошибки в application/problem+json
+	api.RegisterV2(http.DefaultServeMux)
 }
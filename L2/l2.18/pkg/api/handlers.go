@@ -23,8 +23,12 @@ Content-Type: application/json
   "user_id": 123,
   "date": "2026-01-15",
   "title": "Встреча",
-  "content": "Описание"
+  "content": "Описание",
+  "rrule": "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10",
+  "exdates": ["2026-01-21"],
+  "remind_before": 15
 }
+rrule, exdates и remind_before (минут до начала) необязательны
 */
 // CreateEventHandler обрабатывет запрос на добавление события
 func (api *API) CreateEventHandler(w http.ResponseWriter, r *http.Request) {
@@ -34,10 +38,14 @@ func (api *API) CreateEventHandler(w http.ResponseWriter, r *http.Request) {
 
 	// req структура для парсинга параметров запроса
 	var req struct {
-		UserID  int    `json:"user_id"`
-		Date    string `json:"date"`
-		Title   string `json:"title"`
-		Content string `json:"content,omitempty"`
+		UserID  int      `json:"user_id"`
+		Date    string   `json:"date"`
+		Title   string   `json:"title"`
+		Content string   `json:"content,omitempty"`
+		RRule   string   `json:"rrule,omitempty"`   // правило повторения RRULE
+		ExDates []string `json:"exdates,omitempty"` // исключённые дни повторений, YYYY-MM-DD
+
+		RemindBefore *int `json:"remind_before,omitempty"` // напоминание за столько минут до начала
 	}
 
 	// читаем запрос
@@ -79,8 +87,31 @@ func (api *API) CreateEventHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// проверяем напоминание
+	if !validRemindBefore(req.RemindBefore) {
+		answer.Error = fmt.Sprintf("remind_before должно быть от 0 до %d минут", storage.MaxRemindBefore)
+		WriterJSON(w, http.StatusBadRequest, answer) // 400
+		return
+	}
+
+	// разбираем повторения
+	recurrence, exDates, err := parseRecurrence(req.RRule, req.ExDates)
+	if err != nil {
+		answer.Error = err.Error()
+		WriterJSON(w, http.StatusBadRequest, answer) // 400
+		return
+	}
+
 	// вызываем storage
-	id, err := api.Storage.Create(req.UserID, date, req.Title, req.Content)
+	id, err := api.Storage.Add(&storage.Event{
+		UserID:       req.UserID,
+		Date:         date,
+		Title:        req.Title,
+		Content:      req.Content,
+		Recurrence:   recurrence,
+		ExDates:      exDates,
+		RemindBefore: req.RemindBefore,
+	})
 	if err != nil {
 		answer.Error = err.Error()
 		WriterJSON(w, http.StatusServiceUnavailable, answer) // 503
@@ -99,8 +130,12 @@ POST /update_event
   "user_id": 123,
   "date": "2026-01-15",
   "title": "Новое название",
-  "content": "Новое описание"
+  "content": "Новое описание",
+  "rrule": "FREQ=DAILY;UNTIL=20260131",
+  "exdates": ["2026-01-20"],
+  "remind_before": 30
 }
+без rrule событие становится неповторяющимся, без remind_before - без напоминания
 */
 // UpdateEventHandler обрабатывет запрос на обновление события
 func (api *API) UpdateEventHandler(w http.ResponseWriter, r *http.Request) {
@@ -110,11 +145,15 @@ func (api *API) UpdateEventHandler(w http.ResponseWriter, r *http.Request) {
 
 	// структура для парсинга запроса
 	var req struct {
-		ID      int    `json:"id"`      // ID события
-		UserID  int    `json:"user_id"` // ID пользователя
-		Date    string `json:"date"`    // новая дата
-		Title   string `json:"title"`   // новый заголовок
-		Content string `json:"content,omitempty"`
+		ID      int      `json:"id"`      // ID события
+		UserID  int      `json:"user_id"` // ID пользователя
+		Date    string   `json:"date"`    // новая дата
+		Title   string   `json:"title"`   // новый заголовок
+		Content string   `json:"content,omitempty"`
+		RRule   string   `json:"rrule,omitempty"`   // правило повторения RRULE
+		ExDates []string `json:"exdates,omitempty"` // исключённые дни повторений, YYYY-MM-DD
+
+		RemindBefore *int `json:"remind_before,omitempty"` // напоминание за столько минут до начала
 	}
 
 	// читаем запрос
@@ -158,13 +197,31 @@ func (api *API) UpdateEventHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// проверяем напоминание
+	if !validRemindBefore(req.RemindBefore) {
+		answer.Error = fmt.Sprintf("remind_before должно быть от 0 до %d минут", storage.MaxRemindBefore)
+		WriterJSON(w, http.StatusBadRequest, answer) // 400
+		return
+	}
+
+	// разбираем повторения
+	recurrence, exDates, err := parseRecurrence(req.RRule, req.ExDates)
+	if err != nil {
+		answer.Error = err.Error()
+		WriterJSON(w, http.StatusBadRequest, answer) // 400
+		return
+	}
+
 	// создаем экземпляр события
 	event := &storage.Event{
-		ID:      req.ID,
-		UserID:  req.UserID,
-		Date:    date,
-		Title:   req.Title,
-		Content: req.Content,
+		ID:           req.ID,
+		UserID:       req.UserID,
+		Date:         date,
+		Title:        req.Title,
+		Content:      req.Content,
+		Recurrence:   recurrence,
+		ExDates:      exDates,
+		RemindBefore: req.RemindBefore,
 	}
 
 	// вызываем storage
@@ -369,3 +426,37 @@ func (api *API) GetEventsForMonthHandler(w http.ResponseWriter, r *http.Request)
 
 	WriterJSON(w, http.StatusOK, answer) // 200
 }
+
+// validRemindBefore проверяет время напоминания (nil - без напоминания)
+func validRemindBefore(minutes *int) bool {
+	return minutes == nil || (*minutes >= 0 && *minutes <= storage.MaxRemindBefore)
+}
+
+// parseRecurrence разбирает правило повторения и исключённые дни из запроса
+func parseRecurrence(rrule string, exdates []string) (*storage.Recurrence, []time.Time, error) {
+
+	var recurrence *storage.Recurrence
+	if rrule != "" {
+		var err error
+		if recurrence, err = storage.ParseRRule(rrule); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	dates := make([]time.Time, 0, len(exdates))
+	for _, s := range exdates {
+		date, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("неверная дата в exdates %q (используйте YYYY-MM-DD)", s)
+		}
+		dates = append(dates, date)
+	}
+	if len(dates) > 0 && recurrence == nil {
+		return nil, nil, fmt.Errorf("exdates задаются только вместе с rrule")
+	}
+	if len(dates) == 0 {
+		dates = nil
+	}
+
+	return recurrence, dates, nil
+}
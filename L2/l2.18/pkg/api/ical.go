@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/IPampurin/calendar-server/pkg/ical"
+)
+
+// maxImportSize ограничивает размер импортируемого календаря
+const maxImportSize = 10 << 20
+
+// ImportResult - результат импорта календаря
+type ImportResult struct {
+	Imported int            `json:"imported"`          // число добавленных событий
+	IDs      []int          `json:"ids"`               // ID добавленных событий
+	Skipped  []ical.Skipped `json:"skipped,omitempty"` // события, которые не удалось импортировать
+}
+
+// GET /export.ics?user_id=123
+// ExportICSHandler отдаёт все события пользователя календарём iCalendar (RFC 5545)
+func (api *API) ExportICSHandler(w http.ResponseWriter, r *http.Request) {
+
+	var answer Answer
+
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil || userID <= 0 {
+		answer.Error = "неверный user_id"
+		WriterJSON(w, http.StatusBadRequest, answer)
+		return
+	}
+
+	// вызываем storage
+	events, err := api.Storage.GetAll(userID)
+	if err != nil {
+		answer.Error = err.Error()
+		WriterJSON(w, http.StatusServiceUnavailable, answer) // 503
+		return
+	}
+
+	// формируем календарь целиком, чтобы при ошибке ещё можно было ответить JSON
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, events, time.Now()); err != nil {
+		answer.Error = err.Error()
+		WriterJSON(w, http.StatusInternalServerError, answer) // 500
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="calendar-%d.ics"`, userID))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+/*
+POST /import_ics?user_id=123
+Content-Type: text/calendar
+
+BEGIN:VCALENDAR
+...
+END:VCALENDAR
+*/
+// ImportICSHandler добавляет пользователю события из календаря iCalendar (RFC 5545)
+func (api *API) ImportICSHandler(w http.ResponseWriter, r *http.Request) {
+
+	var answer Answer
+
+	userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+	if err != nil || userID <= 0 {
+		answer.Error = "неверный user_id"
+		WriterJSON(w, http.StatusBadRequest, answer)
+		return
+	}
+
+	// разбираем календарь
+	events, skipped, err := ical.Decode(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		answer.Error = fmt.Sprintf("невозможно разобрать календарь: %v", err)
+		WriterJSON(w, http.StatusBadRequest, answer) // 400
+		return
+	}
+
+	// вызываем storage для каждого события
+	result := ImportResult{IDs: make([]int, 0, len(events)), Skipped: skipped}
+	for _, event := range events {
+		event.UserID = userID
+		id, err := api.Storage.Add(event)
+		if err != nil {
+			answer.Error = err.Error()
+			WriterJSON(w, http.StatusServiceUnavailable, answer) // 503
+			return
+		}
+		result.IDs = append(result.IDs, id)
+	}
+	result.Imported = len(result.IDs)
+
+	answer.Result = result
+
+	WriterJSON(w, http.StatusOK, answer) // 200
+}
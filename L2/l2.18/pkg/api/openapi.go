@@ -0,0 +1,21 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPI - описание API v2 (OpenAPI 3.0); при изменении маршрутов v2 его нужно обновить,
+// соответствие обработчикам проверяют контрактные тесты
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// GET /v2/openapi.json
+// OpenAPIHandler отдаёт описание API v2
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(OpenAPI)
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// типы ошибок API v2 (поле type); для остальных ошибок - about:blank и текст статуса HTTP
+const (
+	problemValidation = "/v2/problems/validation" // запрос не прошёл проверку, подробности в invalid-params
+	problemNotFound   = "/v2/problems/not-found"  // нет пользователя или события
+)
+
+// Problem - описание ошибки по RFC 7807 (application/problem+json)
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	Instance      string         `json:"instance,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"` // для type = /v2/problems/validation
+}
+
+// InvalidParam - поле или параметр запроса, не прошедший проверку
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// newProblem создаёт описание ошибки со статусом status и текстом detail
+func newProblem(status int, detail string) *Problem {
+	return &Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+// validationProblem создаёт описание ошибки проверки запроса
+func validationProblem(params []InvalidParam) *Problem {
+
+	return &Problem{
+		Type:          problemValidation,
+		Title:         "Запрос не прошёл проверку",
+		Status:        http.StatusBadRequest,
+		InvalidParams: params,
+	}
+}
+
+// WriterProblem отправляет описание ошибки в формате application/problem+json
+func WriterProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	js, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(js)
+}
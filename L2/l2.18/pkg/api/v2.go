@@ -0,0 +1,345 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/IPampurin/calendar-server/pkg/storage"
+)
+
+// ограничения API v2
+const (
+	maxBodySize    = 1 << 20 // размер тела запроса
+	maxTitleLength = 200     // символов в title
+	maxContentSize = 10000   // символов в content
+	maxPeriodDays  = 366     // длина периода в GET /v2/users/{id}/events
+)
+
+// RegisterV2 регистрирует маршруты API v2 в mux
+func (api *API) RegisterV2(mux *http.ServeMux) {
+
+	mux.HandleFunc("GET /v2/openapi.json", OpenAPIHandler)                      // GET — описание API
+	mux.HandleFunc("POST /v2/users/{id}/events", api.CreateEventV2Handler)      // POST — создание события
+	mux.HandleFunc("GET /v2/users/{id}/events", api.ListEventsV2Handler)        // GET — события за период
+	mux.HandleFunc("GET /v2/users/{id}/events/{eid}", api.GetEventV2Handler)    // GET — одно событие
+	mux.HandleFunc("PUT /v2/users/{id}/events/{eid}", api.UpdateEventV2Handler) // PUT — замена события
+	mux.HandleFunc("DELETE /v2/users/{id}/events/{eid}", api.DeleteEventV2Handler)
+}
+
+// EventRequest - тело запросов POST и PUT /v2/users/{id}/events; даты - YYYY-MM-DD или RFC 3339
+type EventRequest struct {
+	Date    string   `json:"date"`
+	Title   string   `json:"title"`
+	Content string   `json:"content,omitempty"`
+	RRule   string   `json:"rrule,omitempty"`   // правило повторения RRULE
+	ExDates []string `json:"exdates,omitempty"` // исключённые дни повторений
+
+	RemindBefore *int `json:"remind_before,omitempty"` // напоминание за столько минут до начала
+}
+
+// EventList - ответ GET /v2/users/{id}/events
+type EventList struct {
+	From   time.Time        `json:"from"`
+	To     time.Time        `json:"to"`
+	Events []*storage.Event `json:"events"`
+}
+
+// Validate проверяет запрос и возвращает событие пользователя userID или список ошибок
+func (req *EventRequest) Validate(userID int) (*storage.Event, []InvalidParam) {
+
+	var params []InvalidParam
+	invalid := func(name, reason string) {
+		params = append(params, InvalidParam{Name: name, Reason: reason})
+	}
+
+	event := &storage.Event{UserID: userID, Title: req.Title, Content: req.Content, RemindBefore: req.RemindBefore}
+
+	if req.Date == "" {
+		invalid("date", "обязательное поле")
+	} else if date, err := parseDateTime(req.Date); err != nil {
+		invalid("date", err.Error())
+	} else {
+		event.Date = date
+	}
+
+	switch n := utf8.RuneCountInString(req.Title); {
+	case n == 0:
+		invalid("title", "обязательное поле")
+	case n > maxTitleLength:
+		invalid("title", fmt.Sprintf("не длиннее %d символов", maxTitleLength))
+	}
+	if utf8.RuneCountInString(req.Content) > maxContentSize {
+		invalid("content", fmt.Sprintf("не длиннее %d символов", maxContentSize))
+	}
+
+	if req.RRule != "" {
+		recurrence, err := storage.ParseRRule(req.RRule)
+		if err != nil {
+			invalid("rrule", err.Error())
+		}
+		event.Recurrence = recurrence
+	}
+	if len(req.ExDates) > 0 && req.RRule == "" {
+		invalid("exdates", "задаются только вместе с rrule")
+	}
+	for i, s := range req.ExDates {
+		date, err := parseDateTime(s)
+		if err != nil {
+			invalid(fmt.Sprintf("exdates[%d]", i), err.Error())
+			continue
+		}
+		event.ExDates = append(event.ExDates, date)
+	}
+
+	if r := req.RemindBefore; r != nil && (*r < 0 || *r > storage.MaxRemindBefore) {
+		invalid("remind_before", fmt.Sprintf("от 0 до %d минут", storage.MaxRemindBefore))
+	}
+
+	if params != nil {
+		return nil, params
+	}
+
+	return event, nil
+}
+
+// parseDateTime разбирает дату YYYY-MM-DD (начало суток в UTC) или дату-время RFC 3339
+func parseDateTime(s string) (time.Time, error) {
+
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("неверная дата %q (используйте YYYY-MM-DD или RFC 3339)", s)
+	}
+
+	return t, nil
+}
+
+// pathID разбирает положительный числовой параметр пути name
+func pathID(r *http.Request, name string, params *[]InvalidParam) int {
+
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || id <= 0 {
+		*params = append(*params, InvalidParam{Name: name, Reason: "должен быть положительным целым числом"})
+		return 0
+	}
+
+	return id
+}
+
+// decodeBody читает тело запроса JSON в dst; неизвестные поля - ошибка
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) *Problem {
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return newProblem(http.StatusUnsupportedMediaType, "тело запроса должно быть application/json")
+	}
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return newProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("тело запроса больше %d байт", maxBodySize))
+		}
+		return newProblem(http.StatusBadRequest, fmt.Sprintf("невозможно десериализовать тело запроса: %v", err))
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return newProblem(http.StatusBadRequest, "лишние данные после объекта JSON")
+	}
+
+	return nil
+}
+
+// storageProblem переводит ошибку хранилища в описание ошибки
+func storageProblem(err error) *Problem {
+
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		p := newProblem(http.StatusNotFound, err.Error())
+		p.Type = problemNotFound
+		return p
+	case errors.Is(err, storage.ErrInvalid):
+		return validationProblem([]InvalidParam{{Name: "event", Reason: err.Error()}})
+	default:
+		return newProblem(http.StatusInternalServerError, err.Error())
+	}
+}
+
+// findEvent возвращает событие eventID пользователя userID
+func (api *API) findEvent(userID, eventID int) (*storage.Event, *Problem) {
+
+	events, err := api.Storage.GetAll(userID)
+	if err != nil {
+		return nil, storageProblem(err)
+	}
+	for _, event := range events {
+		if event.ID == eventID {
+			return event, nil
+		}
+	}
+
+	p := newProblem(http.StatusNotFound, fmt.Sprintf("событие с %d не найдено", eventID))
+	p.Type = problemNotFound
+
+	return nil, p
+}
+
+// POST /v2/users/{id}/events
+// CreateEventV2Handler создаёт событие пользователя, отвечает 201 с событием и заголовком Location
+func (api *API) CreateEventV2Handler(w http.ResponseWriter, r *http.Request) {
+
+	var params []InvalidParam
+	userID := pathID(r, "id", &params)
+	if params != nil {
+		WriterProblem(w, r, validationProblem(params))
+		return
+	}
+
+	var req EventRequest
+	if p := decodeBody(w, r, &req); p != nil {
+		WriterProblem(w, r, p)
+		return
+	}
+	event, params := req.Validate(userID)
+	if params != nil {
+		WriterProblem(w, r, validationProblem(params))
+		return
+	}
+
+	id, err := api.Storage.Add(event)
+	if err != nil {
+		WriterProblem(w, r, storageProblem(err))
+		return
+	}
+	event.ID = id
+
+	w.Header().Set("Location", fmt.Sprintf("/v2/users/%d/events/%d", userID, id))
+	WriterJSON(w, http.StatusCreated, event)
+}
+
+// GET /v2/users/{id}/events?from=2026-01-01&to=2026-02-01
+// ListEventsV2Handler возвращает события пользователя с датой в [from, to), повторения - отдельными событиями
+func (api *API) ListEventsV2Handler(w http.ResponseWriter, r *http.Request) {
+
+	var params []InvalidParam
+	userID := pathID(r, "id", &params)
+
+	var from, to time.Time
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		value := r.URL.Query().Get(p.name)
+		if value == "" {
+			params = append(params, InvalidParam{Name: p.name, Reason: "обязательный параметр"})
+			continue
+		}
+		t, err := parseDateTime(value)
+		if err != nil {
+			params = append(params, InvalidParam{Name: p.name, Reason: err.Error()})
+			continue
+		}
+		*p.dst = t
+	}
+	if !from.IsZero() && !to.IsZero() {
+		if !to.After(from) {
+			params = append(params, InvalidParam{Name: "to", Reason: "должен быть позже from"})
+		} else if to.Sub(from) > maxPeriodDays*24*time.Hour {
+			params = append(params, InvalidParam{Name: "to", Reason: fmt.Sprintf("период не длиннее %d дней", maxPeriodDays)})
+		}
+	}
+	if params != nil {
+		WriterProblem(w, r, validationProblem(params))
+		return
+	}
+
+	events, err := api.Storage.GetForPeriod(userID, from, to)
+	if err != nil {
+		WriterProblem(w, r, storageProblem(err))
+		return
+	}
+
+	WriterJSON(w, http.StatusOK, EventList{From: from, To: to, Events: events})
+}
+
+// GET /v2/users/{id}/events/{eid}
+// GetEventV2Handler возвращает событие (повторяющееся - с правилом, без развёртывания)
+func (api *API) GetEventV2Handler(w http.ResponseWriter, r *http.Request) {
+
+	var params []InvalidParam
+	userID := pathID(r, "id", &params)
+	eventID := pathID(r, "eid", &params)
+	if params != nil {
+		WriterProblem(w, r, validationProblem(params))
+		return
+	}
+
+	event, p := api.findEvent(userID, eventID)
+	if p != nil {
+		WriterProblem(w, r, p)
+		return
+	}
+
+	WriterJSON(w, http.StatusOK, event)
+}
+
+// PUT /v2/users/{id}/events/{eid}
+// UpdateEventV2Handler заменяет событие целиком, отвечает обновлённым событием
+func (api *API) UpdateEventV2Handler(w http.ResponseWriter, r *http.Request) {
+
+	var params []InvalidParam
+	userID := pathID(r, "id", &params)
+	eventID := pathID(r, "eid", &params)
+	if params != nil {
+		WriterProblem(w, r, validationProblem(params))
+		return
+	}
+
+	var req EventRequest
+	if p := decodeBody(w, r, &req); p != nil {
+		WriterProblem(w, r, p)
+		return
+	}
+	event, params := req.Validate(userID)
+	if params != nil {
+		WriterProblem(w, r, validationProblem(params))
+		return
+	}
+	event.ID = eventID
+
+	if err := api.Storage.Update(event); err != nil {
+		WriterProblem(w, r, storageProblem(err))
+		return
+	}
+
+	WriterJSON(w, http.StatusOK, event)
+}
+
+// DELETE /v2/users/{id}/events/{eid}
+// DeleteEventV2Handler удаляет событие, отвечает 204 без тела
+func (api *API) DeleteEventV2Handler(w http.ResponseWriter, r *http.Request) {
+
+	var params []InvalidParam
+	userID := pathID(r, "id", &params)
+	eventID := pathID(r, "eid", &params)
+	if params != nil {
+		WriterProblem(w, r, validationProblem(params))
+		return
+	}
+
+	if err := api.Storage.Delete(userID, eventID); err != nil {
+		WriterProblem(w, r, storageProblem(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
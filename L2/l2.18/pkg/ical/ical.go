@@ -0,0 +1,357 @@
+// Package ical читает и записывает события календаря в формате iCalendar (RFC 5545)
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/IPampurin/calendar-server/pkg/storage"
+)
+
+const (
+	prodID        = "-//IPampurin//calendar-server//RU"
+	maxLineOctets = 75 // длина строки без CRLF, после которой строка переносится (RFC 5545, 3.1)
+	untitled      = "Без названия"
+)
+
+// Encode записывает события одним календарём VCALENDAR; now - отметка DTSTAMP
+func Encode(w io.Writer, events []*storage.Event, now time.Time) error {
+
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	line("CALSCALE", "GREGORIAN")
+
+	for _, event := range events {
+		date := isDate(event.Date)
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("%d-%d@calendar-server", event.UserID, event.ID))
+		line("DTSTAMP", storage.FormatICalTime(now, false))
+		if date {
+			line("DTSTART;VALUE=DATE", storage.FormatICalTime(event.Date, true))
+		} else {
+			line("DTSTART", storage.FormatICalTime(event.Date, false))
+		}
+		line("SUMMARY", escapeText(event.Title))
+		if event.Content != "" {
+			line("DESCRIPTION", escapeText(event.Content))
+		}
+		if event.Recurrence != nil {
+			line("RRULE", event.Recurrence.String())
+		}
+		for _, ex := range event.ExDates {
+			// EXDATE должен быть того же вида, что и DTSTART
+			if date {
+				line("EXDATE;VALUE=DATE", storage.FormatICalTime(ex, true))
+			} else {
+				line("EXDATE", storage.FormatICalTime(ex, false))
+			}
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+
+	return bw.Flush()
+}
+
+// isDate сообщает, что время - начало суток, то есть событие задано датой без времени
+func isDate(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+// writeFolded пишет строку, перенося её каждые 75 октетов (не разрывая символы UTF-8)
+func writeFolded(w *bufio.Writer, s string) {
+
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1 // пробел в начале продолжения входит в длину строки
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// escapeText экранирует значение типа TEXT
+func escapeText(s string) string {
+
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+	return r.Replace(s)
+}
+
+// unescapeText снимает экранирование значения типа TEXT
+func unescapeText(s string) string {
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// property - строка содержимого: NAME;PARAM=VALUE:значение
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Skipped - событие календаря, которое не удалось импортировать
+type Skipped struct {
+	UID    string `json:"uid,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Decode читает события VEVENT из календаря. Поддерживаются DTSTART (дата, UTC, TZID или
+// плавающее время - оно считается UTC), SUMMARY, DESCRIPTION, RRULE и EXDATE; прочие свойства
+// и компоненты (VTIMEZONE, VTODO, VALARM...) пропускаются. События, которые нельзя представить
+// в календаре (нет DTSTART, неподдерживаемое RRULE), не прерывают разбор, а попадают в skipped.
+// У событий не заполнены ID и UserID
+func Decode(r io.Reader) (events []*storage.Event, skipped []Skipped, err error) {
+
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		stack    []string // открытые компоненты
+		current  []property
+		inEvent  bool
+		calendar bool
+	)
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("строка %d: %w", n+1, err)
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			component := strings.ToUpper(prop.value)
+			if len(stack) == 0 && component != "VCALENDAR" {
+				return nil, nil, fmt.Errorf("строка %d: ожидался BEGIN:VCALENDAR", n+1)
+			}
+			stack = append(stack, component)
+			calendar = true
+			if component == "VEVENT" && len(stack) == 2 {
+				inEvent = true
+				current = current[:0]
+			}
+			continue
+		case "END":
+			component := strings.ToUpper(prop.value)
+			if len(stack) == 0 || stack[len(stack)-1] != component {
+				return nil, nil, fmt.Errorf("строка %d: END:%s без BEGIN", n+1, prop.value)
+			}
+			stack = stack[:len(stack)-1]
+			if component == "VEVENT" && len(stack) == 1 {
+				inEvent = false
+				event, uid, err := toEvent(current)
+				if err != nil {
+					skipped = append(skipped, Skipped{UID: uid, Reason: err.Error()})
+				} else {
+					events = append(events, event)
+				}
+			}
+			continue
+		}
+
+		// свойства вложенных в VEVENT компонентов (VALARM) не относятся к событию
+		if inEvent && len(stack) == 2 {
+			current = append(current, prop)
+		}
+	}
+
+	if len(stack) != 0 {
+		return nil, nil, fmt.Errorf("нет END:%s", stack[len(stack)-1])
+	}
+	if !calendar {
+		return nil, nil, errors.New("нет BEGIN:VCALENDAR")
+	}
+
+	return events, skipped, nil
+}
+
+// unfold читает строки, склеивая перенесённые (продолжение начинается с пробела или табуляции)
+func unfold(r io.Reader) ([]string, error) {
+
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSuffix(sc.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// parseLine разбирает строку содержимого; значения параметров могут быть в кавычках
+func parseLine(line string) (property, error) {
+
+	prop := property{params: make(map[string]string)}
+
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return prop, fmt.Errorf("неверная строка %q", line)
+	}
+	prop.name = strings.ToUpper(line[:i])
+
+	for line[i] == ';' {
+		line = line[i+1:]
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return prop, fmt.Errorf("неверный параметр в %s", prop.name)
+		}
+		name := strings.ToUpper(line[:eq])
+		line = line[eq+1:]
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				return prop, fmt.Errorf("незакрытая кавычка в %s", prop.name)
+			}
+			value = line[1 : end+1]
+			line = line[end+2:]
+			i = 0
+		} else {
+			i = strings.IndexAny(line, ";:")
+			if i < 0 {
+				return prop, fmt.Errorf("нет значения у %s", prop.name)
+			}
+			value = line[:i]
+			line = line[i:]
+			i = 0
+		}
+		if line == "" || (line[0] != ';' && line[0] != ':') {
+			return prop, fmt.Errorf("нет значения у %s", prop.name)
+		}
+		prop.params[name] = value
+	}
+	prop.value = line[i+1:]
+
+	return prop, nil
+}
+
+// toEvent собирает событие из свойств VEVENT; uid возвращается и при ошибке
+func toEvent(props []property) (event *storage.Event, uid string, err error) {
+
+	event = &storage.Event{}
+	var hasStart, startDate bool
+	for _, p := range props {
+		switch p.name {
+		case "UID":
+			uid = p.value
+		case "SUMMARY":
+			event.Title = unescapeText(p.value)
+		case "DESCRIPTION":
+			event.Content = unescapeText(p.value)
+		case "DTSTART":
+			times, date, err := parseTimes(p)
+			if err != nil {
+				return nil, uid, fmt.Errorf("DTSTART: %w", err)
+			}
+			event.Date, startDate, hasStart = times[0], date, true
+		case "RRULE":
+			if event.Recurrence != nil {
+				return nil, uid, errors.New("несколько RRULE не поддерживаются")
+			}
+			if event.Recurrence, err = storage.ParseRRule(p.value); err != nil {
+				return nil, uid, err
+			}
+		case "EXDATE":
+			times, _, err := parseTimes(p)
+			if err != nil {
+				return nil, uid, fmt.Errorf("EXDATE: %w", err)
+			}
+			event.ExDates = append(event.ExDates, times...)
+		case "RDATE":
+			return nil, uid, errors.New("RDATE не поддерживается")
+		}
+	}
+
+	if !hasStart {
+		return nil, uid, errors.New("нет DTSTART")
+	}
+	if event.Title == "" {
+		event.Title = untitled
+	}
+	// UNTIL у события с датой без времени - тоже дата; у UNTIL в UTC сравниваем время
+	if r := event.Recurrence; r != nil && startDate && !r.Until.IsZero() && !r.UntilDate {
+		y, m, d := r.Until.Date()
+		r.Until, r.UntilDate = time.Date(y, m, d, 0, 0, 0, 0, time.UTC), true
+	}
+
+	return event, uid, nil
+}
+
+// parseTimes разбирает значение DTSTART или EXDATE (список через запятую) с учётом TZID;
+// даты без времени и плавающее время считаются в UTC
+func parseTimes(p property) ([]time.Time, bool, error) {
+
+	loc := time.UTC
+	if tzid := p.params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = time.LoadLocation(strings.TrimPrefix(tzid, "/")); err != nil {
+			return nil, false, fmt.Errorf("неизвестный часовой пояс %q", tzid)
+		}
+	}
+
+	var times []time.Time
+	date := false
+	for _, s := range strings.Split(p.value, ",") {
+		t, isDate, err := storage.ParseICalTime(strings.TrimSpace(s), loc)
+		if err != nil {
+			return nil, false, err
+		}
+		if value := p.params["VALUE"]; value != "" && strings.EqualFold(value, "DATE") != isDate {
+			return nil, false, fmt.Errorf("значение %q не соответствует VALUE=%s", s, value)
+		}
+		times = append(times, t)
+		date = isDate
+	}
+	if len(times) == 0 {
+		return nil, false, errors.New("пустое значение")
+	}
+
+	return times, date, nil
+}
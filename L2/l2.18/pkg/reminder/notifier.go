@@ -0,0 +1,111 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Notification - напоминание о повторении события
+type Notification struct {
+	UserID       int       `json:"user_id"`
+	EventID      int       `json:"event_id"`
+	Title        string    `json:"title"`
+	Content      string    `json:"content,omitempty"`
+	Start        time.Time `json:"start"`         // начало события (для повторяющегося - этого повторения)
+	RemindBefore int       `json:"remind_before"` // минут до начала
+}
+
+// Notifier доставляет напоминания
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier пишет напоминания в лог
+type LogNotifier struct {
+	Logger *log.Logger
+}
+
+// Notify пишет напоминание в лог
+func (l LogNotifier) Notify(ctx context.Context, n Notification) error {
+
+	l.Logger.Printf("%s Напоминание пользователю %d: событие %d %q начнётся %s\n",
+		time.Now().Format("2006-01-02 15:04:05"), n.UserID, n.EventID, n.Title, n.Start.Format(time.RFC3339))
+
+	return nil
+}
+
+// SignatureHeader - заголовок с подписью тела запроса вебхука: "sha256=<hex HMAC-SHA256>"
+const SignatureHeader = "X-Calendar-Signature"
+
+// WebhookNotifier отправляет напоминание POST-запросом с JSON-телом Notification;
+// при заданном Secret тело подписывается (заголовок SignatureHeader)
+type WebhookNotifier struct {
+	URL    string
+	Secret string
+	Client *http.Client // nil - клиент с таймаутом 10 секунд
+}
+
+// Notify отправляет напоминание; ответ не 2xx считается ошибкой
+func (wh *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if wh.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(wh.Secret, body))
+	}
+
+	client := wh.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("вебхук ответил %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Sign возвращает подпись тела запроса вебхука
+func Sign(secret string, body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NotifierFromEnv выбирает способ доставки: вебхук CALENDAR_REMINDER_WEBHOOK
+// (подпись - ключом CALENDAR_REMINDER_SECRET) или, если он не задан, лог logger
+func NotifierFromEnv(logger *log.Logger) Notifier {
+
+	if url := os.Getenv("CALENDAR_REMINDER_WEBHOOK"); url != "" {
+		return &WebhookNotifier{URL: url, Secret: os.Getenv("CALENDAR_REMINDER_SECRET")}
+	}
+
+	return LogNotifier{Logger: logger}
+}
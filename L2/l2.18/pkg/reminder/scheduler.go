@@ -0,0 +1,296 @@
+// Package reminder отправляет напоминания о событиях календаря: планировщик хранит ближайшее
+// напоминание каждого события и передаёт его в Notifier (лог, вебхук) в срок
+package reminder
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/IPampurin/calendar-server/pkg/storage"
+)
+
+// notifyTimeout ограничивает доставку одного напоминания
+const notifyTimeout = 30 * time.Second
+
+// key - событие пользователя
+type key struct {
+	userID, eventID int
+}
+
+// item - ожидающее напоминание о повторении события
+type item struct {
+	key
+	at    time.Time      // когда напомнить
+	start time.Time      // начало повторения
+	event *storage.Event // копия события
+	index int            // место в куче
+}
+
+// queue - куча напоминаний по времени
+type queue []*item
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].at.Before(q[j].at) }
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+func (q *queue) Push(x any) {
+	it := x.(*item)
+	it.index = len(*q)
+	*q = append(*q, it)
+}
+func (q *queue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return it
+}
+
+// Scheduler - планировщик напоминаний. У каждого события ждёт не больше одного напоминания -
+// о ближайшем повторении; после отправки планируется следующее. Напоминание, время которого
+// уже прошло (например, пока сервер был остановлен), не отправляется
+type Scheduler struct {
+	notifier Notifier
+	logger   *log.Logger
+
+	mu      sync.Mutex
+	queue   queue
+	pending map[key]*item
+	wake    chan struct{} // очередь изменилась - Run пересчитывает ожидание
+	wg      sync.WaitGroup
+}
+
+// NewScheduler создаёт планировщик, ошибки доставки пишутся в logger
+func NewScheduler(notifier Notifier, logger *log.Logger) *Scheduler {
+
+	return &Scheduler{
+		notifier: notifier,
+		logger:   logger,
+		pending:  make(map[key]*item),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Load планирует напоминания всех событий хранилища (при запуске сервера)
+func (s *Scheduler) Load(repo storage.Repository) error {
+
+	users, err := repo.Users()
+	if err != nil {
+		return err
+	}
+	for _, userID := range users {
+		events, err := repo.GetAll(userID)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			s.Schedule(event)
+		}
+	}
+
+	return nil
+}
+
+// Schedule (пере)планирует напоминание события; событие без напоминания снимается с учёта
+func (s *Scheduler) Schedule(event *storage.Event) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{event.UserID, event.ID}
+	s.remove(k)
+
+	if event.RemindBefore == nil {
+		return
+	}
+	copied := *event
+	s.push(&copied, time.Now())
+}
+
+// Cancel снимает напоминание удалённого события
+func (s *Scheduler) Cancel(userID, eventID int) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(key{userID, eventID})
+}
+
+// Pending возвращает время ближайшего напоминания события
+func (s *Scheduler) Pending(userID, eventID int) (time.Time, bool) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it, ok := s.pending[key{userID, eventID}]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return it.at, true
+}
+
+// remove убирает напоминание события из очереди (вызывается под s.mu)
+func (s *Scheduler) remove(k key) {
+
+	if it, ok := s.pending[k]; ok {
+		heap.Remove(&s.queue, it.index)
+		delete(s.pending, k)
+		s.signal()
+	}
+}
+
+// push ставит в очередь напоминание о первом повторении, время напоминания которого
+// не раньше from (вызывается под s.mu)
+func (s *Scheduler) push(event *storage.Event, from time.Time) {
+
+	before := time.Duration(*event.RemindBefore) * time.Minute
+	start, ok := event.NextOccurrence(from.Add(before))
+	if !ok {
+		return
+	}
+
+	it := &item{key: key{event.UserID, event.ID}, at: start.Add(-before), start: start, event: event}
+	heap.Push(&s.queue, it)
+	s.pending[it.key] = it
+	s.signal()
+}
+
+// signal будит Run, не блокируясь
+func (s *Scheduler) signal() {
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run отправляет напоминания в срок до отмены ctx, затем ждёт начатые отправки
+func (s *Scheduler) Run(ctx context.Context) {
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		wait := s.fireDue(ctx)
+
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// fireDue отправляет наступившие напоминания, планирует следующие повторения
+// и возвращает время до ближайшего напоминания
+func (s *Scheduler) fireDue(ctx context.Context) time.Duration {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for len(s.queue) > 0 && !s.queue[0].at.After(now) {
+		it := heap.Pop(&s.queue).(*item)
+		delete(s.pending, it.key)
+
+		s.wg.Add(1)
+		go s.notify(ctx, Notification{
+			UserID:       it.userID,
+			EventID:      it.eventID,
+			Title:        it.event.Title,
+			Content:      it.event.Content,
+			Start:        it.start,
+			RemindBefore: *it.event.RemindBefore,
+		})
+
+		// следующее повторение - после отправленного
+		from := now
+		if next := it.start.Add(time.Nanosecond - time.Duration(*it.event.RemindBefore)*time.Minute); next.After(from) {
+			from = next
+		}
+		s.push(it.event, from)
+	}
+
+	if len(s.queue) == 0 {
+		return time.Hour
+	}
+
+	return time.Until(s.queue[0].at)
+}
+
+// notify доставляет напоминание, ошибку пишет в лог
+func (s *Scheduler) notify(ctx context.Context, n Notification) {
+
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	if err := s.notifier.Notify(ctx, n); err != nil {
+		s.logger.Printf("%s Ошибка отправки напоминания о событии %d пользователя %d: %v\n",
+			time.Now().Format("2006-01-02 15:04:05"), n.EventID, n.UserID, err)
+	}
+}
+
+// Repository - хранилище, сообщающее планировщику об изменениях событий:
+// напоминания перепланируются при создании, изменении и удалении
+type Repository struct {
+	storage.Repository
+	scheduler *Scheduler
+}
+
+// Wrap возвращает хранилище repo, изменения событий в котором учитывает планировщик
+func (s *Scheduler) Wrap(repo storage.Repository) *Repository {
+	return &Repository{Repository: repo, scheduler: s}
+}
+
+// Create добавляет событие через Add, чтобы его учёл планировщик
+func (r *Repository) Create(userID int, date time.Time, title, content string) (int, error) {
+	return r.Add(&storage.Event{UserID: userID, Date: date, Title: title, Content: content})
+}
+
+// Add добавляет событие и планирует его напоминание
+func (r *Repository) Add(event *storage.Event) (int, error) {
+
+	id, err := r.Repository.Add(event)
+	if err != nil {
+		return id, err
+	}
+
+	added := *event
+	added.ID = id
+	r.scheduler.Schedule(&added)
+
+	return id, nil
+}
+
+// Update обновляет событие и перепланирует его напоминание
+func (r *Repository) Update(event *storage.Event) error {
+
+	if err := r.Repository.Update(event); err != nil {
+		return err
+	}
+	r.scheduler.Schedule(event)
+
+	return nil
+}
+
+// Delete удаляет событие и снимает его напоминание
+func (r *Repository) Delete(userID, eventID int) error {
+
+	if err := r.Repository.Delete(userID, eventID); err != nil {
+		return err
+	}
+	r.scheduler.Cancel(userID, eventID)
+
+	return nil
+}
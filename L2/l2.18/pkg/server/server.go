@@ -12,7 +12,9 @@ import (
 	"time"
 
 	"github.com/IPampurin/calendar-server/pkg/api"
+	"github.com/IPampurin/calendar-server/pkg/reminder"
 	"github.com/IPampurin/calendar-server/pkg/storage"
+	"github.com/IPampurin/calendar-server/pkg/web"
 )
 
 const calendarPortDefault = "8081"
@@ -25,9 +27,6 @@ func Run(db storage.Repository) error {
 		port = calendarPortDefault
 	}
 
-	// инициализируем api
-	api.Init(db)
-
 	// настраиваем логирование
 	logger, logFile, err := SetupLogging()
 	if err != nil {
@@ -35,6 +34,29 @@ func Run(db storage.Repository) error {
 	}
 	defer logFile.Close()
 
+	// планировщик напоминаний: загружаем события хранилища, дальше он узнаёт об изменениях
+	// через обёртку хранилища
+	scheduler := reminder.NewScheduler(reminder.NotifierFromEnv(logger), logger)
+	if err := scheduler.Load(db); err != nil {
+		return fmt.Errorf("ошибка загрузки напоминаний: %w", err)
+	}
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone := make(chan struct{})
+	go func() {
+		scheduler.Run(schedulerCtx)
+		close(schedulerDone)
+	}()
+	defer func() {
+		stopScheduler()
+		<-schedulerDone
+	}()
+
+	// инициализируем api
+	api.Init(scheduler.Wrap(db))
+
+	// веб-интерфейс (месяц событий) поверх JSON API
+	http.Handle("GET /", web.Handler())
+
 	// оборачиваем в middleware
 	handler := LoggingMiddleware(logger)(http.DefaultServeMux)
 
@@ -47,8 +69,6 @@ func Run(db storage.Repository) error {
 	// канал для shutdown
 	idleConnsClosed := make(chan struct{})
 
-	// http.Handle("/", http.FileServer(http.Dir("web"))) // здесь может быть запуск фронтэнда
-
 	// горутина для graceful shutdown
 	go func() {
 
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// виды ошибок хранилища, проверяются через errors.Is
+var (
+	ErrInvalid  = errors.New("неверные данные события")             // событие не прошло проверку
+	ErrNotFound = errors.New("пользователь или событие не найдены") // нет пользователя или события
+)
+
+// kindError - ошибка со своим текстом, относящаяся к виду kind (ErrInvalid или ErrNotFound)
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Unwrap() error { return e.kind }
+
+// ошибки и проверки, общие для всех реализаций Repository
+
+func errInvalidUser() error {
+	return &kindError{ErrInvalid, "ошибочный ID пользователя"}
+}
+
+func errEmptyTitle() error {
+	return &kindError{ErrInvalid, "поле title должно быть заполнено"}
+}
+
+func errNilEvent() error {
+	return &kindError{ErrInvalid, "событие не может быть nil"}
+}
+
+func errInvalidRecurrence(err error) error {
+	return &kindError{ErrInvalid, err.Error()}
+}
+
+func errUserNotFound(userID int) error {
+	return &kindError{ErrNotFound, fmt.Sprintf("пользователь с %d не найден", userID)}
+}
+
+func errNoEvents(userID int) error {
+	return &kindError{ErrNotFound, fmt.Sprintf("у пользователя с %d событий ваще не найдено", userID)}
+}
+
+func errEventNotFound(eventID int) error {
+	return &kindError{ErrNotFound, fmt.Sprintf("событие с %d не найдено", eventID)}
+}
+
+// MaxRemindBefore - наибольшее время напоминания до начала события, минут (4 недели)
+const MaxRemindBefore = 4 * 7 * 24 * 60
+
+// validateOptional проверяет необязательные поля события: правило повторения и напоминание
+func validateOptional(event *Event) error {
+
+	if event.Recurrence != nil {
+		if err := event.Recurrence.Validate(); err != nil {
+			return errInvalidRecurrence(err)
+		}
+	}
+	if r := event.RemindBefore; r != nil && (*r < 0 || *r > MaxRemindBefore) {
+		return &kindError{ErrInvalid, fmt.Sprintf("remind_before должно быть от 0 до %d минут", MaxRemindBefore)}
+	}
+
+	return nil
+}
+
+// validateNew проверяет данные нового события
+func validateNew(userID int, title string) error {
+
+	if userID < 0 {
+		return errInvalidUser()
+	}
+	if title == "" {
+		return errEmptyTitle()
+	}
+
+	return nil
+}
+
+// validateEvent проверяет событие перед добавлением
+func validateEvent(event *Event) error {
+
+	if event == nil {
+		return errNilEvent()
+	}
+	if err := validateNew(event.UserID, event.Title); err != nil {
+		return err
+	}
+
+	return validateOptional(event)
+}
+
+// dayPeriod, weekPeriod и monthPeriod возвращают границы [from, to) дня, недели или месяца,
+// в который попадает date
+func dayPeriod(date time.Time) (time.Time, time.Time) {
+
+	from := dayNormalizer(date)
+
+	return from, from.AddDate(0, 0, 1)
+}
+
+func weekPeriod(date time.Time) (time.Time, time.Time) {
+
+	from := weekNormalizer(date)
+
+	return from, from.AddDate(0, 0, 7)
+}
+
+func monthPeriod(date time.Time) (time.Time, time.Time) {
+
+	from := monthNormalizer(date)
+
+	return from, from.AddDate(0, 1, 0)
+}
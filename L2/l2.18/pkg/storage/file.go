@@ -0,0 +1,456 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	journalName          = "journal.log"   // журнал изменений
+	snapshotName         = "snapshot.json" // снимок состояния
+	snapshotEveryDefault = 1000            // записей журнала между снимками по умолчанию
+)
+
+// операции журнала
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+)
+
+// journalRecord - запись журнала: одно изменение хранилища
+type journalRecord struct {
+	Seq     uint64 `json:"seq"` // порядковый номер изменения
+	Op      string `json:"op"`
+	Event   *Event `json:"event,omitempty"`    // для create и update
+	UserID  int    `json:"user_id,omitempty"`  // для delete
+	EventID int    `json:"event_id,omitempty"` // для delete
+}
+
+// snapshot - снимок состояния хранилища на момент изменения Seq
+type snapshot struct {
+	Seq    uint64           `json:"seq"`
+	NextID int              `json:"next_id"`
+	Events map[int][]*Event `json:"events"`
+}
+
+// FileStorage - хранилище в каталоге на диске. Каждое изменение дописывается в журнал
+// и сбрасывается на диск (fsync) до ответа; периодически состояние целиком сохраняется
+// в снимок, а журнал очищается. При открытии загружается снимок и применяются записи
+// журнала после него; запись, оборванная при аварийном завершении, отбрасывается
+type FileStorage struct {
+	mu            sync.Mutex // упорядочивает изменения и запись в журнал
+	mem           *Storage   // текущее состояние, из него отвечают запросы на чтение
+	dir           string
+	journal       *os.File
+	size          int64  // размер журнала после последней целой записи
+	seq           uint64 // номер последнего изменения
+	records       int    // записей в журнале после снимка
+	snapshotEvery int
+	err           error // ошибка записи, после которой журнал не дописывается
+}
+
+// NewFileStorage открывает (или создаёт) хранилище в каталоге dir;
+// снимок делается каждые snapshotEvery записей журнала (0 - значение по умолчанию)
+func NewFileStorage(dir string, snapshotEvery int) (*FileStorage, error) {
+
+	if snapshotEvery <= 0 {
+		snapshotEvery = snapshotEveryDefault
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	fs := &FileStorage{
+		mem:           NewStorage(),
+		dir:           dir,
+		snapshotEvery: snapshotEvery,
+	}
+
+	if err := fs.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := fs.loadJournal(); err != nil {
+		return nil, err
+	}
+
+	if fs.records >= fs.snapshotEvery {
+		if err := fs.saveSnapshot(); err != nil {
+			fs.journal.Close()
+			return nil, err
+		}
+	}
+
+	return fs, nil
+}
+
+// loadSnapshot загружает последний снимок, если он есть
+func (fs *FileStorage) loadSnapshot() error {
+
+	data, err := os.ReadFile(filepath.Join(fs.dir, snapshotName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("снимок %s повреждён: %w", snapshotName, err)
+	}
+	if snap.Events == nil {
+		snap.Events = make(map[int][]*Event)
+	}
+	fs.mem.Events = snap.Events
+	fs.mem.NextID = max(snap.NextID, 1)
+	fs.seq = snap.Seq
+
+	return nil
+}
+
+// loadJournal применяет записи журнала, сделанные после снимка, и открывает журнал для дозаписи
+func (fs *FileStorage) loadJournal() error {
+
+	f, err := os.OpenFile(filepath.Join(fs.dir, journalName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(f)
+	var offset int64
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// строка без перевода строки - запись оборвалась при сбое
+			break
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		rec, perr := parseRecord(data)
+		if perr != nil {
+			if _, err := r.Peek(1); err == io.EOF {
+				// испорчена последняя запись - её запись не завершилась
+				break
+			}
+			f.Close()
+			return fmt.Errorf("журнал %s повреждён, строка %d: %w", journalName, line, perr)
+		}
+
+		offset += int64(len(data))
+		fs.records++
+		if rec.Seq <= fs.seq {
+			// запись уже учтена в снимке (сбой между снимком и очисткой журнала)
+			continue
+		}
+		if err := fs.apply(rec); err != nil {
+			f.Close()
+			return fmt.Errorf("журнал %s, строка %d: %w", journalName, line, err)
+		}
+		fs.seq = rec.Seq
+	}
+
+	// отрезаем оборванный хвост, дальше дописываем с конца целых записей
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	fs.journal = f
+	fs.size = offset
+
+	return nil
+}
+
+// parseRecord разбирает строку журнала "<crc32> <json>\n"
+func parseRecord(line []byte) (journalRecord, error) {
+
+	var rec journalRecord
+
+	sum, payload, ok := bytes.Cut(bytes.TrimSuffix(line, []byte("\n")), []byte(" "))
+	if !ok {
+		return rec, errors.New("нет контрольной суммы")
+	}
+	want, err := strconv.ParseUint(string(sum), 16, 32)
+	if err != nil || uint32(want) != crc32.ChecksumIEEE(payload) {
+		return rec, errors.New("неверная контрольная сумма")
+	}
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return rec, err
+	}
+
+	return rec, nil
+}
+
+// apply применяет запись журнала к состоянию в памяти
+func (fs *FileStorage) apply(rec journalRecord) error {
+
+	switch rec.Op {
+	case opCreate:
+		if rec.Event == nil {
+			return errNilEvent()
+		}
+		fs.mem.Mu.Lock()
+		fs.mem.NextID = rec.Event.ID
+		fs.mem.Mu.Unlock()
+		_, err := fs.mem.Add(rec.Event)
+		return err
+	case opUpdate:
+		return fs.mem.Update(rec.Event)
+	case opDelete:
+		return fs.mem.Delete(rec.UserID, rec.EventID)
+	default:
+		return fmt.Errorf("неизвестная операция %q", rec.Op)
+	}
+}
+
+// write дописывает запись в журнал и сбрасывает её на диск; при ошибке журнал
+// возвращается к прежнему размеру, а если это не удалось - дальнейшие изменения запрещаются
+func (fs *FileStorage) write(rec journalRecord) error {
+
+	if fs.err != nil {
+		return fs.err
+	}
+	if fs.journal == nil {
+		return errors.New("хранилище закрыто")
+	}
+
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line := fmt.Appendf(nil, "%08x %s\n", crc32.ChecksumIEEE(payload), payload)
+
+	if _, err = fs.journal.Write(line); err == nil {
+		err = fs.journal.Sync()
+	}
+	if err != nil {
+		if terr := fs.journal.Truncate(fs.size); terr != nil {
+			fs.err = fmt.Errorf("журнал недоступен для записи: %w", err)
+		}
+		fs.journal.Seek(fs.size, io.SeekStart)
+		return fmt.Errorf("ошибка записи журнала: %w", err)
+	}
+
+	fs.size += int64(len(line))
+	fs.seq = rec.Seq
+	fs.records++
+
+	return nil
+}
+
+// commit записывает изменение в журнал, применяет его и при необходимости делает снимок
+func (fs *FileStorage) commit(rec journalRecord) error {
+
+	rec.Seq = fs.seq + 1
+	if err := fs.write(rec); err != nil {
+		return err
+	}
+	if err := fs.apply(rec); err != nil {
+		return err
+	}
+
+	if fs.records >= fs.snapshotEvery {
+		// изменение уже надёжно в журнале - ошибку снимка не показываем клиенту,
+		// снимок повторится при следующем изменении
+		_ = fs.saveSnapshot()
+	}
+
+	return nil
+}
+
+// saveSnapshot сохраняет состояние в снимок и очищает журнал. Снимок пишется во временный
+// файл и переименовывается, поэтому на диске всегда остаётся целый снимок
+func (fs *FileStorage) saveSnapshot() error {
+
+	fs.mem.Mu.RLock()
+	data, err := json.Marshal(snapshot{Seq: fs.seq, NextID: fs.mem.NextID, Events: fs.mem.Events})
+	fs.mem.Mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(fs.dir, snapshotName)
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	if err := syncDir(fs.dir); err != nil {
+		return err
+	}
+
+	// сбой до очистки журнала не страшен: записи с номерами из снимка пропускаются
+	if err := fs.journal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := fs.journal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	fs.size = 0
+	fs.records = 0
+
+	return fs.journal.Sync()
+}
+
+// writeFileSync записывает файл и сбрасывает его на диск
+func writeFileSync(path string, data []byte) error {
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// syncDir сбрасывает на диск каталог (после переименования файла)
+func syncDir(dir string) error {
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// Close сохраняет снимок и закрывает журнал
+func (fs *FileStorage) Close() error {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.journal == nil {
+		return nil
+	}
+
+	var err error
+	if fs.err == nil && fs.records > 0 {
+		err = fs.saveSnapshot()
+	}
+	if cerr := fs.journal.Close(); err == nil {
+		err = cerr
+	}
+	fs.journal = nil
+
+	return err
+}
+
+// Create добавляет event в хранилище, возвращает ID event или ошибку
+func (fs *FileStorage) Create(userID int, date time.Time, title, content string) (int, error) {
+	return fs.Add(&Event{UserID: userID, Date: date, Title: title, Content: content})
+}
+
+// Add добавляет event целиком (с повторениями), ID назначает хранилище
+func (fs *FileStorage) Add(event *Event) (int, error) {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := validateEvent(event); err != nil {
+		return 0, err
+	}
+
+	fs.mem.Mu.RLock()
+	id := fs.mem.NextID
+	fs.mem.Mu.RUnlock()
+
+	added := *event
+	added.ID = id
+	if err := fs.commit(journalRecord{Op: opCreate, Event: &added}); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Update обновляет event в хранилище, возвращает ошибку, если событие не найдено
+func (fs *FileStorage) Update(event *Event) error {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if event == nil {
+		return errNilEvent()
+	}
+	if err := fs.mem.check(event.UserID, event.ID); err != nil {
+		return err
+	}
+	if err := validateOptional(event); err != nil {
+		return err
+	}
+
+	updated := *event
+
+	return fs.commit(journalRecord{Op: opUpdate, Event: &updated})
+}
+
+// Delete удаляет event из хранилища, возвращает ошибку, если событие не найдено
+func (fs *FileStorage) Delete(userID, eventID int) error {
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.mem.check(userID, eventID); err != nil {
+		return err
+	}
+
+	return fs.commit(journalRecord{Op: opDelete, UserID: userID, EventID: eventID})
+}
+
+// GetForDay возвращает перечень событий на день или ошибку
+func (fs *FileStorage) GetForDay(userID int, date time.Time) ([]*Event, error) {
+	return fs.mem.GetForDay(userID, date)
+}
+
+// GetForWeek возвращает перечень событий на неделю или ошибку
+func (fs *FileStorage) GetForWeek(userID int, date time.Time) ([]*Event, error) {
+	return fs.mem.GetForWeek(userID, date)
+}
+
+// GetForMonth возвращает перечень событий на месяц или ошибку
+func (fs *FileStorage) GetForMonth(userID int, date time.Time) ([]*Event, error) {
+	return fs.mem.GetForMonth(userID, date)
+}
+
+// GetForPeriod возвращает события с датой в [from, to), повторяющиеся - развёрнутыми
+func (fs *FileStorage) GetForPeriod(userID int, from, to time.Time) ([]*Event, error) {
+	return fs.mem.GetForPeriod(userID, from, to)
+}
+
+// GetAll возвращает все события пользователя (повторяющиеся - без развёртывания)
+func (fs *FileStorage) GetAll(userID int) ([]*Event, error) {
+	return fs.mem.GetAll(userID)
+}
+
+// Users возвращает ID известных хранилищу пользователей по возрастанию
+func (fs *FileStorage) Users() ([]int, error) {
+	return fs.mem.Users()
+}
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// userShard - события одного пользователя со своей блокировкой
+type userShard struct {
+	mu        sync.RWMutex
+	byID      map[int]*Event // все события по ID - Update и Delete без перебора
+	byDate    *skipList      // события без повторений по дате - выборка за период O(log n + k)
+	recurring map[int]*Event // повторяющиеся события - разворачиваются при каждой выборке
+}
+
+func newUserShard() *userShard {
+
+	return &userShard{
+		byID:      make(map[int]*Event),
+		byDate:    newSkipList(),
+		recurring: make(map[int]*Event),
+	}
+}
+
+// put добавляет событие в индексы
+func (sh *userShard) put(event *Event) {
+
+	sh.byID[event.ID] = event
+	if event.Recurrence != nil {
+		sh.recurring[event.ID] = event
+	} else {
+		sh.byDate.insert(event)
+	}
+}
+
+// remove убирает событие из индексов
+func (sh *userShard) remove(event *Event) {
+
+	delete(sh.byID, event.ID)
+	if event.Recurrence != nil {
+		delete(sh.recurring, event.ID)
+	} else {
+		sh.byDate.remove(event)
+	}
+}
+
+// IndexedStorage - хранилище в памяти с индексами: у каждого пользователя своя часть (shard)
+// с отдельной блокировкой, события в ней упорядочены по дате (список с пропусками) и
+// проиндексированы по ID. Сохранённые события не изменяются: Update заменяет событие
+// новой копией, поэтому отданные раньше указатели можно читать без блокировок
+type IndexedStorage struct {
+	mu     sync.RWMutex       // защищает только карту shards
+	shards map[int]*userShard // user_id -> события пользователя
+	lastID atomic.Int64       // последний выданный ID события
+}
+
+// NewIndexedStorage создаёт новое хранилище с индексами
+func NewIndexedStorage() *IndexedStorage {
+
+	return &IndexedStorage{shards: make(map[int]*userShard)}
+}
+
+// shard возвращает часть пользователя; create - создать, если её нет
+func (s *IndexedStorage) shard(userID int, create bool) *userShard {
+
+	s.mu.RLock()
+	sh := s.shards[userID]
+	s.mu.RUnlock()
+	if sh != nil || !create {
+		return sh
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sh = s.shards[userID]; sh == nil {
+		sh = newUserShard()
+		s.shards[userID] = sh
+	}
+
+	return sh
+}
+
+// Create добавляет event в хранилище, возвращает ID event или ошибку
+func (s *IndexedStorage) Create(userID int, date time.Time, title, content string) (int, error) {
+	return s.Add(&Event{UserID: userID, Date: date, Title: title, Content: content})
+}
+
+// Add добавляет event целиком (с повторениями), ID назначает хранилище
+func (s *IndexedStorage) Add(event *Event) (int, error) {
+
+	if err := validateEvent(event); err != nil {
+		return 0, err
+	}
+
+	sh := s.shard(event.UserID, true)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	added := *event
+	added.ID = int(s.lastID.Add(1))
+	sh.put(&added)
+
+	return added.ID, nil
+}
+
+// Update обновляет event в хранилище, возвращает ошибку, если событие не найдено
+func (s *IndexedStorage) Update(event *Event) error {
+
+	if event == nil {
+		return errNilEvent()
+	}
+	if err := validateOptional(event); err != nil {
+		return err
+	}
+
+	sh := s.shard(event.UserID, false)
+	if sh == nil {
+		return errUserNotFound(event.UserID)
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	old, ok := sh.byID[event.ID]
+	if !ok {
+		return errEventNotFound(event.ID)
+	}
+
+	updated := *old
+	updated.Date = event.Date
+	updated.Title = event.Title
+	updated.Content = event.Content
+	updated.Recurrence = event.Recurrence
+	updated.ExDates = event.ExDates
+	updated.RemindBefore = event.RemindBefore
+
+	sh.remove(old)
+	sh.put(&updated)
+
+	return nil
+}
+
+// Delete удаляет event из хранилища, возвращает ошибку, если событие не найдено
+func (s *IndexedStorage) Delete(userID, eventID int) error {
+
+	sh := s.shard(userID, false)
+	if sh == nil {
+		return errUserNotFound(userID)
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	event, ok := sh.byID[eventID]
+	if !ok {
+		return errEventNotFound(eventID)
+	}
+	sh.remove(event)
+
+	return nil
+}
+
+// GetForDay возвращает перечень событий на день или ошибку
+func (s *IndexedStorage) GetForDay(userID int, date time.Time) ([]*Event, error) {
+
+	from, to := dayPeriod(date)
+
+	return s.GetForPeriod(userID, from, to)
+}
+
+// GetForWeek возвращает перечень событий на неделю или ошибку
+func (s *IndexedStorage) GetForWeek(userID int, date time.Time) ([]*Event, error) {
+
+	from, to := weekPeriod(date)
+
+	return s.GetForPeriod(userID, from, to)
+}
+
+// GetForMonth возвращает перечень событий на месяц или ошибку
+func (s *IndexedStorage) GetForMonth(userID int, date time.Time) ([]*Event, error) {
+
+	from, to := monthPeriod(date)
+
+	return s.GetForPeriod(userID, from, to)
+}
+
+// GetForPeriod возвращает события с датой в [from, to), повторяющиеся - развёрнутыми.
+// Как и в остальных хранилищах, события упорядочены по ID
+func (s *IndexedStorage) GetForPeriod(userID int, from, to time.Time) ([]*Event, error) {
+
+	sh := s.shard(userID, false)
+	if sh == nil {
+		return []*Event{}, errUserNotFound(userID)
+	}
+
+	sh.mu.RLock()
+	result := make([]*Event, 0)
+	sh.byDate.ascend(from, to, func(event *Event) {
+		result = append(result, event)
+	})
+	recurring := slices.Collect(maps.Values(sh.recurring))
+	sh.mu.RUnlock()
+
+	// события неизменяемы - разворачиваем повторения уже без блокировки
+	result = append(result, expand(recurring, from, to)...)
+	slices.SortStableFunc(result, func(a, b *Event) int { return cmp.Compare(a.ID, b.ID) })
+
+	return result, nil
+}
+
+// GetAll возвращает все события пользователя (повторяющиеся - без развёртывания)
+func (s *IndexedStorage) GetAll(userID int) ([]*Event, error) {
+
+	sh := s.shard(userID, false)
+	if sh == nil {
+		return []*Event{}, errUserNotFound(userID)
+	}
+
+	sh.mu.RLock()
+	events := slices.Collect(maps.Values(sh.byID))
+	sh.mu.RUnlock()
+
+	slices.SortFunc(events, func(a, b *Event) int { return cmp.Compare(a.ID, b.ID) })
+
+	return events, nil
+}
+
+// Users возвращает ID известных хранилищу пользователей по возрастанию
+func (s *IndexedStorage) Users() ([]int, error) {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(s.shards)), nil
+}
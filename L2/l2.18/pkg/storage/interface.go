@@ -9,14 +9,23 @@ type Event struct {
 	Date    time.Time `json:"date"`              // дата события
 	Title   string    `json:"title"`             // заголовок события
 	Content string    `json:"content,omitempty"` // содержание события
+
+	Recurrence *Recurrence `json:"rrule,omitempty"`   // правило повторения (RRULE), nil - событие без повторений
+	ExDates    []time.Time `json:"exdates,omitempty"` // исключённые дни повторений (EXDATE)
+
+	RemindBefore *int `json:"remind_before,omitempty"` // напоминание за столько минут до начала, nil - без напоминания
 }
 
 // Repository - интерфейс, реализующий требуемые методы
 type Repository interface {
 	Create(userID int, date time.Time, title, content string) (int, error) // добавляет event в хранилище, возвращает ID event или ошибку
+	Add(event *Event) (int, error)                                         // добавляет event целиком (с повторениями), ID назначает хранилище
 	Update(event *Event) error                                             // обновляет event в хранилище, возвращает ошибку, если событие не найдено
 	Delete(userID, eventID int) error                                      // удаляет event из хранилища, возвращает ошибку, если событие не найдено
 	GetForDay(userID int, date time.Time) ([]*Event, error)                // возвращает перечень событий на день или ошибку
 	GetForWeek(userID int, date time.Time) ([]*Event, error)               // возвращает перечень событий на неделю или ошибку
 	GetForMonth(userID int, date time.Time) ([]*Event, error)              // возвращает перечень событий на месяц или ошибку
+	GetForPeriod(userID int, from, to time.Time) ([]*Event, error)         // возвращает события с датой в [from, to) или ошибку
+	GetAll(userID int) ([]*Event, error)                                   // возвращает все события пользователя (повторяющиеся - без развёртывания)
+	Users() ([]int, error)                                                 // возвращает ID известных хранилищу пользователей по возрастанию
 }
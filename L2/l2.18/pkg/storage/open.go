@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// значения переменной окружения CALENDAR_STORAGE
+const (
+	KindMemory  = "memory"  // в памяти, данные теряются при перезапуске (по умолчанию)
+	KindIndexed = "indexed" // в памяти с индексами по дате и ID, части по пользователям
+	KindFile    = "file"    // журнал и снимки в каталоге CALENDAR_DATA
+	KindSQLite  = "sqlite"  // база SQLite в файле CALENDAR_DATA
+)
+
+// пути к данным по умолчанию
+const (
+	fileDataDefault   = "data"
+	sqliteDataDefault = "calendar.db"
+)
+
+// FromEnv создаёт хранилище по переменным окружения:
+// CALENDAR_STORAGE - вид хранилища (memory, indexed, file или sqlite),
+// CALENDAR_DATA - каталог (file) или файл базы (sqlite),
+// CALENDAR_SNAPSHOT_EVERY - записей журнала между снимками (file).
+// Хранилища file и sqlite нужно закрыть методом Close
+func FromEnv() (Repository, error) {
+
+	kind := os.Getenv("CALENDAR_STORAGE")
+	data := os.Getenv("CALENDAR_DATA")
+
+	switch kind {
+	case "", KindMemory:
+		return NewStorage(), nil
+
+	case KindIndexed:
+		return NewIndexedStorage(), nil
+
+	case KindFile:
+		if data == "" {
+			data = fileDataDefault
+		}
+		snapshotEvery := 0
+		if v := os.Getenv("CALENDAR_SNAPSHOT_EVERY"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("CALENDAR_SNAPSHOT_EVERY должно быть положительным числом: %q", v)
+			}
+			snapshotEvery = n
+		}
+		return NewFileStorage(data, snapshotEvery)
+
+	case KindSQLite:
+		if data == "" {
+			data = sqliteDataDefault
+		}
+		return NewSQLiteStorage(data)
+
+	default:
+		return nil, fmt.Errorf("неизвестный вид хранилища CALENDAR_STORAGE=%q (memory, indexed, file или sqlite)", kind)
+	}
+}
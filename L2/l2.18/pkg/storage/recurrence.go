@@ -0,0 +1,401 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// частоты повторения
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+// weekdayCodes - дни недели в RRULE (BYDAY)
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence - правило повторения события, подмножество RRULE из RFC 5545:
+// FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY (только для WEEKLY), COUNT или UNTIL.
+// Первое повторение - дата самого события (DTSTART). В JSON записывается строкой RRULE
+type Recurrence struct {
+	Freq      string         // DAILY, WEEKLY или MONTHLY
+	Interval  int            // шаг в единицах Freq, по умолчанию 1
+	ByDay     []time.Weekday // дни недели для WEEKLY (по умолчанию - день DTSTART)
+	Count     int            // число повторений (0 - без ограничения)
+	Until     time.Time      // последнее повторение не позже (нулевое - без ограничения)
+	UntilDate bool           // UNTIL задан датой без времени: включается весь день
+}
+
+// ParseRRule разбирает значение свойства RRULE, например "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10"
+func ParseRRule(s string) (*Recurrence, error) {
+
+	r := &Recurrence{}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("RRULE: неверная часть %q", part)
+		}
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err == nil && r.Interval <= 0 {
+				err = fmt.Errorf("должен быть положительным")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err == nil && r.Count <= 0 {
+				err = fmt.Errorf("должен быть положительным")
+			}
+		case "UNTIL":
+			r.Until, r.UntilDate, err = ParseICalTime(value, time.UTC)
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[strings.ToUpper(code)]
+				if !ok {
+					return nil, fmt.Errorf("RRULE: BYDAY %q не поддерживается (только MO..SU без номера)", code)
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "WKST":
+			// неделя всегда начинается с понедельника
+			if strings.ToUpper(value) != "MO" {
+				return nil, fmt.Errorf("RRULE: поддерживается только WKST=MO")
+			}
+		default:
+			return nil, fmt.Errorf("RRULE: часть %s не поддерживается", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("RRULE: %s: %w", name, err)
+		}
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Validate проверяет правило
+func (r *Recurrence) Validate() error {
+
+	switch r.Freq {
+	case FreqDaily, FreqMonthly:
+		if len(r.ByDay) > 0 {
+			return fmt.Errorf("RRULE: BYDAY поддерживается только для FREQ=WEEKLY")
+		}
+	case FreqWeekly:
+	case "":
+		return fmt.Errorf("RRULE: FREQ обязателен")
+	default:
+		return fmt.Errorf("RRULE: FREQ=%s не поддерживается (DAILY, WEEKLY или MONTHLY)", r.Freq)
+	}
+	if r.Interval < 0 || r.Count < 0 {
+		return fmt.Errorf("RRULE: INTERVAL и COUNT должны быть положительными")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return fmt.Errorf("RRULE: COUNT и UNTIL нельзя задавать вместе")
+	}
+
+	return nil
+}
+
+// String возвращает правило в виде значения RRULE
+func (r *Recurrence) String() string {
+
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			codes = append(codes, weekdayCode(day))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+FormatICalTime(r.Until, r.UntilDate))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func weekdayCode(day time.Weekday) string {
+
+	for code, d := range weekdayCodes {
+		if d == day {
+			return code
+		}
+	}
+
+	return ""
+}
+
+// MarshalJSON записывает правило строкой RRULE
+func (r *Recurrence) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON читает правило из строки RRULE
+func (r *Recurrence) UnmarshalJSON(data []byte) error {
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseRRule(s)
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+
+	return nil
+}
+
+// ParseICalTime разбирает дату или дату-время RFC 5545: 20260115, 20260115T103000Z
+// или 20260115T103000 (локальное время в loc); date сообщает, что задана только дата
+func ParseICalTime(s string, loc *time.Location) (t time.Time, date bool, err error) {
+
+	switch {
+	case len(s) == 8:
+		t, err = time.ParseInLocation("20060102", s, loc)
+		date = true
+	case strings.HasSuffix(s, "Z"):
+		t, err = time.Parse("20060102T150405Z", s)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", s, loc)
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("неверная дата %q", s)
+	}
+
+	return t, date, nil
+}
+
+// FormatICalTime записывает время в формате RFC 5545: дату или дату-время в UTC
+func FormatICalTime(t time.Time, date bool) string {
+
+	if date {
+		return t.Format("20060102")
+	}
+
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// maxOccurrences ограничивает развёртывание одного события в одном запросе
+const maxOccurrences = 10000
+
+// Occurrences возвращает даты повторений события в периоде [from, to):
+// для обычного события - его дату, если она попадает в период
+func (e *Event) Occurrences(from, to time.Time) []time.Time {
+
+	if e.Recurrence == nil {
+		if !e.Date.Before(from) && e.Date.Before(to) {
+			return []time.Time{e.Date}
+		}
+		return nil
+	}
+
+	var result []time.Time
+	e.Recurrence.each(e.Date, from, to, func(t time.Time) {
+		if !e.excluded(t) {
+			result = append(result, t)
+		}
+	})
+
+	return result
+}
+
+// NextOccurrence возвращает первое повторение события не раньше from
+// (для обычного события - его дату); ok = false, если повторений больше нет
+func (e *Event) NextOccurrence(from time.Time) (t time.Time, ok bool) {
+
+	if e.Recurrence == nil {
+		return e.Date, !e.Date.Before(from)
+	}
+
+	// окно поиска растёт, пока не найдётся повторение; дальше 10 лет не ищем
+	end := from.AddDate(10, 0, 0)
+	for window := 24 * time.Hour; ; window *= 4 {
+		to := from.Add(window)
+		if to.After(end) {
+			to = end
+		}
+		if occurrences := e.Occurrences(from, to); len(occurrences) > 0 {
+			return occurrences[0], true
+		}
+		if !to.Before(end) {
+			return time.Time{}, false
+		}
+	}
+}
+
+// excluded сообщает, что повторение t исключено (EXDATE); исключение действует на весь день
+func (e *Event) excluded(t time.Time) bool {
+
+	for _, ex := range e.ExDates {
+		ex = ex.In(t.Location())
+		if ex.Equal(t) || (ex.Year() == t.Year() && ex.YearDay() == t.YearDay()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// each вызывает f для каждого повторения правила с началом start, попадающего в [from, to)
+func (r *Recurrence) each(start, from, to time.Time, f func(time.Time)) {
+
+	interval := max(r.Interval, 1)
+	count := 0
+	emitted := 0
+
+	// emit учитывает повторение t и сообщает, продолжать ли
+	emit := func(t time.Time) bool {
+		if t.Before(start) {
+			return true
+		}
+		if !t.Before(to) || r.after(t) {
+			return false
+		}
+		count++
+		if r.Count > 0 && count > r.Count {
+			return false
+		}
+		if !t.Before(from) {
+			f(t)
+			emitted++
+		}
+		return emitted < maxOccurrences
+	}
+
+	// без COUNT повторения до начала периода можно пропустить, не перебирая
+	skip := 0
+	if r.Count == 0 && from.After(start) {
+		skip = r.periodsBefore(start, from) / interval
+	}
+
+	switch r.Freq {
+	case FreqDaily:
+		for k := skip; emit(start.AddDate(0, 0, k*interval)); k++ {
+		}
+
+	case FreqWeekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		// дни недели по порядку от понедельника; повторения считаются с понедельника недели DTSTART
+		offsets := make([]int, 0, len(days))
+		for _, day := range days {
+			offsets = append(offsets, (int(day)+6)%7)
+		}
+		slices.Sort(offsets)
+		offsets = slices.Compact(offsets)
+		startOffset := (int(start.Weekday()) + 6) % 7
+		monday := start.AddDate(0, 0, -startOffset)
+		// DTSTART - всегда первое повторение, даже если его дня нет в BYDAY
+		if skip == 0 && !slices.Contains(offsets, startOffset) && !emit(start) {
+			return
+		}
+		for k := skip; ; k++ {
+			week := monday.AddDate(0, 0, 7*k*interval)
+			for _, offset := range offsets {
+				if !emit(week.AddDate(0, 0, offset)) {
+					return
+				}
+			}
+		}
+
+	case FreqMonthly:
+		// месяцы без нужного числа (например, 31-го) пропускаются, как в RFC 5545
+		for k := skip; ; k++ {
+			t := time.Date(start.Year(), start.Month()+time.Month(k*interval), start.Day(),
+				start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+			if t.Day() != start.Day() {
+				if !t.Before(to) {
+					return
+				}
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// after сообщает, что время t позже UNTIL
+func (r *Recurrence) after(t time.Time) bool {
+
+	if r.Until.IsZero() {
+		return false
+	}
+	if r.UntilDate {
+		y, m, d := r.Until.Date()
+		return !t.Before(time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()))
+	}
+
+	return t.After(r.Until)
+}
+
+// periodsBefore возвращает число целых периодов Freq между start и from (с запасом в один)
+func (r *Recurrence) periodsBefore(start, from time.Time) int {
+
+	var n int
+	switch r.Freq {
+	case FreqDaily:
+		n = int(from.Sub(start).Hours() / 24)
+	case FreqWeekly:
+		n = int(from.Sub(start).Hours() / (24 * 7))
+	case FreqMonthly:
+		n = (from.Year()-start.Year())*12 + int(from.Month()) - int(start.Month())
+	}
+
+	return max(n-1, 0)
+}
+
+// expand возвращает события из events, попадающие в период [from, to); повторяющиеся события
+// разворачиваются в отдельные копии с датами повторений
+func expand(events []*Event, from, to time.Time) []*Event {
+
+	result := make([]*Event, 0)
+	for _, event := range events {
+		if event.Recurrence == nil {
+			if !event.Date.Before(from) && event.Date.Before(to) {
+				result = append(result, event)
+			}
+			continue
+		}
+		for _, t := range event.Occurrences(from, to) {
+			instance := *event
+			instance.Date = t
+			result = append(result, &instance)
+		}
+	}
+
+	return result
+}
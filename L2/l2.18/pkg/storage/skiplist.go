@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// параметры списка с пропусками: вероятность перейти на уровень выше 1/4,
+// 16 уровней хватает на 4^16 элементов
+const (
+	skipMaxLevel = 16
+	skipP        = 4
+)
+
+// skipNode - узел списка с пропусками. Ключ (дата и ID) хранится в самом узле,
+// чтобы при поиске не обращаться к событию
+type skipNode struct {
+	date  time.Time
+	id    int
+	event *Event
+	next  []*skipNode // следующий узел на каждом уровне
+}
+
+// before сообщает, что узел идёт раньше ключа (date, id)
+func (n *skipNode) before(date time.Time, id int) bool {
+
+	if n.date.Equal(date) {
+		return n.id < id
+	}
+
+	return n.date.Before(date)
+}
+
+// skipList - события, упорядоченные по (Date, ID): поиск, вставка и удаление за O(log n)
+// в среднем, обход диапазона - O(log n + k). Не безопасен для одновременного использования
+type skipList struct {
+	head  skipNode
+	level int // число используемых уровней
+	len   int
+}
+
+func newSkipList() *skipList {
+
+	return &skipList{head: skipNode{next: make([]*skipNode, skipMaxLevel)}, level: 1}
+}
+
+// randomLevel выбирает высоту нового узла
+func randomLevel() int {
+
+	level := 1
+	for level < skipMaxLevel && rand.IntN(skipP) == 0 {
+		level++
+	}
+
+	return level
+}
+
+// findPath заполняет update последними узлами перед ключом (date, id) на каждом уровне
+func (l *skipList) findPath(date time.Time, id int, update []*skipNode) {
+
+	node := &l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for node.next[i] != nil && node.next[i].before(date, id) {
+			node = node.next[i]
+		}
+		update[i] = node
+	}
+}
+
+// insert добавляет событие (ключ (Date, ID) должен быть уникальным)
+func (l *skipList) insert(e *Event) {
+
+	var update [skipMaxLevel]*skipNode
+	l.findPath(e.Date, e.ID, update[:])
+
+	level := randomLevel()
+	for i := l.level; i < level; i++ {
+		update[i] = &l.head
+	}
+	l.level = max(l.level, level)
+
+	node := &skipNode{date: e.Date, id: e.ID, event: e, next: make([]*skipNode, level)}
+	for i := range level {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+	l.len++
+}
+
+// remove удаляет событие с ключом (e.Date, e.ID) и сообщает, было ли оно в списке
+func (l *skipList) remove(e *Event) bool {
+
+	var update [skipMaxLevel]*skipNode
+	l.findPath(e.Date, e.ID, update[:])
+
+	node := update[0].next[0]
+	if node == nil || node.id != e.ID || !node.date.Equal(e.Date) {
+		return false
+	}
+	for i := range len(node.next) {
+		update[i].next[i] = node.next[i]
+	}
+	for l.level > 1 && l.head.next[l.level-1] == nil {
+		l.level--
+	}
+	l.len--
+
+	return true
+}
+
+// ascend вызывает f для событий с датой в [from, to) по возрастанию даты
+func (l *skipList) ascend(from, to time.Time, f func(*Event)) {
+
+	// первый узел не раньше from: ключ (from, минимальный ID)
+	node := &l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for node.next[i] != nil && node.next[i].date.Before(from) {
+			node = node.next[i]
+		}
+	}
+
+	for node = node.next[0]; node != nil && node.date.Before(to); node = node.next[0] {
+		f(node.event)
+	}
+}
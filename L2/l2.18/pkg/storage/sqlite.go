@@ -0,0 +1,371 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // драйвер SQLite
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS events (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id   INTEGER NOT NULL REFERENCES users(id),
+	date      TEXT    NOT NULL, -- дата в RFC 3339 (сохраняет смещение часового пояса)
+	date_unix INTEGER NOT NULL, -- та же дата в секундах Unix, для выборки по периоду
+	title     TEXT    NOT NULL,
+	content   TEXT    NOT NULL DEFAULT '',
+	rrule     TEXT    NOT NULL DEFAULT '', -- правило повторения RRULE, пусто - событие не повторяется
+	exdates   TEXT    NOT NULL DEFAULT '', -- исключённые повторения, JSON-массив дат
+	remind_before INTEGER                  -- напоминание за столько минут до начала, NULL - без напоминания
+);
+CREATE INDEX IF NOT EXISTS events_user_date ON events(user_id, date_unix);
+`
+
+// sqliteMigrations - столбцы, добавленные после первой версии схемы
+var sqliteMigrations = []struct{ column, ddl string }{
+	{"rrule", `ALTER TABLE events ADD COLUMN rrule TEXT NOT NULL DEFAULT ''`},
+	{"exdates", `ALTER TABLE events ADD COLUMN exdates TEXT NOT NULL DEFAULT ''`},
+	{"remind_before", `ALTER TABLE events ADD COLUMN remind_before INTEGER`},
+}
+
+// eventColumns - столбцы, из которых читается Event (см. scanEvent)
+const eventColumns = `id, user_id, date, title, content, rrule, exdates, remind_before`
+
+// SQLiteStorage - хранилище в базе SQLite. Пользователь считается известным после
+// первого созданного события (как в Storage), поэтому пользователи хранятся отдельно
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+// NewSQLiteStorage открывает (или создаёт) базу в файле path; ":memory:" - база в памяти
+func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
+
+	dsn := "file:" + path + "?_journal_mode=WAL&_synchronous=FULL&_busy_timeout=5000&_foreign_keys=on"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite пишет в один поток; одно соединение заодно нужно базе в памяти
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка создания схемы: %w", err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка обновления схемы: %w", err)
+	}
+
+	return &SQLiteStorage{db: db}, nil
+}
+
+// migrate добавляет в таблицу events столбцы, которых нет в базе, созданной прежней версией
+func migrate(db *sql.DB) error {
+
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('events')`)
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		columns[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range sqliteMigrations {
+		if columns[m.column] {
+			continue
+		}
+		if _, err := db.Exec(m.ddl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeRecurrence возвращает значения столбцов rrule и exdates события
+func encodeRecurrence(event *Event) (string, string, error) {
+
+	var rrule, exdates string
+	if event.Recurrence != nil {
+		rrule = event.Recurrence.String()
+	}
+	if len(event.ExDates) > 0 {
+		data, err := json.Marshal(event.ExDates)
+		if err != nil {
+			return "", "", err
+		}
+		exdates = string(data)
+	}
+
+	return rrule, exdates, nil
+}
+
+// scanEvent читает событие из строки выборки со столбцами eventColumns
+func scanEvent(rows *sql.Rows) (*Event, error) {
+
+	var event Event
+	var date, rrule, exdates string
+	var remindBefore sql.NullInt64
+	if err := rows.Scan(&event.ID, &event.UserID, &date, &event.Title, &event.Content, &rrule, &exdates, &remindBefore); err != nil {
+		return nil, err
+	}
+	if remindBefore.Valid {
+		minutes := int(remindBefore.Int64)
+		event.RemindBefore = &minutes
+	}
+
+	var err error
+	if event.Date, err = time.Parse(time.RFC3339Nano, date); err != nil {
+		return nil, fmt.Errorf("событие %d: неверная дата %q", event.ID, date)
+	}
+	if rrule != "" {
+		if event.Recurrence, err = ParseRRule(rrule); err != nil {
+			return nil, fmt.Errorf("событие %d: %w", event.ID, err)
+		}
+	}
+	if exdates != "" {
+		if err := json.Unmarshal([]byte(exdates), &event.ExDates); err != nil {
+			return nil, fmt.Errorf("событие %d: неверные исключения %q", event.ID, exdates)
+		}
+	}
+
+	return &event, nil
+}
+
+// Close закрывает базу
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// userExists проверяет, что пользователь известен хранилищу
+func userExists(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, userID int) error {
+
+	var one int
+	err := q.QueryRow(`SELECT 1 FROM users WHERE id = ?`, userID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errUserNotFound(userID)
+	}
+
+	return err
+}
+
+// Create добавляет event в хранилище, возвращает ID event или ошибку
+func (s *SQLiteStorage) Create(userID int, date time.Time, title, content string) (int, error) {
+	return s.Add(&Event{UserID: userID, Date: date, Title: title, Content: content})
+}
+
+// Add добавляет event целиком (с повторениями), ID назначает хранилище
+func (s *SQLiteStorage) Add(event *Event) (int, error) {
+
+	if err := validateEvent(event); err != nil {
+		return 0, err
+	}
+	rrule, exdates, err := encodeRecurrence(event)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO users (id) VALUES (?)`, event.UserID); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`INSERT INTO events (user_id, date, date_unix, title, content, rrule, exdates, remind_before)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.UserID, event.Date.Format(time.RFC3339Nano), event.Date.Unix(), event.Title, event.Content, rrule, exdates,
+		event.RemindBefore)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// Update обновляет event в хранилище, возвращает ошибку, если событие не найдено
+func (s *SQLiteStorage) Update(event *Event) error {
+
+	if event == nil {
+		return errNilEvent()
+	}
+	if err := validateOptional(event); err != nil {
+		return err
+	}
+	rrule, exdates, err := encodeRecurrence(event)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := userExists(tx, event.UserID); err != nil {
+		return err
+	}
+	res, err := tx.Exec(`UPDATE events SET date = ?, date_unix = ?, title = ?, content = ?, rrule = ?, exdates = ?,
+		remind_before = ? WHERE id = ? AND user_id = ?`,
+		event.Date.Format(time.RFC3339Nano), event.Date.Unix(), event.Title, event.Content, rrule, exdates,
+		event.RemindBefore, event.ID, event.UserID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errEventNotFound(event.ID)
+	}
+
+	return tx.Commit()
+}
+
+// Delete удаляет event из хранилища, возвращает ошибку, если событие не найдено
+func (s *SQLiteStorage) Delete(userID, eventID int) error {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := userExists(tx, userID); err != nil {
+		return err
+	}
+	res, err := tx.Exec(`DELETE FROM events WHERE id = ? AND user_id = ?`, eventID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errEventNotFound(eventID)
+	}
+
+	return tx.Commit()
+}
+
+// query возвращает события пользователя, выбранные условием where (после user_id = ?)
+func (s *SQLiteStorage) query(userID int, where string, args ...any) ([]*Event, error) {
+
+	if err := userExists(s.db, userID); err != nil {
+		return []*Event{}, err
+	}
+
+	rows, err := s.db.Query(`SELECT `+eventColumns+` FROM events WHERE user_id = ? `+where+` ORDER BY id`,
+		append([]any{userID}, args...)...)
+	if err != nil {
+		return []*Event{}, err
+	}
+	defer rows.Close()
+
+	events := make([]*Event, 0)
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return []*Event{}, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return []*Event{}, err
+	}
+
+	return events, nil
+}
+
+// GetForPeriod возвращает события пользователя с датой в [from, to), повторяющиеся - развёрнутыми
+func (s *SQLiteStorage) GetForPeriod(userID int, from, to time.Time) ([]*Event, error) {
+
+	// границы периода - целые секунды, поэтому сравнения в секундах достаточно;
+	// повторяющиеся события выбираются все начавшиеся до конца периода и разворачиваются
+	events, err := s.query(userID, `AND ((rrule = '' AND date_unix >= ? AND date_unix < ?)
+		OR (rrule <> '' AND date_unix < ?))`, from.Unix(), to.Unix(), to.Unix())
+	if err != nil {
+		return events, err
+	}
+
+	return expand(events, from, to), nil
+}
+
+// GetAll возвращает все события пользователя (повторяющиеся - без развёртывания)
+func (s *SQLiteStorage) GetAll(userID int) ([]*Event, error) {
+	return s.query(userID, "")
+}
+
+// GetForDay возвращает перечень событий на день или ошибку
+func (s *SQLiteStorage) GetForDay(userID int, date time.Time) ([]*Event, error) {
+
+	from, to := dayPeriod(date)
+
+	return s.GetForPeriod(userID, from, to)
+}
+
+// GetForWeek возвращает перечень событий на неделю или ошибку
+func (s *SQLiteStorage) GetForWeek(userID int, date time.Time) ([]*Event, error) {
+
+	from, to := weekPeriod(date)
+
+	return s.GetForPeriod(userID, from, to)
+}
+
+// GetForMonth возвращает перечень событий на месяц или ошибку
+func (s *SQLiteStorage) GetForMonth(userID int, date time.Time) ([]*Event, error) {
+
+	from, to := monthPeriod(date)
+
+	return s.GetForPeriod(userID, from, to)
+}
+
+// Users возвращает ID известных хранилищу пользователей по возрастанию
+func (s *SQLiteStorage) Users() ([]int, error) {
+
+	rows, err := s.db.Query(`SELECT id FROM users ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+
+	return users, rows.Err()
+}
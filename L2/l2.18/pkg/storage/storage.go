@@ -1,7 +1,7 @@
 package storage
 
 import (
-	"fmt"
+	"slices"
 	"sync"
 	"time"
 )
@@ -24,30 +24,34 @@ func NewStorage() *Storage {
 // Create добавляет event в хранилище, возвращает ID event или ошибку
 func (s *Storage) Create(userID int, date time.Time, title, content string) (int, error) {
 
+	return s.Add(&Event{
+		UserID:  userID,
+		Date:    date,
+		Title:   title,
+		Content: content,
+	})
+}
+
+// Add добавляет event целиком (с повторениями), ID назначает хранилище
+func (s *Storage) Add(event *Event) (int, error) {
+
 	s.Mu.Lock()
 	defer s.Mu.Unlock()
 
 	// выполняем базовые проверки
-	if userID < 0 {
-		return 0, fmt.Errorf("ошибочный ID пользователя")
-	}
-	if title == "" {
-		return 0, fmt.Errorf("поле title должно быть заполнено")
+	if err := validateEvent(event); err != nil {
+		return 0, err
 	}
 
 	// проверяем, что память под слайс событий есть и пользователь существует
-	if _, ok := s.Events[userID]; !ok || s.Events[userID] == nil {
-		s.Events[userID] = make([]*Event, 0)
+	if _, ok := s.Events[event.UserID]; !ok || s.Events[event.UserID] == nil {
+		s.Events[event.UserID] = make([]*Event, 0)
 	}
 
-	// добавляем пользователю событие в список
-	s.Events[userID] = append(s.Events[userID], &Event{
-		ID:      s.NextID,
-		UserID:  userID,
-		Date:    date,
-		Title:   title,
-		Content: content,
-	})
+	// добавляем пользователю копию события в список
+	added := *event
+	added.ID = s.NextID
+	s.Events[event.UserID] = append(s.Events[event.UserID], &added)
 	// добаляем счётчик событий
 	s.NextID++
 
@@ -61,15 +65,18 @@ func (s *Storage) Update(event *Event) error {
 	defer s.Mu.Unlock()
 
 	if event == nil {
-		return fmt.Errorf("событие не может быть nil")
+		return errNilEvent()
+	}
+	if err := validateOptional(event); err != nil {
+		return err
 	}
 
 	events, ok := s.Events[event.UserID]
 	if !ok {
-		return fmt.Errorf("пользователь с %d не найден", event.UserID)
+		return errUserNotFound(event.UserID)
 	}
 	if events == nil {
-		return fmt.Errorf("у пользователя с %d событий ваще не найдено", event.UserID)
+		return errNoEvents(event.UserID)
 	}
 
 	for i := 0; i < len(events); i++ {
@@ -78,12 +85,15 @@ func (s *Storage) Update(event *Event) error {
 			events[i].Date = event.Date
 			events[i].Title = event.Title
 			events[i].Content = event.Content
+			events[i].Recurrence = event.Recurrence
+			events[i].ExDates = event.ExDates
+			events[i].RemindBefore = event.RemindBefore
 			return nil
 		}
 	}
 
 	// если событие не найдено - что-то пошло не так
-	return fmt.Errorf("событие с %d не найдено", event.ID)
+	return errEventNotFound(event.ID)
 }
 
 // Delete удаляет event из хранилища, возвращает ошибку, если событие не найдено
@@ -94,10 +104,10 @@ func (s *Storage) Delete(userID, eventID int) error {
 
 	events, ok := s.Events[userID]
 	if !ok {
-		return fmt.Errorf("пользователь с %d не найден", userID)
+		return errUserNotFound(userID)
 	}
 	if events == nil {
-		return fmt.Errorf("у пользователя с %d событий ваще не найдено", userID)
+		return errNoEvents(userID)
 	}
 
 	for i := 0; i < len(events); i++ {
@@ -111,40 +121,45 @@ func (s *Storage) Delete(userID, eventID int) error {
 	}
 
 	// если событие не найдено - что-то пошло не так
-	return fmt.Errorf("событие с %d не найдено", eventID)
-}
-
-// dayNormalizer возвращает начало дня
-func dayNormalizer(t time.Time) time.Time {
-
-	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return errEventNotFound(eventID)
 }
 
-// возвращает перечень событий на день или ошибку
-func (s *Storage) GetForDay(userID int, date time.Time) ([]*Event, error) {
+// check проверяет, что у пользователя userID есть событие eventID
+// (ошибки - те же, что возвращают Update и Delete)
+func (s *Storage) check(userID, eventID int) error {
 
 	s.Mu.RLock()
 	defer s.Mu.RUnlock()
 
 	events, ok := s.Events[userID]
 	if !ok {
-		return []*Event{}, fmt.Errorf("пользователь с %d не найден", userID)
+		return errUserNotFound(userID)
 	}
 	if events == nil {
-		return []*Event{}, fmt.Errorf("у пользователя с %d событий ваще не найдено", userID)
+		return errNoEvents(userID)
 	}
 
-	eventsForDay := make([]*Event, 0)
-	fromDay := dayNormalizer(date)
-	toDay := fromDay.AddDate(0, 0, 1)
-
 	for i := 0; i < len(events); i++ {
-		if !events[i].Date.Before(fromDay) && events[i].Date.Before(toDay) {
-			eventsForDay = append(eventsForDay, events[i])
+		if eventID == events[i].ID {
+			return nil
 		}
 	}
 
-	return eventsForDay, nil
+	return errEventNotFound(eventID)
+}
+
+// dayNormalizer возвращает начало дня
+func dayNormalizer(t time.Time) time.Time {
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// возвращает перечень событий на день или ошибку
+func (s *Storage) GetForDay(userID int, date time.Time) ([]*Event, error) {
+
+	from, to := dayPeriod(date)
+
+	return s.GetForPeriod(userID, from, to)
 }
 
 // weekNormalizer возвращает начало недели
@@ -164,28 +179,9 @@ func weekNormalizer(t time.Time) time.Time {
 // возвращает перечень событий на неделю или ошибку
 func (s *Storage) GetForWeek(userID int, date time.Time) ([]*Event, error) {
 
-	s.Mu.RLock()
-	defer s.Mu.RUnlock()
-
-	events, ok := s.Events[userID]
-	if !ok {
-		return []*Event{}, fmt.Errorf("пользователь с %d не найден", userID)
-	}
-	if events == nil {
-		return []*Event{}, fmt.Errorf("у пользователя с %d событий ваще не найдено", userID)
-	}
-
-	eventsForWeek := make([]*Event, 0)
-	fromDay := weekNormalizer(date)
-	toDay := fromDay.AddDate(0, 0, 7)
+	from, to := weekPeriod(date)
 
-	for i := 0; i < len(events); i++ {
-		if !events[i].Date.Before(fromDay) && events[i].Date.Before(toDay) {
-			eventsForWeek = append(eventsForWeek, events[i])
-		}
-	}
-
-	return eventsForWeek, nil
+	return s.GetForPeriod(userID, from, to)
 }
 
 // monthNormalizer возвращает начало месяца
@@ -197,26 +193,54 @@ func monthNormalizer(t time.Time) time.Time {
 // возвращает перечень событий на месяц или ошибку
 func (s *Storage) GetForMonth(userID int, date time.Time) ([]*Event, error) {
 
+	from, to := monthPeriod(date)
+
+	return s.GetForPeriod(userID, from, to)
+}
+
+// GetForPeriod возвращает события с датой в [from, to); повторяющиеся события
+// разворачиваются в повторения, попадающие в период
+func (s *Storage) GetForPeriod(userID int, from, to time.Time) ([]*Event, error) {
+
 	s.Mu.RLock()
 	defer s.Mu.RUnlock()
 
 	events, ok := s.Events[userID]
 	if !ok {
-		return []*Event{}, fmt.Errorf("пользователь с %d не найден", userID)
+		return []*Event{}, errUserNotFound(userID)
 	}
 	if events == nil {
-		return []*Event{}, fmt.Errorf("у пользователя с %d событий ваще не найдено", userID)
+		return []*Event{}, errNoEvents(userID)
 	}
 
-	eventsForMonth := make([]*Event, 0)
-	fromDay := monthNormalizer(date)
-	toDay := fromDay.AddDate(0, 1, 0)
+	return expand(events, from, to), nil
+}
 
-	for i := 0; i < len(events); i++ {
-		if !events[i].Date.Before(fromDay) && events[i].Date.Before(toDay) {
-			eventsForMonth = append(eventsForMonth, events[i])
-		}
+// GetAll возвращает все события пользователя (повторяющиеся - без развёртывания)
+func (s *Storage) GetAll(userID int) ([]*Event, error) {
+
+	s.Mu.RLock()
+	defer s.Mu.RUnlock()
+
+	events, ok := s.Events[userID]
+	if !ok {
+		return []*Event{}, errUserNotFound(userID)
+	}
+
+	return append(make([]*Event, 0, len(events)), events...), nil
+}
+
+// Users возвращает ID известных хранилищу пользователей по возрастанию
+func (s *Storage) Users() ([]int, error) {
+
+	s.Mu.RLock()
+	defer s.Mu.RUnlock()
+
+	users := make([]int, 0, len(s.Events))
+	for userID := range s.Events {
+		users = append(users, userID)
 	}
+	slices.Sort(users)
 
-	return eventsForMonth, nil
+	return users, nil
 }
@@ -0,0 +1,551 @@
+// Package storagetest - общий набор тестов для реализаций storage.Repository:
+// каждая реализация должна проходить его целиком
+package storagetest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IPampurin/calendar-server/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewFunc создаёт новое пустое хранилище для одного теста
+// (закрыть его при необходимости нужно через t.Cleanup)
+type NewFunc func(t *testing.T) storage.Repository
+
+// Run проверяет реализацию Repository: каждый тест получает новое хранилище от newRepo
+func Run(t *testing.T, newRepo NewFunc) {
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, newRepo NewFunc)
+	}{
+		{"Create", testCreate},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"GetForDay", testGetForDay},
+		{"GetForWeek", testGetForWeek},
+		{"GetForMonth", testGetForMonth},
+		{"Recurrence", testRecurrence},
+		{"Reminder", testReminder},
+		{"Users", testUsers},
+		{"ConcurrentAccess", testConcurrentAccess},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepo)
+		})
+	}
+}
+
+// testCreate проверяет создание событий
+func testCreate(t *testing.T, newRepo NewFunc) {
+
+	s := newRepo(t)
+	date := time.Now()
+
+	// создаем первое событие - ожидаем ID = 1
+	t.Log("Создание первого события")
+	id, err := s.Create(1, date, "Meeting", "Team meeting")
+	assert.NoError(t, err, "Создание события не должно вернуть ошибку")
+	assert.Equal(t, 1, id, "ID первого события должен быть 1")
+
+	// создаем второе событие для того же пользователя - ожидаем ID = 2
+	t.Log("Создание второго события для того же пользователя")
+	id2, err := s.Create(1, date, "Lunch", "With colleagues")
+	assert.NoError(t, err, "Создание второго события не должно вернуть ошибку")
+	assert.Equal(t, 2, id2, "ID второго события должен быть 2 (инкремент счетчика)")
+
+	// попытка создать событие с пустым заголовком
+	t.Log("Проверка валидации: пустой заголовок")
+	_, err = s.Create(1, date, "", "Empty title")
+	assert.Error(t, err, "Должна быть ошибка при пустом заголовке")
+	assert.Contains(t, err.Error(), "title", "Ошибка должна упоминать поле title")
+
+	// попытка создать событие с отрицательным ID пользователя
+	t.Log("Проверка валидации: отрицательный userID")
+	_, err = s.Create(-1, date, "Test", "Test")
+	assert.Error(t, err, "Должна быть ошибка при отрицательном userID")
+	assert.Contains(t, err.Error(), "ошибочный ID", "Ошибка должна указывать на некорректный ID")
+}
+
+// testUpdate проверяет обновление существующего события
+func testUpdate(t *testing.T, newRepo NewFunc) {
+
+	s := newRepo(t)
+	date := time.Now()
+
+	// создаем событие для последующего обновления
+	id, err := s.Create(1, date, "Old title", "Old content")
+	require.NoError(t, err, "Не удалось создать тестовое событие")
+	require.Equal(t, 1, id, "ID тестового события должен быть 1")
+
+	// готовим обновленные данные: меняем дату (+1 день), заголовок и содержание
+	newDate := date.Add(24 * time.Hour)
+	updatedEvent := &storage.Event{
+		ID:      id, // ID должен совпадать с существующим событием
+		UserID:  1,  // UserID должен совпадать с владельцем
+		Date:    newDate,
+		Title:   "New title",
+		Content: "New content",
+	}
+
+	// обновляем событие
+	err = s.Update(updatedEvent)
+	assert.NoError(t, err, "Обновление существующего события не должно вернуть ошибку")
+
+	// получаем событие на новую дату и проверяем поля
+	events, err := s.GetForDay(1, newDate)
+	require.NoError(t, err, "Не удалось получить события на обновленную дату")
+	require.Len(t, events, 1, "На новую дату должно быть ровно одно событие")
+
+	// проверяем, что все поля действительно обновились
+	assert.Equal(t, "New title", events[0].Title, "Заголовок не обновился")
+	assert.Equal(t, "New content", events[0].Content, "Содержание не обновилось")
+	assert.True(t, events[0].Date.Equal(newDate), "Дата не обновилась")
+
+	// попытка обновить несуществующее событие
+	t.Log("Проверка: обновление несуществующего события")
+	err = s.Update(&storage.Event{ID: 999, UserID: 1})
+	assert.Error(t, err, "Должна быть ошибка при обновлении несуществующего события")
+	assert.Contains(t, err.Error(), "не найдено", "Ошибка должна указывать, что событие не найдено")
+
+	// передача nil вместо указателя на событие
+	t.Log("Проверка: передача nil")
+	err = s.Update(nil)
+	assert.Error(t, err, "Должна быть ошибка при передаче nil")
+	assert.Contains(t, err.Error(), "nil", "Ошибка должна упоминать nil")
+}
+
+// testDelete проверяет удаление событий
+func testDelete(t *testing.T, newRepo NewFunc) {
+
+	s := newRepo(t)
+	date := time.Now()
+
+	// создаем событие для удаления
+	id, err := s.Create(1, date, "To delete", "Content")
+	require.NoError(t, err, "Не удалось создать тестовое событие")
+	require.Equal(t, 1, id, "ID тестового события должен быть 1")
+
+	// удаляем событие
+	err = s.Delete(1, id)
+	assert.NoError(t, err, "Удаление существующего события не должно вернуть ошибку")
+
+	// убеждаемся, что событие действительно удалено
+	events, err := s.GetForDay(1, date)
+	require.NoError(t, err, "Не удалось получить список событий")
+	assert.Empty(t, events, "После удаления список событий должен быть пуст")
+
+	// попытка удалить уже удаленное (несуществующее) событие
+	t.Log("Проверка: удаление несуществующего события")
+	err = s.Delete(1, 999)
+	assert.Error(t, err, "Должна быть ошибка при удалении несуществующего события")
+	assert.Contains(t, err.Error(), "не найдено", "Ошибка должна указывать, что событие не найдено")
+
+	// попытка удалить событие у несуществующего пользователя
+	t.Log("Проверка: удаление у несуществующего пользователя")
+	err = s.Delete(999, 1)
+	assert.Error(t, err, "Должна быть ошибка при удалении у несуществующего пользователя")
+	assert.Contains(t, err.Error(), "не найден", "Ошибка должна указывать, что пользователь не найден")
+}
+
+// testGetForDay проверяет получение событий за конкретный день
+func testGetForDay(t *testing.T, newRepo NewFunc) {
+
+	s := newRepo(t)
+
+	// фиксированная дата для предсказуемости теста
+	baseDate := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	// создаем события на разные дни
+	// событие в целевой день (15.01.2024)
+	_, err := s.Create(1, baseDate, "Event 1", "")
+	require.NoError(t, err, "Не удалось создать событие на целевую дату")
+
+	// событие на следующий день (16.01.2024) - не должно попасть в выборку
+	_, err = s.Create(1, baseDate.Add(24*time.Hour), "Event 2", "")
+	require.NoError(t, err, "Не удалось создать событие на следующий день")
+
+	// событие на предыдущий день (14.01.2024) - не должно попасть в выборку
+	_, err = s.Create(1, baseDate.Add(-24*time.Hour), "Event 3", "")
+	require.NoError(t, err, "Не удалось создать событие на предыдущий день")
+
+	// получение событий за день
+	t.Log("Получение событий за 15.01.2024")
+	events, err := s.GetForDay(1, baseDate)
+	assert.NoError(t, err, "Получение событий не должно вернуть ошибку")
+	assert.Len(t, events, 1, "Должно быть ровно одно событие за целевую дату")
+	assert.Equal(t, "Event 1", events[0].Title, "Найдено не то событие")
+
+	// запрашиваем день, на который нет событий
+	t.Log("Получение событий за день без событий")
+	emptyDay := baseDate.Add(48 * time.Hour) // 17.01.2024
+	events, err = s.GetForDay(1, emptyDay)
+	assert.NoError(t, err, "Получение пустого списка не должно вернуть ошибку")
+	assert.Empty(t, events, "Должен вернуться пустой слайс, а не nil")
+
+	// проверяем несуществующего пользователя
+	t.Log("Проверка: получение событий несуществующего пользователя")
+	events, err = s.GetForDay(999, baseDate)
+	assert.Error(t, err, "Должна быть ошибка для несуществующего пользователя")
+	assert.Contains(t, err.Error(), "не найден", "Ошибка должна указывать, что пользователь не найден")
+	assert.Empty(t, events, "При ошибке должен возвращаться пустой слайс")
+}
+
+// testGetForWeek проверяет получение событий за неделю
+func testGetForWeek(t *testing.T, newRepo NewFunc) {
+
+	s := newRepo(t)
+
+	// 15 января 2024 - понедельник
+	monday := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	// создаем события внутри и вне целевой недели
+	// событие в понедельник - должно быть в выборке
+	_, err := s.Create(1, monday, "Monday event", "")
+	require.NoError(t, err)
+
+	// событие в среду - должно быть в выборке
+	_, err = s.Create(1, monday.Add(48*time.Hour), "Wednesday event", "")
+	require.NoError(t, err)
+
+	// событие в следующий понедельник - не должно быть в выборке
+	_, err = s.Create(1, monday.Add(7*24*time.Hour), "Next Monday", "")
+	require.NoError(t, err)
+
+	// получение событий за неделю
+	t.Log("Получение событий за неделю с 15.01.2024")
+	events, err := s.GetForWeek(1, monday)
+	assert.NoError(t, err, "Получение событий за неделю не должно вернуть ошибку")
+	assert.Len(t, events, 2, "Должно быть ровно 2 события (пн и ср)")
+
+	// проверяем, что это именно те события, которые мы ожидаем (проверяем заголовки)
+	titles := []string{events[0].Title, events[1].Title}
+	assert.Contains(t, titles, "Monday event", "Событие понедельника не найдено")
+	assert.Contains(t, titles, "Wednesday event", "Событие среды не найдено")
+}
+
+// testGetForMonth проверяет получение событий за месяц
+func testGetForMonth(t *testing.T, newRepo NewFunc) {
+
+	s := newRepo(t)
+
+	// 15 января 2024 - середина месяца
+	january := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	// создаем события в январе и феврале
+	// событие в январе - должно быть в выборке
+	_, err := s.Create(1, january, "January event", "")
+	require.NoError(t, err)
+
+	// событие 15 февраля (январь + 31 день) - не должно быть в выборке за январь
+	_, err = s.Create(1, january.AddDate(0, 1, 0), "February event", "")
+	require.NoError(t, err)
+
+	// получение событий за январь
+	t.Log("Получение событий за январь 2024")
+	events, err := s.GetForMonth(1, january)
+	assert.NoError(t, err, "Получение событий за месяц не должно вернуть ошибку")
+	assert.Len(t, events, 1, "Должно быть ровно одно событие в январе")
+	assert.Equal(t, "January event", events[0].Title, "Найдено не то событие")
+
+	// проверка границ месяца
+	// проверяем последний день января
+	lastDayOfJanuary := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+	events, err = s.GetForMonth(1, lastDayOfJanuary)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1, "Даже при запросе в последний день января должно найтись событие")
+}
+
+// testConcurrentAccess проверяет, что хранилище корректно работает при конкурентном доступе
+func testConcurrentAccess(t *testing.T, newRepo NewFunc) {
+
+	s := newRepo(t)
+	date := time.Now()
+
+	// количество конкурентных операций
+	const goroutinesCount = 100
+
+	var wg sync.WaitGroup
+	wg.Add(goroutinesCount)
+
+	// канал для сбора ошибок от горутин
+	// используем буферизированный канал, чтобы горутины не блокировались при отправке
+	errors := make(chan error, goroutinesCount)
+
+	// запускаем конкурентное создание событий
+	t.Logf("Запуск %d горутин для конкурентного создания событий", goroutinesCount)
+	for i := 0; i < goroutinesCount; i++ {
+		go func(id int) {
+			defer wg.Done()
+			// каждая горутина создает свое событие
+			_, err := s.Create(1, date, fmt.Sprintf("Concurrent Event %d", id), "")
+			if err != nil {
+				errors <- err
+			}
+		}(i)
+	}
+
+	// ожидаем завершения всех горутин
+	wg.Wait()
+	close(errors)
+
+	// проверяем, что не было ошибок при создании
+	for err := range errors {
+		assert.NoError(t, err, "Горутина вернула ошибку при создании")
+	}
+
+	// проверяем, что все 100 событий действительно создались
+	events, err := s.GetForDay(1, date)
+	assert.NoError(t, err, "Не удалось получить список событий")
+	assert.Len(t, events, goroutinesCount, "Должно быть создано ровно %d событий, но создано %d", goroutinesCount, len(events))
+
+	// проверка, что ID событий уникальные
+	// (это косвенно проверяет, что счетчик NextID увеличивается атомарно)
+	ids := make(map[int]bool)
+	for _, event := range events {
+		assert.False(t, ids[event.ID], "Обнаружен дубликат ID: %d", event.ID)
+		ids[event.ID] = true
+	}
+}
+
+// dates возвращает даты событий в виде YYYY-MM-DD
+func dates(events []*storage.Event) []string {
+
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.Date.Format("2006-01-02"))
+	}
+
+	return result
+}
+
+// rrule разбирает правило повторения или прерывает тест
+func rrule(t *testing.T, s string) *storage.Recurrence {
+
+	t.Helper()
+
+	r, err := storage.ParseRRule(s)
+	require.NoError(t, err, "правило %q", s)
+
+	return r
+}
+
+// testRecurrence проверяет развёртывание повторяющихся событий в выборках по периоду
+func testRecurrence(t *testing.T, newRepo NewFunc) {
+
+	s := newRepo(t)
+	// четверг, 1 января 2026
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Log("Еженедельно по понедельникам и средам, 5 раз; DTSTART (четверг) - первое повторение")
+	weekly, err := s.Add(&storage.Event{UserID: 1, Date: start, Title: "Стендап",
+		Recurrence: rrule(t, "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5")})
+	require.NoError(t, err)
+	events, err := s.GetForMonth(1, start)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-01-01", "2026-01-05", "2026-01-07", "2026-01-12", "2026-01-14"}, dates(events))
+	for _, event := range events {
+		assert.Equal(t, weekly, event.ID, "у повторений ID исходного события")
+		assert.Equal(t, 9, event.Date.Hour(), "время повторения берётся из DTSTART")
+	}
+
+	t.Log("Исключение одного повторения (EXDATE)")
+	require.NoError(t, s.Update(&storage.Event{ID: weekly, UserID: 1, Date: start, Title: "Стендап",
+		Recurrence: rrule(t, "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5"),
+		ExDates:    []time.Time{time.Date(2026, 1, 7, 0, 0, 0, 0, time.UTC)}}))
+	events, err = s.GetForWeek(1, time.Date(2026, 1, 7, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-01-05"}, dates(events), "исключённый день не должен попасть в выборку")
+
+	t.Log("Ежедневно до даты включительно (UNTIL)")
+	daily, err := s.Add(&storage.Event{UserID: 2, Date: start, Title: "Зарядка",
+		Recurrence: rrule(t, "FREQ=DAILY;INTERVAL=2;UNTIL=20260107")})
+	require.NoError(t, err)
+	events, err = s.GetForMonth(2, start)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-01-01", "2026-01-03", "2026-01-05", "2026-01-07"}, dates(events))
+
+	t.Log("Ежемесячно 31-го: месяцы без 31-го пропускаются, запрос далеко от начала")
+	monthly, err := s.Add(&storage.Event{UserID: 3, Date: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC), Title: "Отчёт",
+		Recurrence: rrule(t, "FREQ=MONTHLY")})
+	require.NoError(t, err)
+	events, err = s.GetForMonth(3, time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, events, "в феврале нет 31-го")
+	events, err = s.GetForDay(3, time.Date(2031, 3, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2031-03-31"}, dates(events))
+
+	t.Log("Повторения не появляются раньше начала события")
+	events, err = s.GetForMonth(3, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	t.Log("GetAll возвращает события без развёртывания")
+	all, err := s.GetAll(1)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.True(t, all[0].Date.Equal(start))
+	require.NotNil(t, all[0].Recurrence)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5", all[0].Recurrence.String())
+	assert.Len(t, all[0].ExDates, 1)
+
+	t.Log("Обновление без правила делает событие обычным")
+	require.NoError(t, s.Update(&storage.Event{ID: monthly, UserID: 3, Date: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC), Title: "Отчёт"}))
+	events, err = s.GetForDay(3, time.Date(2031, 3, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	t.Log("Неверное правило отклоняется")
+	_, err = s.Add(&storage.Event{UserID: 1, Date: start, Title: "x",
+		Recurrence: &storage.Recurrence{Freq: storage.FreqDaily, Count: 3, Until: start}})
+	assert.Error(t, err, "COUNT и UNTIL вместе недопустимы")
+	assert.Error(t, s.Update(&storage.Event{ID: daily, UserID: 2, Date: start, Title: "x",
+		Recurrence: &storage.Recurrence{Freq: "YEARLY"}}))
+}
+
+// testReminder проверяет хранение напоминания
+func testReminder(t *testing.T, newRepo NewFunc) {
+
+	s := newRepo(t)
+	date := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	minutes := func(n int) *int { return &n }
+
+	id, err := s.Add(&storage.Event{UserID: 1, Date: date, Title: "Встреча", RemindBefore: minutes(15)})
+	require.NoError(t, err)
+	all, err := s.GetAll(1)
+	require.NoError(t, err)
+	require.NotNil(t, all[0].RemindBefore)
+	assert.Equal(t, 15, *all[0].RemindBefore)
+
+	t.Log("Напоминание в момент начала (0 минут) - не то же, что без напоминания")
+	require.NoError(t, s.Update(&storage.Event{ID: id, UserID: 1, Date: date, Title: "Встреча", RemindBefore: minutes(0)}))
+	all, err = s.GetAll(1)
+	require.NoError(t, err)
+	require.NotNil(t, all[0].RemindBefore)
+	assert.Equal(t, 0, *all[0].RemindBefore)
+
+	require.NoError(t, s.Update(&storage.Event{ID: id, UserID: 1, Date: date, Title: "Встреча"}))
+	all, err = s.GetAll(1)
+	require.NoError(t, err)
+	assert.Nil(t, all[0].RemindBefore, "обновление без напоминания снимает его")
+
+	t.Log("Неверное время напоминания")
+	_, err = s.Add(&storage.Event{UserID: 1, Date: date, Title: "x", RemindBefore: minutes(-1)})
+	assert.ErrorIs(t, err, storage.ErrInvalid)
+	err = s.Update(&storage.Event{ID: id, UserID: 1, Date: date, Title: "x", RemindBefore: minutes(storage.MaxRemindBefore + 1)})
+	assert.ErrorIs(t, err, storage.ErrInvalid)
+}
+
+// testUsers проверяет список пользователей
+func testUsers(t *testing.T, newRepo NewFunc) {
+
+	s := newRepo(t)
+
+	users, err := s.Users()
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	date := time.Now()
+	for _, userID := range []int{5, 2, 5, 9} {
+		_, err := s.Create(userID, date, "x", "")
+		require.NoError(t, err)
+	}
+	id, err := s.Create(3, date, "x", "")
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(3, id))
+
+	users, err = s.Users()
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 5, 9}, users, "пользователь без событий остаётся известным")
+
+	_, err = s.GetAll(42)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// OpenFunc открывает хранилище с данными в каталоге dir; повторный вызов с тем же каталогом
+// после Close должен вернуть сохранённые данные
+type OpenFunc func(t *testing.T, dir string) storage.Repository
+
+// RunPersistence проверяет, что хранилище сохраняет данные между открытиями
+func RunPersistence(t *testing.T, open OpenFunc) {
+
+	dir := t.TempDir()
+	date := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	s := open(t, dir)
+	id1, err := s.Create(1, date, "Meeting", "Team meeting")
+	require.NoError(t, err)
+	id2, err := s.Create(1, date.AddDate(0, 0, 1), "Lunch", "")
+	require.NoError(t, err)
+	id3, err := s.Create(2, date, "Other user", "")
+	require.NoError(t, err)
+	require.NoError(t, s.Update(&storage.Event{ID: id2, UserID: 1, Date: date.AddDate(0, 0, 2), Title: "Late lunch", Content: "Moved"}))
+	require.NoError(t, s.Delete(2, id3))
+	remind := 10
+	recurring, err := s.Add(&storage.Event{UserID: 4, Date: date, Title: "Weekly",
+		Recurrence: rrule(t, "FREQ=WEEKLY;COUNT=3"), ExDates: []time.Time{date.AddDate(0, 0, 7)}, RemindBefore: &remind})
+	require.NoError(t, err)
+	closeRepo(t, s)
+
+	// после повторного открытия - то же состояние
+	s = open(t, dir)
+	events, err := s.GetForMonth(1, date)
+	require.NoError(t, err)
+	require.Len(t, events, 2, "после перезапуска должны остаться оба события пользователя 1")
+	byID := map[int]*storage.Event{}
+	for _, event := range events {
+		byID[event.ID] = event
+	}
+	require.Contains(t, byID, id1)
+	require.Contains(t, byID, id2)
+	assert.Equal(t, "Meeting", byID[id1].Title)
+	assert.Equal(t, "Team meeting", byID[id1].Content)
+	assert.True(t, byID[id1].Date.Equal(date), "дата события не сохранилась")
+	assert.Equal(t, "Late lunch", byID[id2].Title, "обновление не сохранилось")
+	assert.True(t, byID[id2].Date.Equal(date.AddDate(0, 0, 2)), "новая дата не сохранилась")
+
+	// повторения и исключения сохраняются
+	events, err = s.GetForMonth(4, date)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-01-15", "2024-01-29"}, dates(events), "повторения после перезапуска")
+	for _, event := range events {
+		assert.Equal(t, recurring, event.ID)
+		require.NotNil(t, event.RemindBefore, "напоминание после перезапуска")
+		assert.Equal(t, 10, *event.RemindBefore)
+	}
+
+	// пользователь, у которого удалили все события, остаётся известным
+	events, err = s.GetForDay(2, date)
+	assert.NoError(t, err)
+	assert.Empty(t, events, "удалённое событие не должно вернуться")
+
+	// ID не повторяются после перезапуска
+	id4, err := s.Create(1, date, "After restart", "")
+	require.NoError(t, err)
+	assert.Greater(t, id4, id3, "ID нового события должен быть больше всех выданных раньше")
+	closeRepo(t, s)
+
+	s = open(t, dir)
+	defer closeRepo(t, s)
+	events, err = s.GetForDay(1, date)
+	require.NoError(t, err)
+	assert.Len(t, events, 2, "событие, созданное после перезапуска, тоже должно сохраниться")
+}
+
+// closeRepo закрывает хранилище, если у него есть Close
+func closeRepo(t *testing.T, s storage.Repository) {
+
+	t.Helper()
+
+	if closer, ok := s.(interface{ Close() error }); ok {
+		require.NoError(t, closer.Close(), "ошибка закрытия хранилища")
+	}
+}
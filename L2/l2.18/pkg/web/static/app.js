@@ -0,0 +1,236 @@
+// Вид месяца поверх JSON API сервера: события месяца - GET /events_for_month,
+// изменения - POST /create_event, /update_event, /delete_event.
+// Повторяющееся событие для изменения читается целиком через GET /v2/users/{id}/events/{eid}.
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+const monthNames = ["Январь", "Февраль", "Март", "Апрель", "Май", "Июнь",
+  "Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"];
+
+const state = {
+  month: firstOfMonth(new Date()),
+  user: Number(localStorage.getItem("calendar.user")) || 1,
+  editing: null, // изменяемое событие (как его вернул сервер) или null для нового
+};
+
+function firstOfMonth(d) {
+  return new Date(Date.UTC(d.getFullYear(), d.getMonth(), 1));
+}
+
+// isoDate возвращает дату в виде YYYY-MM-DD (даты событий хранятся в UTC)
+function isoDate(d) {
+  return d.toISOString().slice(0, 10);
+}
+
+function setStatus(text, isError) {
+  const el = $("status");
+  el.textContent = text || "";
+  el.className = isError ? "error" : "";
+}
+
+// call выполняет запрос к API и возвращает поле result ответа; ошибку API выбрасывает
+async function call(method, url, body) {
+  const options = { method, headers: {} };
+  if (body !== undefined) {
+    options.headers["Content-Type"] = "application/json";
+    options.body = JSON.stringify(body);
+  }
+  const resp = await fetch(url, options);
+  const data = await resp.json().catch(() => ({}));
+  if (!resp.ok) {
+    throw new Error(data.error || data.detail || resp.statusText);
+  }
+  return data.result !== undefined ? data.result : data;
+}
+
+async function load() {
+  $("month").textContent = monthNames[state.month.getUTCMonth()] + " " + state.month.getUTCFullYear();
+  $("user").value = state.user;
+  $("export").href = "/export.ics?user_id=" + state.user;
+
+  let events = [];
+  try {
+    events = await call("GET", `/events_for_month?user_id=${state.user}&date=${isoDate(state.month)}`) || [];
+    setStatus("");
+  } catch (err) {
+    // у нового пользователя ещё нет событий - это не ошибка
+    setStatus(/не найден/.test(err.message) ? "Событий пока нет — нажмите на день, чтобы добавить" : err.message,
+      !/не найден/.test(err.message));
+  }
+  render(events);
+}
+
+function render(events) {
+  const byDay = new Map();
+  for (const event of events) {
+    const day = event.date.slice(0, 10);
+    if (!byDay.has(day)) {
+      byDay.set(day, []);
+    }
+    byDay.get(day).push(event);
+  }
+
+  const grid = $("grid");
+  grid.replaceChildren();
+  const today = isoDate(new Date(Date.UTC(new Date().getFullYear(), new Date().getMonth(), new Date().getDate())));
+  // сетка начинается с понедельника недели, в которую попадает первое число
+  const start = new Date(state.month);
+  start.setUTCDate(1 - (start.getUTCDay() + 6) % 7);
+
+  for (let i = 0; i < 42; i++) {
+    const d = new Date(start);
+    d.setUTCDate(start.getUTCDate() + i);
+    const day = isoDate(d);
+
+    const cell = document.createElement("div");
+    cell.className = "day";
+    if (d.getUTCMonth() !== state.month.getUTCMonth()) {
+      cell.classList.add("other");
+    }
+    if (day === today) {
+      cell.classList.add("today");
+    }
+    cell.addEventListener("click", () => openForm(null, day));
+
+    const number = document.createElement("span");
+    number.className = "number";
+    number.textContent = d.getUTCDate();
+    cell.append(number);
+
+    for (const event of byDay.get(day) || []) {
+      const item = document.createElement("button");
+      item.type = "button";
+      item.className = "event";
+      if (event.rrule) {
+        item.classList.add("recurring");
+      }
+      item.textContent = (event.remind_before !== undefined ? "🔔 " : "") + event.title;
+      item.title = event.content || event.title;
+      item.addEventListener("click", (e) => {
+        e.stopPropagation();
+        openForm(event, day);
+      });
+      cell.append(item);
+    }
+    grid.append(cell);
+  }
+}
+
+// parseRule разбирает FREQ и COUNT из правила повторения
+function parseRule(rrule) {
+  const parts = Object.fromEntries((rrule || "").split(";").filter(Boolean).map((p) => p.split("=")));
+  return { freq: parts.FREQ || "", count: parts.COUNT || "" };
+}
+
+async function openForm(event, day) {
+  const form = $("form");
+  form.reset();
+  state.editing = null;
+
+  if (event) {
+    // для повторяющегося события в месяце - повторение; изменяем событие целиком
+    let original = event;
+    if (event.rrule) {
+      try {
+        original = await call("GET", `/v2/users/${state.user}/events/${event.id}`);
+      } catch (err) {
+        setStatus(err.message, true);
+        return;
+      }
+    }
+    state.editing = original;
+    const rule = parseRule(original.rrule);
+    form.elements.date.value = original.date.slice(0, 10);
+    form.elements.title.value = original.title;
+    form.elements.content.value = original.content || "";
+    form.elements.freq.value = rule.freq;
+    form.elements.count.value = rule.count;
+    form.elements.remind.value = original.remind_before !== undefined ? original.remind_before : "";
+  } else {
+    form.elements.date.value = day;
+  }
+
+  $("form-title").textContent = event ? "Событие" : "Новое событие";
+  $("delete").hidden = !event;
+  $("series-hint").hidden = !(event && event.rrule);
+  $("dialog").showModal();
+}
+
+async function save() {
+  const form = $("form");
+  const body = {
+    user_id: state.user,
+    date: form.elements.date.value,
+    title: form.elements.title.value.trim(),
+    content: form.elements.content.value,
+  };
+
+  if (form.elements.freq.value) {
+    const original = state.editing && parseRule(state.editing.rrule);
+    if (original && original.freq === form.elements.freq.value && original.count === form.elements.count.value) {
+      // правило не меняли - сохраняем его целиком (в нём могут быть BYDAY, UNTIL...) вместе с исключениями
+      body.rrule = state.editing.rrule;
+      body.exdates = (state.editing.exdates || []).map((d) => d.slice(0, 10));
+    } else {
+      body.rrule = "FREQ=" + form.elements.freq.value + (form.elements.count.value ? ";COUNT=" + form.elements.count.value : "");
+    }
+  }
+  if (form.elements.remind.value !== "") {
+    body.remind_before = Number(form.elements.remind.value);
+  }
+
+  try {
+    if (state.editing) {
+      body.id = state.editing.id;
+      await call("POST", "/update_event", body);
+    } else {
+      await call("POST", "/create_event", body);
+    }
+    $("dialog").close();
+    await load();
+  } catch (err) {
+    setStatus(err.message, true);
+  }
+}
+
+async function remove() {
+  if (!state.editing || !confirm("Удалить событие «" + state.editing.title + "»?")) {
+    return;
+  }
+  try {
+    await call("POST", "/delete_event", { user_id: state.user, event_id: state.editing.id });
+    $("dialog").close();
+    await load();
+  } catch (err) {
+    setStatus(err.message, true);
+  }
+}
+
+function shiftMonth(delta) {
+  state.month = new Date(Date.UTC(state.month.getUTCFullYear(), state.month.getUTCMonth() + delta, 1));
+  load();
+}
+
+$("prev").addEventListener("click", () => shiftMonth(-1));
+$("next").addEventListener("click", () => shiftMonth(1));
+$("today").addEventListener("click", () => {
+  state.month = firstOfMonth(new Date());
+  load();
+});
+$("user").addEventListener("change", (e) => {
+  const user = Number(e.target.value);
+  if (user > 0) {
+    state.user = user;
+    localStorage.setItem("calendar.user", String(user));
+    load();
+  }
+});
+$("form").addEventListener("submit", (e) => {
+  if (e.submitter && e.submitter.value === "save") {
+    e.preventDefault();
+    save();
+  }
+});
+$("delete").addEventListener("click", remove);
+
+load();
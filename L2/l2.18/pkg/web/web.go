@@ -0,0 +1,23 @@
+// Package web - встроенный в бинарник веб-интерфейс календаря (вид месяца) поверх JSON API
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler отдаёт файлы интерфейса: index.html, app.js, style.css
+func Handler() http.Handler {
+
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		// каталог static встроен при сборке, ошибка здесь - ошибка программы
+		panic(err)
+	}
+
+	return http.FileServer(http.FS(files))
+}
@@ -0,0 +1,292 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IPampurin/calendar-server/pkg/api"
+	"github.com/IPampurin/calendar-server/pkg/ical"
+	"github.com/IPampurin/calendar-server/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseRRule проверяет разбор и запись правил повторения
+func TestParseRRule(t *testing.T) {
+
+	tests := []struct {
+		in, want, wantErr string
+	}{
+		{in: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{in: "RRULE:freq=weekly;byday=mo,fr;interval=2;count=4", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=4"},
+		{in: "FREQ=MONTHLY;UNTIL=20261231", want: "FREQ=MONTHLY;UNTIL=20261231"},
+		{in: "FREQ=DAILY;UNTIL=20261231T235959Z", want: "FREQ=DAILY;UNTIL=20261231T235959Z"},
+		{in: "FREQ=DAILY;INTERVAL=1;WKST=MO", want: "FREQ=DAILY"},
+		{in: "", wantErr: "FREQ обязателен"},
+		{in: "FREQ=YEARLY", wantErr: "FREQ=YEARLY не поддерживается"},
+		{in: "FREQ=WEEKLY;BYDAY=1MO", wantErr: "BYDAY"},
+		{in: "FREQ=DAILY;BYDAY=MO", wantErr: "только для FREQ=WEEKLY"},
+		{in: "FREQ=DAILY;COUNT=2;UNTIL=20260101", wantErr: "COUNT и UNTIL"},
+		{in: "FREQ=DAILY;COUNT=0", wantErr: "COUNT"},
+		{in: "FREQ=DAILY;BYMONTH=1", wantErr: "BYMONTH не поддерживается"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			r, err := storage.ParseRRule(tt.in)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, r.String())
+		})
+	}
+}
+
+// TestOccurrences проверяет даты повторений на границах периода
+func TestOccurrences(t *testing.T) {
+
+	start := time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC) // понедельник
+	r, err := storage.ParseRRule("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;UNTIL=20260205T180000Z")
+	require.NoError(t, err)
+	event := &storage.Event{Date: start, Recurrence: r}
+
+	var got []string
+	for _, d := range event.Occurrences(start, start.AddDate(1, 0, 0)) {
+		got = append(got, d.Format("2006-01-02"))
+	}
+	// через неделю, UNTIL включает повторение ровно в 18:00 5 февраля
+	assert.Equal(t, []string{"2026-01-05", "2026-01-08", "2026-01-19", "2026-01-22", "2026-02-02", "2026-02-05"}, got)
+
+	// конец периода не включается
+	assert.Empty(t, event.Occurrences(start.AddDate(0, 0, -1), start))
+
+	// бесконечное правило далеко в будущем считается без перебора с начала
+	endless := &storage.Event{Date: start, Recurrence: &storage.Recurrence{Freq: storage.FreqDaily}}
+	from := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Len(t, endless.Occurrences(from, from.AddDate(0, 0, 7)), 7)
+}
+
+// TestICalRoundTrip проверяет, что экспортированный календарь читается обратно без потерь
+func TestICalRoundTrip(t *testing.T) {
+
+	until, _, err := storage.ParseICalTime("20260301", time.UTC)
+	require.NoError(t, err)
+	events := []*storage.Event{
+		{ID: 1, UserID: 7, Date: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), Title: "День рождения, торт; свечи",
+			Content: "Строка 1\nСтрока 2 \\ конец", Recurrence: &storage.Recurrence{Freq: storage.FreqMonthly, Until: until, UntilDate: true},
+			ExDates: []time.Time{time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)}},
+		{ID: 2, UserID: 7, Date: time.Date(2026, 1, 16, 9, 30, 0, 0, time.UTC), Title: strings.Repeat("Длинный заголовок ", 10)},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, ical.Encode(&buf, events, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20260115\r\n")
+	assert.Contains(t, out, "DTSTART:20260116T093000Z\r\n")
+	assert.Contains(t, out, `SUMMARY:День рождения\, торт\; свечи`)
+	assert.Contains(t, out, "RRULE:FREQ=MONTHLY;UNTIL=20260301\r\n")
+	assert.Contains(t, out, "EXDATE;VALUE=DATE:20260215\r\n")
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, "строка длиннее 75 октетов: %q", line)
+	}
+
+	decoded, skipped, err := ical.Decode(&buf)
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+	require.Len(t, decoded, 2)
+	for i, event := range decoded {
+		assert.Equal(t, events[i].Title, event.Title)
+		assert.Equal(t, events[i].Content, event.Content)
+		assert.True(t, events[i].Date.Equal(event.Date), "дата %v != %v", event.Date, events[i].Date)
+	}
+	require.NotNil(t, decoded[0].Recurrence)
+	assert.Equal(t, "FREQ=MONTHLY;UNTIL=20260301", decoded[0].Recurrence.String())
+	require.Len(t, decoded[0].ExDates, 1)
+	assert.True(t, decoded[0].ExDates[0].Equal(events[0].ExDates[0]))
+}
+
+// TestICalDecode проверяет разбор календарей, созданных другими программами
+func TestICalDecode(t *testing.T) {
+
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"PRODID:-//Other//EN",
+		"VERSION:2.0",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Moscow",
+		"BEGIN:STANDARD",
+		"DTSTART:19700101T000000",
+		"TZOFFSETFROM:+0300",
+		"TZOFFSETTO:+0300",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:a@example.com",
+		`DTSTART;TZID="Europe/Moscow":20260110T100000`,
+		"SUMMARY:Планёрка с очень длинным назва",
+		" нием",
+		"RRULE:FREQ=WEEKLY;BYDAY=SA,SU;COUNT=4",
+		"EXDATE;TZID=Europe/Moscow:20260111T100000,20260117T100000",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:Напоминание",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:b@example.com",
+		"DTSTART:20260110T100000Z",
+		"RRULE:FREQ=YEARLY",
+		"SUMMARY:Годовщина",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:c@example.com",
+		"SUMMARY:Без начала",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"SUMMARY:Задача",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+
+	events, skipped, err := ical.Decode(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	event := events[0]
+	assert.Equal(t, "Планёрка с очень длинным названием", event.Title, "перенесённая строка склеивается")
+	assert.Empty(t, event.Content, "DESCRIPTION из VALARM не относится к событию")
+	assert.Equal(t, time.Date(2026, 1, 10, 7, 0, 0, 0, time.UTC), event.Date.UTC(), "время в поясе TZID")
+	assert.Len(t, event.ExDates, 2)
+
+	var got []string
+	for _, d := range event.Occurrences(event.Date, event.Date.AddDate(0, 1, 0)) {
+		got = append(got, d.Format("2006-01-02"))
+	}
+	assert.Equal(t, []string{"2026-01-10", "2026-01-18"}, got, "COUNT учитывает исключённые повторения")
+
+	require.Len(t, skipped, 2)
+	assert.Equal(t, "b@example.com", skipped[0].UID)
+	assert.Contains(t, skipped[0].Reason, "YEARLY")
+	assert.Equal(t, "c@example.com", skipped[1].UID)
+	assert.Contains(t, skipped[1].Reason, "DTSTART")
+
+	// неверная структура календаря - ошибка
+	for _, bad := range []string{
+		"",
+		"BEGIN:VEVENT\r\nEND:VEVENT\r\n",
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n",
+		"BEGIN:VCALENDAR\r\nEND:VEVENT\r\n",
+		"BEGIN:VCALENDAR\r\nnot a property\r\nEND:VCALENDAR\r\n",
+	} {
+		_, _, err := ical.Decode(strings.NewReader(bad))
+		assert.Error(t, err, "календарь %q", bad)
+	}
+}
+
+// TestICalAPI проверяет эндпоинты импорта и экспорта календаря
+func TestICalAPI(t *testing.T) {
+
+	mock := newMockStorage()
+	apiMock := api.NewAPI(mock)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /create_event", apiMock.CreateEventHandler)
+	mux.HandleFunc("GET /events_for_month", apiMock.GetEventsForMonthHandler)
+	mux.HandleFunc("GET /export.ics", apiMock.ExportICSHandler)
+	mux.HandleFunc("POST /import_ics", apiMock.ImportICSHandler)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := server.Client()
+
+	t.Run("CREATE recurring", func(t *testing.T) {
+		body := `{"user_id": 1, "date": "2026-01-05", "title": "Спорт",
+			"rrule": "FREQ=WEEKLY;BYDAY=MO,WE", "exdates": ["2026-01-07"]}`
+		resp, err := client.Post(server.URL+"/create_event", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		resp, err = client.Get(server.URL + "/events_for_month?user_id=1&date=2026-01-01")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var answer struct {
+			Result []*storage.Event `json:"result"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&answer))
+		// понедельники и среды января кроме 7-го
+		assert.Len(t, answer.Result, 7)
+		require.NotNil(t, answer.Result[0].Recurrence, "правило возвращается в ответе")
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,WE", answer.Result[0].Recurrence.String())
+	})
+
+	t.Run("CREATE bad rrule", func(t *testing.T) {
+		for _, body := range []string{
+			`{"user_id": 1, "date": "2026-01-05", "title": "x", "rrule": "FREQ=HOURLY"}`,
+			`{"user_id": 1, "date": "2026-01-05", "title": "x", "exdates": ["2026-01-07"]}`,
+			`{"user_id": 1, "date": "2026-01-05", "title": "x", "rrule": "FREQ=DAILY", "exdates": ["07.01.2026"]}`,
+		} {
+			resp, err := client.Post(server.URL+"/create_event", "application/json", strings.NewReader(body))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+		}
+	})
+
+	t.Run("EXPORT", func(t *testing.T) {
+		resp, err := client.Get(server.URL + "/export.ics?user_id=1")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/calendar; charset=utf-8", resp.Header.Get("Content-Type"))
+
+		events, skipped, err := ical.Decode(resp.Body)
+		require.NoError(t, err)
+		assert.Empty(t, skipped)
+		require.Len(t, events, 1, "повторяющееся событие экспортируется одним VEVENT")
+		assert.Equal(t, "Спорт", events[0].Title)
+
+		resp, err = client.Get(server.URL + "/export.ics?user_id=99")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "неизвестный пользователь")
+	})
+
+	t.Run("IMPORT", func(t *testing.T) {
+		body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+			"BEGIN:VEVENT\r\nUID:1\r\nDTSTART;VALUE=DATE:20260120\r\nSUMMARY:Импорт\r\nRRULE:FREQ=DAILY;COUNT=3\r\nEND:VEVENT\r\n" +
+			"BEGIN:VEVENT\r\nUID:2\r\nSUMMARY:Без даты\r\nEND:VEVENT\r\n" +
+			"END:VCALENDAR\r\n"
+		resp, err := client.Post(server.URL+"/import_ics?user_id=2", "text/calendar", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var answer struct {
+			Result api.ImportResult `json:"result"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&answer))
+		assert.Equal(t, 1, answer.Result.Imported)
+		require.Len(t, answer.Result.Skipped, 1)
+		assert.Equal(t, "2", answer.Result.Skipped[0].UID)
+
+		events, err := mock.GetForMonth(2, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Len(t, events, 3)
+
+		resp2, err := client.Post(server.URL+"/import_ics?user_id=2", "text/calendar", strings.NewReader("garbage"))
+		require.NoError(t, err)
+		resp2.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp2.StatusCode)
+	})
+}
@@ -0,0 +1,453 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/IPampurin/calendar-server/pkg/api"
+	"github.com/IPampurin/calendar-server/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contract проверяет ответы обработчиков API v2 по описанию OpenAPI: операция находится
+// по шаблону маршрута, который выбрал mux, поэтому описание и обработчики не могут разойтись
+type contract struct {
+	t       *testing.T
+	spec    map[string]any
+	mux     *http.ServeMux
+	covered map[string]bool // "METHOD путь статус" - проверенные ответы
+}
+
+func newContract(t *testing.T, mux *http.ServeMux) *contract {
+
+	var spec map[string]any
+	require.NoError(t, json.Unmarshal(api.OpenAPI, &spec), "openapi.json - неверный JSON")
+
+	return &contract{t: t, spec: spec, mux: mux, covered: make(map[string]bool)}
+}
+
+// in возвращает проверку для подтеста t с общим учётом проверенных ответов
+func (c *contract) in(t *testing.T) *contract {
+	return &contract{t: t, spec: c.spec, mux: c.mux, covered: c.covered}
+}
+
+// ref возвращает узел описания, раскрывая $ref
+func (c *contract) ref(node any) map[string]any {
+
+	m, _ := node.(map[string]any)
+	for m != nil {
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			break
+		}
+		var next any = c.spec
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			next = next.(map[string]any)[part]
+		}
+		require.NotNil(c.t, next, "нет %s", ref)
+		m = next.(map[string]any)
+	}
+
+	return m
+}
+
+// operations возвращает все операции описания: "METHOD путь" -> операция
+func (c *contract) operations() map[string]map[string]any {
+
+	ops := make(map[string]map[string]any)
+	for path, item := range c.spec["paths"].(map[string]any) {
+		for method, op := range item.(map[string]any) {
+			if method == "parameters" {
+				continue
+			}
+			ops[strings.ToUpper(method)+" "+path] = op.(map[string]any)
+		}
+	}
+
+	return ops
+}
+
+// do выполняет запрос, проверяет ответ по описанию и возвращает его
+func (c *contract) do(method, url, contentType string, body []byte) *httptest.ResponseRecorder {
+
+	t := c.t
+	t.Helper()
+
+	req := httptest.NewRequest(method, url, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	_, pattern := c.mux.Handler(req)
+	op, ok := c.operations()[pattern]
+	require.True(t, ok, "%s %s: маршрут %q не описан в openapi.json", method, url, pattern)
+
+	w := httptest.NewRecorder()
+	c.mux.ServeHTTP(w, req)
+
+	status := strconv.Itoa(w.Code)
+	response := c.ref(op["responses"].(map[string]any)[status])
+	require.NotNil(t, response, "%s %s: статус %s не описан", method, url, status)
+	c.covered[pattern+" "+status] = true
+
+	content, _ := response["content"].(map[string]any)
+	if content == nil {
+		assert.Empty(t, w.Body.Bytes(), "%s %s: у ответа %s не должно быть тела", method, url, status)
+		return w
+	}
+
+	mediaType, _, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	require.NoError(t, err, "%s %s: Content-Type", method, url)
+	media, ok := content[mediaType].(map[string]any)
+	require.True(t, ok, "%s %s: тип %s не описан для статуса %s", method, url, mediaType, status)
+
+	var value any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &value), "%s %s: тело не JSON", method, url)
+	for _, problem := range c.validate(media["schema"], value, "$") {
+		t.Errorf("%s %s (%s): %s", method, url, status, problem)
+	}
+
+	return w
+}
+
+// validate проверяет значение по схеме (подмножество JSON Schema из OpenAPI 3.0)
+func (c *contract) validate(node, value any, path string) []string {
+
+	schema := c.ref(node)
+	if schema == nil {
+		return nil
+	}
+
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			fail("ожидался объект, получено %T", value)
+			return problems
+		}
+		props, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				fail("нет обязательного поля %s", name)
+			}
+		}
+		for name, v := range obj {
+			prop, ok := props[name]
+			if !ok {
+				if schema["additionalProperties"] == false {
+					fail("поле %s не описано", name)
+				}
+				continue
+			}
+			problems = append(problems, c.validate(prop, v, path+"."+name)...)
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			fail("ожидался массив, получено %T", value)
+			return problems
+		}
+		for i, v := range arr {
+			problems = append(problems, c.validate(schema["items"], v, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("ожидалась строка, получено %T", value)
+			return problems
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				fail("не date-time: %q", s)
+			}
+		}
+		if n, ok := schema["minLength"].(float64); ok && utf8.RuneCountInString(s) < int(n) {
+			fail("короче %v", n)
+		}
+		if n, ok := schema["maxLength"].(float64); ok && utf8.RuneCountInString(s) > int(n) {
+			fail("длиннее %v", n)
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			fail("ожидалось целое, получено %v", value)
+			return problems
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			fail("меньше %v", min)
+		}
+	}
+
+	return problems
+}
+
+// failingStorage - хранилище, все операции которого завершаются ошибкой
+type failingStorage struct {
+	storage.Repository
+}
+
+var errDisk = errors.New("диск недоступен")
+
+func (failingStorage) Add(*storage.Event) (int, error) { return 0, errDisk }
+func (failingStorage) Update(*storage.Event) error     { return errDisk }
+func (failingStorage) Delete(int, int) error           { return errDisk }
+func (failingStorage) GetAll(int) ([]*storage.Event, error) {
+	return nil, errDisk
+}
+func (failingStorage) GetForPeriod(int, time.Time, time.Time) ([]*storage.Event, error) {
+	return nil, errDisk
+}
+
+// problemOf разбирает описание ошибки из ответа
+func problemOf(t *testing.T, w *httptest.ResponseRecorder) api.Problem {
+
+	t.Helper()
+
+	var p api.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, w.Code, p.Status, "status в теле должен совпадать со статусом ответа")
+
+	return p
+}
+
+// paramNames возвращает имена параметров, не прошедших проверку
+func paramNames(p api.Problem) []string {
+
+	names := make([]string, 0, len(p.InvalidParams))
+	for _, param := range p.InvalidParams {
+		names = append(names, param.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// TestAPIv2Contract проверяет обработчики API v2 по описанию /v2/openapi.json
+func TestAPIv2Contract(t *testing.T) {
+
+	mux := http.NewServeMux()
+	api.NewAPI(newMockStorage()).RegisterV2(mux)
+	c := newContract(t, mux)
+
+	const jsonType = "application/json"
+	body := func(s string) []byte { return []byte(s) }
+
+	t.Run("описание", func(t *testing.T) {
+		c := c.in(t)
+		w := c.do("GET", "/v2/openapi.json", "", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3.0.3", c.spec["openapi"])
+
+		// у каждой операции описания есть обработчик именно с этим шаблоном
+		for key := range c.operations() {
+			method, path, _ := strings.Cut(key, " ")
+			url := strings.NewReplacer("{id}", "1", "{eid}", "1").Replace(path)
+			_, pattern := mux.Handler(httptest.NewRequest(method, url, nil))
+			assert.Equal(t, key, pattern, "операция %s не обрабатывается", key)
+		}
+	})
+
+	var id int
+	t.Run("создание", func(t *testing.T) {
+		c := c.in(t)
+		w := c.do("POST", "/v2/users/1/events", jsonType+"; charset=utf-8",
+			body(`{"date": "2026-01-05T09:00:00Z", "title": "Спорт", "rrule": "FREQ=WEEKLY;BYDAY=MO,WE", "exdates": ["2026-01-07"]}`))
+		require.Equal(t, http.StatusCreated, w.Code)
+		var event storage.Event
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &event))
+		id = event.ID
+		assert.Equal(t, fmt.Sprintf("/v2/users/1/events/%d", id), w.Header().Get("Location"))
+		assert.Equal(t, 1, event.UserID)
+
+		w = c.do("POST", "/v2/users/1/events", jsonType, body(`{"date": "2026-01-10", "title": "Разовое"}`))
+		require.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("ошибки создания", func(t *testing.T) {
+		c := c.in(t)
+		w := c.do("POST", "/v2/users/1/events", jsonType,
+			body(`{"date": "15.01.2026", "title": "", "rrule": "FREQ=HOURLY", "exdates": ["x"]}`))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		p := problemOf(t, w)
+		assert.Equal(t, "/v2/problems/validation", p.Type)
+		assert.Equal(t, "/v2/users/1/events", p.Instance)
+		assert.Equal(t, []string{"date", "exdates[0]", "rrule", "title"}, paramNames(p))
+
+		w = c.do("POST", "/v2/users/1/events", jsonType, body(`{"date": "2026-01-10", "title": "x", "exdates": ["2026-01-11"]}`))
+		assert.Equal(t, []string{"exdates"}, paramNames(problemOf(t, w)))
+
+		w = c.do("POST", "/v2/users/0/events", jsonType, body(`{"date": "2026-01-10", "title": "x"}`))
+		assert.Equal(t, []string{"id"}, paramNames(problemOf(t, w)))
+
+		w = c.do("POST", "/v2/users/1/events", jsonType, body(`{"date": "2026-01-10", "title": "x", "user_id": 5}`))
+		require.Equal(t, http.StatusBadRequest, w.Code, "неизвестное поле")
+		assert.Contains(t, problemOf(t, w).Detail, "user_id")
+
+		w = c.do("POST", "/v2/users/1/events", jsonType, body(`{"date": "2026-01-10", "title": "x"} {}`))
+		assert.Equal(t, http.StatusBadRequest, w.Code, "лишние данные")
+
+		w = c.do("POST", "/v2/users/1/events", "text/plain", body(`{"date": "2026-01-10", "title": "x"}`))
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+		big := body(`{"date": "2026-01-10", "title": "x", "content": "` + strings.Repeat("a", 2<<20) + `"}`)
+		w = c.do("POST", "/v2/users/1/events", jsonType, big)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("список", func(t *testing.T) {
+		c := c.in(t)
+		w := c.do("GET", "/v2/users/1/events?from=2026-01-01&to=2026-01-15", "", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var list api.EventList
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		var dates []string
+		for _, event := range list.Events {
+			dates = append(dates, event.Date.Format("2006-01-02"))
+		}
+		assert.ElementsMatch(t, []string{"2026-01-05", "2026-01-10", "2026-01-12", "2026-01-14"}, dates)
+
+		w = c.do("GET", "/v2/users/1/events?from=2026-01-01", "", nil)
+		assert.Equal(t, []string{"to"}, paramNames(problemOf(t, w)))
+		w = c.do("GET", "/v2/users/1/events?from=2026-02-01&to=2026-01-01", "", nil)
+		assert.Equal(t, []string{"to"}, paramNames(problemOf(t, w)))
+		w = c.do("GET", "/v2/users/1/events?from=2026-01-01&to=2028-01-01", "", nil)
+		assert.Equal(t, []string{"to"}, paramNames(problemOf(t, w)), "слишком длинный период")
+		w = c.do("GET", "/v2/users/x/events?from=bad&to=2026-01-01T00:00:00%2B03:00", "", nil)
+		assert.Equal(t, []string{"from", "id"}, paramNames(problemOf(t, w)))
+
+		w = c.do("GET", "/v2/users/99/events?from=2026-01-01&to=2026-02-01", "", nil)
+		require.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "/v2/problems/not-found", problemOf(t, w).Type)
+	})
+
+	t.Run("одно событие", func(t *testing.T) {
+		c := c.in(t)
+		w := c.do("GET", fmt.Sprintf("/v2/users/1/events/%d", id), "", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var event storage.Event
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &event))
+		assert.Equal(t, "Спорт", event.Title)
+		require.NotNil(t, event.Recurrence)
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,WE", event.Recurrence.String())
+
+		assert.Equal(t, http.StatusNotFound, c.do("GET", "/v2/users/1/events/999", "", nil).Code)
+		assert.Equal(t, http.StatusNotFound, c.do("GET", fmt.Sprintf("/v2/users/2/events/%d", id), "", nil).Code,
+			"событие другого пользователя")
+		assert.Equal(t, http.StatusBadRequest, c.do("GET", "/v2/users/1/events/-1", "", nil).Code)
+	})
+
+	t.Run("замена", func(t *testing.T) {
+		c := c.in(t)
+		url := fmt.Sprintf("/v2/users/1/events/%d", id)
+		w := c.do("PUT", url, jsonType, body(`{"date": "2026-01-06", "title": "Бассейн", "content": "Дорожка 3"}`))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		w = c.do("GET", url, "", nil)
+		var event storage.Event
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &event))
+		assert.Equal(t, "Бассейн", event.Title)
+		assert.Nil(t, event.Recurrence, "PUT заменяет событие целиком")
+
+		assert.Equal(t, http.StatusNotFound, c.do("PUT", "/v2/users/1/events/999", jsonType, body(`{"date": "2026-01-06", "title": "x"}`)).Code)
+		assert.Equal(t, http.StatusBadRequest, c.do("PUT", url, jsonType, body(`{"title": "x"}`)).Code)
+		assert.Equal(t, http.StatusBadRequest, c.do("PUT", "/v2/users/1/events/x", jsonType, body(`{}`)).Code)
+		assert.Equal(t, http.StatusUnsupportedMediaType, c.do("PUT", url, "", body(`{}`)).Code)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, c.do("PUT", url, jsonType, bytes.Repeat([]byte(" "), 2<<20)).Code)
+	})
+
+	t.Run("удаление", func(t *testing.T) {
+		c := c.in(t)
+		url := fmt.Sprintf("/v2/users/1/events/%d", id)
+		assert.Equal(t, http.StatusNoContent, c.do("DELETE", url, "", nil).Code)
+		assert.Equal(t, http.StatusNotFound, c.do("DELETE", url, "", nil).Code, "повторное удаление")
+		assert.Equal(t, http.StatusBadRequest, c.do("DELETE", "/v2/users/1/events/0", "", nil).Code)
+	})
+
+	t.Run("ошибка хранилища", func(t *testing.T) {
+		c := c.in(t)
+		failing := http.NewServeMux()
+		api.NewAPI(failingStorage{}).RegisterV2(failing)
+		fc := c.in(t)
+		fc.mux = failing
+
+		event := body(`{"date": "2026-01-06", "title": "x"}`)
+		for _, r := range []struct {
+			method, url string
+			body        []byte
+		}{
+			{"POST", "/v2/users/1/events", event},
+			{"GET", "/v2/users/1/events?from=2026-01-01&to=2026-02-01", nil},
+			{"GET", "/v2/users/1/events/1", nil},
+			{"PUT", "/v2/users/1/events/1", event},
+			{"DELETE", "/v2/users/1/events/1", nil},
+		} {
+			w := fc.do(r.method, r.url, jsonType, r.body)
+			require.Equal(t, http.StatusInternalServerError, w.Code, "%s %s", r.method, r.url)
+			assert.Equal(t, "диск недоступен", problemOf(t, w).Detail)
+		}
+	})
+
+	// каждый описанный ответ должен быть получен хотя бы одним запросом
+	for key, op := range c.operations() {
+		for status := range op["responses"].(map[string]any) {
+			assert.True(t, c.covered[key+" "+status], "ответ %s %s не проверен", key, status)
+		}
+	}
+}
+
+// TestAPIv2Storages проверяет, что API v2 одинаково работает со всеми хранилищами
+func TestAPIv2Storages(t *testing.T) {
+
+	for name, repo := range map[string]storage.Repository{
+		"memory": storage.NewStorage(),
+		"sqlite": newSQLiteStorage(t, t.TempDir()+"/calendar.db"),
+		"file":   newFileStorage(t, t.TempDir(), 0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			api.NewAPI(repo).RegisterV2(mux)
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			resp, err := http.Post(server.URL+"/v2/users/3/events", "application/json",
+				strings.NewReader(`{"date": "2026-03-01", "title": "x", "rrule": "FREQ=DAILY;COUNT=3"}`))
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+			resp, err = http.Get(server.URL + "/v2/users/3/events?from=2026-03-02&to=2026-04-01")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			var list api.EventList
+			require.NoError(t, json.Unmarshal(data, &list))
+			assert.Len(t, list.Events, 2)
+
+			req, _ := http.NewRequest(http.MethodDelete, server.URL+"/v2/users/3/events/777", nil)
+			resp, err = http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+			assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+		})
+	}
+}
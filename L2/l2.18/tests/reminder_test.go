@@ -0,0 +1,266 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/IPampurin/calendar-server/pkg/api"
+	"github.com/IPampurin/calendar-server/pkg/reminder"
+	"github.com/IPampurin/calendar-server/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const webhookSecret = "test-secret"
+
+// webhookStub - локальный приёмник вебхука: проверяет подпись и складывает напоминания в канал
+func webhookStub(t *testing.T) (*httptest.Server, <-chan reminder.Notification) {
+
+	received := make(chan reminder.Notification, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil || r.Header.Get(reminder.SignatureHeader) != reminder.Sign(webhookSecret, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var n reminder.Notification
+		if err := json.Unmarshal(body, &n); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- n
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, received
+}
+
+// runScheduler запускает планировщик с доставкой в заглушку вебхука
+func runScheduler(t *testing.T) (*reminder.Scheduler, <-chan reminder.Notification, *bytes.Buffer) {
+
+	srv, received := webhookStub(t)
+	var logs bytes.Buffer
+	s := reminder.NewScheduler(&reminder.WebhookNotifier{URL: srv.URL, Secret: webhookSecret}, log.New(&logs, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return s, received, &logs
+}
+
+func minutes(n int) *int { return &n }
+
+func TestReminderFires(t *testing.T) {
+
+	s, received, _ := runScheduler(t)
+	repo := s.Wrap(storage.NewStorage())
+
+	start := time.Now().Add(time.Minute + 300*time.Millisecond)
+	id, err := repo.Add(&storage.Event{UserID: 1, Date: start, Title: "Созвон", Content: "ссылка", RemindBefore: minutes(1)})
+	require.NoError(t, err)
+
+	at, ok := s.Pending(1, id)
+	require.True(t, ok)
+	assert.WithinDuration(t, start.Add(-time.Minute), at, time.Millisecond)
+
+	select {
+	case n := <-received:
+		assert.Equal(t, 1, n.UserID)
+		assert.Equal(t, id, n.EventID)
+		assert.Equal(t, "Созвон", n.Title)
+		assert.Equal(t, "ссылка", n.Content)
+		assert.Equal(t, 1, n.RemindBefore)
+		assert.True(t, n.Start.Equal(start))
+	case <-time.After(5 * time.Second):
+		t.Fatal("напоминание не отправлено")
+	}
+
+	_, ok = s.Pending(1, id)
+	assert.False(t, ok, "у разового события после отправки напоминаний не остаётся")
+}
+
+func TestReminderRescheduledOnUpdateAndDelete(t *testing.T) {
+
+	s, received, _ := runScheduler(t)
+	repo := s.Wrap(storage.NewStorage())
+
+	start := time.Now().Add(time.Hour)
+	id, err := repo.Add(&storage.Event{UserID: 1, Date: start, Title: "Встреча", RemindBefore: minutes(30)})
+	require.NoError(t, err)
+	at, ok := s.Pending(1, id)
+	require.True(t, ok)
+	assert.WithinDuration(t, start.Add(-30*time.Minute), at, time.Millisecond)
+
+	t.Log("Перенос события переносит напоминание")
+	moved := start.Add(24 * time.Hour)
+	require.NoError(t, repo.Update(&storage.Event{ID: id, UserID: 1, Date: moved, Title: "Встреча", RemindBefore: minutes(10)}))
+	at, ok = s.Pending(1, id)
+	require.True(t, ok)
+	assert.WithinDuration(t, moved.Add(-10*time.Minute), at, time.Millisecond)
+
+	t.Log("Событие без напоминания снимается с учёта")
+	require.NoError(t, repo.Update(&storage.Event{ID: id, UserID: 1, Date: moved, Title: "Встреча"}))
+	_, ok = s.Pending(1, id)
+	assert.False(t, ok)
+
+	t.Log("Удалённое событие не напоминает о себе")
+	soon := time.Now().Add(time.Minute + 200*time.Millisecond)
+	other, err := repo.Add(&storage.Event{UserID: 1, Date: soon, Title: "Отменено", RemindBefore: minutes(1)})
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(1, other))
+	_, ok = s.Pending(1, other)
+	assert.False(t, ok)
+
+	select {
+	case n := <-received:
+		t.Fatalf("лишнее напоминание: %+v", n)
+	case <-time.After(time.Second):
+	}
+
+	t.Log("Неудачное изменение не трогает напоминание")
+	require.NoError(t, repo.Update(&storage.Event{ID: id, UserID: 1, Date: moved, Title: "Встреча", RemindBefore: minutes(5)}))
+	err = repo.Update(&storage.Event{ID: id, UserID: 1, Date: moved, Title: "Встреча", RemindBefore: minutes(-60)})
+	require.ErrorIs(t, err, storage.ErrInvalid)
+	at, ok = s.Pending(1, id)
+	require.True(t, ok)
+	assert.WithinDuration(t, moved.Add(-5*time.Minute), at, time.Millisecond)
+}
+
+func TestReminderRecurring(t *testing.T) {
+
+	s, received, _ := runScheduler(t)
+	repo := s.Wrap(storage.NewStorage())
+
+	rule, err := storage.ParseRRule("FREQ=DAILY;COUNT=2")
+	require.NoError(t, err)
+	start := time.Now().Add(time.Minute + 300*time.Millisecond)
+	id, err := repo.Add(&storage.Event{UserID: 2, Date: start, Title: "Зарядка", Recurrence: rule, RemindBefore: minutes(1)})
+	require.NoError(t, err)
+
+	select {
+	case n := <-received:
+		assert.True(t, n.Start.Equal(start))
+	case <-time.After(5 * time.Second):
+		t.Fatal("напоминание не отправлено")
+	}
+
+	require.Eventually(t, func() bool {
+		at, ok := s.Pending(2, id)
+		return ok && at.Equal(start.AddDate(0, 0, 1).Add(-time.Minute))
+	}, 2*time.Second, 10*time.Millisecond, "после отправки планируется следующее повторение")
+}
+
+func TestReminderLoad(t *testing.T) {
+
+	db := storage.NewStorage()
+	future := time.Now().Add(2 * time.Hour)
+	withReminder, err := db.Add(&storage.Event{UserID: 1, Date: future, Title: "С напоминанием", RemindBefore: minutes(15)})
+	require.NoError(t, err)
+	without, err := db.Add(&storage.Event{UserID: 1, Date: future, Title: "Без напоминания"})
+	require.NoError(t, err)
+	late, err := db.Add(&storage.Event{UserID: 3, Date: time.Now().Add(5 * time.Minute), Title: "Опоздали", RemindBefore: minutes(10)})
+	require.NoError(t, err)
+
+	s := reminder.NewScheduler(reminder.LogNotifier{Logger: log.New(io.Discard, "", 0)}, log.New(io.Discard, "", 0))
+	require.NoError(t, s.Load(db))
+
+	at, ok := s.Pending(1, withReminder)
+	require.True(t, ok)
+	assert.WithinDuration(t, future.Add(-15*time.Minute), at, time.Millisecond)
+	_, ok = s.Pending(1, without)
+	assert.False(t, ok)
+	_, ok = s.Pending(3, late)
+	assert.False(t, ok, "прошедшее время напоминания не планируется")
+}
+
+func TestReminderWebhookFailureLogged(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	wh := &reminder.WebhookNotifier{URL: srv.URL}
+	err := wh.Notify(context.Background(), reminder.Notification{UserID: 1, EventID: 1, Title: "x"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "502")
+
+	t.Log("Ошибка доставки попадает в лог планировщика")
+	var logs bytes.Buffer
+	s := reminder.NewScheduler(wh, log.New(&logs, "", 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	s.Schedule(&storage.Event{ID: 7, UserID: 1, Date: time.Now().Add(time.Minute + 100*time.Millisecond), Title: "x", RemindBefore: minutes(1)})
+	require.Eventually(t, func() bool {
+		_, ok := s.Pending(1, 7)
+		return !ok
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done // Run дожидается начатых отправок
+	assert.Contains(t, logs.String(), "Ошибка отправки напоминания о событии 7")
+}
+
+func TestLogNotifier(t *testing.T) {
+
+	var logs bytes.Buffer
+	n := reminder.LogNotifier{Logger: log.New(&logs, "", 0)}
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, n.Notify(context.Background(), reminder.Notification{UserID: 4, EventID: 9, Title: "Врач", Start: start, RemindBefore: 60}))
+	assert.Contains(t, logs.String(), `пользователю 4: событие 9 "Врач" начнётся 2026-03-01T10:00:00Z`)
+}
+
+func TestNotifierFromEnv(t *testing.T) {
+
+	t.Setenv("CALENDAR_REMINDER_WEBHOOK", "")
+	_, ok := reminder.NotifierFromEnv(log.New(io.Discard, "", 0)).(reminder.LogNotifier)
+	assert.True(t, ok, "без вебхука - лог")
+
+	t.Setenv("CALENDAR_REMINDER_WEBHOOK", "http://127.0.0.1:1/hook")
+	t.Setenv("CALENDAR_REMINDER_SECRET", "s")
+	wh, ok := reminder.NotifierFromEnv(log.New(io.Discard, "", 0)).(*reminder.WebhookNotifier)
+	require.True(t, ok)
+	assert.Equal(t, "http://127.0.0.1:1/hook", wh.URL)
+	assert.Equal(t, "s", wh.Secret)
+}
+
+func TestRemindBeforeValidationAPI(t *testing.T) {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /create_event", api.NewAPI(storage.NewStorage()).CreateEventHandler)
+
+	for _, tc := range []struct {
+		body   string
+		status int
+	}{
+		{`{"user_id":1,"date":"2026-01-01","title":"a","remind_before":15}`, http.StatusCreated},
+		{`{"user_id":1,"date":"2026-01-01","title":"a","remind_before":-1}`, http.StatusBadRequest},
+		{`{"user_id":1,"date":"2026-01-01","title":"a","remind_before":40321}`, http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/create_event", bytes.NewBufferString(tc.body)))
+		assert.Equal(t, tc.status, w.Code, tc.body)
+	}
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/IPampurin/calendar-server/pkg/storage"
+)
+
+// benchEvents - сколько событий в хранилище при замерах
+const benchEvents = 1_000_000
+
+// benchStart и benchDays - события равномерно распределены по десяти годам
+var benchStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const benchDays = 3653
+
+// benchRepo - хранилище с событиями для замеров
+type benchRepo struct {
+	repo  storage.Repository
+	users int
+	ids   [][]int // ID событий каждого пользователя
+}
+
+// fillBench заполняет хранилище benchEvents событиями users пользователей
+func fillBench(b *testing.B, repo storage.Repository, users int) *benchRepo {
+
+	b.Helper()
+
+	rng := rand.New(rand.NewPCG(1, 1))
+	br := &benchRepo{repo: repo, users: users, ids: make([][]int, users)}
+	for i := range benchEvents {
+		userID := i % users
+		id, err := repo.Create(userID, benchDate(rng), "event", "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		br.ids[userID] = append(br.ids[userID], id)
+	}
+
+	return br
+}
+
+// benchDate возвращает случайное время в пределах десяти лет
+func benchDate(rng *rand.Rand) time.Time {
+	return benchStart.Add(time.Duration(rng.Int64N(benchDays * 24 * int64(time.Hour))))
+}
+
+// BenchmarkStorage сравнивает хранилище в памяти (один RWMutex, перебор событий) и хранилище
+// с индексами на миллионе событий: у одного пользователя и поровну у тысячи пользователей.
+// Запуск: go test ./tests -run '^$' -bench Storage -benchmem
+func BenchmarkStorage(b *testing.B) {
+
+	impls := []struct {
+		name string
+		new  func() storage.Repository
+	}{
+		{"memory", func() storage.Repository { return storage.NewStorage() }},
+		{"indexed", func() storage.Repository { return storage.NewIndexedStorage() }},
+	}
+
+	for _, users := range []int{1, 1000} {
+		for _, impl := range impls {
+			b.Run(fmt.Sprintf("%s/users=%d", impl.name, users), func(b *testing.B) {
+				br := fillBench(b, impl.new(), users)
+				benchQueries(b, br)
+			})
+		}
+	}
+}
+
+// benchQueries замеряет операции над заполненным хранилищем
+func benchQueries(b *testing.B, br *benchRepo) {
+
+	gets := []struct {
+		name string
+		get  func(userID int, date time.Time) ([]*storage.Event, error)
+	}{
+		{"GetForDay", br.repo.GetForDay},
+		{"GetForWeek", br.repo.GetForWeek},
+		{"GetForMonth", br.repo.GetForMonth},
+	}
+	for _, g := range gets {
+		b.Run(g.name, func(b *testing.B) {
+			rng := rand.New(rand.NewPCG(2, 2))
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := g.get(rng.IntN(br.users), benchDate(rng)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	b.Run("Update", func(b *testing.B) {
+		rng := rand.New(rand.NewPCG(3, 3))
+		b.ReportAllocs()
+		for b.Loop() {
+			userID := rng.IntN(br.users)
+			ids := br.ids[userID]
+			event := &storage.Event{ID: ids[rng.IntN(len(ids))], UserID: userID, Date: benchDate(rng), Title: "updated"}
+			if err := br.repo.Update(event); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// удаление вместе с добавлением, чтобы число событий не менялось
+	b.Run("DeleteAdd", func(b *testing.B) {
+		rng := rand.New(rand.NewPCG(4, 4))
+		b.ReportAllocs()
+		for b.Loop() {
+			userID := rng.IntN(br.users)
+			ids := br.ids[userID]
+			k := rng.IntN(len(ids))
+			if err := br.repo.Delete(userID, ids[k]); err != nil {
+				b.Fatal(err)
+			}
+			id, err := br.repo.Create(userID, benchDate(rng), "event", "")
+			if err != nil {
+				b.Fatal(err)
+			}
+			ids[k] = id
+		}
+	})
+
+	// смешанная нагрузка из горутин: 90% выборок за неделю, 10% изменений
+	// (br.ids здесь только читается - Update не меняет ID)
+	b.Run("ParallelMixed", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			rng := rand.New(rand.NewPCG(rand.Uint64(), 5))
+			for pb.Next() {
+				userID := rng.IntN(br.users)
+				if rng.IntN(10) == 0 {
+					ids := br.ids[userID]
+					event := &storage.Event{ID: ids[rng.IntN(len(ids))], UserID: userID, Date: benchDate(rng), Title: "updated"}
+					if err := br.repo.Update(event); err != nil {
+						b.Error(err)
+						return
+					}
+					continue
+				}
+				if _, err := br.repo.GetForWeek(userID, benchDate(rng)); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+}
@@ -1,12 +1,19 @@
 package tests
 
 import (
+	"bytes"
+	"database/sql"
 	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/IPampurin/calendar-server/pkg/storage"
+	"github.com/IPampurin/calendar-server/pkg/storage/storagetest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,274 +27,397 @@ func TestNewStorage(t *testing.T) {
 	require.Equal(t, 1, s.NextID, "NextID должен быть 1")
 }
 
-// TestCreate проверяет создание событий
-func TestCreate(t *testing.T) {
+// newFileStorage открывает файловое хранилище в каталоге dir и закрывает его после теста
+func newFileStorage(t *testing.T, dir string, snapshotEvery int) *storage.FileStorage {
 
-	s := storage.NewStorage()
-	date := time.Now()
-
-	// создаем первое событие - ожидаем ID = 1
-	t.Log("Создание первого события")
-	id, err := s.Create(1, date, "Meeting", "Team meeting")
-	assert.NoError(t, err, "Создание события не должно вернуть ошибку")
-	assert.Equal(t, 1, id, "ID первого события должен быть 1")
-
-	// создаем второе событие для того же пользователя - ожидаем ID = 2
-	t.Log("Создание второго события для того же пользователя")
-	id2, err := s.Create(1, date, "Lunch", "With colleagues")
-	assert.NoError(t, err, "Создание второго события не должно вернуть ошибку")
-	assert.Equal(t, 2, id2, "ID второго события должен быть 2 (инкремент счетчика)")
-
-	// попытка создать событие с пустым заголовком
-	t.Log("Проверка валидации: пустой заголовок")
-	_, err = s.Create(1, date, "", "Empty title")
-	assert.Error(t, err, "Должна быть ошибка при пустом заголовке")
-	assert.Contains(t, err.Error(), "title", "Ошибка должна упоминать поле title")
-
-	// попытка создать событие с отрицательным ID пользователя
-	t.Log("Проверка валидации: отрицательный userID")
-	_, err = s.Create(-1, date, "Test", "Test")
-	assert.Error(t, err, "Должна быть ошибка при отрицательном userID")
-	assert.Contains(t, err.Error(), "ошибочный ID", "Ошибка должна указывать на некорректный ID")
+	t.Helper()
+
+	s, err := storage.NewFileStorage(dir, snapshotEvery)
+	require.NoError(t, err, "не удалось открыть файловое хранилище")
+	t.Cleanup(func() { s.Close() })
+
+	return s
 }
 
-// TestUpdate проверяет обновление существующего события
-func TestUpdate(t *testing.T) {
+// newSQLiteStorage открывает базу SQLite в файле path и закрывает её после теста
+func newSQLiteStorage(t *testing.T, path string) *storage.SQLiteStorage {
 
-	s := storage.NewStorage()
-	date := time.Now()
-
-	// создаем событие для последующего обновления
-	id, err := s.Create(1, date, "Old title", "Old content")
-	require.NoError(t, err, "Не удалось создать тестовое событие")
-	require.Equal(t, 1, id, "ID тестового события должен быть 1")
-
-	// готовим обновленные данные: меняем дату (+1 день), заголовок и содержание
-	newDate := date.Add(24 * time.Hour)
-	updatedEvent := &storage.Event{
-		ID:      id, // ID должен совпадать с существующим событием
-		UserID:  1,  // UserID должен совпадать с владельцем
-		Date:    newDate,
-		Title:   "New title",
-		Content: "New content",
-	}
+	t.Helper()
+
+	s, err := storage.NewSQLiteStorage(path)
+	require.NoError(t, err, "не удалось открыть базу SQLite")
+	t.Cleanup(func() { s.Close() })
 
-	// обновляем событие
-	err = s.Update(updatedEvent)
-	assert.NoError(t, err, "Обновление существующего события не должно вернуть ошибку")
-
-	// получаем событие на новую дату и проверяем поля
-	events, err := s.GetForDay(1, newDate)
-	require.NoError(t, err, "Не удалось получить события на обновленную дату")
-	require.Len(t, events, 1, "На новую дату должно быть ровно одно событие")
-
-	// проверяем, что все поля действительно обновились
-	assert.Equal(t, "New title", events[0].Title, "Заголовок не обновился")
-	assert.Equal(t, "New content", events[0].Content, "Содержание не обновилось")
-	assert.True(t, events[0].Date.Equal(newDate), "Дата не обновилась")
-
-	// попытка обновить несуществующее событие
-	t.Log("Проверка: обновление несуществующего события")
-	err = s.Update(&storage.Event{ID: 999, UserID: 1})
-	assert.Error(t, err, "Должна быть ошибка при обновлении несуществующего события")
-	assert.Contains(t, err.Error(), "не найдено", "Ошибка должна указывать, что событие не найдено")
-
-	// передача nil вместо указателя на событие
-	t.Log("Проверка: передача nil")
-	err = s.Update(nil)
-	assert.Error(t, err, "Должна быть ошибка при передаче nil")
-	assert.Contains(t, err.Error(), "nil", "Ошибка должна упоминать nil")
+	return s
 }
 
-// TestDelete проверяет удаление событий
-func TestDelete(t *testing.T) {
+// TestMemoryStorage проверяет хранилище в памяти общим набором тестов
+func TestMemoryStorage(t *testing.T) {
 
-	s := storage.NewStorage()
-	date := time.Now()
-
-	// создаем событие для удаления
-	id, err := s.Create(1, date, "To delete", "Content")
-	require.NoError(t, err, "Не удалось создать тестовое событие")
-	require.Equal(t, 1, id, "ID тестового события должен быть 1")
-
-	// удаляем событие
-	err = s.Delete(1, id)
-	assert.NoError(t, err, "Удаление существующего события не должно вернуть ошибку")
-
-	// убеждаемся, что событие действительно удалено
-	events, err := s.GetForDay(1, date)
-	require.NoError(t, err, "Не удалось получить список событий")
-	assert.Empty(t, events, "После удаления список событий должен быть пуст")
-
-	// попытка удалить уже удаленное (несуществующее) событие
-	t.Log("Проверка: удаление несуществующего события")
-	err = s.Delete(1, 999)
-	assert.Error(t, err, "Должна быть ошибка при удалении несуществующего события")
-	assert.Contains(t, err.Error(), "не найдено", "Ошибка должна указывать, что событие не найдено")
-
-	// попытка удалить событие у несуществующего пользователя
-	t.Log("Проверка: удаление у несуществующего пользователя")
-	err = s.Delete(999, 1)
-	assert.Error(t, err, "Должна быть ошибка при удалении у несуществующего пользователя")
-	assert.Contains(t, err.Error(), "не найден", "Ошибка должна указывать, что пользователь не найден")
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		return storage.NewStorage()
+	})
 }
 
-// TestGetForDay проверяет получение событий за конкретный день
-func TestGetForDay(t *testing.T) {
+// TestIndexedStorage проверяет хранилище с индексами общим набором тестов
+func TestIndexedStorage(t *testing.T) {
 
-	s := storage.NewStorage()
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		return storage.NewIndexedStorage()
+	})
+}
+
+// TestIndexedStorageMatchesStorage выполняет одни и те же случайные операции над хранилищем
+// с индексами и обычным и сравнивает выборки: индексы должны давать тот же результат, что перебор
+func TestIndexedStorageMatchesStorage(t *testing.T) {
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	randomDate := func() time.Time {
+		// много совпадающих дат, чтобы проверить порядок по ID при равной дате
+		return base.Add(time.Duration(rng.IntN(90*24)) * time.Hour)
+	}
+
+	plain, indexed := storage.NewStorage(), storage.NewIndexedStorage()
+	var ids []storage.Event // события, которые сейчас есть в хранилищах
+
+	for i := range 3000 {
+		switch op := rng.IntN(10); {
+		case op < 5 || len(ids) == 0:
+			event := &storage.Event{UserID: rng.IntN(3), Date: randomDate(), Title: fmt.Sprint("event ", i)}
+			if rng.IntN(20) == 0 {
+				event.Recurrence = &storage.Recurrence{Freq: storage.FreqWeekly, Count: 5}
+			}
+			id1, err1 := plain.Add(event)
+			id2, err2 := indexed.Add(event)
+			require.NoError(t, err1)
+			require.NoError(t, err2)
+			require.Equal(t, id1, id2)
+			event.ID = id1
+			ids = append(ids, *event)
+
+		case op < 8:
+			k := rng.IntN(len(ids))
+			ids[k].Date = randomDate()
+			ids[k].Title += " upd"
+			require.NoError(t, plain.Update(&ids[k]))
+			require.NoError(t, indexed.Update(&ids[k]))
+
+		default:
+			k := rng.IntN(len(ids))
+			require.NoError(t, plain.Delete(ids[k].UserID, ids[k].ID))
+			require.NoError(t, indexed.Delete(ids[k].UserID, ids[k].ID))
+			ids = slices.Delete(ids, k, k+1)
+		}
+	}
 
-	// фиксированная дата для предсказуемости теста
-	baseDate := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
-
-	// создаем события на разные дни
-	// событие в целевой день (15.01.2024)
-	_, err := s.Create(1, baseDate, "Event 1", "")
-	require.NoError(t, err, "Не удалось создать событие на целевую дату")
-
-	// событие на следующий день (16.01.2024) - не должно попасть в выборку
-	_, err = s.Create(1, baseDate.Add(24*time.Hour), "Event 2", "")
-	require.NoError(t, err, "Не удалось создать событие на следующий день")
-
-	// событие на предыдущий день (14.01.2024) - не должно попасть в выборку
-	_, err = s.Create(1, baseDate.Add(-24*time.Hour), "Event 3", "")
-	require.NoError(t, err, "Не удалось создать событие на предыдущий день")
-
-	// получение событий за день
-	t.Log("Получение событий за 15.01.2024")
-	events, err := s.GetForDay(1, baseDate)
-	assert.NoError(t, err, "Получение событий не должно вернуть ошибку")
-	assert.Len(t, events, 1, "Должно быть ровно одно событие за целевую дату")
-	assert.Equal(t, "Event 1", events[0].Title, "Найдено не то событие")
-
-	// запрашиваем день, на который нет событий
-	t.Log("Получение событий за день без событий")
-	emptyDay := baseDate.Add(48 * time.Hour) // 17.01.2024
-	events, err = s.GetForDay(1, emptyDay)
-	assert.NoError(t, err, "Получение пустого списка не должно вернуть ошибку")
-	assert.Empty(t, events, "Должен вернуться пустой слайс, а не nil")
-
-	// проверяем несуществующего пользователя
-	t.Log("Проверка: получение событий несуществующего пользователя")
-	events, err = s.GetForDay(999, baseDate)
-	assert.Error(t, err, "Должна быть ошибка для несуществующего пользователя")
-	assert.Contains(t, err.Error(), "не найден", "Ошибка должна указывать, что пользователь не найден")
-	assert.Empty(t, events, "При ошибке должен возвращаться пустой слайс")
+	for userID := range 3 {
+		for day := base; day.Before(base.AddDate(0, 4, 0)); day = day.AddDate(0, 0, 5) {
+			for _, get := range []string{"day", "week", "month"} {
+				var want, got []*storage.Event
+				var err error
+				switch get {
+				case "day":
+					want, _ = plain.GetForDay(userID, day)
+					got, err = indexed.GetForDay(userID, day)
+				case "week":
+					want, _ = plain.GetForWeek(userID, day)
+					got, err = indexed.GetForWeek(userID, day)
+				case "month":
+					want, _ = plain.GetForMonth(userID, day)
+					got, err = indexed.GetForMonth(userID, day)
+				}
+				require.NoError(t, err)
+				require.Equal(t, summary(want), summary(got), "user %d, %s %s", userID, get, day.Format("2006-01-02"))
+			}
+		}
+
+		want, _ := plain.GetAll(userID)
+		got, err := indexed.GetAll(userID)
+		require.NoError(t, err)
+		require.Equal(t, summary(want), summary(got), "GetAll user %d", userID)
+	}
 }
 
-// TestGetForWeek проверяет получение событий за неделю
-func TestGetForWeek(t *testing.T) {
+// summary описывает события строками "ID дата заголовок" для сравнения
+func summary(events []*storage.Event) []string {
 
-	s := storage.NewStorage()
+	result := make([]string, 0, len(events))
+	for _, e := range events {
+		result = append(result, fmt.Sprintf("%d %s %s", e.ID, e.Date.Format(time.RFC3339), e.Title))
+	}
 
-	// 15 января 2024 - понедельник
-	monday := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	return result
+}
 
-	// создаем события внутри и вне целевой недели
-	// событие в понедельник - должно быть в выборке
-	_, err := s.Create(1, monday, "Monday event", "")
-	require.NoError(t, err)
+// TestIndexedStorageConsistentReads проверяет под детектором гонок, что при одновременных
+// переносах событий выборка за день видит только события этого дня
+func TestIndexedStorageConsistentReads(t *testing.T) {
 
-	// событие в среду - должно быть в выборке
-	_, err = s.Create(1, monday.Add(48*time.Hour), "Wednesday event", "")
-	require.NoError(t, err)
+	s := storage.NewIndexedStorage()
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
 
-	// событие в следующий понедельник - не должно быть в выборке
-	_, err = s.Create(1, monday.Add(7*24*time.Hour), "Next Monday", "")
-	require.NoError(t, err)
+	var ids []int
+	for i := range 50 {
+		id, err := s.Create(i%2, day.Add(time.Duration(i)*time.Minute), "event", "")
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
 
-	// получение событий за неделю
-	t.Log("Получение событий за неделю с 15.01.2024")
-	events, err := s.GetForWeek(1, monday)
-	assert.NoError(t, err, "Получение событий за неделю не должно вернуть ошибку")
-	assert.Len(t, events, 2, "Должно быть ровно 2 события (пн и ср)")
+	// писатели переносят события между днями
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				k := (w + i*4) % len(ids)
+				date := day.AddDate(0, 0, i%3).Add(time.Duration(k) * time.Minute)
+				assert.NoError(t, s.Update(&storage.Event{ID: ids[k], UserID: k % 2, Date: date, Title: "moved"}))
+			}
+		}()
+	}
 
-	// проверяем, что это именно те события, которые мы ожидаем (проверяем заголовки)
-	titles := []string{events[0].Title, events[1].Title}
-	assert.Contains(t, titles, "Monday event", "Событие понедельника не найдено")
-	assert.Contains(t, titles, "Wednesday event", "Событие среды не найдено")
+	// читатели проверяют, что выборка не содержит событий других дней
+	for r := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 300 {
+				events, err := s.GetForDay(r%2, day)
+				assert.NoError(t, err)
+				for _, e := range events {
+					assert.True(t, !e.Date.Before(day) && e.Date.Before(day.AddDate(0, 0, 1)), "событие %d другого дня: %s", e.ID, e.Date)
+				}
+			}
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	all0, _ := s.GetAll(0)
+	all1, _ := s.GetAll(1)
+	assert.Equal(t, len(ids), len(all0)+len(all1), "события не должны теряться или дублироваться")
 }
 
-// TestGetForMonth проверяет получение событий за месяц
-func TestGetForMonth(t *testing.T) {
+// TestFileStorage проверяет файловое хранилище общим набором тестов
+func TestFileStorage(t *testing.T) {
 
-	s := storage.NewStorage()
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		// частые снимки, чтобы тесты проходили и через журнал, и через снимок
+		return newFileStorage(t, t.TempDir(), 3)
+	})
 
-	// 15 января 2024 - середина месяца
-	january := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	t.Run("Persistence", func(t *testing.T) {
+		storagetest.RunPersistence(t, func(t *testing.T, dir string) storage.Repository {
+			return newFileStorage(t, dir, 2)
+		})
+	})
+}
 
-	// создаем события в январе и феврале
-	// событие в январе - должно быть в выборке
-	_, err := s.Create(1, january, "January event", "")
-	require.NoError(t, err)
+// TestSQLiteStorage проверяет хранилище SQLite общим набором тестов
+func TestSQLiteStorage(t *testing.T) {
 
-	// событие 15 февраля (январь + 31 день) - не должно быть в выборке за январь
-	_, err = s.Create(1, january.AddDate(0, 1, 0), "February event", "")
-	require.NoError(t, err)
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		return newSQLiteStorage(t, filepath.Join(t.TempDir(), "calendar.db"))
+	})
 
-	// получение событий за январь
-	t.Log("Получение событий за январь 2024")
-	events, err := s.GetForMonth(1, january)
-	assert.NoError(t, err, "Получение событий за месяц не должно вернуть ошибку")
-	assert.Len(t, events, 1, "Должно быть ровно одно событие в январе")
-	assert.Equal(t, "January event", events[0].Title, "Найдено не то событие")
-
-	// проверка границ месяца
-	// проверяем последний день января
-	lastDayOfJanuary := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
-	events, err = s.GetForMonth(1, lastDayOfJanuary)
-	assert.NoError(t, err)
-	assert.Len(t, events, 1, "Даже при запросе в последний день января должно найтись событие")
+	t.Run("Persistence", func(t *testing.T) {
+		storagetest.RunPersistence(t, func(t *testing.T, dir string) storage.Repository {
+			return newSQLiteStorage(t, filepath.Join(dir, "calendar.db"))
+		})
+	})
 }
 
-// TestConcurrentAccess проверяет, что хранилище корректно работает при конкурентном доступе
-func TestConcurrentAccess(t *testing.T) {
+// TestSQLiteMigration проверяет, что база прежней версии (без столбцов повторений) дополняется при открытии
+func TestSQLiteMigration(t *testing.T) {
 
-	s := storage.NewStorage()
-	date := time.Now()
+	path := filepath.Join(t.TempDir(), "calendar.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE users (id INTEGER PRIMARY KEY);
+		CREATE TABLE events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL REFERENCES users(id),
+			date TEXT NOT NULL, date_unix INTEGER NOT NULL, title TEXT NOT NULL, content TEXT NOT NULL DEFAULT '');
+		INSERT INTO users (id) VALUES (1);
+		INSERT INTO events (user_id, date, date_unix, title) VALUES (1, '2024-01-15T10:00:00Z', 1705312800, 'Old');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
 
-	// количество конкурентных операций
-	const goroutinesCount = 100
+	s := newSQLiteStorage(t, path)
+	events, err := s.GetForDay(1, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, events, 1, "старое событие должно читаться")
+	assert.Nil(t, events[0].Recurrence)
 
-	var wg sync.WaitGroup
-	wg.Add(goroutinesCount)
+	r, err := storage.ParseRRule("FREQ=DAILY;COUNT=2")
+	require.NoError(t, err)
+	_, err = s.Add(&storage.Event{UserID: 1, Date: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), Title: "New", Recurrence: r})
+	require.NoError(t, err)
+	events, err = s.GetForDay(1, time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Len(t, events, 1, "повторение нового события")
+}
 
-	// канал для сбора ошибок от горутин
-	// используем буферизированный канал, чтобы горутины не блокировались при отправке
-	errors := make(chan error, goroutinesCount)
+// TestFileStorageJournal проверяет восстановление файлового хранилища после сбоев
+func TestFileStorageJournal(t *testing.T) {
+
+	date := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	journal := func(dir string) string { return filepath.Join(dir, "journal.log") }
+
+	t.Run("снимок по числу записей", func(t *testing.T) {
+		dir := t.TempDir()
+		s := newFileStorage(t, dir, 3)
+		for i := 0; i < 4; i++ {
+			_, err := s.Create(1, date, "Event", "")
+			require.NoError(t, err)
+		}
+
+		assert.FileExists(t, filepath.Join(dir, "snapshot.json"), "после 3 записей должен появиться снимок")
+		data, err := os.ReadFile(journal(dir))
+		require.NoError(t, err)
+		assert.Equal(t, 1, countLines(data), "в журнале должна остаться только запись после снимка")
+	})
+
+	t.Run("оборванная запись", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := storage.NewFileStorage(dir, 100)
+		require.NoError(t, err)
+		_, err = s.Create(1, date, "Saved", "")
+		require.NoError(t, err)
+
+		// процесс "упал" во время записи второго события: журнал без снимка и с хвостом
+		data, err := os.ReadFile(journal(dir))
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+		require.NoError(t, os.Remove(filepath.Join(dir, "snapshot.json")))
+		require.NoError(t, os.WriteFile(journal(dir), append(data, []byte(`1a2b3c4d {"seq":2,"op":"cre`)...), 0o644))
+
+		s2 := newFileStorage(t, dir, 100)
+		events, err := s2.GetForDay(1, date)
+		require.NoError(t, err)
+		require.Len(t, events, 1, "целая запись должна восстановиться, оборванная - отброситься")
+		assert.Equal(t, "Saved", events[0].Title)
+
+		// журнал продолжается с конца целой записи
+		id, err := s2.Create(1, date, "Next", "")
+		require.NoError(t, err)
+		assert.Equal(t, 2, id)
+		data, err = os.ReadFile(journal(dir))
+		require.NoError(t, err)
+		assert.Equal(t, 2, countLines(data))
+		assert.NotContains(t, string(data), "1a2b3c4d", "оборванная запись должна быть отрезана")
+	})
+
+	t.Run("испорченная запись в середине", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := storage.NewFileStorage(dir, 100)
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			_, err = s.Create(1, date, "Event", "")
+			require.NoError(t, err)
+		}
+		data, err := os.ReadFile(journal(dir))
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+		require.NoError(t, os.Remove(filepath.Join(dir, "snapshot.json")))
+
+		// меняем байт во второй записи - контрольная сумма не сойдётся
+		lines := splitLines(data)
+		lines[1][len(lines[1])-3] ^= 1
+		require.NoError(t, os.WriteFile(journal(dir), joinLines(lines), 0o644))
+
+		_, err = storage.NewFileStorage(dir, 100)
+		require.Error(t, err, "повреждение в середине журнала нельзя молча пропускать")
+		assert.Contains(t, err.Error(), "строка 2")
+	})
+
+	t.Run("сбой между снимком и очисткой журнала", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := storage.NewFileStorage(dir, 100)
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			_, err = s.Create(1, date, "Event", "")
+			require.NoError(t, err)
+		}
+		data, err := os.ReadFile(journal(dir))
+		require.NoError(t, err)
+		require.NoError(t, s.Close()) // снимок сохранён, журнал очищен
+
+		// возвращаем журнал, как будто очистка не успела выполниться
+		require.NoError(t, os.WriteFile(journal(dir), data, 0o644))
+
+		s2 := newFileStorage(t, dir, 100)
+		events, err := s2.GetForDay(1, date)
+		require.NoError(t, err)
+		assert.Len(t, events, 2, "записи, учтённые в снимке, не должны применяться повторно")
+		id, err := s2.Create(1, date, "Event", "")
+		require.NoError(t, err)
+		assert.Equal(t, 3, id)
+	})
+}
 
-	// запускаем конкурентное создание событий
-	t.Logf("Запуск %d горутин для конкурентного создания событий", goroutinesCount)
-	for i := 0; i < goroutinesCount; i++ {
-		go func(id int) {
-			defer wg.Done()
-			// каждая горутина создает свое событие
-			_, err := s.Create(1, date, fmt.Sprintf("Concurrent Event %d", id), "")
-			if err != nil {
-				errors <- err
-			}
-		}(i)
+// TestFromEnv проверяет выбор хранилища по переменным окружения
+func TestFromEnv(t *testing.T) {
+
+	dir := t.TempDir()
+	tests := []struct {
+		kind, data string
+		want       interface{}
+		wantErr    bool
+	}{
+		{kind: "", want: &storage.Storage{}},
+		{kind: "memory", want: &storage.Storage{}},
+		{kind: "indexed", want: &storage.IndexedStorage{}},
+		{kind: "file", data: filepath.Join(dir, "data"), want: &storage.FileStorage{}},
+		{kind: "sqlite", data: filepath.Join(dir, "calendar.db"), want: &storage.SQLiteStorage{}},
+		{kind: "redis", wantErr: true},
 	}
 
-	// ожидаем завершения всех горутин
-	wg.Wait()
-	close(errors)
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			t.Setenv("CALENDAR_STORAGE", tt.kind)
+			t.Setenv("CALENDAR_DATA", tt.data)
 
-	// проверяем, что не было ошибок при создании
-	for err := range errors {
-		assert.NoError(t, err, "Горутина вернула ошибку при создании")
+			s, err := storage.FromEnv()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, tt.want, s)
+			if closer, ok := s.(interface{ Close() error }); ok {
+				assert.NoError(t, closer.Close())
+			}
+		})
 	}
+}
+
+// splitLines делит журнал на строки, сохраняя переводы строк
+func splitLines(data []byte) [][]byte {
 
-	// проверяем, что все 100 событий действительно создались
-	events, err := s.GetForDay(1, date)
-	assert.NoError(t, err, "Не удалось получить список событий")
-	assert.Len(t, events, goroutinesCount, "Должно быть создано ровно %d событий, но создано %d", goroutinesCount, len(events))
-
-	// проверка, что ID событий уникальные
-	// (это косвенно проверяет, что счетчик NextID увеличивается атомарно)
-	ids := make(map[int]bool)
-	for _, event := range events {
-		assert.False(t, ids[event.ID], "Обнаружен дубликат ID: %d", event.ID)
-		ids[event.ID] = true
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
 	}
+
+	return lines
+}
+
+func joinLines(lines [][]byte) []byte {
+	return bytes.Join(lines, nil)
+}
+
+func countLines(data []byte) int {
+	return len(splitLines(data))
 }
@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IPampurin/calendar-server/pkg/web"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWebHandler проверяет, что встроенный интерфейс отдаётся сервером
+func TestWebHandler(t *testing.T) {
+
+	h := web.Handler()
+
+	for _, tc := range []struct {
+		path, contentType, contains string
+	}{
+		{"/", "text/html", `<script src="app.js">`},
+		{"/app.js", "javascript", "/events_for_month"},
+		{"/style.css", "text/css", ".grid"},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Equal(t, http.StatusOK, w.Code, tc.path)
+		assert.Contains(t, w.Header().Get("Content-Type"), tc.contentType, tc.path)
+		assert.Contains(t, w.Body.String(), tc.contains, tc.path)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing.js", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,41 +1,264 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log"
+	"net/netip"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
-	"github.com/beevik/ntp"
+	"l2.8/pkg/ntpcheck"
+	"l2.8/pkg/sntp"
 )
 
+// коды завершения: программа пригодна как проверка для систем мониторинга
+const (
+	exitOK    = 0 // часы в пределах допуска
+	exitError = 1 // ошибка: неверные флаги, нет ответов или согласия серверов
+	exitDrift = 2 // смещение локальных часов больше допустимого
+)
+
+// defaultServers - серверы по умолчанию (рекомендация пакета ntp)
+const defaultServers = "0.beevik-ntp.pool.ntp.org,1.beevik-ntp.pool.ntp.org,2.beevik-ntp.pool.ntp.org,3.beevik-ntp.pool.ntp.org"
+
+// config - параметры запуска
+type config struct {
+	servers   []string
+	timeout   time.Duration
+	maxOffset time.Duration
+	quiet     bool
+}
+
+// days - мапа для перевода дней недели
+var days = map[time.Weekday]string{
+	time.Monday:    "понедельник",
+	time.Tuesday:   "вторник",
+	time.Wednesday: "среда",
+	time.Thursday:  "четверг",
+	time.Friday:    "пятница",
+	time.Saturday:  "суббота",
+	time.Sunday:    "воскресенье",
+}
+
 func main() {
 
-	// выполняем запрос к серверу согласно рекомендации пакета ntp
-	ntpTime, err := ntp.Time("0.beevik-ntp.pool.ntp.org")
+	// режим сервера работает до Ctrl+C или SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+
+	os.Exit(code)
+}
+
+// parseFlags разбирает аргументы командной строки
+func parseFlags(args []string, stderr io.Writer) (config, error) {
+
+	var cfg config
+	var servers string
+
+	fs := flag.NewFlagSet("l2.8", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&servers, "servers", defaultServers, "NTP-серверы через запятую (host или host:port)")
+	fs.DurationVar(&cfg.timeout, "timeout", 5*time.Second, "ожидание ответа одного сервера")
+	fs.DurationVar(&cfg.maxOffset, "max-offset", time.Second, "допустимое смещение локальных часов, больше - код выхода 2")
+	fs.BoolVar(&cfg.quiet, "q", false, "не выводить таблицу серверов")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("лишние аргументы: %s", strings.Join(fs.Args(), " "))
+	}
+
+	for _, server := range strings.Split(servers, ",") {
+		if server = strings.TrimSpace(server); server != "" {
+			cfg.servers = append(cfg.servers, server)
+		}
+	}
+	if len(cfg.servers) == 0 {
+		return cfg, errors.New("не задано ни одного сервера")
+	}
+	if cfg.timeout <= 0 || cfg.maxOffset <= 0 {
+		return cfg, errors.New("-timeout и -max-offset должны быть положительными")
+	}
+
+	return cfg, nil
+}
+
+// run выполняет команду и возвращает код завершения: "serve ..." - режим сервера SNTP,
+// иначе - опрос серверов
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+
+	if len(args) > 0 && args[0] == "serve" {
+		return serve(ctx, args[1:], stderr)
+	}
+
+	return check(args, stdout, stderr)
+}
+
+// check опрашивает серверы, выводит результат и возвращает код завершения
+func check(args []string, stdout, stderr io.Writer) int {
+
+	cfg, err := parseFlags(args, stderr)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(stderr, "Ошибка: %v\n", err)
+		}
+		return exitError
+	}
+
+	samples := ntpcheck.QueryAll(cfg.servers, ntpcheck.Options{Timeout: cfg.timeout})
+	res, err := ntpcheck.Consensus(samples)
+
+	if !cfg.quiet {
+		printSamples(stdout, samples, res)
+	}
 	// если есть ошибка при получении, выводим ошибку в STDERR и завершаемся с ненулевым кодом
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Ошибка получения времени: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Ошибка получения времени: %v\n", err)
+		return exitError
 	}
 
-	// days - мапа для перевода дней недели
-	days := map[time.Weekday]string{
-		time.Monday:    "понедельник",
-		time.Tuesday:   "вторник",
-		time.Wednesday: "среда",
-		time.Thursday:  "четверг",
-		time.Friday:    "пятница",
-		time.Saturday:  "суббота",
-		time.Sunday:    "воскресенье",
+	ntpTime := time.Now().Add(res.Offset)
+	// выводим время в привычном формате
+	fmt.Fprintf(stdout, "Текущее время (по NTP серверам): %s %s %s\n",
+		ntpTime.Format("02.01.2006"), days[ntpTime.Weekday()], ntpTime.Format("15:04:05"))
+	fmt.Fprintf(stdout, "Смещение локальных часов: %s (разброс %s, серверов %d из %d, основной %s)\n",
+		signed(res.Offset), res.Jitter.Round(time.Microsecond), len(res.Survivors), len(samples), res.SystemPeer.Server)
+
+	if res.Offset.Abs() > cfg.maxOffset {
+		fmt.Fprintf(stderr, "Смещение часов %s больше допустимого %s\n", signed(res.Offset), cfg.maxOffset)
+		return exitDrift
 	}
 
-	// получаем русское название дня недели
-	rusWeekday := days[ntpTime.Weekday()]
+	return exitOK
+}
 
-	// форматы даты и времени
-	datePartFormat := ntpTime.Format("02.01.2006")
-	timePartFormat := ntpTime.Format("15:04:05")
+// parseServeFlags разбирает аргументы режима сервера
+func parseServeFlags(args []string, stderr io.Writer) (sntp.Config, error) {
 
-	// выводим время в привычном формате
-	fmt.Printf("Текущее время (по NTP серверу): %s %s %s\n", datePartFormat, rusWeekday, timePartFormat)
+	var cfg sntp.Config
+	var stratum, leap uint
+	var deny string
+
+	fs := flag.NewFlagSet("l2.8 serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&cfg.Addr, "listen", ":123", "адрес UDP")
+	fs.StringVar(&cfg.Upstream, "upstream", "", "вышестоящий NTP-сервер (host или host:port); пусто - локальные часы")
+	fs.DurationVar(&cfg.Poll, "poll", 64*time.Second, "интервал опроса вышестоящего сервера")
+	fs.UintVar(&stratum, "stratum", 0, "страта в ответах, 0 - автоматически (локальные часы - 10, иначе страта upstream + 1)")
+	fs.UintVar(&leap, "leap", sntp.LeapNone, "признак секунды координации: 0 - нет, 1 - +1 с, 2 - -1 с, 3 - часы не синхронизированы")
+	fs.Float64Var(&cfg.Rate, "rate", 0, "запросов в секунду с одного IP, сверх - Kiss-o'-Death RATE; 0 - без ограничения")
+	fs.IntVar(&cfg.Burst, "burst", 4, "запас запросов сверх -rate")
+	fs.StringVar(&deny, "deny", "", "сети через запятую (CIDR), которым отвечать Kiss-o'-Death DENY")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("лишние аргументы: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if stratum >= sntp.MaxStratum || leap > sntp.LeapNotInSync {
+		return cfg, fmt.Errorf("-stratum должна быть от 0 до %d, -leap - от 0 до 3", sntp.MaxStratum-1)
+	}
+	cfg.Stratum, cfg.Leap = uint8(stratum), uint8(leap)
+	if cfg.Poll <= 0 {
+		return cfg, errors.New("-poll должен быть положительным")
+	}
+
+	for _, cidr := range strings.Split(deny, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return cfg, fmt.Errorf("-deny: %w", err)
+		}
+		cfg.Deny = append(cfg.Deny, prefix)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// serve отвечает на запросы SNTP до отмены ctx
+func serve(ctx context.Context, args []string, stderr io.Writer) int {
+
+	cfg, err := parseServeFlags(args, stderr)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(stderr, "Ошибка: %v\n", err)
+		}
+		return exitError
+	}
+
+	logger := log.New(stderr, "", log.LstdFlags)
+	srv, err := sntp.Listen(cfg, logger)
+	if err != nil {
+		fmt.Fprintf(stderr, "Ошибка запуска сервера: %v\n", err)
+		return exitError
+	}
+
+	source := "локальные часы"
+	if cfg.Upstream != "" {
+		source = cfg.Upstream
+	}
+	logger.Printf("SNTP-сервер слушает %s, источник времени: %s\n", srv.Addr(), source)
+
+	if err := srv.Serve(ctx); err != nil {
+		fmt.Fprintf(stderr, "Ошибка сервера: %v\n", err)
+		return exitError
+	}
+	logger.Println("SNTP-сервер остановлен")
+
+	return exitOK
+}
+
+// printSamples выводит таблицу ответов серверов с ролью каждого в выборе
+func printSamples(w io.Writer, samples []ntpcheck.Sample, res ntpcheck.Result) {
+
+	role := make(map[string]string)
+	for _, s := range res.Survivors {
+		role[s.Server] = "выбран"
+	}
+	role[res.SystemPeer.Server] = "основной"
+	for _, s := range res.Outliers {
+		role[s.Server] = "выброс"
+	}
+	for _, s := range res.Falsetickers {
+		role[s.Server] = "лжец"
+	}
+	for _, s := range res.Rejected {
+		role[s.Server] = "отброшен"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "СЕРВЕР\tСМЕЩЕНИЕ\tЗАДЕРЖКА\tСТРАТА\tROOT DELAY\tROOT DISP\tВЫБОР")
+	for _, s := range samples {
+		if s.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t-\tошибка: %v\n", s.Server, s.Err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", s.Server, signed(s.Offset),
+			s.Delay.Round(time.Microsecond), s.Stratum, s.RootDelay.Round(time.Microsecond),
+			s.RootDispersion.Round(time.Microsecond), role[s.Server])
+	}
+	_ = tw.Flush()
+}
+
+// signed форматирует смещение со знаком, с точностью до микросекунды
+func signed(d time.Duration) string {
+
+	d = d.Round(time.Microsecond)
+	if d >= 0 {
+		return "+" + d.String()
+	}
+
+	return d.String()
 }
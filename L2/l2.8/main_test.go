@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"l2.8/pkg/ntpcheck/ntptest"
+)
+
+func TestRun(t *testing.T) {
+
+	honest := func(offset time.Duration) string {
+		return ntptest.Start(t, ntptest.Server{Offset: offset})
+	}
+	liar := ntptest.Start(t, ntptest.Server{Offset: time.Hour})
+	agreed := strings.Join([]string{honest(50 * time.Millisecond), honest(52 * time.Millisecond), honest(48 * time.Millisecond), liar}, ",")
+	drifted := strings.Join([]string{honest(3 * time.Second), honest(3 * time.Second), honest(3 * time.Second)}, ",")
+
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout []string
+		stderr string
+	}{
+		{"в допуске, лжец отброшен", []string{"-servers", agreed, "-max-offset", "500ms"}, exitOK,
+			[]string{"Текущее время (по NTP серверам):", "серверов 3 из 4", "лжец", "основной"}, ""},
+		{"смещение больше допуска", []string{"-servers", drifted, "-max-offset", "1s", "-q"}, exitDrift,
+			[]string{"Смещение локальных часов: +3"}, "больше допустимого 1s"},
+		{"нет ответов", []string{"-servers", ntptest.Start(t, ntptest.Server{Silent: true}), "-timeout", "200ms"}, exitError,
+			[]string{"ошибка:"}, "нет пригодных ответов"},
+		{"пустой список", []string{"-servers", " , "}, exitError, nil, "не задано ни одного сервера"},
+		{"лишний аргумент", []string{"pool.ntp.org"}, exitError, nil, "лишние аргументы"},
+		{"неизвестный флаг", []string{"-x"}, exitError, nil, "flag provided but not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(context.Background(), tt.args, &stdout, &stderr); code != tt.code {
+				t.Errorf("code = %d, want %d\nstdout: %s\nstderr: %s", code, tt.code, stdout.String(), stderr.String())
+			}
+			for _, want := range tt.stdout {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("stdout не содержит %q:\n%s", want, stdout.String())
+				}
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("stderr не содержит %q:\n%s", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+// TestServeAndCheck запускает программу в режиме сервера и проверяет её же клиентом
+func TestServeAndCheck(t *testing.T) {
+
+	// свободный порт: занимаем и сразу освобождаем
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := conn.LocalAddr().String()
+	_ = conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var serveLog bytes.Buffer
+	done := make(chan int)
+	go func() { done <- run(ctx, []string{"serve", "-listen", addr, "-stratum", "3"}, io.Discard, &serveLog) }()
+
+	var stdout, stderr bytes.Buffer
+	code := exitError
+	for deadline := time.Now().Add(3 * time.Second); code != exitOK && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+		stdout.Reset()
+		stderr.Reset()
+		code = run(context.Background(), []string{"-servers", addr, "-timeout", "200ms", "-max-offset", "100ms"}, &stdout, &stderr)
+	}
+	if code != exitOK {
+		t.Errorf("code = %d, want %d\nstdout: %s\nstderr: %s", code, exitOK, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), addr) || !strings.Contains(stdout.String(), "основной") {
+		t.Errorf("stdout:\n%s", stdout.String())
+	}
+
+	cancel()
+	if code := <-done; code != exitOK {
+		t.Errorf("serve: code = %d, want %d\n%s", code, exitOK, serveLog.String())
+	}
+	if !strings.Contains(serveLog.String(), "SNTP-сервер слушает "+addr) {
+		t.Errorf("лог сервера:\n%s", serveLog.String())
+	}
+}
+
+func TestServeFlags(t *testing.T) {
+
+	for _, args := range [][]string{
+		{"serve", "-stratum", "16"},
+		{"serve", "-leap", "4"},
+		{"serve", "-deny", "10.0.0.0/33"},
+		{"serve", "-poll", "0s"},
+		{"serve", "extra"},
+	} {
+		var stderr bytes.Buffer
+		if code := run(context.Background(), args, io.Discard, &stderr); code != exitError {
+			t.Errorf("%v: code = %d, want %d", args, code, exitError)
+		}
+		if !strings.Contains(stderr.String(), "Ошибка") {
+			t.Errorf("%v: stderr = %q", args, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package ntpcheck
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"l2.8/pkg/ntpcheck/ntptest"
+)
+
+func ms(n float64) time.Duration { return time.Duration(n * float64(time.Millisecond)) }
+
+func sample(server string, offset time.Duration) Sample {
+	return Sample{Server: server, Offset: offset, Stratum: 2, RootDistance: ms(20), Delay: ms(1), Precision: time.Microsecond}
+}
+
+func servers(samples []Sample) []string {
+
+	var names []string
+	for _, s := range samples {
+		names = append(names, s.Server)
+	}
+
+	return names
+}
+
+func TestConsensus(t *testing.T) {
+
+	t.Run("согласные серверы", func(t *testing.T) {
+		res, err := Consensus([]Sample{sample("a", ms(100)), sample("b", ms(102)), sample("c", ms(98))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Offset != ms(100) {
+			t.Errorf("Offset = %v, want 100ms", res.Offset)
+		}
+		if len(res.Survivors) != 3 || len(res.Falsetickers) != 0 {
+			t.Errorf("Survivors = %v, Falsetickers = %v", servers(res.Survivors), servers(res.Falsetickers))
+		}
+	})
+
+	t.Run("лжец в меньшинстве", func(t *testing.T) {
+		res, err := Consensus([]Sample{sample("a", ms(100)), sample("liar", 5*time.Second), sample("b", ms(105)), sample("c", ms(95))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := servers(res.Falsetickers); len(got) != 1 || got[0] != "liar" {
+			t.Errorf("Falsetickers = %v, want [liar]", got)
+		}
+		if res.Offset < ms(95) || res.Offset > ms(105) {
+			t.Errorf("Offset = %v, want около 100ms", res.Offset)
+		}
+	})
+
+	t.Run("нет большинства", func(t *testing.T) {
+		_, err := Consensus([]Sample{sample("a", 0), sample("b", time.Second), sample("c", 2*time.Second), sample("d", 3*time.Second)})
+		if !errors.Is(err, ErrNoMajority) {
+			t.Errorf("err = %v, want ErrNoMajority", err)
+		}
+	})
+
+	t.Run("два сервера против двух", func(t *testing.T) {
+		_, err := Consensus([]Sample{sample("a", 0), sample("b", ms(1)), sample("c", time.Second), sample("d", time.Second)})
+		if !errors.Is(err, ErrNoMajority) {
+			t.Errorf("err = %v, want ErrNoMajority", err)
+		}
+	})
+
+	t.Run("нет пригодных ответов", func(t *testing.T) {
+		far := sample("far", 0)
+		far.RootDistance = 2 * time.Second
+		res, err := Consensus([]Sample{{Server: "down", Err: errors.New("timeout")}, far})
+		if !errors.Is(err, ErrNoCandidates) {
+			t.Errorf("err = %v, want ErrNoCandidates", err)
+		}
+		if len(res.Rejected) != 2 {
+			t.Errorf("Rejected = %v", servers(res.Rejected))
+		}
+	})
+
+	t.Run("один сервер", func(t *testing.T) {
+		res, err := Consensus([]Sample{sample("a", ms(-7))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Offset != ms(-7) || res.SystemPeer.Server != "a" {
+			t.Errorf("Offset = %v, SystemPeer = %s", res.Offset, res.SystemPeer.Server)
+		}
+	})
+
+	t.Run("кластеризация и выбор основного", func(t *testing.T) {
+		// интервалы широкие, и все пересекаются, но d заметно дальше остальных
+		wide := func(server string, offset time.Duration) Sample {
+			s := sample(server, offset)
+			s.RootDistance = ms(50)
+			return s
+		}
+		best := wide("best", ms(100))
+		best.Stratum = 1
+		res, err := Consensus([]Sample{wide("a", ms(101)), wide("d", ms(125)), best, wide("b", ms(99))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.SystemPeer.Server != "best" {
+			t.Errorf("SystemPeer = %s, want best (страта 1)", res.SystemPeer.Server)
+		}
+		if got := servers(res.Outliers); len(got) != 1 || got[0] != "d" {
+			t.Errorf("Outliers = %v, want [d]", got)
+		}
+		if len(res.Survivors) != minClusterSurvivors {
+			t.Errorf("Survivors = %v", servers(res.Survivors))
+		}
+	})
+
+	t.Run("вес по оценке ошибки", func(t *testing.T) {
+		near, far := sample("near", 0), sample("far", ms(8))
+		near.RootDistance, far.RootDistance = ms(10), ms(40)
+		res, err := Consensus([]Sample{near, far})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// веса 1/10 и 1/40: (0*4 + 8*1) / 5 = 1.6ms
+		if d := res.Offset - ms(1.6); d.Abs() > time.Microsecond {
+			t.Errorf("Offset = %v, want 1.6ms", res.Offset)
+		}
+		if len(res.Falsetickers) != 0 {
+			t.Errorf("Falsetickers = %v", servers(res.Falsetickers))
+		}
+	})
+}
+
+func TestQueryAll(t *testing.T) {
+
+	addrs := []string{
+		ntptest.Start(t, ntptest.Server{Offset: ms(250), RootDelay: ms(2), RootDispersion: ms(3)}),
+		ntptest.Start(t, ntptest.Server{Offset: ms(250), Stratum: 1}),
+		ntptest.Start(t, ntptest.Server{KissCode: "RATE"}),
+		ntptest.Start(t, ntptest.Server{Leap: 3}),
+		ntptest.Start(t, ntptest.Server{Silent: true}),
+	}
+	samples := QueryAll(addrs, Options{Timeout: 300 * time.Millisecond})
+
+	if len(samples) != len(addrs) {
+		t.Fatalf("len = %d", len(samples))
+	}
+	for i, s := range samples {
+		if s.Server != addrs[i] {
+			t.Errorf("samples[%d].Server = %s, want %s", i, s.Server, addrs[i])
+		}
+	}
+
+	good := samples[0]
+	if good.Err != nil {
+		t.Fatal(good.Err)
+	}
+	if d := good.Offset - ms(250); d.Abs() > ms(20) {
+		t.Errorf("Offset = %v, want около 250ms", good.Offset)
+	}
+	if good.Stratum != 2 || good.RootDelay.Round(time.Millisecond) != ms(2) || good.RootDispersion.Round(time.Millisecond) != ms(3) {
+		t.Errorf("Stratum = %d, RootDelay = %v, RootDispersion = %v", good.Stratum, good.RootDelay, good.RootDispersion)
+	}
+	if samples[1].Err != nil || samples[1].Stratum != 1 {
+		t.Errorf("страта 1: %+v", samples[1])
+	}
+
+	var kod *KissOfDeathError
+	if !errors.As(samples[2].Err, &kod) || kod.Code != "RATE" {
+		t.Errorf("Kiss-o'-Death: err = %v", samples[2].Err)
+	}
+	if samples[3].Err == nil {
+		t.Error("ответ с несинхронизированными часами принят")
+	}
+	if samples[4].Err == nil {
+		t.Error("молчащий сервер без ошибки")
+	}
+
+	res, err := Consensus(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Survivors) != 2 || len(res.Rejected) != 3 {
+		t.Errorf("Survivors = %v, Rejected = %v", servers(res.Survivors), servers(res.Rejected))
+	}
+	if d := res.Offset - ms(250); d.Abs() > ms(20) {
+		t.Errorf("Offset = %v, want около 250ms", res.Offset)
+	}
+}
@@ -0,0 +1,91 @@
+// Package ntptest - NTP-сервер-заглушка на локальном UDP-порту для тестов клиента:
+// отвечает по часам с заданным смещением и заданными полями заголовка
+package ntptest
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"l2.8/pkg/sntp"
+)
+
+// Server описывает ответы заглушки
+type Server struct {
+	Offset         time.Duration // на сколько часы сервера спешат относительно локальных
+	Stratum        uint8         // 0 - 2
+	Leap           uint8         // признак секунды координации, 3 - часы не синхронизированы
+	RootDelay      time.Duration
+	RootDispersion time.Duration
+	Precision      int8          // log2 секунд, 0 - -20 (около микросекунды)
+	KissCode       string        // непустой - ответ Kiss-o'-Death с этим кодом (RATE, DENY...)
+	ReplyDelay     time.Duration // пауза перед ответом
+	Silent         bool          // не отвечать вовсе
+}
+
+// Start запускает заглушку и возвращает её адрес "127.0.0.1:порт"; останавливается по завершении теста
+func Start(tb testing.TB, srv Server) string {
+
+	tb.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("заглушка NTP: %v", err)
+	}
+	tb.Cleanup(func() { _ = conn.Close() })
+
+	go srv.serve(conn)
+
+	return conn.LocalAddr().String()
+}
+
+func (srv Server) serve(conn net.PacketConn) {
+
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return // соединение закрыто
+		}
+		req, err := sntp.ParsePacket(buf[:n])
+		if err != nil || srv.Silent {
+			continue
+		}
+		received := time.Now().Add(srv.Offset)
+
+		time.Sleep(srv.ReplyDelay)
+		_, _ = conn.WriteTo(srv.reply(req, received), addr)
+	}
+}
+
+// reply собирает ответ (режим 4) на запрос
+func (srv Server) reply(req sntp.Packet, received time.Time) []byte {
+
+	resp := sntp.Packet{
+		Leap:           srv.Leap,
+		Version:        req.Version,
+		Mode:           sntp.ModeServer,
+		Stratum:        srv.Stratum,
+		Poll:           6, // опрос раз в 64 секунды
+		Precision:      srv.Precision,
+		RootDelay:      srv.RootDelay,
+		RootDispersion: srv.RootDispersion,
+		ReferenceID:    [4]byte{127, 0, 0, 1},
+		ReferenceTime:  sntp.NewTimestamp(received.Add(-time.Minute)),
+		OriginTime:     req.TransmitTime, // время отправки из запроса
+		ReceiveTime:    sntp.NewTimestamp(received),
+	}
+	if resp.Stratum == 0 && srv.KissCode == "" {
+		resp.Stratum = 2
+	}
+	if resp.Precision == 0 {
+		resp.Precision = -20
+	}
+	if srv.KissCode != "" {
+		resp.ReferenceID = [4]byte{}
+		copy(resp.ReferenceID[:], srv.KissCode)
+	}
+	resp.TransmitTime = sntp.NewTimestamp(time.Now().Add(srv.Offset))
+
+	return resp.Marshal()
+}
@@ -0,0 +1,88 @@
+// Package ntpcheck опрашивает несколько NTP-серверов и вычисляет согласованное смещение
+// локальных часов по упрощённому алгоритму выбора и кластеризации NTP (RFC 5905, раздел 11.2)
+package ntpcheck
+
+import (
+	"sync"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// Sample - результат опроса одного сервера
+type Sample struct {
+	Server         string
+	Offset         time.Duration // смещение локальных часов относительно сервера (+ - локальные отстают)
+	Delay          time.Duration // задержка запроса туда и обратно
+	Stratum        uint8
+	RootDelay      time.Duration
+	RootDispersion time.Duration
+	RootDistance   time.Duration // оценка максимальной ошибки времени сервера
+	Precision      time.Duration
+	Leap           ntp.LeapIndicator
+	Err            error // ошибка запроса или ответ, непригодный для синхронизации
+}
+
+// Options - параметры опроса
+type Options struct {
+	Timeout time.Duration // ожидание ответа одного сервера, 0 - 5 секунд
+	Version int           // версия протокола, 0 - 4
+}
+
+// QueryAll опрашивает серверы одновременно; результаты возвращаются в порядке servers
+func QueryAll(servers []string, opt Options) []Sample {
+
+	samples := make([]Sample, len(servers))
+
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			samples[i] = Query(server, opt)
+		}()
+	}
+	wg.Wait()
+
+	return samples
+}
+
+// Query опрашивает один сервер; ответ проверяется на пригодность для синхронизации
+// (Kiss-o'-Death, страта, признак несинхронизированных часов, дисперсия)
+func Query(server string, opt Options) Sample {
+
+	sample := Sample{Server: server}
+
+	resp, err := ntp.QueryWithOptions(server, ntp.QueryOptions{Timeout: opt.Timeout, Version: opt.Version})
+	if err != nil {
+		sample.Err = err
+		return sample
+	}
+
+	sample.Offset = resp.ClockOffset
+	sample.Delay = resp.RTT
+	sample.Stratum = resp.Stratum
+	sample.RootDelay = resp.RootDelay
+	sample.RootDispersion = resp.RootDispersion
+	sample.RootDistance = resp.RootDistance
+	sample.Precision = resp.Precision
+	sample.Leap = resp.Leap
+
+	if err := resp.Validate(); err != nil {
+		if resp.IsKissOfDeath() {
+			err = &KissOfDeathError{Code: resp.KissCode}
+		}
+		sample.Err = err
+	}
+
+	return sample
+}
+
+// KissOfDeathError - сервер отказал в обслуживании (страта 0, код в ReferenceID)
+type KissOfDeathError struct {
+	Code string // RATE, DENY, RSTR...
+}
+
+func (e *KissOfDeathError) Error() string {
+	return "сервер ответил Kiss-o'-Death " + e.Code
+}
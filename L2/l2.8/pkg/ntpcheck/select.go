@@ -0,0 +1,216 @@
+package ntpcheck
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	maxDistance         = 1500 * time.Millisecond // MAXDIST: серверы с большей оценкой ошибки не участвуют
+	minDispersion       = 10 * time.Millisecond   // MINDISP: нижняя граница интервала сервера
+	minClusterSurvivors = 3                       // NMIN: кластеризация не оставляет меньше серверов
+)
+
+var (
+	// ErrNoCandidates - ни один сервер не дал пригодного ответа
+	ErrNoCandidates = errors.New("нет пригодных ответов серверов")
+	// ErrNoMajority - интервалы большинства серверов не пересекаются
+	ErrNoMajority = errors.New("серверы не согласны между собой: нет большинства")
+)
+
+// Result - согласованное смещение и разбор серверов
+type Result struct {
+	Offset       time.Duration // смещение локальных часов (взвешенное среднее выживших)
+	Jitter       time.Duration // разброс смещений выживших относительно системного пира
+	SystemPeer   Sample        // лучший из выживших
+	Survivors    []Sample      // серверы, по которым вычислено смещение
+	Falsetickers []Sample      // не попали в пересечение интервалов большинства
+	Outliers     []Sample      // честные, но отброшены кластеризацией
+	Rejected     []Sample      // ошибка запроса или непригодный ответ
+}
+
+// endpoint - граница или середина интервала сервера для алгоритма пересечения
+type endpoint struct {
+	value time.Duration
+	kind  int // -1 нижняя граница, 0 середина, +1 верхняя
+}
+
+// distance - оценка ошибки сервера, не меньше minDispersion
+func distance(s Sample) time.Duration {
+	return max(s.RootDistance, minDispersion)
+}
+
+// merit - порядок предпочтения серверов: меньшая страта, затем меньшая оценка ошибки
+func merit(s Sample) time.Duration {
+	return time.Duration(s.Stratum)*maxDistance + distance(s)
+}
+
+// Consensus выбирает честные серверы и вычисляет по ним смещение локальных часов:
+//   - отбор: без ошибок, оценка ошибки не больше maxDistance;
+//   - выбор (алгоритм пересечения Марзулло): каждый сервер задаёт интервал offset ± distance,
+//     ищется наименьшее число допустимых лжецов f < n/2, при котором n-f интервалов пересекаются;
+//     серверы, чьё смещение вне пересечения, - лжецы;
+//   - кластеризация: пока серверов больше minClusterSurvivors, отбрасывается сервер
+//     с наибольшим разбросом относительно остальных, если этот разброс больше собственного
+//     шума лучшего сервера (оценивается как max(точность, задержка/2) - по одному ответу
+//     настоящий джиттер не вычислить);
+//   - объединение: среднее смещений с весами 1/distance.
+func Consensus(samples []Sample) (Result, error) {
+
+	var res Result
+
+	var candidates []Sample
+	for _, s := range samples {
+		if s.Err != nil || s.RootDistance > maxDistance {
+			res.Rejected = append(res.Rejected, s)
+			continue
+		}
+		candidates = append(candidates, s)
+	}
+	if len(candidates) == 0 {
+		return res, ErrNoCandidates
+	}
+
+	low, high, ok := intersection(candidates)
+	if !ok {
+		res.Falsetickers = candidates
+		return res, ErrNoMajority
+	}
+	var survivors []Sample
+	for _, s := range candidates {
+		if s.Offset < low || s.Offset > high {
+			res.Falsetickers = append(res.Falsetickers, s)
+			continue
+		}
+		survivors = append(survivors, s)
+	}
+
+	slices.SortStableFunc(survivors, func(a, b Sample) int {
+		return cmp.Compare(merit(a), merit(b))
+	})
+	survivors, res.Outliers = cluster(survivors)
+
+	res.Survivors = survivors
+	res.SystemPeer = survivors[0]
+	res.Offset, res.Jitter = combine(survivors)
+
+	return res, nil
+}
+
+// intersection находит пересечение [low, high] интервалов большинства серверов
+func intersection(candidates []Sample) (time.Duration, time.Duration, bool) {
+
+	n := len(candidates)
+	points := make([]endpoint, 0, 3*n)
+	for _, s := range candidates {
+		d := distance(s)
+		points = append(points,
+			endpoint{s.Offset - d, -1},
+			endpoint{s.Offset, 0},
+			endpoint{s.Offset + d, +1})
+	}
+	// при равных значениях нижние границы идут раньше верхних: касающиеся интервалы пересекаются
+	slices.SortFunc(points, func(a, b endpoint) int {
+		return cmp.Or(cmp.Compare(a.value, b.value), cmp.Compare(a.kind, b.kind))
+	})
+
+	for allow := 0; 2*allow < n; allow++ {
+		var low, high time.Duration
+		found, chime := 0, 0
+
+		for _, p := range points {
+			chime -= p.kind
+			if chime >= n-allow {
+				low = p.value
+				break
+			}
+			if p.kind == 0 {
+				found++
+			}
+		}
+
+		chime = 0
+		for i := len(points) - 1; i >= 0; i-- {
+			p := points[i]
+			chime += p.kind
+			if chime >= n-allow {
+				high = p.value
+				break
+			}
+			if p.kind == 0 {
+				found++
+			}
+		}
+
+		// середины вне пересечения - серверы, которых пришлось признать лжецами; их не больше allow
+		if found > allow || low > high {
+			continue
+		}
+
+		return low, high, true
+	}
+
+	return 0, 0, false
+}
+
+// cluster отбрасывает выбросы среди выживших (отсортированы по merit)
+func cluster(survivors []Sample) ([]Sample, []Sample) {
+
+	var outliers []Sample
+
+	for len(survivors) > minClusterSurvivors {
+		worst, worstJitter := 0, time.Duration(0)
+		for i := range survivors {
+			if j := selectionJitter(survivors, i); j > worstJitter {
+				worst, worstJitter = i, j
+			}
+		}
+
+		best := survivors[0]
+		if worstJitter <= max(best.Precision, best.Delay/2) {
+			break
+		}
+		outliers = append(outliers, survivors[worst])
+		survivors = slices.Delete(slices.Clone(survivors), worst, worst+1)
+	}
+
+	return survivors, outliers
+}
+
+// selectionJitter - среднеквадратичное отклонение смещений остальных серверов от смещения i-го
+func selectionJitter(survivors []Sample, i int) time.Duration {
+
+	var sum float64
+	for j, s := range survivors {
+		if j == i {
+			continue
+		}
+		d := (s.Offset - survivors[i].Offset).Seconds()
+		sum += d * d
+	}
+
+	return seconds(math.Sqrt(sum / float64(len(survivors)-1)))
+}
+
+// combine вычисляет взвешенное среднее смещений и разброс относительно системного пира
+func combine(survivors []Sample) (time.Duration, time.Duration) {
+
+	var weights, offset, jitter float64
+	peer := survivors[0].Offset
+	for _, s := range survivors {
+		w := 1 / distance(s).Seconds()
+		weights += w
+		offset += w * s.Offset.Seconds()
+		d := (s.Offset - peer).Seconds()
+		jitter += w * d * d
+	}
+
+	return seconds(offset / weights), seconds(math.Sqrt(jitter / weights))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,139 @@
+// Package sntp - сервер SNTPv4 (RFC 4330): отвечает на запросы клиентов по локальным часам
+// или по часам опрашиваемого вышестоящего сервера, ограничивает частоту запросов клиентов
+// и отказывает ответами Kiss-o'-Death
+package sntp
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// PacketSize - размер заголовка NTP без расширений и аутентификации
+const PacketSize = 48
+
+// режимы ассоциации
+const (
+	ModeClient = 3
+	ModeServer = 4
+)
+
+// признаки секунды координации
+const (
+	LeapNone      = 0
+	LeapAddSec    = 1 // последняя минута суток длится 61 секунду
+	LeapDelSec    = 2 // последняя минута суток длится 59 секунд
+	LeapNotInSync = 3 // часы не синхронизированы
+)
+
+// MaxStratum - страта несинхронизированного сервера
+const MaxStratum = 16
+
+// ntpEpochOffset - секунд от 1900-01-01 (эпоха NTP) до 1970-01-01
+const ntpEpochOffset = 2208988800
+
+// ErrShortPacket - пакет короче заголовка NTP
+var ErrShortPacket = errors.New("пакет короче 48 байт")
+
+// Timestamp - 64-битная метка времени NTP: секунды от 1900 года и доли секунды
+type Timestamp uint64
+
+// NewTimestamp переводит время в метку NTP
+func NewTimestamp(t time.Time) Timestamp {
+
+	sec := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+
+	return Timestamp(sec<<32 | frac)
+}
+
+// Time переводит метку NTP во время (эра 0, до 2036 года, и эра 1 после)
+func (ts Timestamp) Time() time.Time {
+
+	sec := int64(ts >> 32)
+	if sec < 1<<31 {
+		sec += 1 << 32 // после 7 февраля 2036 года счётчик секунд переполняется
+	}
+	nsec := (uint64(ts&0xffffffff)*uint64(time.Second) + 1<<31) >> 32
+
+	return time.Unix(sec-ntpEpochOffset, int64(nsec))
+}
+
+// Packet - заголовок NTP
+type Packet struct {
+	Leap           uint8
+	Version        uint8
+	Mode           uint8
+	Stratum        uint8
+	Poll           int8 // log2 секунд
+	Precision      int8 // log2 секунд
+	RootDelay      time.Duration
+	RootDispersion time.Duration
+	ReferenceID    [4]byte // у страты 0 - код Kiss-o'-Death
+	ReferenceTime  Timestamp
+	OriginTime     Timestamp
+	ReceiveTime    Timestamp
+	TransmitTime   Timestamp
+}
+
+// ParsePacket разбирает заголовок; расширения и MAC после него не учитываются
+func ParsePacket(b []byte) (Packet, error) {
+
+	if len(b) < PacketSize {
+		return Packet{}, ErrShortPacket
+	}
+
+	p := Packet{
+		Leap:           b[0] >> 6,
+		Version:        b[0] >> 3 & 0x7,
+		Mode:           b[0] & 0x7,
+		Stratum:        b[1],
+		Poll:           int8(b[2]),
+		Precision:      int8(b[3]),
+		RootDelay:      fromShort(binary.BigEndian.Uint32(b[4:])),
+		RootDispersion: fromShort(binary.BigEndian.Uint32(b[8:])),
+		ReferenceTime:  Timestamp(binary.BigEndian.Uint64(b[16:])),
+		OriginTime:     Timestamp(binary.BigEndian.Uint64(b[24:])),
+		ReceiveTime:    Timestamp(binary.BigEndian.Uint64(b[32:])),
+		TransmitTime:   Timestamp(binary.BigEndian.Uint64(b[40:])),
+	}
+	copy(p.ReferenceID[:], b[12:16])
+
+	return p, nil
+}
+
+// Marshal собирает заголовок для отправки
+func (p Packet) Marshal() []byte {
+
+	b := make([]byte, PacketSize)
+	b[0] = p.Leap<<6 | (p.Version&0x7)<<3 | p.Mode&0x7
+	b[1] = p.Stratum
+	b[2] = byte(p.Poll)
+	b[3] = byte(p.Precision)
+	binary.BigEndian.PutUint32(b[4:], toShort(p.RootDelay))
+	binary.BigEndian.PutUint32(b[8:], toShort(p.RootDispersion))
+	copy(b[12:16], p.ReferenceID[:])
+	binary.BigEndian.PutUint64(b[16:], uint64(p.ReferenceTime))
+	binary.BigEndian.PutUint64(b[24:], uint64(p.OriginTime))
+	binary.BigEndian.PutUint64(b[32:], uint64(p.ReceiveTime))
+	binary.BigEndian.PutUint64(b[40:], uint64(p.TransmitTime))
+
+	return b
+}
+
+// toShort переводит интервал в 32-битный формат NTP 16.16 (отрицательный - в 0)
+func toShort(d time.Duration) uint32 {
+
+	if d <= 0 {
+		return 0
+	}
+	if d >= 1<<16*time.Second {
+		return 1<<32 - 1
+	}
+
+	return uint32(uint64(d) << 16 / uint64(time.Second))
+}
+
+func fromShort(v uint32) time.Duration {
+	return time.Duration(uint64(v) * uint64(time.Second) >> 16)
+}
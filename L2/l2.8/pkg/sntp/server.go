@@ -0,0 +1,357 @@
+package sntp
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+const (
+	precision     = -20              // точность часов сервера, log2 секунд (около микросекунды)
+	phi           = 15e-6            // PHI: допустимый дрейф частоты часов, 15 мкс/с (RFC 5905)
+	localStratum  = 10               // страта локальных часов без источника, как у ntpd
+	defaultPoll   = 64 * time.Second // опрос вышестоящего сервера по умолчанию
+	clientIdle    = 10 * time.Minute // клиент без запросов забывается ограничителем
+	upstreamQuery = 5 * time.Second  // ожидание ответа вышестоящего сервера
+	purgeEvery    = time.Minute      // как часто чистить ограничитель
+)
+
+// Config - параметры сервера
+type Config struct {
+	Addr     string        // адрес UDP, по умолчанию ":123"
+	Upstream string        // вышестоящий сервер; пусто - локальные часы
+	Poll     time.Duration // интервал опроса Upstream, 0 - 64 секунды
+
+	Stratum uint8 // 0 - автоматически: 10 для локальных часов, страта Upstream + 1
+	Leap    uint8 // признак секунды координации в ответах (LeapNone...LeapNotInSync)
+
+	Rate  float64        // запросов в секунду с одного IP, 0 - без ограничения
+	Burst int            // запас запросов сверх Rate, 0 - 1
+	Deny  []netip.Prefix // клиенты, которым отвечать Kiss-o'-Death DENY
+}
+
+// Validate проверяет параметры
+func (cfg Config) Validate() error {
+
+	if cfg.Stratum >= MaxStratum {
+		return fmt.Errorf("страта должна быть от 1 до %d (0 - автоматически)", MaxStratum-1)
+	}
+	if cfg.Leap > LeapNotInSync {
+		return errors.New("признак leap должен быть от 0 до 3")
+	}
+	if cfg.Rate < 0 || cfg.Burst < 0 || cfg.Poll < 0 {
+		return errors.New("rate, burst и poll не могут быть отрицательными")
+	}
+
+	return nil
+}
+
+// source - состояние часов, по которым отвечает сервер
+type source struct {
+	offset         time.Duration // поправка к локальным часам
+	stratum        uint8
+	referenceID    [4]byte
+	referenceTime  time.Time // время последней синхронизации
+	rootDelay      time.Duration
+	rootDispersion time.Duration
+	synced         bool
+}
+
+// bucket - ведро токенов одного клиента
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Server - сервер SNTP
+type Server struct {
+	cfg    Config
+	conn   net.PacketConn
+	logger *log.Logger
+
+	mu      sync.Mutex
+	src     source
+	clients map[netip.Addr]*bucket
+	purged  time.Time
+}
+
+// Listen открывает UDP-порт сервера; отвечать сервер начинает в Serve
+func Listen(cfg Config, logger *log.Logger) (*Server, error) {
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = ":123"
+	}
+	if cfg.Poll == 0 {
+		cfg.Poll = defaultPoll
+	}
+	if cfg.Burst == 0 {
+		cfg.Burst = 1
+	}
+
+	conn, err := net.ListenPacket("udp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{cfg: cfg, conn: conn, logger: logger, clients: make(map[netip.Addr]*bucket)}
+	if cfg.Upstream == "" {
+		s.src = source{stratum: localStratum, referenceID: [4]byte{'L', 'O', 'C', 'L'}, synced: true}
+		if cfg.Stratum != 0 {
+			s.src.stratum = cfg.Stratum
+		}
+	}
+
+	return s, nil
+}
+
+// Addr возвращает адрес, на котором слушает сервер
+func (s *Server) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// Serve отвечает на запросы до отмены ctx, затем закрывает порт
+func (s *Server) Serve(ctx context.Context) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	if s.cfg.Upstream != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.pollUpstream(ctx)
+		}()
+	}
+	go func() {
+		<-ctx.Done()
+		_ = s.conn.Close()
+	}()
+
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				wg.Wait()
+				return nil
+			}
+			cancel()
+			wg.Wait()
+			return err
+		}
+		received := time.Now()
+
+		reply, ok := s.reply(buf[:n], addr, received)
+		if !ok {
+			continue
+		}
+		if _, err := s.conn.WriteTo(reply, addr); err != nil && ctx.Err() == nil {
+			s.logger.Printf("Ошибка ответа %s: %v\n", addr, err)
+		}
+	}
+}
+
+// reply собирает ответ на запрос; false - запрос не требует ответа
+func (s *Server) reply(req []byte, addr net.Addr, received time.Time) ([]byte, bool) {
+
+	p, err := ParsePacket(req)
+	// отвечаем только клиентам (режим 3) версий 1-4; остальное молча отбрасываем
+	if err != nil || p.Mode != ModeClient || p.Version < 1 || p.Version > 4 {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := Packet{
+		Leap:        s.cfg.Leap,
+		Version:     p.Version,
+		Mode:        ModeServer,
+		Stratum:     s.src.stratum,
+		Poll:        p.Poll,
+		Precision:   precision,
+		ReferenceID: s.src.referenceID,
+		OriginTime:  p.TransmitTime, // клиент сверит его со своим временем отправки
+		ReceiveTime: NewTimestamp(received.Add(s.src.offset)),
+	}
+
+	ip := clientIP(addr)
+	switch {
+	case s.denied(ip):
+		resp = kissOfDeath(resp, "DENY")
+	case !s.allow(ip, received):
+		resp = kissOfDeath(resp, "RATE")
+	case !s.src.synced:
+		// вышестоящий сервер ещё не ответил: сообщаем клиенту, что часы не синхронизированы
+		resp.Leap = LeapNotInSync
+		resp.Stratum = MaxStratum
+		resp.ReferenceID = [4]byte{'I', 'N', 'I', 'T'}
+	default:
+		if s.cfg.Upstream == "" {
+			// локальные часы считаются только что сверенными
+			resp.ReferenceTime = NewTimestamp(received)
+			break
+		}
+		// ошибка растёт с момента последнего опроса вышестоящего сервера
+		resp.ReferenceTime = NewTimestamp(s.src.referenceTime)
+		resp.RootDelay = s.src.rootDelay
+		resp.RootDispersion = s.src.rootDispersion +
+			time.Duration(phi*float64(received.Add(s.src.offset).Sub(s.src.referenceTime)))
+	}
+
+	resp.TransmitTime = NewTimestamp(time.Now().Add(s.src.offset))
+
+	return resp.Marshal(), true
+}
+
+// kissOfDeath превращает ответ в отказ с кодом code: страта 0, код в ReferenceID
+func kissOfDeath(p Packet, code string) Packet {
+
+	p.Leap = LeapNotInSync
+	p.Stratum = 0
+	p.ReferenceID = [4]byte{}
+	copy(p.ReferenceID[:], code)
+	p.RootDelay, p.RootDispersion = 0, 0
+
+	return p
+}
+
+// clientIP возвращает IP клиента без зоны (IPv4 в виде IPv4)
+func clientIP(addr net.Addr) netip.Addr {
+
+	if udp, ok := addr.(*net.UDPAddr); ok {
+		if ip, ok := netip.AddrFromSlice(udp.IP); ok {
+			return ip.Unmap().WithZone("")
+		}
+	}
+
+	return netip.Addr{}
+}
+
+// denied сообщает, запрещён ли клиент (вызывается под s.mu)
+func (s *Server) denied(ip netip.Addr) bool {
+
+	for _, prefix := range s.cfg.Deny {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allow забирает токен из ведра клиента: Rate токенов в секунду, не больше Burst (вызывается под s.mu)
+func (s *Server) allow(ip netip.Addr, now time.Time) bool {
+
+	if s.cfg.Rate == 0 {
+		return true
+	}
+	if now.Sub(s.purged) > purgeEvery {
+		s.purge(now)
+	}
+
+	b, ok := s.clients[ip]
+	if !ok {
+		b = &bucket{tokens: float64(s.cfg.Burst), last: now}
+		s.clients[ip] = b
+	}
+	b.tokens = min(float64(s.cfg.Burst), b.tokens+now.Sub(b.last).Seconds()*s.cfg.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
+
+// purge забывает клиентов, давно не присылавших запросов (вызывается под s.mu)
+func (s *Server) purge(now time.Time) {
+
+	for ip, b := range s.clients {
+		if now.Sub(b.last) > clientIdle {
+			delete(s.clients, ip)
+		}
+	}
+	s.purged = now
+}
+
+// pollUpstream опрашивает вышестоящий сервер сразу и затем раз в Poll до отмены ctx
+func (s *Server) pollUpstream(ctx context.Context) {
+
+	ticker := time.NewTicker(s.cfg.Poll)
+	defer ticker.Stop()
+
+	for {
+		s.syncUpstream()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncUpstream сверяет часы с вышестоящим сервером; при ошибке остаётся прежнее состояние
+func (s *Server) syncUpstream() {
+
+	resp, err := ntp.QueryWithOptions(s.cfg.Upstream, ntp.QueryOptions{Timeout: upstreamQuery})
+	if err == nil {
+		err = resp.Validate()
+	}
+	if err != nil {
+		s.logger.Printf("Ошибка опроса %s: %v\n", s.cfg.Upstream, err)
+		return
+	}
+
+	stratum := s.cfg.Stratum
+	if stratum == 0 {
+		stratum = min(resp.Stratum+1, MaxStratum-1)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.src = source{
+		offset:         resp.ClockOffset,
+		stratum:        stratum,
+		referenceID:    referenceID(s.cfg.Upstream),
+		referenceTime:  time.Now().Add(resp.ClockOffset),
+		rootDelay:      resp.RootDelay + resp.RTT,
+		rootDispersion: resp.RootDispersion + resp.Precision + resp.MinError,
+		synced:         true,
+	}
+}
+
+// referenceID - идентификатор вышестоящего сервера: IPv4-адрес или первые 4 байта MD5 имени (RFC 5905)
+func referenceID(upstream string) [4]byte {
+
+	host, _, err := net.SplitHostPort(upstream)
+	if err != nil {
+		host = upstream
+	}
+
+	var id [4]byte
+	if ip, err := netip.ParseAddr(host); err == nil && ip.Is4() {
+		id = ip.As4()
+		return id
+	}
+	sum := md5.Sum([]byte(host))
+	copy(id[:], sum[:4])
+
+	return id
+}
@@ -0,0 +1,213 @@
+package sntp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"l2.8/pkg/ntpcheck"
+	"l2.8/pkg/ntpcheck/ntptest"
+	"l2.8/pkg/sntp"
+)
+
+// start запускает сервер на свободном локальном порту до завершения теста
+func start(t *testing.T, cfg sntp.Config) string {
+
+	t.Helper()
+
+	cfg.Addr = "127.0.0.1:0"
+	srv, err := sntp.Listen(cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- srv.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+
+	return srv.Addr().String()
+}
+
+func query(addr string) ntpcheck.Sample {
+	return ntpcheck.Query(addr, ntpcheck.Options{Timeout: time.Second})
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+
+	now := time.Date(2026, 10, 18, 12, 30, 15, 123456789, time.UTC)
+	p := sntp.Packet{
+		Leap: sntp.LeapAddSec, Version: 4, Mode: sntp.ModeServer, Stratum: 3, Poll: 6, Precision: -20,
+		RootDelay: 1500 * time.Microsecond, RootDispersion: 250 * time.Millisecond,
+		ReferenceID: [4]byte{'G', 'P', 'S', 0}, ReferenceTime: sntp.NewTimestamp(now.Add(-time.Minute)),
+		OriginTime: 0x0123456789abcdef, ReceiveTime: sntp.NewTimestamp(now), TransmitTime: sntp.NewTimestamp(now),
+	}
+
+	got, err := sntp.ParsePacket(p.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// короткий формат 16.16 хранит интервалы с точностью около 15 мкс
+	if d := got.RootDelay - p.RootDelay; d.Abs() > 20*time.Microsecond {
+		t.Errorf("RootDelay = %v", got.RootDelay)
+	}
+	if d := got.RootDispersion - p.RootDispersion; d.Abs() > 20*time.Microsecond {
+		t.Errorf("RootDispersion = %v", got.RootDispersion)
+	}
+	got.RootDelay, got.RootDispersion = p.RootDelay, p.RootDispersion
+	if got != p {
+		t.Errorf("ParsePacket(Marshal()) = %+v, want %+v", got, p)
+	}
+
+	if _, err := sntp.ParsePacket(make([]byte, 47)); !errors.Is(err, sntp.ErrShortPacket) {
+		t.Errorf("короткий пакет: err = %v", err)
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+
+	for _, tm := range []time.Time{
+		time.Date(2026, 10, 18, 12, 30, 15, 123456789, time.UTC),
+		time.Date(2040, 1, 1, 0, 0, 0, 500000000, time.UTC), // эра 1: после переполнения в 2036 году
+	} {
+		if got := sntp.NewTimestamp(tm).Time(); got.Sub(tm).Abs() > time.Nanosecond {
+			t.Errorf("NewTimestamp(%v).Time() = %v", tm, got)
+		}
+	}
+}
+
+func TestServeLocalClock(t *testing.T) {
+
+	s := query(start(t, sntp.Config{}))
+	if s.Err != nil {
+		t.Fatal(s.Err)
+	}
+	if s.Offset.Abs() > 50*time.Millisecond {
+		t.Errorf("Offset = %v, want около 0", s.Offset)
+	}
+	if s.Stratum != 10 || s.Leap != sntp.LeapNone {
+		t.Errorf("Stratum = %d, Leap = %d", s.Stratum, s.Leap)
+	}
+
+	t.Log("Страта и признак leap из настроек")
+	s = query(start(t, sntp.Config{Stratum: 2, Leap: sntp.LeapAddSec}))
+	if s.Err != nil || s.Stratum != 2 || s.Leap != sntp.LeapAddSec {
+		t.Errorf("Stratum = %d, Leap = %d, Err = %v", s.Stratum, s.Leap, s.Err)
+	}
+
+	s = query(start(t, sntp.Config{Leap: sntp.LeapNotInSync}))
+	if s.Err == nil {
+		t.Error("клиент принял ответ несинхронизированного сервера")
+	}
+}
+
+func TestServeUpstream(t *testing.T) {
+
+	upstream := ntptest.Start(t, ntptest.Server{Offset: 2 * time.Second, Stratum: 3, RootDelay: 4 * time.Millisecond})
+	addr := start(t, sntp.Config{Upstream: upstream, Poll: time.Hour})
+
+	var s ntpcheck.Sample
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		if s = query(addr); s.Err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if s.Err != nil {
+		t.Fatal(s.Err)
+	}
+	if d := s.Offset - 2*time.Second; d.Abs() > 50*time.Millisecond {
+		t.Errorf("Offset = %v, want около 2s (время вышестоящего сервера)", s.Offset)
+	}
+	if s.Stratum != 4 {
+		t.Errorf("Stratum = %d, want 4 (upstream + 1)", s.Stratum)
+	}
+	if s.RootDelay < 4*time.Millisecond {
+		t.Errorf("RootDelay = %v, want не меньше root delay upstream", s.RootDelay)
+	}
+}
+
+func TestServeUpstreamNotSynced(t *testing.T) {
+
+	upstream := ntptest.Start(t, ntptest.Server{Silent: true})
+	s := query(start(t, sntp.Config{Upstream: upstream}))
+	if s.Err == nil {
+		t.Fatal("сервер без ответа upstream выдал время как синхронизированное")
+	}
+	if s.Leap != sntp.LeapNotInSync {
+		t.Errorf("Leap = %d, want %d", s.Leap, sntp.LeapNotInSync)
+	}
+}
+
+func TestServeKissOfDeath(t *testing.T) {
+
+	t.Run("RATE", func(t *testing.T) {
+		addr := start(t, sntp.Config{Rate: 0.1, Burst: 2})
+		for i := range 2 {
+			if s := query(addr); s.Err != nil {
+				t.Fatalf("запрос %d: %v", i+1, s.Err)
+			}
+		}
+		var kod *ntpcheck.KissOfDeathError
+		if s := query(addr); !errors.As(s.Err, &kod) || kod.Code != "RATE" {
+			t.Errorf("третий запрос: err = %v, want Kiss-o'-Death RATE", s.Err)
+		}
+	})
+
+	t.Run("DENY", func(t *testing.T) {
+		addr := start(t, sntp.Config{Deny: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}})
+		var kod *ntpcheck.KissOfDeathError
+		if s := query(addr); !errors.As(s.Err, &kod) || kod.Code != "DENY" {
+			t.Errorf("err = %v, want Kiss-o'-Death DENY", s.Err)
+		}
+	})
+}
+
+func TestServeIgnoresNonClient(t *testing.T) {
+
+	conn, err := net.Dial("udp", start(t, sntp.Config{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, req := range [][]byte{
+		sntp.Packet{Version: 4, Mode: sntp.ModeServer}.Marshal(), // ответ сервера
+		sntp.Packet{Version: 0, Mode: sntp.ModeClient}.Marshal(), // неверная версия
+		make([]byte, 20), // короткий пакет
+	} {
+		if _, err := conn.Write(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if n, err := conn.Read(make([]byte, 100)); err == nil {
+		t.Errorf("сервер ответил на неверный запрос (%d байт)", n)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+
+	for _, cfg := range []sntp.Config{
+		{Stratum: 16},
+		{Leap: 4},
+		{Rate: -1},
+		{Poll: -time.Second},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil", cfg)
+		}
+	}
+}
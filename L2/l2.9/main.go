@@ -3,12 +3,20 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
+// maxRepeatCount - предельное количество повторений одного символа,
+// защищает от строк вида "a999999999", распаковка которых съест всю память
+const maxRepeatCount = 1 << 20
+
 // unpackingString распаковывает строку с учётом escape-последовательностей
 func unpackingString(str string) (string, error) {
 
@@ -21,67 +29,201 @@ func unpackingString(str string) (string, error) {
 		return "", fmt.Errorf("некорректная строка: в строке только знак экранирования")
 	}
 
-	runes := []rune(str) // представляем вход как []rune
-
-	// если цифра в начале строки, выдаём ошибку (под этот случай подпадает и строка из цифр)
-	if unicode.IsDigit(runes[0]) {
-		return "", fmt.Errorf("некорректная строка: цифра в начале")
+	var sb strings.Builder
+	if err := unpackStream(strings.NewReader(str), &sb, maxRepeatCount); err != nil {
+		return "", err
 	}
 
-	result := make([]rune, 0) // место под распаковку
-	escapeFlag := false       // флаг встречи с '\'
+	return sb.String(), nil
+}
 
-	// итерируемся по runes
-	for i := 0; i < len(runes); i++ {
+// unpackStream потоково распаковывает данные из r в w, не держа в памяти ни вход, ни результат.
+// Количество повторений одного символа ограничено maxCount.
+// При ошибке в w может остаться уже распакованная часть
+func unpackStream(r io.Reader, w io.Writer, maxCount int) error {
 
-		// после экранировки добавляем символ как есть, переключаем флаг и идём за новым символом
-		if escapeFlag {
-			result = append(result, runes[i])
-			escapeFlag = false
-			continue
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+
+	var prev []byte     // последний выведенный символ (в байтах) для повторения
+	count := 0          // накопленное число повторений
+	inCount := false    // читаем ли сейчас число
+	escapeFlag := false // флаг встречи с '\'
+	var buf [utf8.UTFMax]byte
+
+	// flushCount дописывает предыдущий символ count-1 раз, когда число закончилось
+	flushCount := func() error {
+		if !inCount {
+			return nil
+		}
+		inCount = false
+		if count == 0 {
+			return fmt.Errorf("некорректная строка: 0 в количестве повторений")
 		}
+		for range count - 1 {
+			if _, err := out.Write(prev); err != nil {
+				return err
+			}
+		}
+		count = 0
+		return nil
+	}
 
-		// если встречаем '\', переключаем флаг и идём за новым символом
-		if runes[i] == '\\' {
-			escapeFlag = true
+	// итерируемся по рунам
+	for {
+		ch, _, err := in.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка чтения: %w", err)
+		}
+
+		// после экранировки добавляем символ как есть и переключаем флаг
+		if escapeFlag {
+			n := utf8.EncodeRune(buf[:], ch)
+			prev = append(prev[:0], buf[:n]...)
+			if _, err := out.Write(prev); err != nil {
+				return err
+			}
+			escapeFlag = false
 			continue
 		}
 
-		// если экранировки нет и
-		// если встречаем число, надо повторить предыдущий символ n-1 раз
-		if unicode.IsDigit(runes[i]) {
-			// находим конец числа
-			numStart := i
-			numEnd := i
-			for numEnd < len(runes) && unicode.IsDigit(runes[numEnd]) {
-				numEnd++
+		// если встречаем число, копим его (число может быть многозначным)
+		if unicode.IsDigit(ch) {
+			// цифра в начале строки - ошибка (под этот случай подпадает и строка из цифр)
+			if prev == nil {
+				return fmt.Errorf("некорректная строка: цифра в начале")
 			}
-			// извлекаем число
-			countInRunes := string(runes[numStart:numEnd])
-			n, err := strconv.Atoi(countInRunes)
+			digit, err := strconv.Atoi(string(ch))
 			if err != nil {
-				return "", fmt.Errorf("ошибка парсинга числа '%s': %w", countInRunes, err)
+				return fmt.Errorf("ошибка парсинга числа '%c': %w", ch, err)
 			}
-			if n == 0 {
-				return "", fmt.Errorf("некорректная строка: 0 в количестве повторений")
+			inCount = true
+			count = count*10 + digit
+			// проверяем лимит сразу, чтобы не переполнить int на длинном числе
+			if count > maxCount {
+				return fmt.Errorf("некорректная строка: количество повторений больше %d", maxCount)
 			}
-			symbolRepeat := strings.Repeat(string(result[len(result)-1]), n-1)
-			result = append(result, []rune(symbolRepeat)...)
+			continue
+		}
+
+		// любой другой символ завершает число
+		if err := flushCount(); err != nil {
+			return err
+		}
 
-			// перемещаем индекс за число
-			i = numEnd - 1
-		} else {
-			// а если встретили не цифру, то просто добавляем в result
-			result = append(result, runes[i])
+		// если встречаем '\', переключаем флаг и идём за новым символом
+		if ch == '\\' {
+			escapeFlag = true
+			continue
+		}
+
+		// а если встретили не цифру, то просто выводим
+		n := utf8.EncodeRune(buf[:], ch)
+		prev = append(prev[:0], buf[:n]...)
+		if _, err := out.Write(prev); err != nil {
+			return err
 		}
 	}
 
+	if err := flushCount(); err != nil {
+		return err
+	}
+
 	// если по окончанию итерации у нас escapeFlag == true, значит '\' в конце строки
 	if escapeFlag {
-		return "", fmt.Errorf("некорректная строка: escape-символ в конце")
+		return fmt.Errorf("некорректная строка: escape-символ в конце")
+	}
+
+	return out.Flush()
+}
+
+// packingString упаковывает строку - операция, обратная unpackingString.
+// Возвращает самую короткую (в байтах) корректную запись: серия символов сворачивается
+// в "символ+число" только тогда, когда это короче, а цифры и '\' экранируются
+func packingString(str string) string {
+
+	var sb strings.Builder
+	// запись в strings.Builder не возвращает ошибок
+	_ = packStream(strings.NewReader(str), &sb, maxRepeatCount)
+
+	return sb.String()
+}
+
+// packStream потоково упаковывает данные из r в w.
+// Серии длиннее maxCount разбиваются на части, чтобы результат распаковывался с тем же лимитом
+func packStream(r io.Reader, w io.Writer, maxCount int) error {
+
+	if maxCount < 1 {
+		return errors.New("лимит повторений должен быть положительным")
+	}
+
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+
+	var current rune // символ текущей серии
+	count := 0       // длина текущей серии
+
+	for {
+		ch, _, err := in.ReadRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка чтения: %w", err)
+		}
+
+		if count > 0 && ch == current {
+			count++
+			continue
+		}
+
+		if err := writeRun(out, current, count, maxCount); err != nil {
+			return err
+		}
+		current = ch
+		count = 1
+	}
+
+	if err := writeRun(out, current, count, maxCount); err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
+
+// writeRun записывает серию из count символов ch в кратчайшем виде
+func writeRun(out *bufio.Writer, ch rune, count, maxCount int) error {
+
+	// цифры и '\' при упаковке нужно экранировать
+	literal := string(ch)
+	if unicode.IsDigit(ch) || ch == '\\' {
+		literal = "\\" + literal
+	}
+
+	for count > 0 {
+		n := min(count, maxCount)
+		count -= n
+
+		encoded := literal
+		if n > 1 {
+			counted := literal + strconv.Itoa(n)
+			// при равной длине оставляем серию как есть - так читабельнее
+			if len(counted) < len(literal)*n {
+				encoded = counted
+			} else {
+				encoded = strings.Repeat(literal, n)
+			}
+		}
+
+		if _, err := out.WriteString(encoded); err != nil {
+			return err
+		}
 	}
 
-	return string(result), nil
+	return nil
 }
 
 func main() {
@@ -99,6 +241,7 @@ func main() {
 		"\\\\a",     // "\a"
 		"\\510",     // "5555555555"
 		"a0",        // "" + err (некорректная строка, т.к. 0 в количестве повторений)
+		"a9999999",  // "" + err (некорректная строка, т.к. превышен лимит повторений)
 	}
 
 	for _, v := range input {
@@ -107,6 +250,6 @@ func main() {
 			fmt.Printf("Строка: %10s,  ошибка: %v\n", v, err)
 			continue
 		}
-		fmt.Printf("Строка: %10s,  распаковка: %s\n", v, resUnpack)
+		fmt.Printf("Строка: %10s,  распаковка: %s,  упаковка обратно: %s\n", v, resUnpack, packingString(resUnpack))
 	}
 }
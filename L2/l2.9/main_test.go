@@ -1,6 +1,10 @@
 package main
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
 
 func TestUnpackingString(t *testing.T) {
 
@@ -40,3 +44,157 @@ func TestUnpackingString(t *testing.T) {
 		})
 	}
 }
+
+func TestUnpackingStringLimit(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		str      string
+		maxCount int
+		want     string
+		wantErr  bool
+	}{
+		{"многозначное число", "a12", 20, "aaaaaaaaaaaa", false},
+		{"ровно лимит", "b5", 5, "bbbbb", false},
+		{"больше лимита", "b6", 5, "", true},
+		{"очень длинное число", "c99999999999999999999999", 100, "", true},
+		{"ведущий ноль", "d03", 5, "ddd", false},
+		{"ноль из нескольких цифр", "e00", 5, "", true},
+		{"кириллица", "я3б", 5, "яяяб", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := unpackStream(strings.NewReader(tt.str), &sb, tt.maxCount)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unpackStream() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && sb.String() != tt.want {
+				t.Errorf("unpackStream() = %v, want %v", sb.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPackingString(t *testing.T) {
+
+	tests := []struct {
+		name string
+		str  string
+		want string
+	}{
+		{"1", "aaaabccddddde", "a4bccd5e"},
+		{"2", "abcd", "abcd"},
+		{"3", "", ""},
+		{"4", "qwe45", "qwe\\4\\5"},
+		{"5", "qwe44444", "qwe\\45"},
+		{"6", "44", "\\42"},
+		{"7", "\\a", "\\\\a"},
+		{"8", "5555555555", "\\510"},
+		{"9", "aaaaaaaaaaaa", "a12"},
+		{"10", "яяяяб", "я4б"},
+		{"11", "яя", "я2"}, // в байтах "я2" короче, чем "яя"
+		{"12", "aa", "aa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := packingString(tt.str); got != tt.want {
+				t.Errorf("packingString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackStreamChunks(t *testing.T) {
+
+	// серия длиннее лимита разбивается на части, каждая из которых распаковывается с тем же лимитом
+	str := strings.Repeat("x", 25)
+
+	var packed strings.Builder
+	if err := packStream(strings.NewReader(str), &packed, 10); err != nil {
+		t.Fatalf("packStream() error = %v", err)
+	}
+	if packed.String() != "x10x10x5" {
+		t.Errorf("packStream() = %v, want %v", packed.String(), "x10x10x5")
+	}
+
+	var unpacked strings.Builder
+	if err := unpackStream(strings.NewReader(packed.String()), &unpacked, 10); err != nil {
+		t.Fatalf("unpackStream() error = %v", err)
+	}
+	if unpacked.String() != str {
+		t.Errorf("unpackStream() = %v, want %v", unpacked.String(), str)
+	}
+
+	if err := packStream(strings.NewReader(str), &packed, 0); err == nil {
+		t.Error("packStream() expected error for zero limit")
+	}
+}
+
+// FuzzPackUnpack проверяет, что любая строка переживает упаковку и распаковку без изменений
+func FuzzPackUnpack(f *testing.F) {
+
+	for _, s := range []string{"", "a", "aaaabccddddde", "qwe45", "\\\\", "44444", "яяя123"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		// упаковка работает с рунами, поэтому некорректный UTF-8 не сохраняется
+		if !utf8.ValidString(s) {
+			t.Skip()
+		}
+
+		packed := packingString(s)
+		unpacked, err := unpackingString(packed)
+		if err != nil {
+			t.Fatalf("unpackingString(%q) error = %v", packed, err)
+		}
+		if unpacked != s {
+			t.Fatalf("unpackingString(packingString(%q)) = %q", s, unpacked)
+		}
+		if len(packed) > 2*len(s) {
+			t.Fatalf("packingString(%q) = %q is longer than escaping every rune", s, packed)
+		}
+	})
+}
+
+// FuzzUnpackPack проверяет инварианты pack(unpack(x)) для корректных упакованных строк:
+// повторная распаковка даёт то же самое, а упаковка не длиннее исходной записи
+func FuzzUnpackPack(f *testing.F) {
+
+	for _, s := range []string{"a4bc2d5e", "abcd", "qwe\\4\\5", "qwe\\45", "\\\\a", "\\510", "a2a2", "a1"} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, x string) {
+		if !utf8.ValidString(x) {
+			t.Skip()
+		}
+
+		unpacked, err := unpackingString(x)
+		if err != nil {
+			t.Skip()
+		}
+
+		packed := packingString(unpacked)
+		if len(packed) > len(x) {
+			t.Fatalf("packingString(%q) = %q is longer than %q", unpacked, packed, x)
+		}
+
+		again, err := unpackingString(packed)
+		if err != nil {
+			t.Fatalf("unpackingString(%q) error = %v", packed, err)
+		}
+		if again != unpacked {
+			t.Fatalf("unpackingString(%q) = %q, want %q", packed, again, unpacked)
+		}
+
+		// упаковка идемпотентна
+		if packingString(again) != packed {
+			t.Fatalf("packingString is not stable for %q", unpacked)
+		}
+	})
+}